@@ -0,0 +1,16 @@
+#!/bin/bash
+# Builds llmconfig-extension.zip, a Lambda layer holding the llmconfig
+# extension, for the architecture in $GOARCH (amd64 or arm64).
+set -euo pipefail
+
+GOARCH=${GOARCH:-amd64}
+ROOT=$(cd "$(dirname "$0")/../.." && pwd)
+BUILD=$(mktemp -d)
+trap 'rm -rf "$BUILD"' EXIT
+
+mkdir -p "$BUILD/bin" "$BUILD/extensions"
+(cd "$ROOT/docs/api/examples" && CGO_ENABLED=0 GOOS=linux GOARCH=$GOARCH go build -o "$BUILD/bin/llmconfig" .)
+cp "$ROOT/deployment/lambda/extensions/llmconfig" "$BUILD/extensions/"
+
+(cd "$BUILD" && zip -qr - bin extensions) > llmconfig-extension.zip
+echo "Built llmconfig-extension.zip ($GOARCH)"
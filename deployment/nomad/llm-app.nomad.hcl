@@ -0,0 +1,73 @@
+# An application reading its config from a file rendered by
+# `llmconfig consul-template` in a sidecar task. The sidecar starts first,
+# renders app.conf into the shared alloc directory, and keeps it current;
+# the template is the one the job used with Consul, with key paths changed
+# to namespace/key.
+job "llm-app" {
+  datacenters = ["dc1"]
+
+  group "app" {
+    task "llm-config" {
+      driver = "docker"
+
+      lifecycle {
+        hook    = "prestart"
+        sidecar = true
+      }
+
+      config {
+        image = "llmconfig:latest"
+        args = [
+          "consul-template",
+          "--env", "production",
+          "--template", "${NOMAD_TASK_DIR}/app.conf.ctmpl:${NOMAD_ALLOC_DIR}/config/app.conf",
+        ]
+      }
+
+      env {
+        LLM_CONFIG_URL = "http://llm-config-manager.service.consul:8080/api/v1"
+      }
+
+      # The token comes from Nomad Variables rather than the job file
+      template {
+        destination = "${NOMAD_SECRETS_DIR}/token.env"
+        env         = true
+        data        = <<-EOT
+          LLM_CONFIG_TOKEN={{ with nomadVar "nomad/jobs/llm-app" }}{{ .llm_config_token }}{{ end }}
+        EOT
+      }
+
+      # Rendered by llmconfig, not by Nomad: the delimiters are changed so
+      # Nomad copies it through untouched
+      template {
+        destination     = "${NOMAD_TASK_DIR}/app.conf.ctmpl"
+        left_delimiter  = "[["
+        right_delimiter = "]]"
+        data            = <<-EOT
+          model = "{{ key "app/llm/model" }}"
+          timeout = {{ keyOrDefault "app/llm/timeout" "30" }}
+          {{ with secret "app/llm/openai_api_key" }}api_key = "{{ .Data.value }}"{{ end }}
+        EOT
+      }
+
+      resources {
+        cpu    = 50
+        memory = 64
+      }
+    }
+
+    task "app" {
+      driver = "docker"
+
+      config {
+        image = "my-llm-app:latest"
+        args  = ["--config", "${NOMAD_ALLOC_DIR}/config/app.conf"]
+      }
+
+      resources {
+        cpu    = 500
+        memory = 512
+      }
+    }
+  }
+}
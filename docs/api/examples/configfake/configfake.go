@@ -0,0 +1,524 @@
+// Package configfake is an in-memory, httptest-backed fake of the LLM Config
+// Manager REST API for hermetic integration tests:
+//
+//	fake := configfake.New(configfake.WithRateLimit(100, time.Minute))
+//	defer fake.Close()
+//	fake.Seed("llm", "model", "base", "gpt-4")
+//
+//	client := NewLLMConfigClient(fake.URL(), "")
+//
+// It mirrors the server's routes, response shapes, and error bodies:
+// environment aliases, versioned history (newest first), rollback as a new
+// version, secrets returned as "<encrypted>", and with_overrides resolution
+// through the base → development → staging → production chain.
+package configfake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encryptedPlaceholder is what the API returns in place of secret values
+const encryptedPlaceholder = "<encrypted>"
+
+// Server is a running fake API
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	entries map[entryKey]*entry
+	token   string
+	limiter *rateLimiter
+	now     func() time.Time
+	calls   []Call
+}
+
+// Call is a request the fake received, for asserting on client behavior
+type Call struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithToken makes the fake require "Authorization: Bearer <token>"
+func WithToken(token string) Option {
+	return func(s *Server) { s.token = token }
+}
+
+// WithRateLimit allows limit requests per window, answering 429 with
+// Retry-After beyond that and sending X-RateLimit-* headers throughout
+func WithRateLimit(limit int, window time.Duration) Option {
+	return func(s *Server) { s.limiter = &rateLimiter{limit: limit, window: window} }
+}
+
+// WithClock overrides the time source used for timestamps and rate limits
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) { s.now = now }
+}
+
+type entryKey struct {
+	namespace, key, env string
+}
+
+type version struct {
+	Version           int64       `json:"version"`
+	Value             interface{} `json:"value"`
+	CreatedAt         string      `json:"created_at"`
+	CreatedBy         string      `json:"created_by"`
+	ChangeDescription *string     `json:"change_description"`
+}
+
+type entry struct {
+	id        string
+	value     interface{}
+	secret    bool
+	version   int64
+	createdAt time.Time
+	createdBy string
+	updatedAt time.Time
+	updatedBy string
+	tags      []string
+	// history is newest first, like the server's
+	history []version
+}
+
+// New starts a fake server; call Close when done
+func New(opts ...Option) *Server {
+	s := &Server{entries: map[entryKey]*entry{}, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL is the API base URL to pass to the client
+func (s *Server) URL() string {
+	return s.Server.URL + "/api/v1"
+}
+
+// Seed stores a plain value as if written by "seed"
+func (s *Server) Seed(namespace, key, env string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(namespace, key, mustEnv(env), normalize(value), false, "seed", nil)
+}
+
+// SeedSecret stores a secret value, returned as "<encrypted>"
+func (s *Server) SeedSecret(namespace, key, env, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(namespace, key, mustEnv(env), value, true, "seed", nil)
+}
+
+// Value returns the stored value of a key, including secret plaintext
+func (s *Server) Value(namespace, key, env string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[entryKey{namespace, key, mustEnv(env)}]
+	if !ok {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Calls returns every request received so far
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Reset clears all stored configs, recorded calls, and rate-limit state
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = map[entryKey]*entry{}
+	s.calls = nil
+	if s.limiter != nil {
+		s.limiter.count, s.limiter.windowStart = 0, time.Time{}
+	}
+}
+
+func mustEnv(env string) string {
+	canonical, ok := parseEnvironment(env)
+	if !ok {
+		panic(fmt.Sprintf("configfake: unknown environment %q", env))
+	}
+	return canonical
+}
+
+// parseEnvironment accepts the same names and aliases as the server
+func parseEnvironment(env string) (string, bool) {
+	switch strings.ToLower(env) {
+	case "base":
+		return "base", true
+	case "dev", "development":
+		return "development", true
+	case "staging", "stage":
+		return "staging", true
+	case "prod", "production":
+		return "production", true
+	case "edge":
+		return "edge", true
+	}
+	return "", false
+}
+
+// overrideChain lists the environments applied, in order, when resolving
+// with overrides
+var overrideChain = map[string][]string{
+	"base":        {"base"},
+	"development": {"base", "development"},
+	"staging":     {"base", "development", "staging"},
+	"production":  {"base", "development", "staging", "production"},
+	"edge":        {"base", "edge"},
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, Call{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Body: body})
+
+	if r.URL.Path == "/health" && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "llm-config-manager", "version": "fake"})
+		return
+	}
+
+	apiPath, ok := strings.CutPrefix(r.URL.Path, "/api/v1/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "no route")
+		return
+	}
+
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, http.StatusUnauthorized, "Missing or invalid token")
+		return
+	}
+	if s.limiter != nil && !s.limiter.allow(w, s.now()) {
+		writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+		return
+	}
+
+	env := r.URL.Query().Get("env")
+	segments := strings.Split(apiPath, "/")
+	if len(segments) < 2 || segments[0] != "configs" {
+		writeError(w, http.StatusNotFound, "no route")
+		return
+	}
+	namespace := segments[1]
+
+	switch {
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		s.handleList(w, namespace, env)
+	case len(segments) == 3 && r.Method == http.MethodGet:
+		s.handleGet(w, namespace, segments[2], env, r.URL.Query().Get("with_overrides") == "true")
+	case len(segments) == 3 && r.Method == http.MethodPost:
+		s.handleSet(w, namespace, segments[2], body)
+	case len(segments) == 3 && r.Method == http.MethodDelete:
+		s.handleDelete(w, namespace, segments[2], env)
+	case len(segments) == 4 && segments[3] == "history" && r.Method == http.MethodGet:
+		s.handleHistory(w, namespace, segments[2], env)
+	case len(segments) == 5 && segments[3] == "rollback" && r.Method == http.MethodPost:
+		s.handleRollback(w, namespace, segments[2], segments[4], env)
+	default:
+		writeError(w, http.StatusNotFound, "no route")
+	}
+}
+
+// resolveEnv applies the server's default of development
+func resolveEnv(w http.ResponseWriter, env string) (string, bool) {
+	if env == "" {
+		env = "development"
+	}
+	canonical, ok := parseEnvironment(env)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Unknown environment: "+env)
+	}
+	return canonical, ok
+}
+
+func (s *Server) handleList(w http.ResponseWriter, namespace, env string) {
+	env, ok := resolveEnv(w, env)
+	if !ok {
+		return
+	}
+	var keys []string
+	for k := range s.entries {
+		if k.namespace == namespace && k.env == env {
+			keys = append(keys, k.key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := make([]configResponse, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, s.response(entryKey{namespace, key, env}))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, namespace, key, env string, withOverrides bool) {
+	env, ok := resolveEnv(w, env)
+	if !ok {
+		return
+	}
+
+	k := entryKey{namespace, key, env}
+	if withOverrides {
+		var resolved *entryKey
+		for _, candidate := range overrideChain[env] {
+			ck := entryKey{namespace, key, candidate}
+			if _, ok := s.entries[ck]; ok {
+				resolved = &ck
+			}
+		}
+		if resolved != nil {
+			k = *resolved
+		}
+	}
+
+	if _, ok := s.entries[k]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Configuration not found: %s:%s", namespace, key))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.response(k))
+}
+
+func (s *Server) handleSet(w http.ResponseWriter, namespace, key string, body []byte) {
+	var req struct {
+		Value  interface{} `json:"value"`
+		Env    string      `json:"env"`
+		User   string      `json:"user"`
+		Secret bool        `json:"secret"`
+		Tags   []string    `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	env, ok := parseEnvironment(req.Env)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Unknown environment: "+req.Env)
+		return
+	}
+	if req.User == "" {
+		req.User = "api-user"
+	}
+	if _, isString := req.Value.(string); req.Secret && !isString {
+		writeError(w, http.StatusBadRequest, "Secret value must be a string")
+		return
+	}
+	if req.Value == nil {
+		req.Value = ""
+	}
+
+	s.set(namespace, key, env, normalize(req.Value), req.Secret, req.User, req.Tags)
+	writeJSON(w, http.StatusOK, s.response(entryKey{namespace, key, env}))
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, namespace, key, env string) {
+	env, ok := resolveEnv(w, env)
+	if !ok {
+		return
+	}
+	k := entryKey{namespace, key, env}
+	if _, ok := s.entries[k]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Configuration not found: %s:%s", namespace, key))
+		return
+	}
+	delete(s.entries, k)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, namespace, key, env string) {
+	env, ok := resolveEnv(w, env)
+	if !ok {
+		return
+	}
+	history := []version{}
+	if e, ok := s.entries[entryKey{namespace, key, env}]; ok {
+		for _, v := range e.history {
+			if e.secret {
+				v.Value = encryptedPlaceholder
+			}
+			history = append(history, v)
+		}
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (s *Server) handleRollback(w http.ResponseWriter, namespace, key, rawVersion, env string) {
+	env, ok := resolveEnv(w, env)
+	if !ok {
+		return
+	}
+	target, err := strconv.ParseInt(rawVersion, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid version: "+rawVersion)
+		return
+	}
+
+	k := entryKey{namespace, key, env}
+	e, ok := s.entries[k]
+	if ok {
+		for _, v := range e.history {
+			if v.Version == target {
+				desc := fmt.Sprintf("Rollback to version %d", target)
+				s.set(namespace, key, env, v.Value, e.secret, e.updatedBy, e.tags)
+				e.history[0].ChangeDescription = &desc
+				writeJSON(w, http.StatusOK, s.response(k))
+				return
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("Version %d not found", target))
+}
+
+// set writes a new version of a key; the caller holds s.mu
+func (s *Server) set(namespace, key, env string, value interface{}, secret bool, user string, tags []string) {
+	now := s.now().UTC()
+	k := entryKey{namespace, key, env}
+	e, ok := s.entries[k]
+	if !ok {
+		e = &entry{id: newID(), createdAt: now, createdBy: user}
+		s.entries[k] = e
+	}
+	e.value = value
+	e.secret = secret
+	e.version++
+	e.updatedAt = now
+	e.updatedBy = user
+	if tags != nil {
+		e.tags = tags
+	}
+	e.history = append([]version{{
+		Version:   e.version,
+		Value:     value,
+		CreatedAt: now.Format(time.RFC3339Nano),
+		CreatedBy: user,
+	}}, e.history...)
+}
+
+type configMetadata struct {
+	CreatedAt   string   `json:"created_at"`
+	CreatedBy   string   `json:"created_by"`
+	UpdatedAt   string   `json:"updated_at"`
+	UpdatedBy   string   `json:"updated_by"`
+	Tags        []string `json:"tags"`
+	Description *string  `json:"description"`
+}
+
+type configResponse struct {
+	ID          string         `json:"id"`
+	Namespace   string         `json:"namespace"`
+	Key         string         `json:"key"`
+	Value       interface{}    `json:"value"`
+	Environment string         `json:"environment"`
+	Version     int64          `json:"version"`
+	Metadata    configMetadata `json:"metadata"`
+}
+
+func (s *Server) response(k entryKey) configResponse {
+	e := s.entries[k]
+	value := e.value
+	if e.secret {
+		value = encryptedPlaceholder
+	}
+	tags := e.tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return configResponse{
+		ID:          e.id,
+		Namespace:   k.namespace,
+		Key:         k.key,
+		Value:       value,
+		Environment: k.env,
+		Version:     e.version,
+		Metadata: configMetadata{
+			CreatedAt: e.createdAt.Format(time.RFC3339Nano),
+			CreatedBy: e.createdBy,
+			UpdatedAt: e.updatedAt.Format(time.RFC3339Nano),
+			UpdatedBy: e.updatedBy,
+			Tags:      tags,
+		},
+	}
+}
+
+// rateLimiter is a fixed-window limiter matching the server's headers
+type rateLimiter struct {
+	limit       int
+	window      time.Duration
+	count       int
+	windowStart time.Time
+}
+
+func (l *rateLimiter) allow(w http.ResponseWriter, now time.Time) bool {
+	if l.windowStart.IsZero() || now.Sub(l.windowStart) >= l.window {
+		l.windowStart, l.count = now, 0
+	}
+	reset := l.windowStart.Add(l.window)
+
+	allowed := l.count < l.limit
+	if allowed {
+		l.count++
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(l.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(l.limit-l.count))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reset.Sub(now).Seconds()))))
+	}
+	return allowed
+}
+
+// normalize round-trips a value through JSON so seeded Go values look
+// exactly like decoded request bodies
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Sprintf("configfake: value is not JSON-encodable: %v", err))
+	}
+	var out interface{}
+	json.Unmarshal(data, &out)
+	return out
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes the server's {"error", "message"} error body
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": http.StatusText(status), "message": message})
+}
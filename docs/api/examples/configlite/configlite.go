@@ -0,0 +1,365 @@
+// Package configlite is a client for the core of the LLM Config Manager
+// REST API that uses only the standard library's net/http, for minimal
+// binaries that cannot take on resty and the full client's dependencies:
+//
+//	client := configlite.New("http://llm-config:8080/api/v1", token,
+//		configlite.WithTransport(myAuthMiddleware(http.DefaultTransport)))
+//	cfg, err := client.GetConfig(ctx, "app/llm", "model", "production", false)
+//	if errors.Is(err, configlite.ErrNotFound) { ... }
+//
+// It reads, lists, writes, and deletes configs, reads history, and checks
+// health, with the same request IDs, error sentinels, and retry policy as
+// the full client: 429s and 5xx answers other than writes refused for
+// maintenance are retried, honoring Retry-After up to five minutes.
+// Everything else, caching, validation, watches, and the rest, needs the
+// full client.
+package configlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for errors.Is; an *Error matches at most one of these
+var (
+	// ErrNotFound means the namespace, key, or version does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the write lost a race with another writer
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthorized means the token is missing, invalid, or lacks permission
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited means the server answered 429 after every retry
+	ErrRateLimited = errors.New("rate limited")
+	// ErrValidation means the server rejected the request
+	ErrValidation = errors.New("validation failed")
+)
+
+// Error is an error answer from the server
+type Error struct {
+	StatusCode int
+	// Code is the server's machine-readable error code, if any
+	Code    string
+	Message string
+	// RetryAfter is the server's requested backoff, e.g. on 429 answers
+	RetryAfter time.Duration
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("config client error (status %d, request %s): %s", e.StatusCode, e.RequestID, e.Message)
+}
+
+// Is maps the HTTP status to the matching sentinel error
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	}
+	return false
+}
+
+// Metadata is a config's bookkeeping
+type Metadata struct {
+	CreatedAt   string   `json:"created_at"`
+	CreatedBy   string   `json:"created_by"`
+	UpdatedAt   string   `json:"updated_at"`
+	UpdatedBy   string   `json:"updated_by"`
+	Tags        []string `json:"tags"`
+	Description *string  `json:"description"`
+}
+
+// Config is a configuration entry
+type Config struct {
+	ID          string      `json:"id"`
+	Namespace   string      `json:"namespace"`
+	Key         string      `json:"key"`
+	Value       interface{} `json:"value"`
+	Environment string      `json:"environment"`
+	Version     int64       `json:"version"`
+	Metadata    Metadata    `json:"metadata"`
+}
+
+// VersionEntry is one version in a config's history
+type VersionEntry struct {
+	Version           int64       `json:"version"`
+	Value             interface{} `json:"value"`
+	CreatedAt         string      `json:"created_at"`
+	CreatedBy         string      `json:"created_by"`
+	ChangeDescription *string     `json:"change_description"`
+}
+
+// Health is the server's health check answer
+type Health struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+	Version string `json:"version"`
+	// ReadOnly is set while the server is in maintenance and refuses writes
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// Client talks to the REST API
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	retries    int
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithTransport sends requests through rt, e.g. corporate auth middleware
+// or an observability wrapper around http.DefaultTransport
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.httpClient.Transport = rt }
+}
+
+// WithTimeout limits each attempt of a request; 10s by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.httpClient.Timeout = timeout }
+}
+
+// WithRetries sets how many times a 429 or 5xx answer is retried; 3 by
+// default
+func WithRetries(retries int) Option {
+	return func(c *Client) { c.retries = retries }
+}
+
+// New returns a client for the API at baseURL, e.g.
+// "http://llm-config:8080/api/v1", authenticating with token if not empty
+func New(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    baseURL,
+		token:      token,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		retries:    3,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetConfig retrieves a configuration value
+func (c *Client) GetConfig(ctx context.Context, namespace, key, env string, withOverrides bool) (*Config, error) {
+	var result Config
+	query := url.Values{"env": {env}, "with_overrides": {strconv.FormatBool(withOverrides)}}
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/configs/%s/%s", namespace, key), query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListConfigs lists all configurations in a namespace, following every
+// page of a paginated listing
+func (c *Client) ListConfigs(ctx context.Context, namespace, env string) ([]Config, error) {
+	var all []Config
+	query := url.Values{"env": {env}}
+	for {
+		var page []Config
+		header, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/configs/%s", namespace), query, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+
+		next := header.Get("X-Next-Cursor")
+		if next == "" {
+			return all, nil
+		}
+		if next == query.Get("cursor") {
+			return nil, fmt.Errorf("server returned cursor %q again", next)
+		}
+		query.Set("cursor", next)
+	}
+}
+
+// SetConfig creates or updates a configuration value
+func (c *Client) SetConfig(ctx context.Context, namespace, key string, value interface{}, env, user string, secret bool) (*Config, error) {
+	body := map[string]interface{}{"value": value, "env": env, "user": user, "secret": secret}
+	var result Config
+	if _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/configs/%s/%s", namespace, key), nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteConfig deletes a configuration, reporting false if it did not
+// exist
+func (c *Client) DeleteConfig(ctx context.Context, namespace, key, env string) (bool, error) {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/configs/%s/%s", namespace, key), url.Values{"env": {env}}, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetHistory retrieves a configuration's versions, newest first
+func (c *Client) GetHistory(ctx context.Context, namespace, key, env string) ([]VersionEntry, error) {
+	var result []VersionEntry
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/configs/%s/%s/history", namespace, key), url.Values{"env": {env}}, nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// HealthCheck reports the server's health
+func (c *Client) HealthCheck(ctx context.Context) (*Health, error) {
+	var result Health
+	if _, err := c.do(ctx, http.MethodGet, "/health", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Retry backoff bounds, as in the full client
+const (
+	retryWaitTime    = 1 * time.Second
+	retryMaxWaitTime = 30 * time.Second
+	// defaultRateLimitWait is waited on 429s without a Retry-After
+	defaultRateLimitWait = 60 * time.Second
+	// maxRetryWait is the longest wait retried; the server asking for
+	// longer fails the request instead
+	maxRetryWait = 5 * time.Minute
+)
+
+// do sends a request, retrying 429 and 5xx answers, and decodes a
+// successful answer's JSON into out when it is not nil. A write refused
+// for maintenance would only be refused again, and a wait that would
+// outlast ctx or maxRetryWait is not started: the caller gets the error,
+// with its RetryAfter, at once.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	requestID := newRequestID()
+
+	wait := retryWaitTime
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Request-ID", requestID)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < 400 {
+			if out != nil && len(data) > 0 {
+				if err := json.Unmarshal(data, out); err != nil {
+					return nil, fmt.Errorf("decode %s %s: %w", method, path, err)
+				}
+			}
+			return resp.Header, nil
+		}
+
+		apiErr := errorFrom(resp, data, requestID)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode >= 500 && !readOnly(resp, apiErr))
+		if !retryable || attempt >= c.retries {
+			return nil, apiErr
+		}
+		delay := min(wait, retryMaxWaitTime)
+		switch {
+		case apiErr.RetryAfter > 0:
+			delay = apiErr.RetryAfter
+		case resp.StatusCode == http.StatusTooManyRequests:
+			delay = defaultRateLimitWait
+		}
+		if deadline, ok := ctx.Deadline(); (ok && time.Until(deadline) < delay) || delay > maxRetryWait {
+			return nil, apiErr
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		wait *= 2
+	}
+}
+
+// readOnly reports whether an error answer is a write refused because the
+// server is in maintenance
+func readOnly(resp *http.Response, apiErr *Error) bool {
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	readOnly, _ := strconv.ParseBool(resp.Header.Get("X-LLM-Config-Read-Only"))
+	return readOnly || apiErr.Code == "read_only"
+}
+
+// errorFrom builds an *Error from an error answer
+func errorFrom(resp *http.Response, data []byte, requestID string) *Error {
+	apiErr := &Error{StatusCode: resp.StatusCode, RequestID: requestID, Message: string(data)}
+	if id := resp.Header.Get("X-Request-ID"); id != "" {
+		apiErr.RequestID = id
+	}
+	retryAfter := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs > 0 {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(retryAfter); err == nil {
+		// Relative to the server's clock when it sent one
+		now := time.Now()
+		if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			now = date
+		}
+		apiErr.RetryAfter = max(at.Sub(now), 0)
+	}
+
+	var body struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &body) == nil && (body.Error != "" || body.Message != "") {
+		apiErr.Code, apiErr.Message = body.Error, body.Message
+	}
+	return apiErr
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,228 @@
+// Package configtest serves recorded ("golden") API responses to code under
+// test and asserts that the requests it makes have the recorded shape:
+//
+//	func TestModelLookup(t *testing.T) {
+//		srv := configtest.NewServer(t,
+//			configtest.MustLoad(t, "get_config"),
+//			configtest.MustLoad(t, "history"),
+//		)
+//		client := NewLLMConfigClient(srv.URL(), "")
+//		// exercise code that calls GetConfig then GetHistory ...
+//	}
+//
+// Fixtures are served in order, one per request. A request that does not
+// match the next fixture's method, path, query, and body fails the test, as
+// does finishing the test with fixtures left unserved.
+//
+// Built-in fixtures live in fixtures/*.json; LoadDir reads an application's
+// own recordings in the same format. Fixture paths are relative to the API
+// base URL (/api/v1).
+package configtest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//go:embed fixtures/*.json
+var builtin embed.FS
+
+// apiBasePath is where Server mounts the API, matching the real server
+const apiBasePath = "/api/v1"
+
+// Fixture is one recorded request/response exchange
+type Fixture struct {
+	Name     string          `json:"-"`
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is the expected request shape. Query lists parameters that
+// must be present with these values (others are ignored); Body lists JSON
+// fields that must be present with these values (others are ignored).
+type FixtureRequest struct {
+	Method string                 `json:"method"`
+	Path   string                 `json:"path"`
+	Query  map[string]string      `json:"query,omitempty"`
+	Body   map[string]interface{} `json:"body,omitempty"`
+}
+
+// FixtureResponse is the recorded reply
+type FixtureResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Names lists the built-in fixtures
+func Names() []string {
+	entries, _ := fs.ReadDir(builtin, "fixtures")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return names
+}
+
+// MustLoad returns the built-in fixture name, failing the test if it is
+// missing or malformed
+func MustLoad(t testing.TB, name string) Fixture {
+	t.Helper()
+	data, err := builtin.ReadFile("fixtures/" + name + ".json")
+	if err != nil {
+		t.Fatalf("configtest: unknown fixture %q (have %s)", name, strings.Join(Names(), ", "))
+	}
+	f, err := parseFixture(name, data)
+	if err != nil {
+		t.Fatalf("configtest: %v", err)
+	}
+	return f
+}
+
+// LoadDir reads every *.json fixture in dir, sorted by file name
+func LoadDir(dir string) ([]Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	fixtures := make([]Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseFixture(strings.TrimSuffix(filepath.Base(path), ".json"), data)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+func parseFixture(name string, data []byte) (Fixture, error) {
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("fixture %s: %w", name, err)
+	}
+	if f.Request.Method == "" || f.Request.Path == "" || f.Response.Status == 0 {
+		return f, fmt.Errorf("fixture %s: request.method, request.path, and response.status are required", name)
+	}
+	f.Name = name
+	return f, nil
+}
+
+// Server replays fixtures for one test
+type Server struct {
+	*httptest.Server
+
+	t        testing.TB
+	mu       sync.Mutex
+	fixtures []Fixture
+	next     int
+}
+
+// NewServer starts a server replaying fixtures in order. It is closed, and
+// checked for unserved fixtures, when the test ends.
+func NewServer(t testing.TB, fixtures ...Fixture) *Server {
+	t.Helper()
+	s := &Server{t: t, fixtures: fixtures}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(func() {
+		s.Close()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, f := range s.fixtures[s.next:] {
+			t.Errorf("configtest: fixture %s (%s %s) was never requested", f.Name, f.Request.Method, f.Request.Path)
+		}
+	})
+	return s
+}
+
+// URL is the API base URL to pass to the client
+func (s *Server) URL() string {
+	return s.Server.URL + apiBasePath
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	if s.next >= len(s.fixtures) {
+		s.mu.Unlock()
+		s.t.Errorf("configtest: unexpected request %s %s: all fixtures served", r.Method, r.URL.Path)
+		http.Error(w, "no fixture left", http.StatusNotImplemented)
+		return
+	}
+	f := s.fixtures[s.next]
+	s.next++
+	s.mu.Unlock()
+
+	for _, problem := range requestMismatches(f.Request, r, body) {
+		s.t.Errorf("configtest: fixture %s: %s", f.Name, problem)
+	}
+
+	for name, value := range f.Response.Headers {
+		w.Header().Set(name, value)
+	}
+	if len(f.Response.Body) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(f.Response.Status)
+	w.Write(f.Response.Body)
+}
+
+// requestMismatches describes each way r differs from the expected shape
+func requestMismatches(want FixtureRequest, r *http.Request, body []byte) []string {
+	var problems []string
+
+	if r.Method != want.Method {
+		problems = append(problems, fmt.Sprintf("method = %s, want %s", r.Method, want.Method))
+	}
+	if path := strings.TrimPrefix(r.URL.Path, apiBasePath); path != want.Path {
+		problems = append(problems, fmt.Sprintf("path = %s, want %s", path, want.Path))
+	}
+
+	query := r.URL.Query()
+	for _, name := range sortedKeys(want.Query) {
+		if got := query.Get(name); got != want.Query[name] {
+			problems = append(problems, fmt.Sprintf("query %s = %q, want %q", name, got, want.Query[name]))
+		}
+	}
+
+	if len(want.Body) > 0 {
+		var got map[string]interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			return append(problems, fmt.Sprintf("body is not a JSON object: %v", err))
+		}
+		for _, field := range sortedKeys(want.Body) {
+			if !reflect.DeepEqual(got[field], want.Body[field]) {
+				problems = append(problems, fmt.Sprintf("body.%s = %v, want %v", field, got[field], want.Body[field]))
+			}
+		}
+	}
+	return problems
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
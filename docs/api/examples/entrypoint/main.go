@@ -0,0 +1,401 @@
+// Command llm-config-entrypoint is a container ENTRYPOINT that resolves
+// configs into environment variables and files, then execs the image's
+// command in its place:
+//
+//	COPY --from=llm-config-entrypoint /llm-config-entrypoint /llm-config-entrypoint
+//	ENV LLM_CONFIG_URL=http://llm-config:8080/api/v1 LLM_CONFIG_NAMESPACES=app/llm
+//	ENTRYPOINT ["/llm-config-entrypoint", "--"]
+//	CMD ["./my-service"]
+//
+// It uses only the standard library, so it builds as a small static binary
+// (CGO_ENABLED=0) that fits in scratch and distroless images. It is set up
+// through the environment:
+//
+//	LLM_CONFIG_URL              API base URL (required)
+//	LLM_CONFIG_TOKEN            bearer token, or LLM_CONFIG_TOKEN_FILE to read it from a file
+//	LLM_CONFIG_ENV              environment to read (default production)
+//	LLM_CONFIG_NAMESPACES       namespaces injected as variables, comma-separated
+//	LLM_CONFIG_PREFIX           prefix for every variable name
+//	LLM_CONFIG_FILES            NAMESPACE=PATH pairs written as JSON, comma-separated
+//	LLM_CONFIG_REVEAL_SECRETS   include secrets the API masks (default false: left out)
+//	LLM_CONFIG_RETRIES          attempts per request while the server is unavailable (default 5)
+//	LLM_CONFIG_RETRY_DELAY      first delay between attempts, doubling up to 30s (default 1s)
+//	LLM_CONFIG_FAIL_POLICY      closed (default) exits 1 when the server stays unavailable;
+//	                            open starts the command without configs
+//
+// Keys become upper-case names as with `llmconfig exec` ("max-tokens" ->
+// MAX_TOKENS); strings are passed as-is and other values as JSON, and
+// config variables replace inherited ones. Only an unavailable server is
+// subject to the fail policy: any other error, such as a rejected token or
+// a certificate that does not verify, always fails, since retrying or
+// starting anyway would hide a misconfiguration.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// encryptedPlaceholder is what the API returns in place of secret values
+const encryptedPlaceholder = "<encrypted>"
+
+// maxRetryDelay caps the doubling delay between attempts
+const maxRetryDelay = 30 * time.Second
+
+type settings struct {
+	baseURL       string
+	token         string
+	env           string
+	namespaces    []string
+	prefix        string
+	files         map[string]string
+	revealSecrets bool
+	retries       int
+	retryDelay    time.Duration
+	failOpen      bool
+}
+
+// unavailableError marks a failure the fail policy applies to: the server
+// could not be reached or answered 429 or 5xx
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string { return e.err.Error() }
+func (e *unavailableError) Unwrap() error { return e.err }
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("llm-config entrypoint: ")
+
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		log.Fatal("no command to run; pass it after the entrypoint, e.g. ENTRYPOINT [\"/llm-config-entrypoint\", \"--\"] with a CMD")
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s, err := loadSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
+	env := os.Environ()
+	vars, err := s.resolve(context.Background())
+	var unavailable *unavailableError
+	switch {
+	case err == nil:
+		env = mergeEnv(env, vars)
+	case errors.As(err, &unavailable) && s.failOpen:
+		log.Printf("%v; starting %s without configs (LLM_CONFIG_FAIL_POLICY=open)", err, args[0])
+	default:
+		log.Fatal(err)
+	}
+
+	if err := syscall.Exec(path, args, env); err != nil {
+		log.Fatalf("exec %s: %v", path, err)
+	}
+}
+
+func loadSettings() (*settings, error) {
+	s := &settings{
+		baseURL:    strings.TrimSuffix(os.Getenv("LLM_CONFIG_URL"), "/"),
+		token:      os.Getenv("LLM_CONFIG_TOKEN"),
+		env:        os.Getenv("LLM_CONFIG_ENV"),
+		namespaces: splitList(os.Getenv("LLM_CONFIG_NAMESPACES")),
+		prefix:     os.Getenv("LLM_CONFIG_PREFIX"),
+		files:      map[string]string{},
+		retries:    5,
+		retryDelay: time.Second,
+	}
+	if s.baseURL == "" {
+		return nil, errors.New("LLM_CONFIG_URL is required")
+	}
+	if s.env == "" {
+		s.env = "production"
+	}
+	if file := os.Getenv("LLM_CONFIG_TOKEN_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("LLM_CONFIG_TOKEN_FILE: %w", err)
+		}
+		s.token = strings.TrimSpace(string(data))
+	}
+	for _, pair := range splitList(os.Getenv("LLM_CONFIG_FILES")) {
+		namespace, path, ok := strings.Cut(pair, "=")
+		if !ok || namespace == "" || path == "" {
+			return nil, fmt.Errorf("LLM_CONFIG_FILES: %q must be NAMESPACE=PATH", pair)
+		}
+		s.files[path] = namespace
+	}
+	if len(s.namespaces) == 0 && len(s.files) == 0 {
+		return nil, errors.New("nothing to resolve; set LLM_CONFIG_NAMESPACES or LLM_CONFIG_FILES")
+	}
+
+	var err error
+	if v := os.Getenv("LLM_CONFIG_REVEAL_SECRETS"); v != "" {
+		if s.revealSecrets, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("LLM_CONFIG_REVEAL_SECRETS: %w", err)
+		}
+	}
+	if v := os.Getenv("LLM_CONFIG_RETRIES"); v != "" {
+		if s.retries, err = strconv.Atoi(v); err != nil || s.retries < 1 {
+			return nil, fmt.Errorf("LLM_CONFIG_RETRIES: %q must be a positive number", v)
+		}
+	}
+	if v := os.Getenv("LLM_CONFIG_RETRY_DELAY"); v != "" {
+		if s.retryDelay, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("LLM_CONFIG_RETRY_DELAY: %w", err)
+		}
+	}
+	switch policy := os.Getenv("LLM_CONFIG_FAIL_POLICY"); policy {
+	case "", "closed":
+	case "open":
+		s.failOpen = true
+	default:
+		return nil, fmt.Errorf("LLM_CONFIG_FAIL_POLICY: %q must be open or closed", policy)
+	}
+	return s, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// resolve reads every namespace once, writes the files, and returns the
+// variables. Nothing is written unless every namespace could be read.
+func (s *settings) resolve(ctx context.Context) ([]string, error) {
+	values := map[string]map[string]interface{}{}
+	read := func(namespace string) error {
+		if _, ok := values[namespace]; ok {
+			return nil
+		}
+		nsValues, err := s.namespace(ctx, namespace)
+		values[namespace] = nsValues
+		return err
+	}
+	for _, namespace := range s.namespaces {
+		if err := read(namespace); err != nil {
+			return nil, err
+		}
+	}
+	for _, namespace := range s.files {
+		if err := read(namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	for path, namespace := range s.files {
+		data, err := json.MarshalIndent(values[namespace], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+			return nil, err
+		}
+	}
+
+	var vars []string
+	names := map[string]string{}
+	for _, namespace := range s.namespaces {
+		for key, value := range values[namespace] {
+			name := s.prefix + envName(key)
+			if other, dup := names[name]; dup {
+				return nil, fmt.Errorf("keys %s and %s both map to %s", other, namespace+"/"+key, name)
+			}
+			names[name] = namespace + "/" + key
+			vars = append(vars, name+"="+formatValue(value))
+		}
+	}
+	sort.Strings(vars)
+	return vars, nil
+}
+
+// namespace returns a namespace's key -> value map, revealing masked
+// secrets or leaving them out
+func (s *settings) namespace(ctx context.Context, namespace string) (map[string]interface{}, error) {
+	var configs []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	if err := s.get(ctx, "/configs/"+namespace, &configs); err != nil {
+		return nil, fmt.Errorf("list %s (%s): %w", namespace, s.env, err)
+	}
+	values := make(map[string]interface{}, len(configs))
+	for _, cfg := range configs {
+		if cfg.Value == encryptedPlaceholder {
+			if !s.revealSecrets {
+				continue
+			}
+			var secret struct {
+				Value interface{} `json:"value"`
+			}
+			if err := s.get(ctx, "/configs/"+namespace+"/"+url.PathEscape(cfg.Key)+"/~/secret", &secret); err != nil {
+				return nil, fmt.Errorf("reveal %s/%s (%s): %w", namespace, cfg.Key, s.env, err)
+			}
+			cfg.Value = secret.Value
+		}
+		values[cfg.Key] = cfg.Value
+	}
+	return values, nil
+}
+
+// get decodes a GET of path into v, retrying while the server is
+// unavailable
+func (s *settings) get(ctx context.Context, path string, v interface{}) error {
+	delay := s.retryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = s.getOnce(ctx, path, v)
+		var unavailable *unavailableError
+		if err == nil || !errors.As(err, &unavailable) || attempt == s.retries {
+			return err
+		}
+		log.Printf("%s: %v; retrying in %s (attempt %d of %d)", path, err, delay, attempt, s.retries)
+		time.Sleep(delay)
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+func (s *settings) getOnce(ctx context.Context, path string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path+"?env="+url.QueryEscape(s.env), nil)
+	if err != nil {
+		return err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if isTLSError(err) {
+			return err
+		}
+		return &unavailableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return &unavailableError{fmt.Errorf("server returned %s", resp.Status)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+			return fmt.Errorf("server returned %s: %s", resp.Status, body.Error)
+		}
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	dec := json.NewDecoder(resp.Body)
+	// Keep numbers as written rather than as float64
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// isTLSError reports whether err is a certificate or handshake failure: a
+// wrong CA bundle or URL, not a server that is down
+func isTLSError(err error) bool {
+	var (
+		verification *tls.CertificateVerificationError
+		authority    x509.UnknownAuthorityError
+		hostname     x509.HostnameError
+		invalid      x509.CertificateInvalidError
+		record       tls.RecordHeaderError
+	)
+	return errors.As(err, &verification) || errors.As(err, &authority) ||
+		errors.As(err, &hostname) || errors.As(err, &invalid) || errors.As(err, &record)
+}
+
+// envName turns a key into a variable name: upper case, with anything but
+// letters, digits, and underscores replaced by "_"
+func envName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// formatValue passes strings as-is and renders everything else as JSON
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// mergeEnv appends vars to env, dropping inherited entries they replace
+func mergeEnv(env, vars []string) []string {
+	replaced := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		name, _, _ := strings.Cut(v, "=")
+		replaced[name] = true
+	}
+	merged := make([]string, 0, len(env)+len(vars))
+	for _, e := range env {
+		if name, _, _ := strings.Cut(e, "="); !replaced[name] {
+			merged = append(merged, e)
+		}
+	}
+	return append(merged, vars...)
+}
+
+// writeFileAtomic replaces path through a temporary file in the same
+// directory, so the command never reads a partial file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".llm-config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o640); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// AgentFile is one file the agent keeps in sync with a namespace
+type AgentFile struct {
+	Namespace string
+	Path      string
+	// Format is json, yaml, toml, or dotenv; ignored when Template is set
+	Format ExportFormat
+	// Template renders the namespace's key -> value map instead, e.g.
+	// model={{.model}}; a key missing from the namespace is an error
+	Template *template.Template
+	// Require lists keys the namespace must have; without them no file of
+	// the namespace is written. Secrets left out because RevealSecrets is
+	// off count as missing.
+	Require []string
+}
+
+// MissingKeysError reports required keys absent from a namespace
+type MissingKeysError struct {
+	Namespace   string
+	Environment string
+	Keys        []string
+}
+
+func (e *MissingKeysError) Error() string {
+	return fmt.Sprintf("%s (%s) is missing required keys: %s", e.Namespace, e.Environment, strings.Join(e.Keys, ", "))
+}
+
+// AgentOptions configures RunAgent
+type AgentOptions struct {
+	Environment string
+	// Interval between polls of each namespace; 5s when zero
+	Interval time.Duration
+	Files    []AgentFile
+	// Mode of the written files; 0640 when zero
+	Mode os.FileMode
+	// RevealSecrets writes the plaintext of secrets with RevealSecret,
+	// decrypting client-side encrypted ones with SecretKey. Otherwise
+	// secrets the API masks are left out of the files.
+	RevealSecrets bool
+	SecretKey     SecretKey
+	// Notify is called after a change rewrites at least one file, e.g. to
+	// send the application SIGHUP
+	Notify func() error
+}
+
+// agentTemplateFuncs are available to AgentFile templates
+var agentTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ParseAgentTemplate reads a template file for AgentFile.Template
+func ParseAgentTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(agentTemplateFuncs).Option("missingkey=error").Parse(string(data))
+}
+
+// SyncAgentFiles renders every file once and reports whether any changed.
+// Files whose content is unchanged are not rewritten.
+func (c *LLMConfigClient) SyncAgentFiles(ctx context.Context, opts AgentOptions) (changed bool, err error) {
+	for _, namespace := range agentNamespaces(opts.Files) {
+		nsChanged, err := c.syncAgentNamespace(ctx, namespace, opts)
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || nsChanged
+	}
+	return changed, nil
+}
+
+// RunAgent writes opts.Files, then watches their namespaces and rewrites
+// the files of a namespace when it changes, until ctx is done. The first
+// sync must succeed; later failures, including a required key going
+// missing, are logged and the previous files kept until the next change.
+// Each file is replaced atomically by renaming a temporary file over it,
+// so readers never see a partial write.
+func (c *LLMConfigClient) RunAgent(ctx context.Context, opts AgentOptions) error {
+	changed, err := c.SyncAgentFiles(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if changed {
+		c.notifyAgent(opts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	namespaces := agentNamespaces(opts.Files)
+	updates := make(chan string, len(namespaces))
+	for _, namespace := range namespaces {
+		w, err := c.Watch(ctx, namespace, WatchOptions{Environment: opts.Environment, Interval: opts.Interval})
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		go func(namespace string) {
+			for range w.Events() {
+				// One pending update per namespace is enough: the sync
+				// re-reads the whole namespace
+				select {
+				case updates <- namespace:
+				default:
+				}
+			}
+		}(namespace)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case namespace := <-updates:
+			changed, err := c.syncAgentNamespace(ctx, namespace, opts)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("llm-config agent: %v; keeping the previous files", err)
+				}
+				continue
+			}
+			if changed {
+				c.notifyAgent(opts)
+			}
+		}
+	}
+}
+
+func (c *LLMConfigClient) notifyAgent(opts AgentOptions) {
+	if opts.Notify == nil {
+		return
+	}
+	if err := opts.Notify(); err != nil {
+		log.Printf("llm-config agent: notify: %v", err)
+	}
+}
+
+// syncAgentNamespace renders the files of one namespace
+func (c *LLMConfigClient) syncAgentNamespace(ctx context.Context, namespace string, opts AgentOptions) (bool, error) {
+	values, err := c.agentValues(ctx, namespace, opts)
+	if err != nil {
+		return false, err
+	}
+
+	var missing []string
+	for _, file := range opts.Files {
+		if file.Namespace != namespace {
+			continue
+		}
+		for _, key := range file.Require {
+			if _, ok := values[key]; !ok && !slices.Contains(missing, key) {
+				missing = append(missing, key)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return false, &MissingKeysError{Namespace: namespace, Environment: opts.Environment, Keys: missing}
+	}
+
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0o640
+	}
+	changed := false
+	for _, file := range opts.Files {
+		if file.Namespace != namespace {
+			continue
+		}
+		var data []byte
+		if file.Template != nil {
+			var buf bytes.Buffer
+			if err := file.Template.Execute(&buf, values); err != nil {
+				return changed, fmt.Errorf("render %s: %w", file.Path, err)
+			}
+			data = buf.Bytes()
+		} else if data, err = encodeExport(values, file.Format); err != nil {
+			return changed, fmt.Errorf("render %s: %w", file.Path, err)
+		}
+
+		if current, err := os.ReadFile(file.Path); err == nil && bytes.Equal(current, data) {
+			continue
+		}
+		if err := writeFileAtomic(file.Path, data, mode); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// agentValues reads namespace as a key -> value map, revealing or leaving
+// out masked secrets
+func (c *LLMConfigClient) agentValues(ctx context.Context, namespace string, opts AgentOptions) (map[string]interface{}, error) {
+	configs, err := c.ListConfigsContext(ctx, namespace, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("list %s (%s): %w", namespace, opts.Environment, err)
+	}
+	values := make(map[string]interface{}, len(configs))
+	for _, cfg := range configs {
+		if cfg.Value != encryptedPlaceholder {
+			values[cfg.Key] = normalizeExportValue(cfg.Value)
+			continue
+		}
+		if !opts.RevealSecrets {
+			continue
+		}
+		secret, err := c.RevealSecret(ctx, namespace, cfg.Key, opts.Environment, opts.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("reveal %s/%s: %w", namespace, cfg.Key, err)
+		}
+		values[cfg.Key] = normalizeExportValue(secret.Value)
+	}
+	return values, nil
+}
+
+// agentNamespaces lists the distinct namespaces of files in order
+func agentNamespaces(files []AgentFile) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, file := range files {
+		if !seen[file.Namespace] {
+			seen[file.Namespace] = true
+			namespaces = append(namespaces, file.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// signalProcesses sends sig to every process whose command name is name,
+// read from /proc. In a pod this needs shareProcessNamespace: true.
+func signalProcesses(name string, sig os.Signal) error {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "comm"))
+		if err != nil || strings.TrimSpace(string(comm)) != name {
+			continue
+		}
+		found = true
+		if err := signalPID(pid, sig); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("no process named %s", name)
+	}
+	return nil
+}
+
+// signalPIDFile sends sig to the process whose ID is in path
+func signalPIDFile(path string, sig os.Signal) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("%s: not a process ID", path)
+	}
+	return signalPID(pid, sig)
+}
+
+// systemctl runs `systemctl action units...`, e.g. to reload services
+// after their EnvironmentFile changed
+func systemctl(action string, units []string) error {
+	out, err := exec.Command("systemctl", append([]string{action}, units...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s %s: %w: %s", action, strings.Join(units, " "), err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func signalPID(pid int, sig os.Signal) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := proc.Signal(sig); err != nil {
+		return fmt.Errorf("signal %d: %w", pid, err)
+	}
+	return nil
+}
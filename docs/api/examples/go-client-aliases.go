@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// aliasHeader names the key a read was redirected to, as "namespace/key"
+const aliasHeader = "X-LLM-Config-Alias-Of"
+
+// KeyAlias redirects reads of an old key name to its new one while
+// consumers are migrated after a rename
+type KeyAlias struct {
+	Namespace       string `json:"namespace"`
+	Key             string `json:"key"`
+	TargetNamespace string `json:"target_namespace"`
+	TargetKey       string `json:"target_key"`
+	CreatedBy       string `json:"created_by,omitempty"`
+	CreatedAt       string `json:"created_at,omitempty"`
+
+	// Reads counts reads through the alias since it was created
+	Reads      int64     `json:"reads"`
+	LastReadAt time.Time `json:"last_read_at"`
+	// Readers are the clients that read through the alias most recently,
+	// named as in usage reports
+	Readers []ReaderStats `json:"readers,omitempty"`
+}
+
+// Unused reports whether nothing has read through the alias for d, so it
+// can be removed. An alias never read is unused once it is d old.
+func (a *KeyAlias) Unused(now time.Time, d time.Duration) bool {
+	last := a.LastReadAt
+	if last.IsZero() {
+		created, err := time.Parse(time.RFC3339, a.CreatedAt)
+		if err != nil {
+			return false
+		}
+		last = created
+	}
+	return now.Sub(last) >= d
+}
+
+type createAliasRequest struct {
+	TargetNamespace string `json:"target_namespace"`
+	TargetKey       string `json:"target_key"`
+	User            string `json:"user"`
+}
+
+// aliasWarnings remembers which aliases have been logged, so each is
+// reported once per client rather than on every read
+type aliasWarnings struct {
+	mu     sync.Mutex
+	warned map[string]bool
+}
+
+// installAliases logs the first read of each alias, naming the key to read
+// instead
+func (c *LLMConfigClient) installAliases() {
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		target := resp.Header().Get(aliasHeader)
+		if target == "" {
+			return nil
+		}
+		read := resp.Request.RawRequest.URL.Path
+		if _, key, ok := strings.Cut(read, "/configs/"); ok {
+			read = key
+		}
+		c.aliases.mu.Lock()
+		defer c.aliases.mu.Unlock()
+		if !c.aliases.warned[read] {
+			c.aliases.warned[read] = true
+			log.Printf("llm-config: %s is an alias of %s; read %s instead", read, target, target)
+		}
+		return nil
+	})
+}
+
+// CreateKeyAlias makes namespace/key an alias of targetNamespace/targetKey.
+// Reads of the alias return the target, with its name, and are counted so
+// ListKeyAliases shows when the old name is no longer used. Writes to an
+// alias are refused with ErrConflict. The alias must not name an existing
+// key or another alias.
+func (c *LLMConfigClient) CreateKeyAlias(ctx context.Context, namespace, key, targetNamespace, targetKey, user string) (*KeyAlias, error) {
+	if namespace == targetNamespace && key == targetKey {
+		return nil, fmt.Errorf("alias %s/%s cannot point at itself: %w", namespace, key, ErrValidation)
+	}
+
+	var result KeyAlias
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(createAliasRequest{TargetNamespace: targetNamespace, TargetKey: targetKey, User: user}).
+		SetResult(&result).
+		Put(fmt.Sprintf("/aliases/%s/%s", namespace, key))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListKeyAliases returns the aliases in namespace and those under it (all
+// when empty) with their read counts
+func (c *LLMConfigClient) ListKeyAliases(ctx context.Context, namespace string) ([]KeyAlias, error) {
+	var result []KeyAlias
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if namespace != "" {
+		req.SetQueryParam("namespace", namespace)
+	}
+
+	resp, err := req.Get("/aliases")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// DeleteKeyAlias removes an alias, reporting whether it existed; reads of
+// the old name fail with ErrNotFound afterwards
+func (c *LLMConfigClient) DeleteKeyAlias(ctx context.Context, namespace, key string) (bool, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(fmt.Sprintf("/aliases/%s/%s", namespace, key))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
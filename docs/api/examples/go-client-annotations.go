@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Annotation is a free-form note on a key or on one version of it, e.g.
+// "lowered temperature due to hallucination incident INC-1234"
+type Annotation struct {
+	ID          string `json:"id"`
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Environment string `json:"environment"`
+	// Version is the version annotated; 0 for notes on the key as a whole
+	Version int64 `json:"version,omitempty"`
+	// ReplyTo is the annotation this one answers, making a thread
+	ReplyTo   string    `json:"reply_to,omitempty"`
+	Body      string    `json:"body"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewAnnotation is what Annotate adds
+type NewAnnotation struct {
+	Environment string `json:"env"`
+	// Version 0 annotates the key rather than a version; a reply takes the
+	// version of the annotation it answers
+	Version int64  `json:"version,omitempty"`
+	ReplyTo string `json:"reply_to,omitempty"`
+	Body    string `json:"body"`
+}
+
+type annotateRequest struct {
+	NewAnnotation
+	User string `json:"user"`
+}
+
+// AnnotationThread is an annotation with its replies, oldest first
+type AnnotationThread struct {
+	Annotation
+	Replies []Annotation `json:"replies,omitempty"`
+}
+
+// AnnotatedVersion is a history entry with the threads on that version
+type AnnotatedVersion struct {
+	VersionEntry
+	Annotations []AnnotationThread `json:"annotations,omitempty"`
+}
+
+// AnnotatedHistory is a key's history with its annotations, for change
+// archaeology
+type AnnotatedHistory struct {
+	Versions []AnnotatedVersion `json:"versions"`
+	// KeyAnnotations are the threads on the key as a whole
+	KeyAnnotations []AnnotationThread `json:"key_annotations,omitempty"`
+}
+
+// Annotate adds a note to a key, or to one version of it. Annotations do
+// not change the value or its version.
+func (c *LLMConfigClient) Annotate(ctx context.Context, namespace, key string, a NewAnnotation, user string) (*Annotation, error) {
+	if a.Body == "" {
+		return nil, fmt.Errorf("annotate %s/%s: the annotation is empty: %w", namespace, key, ErrValidation)
+	}
+	if a.Version < 0 {
+		return nil, fmt.Errorf("annotate %s/%s: version %d is invalid: %w", namespace, key, a.Version, ErrValidation)
+	}
+
+	var result Annotation
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(annotateRequest{NewAnnotation: a, User: user}).
+		SetResult(&result).
+		Post(keyRoute(namespace, key, "annotations"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListAnnotations returns a key's annotations in env, oldest first
+func (c *LLMConfigClient) ListAnnotations(ctx context.Context, namespace, key, env string) ([]Annotation, error) {
+	var result []Annotation
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("env", env).
+		SetResult(&result).
+		Get(keyRoute(namespace, key, "annotations"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+// DeleteAnnotation removes an annotation and its replies; only its author
+// and the key's owners may
+func (c *LLMConfigClient) DeleteAnnotation(ctx context.Context, namespace, key, id string) (bool, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(keyRoute(namespace, key, "annotations/"+url.PathEscape(id)))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
+
+// GetAnnotatedHistory is GetHistoryContext with each version's annotation
+// threads attached
+func (c *LLMConfigClient) GetAnnotatedHistory(ctx context.Context, namespace, key, env string) (*AnnotatedHistory, error) {
+	history, err := c.GetHistoryContext(ctx, namespace, key, env)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := c.ListAnnotations(ctx, namespace, key, env)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64][]AnnotationThread{}
+	for _, thread := range ThreadAnnotations(annotations) {
+		byVersion[thread.Version] = append(byVersion[thread.Version], thread)
+	}
+	result := &AnnotatedHistory{KeyAnnotations: byVersion[0]}
+	for _, entry := range history {
+		result.Versions = append(result.Versions, AnnotatedVersion{VersionEntry: entry, Annotations: byVersion[entry.Version]})
+	}
+	return result, nil
+}
+
+// ThreadAnnotations groups replies under the annotation that started their
+// thread, keeping order. Replies to replies join the thread's root; replies
+// whose thread is missing are kept as threads of their own.
+func ThreadAnnotations(annotations []Annotation) []AnnotationThread {
+	byID := make(map[string]Annotation, len(annotations))
+	for _, a := range annotations {
+		byID[a.ID] = a
+	}
+	root := func(a Annotation) string {
+		seen := map[string]bool{}
+		for a.ReplyTo != "" && !seen[a.ID] {
+			seen[a.ID] = true
+			parent, ok := byID[a.ReplyTo]
+			if !ok {
+				break
+			}
+			a = parent
+		}
+		return a.ID
+	}
+
+	var threads []AnnotationThread
+	index := map[string]int{}
+	for _, a := range annotations {
+		id := root(a)
+		if id == a.ID {
+			index[a.ID] = len(threads)
+			threads = append(threads, AnnotationThread{Annotation: a})
+		}
+	}
+	for _, a := range annotations {
+		id := root(a)
+		if id == a.ID {
+			continue
+		}
+		if i, ok := index[id]; ok {
+			threads[i].Replies = append(threads[i].Replies, a)
+		} else {
+			// Only a cycle of replies has no root
+			threads = append(threads, AnnotationThread{Annotation: a})
+		}
+	}
+	return threads
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// APIVersion is a major version of the REST API, the last segment of the
+// base URL path (/api/v1)
+type APIVersion string
+
+const (
+	// APIVersionAuto uses the newest version both the client and the
+	// server speak, asking the server once on the first request
+	APIVersionAuto APIVersion = "auto"
+	APIVersion1    APIVersion = "v1"
+	// APIVersion2 differs from v1 in payloads only: paginated lists are an
+	// {"items", "total", "next_cursor"} object rather than an array with
+	// paging headers, and errors are {"error": {"code", "message"}}. The
+	// client accepts both shapes from either version.
+	APIVersion2 APIVersion = "v2"
+)
+
+// clientAPIVersions are the versions the client speaks, newest first
+var clientAPIVersions = []APIVersion{APIVersion2, APIVersion1}
+
+// apiVersionSegment matches the version at the end of a base URL path
+var apiVersionSegment = regexp.MustCompile(`/v[0-9]+/?$`)
+
+// apiVersionState holds the selected version and, in auto mode, the
+// negotiated one
+type apiVersionState struct {
+	mu         sync.Mutex
+	selected   APIVersion
+	negotiated APIVersion
+}
+
+// WithAPIVersion selects the API version to talk, replacing the version in
+// the base URL, so clients and servers can be upgraded independently: pin
+// APIVersion1 until every server speaks v2, or use APIVersionAuto to move
+// to the newest version the server supports. By default the client uses
+// the base URL's version as given. Other versions make every request fail
+// with ErrValidation.
+//
+//	client := NewLLMConfigClient("https://llm-config.example.com/api/v1", token).
+//		WithAPIVersion(APIVersionAuto)
+func (c *LLMConfigClient) WithAPIVersion(version APIVersion) *LLMConfigClient {
+	c.apiVersion.mu.Lock()
+	defer c.apiVersion.mu.Unlock()
+	c.apiVersion.selected = version
+	c.apiVersion.negotiated = ""
+	return c
+}
+
+// APIVersion reports the version requests use; in auto mode it is empty
+// until the first request has negotiated it
+func (c *LLMConfigClient) APIVersion() APIVersion {
+	c.apiVersion.mu.Lock()
+	defer c.apiVersion.mu.Unlock()
+	if c.apiVersion.selected == APIVersionAuto {
+		return c.apiVersion.negotiated
+	}
+	if c.apiVersion.selected != "" {
+		return c.apiVersion.selected
+	}
+	return APIVersion(strings.Trim(apiVersionSegment.FindString(c.baseURL), "/"))
+}
+
+// installAPIVersion points each request at the selected version's base URL
+func (c *LLMConfigClient) installAPIVersion() {
+	c.httpClient.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		base, err := c.apiBaseURL(req.Context())
+		if err != nil {
+			return err
+		}
+		if base != c.baseURL && !strings.Contains(req.URL, "://") {
+			req.URL = strings.TrimSuffix(base, "/") + req.URL
+		}
+		return nil
+	})
+}
+
+// apiBaseURL is the base URL of the selected version, negotiating it first
+// in auto mode
+func (c *LLMConfigClient) apiBaseURL(ctx context.Context) (string, error) {
+	c.apiVersion.mu.Lock()
+	defer c.apiVersion.mu.Unlock()
+
+	version := c.apiVersion.selected
+	switch version {
+	case "":
+		return c.baseURL, nil
+	case APIVersionAuto:
+		if c.apiVersion.negotiated == "" {
+			negotiated, err := c.negotiateAPIVersion(ctx)
+			if err != nil {
+				return "", err
+			}
+			c.apiVersion.negotiated = negotiated
+		}
+		version = c.apiVersion.negotiated
+	case APIVersion1, APIVersion2:
+	default:
+		return "", fmt.Errorf("API version %q is not one of %v: %w", version, clientAPIVersions, ErrValidation)
+	}
+
+	if !apiVersionSegment.MatchString(c.baseURL) {
+		return "", fmt.Errorf("base URL %q does not end in an API version to replace with %s: %w", c.baseURL, version, ErrValidation)
+	}
+	return apiVersionSegment.ReplaceAllString(c.baseURL, "/"+string(version)), nil
+}
+
+// negotiateAPIVersion asks the server which versions it speaks, at the
+// base URL with the version replaced by "versions" (/api/versions), and
+// picks the newest the client speaks too. Servers that predate the
+// endpoint speak only v1.
+func (c *LLMConfigClient) negotiateAPIVersion(ctx context.Context) (APIVersion, error) {
+	if !apiVersionSegment.MatchString(c.baseURL) {
+		return "", fmt.Errorf("base URL %q does not end in an API version to negotiate: %w", c.baseURL, ErrValidation)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiVersionSegment.ReplaceAllString(c.baseURL, "/versions"), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.GetClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("negotiate API version: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return APIVersion1, nil
+	}
+	var body struct {
+		Versions []APIVersion `json:"versions"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("negotiate API version: server returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("negotiate API version: %w", err)
+	}
+	for _, version := range clientAPIVersions {
+		for _, offered := range body.Versions {
+			if offered == version {
+				return version, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("negotiate API version: the server speaks %v and the client %v", body.Versions, clientAPIVersions)
+}
+
+// trimBasePath strips the API base path off a request path for route
+// labels, whichever version the request went to
+func trimBasePath(path, basePath string) string {
+	if rest, ok := strings.CutPrefix(path, basePath); ok {
+		return rest
+	}
+	if loc := apiVersionSegment.FindStringIndex(basePath); loc != nil {
+		if rest, ok := strings.CutPrefix(path, basePath[:loc[0]]+"/"); ok {
+			if _, rest, ok := strings.Cut(rest, "/"); ok {
+				return "/" + rest
+			}
+		}
+	}
+	return path
+}
+
+// decodeErrorBody reads an error body in the v1 or v2 shape
+func decodeErrorBody(body []byte) (code, message string, ok bool) {
+	var v1 ErrorResponse
+	if err := json.Unmarshal(body, &v1); err == nil {
+		return v1.Error, v1.Message, true
+	}
+	var v2 struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &v2); err == nil {
+		return v2.Error.Code, v2.Error.Message, true
+	}
+	return "", "", false
+}
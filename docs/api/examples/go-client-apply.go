@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeAction is what Apply will do to a single key
+type ChangeAction string
+
+const (
+	ChangeCreate    ChangeAction = "create"
+	ChangeUpdate    ChangeAction = "update"
+	ChangeDelete    ChangeAction = "delete"
+	ChangeUnchanged ChangeAction = "unchanged"
+)
+
+// Manifest declares the desired contents of one namespace/environment.
+// Secret values never live in the manifest: Secrets maps a key to the name
+// of the environment variable holding its value at apply time.
+type Manifest struct {
+	Namespace   string                 `yaml:"namespace" json:"namespace"`
+	Environment string                 `yaml:"environment" json:"environment"`
+	Prune       bool                   `yaml:"prune,omitempty" json:"prune,omitempty"`
+	Configs     map[string]interface{} `yaml:"configs" json:"configs"`
+	Secrets     map[string]string      `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+
+	// Source is the file the manifest was loaded from
+	Source string `yaml:"-" json:"-"`
+	// SecretValues supplies secret values directly, by key, for callers
+	// that resolve them elsewhere; keys missing here fall back to the
+	// environment variables named in Secrets
+	SecretValues map[string]string `yaml:"-" json:"-"`
+	// RevealSecrets compares existing secrets with RevealSecret so
+	// changed values are updated; the token needs permission to read
+	// secrets in the namespace
+	RevealSecrets bool `yaml:"-" json:"-"`
+}
+
+// ApplyChange is one planned key change
+type ApplyChange struct {
+	Action      ChangeAction `json:"action"`
+	Namespace   string       `json:"namespace"`
+	Environment string       `json:"environment"`
+	Key         string       `json:"key"`
+	Old         interface{}  `json:"old,omitempty"`
+	New         interface{}  `json:"new,omitempty"`
+	Secret      bool         `json:"secret"`
+	Source      string       `json:"source,omitempty"`
+}
+
+// MarshalJSON masks secret values so plans can be printed and stored
+func (c ApplyChange) MarshalJSON() ([]byte, error) {
+	type change ApplyChange
+	if c.Secret {
+		if c.Old != nil {
+			c.Old = encryptedPlaceholder
+		}
+		if c.New != nil {
+			c.New = encryptedPlaceholder
+		}
+	}
+	return json.Marshal(change(c))
+}
+
+// ApplyPlan is the full set of changes needed to converge the server on a
+// set of manifests
+type ApplyPlan struct {
+	Changes []ApplyChange `json:"changes"`
+}
+
+// LoadManifests reads every .yaml, .yml, and .json file below dir. YAML files
+// may hold several documents. A namespace/environment pair may only be
+// declared once.
+func LoadManifests(dir string) ([]Manifest, error) {
+	return loadManifests(dir, nil)
+}
+
+// loadManifests is LoadManifests, checking each file's content with verify
+// when it is set
+func loadManifests(dir string, verify func(path string, data []byte) error) ([]Manifest, error) {
+	var manifests []Manifest
+	seen := map[string]string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if verify != nil {
+			if err := verify(path, data); err != nil {
+				return err
+			}
+		}
+		docs, err := decodeManifests(data, ext == ".json")
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, m := range docs {
+			if m.Namespace == "" || m.Environment == "" {
+				return fmt.Errorf("%s: manifest requires namespace and environment", path)
+			}
+			id := m.Namespace + "@" + m.Environment
+			if prev, dup := seen[id]; dup {
+				return fmt.Errorf("%s: %s already declared in %s", path, id, prev)
+			}
+			seen[id] = path
+			m.Source = path
+			manifests = append(manifests, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifests, nil
+}
+
+func decodeManifests(data []byte, isJSON bool) ([]Manifest, error) {
+	if isJSON {
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		return []Manifest{m}, nil
+	}
+
+	var docs []Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var m Manifest
+		err := dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, m)
+	}
+	return docs, nil
+}
+
+// PlanApply diffs manifests against the server. The API masks existing
+// secrets, so they are only created, not updated, unless a manifest sets
+// RevealSecrets; keys missing from a manifest are deleted only when it
+// sets prune.
+func (c *LLMConfigClient) PlanApply(ctx context.Context, manifests []Manifest) (*ApplyPlan, error) {
+	plan := &ApplyPlan{}
+
+	for _, m := range manifests {
+		configs, err := c.ListConfigsContext(ctx, m.Namespace, m.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("list %s (%s): %w", m.Namespace, m.Environment, err)
+		}
+		current := make(map[string]ConfigResponse, len(configs))
+		for _, cfg := range configs {
+			current[cfg.Key] = cfg
+		}
+
+		base := ApplyChange{Namespace: m.Namespace, Environment: m.Environment, Source: m.Source}
+		declared := map[string]bool{}
+
+		for key, value := range m.Configs {
+			declared[key] = true
+			change := base
+			change.Key = key
+			change.New = canonicalValue(value)
+
+			existing, found := current[key]
+			switch {
+			case !found:
+				change.Action = ChangeCreate
+			case reflect.DeepEqual(canonicalValue(existing.Value), change.New):
+				change.Action = ChangeUnchanged
+			default:
+				change.Action = ChangeUpdate
+				change.Old = canonicalValue(existing.Value)
+			}
+			plan.Changes = append(plan.Changes, change)
+		}
+
+		for key, envVar := range m.Secrets {
+			if declared[key] {
+				return nil, fmt.Errorf("%s: key %s declared as both config and secret", m.Source, key)
+			}
+			declared[key] = true
+			change := base
+			change.Key = key
+			change.Secret = true
+			change.Action = ChangeUnchanged
+			_, found := current[key]
+			if found && !m.RevealSecrets {
+				plan.Changes = append(plan.Changes, change)
+				continue
+			}
+			value, ok := m.SecretValues[key]
+			if !ok {
+				value, ok = os.LookupEnv(envVar)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%s: secret %s requires environment variable %s", m.Source, key, envVar)
+			}
+			switch {
+			case !found:
+				change.Action = ChangeCreate
+				change.New = value
+			default:
+				existing, err := c.RevealSecret(ctx, m.Namespace, key, m.Environment, nil)
+				if err != nil {
+					return nil, fmt.Errorf("%s: reveal secret %s: %w", m.Source, key, err)
+				}
+				if existing.Value != value {
+					change.Action = ChangeUpdate
+					change.Old = existing.Value
+					change.New = value
+				}
+			}
+			plan.Changes = append(plan.Changes, change)
+		}
+
+		if m.Prune {
+			for key, existing := range current {
+				if declared[key] {
+					continue
+				}
+				change := base
+				change.Key = key
+				change.Action = ChangeDelete
+				change.Old = canonicalValue(existing.Value)
+				plan.Changes = append(plan.Changes, change)
+			}
+		}
+	}
+
+	sort.Slice(plan.Changes, func(i, j int) bool {
+		a, b := plan.Changes[i], plan.Changes[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Environment != b.Environment {
+			return a.Environment < b.Environment
+		}
+		return a.Key < b.Key
+	})
+
+	return plan, nil
+}
+
+// HasChanges reports whether applying the plan would modify anything
+func (p *ApplyPlan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Action != ChangeUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteDiff prints the plan as a unified-style diff grouped by namespace.
+// Secret values are never printed.
+func (p *ApplyPlan) WriteDiff(w io.Writer) error {
+	var group string
+	for _, change := range p.Changes {
+		if change.Action == ChangeUnchanged {
+			continue
+		}
+		if id := change.Namespace + " (" + change.Environment + ")"; id != group {
+			group = id
+			if _, err := fmt.Fprintf(w, "@@ %s @@\n", group); err != nil {
+				return err
+			}
+		}
+
+		oldValue, newValue := diffValue(change.Old), diffValue(change.New)
+		if change.Secret {
+			oldValue, newValue = "(secret)", "(secret)"
+		}
+
+		var err error
+		switch change.Action {
+		case ChangeCreate:
+			_, err = fmt.Fprintf(w, "+ %s = %s\n", change.Key, newValue)
+		case ChangeUpdate:
+			_, err = fmt.Fprintf(w, "- %s = %s\n+ %s = %s\n", change.Key, oldValue, change.Key, newValue)
+		case ChangeDelete:
+			_, err = fmt.Fprintf(w, "- %s = %s\n", change.Key, oldValue)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply executes every change in the plan
+func (c *LLMConfigClient) Apply(ctx context.Context, plan *ApplyPlan, user string) error {
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case ChangeCreate, ChangeUpdate:
+			_, err = c.SetConfigContext(ctx, change.Namespace, change.Key, change.New, change.Environment, user, change.Secret)
+		case ChangeDelete:
+			_, err = c.DeleteConfigContext(ctx, change.Namespace, change.Key, change.Environment)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s/%s (%s): %w", change.Action, change.Namespace, change.Key, change.Environment, err)
+		}
+	}
+	return nil
+}
+
+// canonicalValue normalizes a value decoded from YAML, JSON, or the API so
+// equal configs compare equal regardless of numeric representation
+func canonicalValue(v interface{}) interface{} {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(encoded, &out); err != nil {
+		return v
+	}
+	return normalizeExportValue(out)
+}
+
+func diffValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
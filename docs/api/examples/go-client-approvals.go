@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ChangeStatus is where a proposed change is in review
+type ChangeStatus string
+
+const (
+	// ChangePending changes await approval
+	ChangePending ChangeStatus = "pending"
+	// ChangeApproved changes can be applied with ApplyChange
+	ChangeApproved ChangeStatus = "approved"
+	// ChangeRejected changes are closed and cannot be applied
+	ChangeRejected ChangeStatus = "rejected"
+	// ChangeApplied changes have been written; AppliedVersion is the result
+	ChangeApplied ChangeStatus = "applied"
+)
+
+// ChangeRequest is a write to put up for review
+type ChangeRequest struct {
+	Namespace   string      `json:"namespace"`
+	Key         string      `json:"key"`
+	Environment string      `json:"environment"`
+	Value       interface{} `json:"value,omitempty"`
+	Secret      bool        `json:"secret,omitempty"`
+	// Delete proposes removing the key instead of setting Value
+	Delete bool `json:"delete,omitempty"`
+	// Description tells reviewers why
+	Description string `json:"description,omitempty"`
+}
+
+// ChangeReview is one reviewer's decision
+type ChangeReview struct {
+	Reviewer string `json:"reviewer"`
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment,omitempty"`
+	At       string `json:"at"`
+}
+
+// ChangeProposal is a change under review. Secret values are returned as
+// "<encrypted>".
+type ChangeProposal struct {
+	ID string `json:"id"`
+	ChangeRequest
+	// BaseVersion is the key's version when the change was proposed, 0 if
+	// it did not exist. Applying fails with ErrConflict once the key has
+	// moved on, so reviewers never approve a diff against a stale value.
+	BaseVersion    int64          `json:"base_version"`
+	Status         ChangeStatus   `json:"status"`
+	ProposedBy     string         `json:"proposed_by"`
+	ProposedAt     string         `json:"proposed_at"`
+	Reviews        []ChangeReview `json:"reviews"`
+	AppliedVersion int64          `json:"applied_version,omitempty"`
+}
+
+// ListChangesOptions filters ListChanges; empty fields match all
+type ListChangesOptions struct {
+	Namespace   string
+	Environment string
+}
+
+type proposeChangeRequest struct {
+	ChangeRequest
+	BaseVersion int64  `json:"base_version"`
+	User        string `json:"user"`
+}
+
+type reviewChangeRequest struct {
+	Reviewer string `json:"reviewer"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type applyChangeRequest struct {
+	User string `json:"user"`
+}
+
+// ProposeChange submits a write for review instead of making it. The value
+// is checked against the client's schemas, constraints, and validators
+// first, so reviewers only see changes that could be applied. Environments
+// the server guards with approvals refuse direct writes with an error
+// matching ErrUnauthorized.
+func (c *LLMConfigClient) ProposeChange(ctx context.Context, change ChangeRequest, user string) (*ChangeProposal, error) {
+	if !change.Delete {
+		if err := c.validateWrite(ctx, change.Namespace, change.Key, SetConfigRequest{
+			Value:  change.Value,
+			Env:    change.Environment,
+			User:   user,
+			Secret: change.Secret,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	var base int64
+	current, err := c.GetConfigContext(ctx, change.Namespace, change.Key, change.Environment, false)
+	switch {
+	case err == nil:
+		base = current.Version
+	case errors.Is(err, ErrNotFound):
+		if change.Delete {
+			return nil, fmt.Errorf("propose deleting %s/%s (%s): %w", change.Namespace, change.Key, change.Environment, err)
+		}
+	default:
+		return nil, err
+	}
+
+	var result ChangeProposal
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(proposeChangeRequest{ChangeRequest: change, BaseVersion: base, User: user}).
+		SetResult(&result).
+		Post("/changes")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetChange returns a proposal in any status
+func (c *LLMConfigClient) GetChange(ctx context.Context, id string) (*ChangeProposal, error) {
+	var result ChangeProposal
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/changes/%s", url.PathEscape(id)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListPendingChanges returns the proposals awaiting review, oldest first
+func (c *LLMConfigClient) ListPendingChanges(ctx context.Context, opts ListChangesOptions) ([]ChangeProposal, error) {
+	return c.ListChanges(ctx, ChangePending, opts)
+}
+
+// ListChanges returns the proposals in status, oldest first
+func (c *LLMConfigClient) ListChanges(ctx context.Context, status ChangeStatus, opts ListChangesOptions) ([]ChangeProposal, error) {
+	var result []ChangeProposal
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("status", string(status)).
+		SetResult(&result)
+	if opts.Namespace != "" {
+		req.SetQueryParam("namespace", opts.Namespace)
+	}
+	if opts.Environment != "" {
+		req.SetQueryParam("env", opts.Environment)
+	}
+
+	resp, err := req.Get("/changes")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// Approve records reviewer's approval. A proposer cannot approve their own
+// change; the server enforces this too, and may require more than one
+// approval before the status becomes ChangeApproved.
+func (c *LLMConfigClient) Approve(ctx context.Context, id, reviewer, comment string) (*ChangeProposal, error) {
+	proposal, err := c.GetChange(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.ProposedBy == reviewer {
+		return nil, fmt.Errorf("approve change %s: %s proposed it; another reviewer must approve: %w", id, reviewer, ErrUnauthorized)
+	}
+	return c.reviewChange(ctx, id, "approve", reviewer, comment)
+}
+
+// Reject closes a proposal without applying it
+func (c *LLMConfigClient) Reject(ctx context.Context, id, reviewer, reason string) (*ChangeProposal, error) {
+	return c.reviewChange(ctx, id, "reject", reviewer, reason)
+}
+
+func (c *LLMConfigClient) reviewChange(ctx context.Context, id, decision, reviewer, comment string) (*ChangeProposal, error) {
+	var result ChangeProposal
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(reviewChangeRequest{Reviewer: reviewer, Comment: comment}).
+		SetResult(&result).
+		Post(fmt.Sprintf("/changes/%s/%s", url.PathEscape(id), decision))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ApplyChange writes an approved change. It fails with ErrConflict if the
+// key changed since the proposal (propose again against the new value),
+// and with ErrValidation if the change is not approved yet.
+func (c *LLMConfigClient) ApplyChange(ctx context.Context, id, user string) (*ChangeProposal, error) {
+	proposal, err := c.GetChange(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != ChangeApproved {
+		return nil, fmt.Errorf("apply change %s: it is %s, not approved: %w", id, proposal.Status, ErrValidation)
+	}
+
+	var result ChangeProposal
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(applyChangeRequest{User: user}).
+		SetResult(&result).
+		Post(fmt.Sprintf("/changes/%s/apply", url.PathEscape(id)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
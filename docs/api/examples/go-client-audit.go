@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// AuditEvent is one entry of the server's audit log
+type AuditEvent struct {
+	ID string `json:"id"`
+	// Cursor resumes a stream just after this event
+	Cursor string    `json:"cursor"`
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	// Action is what was done, e.g. "config.set", "config.delete",
+	// "secret.reveal", or "namespace.create"
+	Action      string `json:"action"`
+	Namespace   string `json:"namespace,omitempty"`
+	Key         string `json:"key,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Version     int64  `json:"version,omitempty"`
+	// Outcome is "success", or "denied" for requests the server refused
+	Outcome   string                 `json:"outcome"`
+	RequestID string                 `json:"request_id,omitempty"`
+	SourceIP  string                 `json:"source_ip,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// AuditCheckpoint remembers how far a consumer has read, so a restarted
+// stream resumes instead of starting over
+type AuditCheckpoint interface {
+	// Load returns the saved cursor, or "" to start from Since
+	Load(ctx context.Context) (string, error)
+	Save(ctx context.Context, cursor string) error
+}
+
+// FileCheckpoint keeps the cursor in a file, replaced atomically on save
+type FileCheckpoint string
+
+// Load implements AuditCheckpoint; a missing file means no checkpoint
+func (f FileCheckpoint) Load(context.Context) (string, error) {
+	data, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+// Save implements AuditCheckpoint
+func (f FileCheckpoint) Save(_ context.Context, cursor string) error {
+	return writeFileAtomic(string(f), []byte(cursor+"\n"), 0o600)
+}
+
+// AuditStreamOptions configures StreamAuditEvents
+type AuditStreamOptions struct {
+	// Checkpoint resumes from and records the cursor; optional
+	Checkpoint AuditCheckpoint
+	// Since is where to start without a checkpoint; the oldest retained
+	// event when zero
+	Since time.Time
+	// Namespace limits events to this namespace and those under it
+	Namespace string
+	// Actions limits events to these actions; all when empty
+	Actions []string
+	// Follow keeps the stream open for new events, reconnecting after
+	// failures, until ctx is done. Otherwise it returns once caught up.
+	Follow bool
+	// CheckpointInterval is how often the cursor is saved while events
+	// flow; 5s when zero. It is always saved when the stream returns.
+	CheckpointInterval time.Duration
+}
+
+// StreamAuditEvents reads the audit log as NDJSON and calls handle for each
+// event in order. Delivery is at least once: after a crash, the events
+// since the last saved checkpoint are delivered again, so consumers should
+// drop duplicates by ID. An error from handle stops the stream without
+// checkpointing that event.
+//
+// To ship the log to a SIEM, write each event to a collector's input:
+//
+//	err := client.StreamAuditEvents(ctx, AuditStreamOptions{
+//		Checkpoint: FileCheckpoint("/var/lib/llm-config/audit.cursor"),
+//		Follow:     true,
+//	}, func(ev AuditEvent) error { return json.NewEncoder(os.Stdout).Encode(ev) })
+func (c *LLMConfigClient) StreamAuditEvents(ctx context.Context, opts AuditStreamOptions, handle func(AuditEvent) error) error {
+	if opts.CheckpointInterval <= 0 {
+		opts.CheckpointInterval = 5 * time.Second
+	}
+	var cursor string
+	if opts.Checkpoint != nil {
+		var err error
+		if cursor, err = opts.Checkpoint.Load(ctx); err != nil {
+			return fmt.Errorf("audit: load checkpoint: %w", err)
+		}
+	}
+
+	saved, lastSave := cursor, c.clock.Now()
+	save := func(ctx context.Context) error {
+		if opts.Checkpoint == nil || cursor == saved {
+			return nil
+		}
+		if err := opts.Checkpoint.Save(ctx, cursor); err != nil {
+			return fmt.Errorf("audit: save checkpoint: %w", err)
+		}
+		saved, lastSave = cursor, c.clock.Now()
+		return nil
+	}
+	defer func() {
+		// Save on the way out even when ctx is what ended the stream
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := save(saveCtx); err != nil {
+			log.Printf("llm-config audit: %v", err)
+		}
+	}()
+
+	backoff := time.Second
+	for {
+		delivered := false
+		err := c.readAuditStream(ctx, opts, cursor, func(ev AuditEvent) error {
+			if err := handle(ev); err != nil {
+				return &auditHandlerError{err}
+			}
+			cursor, delivered = ev.Cursor, true
+			if c.clock.Now().Sub(lastSave) >= opts.CheckpointInterval {
+				return save(ctx)
+			}
+			return nil
+		})
+		var handlerErr *auditHandlerError
+		var clientErr *ConfigClientError
+		switch {
+		case errors.As(err, &handlerErr):
+			return handlerErr.err
+		case ctx.Err() != nil:
+			return nil
+		case !opts.Follow:
+			return err
+		case errors.As(err, &clientErr) && clientErr.StatusCode < 500 && clientErr.StatusCode != http.StatusTooManyRequests:
+			// A rejected token or bad filter will not fix itself
+			return err
+		case err == nil && delivered:
+			// The server closed a followed stream; resume at once
+			continue
+		case err == nil:
+			err = errors.New("server closed the stream")
+		}
+
+		if delivered {
+			backoff = time.Second
+		}
+		log.Printf("llm-config audit: %v; reconnecting in %s", err, backoff)
+		if err := c.clock.Sleep(ctx, backoff); err != nil {
+			return nil
+		}
+		backoff = min(2*backoff, retryMaxWaitTime)
+	}
+}
+
+// auditHandlerError carries an error from the caller's handler out of the
+// stream, so it is returned rather than retried
+type auditHandlerError struct {
+	err error
+}
+
+func (e *auditHandlerError) Error() string { return e.err.Error() }
+
+// readAuditStream makes one request and calls each for every event until
+// the server ends the response
+func (c *LLMConfigClient) readAuditStream(ctx context.Context, opts AuditStreamOptions, cursor string, each func(AuditEvent) error) error {
+	query := url.Values{}
+	switch {
+	case cursor != "":
+		query.Set("after", cursor)
+	case !opts.Since.IsZero():
+		query.Set("since", opts.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if opts.Namespace != "" {
+		query.Set("namespace", opts.Namespace)
+	}
+	for _, action := range opts.Actions {
+		query.Add("action", action)
+	}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+
+	resp, err := c.openStream(ctx, "/audit/events?"+query.Encode(), mediaTypeNDJSON)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 10<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			// Heartbeat keeping an idle stream open
+			continue
+		}
+		var ev AuditEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return fmt.Errorf("audit: decode event after cursor %q: %w", cursor, err)
+		}
+		if err := each(ev); err != nil {
+			return err
+		}
+		cursor = ev.Cursor
+	}
+	return scanner.Err()
+}
+
+// openStream GETs a long-lived response. It goes around resty, whose
+// client timeout would cut the stream; see newRawRequest.
+func (c *LLMConfigClient) openStream(ctx context.Context, path, accept string) (*http.Response, error) {
+	req, err := c.newRawRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	return c.doRaw(req)
+}
+
+// newRawRequest builds a request to send with doRaw rather than resty, for
+// streamed bodies. It carries the same credentials, organization, and
+// request ID headers resty would add.
+func (c *LLMConfigClient) newRawRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	base, err := c.apiBaseURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := RequestIDFromContext(ctx)
+	if !ok {
+		id = newRequestID()
+	}
+	req.Header.Set(requestIDHeader, id)
+
+	token := c.token
+	if org := c.organizationFor(ctx); org.ID != "" {
+		req.Header.Set(organizationHeader, org.ID)
+		if org.Project != "" {
+			req.Header.Set(projectHeader, org.Project)
+		}
+		if org.Token != "" {
+			token = org.Token
+		}
+	} else if c.organizations.required.Load() {
+		return nil, fmt.Errorf("%s %s: no organization; use WithOrganization or SetOrganization: %w", method, path, ErrValidation)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// doRaw sends a newRawRequest on resty's transport, without its timeout or
+// retries, and turns a non-2xx response into a ConfigClientError
+func (c *LLMConfigClient) doRaw(req *http.Request) (*http.Response, error) {
+	resp, err := (&http.Client{Transport: c.httpClient.GetClient().Transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		clientErr := &ConfigClientError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header, c.clock.Now()),
+			RequestID:  req.Header.Get(requestIDHeader),
+		}
+		if body, err := io.ReadAll(resp.Body); err == nil {
+			if code, message, ok := decodeErrorBody(body); ok {
+				clientErr.Code, clientErr.Message = code, message
+			}
+		}
+		return nil, clientErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// SecretsManagerAPI is the subset of *secretsmanager.Client used for migration
+type SecretsManagerAPI interface {
+	secretsmanager.ListSecretsAPIClient
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// SSMMigrationSteps walks every parameter below path (recursively, decrypted)
+// and maps the hierarchy onto namespaces: "/prod/app/llm/model" under "/prod"
+// becomes key "model" in namespace "app/llm". SecureString parameters are
+// kept as secrets, StringList values become arrays, and String values go
+// through the same type inference as dotenv imports.
+func SSMMigrationSteps(ctx context.Context, api ssm.GetParametersByPathAPIClient, path string) ([]MigrationStep, error) {
+	paginator := ssm.NewGetParametersByPathPaginator(api, &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+
+	var steps []MigrationStep
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ssm get parameters by path %s: %w", path, err)
+		}
+
+		for _, param := range page.Parameters {
+			name := aws.ToString(param.Name)
+			namespace, key, ok := splitHierarchy(name, path, "/")
+			if !ok {
+				return nil, fmt.Errorf("ssm parameter %s has no namespace below %s", name, path)
+			}
+
+			raw := aws.ToString(param.Value)
+			step := MigrationStep{
+				Source:    "ssm:" + name,
+				Namespace: namespace,
+				Key:       key,
+				Metadata: map[string]string{
+					"source":         "aws-ssm",
+					"ssm_type":       string(param.Type),
+					"source_version": strconv.FormatInt(param.Version, 10),
+				},
+			}
+
+			switch param.Type {
+			case ssmtypes.ParameterTypeSecureString:
+				step.Value = raw
+				step.Secret = true
+			case ssmtypes.ParameterTypeStringList:
+				items := strings.Split(raw, ",")
+				list := make([]interface{}, len(items))
+				for i, item := range items {
+					list[i] = item
+				}
+				step.Value = list
+			default:
+				step.Value = InferValue(raw)
+			}
+
+			steps = append(steps, step)
+		}
+	}
+
+	return steps, nil
+}
+
+// SecretsManagerMigrationSteps maps every secret whose name starts with prefix
+// onto a namespace secret, using "/" in the name as the hierarchy separator.
+// Binary secrets are carried over base64-encoded.
+func SecretsManagerMigrationSteps(ctx context.Context, api SecretsManagerAPI, prefix string) ([]MigrationStep, error) {
+	paginator := secretsmanager.NewListSecretsPaginator(api, &secretsmanager.ListSecretsInput{
+		Filters: []smtypes.Filter{{Key: smtypes.FilterNameStringTypeName, Values: []string{prefix}}},
+	})
+
+	var steps []MigrationStep
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("secretsmanager list secrets %s: %w", prefix, err)
+		}
+
+		for _, entry := range page.SecretList {
+			name := aws.ToString(entry.Name)
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			namespace, key, ok := splitHierarchy(name, prefix, "/")
+			if !ok {
+				return nil, fmt.Errorf("secret %s has no namespace below %s", name, prefix)
+			}
+
+			out, err := api.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: entry.ARN})
+			if err != nil {
+				return nil, fmt.Errorf("secretsmanager get secret value %s: %w", name, err)
+			}
+
+			step := MigrationStep{
+				Source:    "secretsmanager:" + name,
+				Namespace: namespace,
+				Key:       key,
+				Secret:    true,
+				Metadata: map[string]string{
+					"source":         "aws-secretsmanager",
+					"source_version": aws.ToString(out.VersionId),
+				},
+			}
+			if out.SecretString != nil {
+				step.Value = aws.ToString(out.SecretString)
+			} else {
+				step.Value = base64.StdEncoding.EncodeToString(out.SecretBinary)
+				step.Metadata["encoding"] = "base64"
+			}
+
+			steps = append(steps, step)
+		}
+	}
+
+	return steps, nil
+}
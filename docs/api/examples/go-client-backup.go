@@ -0,0 +1,249 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// knownEnvironments are the environments the API accepts
+var knownEnvironments = []string{"base", "development", "staging", "production", "edge"}
+
+// backupManifestName is the archive entry holding the BackupManifest
+const backupManifestName = "manifest.json"
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	FormatVersion int           `json:"format_version"`
+	CreatedAt     string        `json:"created_at"`
+	Namespaces    []string      `json:"namespaces"`
+	Entries       []BackupEntry `json:"entries"`
+}
+
+// BackupEntry is one namespace/environment file in the archive
+type BackupEntry struct {
+	Path        string `json:"path"`
+	Namespace   string `json:"namespace"`
+	Environment string `json:"environment"`
+	Configs     int    `json:"configs"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// RestoreOptions controls Restore
+type RestoreOptions struct {
+	User string
+	// DryRun verifies the archive without writing anything
+	DryRun bool
+	// Verifier, when set, requires Signature to be its key's signature of
+	// the archive (see SignSnapshot)
+	Verifier  signature.Verifier
+	Signature string
+}
+
+// RestoreReport summarizes a restore
+type RestoreReport struct {
+	Manifest *BackupManifest
+	Restored int
+	// SkippedSecrets lists namespace/env/key entries whose values were masked
+	// when the backup was taken and must be re-entered by hand
+	SkippedSecrets []string
+}
+
+// Backup writes a gzip-compressed tar archive of every environment of the
+// given namespaces to w. The archive starts with manifest.json, which records
+// a SHA-256 checksum for each namespace/environment entry.
+func (c *LLMConfigClient) Backup(ctx context.Context, w io.Writer, namespaces ...string) (*BackupManifest, error) {
+	if len(namespaces) == 0 {
+		return nil, errors.New("backup requires at least one namespace")
+	}
+
+	manifest := &BackupManifest{
+		FormatVersion: 1,
+		CreatedAt:     c.clock.Now().UTC().Format(time.RFC3339),
+		Namespaces:    namespaces,
+	}
+	payloads := map[string][]byte{}
+
+	for _, namespace := range namespaces {
+		for _, env := range knownEnvironments {
+			configs, err := c.ListConfigsContext(ctx, namespace, env)
+			if err != nil {
+				return nil, fmt.Errorf("backup %s (%s): %w", namespace, env, err)
+			}
+			if len(configs) == 0 {
+				continue
+			}
+
+			data, err := json.MarshalIndent(configs, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			sum := sha256.Sum256(data)
+			entryPath := path.Join("configs", namespace, env+".json")
+
+			payloads[entryPath] = data
+			manifest.Entries = append(manifest.Entries, BackupEntry{
+				Path:        entryPath,
+				Namespace:   namespace,
+				Environment: env,
+				Configs:     len(configs),
+				Size:        int64(len(data)),
+				SHA256:      hex.EncodeToString(sum[:]),
+			})
+		}
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	modTime := c.clock.Now().UTC()
+
+	writeEntry := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := writeEntry(backupManifestName, manifestData); err != nil {
+		return nil, err
+	}
+	for _, entry := range manifest.Entries {
+		if err := writeEntry(entry.Path, payloads[entry.Path]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// VerifyBackup reads an archive and checks every entry against the manifest
+// checksums, returning the manifest and decoded entries
+func VerifyBackup(r io.Reader) (*BackupManifest, map[string][]ConfigResponse, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open backup: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read backup: %w", err)
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, tr); err != nil {
+			return nil, nil, fmt.Errorf("read backup entry %s: %w", hdr.Name, err)
+		}
+		files[hdr.Name] = buf.Bytes()
+	}
+
+	manifestData, ok := files[backupManifestName]
+	if !ok {
+		return nil, nil, errors.New("backup has no manifest.json")
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if manifest.FormatVersion != 1 {
+		return nil, nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+
+	entries := make(map[string][]ConfigResponse, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		data, ok := files[entry.Path]
+		if !ok {
+			return nil, nil, fmt.Errorf("backup entry %s is missing", entry.Path)
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != entry.SHA256 {
+			return nil, nil, fmt.Errorf("backup entry %s checksum mismatch: manifest %s, archive %s", entry.Path, entry.SHA256, got)
+		}
+
+		var configs []ConfigResponse
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return nil, nil, fmt.Errorf("decode backup entry %s: %w", entry.Path, err)
+		}
+		entries[entry.Path] = configs
+		delete(files, entry.Path)
+	}
+
+	delete(files, backupManifestName)
+	for name := range files {
+		return nil, nil, fmt.Errorf("backup entry %s is not listed in the manifest", name)
+	}
+
+	return &manifest, entries, nil
+}
+
+// Restore verifies an archive produced by Backup and writes its configs back.
+// Nothing is written unless every checksum matches, and, with a Verifier,
+// the archive's signature. Values are restored as new versions; secrets
+// that were masked at backup time are reported, not written.
+func (c *LLMConfigClient) Restore(ctx context.Context, r io.Reader, opts RestoreOptions) (*RestoreReport, error) {
+	if opts.Verifier != nil {
+		archive, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("read backup: %w", err)
+		}
+		if err := VerifySnapshot(opts.Verifier, archive, opts.Signature); err != nil {
+			return nil, fmt.Errorf("restore: %w", err)
+		}
+		r = bytes.NewReader(archive)
+	}
+	manifest, entries, err := VerifyBackup(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RestoreReport{Manifest: manifest}
+	for _, entry := range manifest.Entries {
+		for _, cfg := range entries[entry.Path] {
+			if cfg.Value == encryptedPlaceholder {
+				report.SkippedSecrets = append(report.SkippedSecrets,
+					fmt.Sprintf("%s/%s (%s)", entry.Namespace, cfg.Key, entry.Environment))
+				continue
+			}
+			if opts.DryRun {
+				report.Restored++
+				continue
+			}
+			if _, err := c.SetConfigContext(ctx, entry.Namespace, cfg.Key, cfg.Value, entry.Environment, opts.User, false); err != nil {
+				return report, fmt.Errorf("restore %s/%s (%s): %w", entry.Namespace, cfg.Key, entry.Environment, err)
+			}
+			report.Restored++
+		}
+	}
+
+	return report, nil
+}
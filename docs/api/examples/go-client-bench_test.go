@@ -0,0 +1,128 @@
+package main
+
+// Benchmarks for the read path, which sits on callers' inference critical
+// path, and the allocation budgets CI enforces for it. Requests are answered
+// in memory by stubTransport, so the numbers measure the client rather than
+// the network:
+//
+//	go test -run '^$' -bench . -benchmem .
+//
+// The client keeps no local cache yet, so GetConfig is the full uncached
+// read; a cache should add its own hit-path entry here.
+//
+// TestAllocationBudgets fails when a hot path allocates more than its budget.
+// Raise a budget only together with the change that needs it.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// allocBudgets caps allocations per operation for each hot path
+var allocBudgets = map[string]float64{
+	"GetConfig":     100,
+	"ListConfigs50": 900,
+	"WatchDispatch": 300,
+}
+
+// benchConfigs returns a namespace snapshot of n keys at version
+func benchConfigs(n int, version int64) []ConfigResponse {
+	configs := make([]ConfigResponse, n)
+	for i := range configs {
+		configs[i] = ConfigResponse{
+			ID:          fmt.Sprintf("00000000-0000-0000-0000-%012d", i),
+			Namespace:   "llm",
+			Key:         fmt.Sprintf("key_%03d", i),
+			Value:       map[string]interface{}{"model": "gpt-4", "temperature": 0.7, "max_tokens": 2000},
+			Environment: "production",
+			Version:     version,
+			Metadata: ConfigMetadata{
+				CreatedAt: "2024-01-15T10:30:00Z",
+				CreatedBy: "admin",
+				UpdatedAt: "2024-01-20T14:45:00Z",
+				UpdatedBy: "devops-team",
+				Tags:      []string{"llm"},
+			},
+		}
+	}
+	return configs
+}
+
+func mustJSON(tb testing.TB, v interface{}) []byte {
+	tb.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+// hotPaths are the operations under budget, keyed like allocBudgets
+func hotPaths(tb testing.TB) map[string]func() {
+	ctx := context.Background()
+
+	single := newStubClient(http.StatusOK, mustJSON(tb, benchConfigs(1, 1)[0]))
+	bulk := newStubClient(http.StatusOK, mustJSON(tb, benchConfigs(50, 1)))
+
+	// Every fifth key changes between snapshots
+	before := indexConfigs(benchConfigs(200, 1))
+	changed := benchConfigs(200, 1)
+	for i := 0; i < len(changed); i += 5 {
+		changed[i].Version = 2
+	}
+	after := indexConfigs(changed)
+	events := make(chan ConfigEvent, len(changed))
+
+	return map[string]func(){
+		"GetConfig": func() {
+			if _, err := single.GetConfigContext(ctx, "llm", "key_000", "production", true); err != nil {
+				tb.Fatal(err)
+			}
+		},
+		"ListConfigs50": func() {
+			if _, err := bulk.ListConfigsContext(ctx, "llm", "production"); err != nil {
+				tb.Fatal(err)
+			}
+		},
+		"WatchDispatch": func() {
+			for _, event := range diffSnapshots("llm", "production", before, after) {
+				events <- event
+			}
+			for len(events) > 0 {
+				<-events
+			}
+		},
+	}
+}
+
+func BenchmarkGetConfig(b *testing.B)     { runHotPath(b, "GetConfig") }
+func BenchmarkListConfigs50(b *testing.B) { runHotPath(b, "ListConfigs50") }
+func BenchmarkWatchDispatch(b *testing.B) { runHotPath(b, "WatchDispatch") }
+
+func runHotPath(b *testing.B, name string) {
+	op := hotPaths(b)[name]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		op()
+	}
+}
+
+func TestAllocationBudgets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation budgets are checked in full runs")
+	}
+	for name, op := range hotPaths(t) {
+		budget, ok := allocBudgets[name]
+		if !ok {
+			t.Errorf("%s has no allocation budget", name)
+			continue
+		}
+		if got := testing.AllocsPerRun(100, op); got > budget {
+			t.Errorf("%s: %.0f allocs/op, budget %.0f", name, got, budget)
+		}
+	}
+}
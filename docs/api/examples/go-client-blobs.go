@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checksumHeader carries a blob's digest as "sha256=<hex>"; uploads send it
+// as a trailer once the body has been read
+const checksumHeader = "X-LLM-Config-Checksum"
+
+// ErrChecksumMismatch means a blob's content did not match its checksum in
+// transit; nothing was stored (uploads) or kept (DownloadBlobFile)
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// BlobInfo describes a stored binary value
+type BlobInfo struct {
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Environment string `json:"environment"`
+	Version     int64  `json:"version"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// SHA256 is the hex digest of the content
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// BlobUploadOptions describes an upload
+type BlobUploadOptions struct {
+	// ContentType defaults to application/octet-stream
+	ContentType string
+	User        string
+}
+
+// UploadBlob streams r as a binary value, e.g. a tokenizer file or prompt
+// bundle, without loading it into memory or base64-encoding it into JSON.
+// The SHA-256 of what was sent is checked against what the server stored.
+// Uploads are not retried, since r cannot be replayed.
+func (c *LLMConfigClient) UploadBlob(ctx context.Context, namespace, key, env string, r io.Reader, opts BlobUploadOptions) (*BlobInfo, error) {
+	if opts.ContentType == "" {
+		opts.ContentType = "application/octet-stream"
+	}
+
+	query := url.Values{"env": {env}}
+	if opts.User != "" {
+		query.Set("user", opts.User)
+	}
+	body := &checksumReader{r: r, hash: sha256.New()}
+	req, err := c.newRawRequest(ctx, http.MethodPut, fmt.Sprintf("/blobs/%s/%s?%s", namespace, key, query.Encode()), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", opts.ContentType)
+	// Chunked, so the checksum can follow the body as a trailer
+	req.ContentLength = -1
+	req.Trailer = http.Header{checksumHeader: nil}
+	body.trailer = req.Trailer
+
+	resp, err := c.doRaw(req)
+	if err != nil {
+		return nil, fmt.Errorf("upload %s/%s (%s): %w", namespace, key, env, err)
+	}
+	defer resp.Body.Close()
+
+	var info BlobInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("upload %s/%s (%s): decode response: %w", namespace, key, env, err)
+	}
+	if sum := hex.EncodeToString(body.hash.Sum(nil)); !strings.EqualFold(info.SHA256, sum) {
+		return nil, fmt.Errorf("upload %s/%s (%s): sent sha256 %s, server stored %s: %w", namespace, key, env, sum, info.SHA256, ErrChecksumMismatch)
+	}
+	return &info, nil
+}
+
+// DownloadBlob streams a binary value into w and returns its description;
+// version 0 is the current one. The content is checked against the stored
+// checksum once fully read, after it has been written to w, so write to a
+// temporary file (or use DownloadBlobFile) when a mismatch must not be used.
+func (c *LLMConfigClient) DownloadBlob(ctx context.Context, namespace, key, env string, version int64, w io.Writer) (*BlobInfo, error) {
+	query := url.Values{"env": {env}}
+	if version > 0 {
+		query.Set("version", strconv.FormatInt(version, 10))
+	}
+	req, err := c.newRawRequest(ctx, http.MethodGet, fmt.Sprintf("/blobs/%s/%s?%s", namespace, key, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doRaw(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s/%s (%s): %w", namespace, key, env, err)
+	}
+	defer resp.Body.Close()
+
+	info := &BlobInfo{
+		Namespace:   namespace,
+		Key:         key,
+		Environment: env,
+		ContentType: resp.Header.Get("Content-Type"),
+		SHA256:      strings.TrimPrefix(resp.Header.Get(checksumHeader), "sha256="),
+	}
+	info.Version, _ = strconv.ParseInt(resp.Header.Get("X-LLM-Config-Version"), 10, 64)
+
+	digest := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, digest), resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("download %s/%s (%s): %w", namespace, key, env, err)
+	}
+	info.Size = n
+	if sum := hex.EncodeToString(digest.Sum(nil)); !strings.EqualFold(info.SHA256, sum) {
+		return nil, fmt.Errorf("download %s/%s (%s): received sha256 %s, expected %q: %w", namespace, key, env, sum, info.SHA256, ErrChecksumMismatch)
+	}
+	return info, nil
+}
+
+// DownloadBlobFile downloads a binary value to path, replacing it only once
+// the content has been verified
+func (c *LLMConfigClient) DownloadBlobFile(ctx context.Context, namespace, key, env string, version int64, path string, mode os.FileMode) (*BlobInfo, error) {
+	var info *BlobInfo
+	err := writeFileAtomicFrom(path, mode, func(w io.Writer) (err error) {
+		info, err = c.DownloadBlob(ctx, namespace, key, env, version, w)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// writeFileAtomicFrom is writeFileAtomic for content written by write; path
+// is left alone if write fails
+func writeFileAtomicFrom(path string, mode os.FileMode, write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// blobRoute is the path of route about the blob namespace/key, after a "~"
+// segment as in keyRoute, so it cannot be read as a key ending in route
+func blobRoute(namespace, key, route string) string {
+	return fmt.Sprintf("/blobs/%s/%s/%s/%s", namespace, key, routeSeparator, route)
+}
+
+// GetBlobInfo describes a binary value without downloading it
+func (c *LLMConfigClient) GetBlobInfo(ctx context.Context, namespace, key, env string) (*BlobInfo, error) {
+	var result BlobInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("env", env).
+		SetResult(&result).
+		Get(blobRoute(namespace, key, "info"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// checksumReader hashes what is read through it and, at EOF, sets the
+// checksum trailer of the request it is the body of
+type checksumReader struct {
+	r       io.Reader
+	hash    hash.Hash
+	trailer http.Header
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && r.trailer != nil {
+		r.trailer.Set(checksumHeader, "sha256="+hex.EncodeToString(r.hash.Sum(nil)))
+	}
+	return n, err
+}
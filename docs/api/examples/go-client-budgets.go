@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// defaultBudgetThresholds are the warning points used when a budget sets
+// none: 80% and 95% of the limit
+var defaultBudgetThresholds = []float64{0.8, 0.95}
+
+// Budget caps a namespace's monthly model spend. A zero limit is
+// unlimited.
+type Budget struct {
+	Namespace     string  `json:"namespace"`
+	MonthlyTokens int64   `json:"monthly_tokens,omitempty"`
+	MonthlyCost   float64 `json:"monthly_cost_usd,omitempty"`
+	// WarnAt lists fractions of the limit at which RecordSpend warns
+	WarnAt []float64 `json:"warn_at,omitempty"`
+	// Enforce makes CheckBudget refuse calls that would exceed the limit;
+	// otherwise going over is only reported through the warnings
+	Enforce bool `json:"enforce"`
+	// AnomalyFactor reports a SpendAnomaly when RecordSpend sees a
+	// window's spend pass this multiple of the trailing baseline, e.g. 3;
+	// 0 turns detection off
+	AnomalyFactor float64 `json:"anomaly_factor,omitempty"`
+	UpdatedAt     string  `json:"updated_at,omitempty"`
+	UpdatedBy     string  `json:"updated_by,omitempty"`
+}
+
+// Spend is an amount of model usage
+type Spend struct {
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost_usd"`
+}
+
+// BudgetUsage is a namespace's consumption in the current period
+type BudgetUsage struct {
+	Namespace string `json:"namespace"`
+	// Period is the budget month, e.g. "2024-01"
+	Period string `json:"period"`
+	Spend
+	Budget Budget `json:"budget"`
+}
+
+// Fraction is the share of the budget used, by whichever of tokens or cost
+// is further along; 0 when the budget is unlimited
+func (u *BudgetUsage) Fraction() float64 {
+	return u.Budget.fraction(u.Spend)
+}
+
+func (b *Budget) fraction(s Spend) float64 {
+	var f float64
+	if b.MonthlyTokens > 0 {
+		f = float64(s.Tokens) / float64(b.MonthlyTokens)
+	}
+	if b.MonthlyCost > 0 {
+		f = max(f, s.Cost/b.MonthlyCost)
+	}
+	return f
+}
+
+// ErrBudgetExceeded is matched by *BudgetExceededError
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError is returned by CheckBudget when a call would take an
+// enforced budget past its limit
+type BudgetExceededError struct {
+	Usage    BudgetUsage
+	Estimate Spend
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s budget for %s: %.0f%% used, estimated call of %d tokens ($%.4f) would exceed it",
+		e.Usage.Namespace, e.Usage.Period, e.Usage.Fraction()*100, e.Estimate.Tokens, e.Estimate.Cost)
+}
+
+// Is reports BudgetExceededError as ErrBudgetExceeded
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetWarning is emitted the first time a namespace's recorded usage
+// crosses one of its budget's thresholds in a period
+type BudgetWarning struct {
+	// Organization is the "org/project" the spend was recorded for, empty
+	// when unscoped
+	Organization string
+	Namespace    string
+	Period       string
+	Threshold    float64
+	// Fraction is the share of the budget used
+	Fraction float64
+	Usage    BudgetUsage
+}
+
+type budgetWarnings struct {
+	mu        sync.Mutex
+	listeners []func(BudgetWarning)
+	// fired records "namespace period threshold" already warned about
+	fired map[string]bool
+}
+
+// OnBudgetWarning calls fn when RecordSpend takes a namespace across a
+// warning threshold, so only calls that were made and paid for count.
+// Without a listener, warnings are logged.
+func (c *LLMConfigClient) OnBudgetWarning(fn func(BudgetWarning)) {
+	c.budgets.mu.Lock()
+	defer c.budgets.mu.Unlock()
+	c.budgets.listeners = append(c.budgets.listeners, fn)
+}
+
+// SetBudget creates or replaces the budget for budget.Namespace
+func (c *LLMConfigClient) SetBudget(ctx context.Context, budget Budget, user string) (*Budget, error) {
+	if budget.MonthlyTokens < 0 || budget.MonthlyCost < 0 {
+		return nil, &ValidationError{Namespace: budget.Namespace, Problems: []ValidationProblem{
+			{Path: "/", Message: "budget limits must not be negative"},
+		}}
+	}
+	if budget.AnomalyFactor != 0 && budget.AnomalyFactor <= 1 {
+		return nil, &ValidationError{Namespace: budget.Namespace, Problems: []ValidationProblem{
+			{Path: "/anomaly_factor", Message: "anomaly factor must be above 1, or 0 to turn detection off"},
+		}}
+	}
+
+	var result Budget
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(map[string]interface{}{
+			"monthly_tokens":   budget.MonthlyTokens,
+			"monthly_cost_usd": budget.MonthlyCost,
+			"warn_at":          budget.WarnAt,
+			"enforce":          budget.Enforce,
+			"anomaly_factor":   budget.AnomalyFactor,
+			"user":             user,
+		}).
+		SetResult(&result).
+		Put(fmt.Sprintf("/budgets/%s", url.PathEscape(budget.Namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetBudgetUsage returns namespace's budget and its consumption so far this
+// period
+func (c *LLMConfigClient) GetBudgetUsage(ctx context.Context, namespace string) (*BudgetUsage, error) {
+	var result BudgetUsage
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/budgets/%s/usage", url.PathEscape(namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// RecordSpend adds the actual usage of a completed call to namespace's
+// consumption, warns as it crosses the budget's thresholds, and checks it
+// for a spike when the budget sets an AnomalyFactor
+func (c *LLMConfigClient) RecordSpend(ctx context.Context, namespace string, spend Spend) (*BudgetUsage, error) {
+	var result BudgetUsage
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(spend).
+		SetResult(&result).
+		Post(fmt.Sprintf("/budgets/%s/usage", url.PathEscape(namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	c.warnBudget(ctx, &result, result.Fraction())
+	c.detectSpendAnomaly(ctx, namespace, spend, &result)
+	return &result, nil
+}
+
+// CheckBudget is called before an expensive model call with its estimated
+// spend. For an enforced budget it returns a *BudgetExceededError instead
+// of letting the call go past the limit:
+//
+//	if _, err := client.CheckBudget(ctx, "app/llm", Spend{Tokens: 4000}); errors.Is(err, ErrBudgetExceeded) {
+//		return fallbackAnswer()
+//	}
+//	// ... make the call, then RecordSpend with the actual usage
+func (c *LLMConfigClient) CheckBudget(ctx context.Context, namespace string, estimate Spend) (*BudgetUsage, error) {
+	usage, err := c.GetBudgetUsage(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("budget %s: %w", namespace, err)
+	}
+
+	projected := usage.Budget.fraction(Spend{
+		Tokens: usage.Tokens + estimate.Tokens,
+		Cost:   usage.Cost + estimate.Cost,
+	})
+	if usage.Budget.Enforce && projected > 1 {
+		return usage, &BudgetExceededError{Usage: *usage, Estimate: estimate}
+	}
+	return usage, nil
+}
+
+// warnBudget emits a warning for the highest threshold newly crossed in the
+// organization of ctx
+func (c *LLMConfigClient) warnBudget(ctx context.Context, usage *BudgetUsage, fraction float64) {
+	organization := c.organizationScopeID(ctx)
+	thresholds := usage.Budget.WarnAt
+	if len(thresholds) == 0 {
+		thresholds = defaultBudgetThresholds
+	}
+	thresholds = slices.Clone(thresholds)
+	sort.Float64s(thresholds)
+
+	w := c.budgets
+	w.mu.Lock()
+	var warning *BudgetWarning
+	for _, threshold := range thresholds {
+		id := fmt.Sprintf("%s %s %s %g", organization, usage.Namespace, usage.Period, threshold)
+		if fraction < threshold || w.fired[id] {
+			continue
+		}
+		w.fired[id] = true
+		warning = &BudgetWarning{
+			Organization: organization,
+			Namespace:    usage.Namespace,
+			Period:       usage.Period,
+			Threshold:    threshold,
+			Fraction:     fraction,
+			Usage:        *usage,
+		}
+	}
+	listeners := slices.Clone(w.listeners)
+	w.mu.Unlock()
+
+	if warning == nil {
+		return
+	}
+	if len(listeners) == 0 {
+		log.Printf("llm-config: %s budget for %s at %.0f%% (warning threshold %.0f%%)",
+			warning.Namespace, warning.Period, warning.Fraction*100, warning.Threshold*100)
+	}
+	for _, fn := range listeners {
+		fn(*warning)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// UsageStatsOptions selects the period and detail of namespace statistics
+type UsageStatsOptions struct {
+	// Environment limits reads and writes to one environment; all when empty
+	Environment string
+	// Window is how far back reads and writes are counted; 24h when zero
+	Window time.Duration
+	// TopReaders is how many of the heaviest readers to return; 10 when zero
+	TopReaders int
+}
+
+// ReaderStats is one client's reads of a namespace within the window.
+// Client is the name usage reports carry (see ReportUsage), or the token's
+// user for reads the server counted itself.
+type ReaderStats struct {
+	Client     string    `json:"client"`
+	User       string    `json:"user"`
+	Reads      int64     `json:"reads"`
+	LastReadAt time.Time `json:"last_read_at"`
+}
+
+// NamespaceStats is a namespace's size and traffic, for capacity planning
+// and chargeback
+type NamespaceStats struct {
+	Namespace string `json:"namespace"`
+	// Usage and Quota are current, across environments
+	Usage NamespaceUsage `json:"usage"`
+	Quota NamespaceQuota `json:"quota"`
+
+	WindowStart time.Time     `json:"window_start"`
+	WindowEnd   time.Time     `json:"window_end"`
+	Reads       int64         `json:"reads"`
+	Writes      int64         `json:"writes"`
+	TopReaders  []ReaderStats `json:"top_readers"`
+}
+
+// ReadsPerSecond is the average read rate over the window
+func (s *NamespaceStats) ReadsPerSecond() float64 {
+	return s.rate(s.Reads)
+}
+
+// WritesPerSecond is the average write rate over the window
+func (s *NamespaceStats) WritesPerSecond() float64 {
+	return s.rate(s.Writes)
+}
+
+func (s *NamespaceStats) rate(count int64) float64 {
+	window := s.WindowEnd.Sub(s.WindowStart).Seconds()
+	if window <= 0 {
+		return 0
+	}
+	return float64(count) / window
+}
+
+// QuotaUtilization returns the fractions of the key and byte quotas in use;
+// a limit that is not set reports 0
+func (s *NamespaceStats) QuotaUtilization() (keys, bytes float64) {
+	if s.Quota.MaxKeys > 0 {
+		keys = float64(s.Usage.Keys) / float64(s.Quota.MaxKeys)
+	}
+	if s.Quota.MaxBytes > 0 {
+		bytes = float64(s.Usage.Bytes) / float64(s.Quota.MaxBytes)
+	}
+	return keys, bytes
+}
+
+func (o UsageStatsOptions) query() map[string]string {
+	window, top := o.Window, o.TopReaders
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	if top <= 0 {
+		top = 10
+	}
+	query := map[string]string{
+		"window_seconds": strconv.FormatInt(int64(window/time.Second), 10),
+		"top":            strconv.Itoa(top),
+	}
+	if o.Environment != "" {
+		query["env"] = o.Environment
+	}
+	return query
+}
+
+// GetNamespaceStats returns one namespace's size, quota, and traffic
+func (c *LLMConfigClient) GetNamespaceStats(ctx context.Context, namespace string, opts UsageStatsOptions) (*NamespaceStats, error) {
+	var result NamespaceStats
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParams(opts.query()).
+		SetResult(&result).
+		Get(fmt.Sprintf("/namespaces/%s/stats", url.PathEscape(namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListNamespaceStats returns the statistics of every namespace under
+// prefix (all when empty), sorted by namespace
+func (c *LLMConfigClient) ListNamespaceStats(ctx context.Context, prefix string, opts UsageStatsOptions) ([]NamespaceStats, error) {
+	var result []NamespaceStats
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParams(opts.query()).
+		SetResult(&result)
+	if prefix != "" {
+		req.SetQueryParam("prefix", prefix)
+	}
+
+	resp, err := req.Get("/stats/namespaces")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// WriteNamespaceStatsCSV writes one row per namespace, with a header, for
+// chargeback spreadsheets. Quota columns are empty when unlimited.
+func WriteNamespaceStatsCSV(w io.Writer, stats []NamespaceStats) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"namespace", "keys", "bytes", "max_keys", "max_bytes",
+		"window_start", "window_end", "reads", "writes", "reads_per_second", "writes_per_second", "top_reader",
+	})
+	limit := func(n int64) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	for i := range stats {
+		s := &stats[i]
+		topReader := ""
+		if len(s.TopReaders) > 0 {
+			topReader = s.TopReaders[0].Client
+		}
+		cw.Write([]string{
+			s.Namespace,
+			strconv.Itoa(s.Usage.Keys),
+			strconv.FormatInt(s.Usage.Bytes, 10),
+			limit(int64(s.Quota.MaxKeys)),
+			limit(s.Quota.MaxBytes),
+			s.WindowStart.UTC().Format(time.RFC3339),
+			s.WindowEnd.UTC().Format(time.RFC3339),
+			strconv.FormatInt(s.Reads, 10),
+			strconv.FormatInt(s.Writes, 10),
+			strconv.FormatFloat(s.ReadsPerSecond(), 'f', 3, 64),
+			strconv.FormatFloat(s.WritesPerSecond(), 'f', 3, 64),
+			topReader,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Chat formats of ChatRoute
+const (
+	ChatSlack = "slack"
+	ChatTeams = "teams"
+)
+
+const (
+	// chatMaxLines caps the diff lines of one message
+	chatMaxLines = 40
+	// chatValueLimit is how much of a value a diff line quotes
+	chatValueLimit = 200
+)
+
+// ChatRoute sends changes to a chat channel's incoming webhook
+type ChatRoute struct {
+	// Namespace is a path.Match glob ("app/*"); every namespace when empty
+	Namespace string
+	// WebhookURL is the channel's incoming webhook
+	WebhookURL string
+	// Format is ChatSlack or ChatTeams. When empty it is ChatTeams for
+	// webhooks of office.com and azure.com hosts (connectors and Power
+	// Automate workflows) and ChatSlack, which Mattermost and Rocket.Chat
+	// also accept, for the rest.
+	Format string
+}
+
+// ChatNotifier posts a summary of each change, with its diff and author,
+// to the Slack or Microsoft Teams channels routed its namespace. It reads
+// changes from a change feed with Watch, or as the receiving end of a
+// WebhookSubscription with Handler and Serve. Masked secrets are never
+// shown.
+type ChatNotifier struct {
+	// Routes are matched in order; every matching route is posted to
+	Routes []ChatRoute
+	// Secret verifies the X-LLM-Config-Signature of subscription
+	// deliveries; unsigned deliveries are accepted when empty
+	Secret string
+	// HTTPClient posts the messages; http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// ParseChatRoute parses a route as given to `llmconfig chat-notify
+// --route`: [FORMAT:][PATTERN=]URL, e.g. "app/*=https://hooks.slack.com/..."
+func ParseChatRoute(spec string) (ChatRoute, error) {
+	var route ChatRoute
+	for _, format := range []string{ChatSlack, ChatTeams} {
+		if rest, ok := strings.CutPrefix(spec, format+":"); ok {
+			route.Format, spec = format, rest
+		}
+	}
+	if i := strings.Index(spec, "="); i >= 0 && !strings.Contains(spec[:i], "://") {
+		route.Namespace, spec = spec[:i], spec[i+1:]
+		if _, err := path.Match(route.Namespace, ""); err != nil {
+			return route, fmt.Errorf("route namespace %q: %w", route.Namespace, err)
+		}
+	}
+	if u, err := url.Parse(spec); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return route, fmt.Errorf("route URL %q must be an http(s) URL", spec)
+	}
+	route.WebhookURL = spec
+	return route, nil
+}
+
+// Watch watches namespaces and posts each poll's changes to a namespace as
+// one message, until ctx is done. Failed posts are logged and skipped.
+func (n *ChatNotifier) Watch(ctx context.Context, client *LLMConfigClient, namespaces []string, opts WatchOptions) error {
+	return client.watchBatches(ctx, namespaces, opts, func(events []ConfigEvent) {
+		if err := n.Notify(ctx, events); err != nil && ctx.Err() == nil {
+			log.Printf("llm-config chat: %v", err)
+		}
+	})
+}
+
+// Handler receives subscription deliveries and posts each change. A post
+// that fails answers 500, so the server redelivers it; test deliveries are
+// posted like the rest, to check the routes end to end.
+func (n *ChatNotifier) Handler() http.Handler {
+	return ChangeNotificationHandler(n.Secret, func(ctx context.Context, cn ChangeNotification) error {
+		return n.Notify(ctx, []ConfigEvent{cn.Event})
+	})
+}
+
+// Serve receives subscription deliveries on addr until ctx is done, then
+// shuts down gracefully
+func (n *ChatNotifier) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: n.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// Notify posts events, one message per namespace and environment, to the
+// routes of their namespaces
+func (n *ChatNotifier) Notify(ctx context.Context, events []ConfigEvent) error {
+	groups := map[string][]ConfigEvent{}
+	var order []string
+	for _, ev := range events {
+		id := ev.Namespace + "\x00" + ev.Environment
+		if _, ok := groups[id]; !ok {
+			order = append(order, id)
+		}
+		groups[id] = append(groups[id], ev)
+	}
+
+	var errs []error
+	for _, id := range order {
+		group := groups[id]
+		for _, route := range n.Routes {
+			if ok, _ := path.Match(route.Namespace, group[0].Namespace); route.Namespace != "" && !ok {
+				continue
+			}
+			if err := n.post(ctx, route, group); err != nil {
+				errs = append(errs, fmt.Errorf("%s (%s): %w", group[0].Namespace, group[0].Environment, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post sends the changes to one namespace to route
+func (n *ChatNotifier) post(ctx context.Context, route ChatRoute, events []ConfigEvent) error {
+	title, lines := chatSummary(events)
+	var message interface{}
+	switch format := route.format(); format {
+	case ChatSlack:
+		message = map[string]interface{}{
+			"text": title + "\n```\n" + strings.Join(lines, "\n") + "\n```",
+		}
+	case ChatTeams:
+		// TextRuns, unlike TextBlocks, are not markdown, which would turn
+		// the diff's "- " lines into a list
+		runs := make([]interface{}, len(lines))
+		for i, line := range lines {
+			run := map[string]interface{}{"type": "TextRun", "text": line + "\n", "fontType": "Monospace"}
+			switch line[0] {
+			case '+':
+				run["color"] = "Good"
+			case '-':
+				run["color"] = "Attention"
+			}
+			runs[i] = run
+		}
+		message = map[string]interface{}{
+			"type": "message",
+			"attachments": []interface{}{map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msteams": map[string]string{"width": "Full"},
+					"body": []interface{}{
+						map[string]interface{}{"type": "TextBlock", "text": title, "weight": "Bolder", "wrap": true},
+						map[string]interface{}{"type": "RichTextBlock", "inlines": runs},
+					},
+				},
+			}},
+		}
+	default:
+		return fmt.Errorf("unknown chat format %q", format)
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, route.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := n.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("post to %s webhook: %s: %s", route.format(), resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// format is the route's Format, or the one its webhook's host implies
+func (r ChatRoute) format() string {
+	if r.Format != "" {
+		return r.Format
+	}
+	if u, err := url.Parse(r.WebhookURL); err == nil {
+		host := u.Hostname()
+		if strings.HasSuffix(host, ".office.com") || strings.HasSuffix(host, ".azure.com") {
+			return ChatTeams
+		}
+	}
+	return ChatSlack
+}
+
+// chatSummary describes the changes to one namespace as a title and the
+// lines of a unified-style diff, as `llmconfig diff` prints them:
+//
+//	app/llm (production): 2 changes by alice
+//	- model = "gpt-4"
+//	+ model = "gpt-4o"
+//	+ temperature = 0.2
+func chatSummary(events []ConfigEvent) (title string, lines []string) {
+	authors := map[string]bool{}
+	for _, ev := range events {
+		switch ev.Type {
+		case ConfigCreated:
+			lines = append(lines, fmt.Sprintf("+ %s = %s", ev.Key, chatValue(ev.Config.Value)))
+		case ConfigUpdated:
+			lines = append(lines,
+				fmt.Sprintf("- %s = %s", ev.Key, chatValue(ev.Previous.Value)),
+				fmt.Sprintf("+ %s = %s", ev.Key, chatValue(ev.Config.Value)))
+		case ConfigDeleted:
+			if ev.Previous != nil {
+				lines = append(lines, fmt.Sprintf("- %s = %s", ev.Key, chatValue(ev.Previous.Value)))
+			} else {
+				lines = append(lines, "- "+ev.Key)
+			}
+		}
+		if ev.Config != nil && ev.Config.Metadata.UpdatedBy != "" {
+			authors[ev.Config.Metadata.UpdatedBy] = true
+		}
+	}
+	if len(lines) > chatMaxLines {
+		lines = append(lines[:chatMaxLines], fmt.Sprintf("… %d more lines", len(lines)-chatMaxLines))
+	}
+
+	changes := "1 change"
+	if len(events) > 1 {
+		changes = fmt.Sprintf("%d changes", len(events))
+	}
+	title = fmt.Sprintf("%s (%s): %s", events[0].Namespace, events[0].Environment, changes)
+	if len(authors) > 0 {
+		names := make([]string, 0, len(authors))
+		for name := range authors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		title += " by " + strings.Join(names, ", ")
+	}
+	return title, lines
+}
+
+// chatValue quotes a value for a diff line; masked secrets stay masked
+func chatValue(v interface{}) string {
+	if v == encryptedPlaceholder {
+		return "(secret)"
+	}
+	s := diffValue(v)
+	if r := []rune(s); len(r) > chatValueLimit {
+		s = string(r[:chatValueLimit]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Content-defined chunk bounds. Cut points depend only on nearby bytes, so
+// an edit changes the chunks around it and the rest are shared with the
+// previous version.
+const (
+	chunkMinSize  = 16 << 10
+	chunkMaxSize  = 256 << 10
+	chunkMaskBits = 16 // 64KiB average past the minimum
+	// chunkBatch is how many chunks are checked and uploaded together,
+	// bounding memory to chunkBatch*chunkMaxSize
+	chunkBatch = 64
+)
+
+// gearTable maps bytes to the random values of the rolling hash. It is
+// derived from SHA-256 so every client cuts identical content identically.
+var gearTable = func() (table [256]uint64) {
+	for i := range table {
+		sum := sha256.Sum256([]byte{byte(i)})
+		table[i] = binary.BigEndian.Uint64(sum[:8])
+	}
+	return table
+}()
+
+// ChunkRef is one chunk of a chunked value, named by its SHA-256
+type ChunkRef struct {
+	ID   string `json:"id"`
+	Size int64  `json:"size"`
+}
+
+// ChunkManifest lists the chunks a chunked value is made of, in order
+type ChunkManifest struct {
+	BlobInfo
+	Chunks []ChunkRef `json:"chunks"`
+}
+
+// ChunkedUploadReport is a chunked upload's result and what it transferred
+type ChunkedUploadReport struct {
+	BlobInfo
+	Chunks int
+	// Uploaded chunks were new to the server; the rest were already stored
+	// from earlier versions or other values
+	Uploaded      int
+	UploadedBytes int64
+}
+
+type missingChunksRequest struct {
+	Chunks []string `json:"chunks"`
+}
+
+type missingChunksResponse struct {
+	Missing []string `json:"missing"`
+}
+
+type putManifestRequest struct {
+	Env         string     `json:"env"`
+	User        string     `json:"user,omitempty"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	SHA256      string     `json:"sha256"`
+	Chunks      []ChunkRef `json:"chunks"`
+}
+
+// UploadChunked stores r as a chunked, content-addressed binary value. It
+// is for multi-megabyte values such as prompt libraries and few-shot
+// corpora: only chunks the server does not already hold are sent, so a
+// small edit uploads little and identical content is stored once across
+// versions and keys. Read it back with DownloadChunked.
+func (c *LLMConfigClient) UploadChunked(ctx context.Context, namespace, key, env string, r io.Reader, opts BlobUploadOptions) (*ChunkedUploadReport, error) {
+	if opts.ContentType == "" {
+		opts.ContentType = "application/octet-stream"
+	}
+
+	report := &ChunkedUploadReport{}
+	manifest := putManifestRequest{Env: env, User: opts.User, ContentType: opts.ContentType}
+	digest := sha256.New()
+	chunker := newChunker(io.TeeReader(r, digest))
+
+	for done := false; !done; {
+		var batch [][]byte
+		for len(batch) < chunkBatch {
+			chunk, err := chunker.next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("upload %s/%s (%s): %w", namespace, key, env, err)
+			}
+			batch = append(batch, chunk)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]string, len(batch))
+		for i, chunk := range batch {
+			sum := sha256.Sum256(chunk)
+			ids[i] = hex.EncodeToString(sum[:])
+			manifest.Chunks = append(manifest.Chunks, ChunkRef{ID: ids[i], Size: int64(len(chunk))})
+			manifest.Size += int64(len(chunk))
+		}
+		missing, err := c.missingChunks(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s/%s (%s): %w", namespace, key, env, err)
+		}
+		for i, chunk := range batch {
+			if !missing[ids[i]] {
+				continue
+			}
+			// A chunk repeated within the batch is only sent once
+			delete(missing, ids[i])
+			if err := c.putChunk(ctx, ids[i], chunk); err != nil {
+				return nil, fmt.Errorf("upload %s/%s (%s): chunk %s: %w", namespace, key, env, ids[i], err)
+			}
+			report.Uploaded++
+			report.UploadedBytes += int64(len(chunk))
+		}
+	}
+	manifest.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	report.Chunks = len(manifest.Chunks)
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(manifest).
+		SetResult(&report.BlobInfo).
+		Put(blobRoute(namespace, key, "manifest"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return report, nil
+}
+
+func (c *LLMConfigClient) missingChunks(ctx context.Context, ids []string) (map[string]bool, error) {
+	var result missingChunksResponse
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(missingChunksRequest{Chunks: ids}).
+		SetResult(&result).
+		Post("/chunks/missing")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	missing := make(map[string]bool, len(result.Missing))
+	for _, id := range result.Missing {
+		missing[id] = true
+	}
+	return missing, nil
+}
+
+func (c *LLMConfigClient) putChunk(ctx context.Context, id string, chunk []byte) error {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/octet-stream").
+		SetBody(chunk).
+		Put("/chunks/" + id)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// GetChunkManifest returns the chunks of a chunked value; version 0 is the
+// current one
+func (c *LLMConfigClient) GetChunkManifest(ctx context.Context, namespace, key, env string, version int64) (*ChunkManifest, error) {
+	var result ChunkManifest
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("env", env).
+		SetResult(&result)
+	if version > 0 {
+		req.SetQueryParam("version", strconv.FormatInt(version, 10))
+	}
+
+	resp, err := req.Get(blobRoute(namespace, key, "manifest"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ChunkedDownloadOptions configures DownloadChunked
+type ChunkedDownloadOptions struct {
+	// Version 0 is the current one
+	Version int64
+	// CacheDir keeps downloaded chunks by ID, so later versions only fetch
+	// the chunks that changed; no cache when empty
+	CacheDir string
+}
+
+// DownloadChunked writes a chunked value to w, fetching chunks missing from
+// the cache. Every chunk is checked against its ID before it is written,
+// and the whole value against its checksum at the end.
+func (c *LLMConfigClient) DownloadChunked(ctx context.Context, namespace, key, env string, w io.Writer, opts ChunkedDownloadOptions) (*ChunkManifest, error) {
+	manifest, err := c.GetChunkManifest(ctx, namespace, key, env, opts.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.New()
+	for _, ref := range manifest.Chunks {
+		chunk, err := c.chunk(ctx, ref.ID, opts.CacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("download %s/%s (%s): chunk %s: %w", namespace, key, env, ref.ID, err)
+		}
+		digest.Write(chunk)
+		if _, err := w.Write(chunk); err != nil {
+			return nil, err
+		}
+	}
+	if sum := hex.EncodeToString(digest.Sum(nil)); !strings.EqualFold(manifest.SHA256, sum) {
+		return nil, fmt.Errorf("download %s/%s (%s): chunks add up to sha256 %s, expected %s: %w", namespace, key, env, sum, manifest.SHA256, ErrChecksumMismatch)
+	}
+	return manifest, nil
+}
+
+// chunk returns a chunk from cacheDir or the server, verified against id
+func (c *LLMConfigClient) chunk(ctx context.Context, id, cacheDir string) ([]byte, error) {
+	if len(id) != sha256.Size*2 || strings.ContainsAny(id, "/\\.") {
+		return nil, fmt.Errorf("invalid chunk ID %q: %w", id, ErrValidation)
+	}
+	var cached string
+	if cacheDir != "" {
+		cached = filepath.Join(cacheDir, id[:2], id)
+		data, err := os.ReadFile(cached)
+		hit := err == nil && chunkMatches(data, id)
+		c.metrics.Load().observeCacheLookup(hit)
+		if hit {
+			return data, nil
+		}
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Get("/chunks/" + id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	data := resp.Body()
+	if !chunkMatches(data, id) {
+		return nil, ErrChecksumMismatch
+	}
+	if cached != "" {
+		if err := os.MkdirAll(filepath.Dir(cached), 0o755); err == nil {
+			// The cache only saves transfers; failing to fill it is not an error
+			_ = writeFileAtomic(cached, data, 0o644)
+		}
+	}
+	return data, nil
+}
+
+func chunkMatches(data []byte, id string) bool {
+	sum := sha256.Sum256(data)
+	return strings.EqualFold(hex.EncodeToString(sum[:]), id)
+}
+
+// chunker splits a stream at content-defined boundaries with a gear
+// rolling hash
+type chunker struct {
+	r   *bufio.Reader
+	buf bytes.Buffer
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: bufio.NewReaderSize(r, chunkMaxSize)}
+}
+
+// next returns the next chunk, or io.EOF after the last
+func (ch *chunker) next() ([]byte, error) {
+	const mask = uint64(1)<<chunkMaskBits - 1
+	ch.buf.Reset()
+	var hash uint64
+	for ch.buf.Len() < chunkMaxSize {
+		b, err := ch.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ch.buf.WriteByte(b)
+		hash = hash<<1 + gearTable[b]
+		if ch.buf.Len() >= chunkMinSize && hash>>(64-chunkMaskBits)&mask == 0 {
+			break
+		}
+	}
+	if ch.buf.Len() == 0 {
+		return nil, io.EOF
+	}
+	return bytes.Clone(ch.buf.Bytes()), nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+// loginRefreshTimeout bounds the token refresh made when a command loads a
+// stored login
+const loginRefreshTimeout = 30 * time.Second
+
+// oidcProvider is the part of an OpenID provider's metadata that device
+// login needs
+type oidcProvider struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+// discoverOIDC reads issuer's /.well-known/openid-configuration
+func discoverOIDC(ctx context.Context, issuer string) (*oidcProvider, error) {
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("discover %s: %w", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discover %s: %s", issuer, resp.Status)
+	}
+
+	var provider oidcProvider
+	if err := json.NewDecoder(resp.Body).Decode(&provider); err != nil {
+		return nil, fmt.Errorf("discover %s: %w", issuer, err)
+	}
+	if provider.DeviceAuthorizationEndpoint == "" || provider.TokenEndpoint == "" {
+		return nil, fmt.Errorf("discover %s: the provider does not support the device authorization flow", issuer)
+	}
+	return &provider, nil
+}
+
+// storedLogin is the keyring entry a device login writes. Besides the token
+// it keeps what is needed to refresh it without the user.
+type storedLogin struct {
+	Token    *oauth2.Token `json:"token"`
+	ClientID string        `json:"client_id"`
+	TokenURL string        `json:"token_url"`
+	Scopes   []string      `json:"scopes,omitempty"`
+}
+
+func (l *storedLogin) config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID: l.ClientID,
+		Scopes:   l.Scopes,
+		Endpoint: oauth2.Endpoint{TokenURL: l.TokenURL},
+	}
+}
+
+// deviceLogin runs the OAuth 2.0 device authorization grant (RFC 8628)
+// against issuer: it prints a code for the user to enter in a browser on
+// w, then polls until the login is approved, denied, or expires
+func deviceLogin(ctx context.Context, w io.Writer, issuer, clientID string, scopes []string) (*storedLogin, error) {
+	provider, err := discoverOIDC(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	conf := &oauth2.Config{
+		ClientID: clientID,
+		Scopes:   scopes,
+		Endpoint: oauth2.Endpoint{
+			DeviceAuthURL: provider.DeviceAuthorizationEndpoint,
+			TokenURL:      provider.TokenEndpoint,
+		},
+	}
+
+	auth, err := conf.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("start device login: %w", err)
+	}
+	if auth.VerificationURIComplete != "" {
+		fmt.Fprintf(w, "Open %s to log in (code %s)\n", auth.VerificationURIComplete, auth.UserCode)
+	} else {
+		fmt.Fprintf(w, "Open %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+	}
+	fmt.Fprintln(w, "Waiting for approval...")
+
+	token, err := conf.DeviceAccessToken(ctx, auth)
+	if err != nil {
+		return nil, fmt.Errorf("device login: %w", err)
+	}
+	return &storedLogin{Token: token, ClientID: clientID, TokenURL: provider.TokenEndpoint, Scopes: scopes}, nil
+}
+
+// saveLogin stores a device login for url in the keyring
+func saveLogin(url string, login *storedLogin) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return keyring.Set(keyringService, url, string(data))
+}
+
+// keyringToken returns the access token stored for url, or "" when there
+// is none. Entries written by a device login are refreshed when expired and
+// the new token is saved back; entries from `login --with-token` are used
+// as-is.
+func keyringToken(url string) (string, error) {
+	stored, err := keyring.Get(keyringService, url)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read token from keyring: %w", err)
+	}
+	if !strings.HasPrefix(stored, "{") {
+		return stored, nil
+	}
+
+	var login storedLogin
+	if err := json.Unmarshal([]byte(stored), &login); err != nil || login.Token == nil {
+		return "", errors.New("read token from keyring: unreadable entry; run llmconfig login again")
+	}
+	if login.Token.Valid() {
+		return login.Token.AccessToken, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), loginRefreshTimeout)
+	defer cancel()
+	token, err := login.config().TokenSource(ctx, login.Token).Token()
+	if err != nil {
+		return "", fmt.Errorf("refresh login for %s: %w; run llmconfig login again", url, err)
+	}
+	login.Token = token
+	if err := saveLogin(url, &login); err != nil {
+		return "", fmt.Errorf("store refreshed token: %w", err)
+	}
+	return token.AccessToken, nil
+}
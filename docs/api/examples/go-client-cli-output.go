@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output
+const (
+	outputText       = "text"
+	outputTable      = "table"
+	outputJSON       = "json"
+	outputYAML       = "yaml"
+	outputGoTemplate = "go-template"
+)
+
+// cliOutput is a parsed --output flag
+type cliOutput struct {
+	format   string
+	template *template.Template
+}
+
+// parseCLIOutput reads text, table, json, yaml, go-template=TEMPLATE, or
+// go-template-file=PATH. Templates run over the JSON form of the result, so
+// fields are named as in -o json, e.g. {{.value}} or {{range .}}{{.key}}{{end}}.
+func parseCLIOutput(flag string) (cliOutput, error) {
+	format, arg, hasArg := strings.Cut(flag, "=")
+	switch format {
+	case outputText, outputTable, outputJSON, outputYAML:
+		if hasArg {
+			return cliOutput{}, fmt.Errorf("--output %s takes no argument", format)
+		}
+		return cliOutput{format: format}, nil
+	case "go-template-file":
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return cliOutput{}, fmt.Errorf("--output: %w", err)
+		}
+		arg = string(data)
+		fallthrough
+	case outputGoTemplate:
+		if arg == "" {
+			return cliOutput{}, fmt.Errorf("--output %s requires a template, e.g. %s='{{.value}}'", format, format)
+		}
+		tmpl, err := template.New("output").Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				var buf bytes.Buffer
+				enc := json.NewEncoder(&buf)
+				enc.SetEscapeHTML(false)
+				err := enc.Encode(v)
+				return strings.TrimSuffix(buf.String(), "\n"), err
+			},
+		}).Parse(arg)
+		if err != nil {
+			return cliOutput{}, fmt.Errorf("--output: %w", err)
+		}
+		return cliOutput{format: outputGoTemplate, template: tmpl}, nil
+	}
+	return cliOutput{}, fmt.Errorf("--output must be text, table, json, yaml, go-template=..., or go-template-file=..., not %q", flag)
+}
+
+// machineReadable reports whether the output is meant for other programs,
+// so progress messages should stay off stdout
+func (out cliOutput) machineReadable() bool {
+	return out.format != outputText && out.format != outputTable
+}
+
+// render writes data in the selected format. text and table both use human,
+// which commands with a tabular form already print as a table.
+func (out cliOutput) render(w io.Writer, data interface{}, human func(io.Writer) error) error {
+	switch out.format {
+	case outputJSON:
+		return writeJSON(w, data)
+	case outputYAML:
+		return writeYAML(w, data)
+	case outputGoTemplate:
+		plain, err := plainData(data)
+		if err != nil {
+			return err
+		}
+		return out.template.Execute(w, plain)
+	}
+	return human(w)
+}
+
+// stream returns a function writing one document per call, for commands
+// that emit results as they arrive: NDJSON for json, "---"-separated
+// documents for yaml, and one template execution each for go-template
+func (out cliOutput) stream(w io.Writer, human func(io.Writer, interface{}) error) func(interface{}) error {
+	switch out.format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		return func(data interface{}) error { return enc.Encode(data) }
+	case outputYAML:
+		first := true
+		return func(data interface{}) error {
+			if !first {
+				if _, err := io.WriteString(w, "---\n"); err != nil {
+					return err
+				}
+			}
+			first = false
+			return writeYAML(w, data)
+		}
+	case outputGoTemplate:
+		return func(data interface{}) error { return out.render(w, data, nil) }
+	}
+	return func(data interface{}) error { return human(w, data) }
+}
+
+// writeYAML writes data as YAML with the field names of its JSON form
+func writeYAML(w io.Writer, data interface{}) error {
+	plain, err := plainData(data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(plain); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// plainData round-trips data through JSON, leaving maps, slices, and
+// scalars keyed by the json tags
+func plainData(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var plain interface{}
+	if err := json.Unmarshal(raw, &plain); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
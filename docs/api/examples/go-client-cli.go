@@ -0,0 +1,3224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the OS keyring service tokens are stored under, one
+// entry per API URL
+const keyringService = "llmconfig"
+
+// cliOptions are the flags shared by every llmconfig command
+type cliOptions struct {
+	url   string
+	token string
+	// socket is a Unix socket to reach the server through
+	socket    string
+	env       string
+	namespace string
+	user      string
+	output    string
+	out       cliOutput
+	// ownerOverride lets writes through ownership enforcement
+	ownerOverride string
+}
+
+// newCLI builds the llmconfig command tree. Build it with
+//
+//	go build -o llmconfig .
+//
+// and run e.g. `llmconfig get app/llm/model -e staging`. Flags default from
+// LLM_CONFIG_URL, LLM_CONFIG_ENV, LLM_CONFIG_NAMESPACE, and
+// LLM_CONFIG_SOCKET; the token comes from --token, then LLM_CONFIG_TOKEN,
+// then the OS keyring (see `login`).
+func newCLI() *cobra.Command {
+	opts := &cliOptions{}
+
+	root := &cobra.Command{
+		Use:           "llmconfig",
+		Short:         "Read and manage LLM Config Manager configuration",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.ownerOverride != "" {
+				cmd.SetContext(WithOwnerOverride(cmd.Context(), opts.ownerOverride))
+			}
+			out, err := parseCLIOutput(opts.output)
+			opts.out = out
+			return err
+		},
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.url, "url", envOrDefault("LLM_CONFIG_URL", "http://localhost:8080/api/v1"), "API base URL")
+	flags.StringVar(&opts.token, "token", "", "API token (default $LLM_CONFIG_TOKEN, then the keyring)")
+	flags.StringVar(&opts.socket, "unix-socket", os.Getenv("LLM_CONFIG_SOCKET"), "connect through this Unix socket, e.g. a local agent's, instead of TCP")
+	flags.StringVarP(&opts.env, "env", "e", envOrDefault("LLM_CONFIG_ENV", "production"), "environment")
+	flags.StringVarP(&opts.namespace, "namespace", "n", os.Getenv("LLM_CONFIG_NAMESPACE"), "namespace; when empty, keys are given as namespace/key")
+	flags.StringVar(&opts.user, "user", os.Getenv("USER"), "user recorded on changes")
+	flags.StringVar(&opts.ownerOverride, "owner-override", "", "reason for writing keys owned by others when ownership is enforced")
+	flags.StringVarP(&opts.output, "output", "o", outputText, "output format: text, table, json, yaml, go-template=TEMPLATE, or go-template-file=PATH")
+	root.SetGlobalNormalizationFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "ns" {
+			name = "namespace"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	_ = root.RegisterFlagCompletionFunc("namespace", opts.completeNamespace)
+
+	root.AddCommand(
+		opts.getCommand(),
+		opts.setCommand(),
+		opts.deleteCommand(),
+		opts.listCommand(),
+		opts.expirationsCommand(),
+		opts.deprecationsCommand(),
+		opts.historyCommand(),
+		opts.annotateCommand(),
+		opts.rollbackCommand(),
+		opts.diffCommand(),
+		opts.promoteCommand(),
+		opts.watchCommand(),
+		opts.execCommand(),
+		opts.uiCommand(),
+		opts.applyCommand(),
+		opts.exportCommand(),
+		opts.importCommand(),
+		opts.operatorCommand(),
+		opts.agentCommand(),
+		opts.snapshotCommand(),
+		opts.consulTemplateCommand(),
+		opts.lambdaExtensionCommand(),
+		opts.systemdEnvCommand(),
+		opts.syncCommand(),
+		opts.webhookCommand(),
+		opts.esoProviderCommand(),
+		opts.serveCommand(),
+		opts.springConfigCommand(),
+		opts.consulKVCommand(),
+		opts.grafanaCommand(),
+		opts.chatNotifyCommand(),
+		opts.pagerDutyCommand(),
+		opts.auditCommand(),
+		opts.secretCommand(),
+		opts.aliasCommand(),
+		opts.tagCommand(),
+		opts.consumerCommand(),
+		opts.ownersCommand(),
+		opts.blobCommand(),
+		opts.templateCommand(),
+		opts.loginCommand(),
+		opts.logoutCommand(),
+		opts.whoamiCommand(),
+		passthroughCommand("gen", "Generate a typed Go package for a namespace", runGenCommand),
+		passthroughCommand("lint", "Lint namespaces for naming, orphaned keys, and untagged secrets", runLintCommand),
+	)
+	return root
+}
+
+func (o *cliOptions) getCommand() *cobra.Command {
+	var noOverrides, resolve bool
+	cmd := &cobra.Command{
+		Use:               "get KEY",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Print a config value",
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			var cfg *ConfigResponse
+			if resolve {
+				cfg, err = client.GetConfigResolved(cmd.Context(), namespace, key, o.env)
+			} else {
+				cfg, err = client.GetConfigContext(cmd.Context(), namespace, key, o.env, !noOverrides)
+			}
+			if err != nil {
+				return err
+			}
+			return o.out.render(cmd.OutOrStdout(), cfg, func(w io.Writer) error {
+				if o.out.format == outputTable {
+					return writeConfigTable(w, []ConfigResponse{*cfg})
+				}
+				return writeValue(w, cfg.Value)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&noOverrides, "no-overrides", false, "ignore environment overrides")
+	cmd.Flags().BoolVar(&resolve, "resolve", false, "resolve ${namespace/key} references in the value")
+	cmd.MarkFlagsMutuallyExclusive("no-overrides", "resolve")
+	return cmd
+}
+
+func (o *cliOptions) setCommand() *cobra.Command {
+	var secret, asString bool
+	var ttl time.Duration
+	var onExpiry string
+	cmd := &cobra.Command{
+		Use:               "set KEY VALUE",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Set a config value; VALUE is parsed as JSON unless --string is given or it is not valid JSON",
+		Args:              cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			value := parseCLIValue(args[1], asString)
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			var cfg *ConfigResponse
+			if ttl > 0 {
+				if secret {
+					return errors.New("set: --ttl cannot be combined with --secret")
+				}
+				cfg, err = client.SetTemporaryConfig(cmd.Context(), namespace, key, value, o.env, o.user, ttl, ExpiryAction(onExpiry))
+			} else {
+				cfg, err = client.SetConfigContext(cmd.Context(), namespace, key, value, o.env, o.user, secret)
+			}
+			if err != nil {
+				return err
+			}
+			return o.out.render(cmd.OutOrStdout(), cfg, func(w io.Writer) error {
+				if _, err := fmt.Fprintf(w, "%s/%s (%s) set to version %d\n", namespace, key, o.env, cfg.Version); err != nil || cfg.Expiry == nil {
+					return err
+				}
+				_, err := fmt.Fprintf(w, "expires %s (%s)\n", cfg.Expiry.At.Local().Format(time.RFC3339), cfg.Expiry.Action)
+				return err
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&secret, "secret", false, "store the value encrypted")
+	cmd.Flags().BoolVar(&asString, "string", false, "store VALUE as a string without parsing it")
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "make the value temporary, expiring after this long")
+	cmd.Flags().StringVar(&onExpiry, "on-expiry", string(ExpireRevert), "with --ttl, what expiry does: revert to the previous value, or delete")
+	return cmd
+}
+
+func (o *cliOptions) deleteCommand() *cobra.Command {
+	var ifUnused bool
+	cmd := &cobra.Command{
+		Use:               "delete KEY",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Delete a config value",
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if ifUnused {
+				consumers, err := client.Consumers(cmd.Context(), namespace, key, ConsumerListOptions{Environment: o.env})
+				if err != nil {
+					return fmt.Errorf("delete: %w", err)
+				}
+				if len(consumers) > 0 {
+					services := make([]string, len(consumers))
+					for i, c := range consumers {
+						services[i] = c.Service
+					}
+					return fmt.Errorf("delete: %s/%s (%s) is read by %s: %w", namespace, key, o.env, strings.Join(slices.Compact(services), ", "), ErrConflict)
+				}
+			}
+			deleted, err := client.DeleteConfigContext(cmd.Context(), namespace, key, o.env)
+			if err != nil {
+				return err
+			}
+			if !deleted {
+				return fmt.Errorf("%s/%s (%s): %w", namespace, key, o.env, ErrNotFound)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s/%s (%s) deleted\n", namespace, key, o.env)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&ifUnused, "if-unused", false, "refuse to delete a key that registered services read")
+	return cmd
+}
+
+func (o *cliOptions) listCommand() *cobra.Command {
+	var (
+		effective bool
+		stream    bool
+		page      PageOptions
+	)
+	cmd := &cobra.Command{
+		Use:               "list [NAMESPACE]",
+		ValidArgsFunction: o.completeNamespace,
+		Short:             "List the configs in a namespace",
+		Args:              cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace := o.namespace
+			if len(args) == 1 {
+				namespace = args[0]
+			}
+			if namespace == "" {
+				return errors.New("list: give a namespace or set --namespace")
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if effective {
+				configs, err := client.EffectiveConfigs(cmd.Context(), namespace, o.env)
+				if err != nil {
+					return err
+				}
+				return o.out.render(cmd.OutOrStdout(), configs, func(out io.Writer) error {
+					w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(w, "KEY\tSOURCE\tVERSION\tVALUE")
+					for _, cfg := range configs {
+						fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", cfg.Key, cfg.Source, cfg.Version, formatCLIValue(cfg.Value))
+					}
+					return w.Flush()
+				})
+			}
+			if page.Limit > 0 || page.Cursor != "" {
+				result, err := client.ListConfigsPage(cmd.Context(), namespace, o.env, page)
+				if err != nil {
+					return err
+				}
+				return o.out.render(cmd.OutOrStdout(), result, func(w io.Writer) error {
+					if err := writeConfigTable(w, result.Items); err != nil {
+						return err
+					}
+					if result.Total >= 0 {
+						fmt.Fprintf(cmd.ErrOrStderr(), "%d of %d configs\n", len(result.Items), result.Total)
+					}
+					if result.HasMore() {
+						fmt.Fprintf(cmd.ErrOrStderr(), "next page: --cursor %s\n", result.NextCursor)
+					}
+					return nil
+				})
+			}
+			if stream {
+				// Rows are written as they arrive, so they are not aligned
+				header := true
+				write := o.out.stream(cmd.OutOrStdout(), func(w io.Writer, data interface{}) error {
+					if header {
+						header = false
+						fmt.Fprintln(w, "KEY\tVERSION\tUPDATED BY\tVALUE")
+					}
+					cfg := data.(ConfigResponse)
+					_, err := fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", cfg.Key, cfg.Version, cfg.Metadata.UpdatedBy, formatCLIValue(cfg.Value))
+					return err
+				})
+				return client.StreamConfigs(cmd.Context(), namespace, o.env, func(cfg ConfigResponse) error {
+					return write(cfg)
+				})
+			}
+			configs, err := client.ListConfigsContext(cmd.Context(), namespace, o.env)
+			if err != nil {
+				return err
+			}
+			return o.out.render(cmd.OutOrStdout(), configs, func(w io.Writer) error {
+				return writeConfigTable(w, configs)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&effective, "effective", false, "include values inherited from parent namespaces, with the namespace each comes from")
+	cmd.Flags().BoolVar(&stream, "stream", false, "print configs as they arrive, one JSON object per line with -o json, for namespaces too large to hold")
+	cmd.Flags().IntVar(&page.Limit, "limit", 0, "list one page of this many configs (default all)")
+	cmd.Flags().StringVar(&page.Cursor, "cursor", "", "continue from the cursor printed after the previous page")
+	cmd.MarkFlagsMutuallyExclusive("effective", "limit")
+	cmd.MarkFlagsMutuallyExclusive("effective", "cursor")
+	cmd.MarkFlagsMutuallyExclusive("stream", "effective", "limit")
+	cmd.MarkFlagsMutuallyExclusive("stream", "cursor")
+	return cmd
+}
+
+func (o *cliOptions) expirationsCommand() *cobra.Command {
+	var within time.Duration
+	var clear bool
+	cmd := &cobra.Command{
+		Use:   "expirations [KEY]",
+		Short: "List temporary values and when they expire, soonest first",
+		Long: `List temporary values and when they expire, soonest first. -n limits the
+list to a namespace and those under it, and --within to values expiring
+soon. With KEY and --clear, the key's expiry is cancelled and its current
+value kept.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if clear {
+				if len(args) == 0 {
+					return errors.New("expirations: --clear needs a KEY")
+				}
+				namespace, key, err := o.splitKey(args[0])
+				if err != nil {
+					return err
+				}
+				cleared, err := client.ClearExpiry(cmd.Context(), namespace, key, o.env)
+				if err != nil {
+					return fmt.Errorf("expirations: %w", err)
+				}
+				if !cleared {
+					return fmt.Errorf("expirations: %s/%s (%s) has no expiry", namespace, key, o.env)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s/%s (%s) no longer expires\n", namespace, key, o.env)
+				return nil
+			}
+			if len(args) > 0 {
+				return errors.New("expirations: KEY is only used with --clear")
+			}
+
+			expirations, err := client.ListExpirations(cmd.Context(), ExpirationOptions{
+				Namespace:   o.namespace,
+				Environment: o.env,
+				Within:      within,
+			})
+			if err != nil {
+				return fmt.Errorf("expirations: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), expirations, func(out io.Writer) error {
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "KEY\tVERSION\tEXPIRES\tACTION\tSET BY")
+				for _, e := range expirations {
+					fmt.Fprintf(w, "%s/%s\t%d\t%s\t%s\t%s\n", e.Namespace, e.Key, e.Version, e.At.Local().Format(time.RFC3339), e.Action, e.SetBy)
+				}
+				return w.Flush()
+			})
+		},
+	}
+	cmd.Flags().DurationVar(&within, "within", 0, "only values expiring within this long")
+	cmd.Flags().BoolVar(&clear, "clear", false, "cancel KEY's expiry, keeping its value")
+	return cmd
+}
+
+func (o *cliOptions) deprecationsCommand() *cobra.Command {
+	var (
+		message, replacedBy, removeAfter string
+		clear                            bool
+	)
+	cmd := &cobra.Command{
+		Use:               "deprecations [KEY]",
+		ValidArgsFunction: o.completeKey,
+		Short:             "List deprecated keys, or deprecate KEY",
+		Long: `List deprecated keys; -n limits the list to a namespace and those under
+it. With KEY and --message or --replaced-by, KEY is deprecated: it keeps
+working, but clients reading it log a warning and count the reads, so
+consumers can be migrated before it is removed. With KEY and --clear, the
+deprecation is withdrawn.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			marking := message != "" || replacedBy != "" || removeAfter != ""
+			if len(args) == 0 {
+				if marking || clear {
+					return errors.New("deprecations: --message, --replaced-by, --remove-after, and --clear need a KEY")
+				}
+				deprecated, err := client.ListDeprecations(cmd.Context(), o.namespace)
+				if err != nil {
+					return fmt.Errorf("deprecations: %w", err)
+				}
+				return o.out.render(cmd.OutOrStdout(), deprecated, func(out io.Writer) error {
+					w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(w, "KEY\tREPLACED BY\tREMOVE AFTER\tMESSAGE")
+					for _, d := range deprecated {
+						removal := "-"
+						if d.RemoveAfter != nil {
+							removal = d.RemoveAfter.Format(time.DateOnly)
+						}
+						fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\n", d.Namespace, d.Key, d.ReplacedBy, removal, d.Message)
+					}
+					return w.Flush()
+				})
+			}
+
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			if clear {
+				if marking {
+					return errors.New("deprecations: --clear cannot be combined with --message, --replaced-by, or --remove-after")
+				}
+				cleared, err := client.Undeprecate(cmd.Context(), namespace, key)
+				if err != nil {
+					return fmt.Errorf("deprecations: %w", err)
+				}
+				if !cleared {
+					return fmt.Errorf("deprecations: %s/%s is not deprecated", namespace, key)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s/%s is no longer deprecated\n", namespace, key)
+				return nil
+			}
+			if !marking {
+				return errors.New("deprecations: KEY needs --message, --replaced-by, or --clear")
+			}
+
+			d := KeyDeprecation{Message: message}
+			if replacedBy != "" {
+				ns, k, err := o.splitKey(replacedBy)
+				if err != nil {
+					return err
+				}
+				d.ReplacedBy = ns + "/" + k
+			}
+			if removeAfter != "" {
+				at, err := time.ParseInLocation(time.DateOnly, removeAfter, time.Local)
+				if err != nil {
+					return fmt.Errorf("deprecations: --remove-after must be YYYY-MM-DD: %w", err)
+				}
+				d.RemoveAfter = &at
+			}
+			stored, err := client.Deprecate(cmd.Context(), namespace, key, d, o.user)
+			if err != nil {
+				return fmt.Errorf("deprecations: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), stored, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s/%s deprecated: %s\n", namespace, key, stored)
+				return err
+			})
+		},
+	}
+	cmd.Flags().StringVar(&message, "message", "", "why KEY is deprecated")
+	cmd.Flags().StringVar(&replacedBy, "replaced-by", "", "the key to read instead")
+	cmd.Flags().StringVar(&removeAfter, "remove-after", "", "date (YYYY-MM-DD) after which KEY may be deleted")
+	cmd.Flags().BoolVar(&clear, "clear", false, "withdraw KEY's deprecation")
+	return cmd
+}
+
+func (o *cliOptions) historyCommand() *cobra.Command {
+	var annotations bool
+	cmd := &cobra.Command{
+		Use:               "history KEY",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Show the version history of a config",
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if annotations {
+				history, err := client.GetAnnotatedHistory(cmd.Context(), namespace, key, o.env)
+				if err != nil {
+					return err
+				}
+				return o.out.render(cmd.OutOrStdout(), history, func(out io.Writer) error {
+					entries := make([]VersionEntry, len(history.Versions))
+					for i, v := range history.Versions {
+						entries[i] = v.VersionEntry
+					}
+					if err := writeHistoryTable(out, entries); err != nil {
+						return err
+					}
+					writeAnnotationThreads(out, "Key annotations:", history.KeyAnnotations)
+					for _, v := range history.Versions {
+						writeAnnotationThreads(out, fmt.Sprintf("Version %d annotations:", v.Version), v.Annotations)
+					}
+					return nil
+				})
+			}
+			history, err := client.GetHistoryContext(cmd.Context(), namespace, key, o.env)
+			if err != nil {
+				return err
+			}
+			return o.out.render(cmd.OutOrStdout(), history, func(out io.Writer) error {
+				return writeHistoryTable(out, history)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&annotations, "annotations", false, "include the notes attached to the key and its versions")
+	return cmd
+}
+
+// writeHistoryTable prints history as the table of `history`
+func writeHistoryTable(out io.Writer, history []VersionEntry) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tCREATED AT\tCREATED BY\tVALUE\tDESCRIPTION")
+	for _, entry := range history {
+		description := ""
+		if entry.ChangeDescription != nil {
+			description = *entry.ChangeDescription
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", entry.Version, entry.CreatedAt, entry.CreatedBy, formatCLIValue(entry.Value), description)
+	}
+	return w.Flush()
+}
+
+func (o *cliOptions) annotateCommand() *cobra.Command {
+	var (
+		version int64
+		replyTo string
+	)
+	cmd := &cobra.Command{
+		Use:               "annotate KEY MESSAGE...",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Attach a note to a config or one of its versions",
+		Long: `Attach a note to KEY in --env, or with --version to one version of it,
+e.g. why a value was changed. --reply-to answers an earlier annotation.
+Notes are shown by "history --annotations".`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			annotation, err := client.Annotate(cmd.Context(), namespace, key, NewAnnotation{
+				Environment: o.env,
+				Version:     version,
+				ReplyTo:     replyTo,
+				Body:        strings.Join(args[1:], " "),
+			}, o.user)
+			if err != nil {
+				return fmt.Errorf("annotate: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), annotation, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "Annotated %s/%s (%s) as %s\n", namespace, key, o.env, annotation.ID)
+				return err
+			})
+		},
+	}
+	cmd.Flags().Int64Var(&version, "version", 0, "annotate this version rather than the key")
+	cmd.Flags().StringVar(&replyTo, "reply-to", "", "ID of the annotation to answer")
+	return cmd
+}
+
+// writeAnnotationThreads prints threads under a heading, replies indented
+func writeAnnotationThreads(w io.Writer, heading string, threads []AnnotationThread) {
+	if len(threads) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", heading)
+	for _, thread := range threads {
+		fmt.Fprintf(w, "  [%s] %s, %s: %s\n", thread.ID, thread.Author, thread.CreatedAt.Local().Format(time.DateTime), thread.Body)
+		for _, reply := range thread.Replies {
+			fmt.Fprintf(w, "    [%s] %s, %s: %s\n", reply.ID, reply.Author, reply.CreatedAt.Local().Format(time.DateTime), reply.Body)
+		}
+	}
+}
+
+func (o *cliOptions) rollbackCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "rollback KEY VERSION",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Restore a config to an earlier version",
+		Args:              cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			version, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || version <= 0 {
+				return fmt.Errorf("rollback: invalid version %q", args[1])
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			cfg, err := client.RollbackContext(cmd.Context(), namespace, key, version, o.env)
+			if err != nil {
+				return err
+			}
+			return o.out.render(cmd.OutOrStdout(), cfg, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s/%s (%s) rolled back to version %d as version %d\n", namespace, key, o.env, version, cfg.Version)
+				return err
+			})
+		},
+	}
+}
+
+func (o *cliOptions) diffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff FROM TO [KEY...]",
+		Short: "Show the changes promoting --namespace from one environment to another would make",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := o.planPromotion(cmd, args, PromoteOptions{Keys: args[2:], Prune: true})
+			if err != nil {
+				return err
+			}
+			return o.writePromotionPlan(cmd.OutOrStdout(), plan)
+		},
+	}
+}
+
+func (o *cliOptions) promoteCommand() *cobra.Command {
+	var prune, yes bool
+	cmd := &cobra.Command{
+		Use:   "promote FROM TO [KEY...]",
+		Short: "Copy --namespace from one environment to another after confirming the changes",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := o.planPromotion(cmd, args, PromoteOptions{Keys: args[2:], Prune: prune})
+			if err != nil {
+				return err
+			}
+			if err := o.writePromotionPlan(cmd.OutOrStdout(), plan); err != nil {
+				return err
+			}
+			if !plan.HasChanges() {
+				return nil
+			}
+
+			if !yes {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Apply %d change(s) to %s (%s)? [y/N] ", countChanges(&plan.ApplyPlan), plan.Namespace, plan.To)
+				answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if err != nil && !errors.Is(err, io.EOF) {
+					return err
+				}
+				if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+					return errors.New("promote: cancelled")
+				}
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if err := client.Promote(cmd.Context(), plan, o.user); err != nil {
+				return err
+			}
+			if o.out.machineReadable() {
+				return nil
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Promoted %s from %s to %s\n", plan.Namespace, plan.From, plan.To)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&prune, "prune", false, "delete keys that exist only in TO")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
+
+func (o *cliOptions) planPromotion(cmd *cobra.Command, args []string, opts PromoteOptions) (*PromotionPlan, error) {
+	if o.namespace == "" {
+		return nil, fmt.Errorf("%s: --namespace is required", cmd.Name())
+	}
+	client, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+	return client.PlanPromotion(cmd.Context(), o.namespace, args[0], args[1], opts)
+}
+
+func (o *cliOptions) writePromotionPlan(w io.Writer, plan *PromotionPlan) error {
+	return o.out.render(w, plan, func(w io.Writer) error {
+		if !plan.HasChanges() {
+			fmt.Fprintf(w, "%s: %s and %s match\n", plan.Namespace, plan.From, plan.To)
+		} else if err := plan.WriteDiff(w); err != nil {
+			return err
+		}
+		for _, key := range plan.SkippedSecrets {
+			fmt.Fprintf(w, "! %s is a secret in %s and is not promoted; set it in %s by hand\n", key, plan.From, plan.To)
+		}
+		return nil
+	})
+}
+
+// countChanges counts the changes in a plan that modify something
+func countChanges(plan *ApplyPlan) int {
+	n := 0
+	for _, change := range plan.Changes {
+		if change.Action != ChangeUnchanged {
+			n++
+		}
+	}
+	return n
+}
+
+func (o *cliOptions) watchCommand() *cobra.Command {
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:               "watch [NAMESPACE]",
+		ValidArgsFunction: o.completeNamespace,
+		Short:             "Stream changes to a namespace until interrupted; -o json prints NDJSON, -o yaml a YAML stream",
+		Args:              cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace := o.namespace
+			if len(args) == 1 {
+				namespace = args[0]
+			}
+			if namespace == "" {
+				return errors.New("watch: give a namespace or set --namespace")
+			}
+			if interval <= 0 {
+				return errors.New("watch: --interval must be positive")
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			w, err := client.Watch(ctx, namespace, WatchOptions{Environment: o.env, Interval: interval})
+			if err != nil {
+				return err
+			}
+			defer w.Close()
+
+			errOut := cmd.ErrOrStderr()
+			write := o.out.stream(cmd.OutOrStdout(), func(w io.Writer, event interface{}) error {
+				return writeWatchEvent(w, client.clock.Now(), event.(ConfigEvent))
+			})
+			if !o.out.machineReadable() {
+				fmt.Fprintf(errOut, "Watching %s (%s) every %s; Ctrl-C to stop\n", namespace, o.env, interval)
+			}
+
+			// Report connection changes on stderr so gaps in the stream are visible
+			connected := true
+			health := time.NewTicker(interval)
+			defer health.Stop()
+			for {
+				select {
+				case event, ok := <-w.Events():
+					if !ok {
+						return nil
+					}
+					if err := write(event); err != nil {
+						return err
+					}
+				case <-health.C:
+					status := w.Status()
+					if status.Connected != connected {
+						connected = status.Connected
+						if connected {
+							fmt.Fprintf(errOut, "! reconnected to %s\n", o.url)
+						} else {
+							fmt.Fprintf(errOut, "! lost connection: %s\n", status.LastError)
+						}
+					}
+				}
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "poll interval")
+	return cmd
+}
+
+// writeWatchEvent prints one change as a line of the human-readable stream
+func writeWatchEvent(w io.Writer, at time.Time, event ConfigEvent) error {
+	prefix := fmt.Sprintf("%s %-7s %s/%s (%s)", at.UTC().Format(time.RFC3339), event.Type, event.Namespace, event.Key, event.Environment)
+	var err error
+	switch {
+	case event.Type == ConfigDeleted && event.Previous != nil:
+		_, err = fmt.Fprintf(w, "%s was %s\n", prefix, diffValue(event.Previous.Value))
+	case event.Config == nil:
+		_, err = fmt.Fprintln(w, prefix)
+	case event.Previous != nil:
+		_, err = fmt.Fprintf(w, "%s v%d by %s: %s -> %s\n", prefix, event.Config.Version, event.Config.Metadata.UpdatedBy,
+			diffValue(event.Previous.Value), diffValue(event.Config.Value))
+	default:
+		_, err = fmt.Fprintf(w, "%s v%d by %s: %s\n", prefix, event.Config.Version, event.Config.Metadata.UpdatedBy, diffValue(event.Config.Value))
+	}
+	return err
+}
+
+// cliExitError carries a child process's exit status out of `exec`
+type cliExitError struct {
+	code int
+}
+
+func (e *cliExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (o *cliOptions) execCommand() *cobra.Command {
+	var envOpts ExecEnvOptions
+	var dryRun bool
+	var keyFile string
+	cmd := &cobra.Command{
+		Use:   "exec [flags] -- COMMAND [ARG...]",
+		Short: "Run a command with --namespace's configs as environment variables",
+		Long: `Run a command with --namespace's configs as environment variables.
+
+Keys become upper-case names ("max-tokens" -> MAX_TOKENS) behind --prefix.
+Strings are passed as-is and other values as JSON. Config variables replace
+inherited ones of the same name. Secrets the API masks are injected with
+--reveal-secrets, decrypting client-side encrypted ones with --key-file, or
+left out with --skip-masked-secrets; otherwise exec fails. Signals are
+forwarded to the command and its exit status becomes exec's.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.namespace == "" {
+				return errors.New("exec: --namespace is required")
+			}
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if envOpts.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			vars, err := client.ExecEnv(cmd.Context(), o.namespace, o.env, envOpts)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				shown := make([]EnvVar, len(vars))
+				for i, v := range vars {
+					if v.Secret {
+						v.Value = "(secret)"
+					}
+					shown[i] = v
+				}
+				return o.out.render(cmd.OutOrStdout(), shown, func(w io.Writer) error {
+					if o.out.format == outputTable {
+						tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+						fmt.Fprintln(tw, "NAME\tKEY\tVALUE")
+						for _, v := range shown {
+							fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Name, v.Key, v.Value)
+						}
+						return tw.Flush()
+					}
+					for _, v := range shown {
+						fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value)
+					}
+					return nil
+				})
+			}
+
+			child := exec.Command(args[0], args[1:]...)
+			child.Stdin, child.Stdout, child.Stderr = cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+			child.Env = os.Environ()
+			for _, v := range vars {
+				child.Env = append(child.Env, v.Name+"="+v.Value)
+			}
+			// Catch signals before the child exists, so one arriving during
+			// startup is forwarded rather than killing us and orphaning it
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+			defer signal.Stop(signals)
+			if err := child.Start(); err != nil {
+				return fmt.Errorf("exec: %w", err)
+			}
+			go func() {
+				for sig := range signals {
+					_ = child.Process.Signal(sig)
+				}
+			}()
+
+			err = child.Wait()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return &cliExitError{code: exitErr.ExitCode()}
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&envOpts.Prefix, "prefix", "", "prefix for every variable name")
+	cmd.Flags().StringSliceVar(&envOpts.Keys, "keys", nil, "only inject these keys")
+	cmd.Flags().BoolVar(&envOpts.RevealSecrets, "reveal-secrets", false, "inject secret values (the token needs permission to read them)")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	cmd.Flags().BoolVar(&envOpts.SkipMaskedSecrets, "skip-masked-secrets", false, "leave out secrets the API masks instead of failing")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the variables instead of running the command, hiding secrets and credential-like values")
+	return cmd
+}
+
+// exitDrift is the status of `apply --dry-run` when the server differs
+// from the manifests
+const exitDrift = 2
+
+func (o *cliOptions) applyCommand() *cobra.Command {
+	var paths []string
+	var dryRun bool
+	var color, verifyKey string
+	cmd := &cobra.Command{
+		Use:   "apply -f PATH [-f PATH...]",
+		Short: "Converge the server on declarative manifests",
+		Long: `Converge the server on declarative manifests.
+
+Each -f names a manifest file or a directory searched for .yaml, .yml, and
+.json manifests. The planned changes are printed as a diff. With --dry-run
+nothing is written and the exit status is 2 when the server has drifted
+from the manifests, 0 when it matches, so CI can gate on it.
+
+With --verify-key, every manifest file needs a signature of that public
+key in FILE.sig, as "cosign sign-blob --key cosign.key --output-signature
+FILE.sig FILE" writes it, or nothing is applied.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			load := LoadManifests
+			if verifyKey != "" {
+				verifier, err := LoadSnapshotVerifier(verifyKey)
+				if err != nil {
+					return fmt.Errorf("apply: %w", err)
+				}
+				load = func(path string) ([]Manifest, error) { return LoadSignedManifests(path, verifier) }
+			}
+			var manifests []Manifest
+			for _, path := range paths {
+				loaded, err := load(path)
+				if err != nil {
+					return err
+				}
+				manifests = append(manifests, loaded...)
+			}
+			if len(manifests) == 0 {
+				return fmt.Errorf("apply: no manifests found in %s", strings.Join(paths, ", "))
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			plan, err := client.PlanApply(cmd.Context(), manifests)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			err = o.out.render(out, plan, func(w io.Writer) error {
+				if !plan.HasChanges() {
+					_, err := fmt.Fprintln(w, "No changes; the server matches the manifests")
+					return err
+				}
+				return writeColoredDiff(w, plan, color)
+			})
+			if err != nil || !plan.HasChanges() {
+				return err
+			}
+
+			if dryRun {
+				return &cliExitError{code: exitDrift}
+			}
+			if err := client.Apply(cmd.Context(), plan, o.user); err != nil {
+				return err
+			}
+			if o.out.machineReadable() {
+				return nil
+			}
+			_, err = fmt.Fprintf(out, "Applied %d change(s)\n", countChanges(plan))
+			return err
+		},
+	}
+	cmd.Flags().StringSliceVarP(&paths, "filename", "f", nil, "manifest file or directory (repeatable)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the plan without applying it; exit 2 on drift")
+	cmd.Flags().StringVar(&color, "color", "auto", "color the diff: auto, always, or never")
+	cmd.Flags().StringVar(&verifyKey, "verify-key", "", "public key (e.g. cosign.pub) every manifest must be signed with")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// writeColoredDiff prints plan.WriteDiff with additions in green, removals
+// in red, and headers in cyan. In auto mode color is used only on a
+// terminal and when NO_COLOR is unset.
+func writeColoredDiff(w io.Writer, plan *ApplyPlan, color string) error {
+	var buf bytes.Buffer
+	if err := plan.WriteDiff(&buf); err != nil {
+		return err
+	}
+
+	renderer := lipgloss.NewRenderer(w)
+	switch color {
+	case "always":
+		renderer.SetColorProfile(termenv.ANSI)
+	case "never":
+		renderer.SetColorProfile(termenv.Ascii)
+	case "auto":
+	default:
+		return fmt.Errorf("apply: --color must be auto, always, or never, not %q", color)
+	}
+	styles := map[byte]lipgloss.Style{
+		'+': renderer.NewStyle().Foreground(lipgloss.Color("2")),
+		'-': renderer.NewStyle().Foreground(lipgloss.Color("1")),
+		'@': renderer.NewStyle().Foreground(lipgloss.Color("6")).Bold(true),
+	}
+
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		if style, ok := styles[text[0]]; ok {
+			text = style.Render(text)
+		}
+		if _, err := fmt.Fprintln(w, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *cliOptions) exportCommand() *cobra.Command {
+	var format, signKey, signatureFile string
+	var exportOpts ExportOptions
+	var k8sOpts K8sManifestOptions
+	cmd := &cobra.Command{
+		Use:               "export [NAMESPACE]",
+		ValidArgsFunction: o.completeNamespace,
+		Short:             "Print a namespace as yaml, json, toml, dotenv, systemd, hcl, or a Kubernetes ConfigMap",
+		Long: `Print a namespace as yaml, json, toml, dotenv, systemd, hcl, or a Kubernetes ConfigMap.
+
+With --sign-key the output is signed and the signature written to
+--signature-file, in the format of "cosign sign-blob", so the snapshot can
+be checked with "cosign verify-blob". A key from "cosign generate-key-pair"
+is decrypted with $COSIGN_PASSWORD.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace := o.namespace
+			if len(args) == 1 {
+				namespace = args[0]
+			}
+			if namespace == "" {
+				return errors.New("export: give a namespace or set --namespace")
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+
+			var data []byte
+			switch format {
+			case "hcl":
+				data, err = client.ExportHCL(cmd.Context(), namespace, o.env)
+			case "configmap":
+				k8sOpts.SecretKeys = exportOpts.SecretKeys
+				data, err = client.GenerateK8sManifests(cmd.Context(), namespace, o.env, k8sOpts)
+			case string(ExportFormatYAML), string(ExportFormatJSON), string(ExportFormatTOML), string(ExportFormatDotenv), string(ExportFormatSystemd):
+				data, err = client.ExportWithOptions(cmd.Context(), namespace, o.env, ExportFormat(format), exportOpts)
+			default:
+				return fmt.Errorf("export: --format must be yaml, json, toml, dotenv, systemd, hcl, or configmap, not %q", format)
+			}
+			if err != nil {
+				return err
+			}
+			if signKey != "" {
+				signer, err := LoadSnapshotSigner(signKey, []byte(os.Getenv("COSIGN_PASSWORD")))
+				if err != nil {
+					return fmt.Errorf("export: %w", err)
+				}
+				sig, err := SignSnapshot(signer, data)
+				if err != nil {
+					return fmt.Errorf("export: %w", err)
+				}
+				if err := os.WriteFile(signatureFile, []byte(sig), 0o644); err != nil {
+					return fmt.Errorf("export: %w", err)
+				}
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", string(ExportFormatYAML), "yaml, json, toml, dotenv, systemd (an EnvironmentFile), hcl, or configmap")
+	cmd.Flags().StringVar(&signKey, "sign-key", "", "private key (e.g. cosign.key) to sign the output with")
+	cmd.Flags().StringVar(&signatureFile, "signature-file", "", "file the signature is written to")
+	cmd.MarkFlagsRequiredTogether("sign-key", "signature-file")
+	cmd.Flags().BoolVar(&exportOpts.Redact, "redact", false, "replace secret values with placeholders (yaml, json, toml, dotenv, systemd)")
+	cmd.Flags().StringSliceVar(&exportOpts.SecretKeys, "secret-keys", nil, "keys to treat as secrets: redacted, or written to the Secret for configmap")
+	cmd.Flags().StringVar(&k8sOpts.KubeNamespace, "kube-namespace", "", "metadata.namespace of the ConfigMap and Secret")
+	cmd.Flags().BoolVar(&k8sOpts.SkipMaskedSecrets, "skip-masked-secrets", false, "leave masked secrets out of a configmap instead of failing")
+	return cmd
+}
+
+func (o *cliOptions) importCommand() *cobra.Command {
+	var path, format string
+	var dryRun bool
+	var dotenvOpts DotenvImportOptions
+	cmd := &cobra.Command{
+		Use:               "import -f FILE [NAMESPACE]",
+		ValidArgsFunction: o.completeNamespace,
+		Short:             "Write the values in a dotenv file or Kubernetes ConfigMap/Secret YAML into a namespace",
+		Long: `Write the values in a dotenv file or Kubernetes ConfigMap/Secret YAML into a namespace.
+
+The format follows the file name (.env files are dotenv, .yaml and .yml are
+Kubernetes manifests) unless --format is given; -f - reads stdin. Unquoted
+dotenv values and ConfigMap values become booleans, numbers, or JSON where
+they parse as such. Secret data is stored as secrets. A ConfigMap written
+by "export --format configmap" carries its namespace, so NAMESPACE is
+optional for one.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace := o.namespace
+			if len(args) == 1 {
+				namespace = args[0]
+			}
+			if format == "" {
+				format = importFormat(path)
+			}
+
+			in := cmd.InOrStdin()
+			if path != "-" {
+				f, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("import: %w", err)
+				}
+				defer f.Close()
+				in = f
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+
+			var entries []ImportEntry
+			switch format {
+			case "dotenv":
+				if namespace == "" {
+					return errors.New("import: give a namespace or set --namespace")
+				}
+				dotenvOpts.Env, dotenvOpts.User, dotenvOpts.DryRun = o.env, o.user, dryRun
+				entries, err = client.ImportDotenv(cmd.Context(), namespace, in, dotenvOpts)
+			case "configmap":
+				entries, err = client.ImportK8sManifests(cmd.Context(), namespace, in, K8sImportOptions{Env: o.env, User: o.user, DryRun: dryRun})
+			default:
+				return fmt.Errorf("import: cannot tell the format of %q; set --format to dotenv or configmap", path)
+			}
+			if err != nil {
+				return err
+			}
+
+			shown := make([]ImportEntry, len(entries))
+			for i, entry := range entries {
+				if entry.Secret {
+					entry.Value = encryptedPlaceholder
+				}
+				shown[i] = entry
+			}
+			return o.out.render(cmd.OutOrStdout(), shown, func(w io.Writer) error {
+				for _, entry := range shown {
+					fmt.Fprintf(w, "%s = %s\n", entry.Key, formatCLIValue(entry.Value))
+				}
+				var err error
+				if dryRun {
+					_, err = fmt.Fprintf(w, "%d key(s) to import (%s); nothing written\n", len(shown), o.env)
+				} else {
+					_, err = fmt.Fprintf(w, "%d key(s) imported (%s)\n", len(shown), o.env)
+				}
+				return err
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&path, "filename", "f", "", "file to import, or - for stdin")
+	cmd.Flags().StringVar(&format, "format", "", "dotenv or configmap (default from the file name)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would be set without writing it")
+	cmd.Flags().StringVar(&dotenvOpts.StripPrefix, "strip-prefix", "", "dotenv: only import variables with this prefix, dropping it from the key")
+	cmd.Flags().StringSliceVar(&dotenvOpts.SecretKeys, "secret-keys", nil, "dotenv: keys to store as secrets")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// importFormat guesses an import file's format from its name
+func importFormat(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env"):
+		return "dotenv"
+	case strings.HasSuffix(base, ".yaml") || strings.HasSuffix(base, ".yml"):
+		return "configmap"
+	}
+	return ""
+}
+
+func (o *cliOptions) operatorCommand() *cobra.Command {
+	var operatorOpts OperatorOptions
+	cmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Run the Kubernetes operator that reconciles LLMConfig resources against --url",
+		Long: `Run the Kubernetes operator that reconciles LLMConfig resources against --url.
+
+Each LLMConfig declares a namespace/environment like an apply manifest, with
+secret values taken from Kubernetes Secrets. The operator applies the spec,
+re-checks it every spec.interval, and reports drift and errors in the
+resource's status and events. Install the CRD and RBAC from
+deployment/kubernetes/operator first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			operatorOpts.User = o.user
+			if operatorOpts.User == "" {
+				operatorOpts.User = "llmconfig-operator"
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return RunOperator(ctx, client, operatorOpts)
+		},
+	}
+	cmd.Flags().StringVar(&operatorOpts.WatchNamespace, "watch-namespace", os.Getenv("WATCH_NAMESPACE"), "only reconcile LLMConfigs in this Kubernetes namespace")
+	cmd.Flags().StringVar(&operatorOpts.MetricsAddr, "metrics-bind-address", ":8080", "metrics endpoint address, or 0 to disable")
+	cmd.Flags().StringVar(&operatorOpts.ProbeAddr, "health-probe-bind-address", ":8081", "health and readiness probe address")
+	cmd.Flags().BoolVar(&operatorOpts.LeaderElection, "leader-elect", false, "elect a leader so only one replica reconciles")
+	return cmd
+}
+
+func (o *cliOptions) agentCommand() *cobra.Command {
+	var dir, keyFile, signalName, signalProcess, pidFile string
+	var renders []string
+	var once bool
+	var mode uint32
+	agentOpts := AgentOptions{}
+	cmd := &cobra.Command{
+		Use:   "agent --render NAMESPACE[:FORMAT] [--render ...]",
+		Short: "Keep config files in a directory in sync with namespaces, e.g. as a pod sidecar",
+		Long: `Keep config files in a directory in sync with namespaces, e.g. as a pod sidecar.
+
+Each --render writes one file into --dir. FORMAT is json (the default),
+yaml, toml, dotenv, or systemd, named after the namespace
+("app/llm:dotenv" writes app-llm.env), or template=PATH, which renders a
+Go template over the namespace's key -> value map into a file named after
+the template without .tmpl. Files are replaced atomically and only when
+their content changes. After a change the agent signals the application
+(--signal-process needs shareProcessNamespace in a pod). With --once it
+writes the files and exits, for init containers.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(renders) == 0 {
+				return errors.New("agent: at least one --render is required")
+			}
+			for _, spec := range renders {
+				file, err := parseAgentRender(dir, spec)
+				if err != nil {
+					return fmt.Errorf("agent: --render %w", err)
+				}
+				agentOpts.Files = append(agentOpts.Files, file)
+			}
+			agentOpts.Environment = o.env
+			agentOpts.Mode = os.FileMode(mode)
+
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if agentOpts.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+
+			sig, ok := agentSignals[strings.TrimPrefix(strings.ToUpper(signalName), "SIG")]
+			if !ok {
+				return fmt.Errorf("agent: unsupported signal %q", signalName)
+			}
+			switch {
+			case signalProcess != "" && pidFile != "":
+				return errors.New("agent: give --signal-process or --signal-pid-file, not both")
+			case signalProcess != "":
+				agentOpts.Notify = func() error { return signalProcesses(signalProcess, sig) }
+			case pidFile != "":
+				agentOpts.Notify = func() error { return signalPIDFile(pidFile, sig) }
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if once {
+				_, err := client.SyncAgentFiles(cmd.Context(), agentOpts)
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return client.RunAgent(ctx, agentOpts)
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory the files are written to, e.g. a shared emptyDir")
+	cmd.Flags().StringArrayVar(&renders, "render", nil, "NAMESPACE[:FORMAT] to write (repeatable)")
+	cmd.Flags().DurationVar(&agentOpts.Interval, "interval", 5*time.Second, "poll interval")
+	cmd.Flags().BoolVar(&once, "once", false, "write the files once and exit")
+	cmd.Flags().Uint32Var(&mode, "mode", 0o640, "permissions of the written files")
+	cmd.Flags().BoolVar(&agentOpts.RevealSecrets, "reveal-secrets", false, "write secret values (the token needs permission to read them); otherwise secrets are left out")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	cmd.Flags().StringVar(&signalName, "signal", "HUP", "signal sent after a change: HUP, USR1, USR2, or TERM")
+	cmd.Flags().StringVar(&signalProcess, "signal-process", "", "signal processes with this command name")
+	cmd.Flags().StringVar(&pidFile, "signal-pid-file", "", "signal the process whose ID is in this file")
+	return cmd
+}
+
+func (o *cliOptions) consulTemplateCommand() *cobra.Command {
+	var specs []string
+	var keyFile string
+	var once bool
+	templateOpts := ConsulTemplateOptions{}
+	cmd := &cobra.Command{
+		Use:   "consul-template --template SOURCE:DESTINATION[:COMMAND] [--template ...]",
+		Short: "Render consul-template templates from the config server",
+		Long: `Render consul-template templates from the config server, e.g. in a Nomad
+sidecar task, so workloads templated for Consul need no changes.
+
+Templates use consul-template's syntax with namespace/key paths:
+{{ key "app/llm/model" }}, {{ keyOrDefault "app/llm/timeout" "30" }},
+{{ range ls "app/llm" }}{{ .Key }}={{ .Value }}{{ end }}, and
+{{ with secret "app/llm/openai_api_key" }}{{ .Data.value }}{{ end }}.
+Files are replaced atomically; COMMAND runs with sh -c after its file
+changes. The namespaces the templates read are watched and the templates
+re-rendered on change; with --once they are rendered once.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(specs) == 0 {
+				return errors.New("consul-template: at least one --template is required")
+			}
+			for _, spec := range specs {
+				t, err := ParseConsulTemplate(spec)
+				if err != nil {
+					return fmt.Errorf("consul-template: %w", err)
+				}
+				templateOpts.Templates = append(templateOpts.Templates, t)
+			}
+			templateOpts.Environment = o.env
+
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if templateOpts.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if once {
+				_, err := client.RenderConsulTemplates(cmd.Context(), templateOpts)
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return client.RunConsulTemplates(ctx, templateOpts)
+		},
+	}
+	cmd.Flags().StringArrayVar(&specs, "template", nil, "SOURCE:DESTINATION[:COMMAND] to render (repeatable)")
+	cmd.Flags().DurationVar(&templateOpts.Interval, "interval", 5*time.Second, "poll interval")
+	cmd.Flags().BoolVar(&once, "once", false, "render the templates once and exit")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	return cmd
+}
+
+func (o *cliOptions) lambdaExtensionCommand() *cobra.Command {
+	var keyFile string
+	lambdaOpts := LambdaExtensionOptions{}
+	cmd := &cobra.Command{
+		Use:   "lambda-extension",
+		Short: "Run as an AWS Lambda extension serving cached namespaces to the function",
+		Long: `Run as an AWS Lambda extension serving cached namespaces to the function.
+
+The namespaces are fetched at cold start, before the first invocation, and
+served from memory on --addr; once they are older than --max-age an
+invocation refreshes them in the background. A failed first fetch fails
+the function's init. Lambda starts extensions without arguments, so every
+flag also reads an environment variable; see deployment/lambda for the
+layer layout.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(lambdaOpts.Namespaces) == 0 {
+				return errors.New("lambda-extension: --namespaces or $LLM_CONFIG_LAMBDA_NAMESPACES is required")
+			}
+			lambdaOpts.Environment = o.env
+
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if lambdaOpts.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			return client.RunLambdaExtension(cmd.Context(), lambdaOpts)
+		},
+	}
+	maxAge, _ := time.ParseDuration(os.Getenv("LLM_CONFIG_LAMBDA_MAX_AGE"))
+	reveal, _ := strconv.ParseBool(os.Getenv("LLM_CONFIG_LAMBDA_REVEAL_SECRETS"))
+	var namespaces []string
+	if v := os.Getenv("LLM_CONFIG_LAMBDA_NAMESPACES"); v != "" {
+		namespaces = strings.Split(v, ",")
+	}
+	cmd.Flags().StringVar(&lambdaOpts.Name, "name", "llmconfig", "extension name; must match its file name in /opt/extensions")
+	cmd.Flags().StringSliceVar(&lambdaOpts.Namespaces, "namespaces", namespaces, "namespaces to serve ($LLM_CONFIG_LAMBDA_NAMESPACES)")
+	cmd.Flags().StringVar(&lambdaOpts.Addr, "addr", envOrDefault("LLM_CONFIG_LAMBDA_ADDR", defaultLambdaAddr), "address to serve on ($LLM_CONFIG_LAMBDA_ADDR)")
+	cmd.Flags().DurationVar(&lambdaOpts.MaxAge, "max-age", maxAge, "age after which an invocation refreshes the snapshots; 5m when 0 ($LLM_CONFIG_LAMBDA_MAX_AGE)")
+	cmd.Flags().BoolVar(&lambdaOpts.RevealSecrets, "reveal-secrets", reveal, "include secret values ($LLM_CONFIG_LAMBDA_REVEAL_SECRETS)")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	return cmd
+}
+
+func (o *cliOptions) systemdEnvCommand() *cobra.Command {
+	var output, keyFile, action string
+	var units []string
+	var once bool
+	var mode uint32
+	agentOpts := AgentOptions{}
+	cmd := &cobra.Command{
+		Use:   "systemd-env NAMESPACE --output PATH [--unit UNIT ...]",
+		Short: "Keep a systemd EnvironmentFile in sync with a namespace",
+		Long: `Keep a systemd EnvironmentFile in sync with a namespace, for services
+outside Kubernetes.
+
+The file is written with systemd's quoting and replaced atomically when
+the namespace changes, after which every --unit gets systemctl --action.
+systemd reads an EnvironmentFile when it starts a process, so reload only
+reaches the main process of units whose ExecReload re-reads the file; use
+--action restart for the rest. With --once the file is written once, e.g.
+from ExecStartPre. deployment/systemd/llmconfig-env@.service runs this
+for a unit.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				return errors.New("systemd-env: --output is required")
+			}
+			switch action {
+			case "reload", "restart", "try-restart", "reload-or-restart", "try-reload-or-restart":
+			default:
+				return fmt.Errorf("systemd-env: unsupported --action %q", action)
+			}
+			agentOpts.Files = []AgentFile{{Namespace: args[0], Path: output, Format: ExportFormatSystemd}}
+			agentOpts.Environment = o.env
+			agentOpts.Mode = os.FileMode(mode)
+			if len(units) > 0 {
+				agentOpts.Notify = func() error { return systemctl(action, units) }
+			}
+
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if agentOpts.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if once {
+				_, err := client.SyncAgentFiles(cmd.Context(), agentOpts)
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return client.RunAgent(ctx, agentOpts)
+		},
+	}
+	cmd.Flags().StringVar(&output, "output", "", "EnvironmentFile to write, e.g. /run/llm-config/batch.env")
+	cmd.Flags().StringArrayVar(&units, "unit", nil, "unit to run systemctl --action on after a change (repeatable)")
+	cmd.Flags().StringVar(&action, "action", "reload", "systemctl verb: reload, restart, try-restart, reload-or-restart, or try-reload-or-restart")
+	cmd.Flags().DurationVar(&agentOpts.Interval, "interval", 5*time.Second, "poll interval")
+	cmd.Flags().BoolVar(&once, "once", false, "write the file once and exit")
+	cmd.Flags().Uint32Var(&mode, "mode", 0o640, "permissions of the written file")
+	cmd.Flags().BoolVar(&agentOpts.RevealSecrets, "reveal-secrets", false, "write secret values (the token needs permission to read them); otherwise secrets are left out")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	return cmd
+}
+
+// exitMissingKeys is the status of `snapshot` when required keys are
+// missing
+const exitMissingKeys = 3
+
+func (o *cliOptions) snapshotCommand() *cobra.Command {
+	var dir, keyFile string
+	var require []string
+	var mode uint32
+	snapshotOpts := AgentOptions{}
+	cmd := &cobra.Command{
+		Use:   "snapshot NAMESPACE[:FORMAT]",
+		Short: "Write a namespace to a file once, failing if required keys are missing",
+		Long: `Write a namespace to a file once, failing if required keys are missing.
+
+Meant for init containers writing to an emptyDir the application mounts:
+the file is named and formatted as with agent --render. When a --require
+key is missing nothing is written, the missing keys are printed, and the
+exit status is 3, so the pod fails to start instead of running without
+its config.`,
+		Example: `  llmconfig snapshot app/llm --dir /config --require model,openai_api_key --reveal-secrets`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := parseAgentRender(dir, args[0])
+			if err != nil {
+				return fmt.Errorf("snapshot: %w", err)
+			}
+			file.Require = require
+			snapshotOpts.Files = []AgentFile{file}
+			snapshotOpts.Environment = o.env
+			snapshotOpts.Mode = os.FileMode(mode)
+
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if snapshotOpts.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			_, err = client.SyncAgentFiles(cmd.Context(), snapshotOpts)
+			var missing *MissingKeysError
+			if errors.As(err, &missing) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "snapshot: %v\n", missing)
+				return &cliExitError{code: exitMissingKeys}
+			}
+			if err != nil {
+				return err
+			}
+			if !o.out.machineReadable() {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s\n", file.Path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory the file is written to")
+	cmd.Flags().StringSliceVar(&require, "require", nil, "keys that must be present (comma-separated or repeated)")
+	cmd.Flags().Uint32Var(&mode, "mode", 0o640, "permissions of the written file")
+	cmd.Flags().BoolVar(&snapshotOpts.RevealSecrets, "reveal-secrets", false, "write secret values (the token needs permission to read them); otherwise secrets are left out")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	return cmd
+}
+
+func (o *cliOptions) syncCommand() *cobra.Command {
+	var mappings []string
+	var keyFile string
+	syncOpts := ConfigMapSyncOptions{}
+	cmd := &cobra.Command{
+		Use:   "sync --map NAMESPACE=KUBE_NAMESPACE[/NAME] [--map ...]",
+		Short: "Mirror namespaces into Kubernetes ConfigMaps and Secrets, reverting direct edits",
+		Long: `Mirror namespaces into Kubernetes ConfigMaps and Secrets, reverting direct edits.
+
+Each --map writes a namespace to a ConfigMap in a Kubernetes namespace,
+named after the namespace ("app/llm=default" writes default/app-llm) or
+NAME. Keys in --secret-keys, and with --reveal-secrets every secret, go to
+a Secret of the same name. The server is re-read every --interval, and the
+objects are watched, so edits made with kubectl are reverted immediately.
+Objects are labelled app.kubernetes.io/managed-by=llmconfig-sync and
+annotated with their source namespace, environment, and key versions;
+existing objects without the label are never overwritten.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(mappings) == 0 {
+				return errors.New("sync: at least one --map is required")
+			}
+			for _, spec := range mappings {
+				namespace, dest, _ := strings.Cut(spec, "=")
+				kubeNamespace, name, _ := strings.Cut(dest, "/")
+				if namespace == "" || kubeNamespace == "" {
+					return fmt.Errorf("sync: --map %q must be NAMESPACE=KUBE_NAMESPACE[/NAME]", spec)
+				}
+				syncOpts.Targets = append(syncOpts.Targets, ConfigMapSyncTarget{Namespace: namespace, KubeNamespace: kubeNamespace, Name: name})
+			}
+			syncOpts.Environment = o.env
+
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if syncOpts.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return RunConfigMapSync(ctx, client, syncOpts)
+		},
+	}
+	cmd.Flags().StringArrayVar(&mappings, "map", nil, "NAMESPACE=KUBE_NAMESPACE[/NAME] to mirror (repeatable)")
+	cmd.Flags().DurationVar(&syncOpts.Interval, "interval", defaultSyncInterval, "how often to re-read the server")
+	cmd.Flags().StringSliceVar(&syncOpts.SecretKeys, "secret-keys", nil, "keys written to the Secret instead of the ConfigMap")
+	cmd.Flags().BoolVar(&syncOpts.RevealSecrets, "reveal-secrets", false, "mirror secret values into the Secrets (the token needs permission to read them); otherwise secrets are left out")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	cmd.Flags().StringVar(&syncOpts.MetricsAddr, "metrics-bind-address", ":8080", "metrics endpoint address, or 0 to disable")
+	cmd.Flags().StringVar(&syncOpts.ProbeAddr, "health-probe-bind-address", ":8081", "health and readiness probe address")
+	cmd.Flags().BoolVar(&syncOpts.LeaderElection, "leader-elect", false, "elect a leader so only one replica syncs")
+	return cmd
+}
+
+func (o *cliOptions) webhookCommand() *cobra.Command {
+	var webhookOpts WebhookOptions
+	var grants []string
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Serve the admission webhook that injects configs into annotated pods",
+		Long: `Serve the admission webhook that injects configs into annotated pods.
+
+A pod annotated with llm-config-manager.io/inject-namespace gets that
+namespace's values (from -e, or llm-config-manager.io/inject-environment)
+as environment variables, or with llm-config-manager.io/inject-as: volume
+as config.json mounted at llm-config-manager.io/inject-mount-path
+(default /etc/llm-config). Masked secrets are never injected. Values are
+read once, at admission; restart the pod to pick up changes. Install the
+webhook from deployment/kubernetes/webhook.
+
+Pods are only injected with namespaces an --allow grants to their
+Kubernetes namespace, or to their service account in it, and are denied
+otherwise:
+
+  --allow team-a=app/team-a/*
+  --allow team-b/inference=app/llm,app/shared`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			webhookOpts.DefaultEnvironment = o.env
+			for _, s := range grants {
+				grant, err := ParseInjectionGrant(s)
+				if err != nil {
+					return err
+				}
+				webhookOpts.Grants = append(webhookOpts.Grants, grant)
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return RunWebhook(ctx, client, webhookOpts)
+		},
+	}
+	cmd.Flags().IntVar(&webhookOpts.Port, "port", 9443, "HTTPS port")
+	cmd.Flags().StringVar(&webhookOpts.CertDir, "cert-dir", "/tmp/k8s-webhook-server/serving-certs", "directory with tls.crt and tls.key")
+	cmd.Flags().StringArrayVar(&grants, "allow", nil, "K8S_NAMESPACE[/SERVICE_ACCOUNT]=CONFIG_NAMESPACE[,...] that may be injected (repeatable)")
+	return cmd
+}
+
+func (o *cliOptions) esoProviderCommand() *cobra.Command {
+	var addr, keyFile string
+	cmd := &cobra.Command{
+		Use:   "eso-provider",
+		Short: "Serve configs and secrets to the External Secrets Operator",
+		Long: `Serve configs and secrets to the External Secrets Operator's webhook
+provider, so ExternalSecrets can read from the config server.
+
+Each request is made with the bearer token of the calling SecretStore;
+--url is used, but --token is not. Secrets are revealed, and client-side
+encrypted ones decrypted with --key-file. Requests without ?env= read the
+-e environment. See deployment/kubernetes/external-secrets for the
+SecretStores.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider := &ESOProvider{BaseURL: o.url, DefaultEnvironment: o.env}
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if provider.SecretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return provider.Serve(ctx, addr)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "listen", ":8090", "address to listen on")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "client-side secret key file (default $LLM_CONFIG_SECRET_KEY)")
+	return cmd
+}
+
+func (o *cliOptions) serveCommand() *cobra.Command {
+	var addr, dir, token string
+	cmd := &cobra.Command{
+		Use:   "serve --dir DIR",
+		Short: "Serve the config API from a directory of manifests, for development",
+		Long: `Serve the config API from a directory of manifests, for development on
+a laptop or in CI without a config server.
+
+The manifests are those of apply; reads see edits to them at once, and
+writes through the API rewrite them. Point clients at
+http://localhost:8080/api/v1 (with the default --listen), and Spring Cloud
+Config clients at http://localhost:8080 (see spring-config). History,
+secrets, and everything beyond reading and writing configs need a real
+server.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir == "" {
+				return errors.New("serve: --dir is required")
+			}
+			srv := &DevServer{Backend: NewFileBackend(dir), Token: token}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return srv.Serve(ctx, addr)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "listen", ":8080", "address to listen on")
+	cmd.Flags().StringVar(&dir, "dir", "", "directory of manifests to serve")
+	cmd.Flags().StringVar(&token, "require-token", "", "bearer token clients must send; empty allows any")
+	return cmd
+}
+
+func (o *cliOptions) springConfigCommand() *cobra.Command {
+	var addr, token string
+	srv := &SpringConfigServer{}
+	cmd := &cobra.Command{
+		Use:   "spring-config",
+		Short: "Serve namespaces to Spring Cloud Config clients",
+		Long: `Serve namespaces over the Spring Cloud Config server's API, so JVM
+services read them with spring-cloud-config-client:
+
+  spring.config.import=configserver:http://localhost:8888
+  spring.application.name=app(_)llm
+  spring.profiles.active=prod
+
+The application names the namespace after --prefix, "(_)" standing for
+"/". Profiles name environments or are mapped with --profile prod=production;
+"default" is development. Values are read from the server with --token and
+masked secrets left out.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			srv.Reader = client
+			srv.Token = token
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return srv.Serve(ctx, addr)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "listen", ":8888", "address to listen on")
+	cmd.Flags().StringVar(&srv.Prefix, "prefix", "", "put before application names to give the namespace, e.g. services/")
+	cmd.Flags().StringToStringVar(&srv.Profiles, "profile", nil, "map a Spring profile to an environment, e.g. prod=production (repeatable)")
+	cmd.Flags().StringVar(&token, "require-token", "", "bearer token clients must send; empty allows any")
+	return cmd
+}
+
+func (o *cliOptions) consulKVCommand() *cobra.Command {
+	var addr string
+	srv := &ConsulKVServer{}
+	cmd := &cobra.Command{
+		Use:   "consul-kv",
+		Short: "Serve namespaces read-only over Consul's KV API",
+		Long: `Serve namespaces read-only over Consul's KV HTTP API, so confd,
+consul-template, and other Consul tools read them unmodified:
+
+  consul-template -consul-addr localhost:8500 -template "app.tmpl:app.conf"
+
+The Consul key <prefix>app/llm/model is key model of namespace app/llm in
+the -e environment, as ExportConsul writes it. Blocking queries re-read
+the keys every --poll until they change. Masked secrets are left out.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			srv.Reader = client
+			srv.Environment = o.env
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return srv.Serve(ctx, addr)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "listen", "127.0.0.1:8500", "address to listen on")
+	cmd.Flags().StringVar(&srv.Prefix, "prefix", "", "prefix of the Consul keys, e.g. config/")
+	cmd.Flags().StringVar(&srv.Token, "require-token", "", "ACL token clients must send; empty allows any")
+	cmd.Flags().DurationVar(&srv.PollInterval, "poll", defaultConsulPoll, "how often blocking queries re-read their keys")
+	return cmd
+}
+
+func (o *cliOptions) grafanaCommand() *cobra.Command {
+	grafanaOpts := GrafanaAnnotationOptions{}
+	cmd := &cobra.Command{
+		Use:   "grafana-annotate --url URL NAMESPACE...",
+		Short: "Annotate Grafana dashboards when namespaces change",
+		Long: `Annotate Grafana dashboards when namespaces change, so model and prompt
+changes show on the latency and quality graphs they affect.
+
+Each poll's changes to a namespace in the -e environment become one
+annotation tagged llm-config, namespace:<namespace>, env:<environment>,
+key:<key> per key, and each --tag. Without --dashboard-uid annotations are
+organization-wide and show on dashboards querying those tags. The service
+account token is read from $GRAFANA_TOKEN.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			grafanaOpts.Namespaces = args
+			grafanaOpts.Environment = o.env
+			grafanaOpts.Token = os.Getenv("GRAFANA_TOKEN")
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return client.RunGrafanaAnnotations(ctx, grafanaOpts)
+		},
+	}
+	cmd.Flags().StringVar(&grafanaOpts.URL, "url", "", "Grafana URL, e.g. https://grafana.example.com")
+	cmd.Flags().StringVar(&grafanaOpts.DashboardUID, "dashboard-uid", "", "dashboard to annotate; empty annotates the organization")
+	cmd.Flags().Int64Var(&grafanaOpts.PanelID, "panel-id", 0, "panel of --dashboard-uid to annotate")
+	cmd.Flags().StringSliceVar(&grafanaOpts.Tags, "tag", nil, "extra tag for every annotation (repeatable)")
+	cmd.Flags().DurationVar(&grafanaOpts.Interval, "interval", 0, "how often to poll each namespace (default 5s)")
+	_ = cmd.MarkFlagRequired("url")
+	return cmd
+}
+
+func (o *cliOptions) chatNotifyCommand() *cobra.Command {
+	var routes []string
+	var addr string
+	var interval time.Duration
+	notifier := &ChatNotifier{}
+	cmd := &cobra.Command{
+		Use:   "chat-notify --route [FORMAT:][PATTERN=]URL... (NAMESPACE... | --listen ADDR)",
+		Short: "Post config changes to Slack or Microsoft Teams channels",
+		Long: `Post a summary of each config change, with its diff and author, to Slack
+or Microsoft Teams incoming webhooks.
+
+Each --route sends the namespaces matching PATTERN (a glob such as app/*;
+every namespace without one) to a channel's webhook; a change goes to
+every matching route. FORMAT is slack or teams, inferred from the URL when
+omitted. Changes are read by watching the NAMESPACE arguments in the -e
+environment, or, with --listen, received from a webhook subscription whose
+deliveries are verified with $LLM_CONFIG_WEBHOOK_SECRET when it is set.
+Masked secrets are never shown.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(routes) == 0 {
+				return errors.New("chat-notify: at least one --route is required")
+			}
+			if (len(args) == 0) == (addr == "") {
+				return errors.New("chat-notify: give either namespaces to watch or --listen")
+			}
+			for _, spec := range routes {
+				route, err := ParseChatRoute(spec)
+				if err != nil {
+					return fmt.Errorf("chat-notify: --route %w", err)
+				}
+				notifier.Routes = append(notifier.Routes, route)
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if addr != "" {
+				notifier.Secret = os.Getenv("LLM_CONFIG_WEBHOOK_SECRET")
+				return notifier.Serve(ctx, addr)
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			return notifier.Watch(ctx, client, args, WatchOptions{Environment: o.env, Interval: interval})
+		},
+	}
+	cmd.Flags().StringArrayVar(&routes, "route", nil, "[FORMAT:][PATTERN=]URL of a channel's incoming webhook (repeatable)")
+	cmd.Flags().StringVar(&addr, "listen", "", "receive webhook subscription deliveries on this address instead of watching")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "how often to poll each namespace (default 5s)")
+	return cmd
+}
+
+func (o *cliOptions) pagerDutyCommand() *cobra.Command {
+	var interval time.Duration
+	alerter := &PagerDutyAlerter{}
+	cmd := &cobra.Command{
+		Use:   "pagerduty NAMESPACE...",
+		Short: "Open PagerDuty incidents for changes made without review",
+		Long: `Open PagerDuty incidents for changes made without review.
+
+Watches the namespaces in the -e environment, e.g. production, and opens
+an incident for each change no applied change request accounts for. The
+incident's dedup key is llm-config:drift:<environment>:<namespace>:<key>,
+so repeated changes to a key add to one incident, which is resolved when
+a reviewed change next sets the key. The integration key is read from
+$PAGERDUTY_ROUTING_KEY.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			alerter.RoutingKey = os.Getenv("PAGERDUTY_ROUTING_KEY")
+			if alerter.RoutingKey == "" {
+				return errors.New("pagerduty: $PAGERDUTY_ROUTING_KEY is not set")
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return alerter.WatchDrift(ctx, client, args, WatchOptions{Environment: o.env, Interval: interval})
+		},
+	}
+	cmd.Flags().StringVar(&alerter.Source, "source", "", "source named in incidents (default llm-config-manager)")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "how often to poll each namespace (default 5s)")
+	return cmd
+}
+
+func (o *cliOptions) auditCommand() *cobra.Command {
+	var streamOpts AuditStreamOptions
+	var checkpoint, since string
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Print the audit log as NDJSON, optionally following it",
+		Long: `Print the audit log as NDJSON, one event per line, for log shippers that
+forward it to a SIEM.
+
+With --checkpoint, the cursor of the last event printed is kept in a file
+and the next run resumes after it; events since the last save (every 5s)
+may be printed twice after a crash, so deduplicate on "id". --follow keeps
+streaming new events and reconnects after failures until interrupted.
+--since takes a duration ago (24h) or an RFC 3339 time and only applies
+without a saved checkpoint. -n limits events to a namespace and those under
+it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since != "" {
+				if d, err := time.ParseDuration(since); err == nil {
+					streamOpts.Since = time.Now().Add(-d)
+				} else if streamOpts.Since, err = time.Parse(time.RFC3339, since); err != nil {
+					return fmt.Errorf("audit: --since %q must be a duration or an RFC 3339 time", since)
+				}
+			}
+			if checkpoint != "" {
+				streamOpts.Checkpoint = FileCheckpoint(checkpoint)
+			}
+			streamOpts.Namespace = o.namespace
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			return client.StreamAuditEvents(ctx, streamOpts, func(ev AuditEvent) error {
+				return enc.Encode(ev)
+			})
+		},
+	}
+	cmd.Flags().BoolVarP(&streamOpts.Follow, "follow", "f", false, "keep streaming new events")
+	cmd.Flags().StringVar(&checkpoint, "checkpoint", "", "file keeping the cursor between runs")
+	cmd.Flags().StringVar(&since, "since", "", "start at this time or duration ago when there is no checkpoint")
+	cmd.Flags().StringSliceVar(&streamOpts.Actions, "action", nil, "only these actions, e.g. config.set,secret.reveal")
+	return cmd
+}
+
+// agentSignals are the signals `agent` can send after a change
+var agentSignals = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// parseAgentRender reads an `agent --render` or `snapshot` spec into the
+// file it writes in dir
+func parseAgentRender(dir, spec string) (AgentFile, error) {
+	namespace, format, _ := strings.Cut(spec, ":")
+	if namespace == "" {
+		return AgentFile{}, fmt.Errorf("%q has no namespace", spec)
+	}
+	file := AgentFile{Namespace: namespace}
+
+	if path, ok := strings.CutPrefix(format, "template="); ok {
+		tmpl, err := ParseAgentTemplate(path)
+		if err != nil {
+			return AgentFile{}, err
+		}
+		name := filepath.Base(path)
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".tmpl"), ".tpl")
+		file.Template, file.Path = tmpl, filepath.Join(dir, name)
+		return file, nil
+	}
+
+	ext := map[ExportFormat]string{
+		ExportFormatJSON:    ".json",
+		ExportFormatYAML:    ".yaml",
+		ExportFormatTOML:    ".toml",
+		ExportFormatDotenv:  ".env",
+		ExportFormatSystemd: ".env",
+	}
+	file.Format = ExportFormat(format)
+	if format == "" {
+		file.Format = ExportFormatJSON
+	}
+	if _, ok := ext[file.Format]; !ok {
+		return AgentFile{}, fmt.Errorf("%q: format must be json, yaml, toml, dotenv, systemd, or template=PATH", spec)
+	}
+	file.Path = filepath.Join(dir, k8sName(namespace)+ext[file.Format])
+	return file, nil
+}
+
+func (o *cliOptions) aliasCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Redirect an old key name to a new one during a rename",
+	}
+	cmd.AddCommand(o.aliasSetCommand(), o.aliasListCommand(), o.aliasRemoveCommand())
+	return cmd
+}
+
+func (o *cliOptions) aliasSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "set OLD NEW",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Make OLD an alias of NEW; reads of OLD return NEW and are counted",
+		Args:              cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			targetNamespace, targetKey, err := o.splitKey(args[1])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			alias, err := client.CreateKeyAlias(cmd.Context(), namespace, key, targetNamespace, targetKey, o.user)
+			if err != nil {
+				return fmt.Errorf("alias set: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), alias, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s/%s now reads %s/%s\n", namespace, key, targetNamespace, targetKey)
+				return err
+			})
+		},
+	}
+}
+
+func (o *cliOptions) aliasListCommand() *cobra.Command {
+	var unusedFor time.Duration
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List aliases with how often and how recently they are read",
+		Long: `List aliases in -n and the namespaces under it, with how often and how
+recently they are read. --unused-for lists only aliases nothing has read for
+that long, which are safe to remove.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			aliases, err := client.ListKeyAliases(cmd.Context(), o.namespace)
+			if err != nil {
+				return fmt.Errorf("alias list: %w", err)
+			}
+			if unusedFor > 0 {
+				now := time.Now()
+				aliases = slices.DeleteFunc(aliases, func(a KeyAlias) bool { return !a.Unused(now, unusedFor) })
+			}
+			return o.out.render(cmd.OutOrStdout(), aliases, func(out io.Writer) error {
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ALIAS\tTARGET\tREADS\tLAST READ\tLAST READER")
+				for _, a := range aliases {
+					lastRead, lastReader := "never", ""
+					if !a.LastReadAt.IsZero() {
+						lastRead = a.LastReadAt.Local().Format(time.RFC3339)
+					}
+					if len(a.Readers) > 0 {
+						lastReader = a.Readers[0].Client
+					}
+					fmt.Fprintf(w, "%s/%s\t%s/%s\t%d\t%s\t%s\n", a.Namespace, a.Key, a.TargetNamespace, a.TargetKey, a.Reads, lastRead, lastReader)
+				}
+				return w.Flush()
+			})
+		},
+	}
+	cmd.Flags().DurationVar(&unusedFor, "unused-for", 0, "only aliases not read for this long")
+	return cmd
+}
+
+func (o *cliOptions) aliasRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm OLD",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Remove an alias; reads of OLD fail afterwards",
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			removed, err := client.DeleteKeyAlias(cmd.Context(), namespace, key)
+			if err != nil {
+				return fmt.Errorf("alias rm: %w", err)
+			}
+			if !removed {
+				return fmt.Errorf("alias rm: %s/%s is not an alias", namespace, key)
+			}
+			return nil
+		},
+	}
+}
+
+func (o *cliOptions) tagCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Group configs across namespaces with tags",
+	}
+	cmd.AddCommand(o.tagUpdateCommand("add"), o.tagUpdateCommand("rm"), o.tagListCommand())
+	return cmd
+}
+
+// tagUpdateCommand is `tag add` or `tag rm`
+func (o *cliOptions) tagUpdateCommand(action string) *cobra.Command {
+	short := "Tag a config without changing its value"
+	if action == "rm" {
+		short = "Untag a config without changing its value"
+	}
+	return &cobra.Command{
+		Use:               action + " KEY TAG...",
+		ValidArgsFunction: o.completeKey,
+		Short:             short,
+		Args:              cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			update := client.AddTags
+			if action == "rm" {
+				update = client.RemoveTags
+			}
+			tags, err := update(cmd.Context(), namespace, key, o.env, args[1:]...)
+			if err != nil {
+				return fmt.Errorf("tag %s: %w", action, err)
+			}
+			return o.out.render(cmd.OutOrStdout(), tags, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s/%s (%s) tags: %s\n", namespace, key, o.env, strings.Join(tags, " "))
+				return err
+			})
+		},
+	}
+}
+
+func (o *cliOptions) tagListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls [TAG]",
+		Short: "List the configs carrying TAG, or every tag in use with counts",
+		Long: `List the configs carrying TAG across namespaces, or without TAG, every tag
+in use with how many configs carry it. -n limits either to a namespace and
+those under it.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				counts, err := client.ListTags(cmd.Context(), o.namespace)
+				if err != nil {
+					return fmt.Errorf("tag ls: %w", err)
+				}
+				return o.out.render(cmd.OutOrStdout(), counts, func(out io.Writer) error {
+					w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(w, "TAG\tCONFIGS")
+					for _, tc := range counts {
+						fmt.Fprintf(w, "%s\t%d\n", tc.Tag, tc.Configs)
+					}
+					return w.Flush()
+				})
+			}
+
+			configs, err := client.ListByTag(cmd.Context(), args[0], TagListOptions{Namespace: o.namespace, Environment: o.env})
+			if err != nil {
+				return fmt.Errorf("tag ls: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), configs, func(out io.Writer) error {
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "KEY\tVERSION\tUPDATED BY\tVALUE")
+				for _, cfg := range configs {
+					fmt.Fprintf(w, "%s/%s\t%d\t%s\t%s\n", cfg.Namespace, cfg.Key, cfg.Version, cfg.Metadata.UpdatedBy, formatCLIValue(cfg.Value))
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func (o *cliOptions) templateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Publish templates and stamp out namespaces from them",
+	}
+	cmd.AddCommand(o.templatePushCommand(), o.templateListCommand(), o.templateInstantiateCommand())
+	return cmd
+}
+
+func (o *cliOptions) templatePushCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push FILE",
+		Short: "Publish a template from a YAML or JSON file as its next version",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := LoadTemplate(args[0])
+			if err != nil {
+				return fmt.Errorf("template push: %w", err)
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			stored, err := client.PutTemplate(cmd.Context(), *t, o.user)
+			if err != nil {
+				return fmt.Errorf("template push: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), stored, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "Pushed %s version %d\n", stored.Name, stored.Version)
+				return err
+			})
+		},
+	}
+}
+
+func (o *cliOptions) templateListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "List templates and their parameters",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			templates, err := client.ListTemplates(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("template ls: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), templates, func(out io.Writer) error {
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tVERSION\tNAMESPACE\tPARAMETERS")
+				for _, t := range templates {
+					var params []string
+					for _, p := range t.Parameters {
+						if p.Default == nil {
+							params = append(params, p.Name)
+						} else {
+							params = append(params, fmt.Sprintf("[%s=%s]", p.Name, formatCLIValue(p.Default)))
+						}
+					}
+					fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", t.Name, t.Version, t.Namespace, strings.Join(params, " "))
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func (o *cliOptions) templateInstantiateCommand() *cobra.Command {
+	var (
+		params, secretParams []string
+		version              int64
+		dryRun               bool
+		color                string
+	)
+	cmd := &cobra.Command{
+		Use:   "instantiate NAME",
+		Short: "Create a namespace for a new team or service from a template",
+		Long: `Create a namespace from the template NAME, owned by the template's owners
+and holding its configs in each of its environments. The namespace must not
+exist yet. --param sets a parameter; secret parameters are read from the
+environment variable named by --secret-param so they stay out of shell
+history. With --dry-run the configs are printed and nothing is created.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values := map[string]interface{}{}
+			for _, param := range params {
+				name, value, ok := strings.Cut(param, "=")
+				if !ok {
+					return fmt.Errorf("template instantiate: --param %q must be NAME=VALUE", param)
+				}
+				values[name] = value
+			}
+			for _, param := range secretParams {
+				name, envVar, ok := strings.Cut(param, "=")
+				if !ok {
+					return fmt.Errorf("template instantiate: --secret-param %q must be NAME=ENV_VAR", param)
+				}
+				value, ok := os.LookupEnv(envVar)
+				if !ok {
+					return fmt.Errorf("template instantiate: secret parameter %s requires environment variable %s", name, envVar)
+				}
+				values[name] = value
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			instance, plan, err := client.Instantiate(cmd.Context(), args[0], values, InstantiateOptions{Version: version, User: o.user, DryRun: dryRun})
+			if err != nil {
+				return fmt.Errorf("template instantiate: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			err = o.out.render(out, plan, func(w io.Writer) error {
+				return writeColoredDiff(w, plan, color)
+			})
+			if err != nil || dryRun || o.out.machineReadable() {
+				return err
+			}
+			_, err = fmt.Fprintf(out, "Created %s with %d config(s), owned by %s\n", instance.Namespace.Name, countChanges(plan), strings.Join(instance.Namespace.Owners, ", "))
+			return err
+		},
+	}
+	cmd.Flags().StringArrayVar(&params, "param", nil, "set a parameter, NAME=VALUE (repeatable)")
+	cmd.Flags().StringArrayVar(&secretParams, "secret-param", nil, "read a secret parameter from an environment variable, NAME=ENV_VAR (repeatable)")
+	cmd.Flags().Int64Var(&version, "version", 0, "template version to use (default latest)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the configs without creating anything")
+	cmd.Flags().StringVar(&color, "color", "auto", "color the diff: auto, always, or never")
+	return cmd
+}
+
+func (o *cliOptions) consumerCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "consumer",
+		Short: "Record which services read which keys, and query it",
+	}
+	cmd.AddCommand(o.consumerRegisterCommand(), o.consumerListCommand(), o.consumerRemoveCommand())
+	return cmd
+}
+
+func (o *cliOptions) consumerRegisterCommand() *cobra.Command {
+	var owner string
+	cmd := &cobra.Command{
+		Use:   "register SERVICE KEY...",
+		Short: "Declare the keys SERVICE reads, replacing its earlier registration",
+		Long: `Declare the keys SERVICE reads in the --env environment, replacing what it
+registered there before. Keys are NAMESPACE/KEY, or relative to -n; the key
+part may be a glob such as app/llm/* for a service reading a namespace.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reg := ConsumerRegistration{Service: args[0], Environment: o.env, Owner: owner}
+			for _, arg := range args[1:] {
+				namespace, key, err := o.splitKey(arg)
+				if err != nil {
+					return err
+				}
+				reg.Keys = append(reg.Keys, namespace+"/"+key)
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			stored, err := client.RegisterConsumer(cmd.Context(), reg)
+			if err != nil {
+				return fmt.Errorf("consumer register: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), stored, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s reads %d key(s) in %s\n", stored.Service, len(stored.Keys), stored.Environment)
+				return err
+			})
+		},
+	}
+	cmd.Flags().StringVar(&owner, "owner", "", "who to contact about the service, e.g. team:search")
+	return cmd
+}
+
+func (o *cliOptions) consumerListCommand() *cobra.Command {
+	var direct bool
+	cmd := &cobra.Command{
+		Use:               "ls KEY",
+		ValidArgsFunction: o.completeKey,
+		Short:             "List the services that read KEY in --env",
+		Long: `List the services that read KEY in the --env environment: those that
+registered it or a glob covering it, and, unless --direct, those reading an
+alias of it or a value that references it. Check it before changing or
+deleting a key.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			consumers, err := client.Consumers(cmd.Context(), namespace, key, ConsumerListOptions{Environment: o.env, DirectOnly: direct})
+			if err != nil {
+				return fmt.Errorf("consumer ls: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), consumers, func(out io.Writer) error {
+				if len(consumers) == 0 {
+					_, err := fmt.Fprintf(out, "No registered service reads %s/%s (%s)\n", namespace, key, o.env)
+					return err
+				}
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "SERVICE\tOWNER\tMATCHED\tVIA")
+				for _, c := range consumers {
+					via := "-"
+					if !c.Direct() {
+						via = strings.Join(c.Via, " <- ")
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Service, c.Owner, c.Pattern, via)
+				}
+				return w.Flush()
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&direct, "direct", false, "only services that read the key itself")
+	return cmd
+}
+
+func (o *cliOptions) consumerRemoveCommand() *cobra.Command {
+	var allEnvs bool
+	cmd := &cobra.Command{
+		Use:   "rm SERVICE",
+		Short: "Remove SERVICE's registration in --env",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			env := o.env
+			if allEnvs {
+				env = ""
+			}
+			removed, err := client.DeregisterConsumer(cmd.Context(), args[0], env)
+			if err != nil {
+				return fmt.Errorf("consumer rm: %w", err)
+			}
+			if !removed {
+				return fmt.Errorf("consumer rm: %s: %w", args[0], ErrNotFound)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s deregistered\n", args[0])
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&allEnvs, "all-envs", false, "remove the registration in every environment")
+	return cmd
+}
+
+func (o *cliOptions) ownersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "owners",
+		Short: "Manage which users and teams own keys, CODEOWNERS-style",
+	}
+	cmd.AddCommand(o.ownersSetCommand(), o.ownersShowCommand(), o.ownersOfCommand())
+	return cmd
+}
+
+func (o *cliOptions) ownersSetCommand() *cobra.Command {
+	var file, enforcement string
+	cmd := &cobra.Command{
+		Use:               "set NAMESPACE",
+		ValidArgsFunction: o.completeNamespace,
+		Short:             "Replace NAMESPACE's ownership rules from a CODEOWNERS-style file",
+		Long: `Replace NAMESPACE's ownership rules from a file of "PATTERN OWNER..."
+lines, where the last matching line wins. With --enforcement enforce, writes
+by non-owners are refused unless given --owner-override REASON.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("owners set: %w", err)
+			}
+			defer f.Close()
+			rules, err := ParseOwnershipRules(f)
+			if err != nil {
+				return fmt.Errorf("owners set: %s: %w", file, err)
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			policy, err := client.SetOwnership(cmd.Context(), OwnershipPolicy{
+				Namespace:   args[0],
+				Enforcement: OwnershipEnforcement(enforcement),
+				Rules:       rules,
+			}, o.user)
+			if err != nil {
+				return fmt.Errorf("owners set: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), policy, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s: %d rule(s), enforcement %s\n", policy.Namespace, len(policy.Rules), policy.Enforcement)
+				return err
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "rules file")
+	cmd.Flags().StringVar(&enforcement, "enforcement", string(OwnershipOff), "off, warn, or enforce")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+func (o *cliOptions) ownersShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "show NAMESPACE",
+		ValidArgsFunction: o.completeNamespace,
+		Short:             "Print NAMESPACE's ownership rules",
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			policy, err := client.GetOwnership(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("owners show: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), policy, func(out io.Writer) error {
+				fmt.Fprintf(out, "# %s, enforcement %s\n", policy.Namespace, policy.Enforcement)
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				for _, rule := range policy.Rules {
+					fmt.Fprintf(w, "%s\t%s\n", rule.Pattern, strings.Join(rule.Owners, " "))
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func (o *cliOptions) ownersOfCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "of KEY",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Print who owns KEY and which rule says so",
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			ownership, err := client.KeyOwners(cmd.Context(), namespace, key)
+			if err != nil {
+				return fmt.Errorf("owners of: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), ownership, func(w io.Writer) error {
+				source := "namespace owners"
+				if ownership.Pattern != "" {
+					source = fmt.Sprintf("rule %q of %s", ownership.Pattern, ownership.PolicyNamespace)
+				}
+				_, err := fmt.Fprintf(w, "%s/%s: %s (%s, enforcement %s)\n", namespace, key, strings.Join(ownership.Owners, ", "), source, ownership.Enforcement)
+				return err
+			})
+		},
+	}
+}
+
+func (o *cliOptions) blobCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blob",
+		Short: "Upload and download binary values such as tokenizer files",
+	}
+	cmd.AddCommand(o.blobPutCommand(), o.blobGetCommand())
+	return cmd
+}
+
+func (o *cliOptions) blobPutCommand() *cobra.Command {
+	var contentType string
+	var chunked bool
+	cmd := &cobra.Command{
+		Use:               "put KEY FILE",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Upload FILE (- for stdin) as a binary value",
+		Args:              cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			in := cmd.InOrStdin()
+			if args[1] != "-" {
+				f, err := os.Open(args[1])
+				if err != nil {
+					return fmt.Errorf("blob put: %w", err)
+				}
+				defer f.Close()
+				in = f
+				if contentType == "" {
+					contentType = mime.TypeByExtension(filepath.Ext(args[1]))
+				}
+			}
+			uploadOpts := BlobUploadOptions{ContentType: contentType, User: o.user}
+			if chunked {
+				report, err := client.UploadChunked(cmd.Context(), namespace, key, o.env, in, uploadOpts)
+				if err != nil {
+					return fmt.Errorf("blob put: %w", err)
+				}
+				return o.out.render(cmd.OutOrStdout(), report, func(w io.Writer) error {
+					_, err := fmt.Fprintf(w, "%s/%s (%s) set to version %d: %d bytes in %d chunks, %d new (%d bytes sent)\n",
+						namespace, key, o.env, report.Version, report.Size, report.Chunks, report.Uploaded, report.UploadedBytes)
+					return err
+				})
+			}
+			info, err := client.UploadBlob(cmd.Context(), namespace, key, o.env, in, uploadOpts)
+			if err != nil {
+				return fmt.Errorf("blob put: %w", err)
+			}
+			return o.out.render(cmd.OutOrStdout(), info, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s/%s (%s) set to version %d: %d bytes, sha256 %s\n", namespace, key, o.env, info.Version, info.Size, info.SHA256)
+				return err
+			})
+		},
+	}
+	cmd.Flags().StringVar(&contentType, "content-type", "", "content type (default from the file extension, else application/octet-stream)")
+	cmd.Flags().BoolVar(&chunked, "chunked", false, "store content-addressed chunks, sending only those the server lacks")
+	return cmd
+}
+
+func (o *cliOptions) blobGetCommand() *cobra.Command {
+	var version int64
+	var chunked bool
+	var cacheDir string
+	cmd := &cobra.Command{
+		Use:               "get KEY FILE",
+		ValidArgsFunction: o.completeKey,
+		Short:             "Download a binary value to FILE (- for stdout), verifying its checksum",
+		Args:              cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			switch {
+			case chunked && args[1] == "-":
+				_, err = client.DownloadChunked(cmd.Context(), namespace, key, o.env, cmd.OutOrStdout(), ChunkedDownloadOptions{Version: version, CacheDir: cacheDir})
+			case chunked:
+				err = writeFileAtomicFrom(args[1], 0o644, func(w io.Writer) error {
+					_, err := client.DownloadChunked(cmd.Context(), namespace, key, o.env, w, ChunkedDownloadOptions{Version: version, CacheDir: cacheDir})
+					return err
+				})
+			case args[1] == "-":
+				_, err = client.DownloadBlob(cmd.Context(), namespace, key, o.env, version, cmd.OutOrStdout())
+			default:
+				_, err = client.DownloadBlobFile(cmd.Context(), namespace, key, o.env, version, args[1], 0o644)
+			}
+			if err != nil {
+				return fmt.Errorf("blob get: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Int64Var(&version, "version", 0, "version to download (default current)")
+	cmd.Flags().BoolVar(&chunked, "chunked", false, "the value was stored with put --chunked")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "with --chunked, keep chunks here so later versions fetch only changes")
+	return cmd
+}
+
+func (o *cliOptions) secretCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Work with secret values",
+	}
+	cmd.AddCommand(o.secretEditCommand())
+	return cmd
+}
+
+func (o *cliOptions) secretEditCommand() *cobra.Command {
+	var clientSide bool
+	var keyFile string
+	cmd := &cobra.Command{
+		Use:   "edit NAMESPACE KEY",
+		Short: "Edit a secret in $EDITOR",
+		Long: `Edit a secret in $EDITOR.
+
+The secret is revealed (the token needs permission to read secrets),
+written to a private temporary file, and opened in $VISUAL or $EDITOR.
+Saving a changed file stores the new value; the file is overwritten and
+removed afterwards. Secrets encrypted client-side are decrypted and
+re-encrypted with the key from --key-file or $LLM_CONFIG_SECRET_KEY;
+--client-side encrypts a plain secret that way from now on.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, key, err := o.splitKey(args[0])
+			if len(args) == 2 {
+				namespace, key, err = args[0], args[1], nil
+			}
+			if err != nil {
+				return err
+			}
+
+			var secretKey SecretKey
+			if raw, err := readSecretKey(keyFile); err != nil {
+				return err
+			} else if raw != "" {
+				if secretKey, err = ParseSecretKey(raw); err != nil {
+					return err
+				}
+			}
+			if clientSide && secretKey == nil {
+				return errors.New("secret edit: --client-side needs --key-file or $LLM_CONFIG_SECRET_KEY")
+			}
+
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			current, wasClientSide := "", false
+			revealed, err := client.RevealSecret(cmd.Context(), namespace, key, o.env, secretKey)
+			switch {
+			case errors.Is(err, ErrNotFound):
+			case err != nil:
+				return err
+			default:
+				current = formatCLIValue(revealed.Value)
+				wasClientSide = revealed.ClientEncrypted
+				clientSide = clientSide || wasClientSide
+			}
+
+			edited, err := editInTempFile(cmd, key, current)
+			if err != nil {
+				return err
+			}
+			switch {
+			case edited == "":
+				return errors.New("secret edit: empty value, nothing saved")
+			case edited == current && clientSide == wasClientSide:
+				fmt.Fprintln(cmd.ErrOrStderr(), "Secret unchanged")
+				return nil
+			}
+
+			var cfg *ConfigResponse
+			if clientSide {
+				cfg, err = client.SetClientEncryptedSecret(cmd.Context(), namespace, key, o.env, o.user, edited, secretKey)
+			} else {
+				cfg, err = client.SetConfigContext(cmd.Context(), namespace, key, edited, o.env, o.user, true)
+			}
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s/%s (%s) saved as version %d\n", namespace, key, o.env, cfg.Version)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&clientSide, "client-side", false, "encrypt the value on this machine before sending it")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "file holding the hex or base64 client-side key")
+	return cmd
+}
+
+// readSecretKey returns the client-side key from keyFile, falling back to
+// $LLM_CONFIG_SECRET_KEY; empty when neither is set
+func readSecretKey(keyFile string) (string, error) {
+	if keyFile == "" {
+		return os.Getenv("LLM_CONFIG_SECRET_KEY"), nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("read key file: %w", err)
+	}
+	return string(data), nil
+}
+
+// editInTempFile opens content in the user's editor and returns the saved
+// text without its final newline. The file is private to the user, placed
+// in memory-backed /dev/shm where available, and zeroed before removal.
+func editInTempFile(cmd *cobra.Command, name, content string) (string, error) {
+	dir := ""
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		dir = "/dev/shm"
+	}
+	f, err := os.CreateTemp(dir, "llmconfig-"+dotenvKey(name)+"-*")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	defer shredFile(path)
+
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return "", err
+	}
+	if _, err := f.WriteString(content + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := strings.Fields(envOrDefault("VISUAL", envOrDefault("EDITOR", "vi")))
+	child := exec.CommandContext(cmd.Context(), editor[0], append(editor[1:], path)...)
+	child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := child.Run(); err != nil {
+		return "", fmt.Errorf("editor %s: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// shredFile overwrites a file with zeros before removing it, so the secret
+// does not linger in freed blocks
+func shredFile(path string) {
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		if info, err := f.Stat(); err == nil {
+			_, _ = f.Write(make([]byte, info.Size()))
+			_ = f.Sync()
+		}
+		f.Close()
+	}
+	_ = os.Remove(path)
+}
+
+func (o *cliOptions) uiCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ui",
+		Short: "Browse namespaces, inspect history, and edit values interactively",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			return client.RunTUI(cmd.Context(), o.namespace, o.env, o.user)
+		},
+	}
+}
+
+// completionTimeout bounds the API calls made while completing a word, so
+// a slow server never hangs the shell
+const completionTimeout = 3 * time.Second
+
+// completeKey completes a KEY argument from the server: the keys of
+// --namespace when it is set, otherwise namespaces and namespace/key paths
+func (o *cliOptions) completeKey(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	client, err := o.client()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), completionTimeout)
+	defer cancel()
+
+	listKeys := func(namespace string) []string {
+		configs, err := client.ListConfigsContext(ctx, namespace, o.env)
+		if err != nil {
+			return nil
+		}
+		keys := make([]string, len(configs))
+		for i, cfg := range configs {
+			keys[i] = cfg.Key
+		}
+		return keys
+	}
+
+	if o.namespace != "" {
+		return listKeys(o.namespace), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates []string
+	if i := strings.LastIndex(toComplete, "/"); i > 0 {
+		namespace := toComplete[:i]
+		for _, key := range listKeys(namespace) {
+			candidates = append(candidates, namespace+"/"+key)
+		}
+	}
+	namespaces, _ := client.ListNamespaces(ctx, toComplete)
+	for _, namespace := range namespaces {
+		candidates = append(candidates, namespace+"/")
+	}
+	// No trailing space, so a completed namespace can be followed by its key
+	return candidates, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeNamespace completes a namespace argument or flag from the server
+func (o *cliOptions) completeNamespace(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	client, err := o.client()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), completionTimeout)
+	defer cancel()
+
+	namespaces, err := client.ListNamespaces(ctx, toComplete)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return namespaces, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (o *cliOptions) loginCommand() *cobra.Command {
+	var issuer, clientID string
+	var scopes []string
+	var withToken bool
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in to --url and store the token in the OS keyring",
+		Long: `Log in to --url and store the token in the OS keyring.
+
+With --issuer (default $LLM_CONFIG_OAUTH_ISSUER) login runs the OAuth device
+flow: it prints a URL and code to approve in a browser, then stores the
+token and its refresh token. Later commands refresh an expired token
+automatically. Without an issuer, or with --with-token, a token is read
+from stdin instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if issuer != "" && !withToken {
+				login, err := deviceLogin(cmd.Context(), cmd.ErrOrStderr(), issuer, clientID, scopes)
+				if err != nil {
+					return fmt.Errorf("login: %w", err)
+				}
+				client := NewLLMConfigClient(o.url, login.Token.AccessToken)
+				if _, err := client.HealthCheckContext(cmd.Context()); err != nil {
+					return fmt.Errorf("login: %w", err)
+				}
+				if err := saveLogin(o.url, login); err != nil {
+					return fmt.Errorf("login: store token: %w", err)
+				}
+				fmt.Fprintln(cmd.ErrOrStderr(), "Logged in.")
+				return nil
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Token for %s: ", o.url)
+			line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			token := strings.TrimSpace(line)
+			if token == "" {
+				return errors.New("login: empty token")
+			}
+
+			client := NewLLMConfigClient(o.url, token)
+			if _, err := client.HealthCheckContext(cmd.Context()); err != nil {
+				return fmt.Errorf("login: %w", err)
+			}
+			if err := keyring.Set(keyringService, o.url, token); err != nil {
+				return fmt.Errorf("login: store token: %w", err)
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), "Token stored.")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&issuer, "issuer", os.Getenv("LLM_CONFIG_OAUTH_ISSUER"), "OpenID provider for the device flow")
+	cmd.Flags().StringVar(&clientID, "client-id", envOrDefault("LLM_CONFIG_OAUTH_CLIENT_ID", "llmconfig"), "OAuth client ID registered with the issuer")
+	cmd.Flags().StringSliceVar(&scopes, "scopes", []string{"openid", "offline_access"}, "OAuth scopes to request")
+	cmd.Flags().BoolVar(&withToken, "with-token", false, "read a token from stdin even when an issuer is set")
+	return cmd
+}
+
+func (o *cliOptions) logoutCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the token for --url from the OS keyring",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := keyring.Delete(keyringService, o.url)
+			if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+				return fmt.Errorf("logout: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+func (o *cliOptions) whoamiCommand() *cobra.Command {
+	var req TokenRequirements
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Print who the token authenticates as and what it may do",
+		Long: `Print who the token authenticates as, its scopes, namespace permissions,
+and expiry.
+
+With --require-* or --min-validity, whoami exits non-zero naming everything
+the token lacks, for container start scripts and CI jobs that should stop
+before using the wrong credentials.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			info, err := client.RequireToken(cmd.Context(), req)
+			if info == nil {
+				return fmt.Errorf("whoami: %w", err)
+			}
+			if renderErr := o.out.render(cmd.OutOrStdout(), info, func(w io.Writer) error {
+				return writeTokenInfo(w, info)
+			}); renderErr != nil {
+				return renderErr
+			}
+			if err != nil {
+				return fmt.Errorf("whoami: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&req.Scopes, "require-scope", nil, "fail unless the token has these scopes")
+	cmd.Flags().StringSliceVar(&req.Read, "require-read", nil, "fail unless the token can read these namespaces")
+	cmd.Flags().StringSliceVar(&req.Write, "require-write", nil, "fail unless the token can write these namespaces")
+	cmd.Flags().StringSliceVar(&req.Reveal, "require-reveal", nil, "fail unless the token can reveal secrets in these namespaces")
+	cmd.Flags().DurationVar(&req.MinValidity, "min-validity", 0, "fail if the token expires sooner than this")
+	return cmd
+}
+
+func writeTokenInfo(out io.Writer, info *TokenInfo) error {
+	fmt.Fprintf(out, "Principal: %s", info.Principal)
+	if info.Principal.Name != "" {
+		fmt.Fprintf(out, " (%s)", info.Principal.Name)
+	}
+	fmt.Fprintln(out)
+	if info.Organization != "" {
+		fmt.Fprintf(out, "Organization: %s\n", info.Organization)
+	}
+	fmt.Fprintf(out, "Scopes: %s\n", strings.Join(info.Scopes, " "))
+	if info.ExpiresAt.IsZero() {
+		fmt.Fprintln(out, "Expires: never")
+	} else {
+		fmt.Fprintf(out, "Expires: %s (in %s)\n", info.ExpiresAt.Format(time.RFC3339), time.Until(info.ExpiresAt).Round(time.Second))
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tACTIONS\tVIA")
+	for _, perm := range info.Permissions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", perm.Namespace, strings.Join(perm.Actions, ","), perm.Via)
+	}
+	return w.Flush()
+}
+
+// passthroughCommand exposes a flag-package subcommand under cobra, leaving
+// its flags to its own parser
+func passthroughCommand(name, short string, run func([]string) error) *cobra.Command {
+	return &cobra.Command{
+		Use:                name,
+		Short:              short,
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(args)
+		},
+	}
+}
+
+// client builds an API client, resolving the token from the flag, the
+// environment, or the keyring in that order
+func (o *cliOptions) client() (*LLMConfigClient, error) {
+	token := o.token
+	if token == "" {
+		token = os.Getenv("LLM_CONFIG_TOKEN")
+	}
+	if token == "" {
+		stored, err := keyringToken(o.url)
+		if err != nil {
+			return nil, err
+		}
+		token = stored
+	}
+	client := NewLLMConfigClient(o.url, token)
+	if o.socket != "" {
+		client.WithUnixSocket(o.socket)
+	}
+	return client, nil
+}
+
+// splitKey returns the namespace and key an argument names: the key within
+// --namespace when it is set, otherwise the last segment of namespace/key
+func (o *cliOptions) splitKey(arg string) (namespace, key string, err error) {
+	if o.namespace != "" {
+		return o.namespace, arg, nil
+	}
+	i := strings.LastIndex(arg, "/")
+	if i <= 0 || i == len(arg)-1 {
+		return "", "", fmt.Errorf("%q is not namespace/key; give the full path or set --namespace", arg)
+	}
+	return arg[:i], arg[i+1:], nil
+}
+
+// parseCLIValue decodes raw as JSON, so numbers, booleans, and objects keep
+// their type, and falls back to the string itself
+func parseCLIValue(raw string, asString bool) interface{} {
+	if asString {
+		return raw
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return raw
+	}
+	return value
+}
+
+// formatCLIValue renders a value on one line: strings bare, anything else
+// as JSON
+func formatCLIValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// writeConfigTable prints configs as the KEY/VERSION/UPDATED BY/VALUE table
+// of `list`
+func writeConfigTable(out io.Writer, configs []ConfigResponse) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVERSION\tUPDATED BY\tVALUE")
+	for _, cfg := range configs {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", cfg.Key, cfg.Version, cfg.Metadata.UpdatedBy, formatCLIValue(cfg.Value))
+	}
+	return w.Flush()
+}
+
+func writeValue(w io.Writer, value interface{}) error {
+	if _, ok := value.(string); ok {
+		_, err := fmt.Fprintln(w, value)
+		return err
+	}
+	return writeJSON(w, value)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
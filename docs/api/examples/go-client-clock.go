@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the client's source of time. Retry backoff, rate-limit waits,
+// watcher polling, and usage timestamps all go through it, so tests can
+// substitute a FakeClock instead of really sleeping.
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for d or until ctx is done
+	Sleep(ctx context.Context, d time.Duration) error
+	// After delivers the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// SetClock replaces the client's clock; nil restores the real clock
+func (c *LLMConfigClient) SetClock(clock Clock) {
+	if clock == nil {
+		clock = realClock{}
+	}
+	c.clock = clock
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// FakeClock is a manually driven Clock for tests. Sleep returns at once,
+// advancing the clock and recording the duration; After fires when Advance
+// (or a Sleep) moves the clock past its deadline.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	sleeps  []time.Duration
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock returns a FakeClock reading start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now implements Clock
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Sleep implements Clock without blocking
+func (f *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.sleeps = append(f.sleeps, d)
+	f.mu.Unlock()
+	f.Advance(d)
+	return nil
+}
+
+// After implements Clock
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.waiters = append(f.waiters, fakeWaiter{deadline: f.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward, firing due After channels in deadline
+// order
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+
+	sort.SliceStable(f.waiters, func(i, j int) bool { return f.waiters[i].deadline.Before(f.waiters[j].deadline) })
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- f.now
+	}
+	f.waiters = pending
+}
+
+// Sleeps returns every duration passed to Sleep, in order
+func (f *FakeClock) Sleeps() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Duration(nil), f.sleeps...)
+}
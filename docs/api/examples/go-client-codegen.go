@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// CodegenOptions controls typed accessor generation
+type CodegenOptions struct {
+	// Package is the generated package name (default "llmconfig")
+	Package string
+	// Namespace and Environment are recorded in the generated source
+	Namespace   string
+	Environment string
+	// Types overrides inferred key types; values are one of string, int,
+	// float, bool, strings, array, or object
+	Types map[string]string
+	// UseSchemas types keys by the JSON Schema bound to them, where there
+	// is one, rather than by their current value; Types still wins
+	UseSchemas bool
+}
+
+// codegenField is one generated accessor
+type codegenField struct {
+	Key    string
+	Method string
+	Const  string
+	GoType string
+	Getter string
+}
+
+// codegenTypes maps schema type names to Go types and generated getters
+var codegenTypes = map[string][2]string{
+	"string":  {"string", "stringValue"},
+	"int":     {"int64", "intValue"},
+	"float":   {"float64", "floatValue"},
+	"bool":    {"bool", "boolValue"},
+	"strings": {"[]string", "stringsValue"},
+	"array":   {"[]interface{}", "arrayValue"},
+	"object":  {"map[string]interface{}", "objectValue"},
+}
+
+// GenerateTypedConfigPackage fetches a namespace and generates a Go package
+// with one typed accessor per key
+func (c *LLMConfigClient) GenerateTypedConfigPackage(ctx context.Context, opts CodegenOptions) ([]byte, error) {
+	configs, err := c.ListConfigsContext(ctx, opts.Namespace, opts.Environment)
+	if err != nil {
+		return nil, err
+	}
+	if opts.UseSchemas {
+		types := map[string]string{}
+		for _, cfg := range configs {
+			bound, err := c.GetBoundSchema(ctx, opts.Namespace, cfg.Key)
+			if err != nil {
+				return nil, fmt.Errorf("schema of %s: %w", cfg.Key, err)
+			}
+			if bound == nil {
+				continue
+			}
+			if t, ok := schemaCodegenType(bound.Schema); ok {
+				types[cfg.Key] = t
+			}
+		}
+		for key, t := range opts.Types {
+			types[key] = t
+		}
+		opts.Types = types
+	}
+	return GenerateTypedConfig(configs, opts)
+}
+
+// schemaCodegenType picks the generated type for values of a JSON Schema,
+// if its type settles one
+func schemaCodegenType(schema []byte) (string, bool) {
+	var s struct {
+		Type  interface{} `json:"type"`
+		Items struct {
+			Type interface{} `json:"type"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return "", false
+	}
+	switch schemaTypeName(s.Type) {
+	case "string":
+		return "string", true
+	case "integer":
+		return "int", true
+	case "number":
+		return "float", true
+	case "boolean":
+		return "bool", true
+	case "object":
+		return "object", true
+	case "array":
+		if schemaTypeName(s.Items.Type) == "string" {
+			return "strings", true
+		}
+		return "array", true
+	}
+	return "", false
+}
+
+// schemaTypeName is a schema's type, the one besides "null" of a list
+func schemaTypeName(t interface{}) string {
+	switch t := t.(type) {
+	case string:
+		return t
+	case []interface{}:
+		var name string
+		for _, item := range t {
+			if item, ok := item.(string); ok && item != "null" {
+				if name != "" {
+					return ""
+				}
+				name = item
+			}
+		}
+		return name
+	}
+	return ""
+}
+
+// ReadConfigSnapshot reads a namespace saved as a file: an object of key ->
+// value in JSON, YAML, or TOML, as written by export, snapshot, or the
+// agent, or a JSON or YAML list of configs, as printed by list -o json
+func ReadConfigSnapshot(path string) ([]ConfigResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		var values map[string]interface{}
+		err = toml.Unmarshal(data, &values)
+		decoded = values
+	case ".json", ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &decoded)
+	default:
+		return nil, fmt.Errorf("%s: snapshot must be .json, .yaml, or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch decoded := jsonNumbers(canonicalValue(decoded)).(type) {
+	case map[string]interface{}:
+		configs := make([]ConfigResponse, 0, len(decoded))
+		for _, key := range sortedMapKeys(decoded) {
+			configs = append(configs, ConfigResponse{Key: key, Value: decoded[key]})
+		}
+		return configs, nil
+	case []interface{}:
+		encoded, err := json.Marshal(decoded)
+		if err != nil {
+			return nil, err
+		}
+		var configs []ConfigResponse
+		if err := json.Unmarshal(encoded, &configs); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return configs, nil
+	}
+	return nil, fmt.Errorf("%s: want an object of keys or a list of configs", path)
+}
+
+// GenerateTypedConfig emits gofmt'd Go source exposing a Config type with
+// typed accessors (Model() string, Temperature() float64, ...), key constants,
+// and a Validate method reporting missing or mistyped keys
+func GenerateTypedConfig(configs []ConfigResponse, opts CodegenOptions) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "llmconfig"
+	}
+
+	types := map[string]string{}
+	for _, cfg := range configs {
+		types[cfg.Key] = inferCodegenType(cfg.Value)
+	}
+	for key, t := range opts.Types {
+		types[key] = t
+	}
+
+	reserved := map[string]bool{"New": true, "Validate": true, "Values": true}
+	used := map[string]string{}
+	var fields []codegenField
+
+	for _, key := range sortedMapKeys(types) {
+		goType, ok := codegenTypes[types[key]]
+		if !ok {
+			return nil, fmt.Errorf("key %s: unknown type %q", key, types[key])
+		}
+
+		method := goIdentifier(key)
+		if reserved[method] {
+			method += "Value"
+		}
+		if prev, dup := used[method]; dup {
+			return nil, fmt.Errorf("keys %s and %s both map to accessor %s", prev, key, method)
+		}
+		used[method] = key
+
+		fields = append(fields, codegenField{
+			Key:    key,
+			Method: method,
+			Const:  "Key" + method,
+			GoType: goType[0],
+			Getter: goType[1],
+		})
+	}
+
+	var buf bytes.Buffer
+	err := codegenTemplate.Execute(&buf, map[string]interface{}{
+		"Package":     opts.Package,
+		"Namespace":   opts.Namespace,
+		"Environment": opts.Environment,
+		"Fields":      fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated source: %w", err)
+	}
+	return src, nil
+}
+
+// inferCodegenType picks a schema type for a current value
+func inferCodegenType(v interface{}) string {
+	switch val := normalizeExportValue(v).(type) {
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		for _, item := range val {
+			if _, ok := item.(string); !ok {
+				return "array"
+			}
+		}
+		return "strings"
+	default:
+		return "string"
+	}
+}
+
+// goInitialisms are rendered upper-case in generated identifiers
+var goInitialisms = map[string]bool{
+	"API": true, "ID": true, "URL": true, "URI": true, "HTTP": true, "HTTPS": true,
+	"JSON": true, "TTL": true, "LLM": true, "SQL": true, "TLS": true, "UUID": true,
+}
+
+// goIdentifier turns a config key such as "max_tokens" or "api-url" into an
+// exported Go identifier ("MaxTokens", "APIURL")
+func goIdentifier(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		upper := strings.ToUpper(word)
+		if goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		first, size := utf8.DecodeRuneInString(word)
+		b.WriteRune(unicode.ToUpper(first))
+		b.WriteString(strings.ToLower(word[size:]))
+	}
+
+	ident := b.String()
+	if first, _ := utf8.DecodeRuneInString(ident); ident == "" || unicode.IsDigit(first) {
+		ident = "K" + ident
+	}
+	return ident
+}
+
+// sortedMapKeys returns the keys of m in ascending order
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runGenCommand implements `go run . gen`, intended for go:generate lines
+// such as:
+//
+//	//go:generate go run ../path/to/examples gen -namespace app/llm -env production -package llmcfg -out llmcfg/config_gen.go
+//
+// The server URL and token come from LLM_CONFIG_URL and LLM_CONFIG_TOKEN.
+// Keys are typed by their bound JSON Schemas, then by their values. With
+// -snapshot, the namespace is read from a saved file instead, so builds
+// without access to the server regenerate the same package:
+//
+//	//go:generate go run ../path/to/examples gen -namespace app/llm -snapshot llm.production.json -package llmcfg -out llmcfg/config_gen.go
+func runGenCommand(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	namespace := fs.String("namespace", "", "namespace to introspect")
+	env := fs.String("env", "production", "environment to introspect")
+	pkg := fs.String("package", "llmconfig", "generated package name")
+	out := fs.String("out", "", "output file (stdout when empty)")
+	typesFile := fs.String("types", "", "YAML/JSON file mapping keys to types, overriding inference")
+	snapshot := fs.String("snapshot", "", "read the namespace from this JSON, YAML, or TOML file instead of the server")
+	schemas := fs.Bool("schemas", true, "type keys by their bound JSON Schemas")
+	baseURL := fs.String("url", envOrDefault("LLM_CONFIG_URL", "http://localhost:8080/api/v1"), "API base URL")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *namespace == "" {
+		return fmt.Errorf("gen: -namespace is required")
+	}
+
+	opts := CodegenOptions{Package: *pkg, Namespace: *namespace, Environment: *env}
+	if *typesFile != "" {
+		data, err := os.ReadFile(*typesFile)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &opts.Types); err != nil {
+			return fmt.Errorf("gen: parse %s: %w", *typesFile, err)
+		}
+	}
+
+	var src []byte
+	if *snapshot != "" {
+		configs, err := ReadConfigSnapshot(*snapshot)
+		if err != nil {
+			return fmt.Errorf("gen: %w", err)
+		}
+		if src, err = GenerateTypedConfig(configs, opts); err != nil {
+			return err
+		}
+	} else {
+		opts.UseSchemas = *schemas
+		client := NewLLMConfigClient(*baseURL, os.Getenv("LLM_CONFIG_TOKEN"))
+		var err error
+		if src, err = client.GenerateTypedConfigPackage(context.Background(), opts); err != nil {
+			return err
+		}
+	}
+
+	var err error
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+func envOrDefault(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+var codegenTemplate = template.Must(template.New("codegen").Parse(`// Code generated by llm-config-manager codegen; DO NOT EDIT.
+
+// Package {{.Package}} provides typed accessors for the {{printf "%q" .Namespace}} namespace
+{{- if .Environment}} ({{.Environment}}){{end}}.
+package {{.Package}}
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Namespace is the namespace this package was generated from
+const Namespace = {{printf "%q" .Namespace}}
+
+// Config keys
+const (
+{{- range .Fields}}
+	{{.Const}} = {{printf "%q" .Key}}
+{{- end}}
+)
+
+// Config wraps a namespace snapshot (key -> decoded JSON value)
+type Config struct {
+	values map[string]interface{}
+}
+
+// New wraps values, typically built from ListConfigs results
+func New(values map[string]interface{}) *Config {
+	return &Config{values: values}
+}
+
+// Values returns the underlying snapshot
+func (c *Config) Values() map[string]interface{} {
+	return c.values
+}
+{{range .Fields}}
+// {{.Method}} returns {{printf "%q" .Key}}
+func (c *Config) {{.Method}}() {{.GoType}} {
+	v, _ := {{.Getter}}(c.values[{{.Const}}])
+	return v
+}
+{{end}}
+// Validate reports every key that is missing or has an unexpected type
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(key string, ok bool) {
+		if _, present := c.values[key]; !present {
+			problems = append(problems, key+": missing")
+		} else if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unexpected type %T", key, c.values[key]))
+		}
+	}
+{{- range .Fields}}
+	_, ok{{.Method}} := {{.Getter}}(c.values[{{.Const}}])
+	check({{.Const}}, ok{{.Method}})
+{{- end}}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("%s config invalid: %s", Namespace, strings.Join(problems, "; "))
+}
+
+func stringValue(v interface{}) (string, bool) {
+	s, ok := v.(string)
+	return s, ok
+}
+
+func intValue(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), n == float64(int64(n))
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func floatValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func boolValue(v interface{}) (bool, bool) {
+	b, ok := v.(bool)
+	return b, ok
+}
+
+func stringsValue(v interface{}) ([]string, bool) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+func arrayValue(v interface{}) ([]interface{}, bool) {
+	items, ok := v.([]interface{})
+	return items, ok
+}
+
+func objectValue(v interface{}) (map[string]interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	return m, ok
+}
+`))
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// The sync only touches objects labelled as its own, so it never clobbers
+// a ConfigMap someone else created under the same name
+const (
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	managedBySync  = "llmconfig-sync"
+)
+
+// defaultSyncInterval is how often the sync re-reads the server when
+// ConfigMapSyncOptions.Interval is zero
+const defaultSyncInterval = 30 * time.Second
+
+// ConfigMapSyncTarget mirrors one namespace into a ConfigMap, plus a Secret
+// of the same name when it has secret keys
+type ConfigMapSyncTarget struct {
+	Namespace     string
+	KubeNamespace string
+	// Name of the objects; the namespace with "/" replaced by "-" when empty
+	Name string
+}
+
+func (t ConfigMapSyncTarget) objectName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return k8sName(t.Namespace)
+}
+
+// ConfigMapSyncOptions configures ConfigMapSyncer and RunConfigMapSync
+type ConfigMapSyncOptions struct {
+	Environment string
+	Targets     []ConfigMapSyncTarget
+	// Interval between reads of the server; 30s when zero
+	Interval time.Duration
+	// SecretKeys are written to the Secrets rather than the ConfigMaps
+	SecretKeys []string
+	// RevealSecrets mirrors the plaintext of secrets the API masks into the
+	// Secrets, decrypting client-side encrypted ones with SecretKey.
+	// Otherwise masked secrets are left out.
+	RevealSecrets bool
+	SecretKey     SecretKey
+
+	MetricsAddr    string
+	ProbeAddr      string
+	LeaderElection bool
+}
+
+// ConfigMapSyncer keeps ConfigMaps and Secrets equal to namespaces on the
+// config server. It re-reads the server every interval, and because it
+// watches the objects, an edit made with kubectl is reverted as soon as it
+// lands. Objects carry the source annotations of `export --format
+// configmap`, so a value in the cluster can be traced to its key version.
+type ConfigMapSyncer struct {
+	client.Client
+	Config *LLMConfigClient
+	ConfigMapSyncOptions
+
+	writes ownWrites
+}
+
+// ownWrites remembers the resourceVersion each of the syncer's own writes
+// produced, so the watch events they cause don't trigger another read of
+// the server
+type ownWrites struct {
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+func ownWriteKey(obj client.Object) string {
+	return fmt.Sprintf("%T %s/%s", obj, obj.GetNamespace(), obj.GetName())
+}
+
+// record notes obj as just written by the syncer
+func (w *ownWrites) record(obj client.Object) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.versions == nil {
+		w.versions = map[string]string{}
+	}
+	w.versions[ownWriteKey(obj)] = obj.GetResourceVersion()
+}
+
+// caused reports whether obj is the state the syncer's last write of it
+// left, so an event carrying it needs no reconcile
+func (w *ownWrites) caused(obj client.Object) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	version, ok := w.versions[ownWriteKey(obj)]
+	return ok && version == obj.GetResourceVersion()
+}
+
+// Reconcile brings the objects of one target in line with the server
+func (s *ConfigMapSyncer) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var target *ConfigMapSyncTarget
+	for i, t := range s.Targets {
+		if t.KubeNamespace == req.Namespace && t.objectName() == req.Name {
+			target = &s.Targets[i]
+			break
+		}
+	}
+	if target == nil {
+		return ctrl.Result{}, nil
+	}
+
+	objects, err := s.render(ctx, *target)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = s.syncObject(ctx, "ConfigMap", cm, target.KubeNamespace, objects.ConfigMeta, func() bool {
+		changed := !maps.Equal(cm.Data, objects.ConfigData) || len(cm.BinaryData) > 0
+		cm.Data, cm.BinaryData = objects.ConfigData, nil
+		return changed
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	secretData := make(map[string][]byte, len(objects.SecretData))
+	for key, value := range objects.SecretData {
+		secretData[key] = []byte(value)
+	}
+	secret := &corev1.Secret{}
+	if len(secretData) == 0 {
+		err = s.deleteSecret(ctx, req.NamespacedName)
+	} else {
+		err = s.syncObject(ctx, "Secret", secret, target.KubeNamespace, objects.SecretMeta, func() bool {
+			changed := !maps.EqualFunc(secret.Data, secretData, bytes.Equal) || len(secret.StringData) > 0
+			secret.Data, secret.StringData, secret.Type = secretData, nil, corev1.SecretTypeOpaque
+			return changed
+		})
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// render reads target's namespace and splits it into ConfigMap and Secret
+// data
+func (s *ConfigMapSyncer) render(ctx context.Context, target ConfigMapSyncTarget) (*k8sObjects, error) {
+	configs, err := s.Config.ListConfigsContext(ctx, target.Namespace, s.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("list %s (%s): %w", target.Namespace, s.Environment, err)
+	}
+	secretKeys := append([]string(nil), s.SecretKeys...)
+	if s.RevealSecrets {
+		for i, cfg := range configs {
+			if cfg.Value != encryptedPlaceholder {
+				continue
+			}
+			secret, err := s.Config.RevealSecret(ctx, target.Namespace, cfg.Key, s.Environment, s.SecretKey)
+			if err != nil {
+				return nil, fmt.Errorf("reveal %s/%s: %w", target.Namespace, cfg.Key, err)
+			}
+			configs[i].Value = secret.Value
+			secretKeys = append(secretKeys, cfg.Key)
+		}
+	}
+	return renderK8sData(target.Namespace, s.Environment, configs, K8sManifestOptions{
+		Name:              target.objectName(),
+		KubeNamespace:     target.KubeNamespace,
+		SecretKeys:        secretKeys,
+		SkipMaskedSecrets: true,
+		Labels:            map[string]string{labelManagedBy: managedBySync},
+	})
+}
+
+// syncObject creates obj from meta, or updates it when setData or the
+// source annotations differ. setData puts the desired data into obj and
+// reports whether that changed it.
+func (s *ConfigMapSyncer) syncObject(ctx context.Context, kind string, obj client.Object, kubeNamespace string, meta k8sObjectMeta, setData func() bool) error {
+	key := types.NamespacedName{Namespace: kubeNamespace, Name: meta.Name}
+	err := s.Get(ctx, key, obj)
+	if apierrors.IsNotFound(err) {
+		obj.SetNamespace(kubeNamespace)
+		obj.SetName(meta.Name)
+		obj.SetLabels(maps.Clone(meta.Labels))
+		obj.SetAnnotations(maps.Clone(meta.Annotations))
+		setData()
+		log.Printf("llm-config sync: creating %s %s at version %s", kind, key, meta.Annotations[annotationSourceVersion])
+		err = s.Create(ctx, obj)
+		if apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("%s %s exists and is not labelled %s=%s; not overwriting it", kind, key, labelManagedBy, managedBySync)
+		}
+		if err == nil {
+			s.writes.record(obj)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if obj.GetLabels()[labelManagedBy] != managedBySync {
+		return fmt.Errorf("%s %s exists and is not labelled %s=%s; not overwriting it", kind, key, labelManagedBy, managedBySync)
+	}
+
+	previousVersion := obj.GetAnnotations()[annotationSourceVersion]
+	previousKeys := obj.GetAnnotations()[annotationKeyVersions]
+	changed := setData()
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for name, value := range meta.Annotations {
+		if annotations[name] != value {
+			annotations[name] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	obj.SetAnnotations(annotations)
+
+	if previousKeys == meta.Annotations[annotationKeyVersions] {
+		log.Printf("llm-config sync: %s %s was edited directly; restoring version %s", kind, key, previousVersion)
+	} else {
+		log.Printf("llm-config sync: updating %s %s from version %s to %s", kind, key, previousVersion, meta.Annotations[annotationSourceVersion])
+	}
+	if err := s.Update(ctx, obj); err != nil {
+		return err
+	}
+	s.writes.record(obj)
+	return nil
+}
+
+// deleteSecret removes a synced Secret once its namespace has no secret
+// keys left
+func (s *ConfigMapSyncer) deleteSecret(ctx context.Context, key types.NamespacedName) error {
+	var secret corev1.Secret
+	if err := s.Get(ctx, key, &secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	log.Printf("llm-config sync: deleting Secret %s; no secret keys left", key)
+	return client.IgnoreNotFound(s.Delete(ctx, &secret))
+}
+
+// SetupWithManager syncs every target once at start, then on each
+// interval and whenever someone else changes one of the synced objects
+func (s *ConfigMapSyncer) SetupWithManager(mgr ctrl.Manager) error {
+	initial := make(chan event.GenericEvent, len(s.Targets))
+	for _, t := range s.Targets {
+		initial <- event.GenericEvent{Object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: t.KubeNamespace, Name: t.objectName()}}}
+	}
+	notOwnWrite := builder.WithPredicates(predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return !s.writes.caused(e.Object) },
+		UpdateFunc: func(e event.UpdateEvent) bool { return !s.writes.caused(e.ObjectNew) },
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("configmap-sync").
+		Watches(&corev1.ConfigMap{}, &handler.EnqueueRequestForObject{}, notOwnWrite).
+		Watches(&corev1.Secret{}, &handler.EnqueueRequestForObject{}, notOwnWrite).
+		WatchesRawSource(source.Channel(initial, &handler.EnqueueRequestForObject{})).
+		Complete(s)
+}
+
+// RunConfigMapSync mirrors opts.Targets into the cluster until ctx is done.
+// Only objects labelled as the sync's own, in the targets' Kubernetes
+// namespaces, are cached and watched.
+func RunConfigMapSync(ctx context.Context, c *LLMConfigClient, opts ConfigMapSyncOptions) error {
+	ctrl.SetLogger(logrFromSlog())
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+	managed := labels.SelectorFromSet(labels.Set{labelManagedBy: managedBySync})
+	namespaces := map[string]cache.Config{}
+	for _, t := range opts.Targets {
+		namespaces[t.KubeNamespace] = cache.Config{}
+	}
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: opts.MetricsAddr},
+		HealthProbeBindAddress: opts.ProbeAddr,
+		LeaderElection:         opts.LeaderElection,
+		LeaderElectionID:       "llmconfig-sync.llm-config-manager.io",
+		Cache: cache.Options{
+			DefaultNamespaces: namespaces,
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.ConfigMap{}: {Label: managed},
+				&corev1.Secret{}:    {Label: managed},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return err
+	}
+
+	syncer := &ConfigMapSyncer{Client: mgr.GetClient(), Config: c, ConfigMapSyncOptions: opts}
+	if err := syncer.SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+	return mgr.Start(ctx)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+)
+
+// Constraint is a lightweight declarative rule attached to a key. Unset
+// fields are not checked.
+type Constraint struct {
+	// Enum lists the only allowed values
+	Enum []interface{} `json:"enum,omitempty"`
+	// Min and Max bound numeric values (inclusive)
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Pattern is a regular expression string values must match
+	Pattern string `json:"pattern,omitempty"`
+	// MinLength and MaxLength bound string length (in characters) and array length
+	MinLength *int `json:"min_length,omitempty"`
+	MaxLength *int `json:"max_length,omitempty"`
+}
+
+// KeyConstraint is a constraint as stored by the server
+type KeyConstraint struct {
+	Namespace  string     `json:"namespace"`
+	Key        string     `json:"key"`
+	Constraint Constraint `json:"constraint"`
+}
+
+// constraintRegistry holds constraints enforced locally before writes
+type constraintRegistry struct {
+	mu      sync.RWMutex
+	entries map[constraintKey]compiledConstraint
+}
+
+// constraintKey is a constrained key within an organization scope; an
+// empty organization applies to every organization
+type constraintKey struct {
+	organization, namespace, key string
+}
+
+type compiledConstraint struct {
+	Constraint
+	pattern *regexp.Regexp
+}
+
+func newConstraintRegistry() *constraintRegistry {
+	return &constraintRegistry{entries: map[constraintKey]compiledConstraint{}}
+}
+
+// Check returns every rule value violates
+func (c Constraint) Check(value interface{}) ([]ValidationProblem, error) {
+	compiled, err := compileConstraint(c)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.check(value), nil
+}
+
+func compileConstraint(c Constraint) (compiledConstraint, error) {
+	compiled := compiledConstraint{Constraint: c}
+	if c.Pattern != "" {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid constraint pattern %q: %w", c.Pattern, err)
+		}
+		compiled.pattern = re
+	}
+	return compiled, nil
+}
+
+func (c compiledConstraint) check(value interface{}) []ValidationProblem {
+	var problems []ValidationProblem
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.Enum) > 0 {
+		normalized := canonicalValue(value)
+		allowed := false
+		for _, candidate := range c.Enum {
+			if reflect.DeepEqual(canonicalValue(candidate), normalized) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			fail("value %v is not one of %v", value, c.Enum)
+		}
+	}
+
+	if c.Min != nil || c.Max != nil {
+		n, ok := numericValue(value)
+		switch {
+		case !ok:
+			fail("expected a number, got %s", valueTypeName(value))
+		case c.Min != nil && n < *c.Min:
+			fail("value %v is below minimum %v", n, *c.Min)
+		case c.Max != nil && n > *c.Max:
+			fail("value %v is above maximum %v", n, *c.Max)
+		}
+	}
+
+	if c.pattern != nil {
+		s, ok := value.(string)
+		if !ok {
+			fail("expected a string for pattern %q, got %s", c.Pattern, valueTypeName(value))
+		} else if !c.pattern.MatchString(s) {
+			fail("value %q does not match pattern %q", s, c.Pattern)
+		}
+	}
+
+	if c.MinLength != nil || c.MaxLength != nil {
+		var length int
+		switch v := value.(type) {
+		case string:
+			length = utf8.RuneCountInString(v)
+		case []interface{}:
+			length = len(v)
+		default:
+			fail("length constraint requires a string or array, got %s", valueTypeName(value))
+			return problems
+		}
+		if c.MinLength != nil && length < *c.MinLength {
+			fail("length %d is below minimum %d", length, *c.MinLength)
+		}
+		if c.MaxLength != nil && length > *c.MaxLength {
+			fail("length %d is above maximum %d", length, *c.MaxLength)
+		}
+	}
+
+	return problems
+}
+
+// numericValue extracts a float64 from any decoded or Go numeric value
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// AddConstraint enforces c on namespace/key for writes made by this client,
+// in every organization
+func (c *LLMConfigClient) AddConstraint(namespace, key string, constraint Constraint) error {
+	return c.addConstraint(constraintKey{"", namespace, key}, constraint)
+}
+
+// AddConstraintContext is AddConstraint for writes made in the
+// organization of ctx only
+func (c *LLMConfigClient) AddConstraintContext(ctx context.Context, namespace, key string, constraint Constraint) error {
+	return c.addConstraint(constraintKey{c.organizationScopeID(ctx), namespace, key}, constraint)
+}
+
+func (c *LLMConfigClient) addConstraint(k constraintKey, constraint Constraint) error {
+	compiled, err := compileConstraint(constraint)
+	if err != nil {
+		return err
+	}
+	c.constraints.mu.Lock()
+	defer c.constraints.mu.Unlock()
+	c.constraints.entries[k] = compiled
+	return nil
+}
+
+// checkConstraints returns the local constraint violations for a write
+// made with ctx. A constraint added for its organization takes precedence
+// over one added for every organization.
+func (c *LLMConfigClient) checkConstraints(ctx context.Context, namespace, key string, value interface{}) []ValidationProblem {
+	c.constraints.mu.RLock()
+	compiled, ok := c.constraints.entries[constraintKey{c.organizationScopeID(ctx), namespace, key}]
+	if !ok {
+		compiled, ok = c.constraints.entries[constraintKey{"", namespace, key}]
+	}
+	c.constraints.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return compiled.check(value)
+}
+
+// PutConstraint stores a constraint on the server, which enforces it for all
+// writers, and once stored also enforces it locally for the organization of
+// ctx
+func (c *LLMConfigClient) PutConstraint(ctx context.Context, namespace, key string, constraint Constraint) error {
+	if _, err := compileConstraint(constraint); err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(constraint).
+		Put(keyRoute(namespace, key, "constraint"))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return c.AddConstraintContext(ctx, namespace, key, constraint)
+}
+
+// DeleteConstraint removes a key's constraint on the server and, once the
+// server no longer holds it, locally
+func (c *LLMConfigClient) DeleteConstraint(ctx context.Context, namespace, key string) error {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(keyRoute(namespace, key, "constraint"))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() && resp.StatusCode() != 404 {
+		return c.handleErrorResponse(resp)
+	}
+
+	c.constraints.mu.Lock()
+	delete(c.constraints.entries, constraintKey{c.organizationScopeID(ctx), namespace, key})
+	c.constraints.mu.Unlock()
+	return nil
+}
+
+// ListConstraints returns every constraint defined in a namespace
+func (c *LLMConfigClient) ListConstraints(ctx context.Context, namespace string) ([]KeyConstraint, error) {
+	var result []KeyConstraint
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/constraints/%s", namespace))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return []KeyConstraint{}, nil
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// LoadConstraints fetches a namespace's constraints and enforces them locally
+// for the organization of ctx, which they were listed in
+func (c *LLMConfigClient) LoadConstraints(ctx context.Context, namespace string) error {
+	constraints, err := c.ListConstraints(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, kc := range constraints {
+		if err := c.AddConstraintContext(ctx, kc.Namespace, kc.Key, kc.Constraint); err != nil {
+			return fmt.Errorf("constraint on %s/%s: %w", kc.Namespace, kc.Key, err)
+		}
+	}
+	return nil
+}
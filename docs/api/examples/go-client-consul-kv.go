@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+const (
+	// defaultConsulPoll is how often a blocking query re-reads its keys
+	// when ConsulKVServer is given no interval
+	defaultConsulPoll = 5 * time.Second
+	// Blocking queries wait 5m without ?wait= and at most 10m, as in Consul
+	defaultConsulWait = 5 * time.Minute
+	maxConsulWait     = 10 * time.Minute
+	// maxConsulReads bounds the queries whose results ConsulKVServer
+	// remembers; the least recently asked is forgotten first
+	maxConsulReads = 4096
+)
+
+// namespaceLister lists namespaces by prefix, as *LLMConfigClient does
+type namespaceLister interface {
+	ListNamespaces(ctx context.Context, prefix string) ([]string, error)
+}
+
+// ConsulKVServer serves namespaces read-only over Consul's KV HTTP API, so
+// tools written for Consul (confd, consul-template, anything on the Consul
+// API client) read configs unmodified:
+//
+//	consul-template -consul-addr localhost:8500 -template "app.tmpl:app.conf"
+//
+// The Consul key <Prefix>app/llm/model is key model of namespace app/llm,
+// the same layout ExportConsul writes and ConsulMigrationSteps reads. Values
+// are those of Environment; non-string values are JSON, flags come from
+// consul_flags tags, and masked secrets are left out. It answers
+//
+//	GET /v1/kv/{key}              ?raw, ?recurse, ?keys with ?separator=
+//	GET /v1/status/leader
+//
+// Blocking queries (?index=&wait=) are honoured by re-reading the keys
+// every PollInterval until they change. Recursive reads past one
+// namespace need a Reader that lists namespaces, as *LLMConfigClient and
+// FileBackend do. Writes answer 405.
+type ConsulKVServer struct {
+	Reader      ConfigReader
+	Environment string
+	// Prefix of the Consul keys, e.g. "config/"
+	Prefix string
+	// Token, when set, is required as the request's ACL token
+	// (X-Consul-Token, ?token=, or a bearer token)
+	Token string
+	// PollInterval of blocking queries; 5s when zero
+	PollInterval time.Duration
+
+	mu    sync.Mutex
+	index uint64
+	// reads remembers what each query last returned, and at what index,
+	// for up to maxConsulReads queries
+	reads map[string]consulRead
+	// asked counts indexOf calls, to order reads by last use
+	asked uint64
+}
+
+// consulRead is the last result of one query
+type consulRead struct {
+	sum   [sha256.Size]byte
+	index uint64
+	// used is the value of asked when the query was last made
+	used uint64
+}
+
+// Handler returns the server's routes
+func (s *ConsulKVServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status/leader", func(w http.ResponseWriter, r *http.Request) {
+		writeDevJSON(w, http.StatusOK, r.Host)
+	})
+	mux.HandleFunc("GET /v1/kv/{key...}", s.authorized(s.serveKV))
+	return mux
+}
+
+// Serve listens on addr until ctx is done, then shuts down gracefully
+func (s *ConsulKVServer) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// authorized checks the ACL token before calling next
+func (s *ConsulKVServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" {
+			token := r.Header.Get("X-Consul-Token")
+			if token == "" {
+				token = r.URL.Query().Get("token")
+			}
+			if token == "" {
+				token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+			if token != s.Token {
+				http.Error(w, "Permission denied", http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *ConsulKVServer) serveKV(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	path := r.PathValue("key")
+	_, recurse := query["recurse"]
+	_, keysOnly := query["keys"]
+	_, raw := query["raw"]
+
+	wait := defaultConsulWait
+	if v := query.Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, "Invalid wait time", http.StatusBadRequest)
+			return
+		}
+		wait = min(d, maxConsulWait)
+	}
+	after, _ := strconv.ParseUint(query.Get("index"), 10, 64)
+
+	pairs, index, err := s.read(r.Context(), path, recurse || keysOnly, after, wait)
+	if err != nil {
+		if r.Context().Err() == nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	w.Header().Set("X-Consul-LastContact", "0")
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch {
+	case keysOnly:
+		separator := query.Get("separator")
+		var keys []string
+		for _, pair := range pairs {
+			key := pair.Key
+			if i := strings.Index(key[len(path):], separator); separator != "" && i >= 0 {
+				key = key[:len(path)+i+len(separator)]
+			}
+			keys = append(keys, key)
+		}
+		writeDevJSON(w, http.StatusOK, slices.Compact(keys))
+	case raw:
+		w.Write(pairs[0].Value)
+	default:
+		writeDevJSON(w, http.StatusOK, pairs)
+	}
+}
+
+// read looks path up, or the keys below it with prefix. With after set,
+// it waits up to wait for the result to move past index after, as a
+// Consul blocking query does.
+func (s *ConsulKVServer) read(ctx context.Context, path string, prefix bool, after uint64, wait time.Duration) (consul.KVPairs, uint64, error) {
+	poll := s.PollInterval
+	if poll <= 0 {
+		poll = defaultConsulPoll
+	}
+	id := path
+	if prefix {
+		id += "?recurse"
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		var pairs consul.KVPairs
+		var err error
+		if prefix {
+			pairs, err = s.list(ctx, path)
+		} else {
+			pairs, err = s.get(ctx, path)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		index, err := s.indexOf(id, pairs)
+		if err != nil {
+			return nil, 0, err
+		}
+		if after == 0 || index != after || !time.Now().Before(deadline) {
+			return pairs, index, nil
+		}
+
+		timer := time.NewTimer(min(poll, time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// indexOf is the index of query id's result: the one it had while the
+// result is unchanged, and a new one each time it changes
+func (s *ConsulKVServer) indexOf(id string, pairs consul.KVPairs) (uint64, error) {
+	data, err := json.Marshal(pairs)
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reads == nil {
+		s.reads = map[string]consulRead{}
+	}
+	read, ok := s.reads[id]
+	if !ok && len(s.reads) >= maxConsulReads {
+		s.forgetOldestRead()
+	}
+	if !ok || read.sum != sum {
+		s.index++
+		read = consulRead{sum: sum, index: s.index}
+	}
+	s.asked++
+	read.used = s.asked
+	s.reads[id] = read
+	return read.index, nil
+}
+
+// forgetOldestRead drops the least recently made query. Asked again, it
+// gets a new index, so at worst a blocking query returns early.
+func (s *ConsulKVServer) forgetOldestRead() {
+	var oldest string
+	used := uint64(math.MaxUint64)
+	for id, read := range s.reads {
+		if read.used < used {
+			oldest, used = id, read.used
+		}
+	}
+	delete(s.reads, oldest)
+}
+
+// get looks up the single key path
+func (s *ConsulKVServer) get(ctx context.Context, path string) (consul.KVPairs, error) {
+	root := s.root()
+	if !strings.HasPrefix(path, root) {
+		return nil, nil
+	}
+	namespace, key, ok := splitHierarchy(path, root, "/")
+	if !ok || root+namespace+"/"+key != path {
+		return nil, nil
+	}
+	cfg, err := s.Reader.GetConfigContext(ctx, namespace, key, s.Environment, false)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pair, err := consulPair(path, *cfg)
+	if err != nil || pair == nil {
+		return nil, err
+	}
+	return consul.KVPairs{pair}, nil
+}
+
+// list looks up every key starting with prefix, by key
+func (s *ConsulKVServer) list(ctx context.Context, prefix string) (consul.KVPairs, error) {
+	root := s.root()
+	var rel string
+	switch {
+	case strings.HasPrefix(prefix, root):
+		rel = prefix[len(root):]
+	case !strings.HasPrefix(root, prefix):
+		return nil, nil
+	}
+
+	// The namespace prefix ends in, and any namespace starting with it
+	var namespaces []string
+	if i := strings.LastIndex(rel, "/"); i > 0 {
+		namespaces = append(namespaces, rel[:i])
+	}
+	if lister, ok := s.Reader.(namespaceLister); ok {
+		more, err := lister.ListNamespaces(ctx, rel)
+		if err != nil {
+			return nil, fmt.Errorf("list namespaces %s: %w", rel, err)
+		}
+		namespaces = append(namespaces, more...)
+	}
+	sort.Strings(namespaces)
+
+	var pairs consul.KVPairs
+	for _, namespace := range slices.Compact(namespaces) {
+		configs, err := s.Reader.ListConfigsContext(ctx, namespace, s.Environment)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list %s (%s): %w", namespace, s.Environment, err)
+		}
+		for _, cfg := range configs {
+			key := root + namespace + "/" + cfg.Key
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			pair, err := consulPair(key, cfg)
+			if err != nil {
+				return nil, err
+			}
+			if pair != nil {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+// root is Prefix ending in a slash, or empty
+func (s *ConsulKVServer) root() string {
+	if root := strings.Trim(s.Prefix, "/"); root != "" {
+		return root + "/"
+	}
+	return ""
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConsulTemplate is a template written for consul-template, rendered from
+// the config server instead of Consul and Vault. Key paths are a namespace
+// and a key, e.g. {{ key "app/llm/model" }} reads model from app/llm.
+//
+// Supported functions:
+//
+//	key "ns/key"                  value of a key; an error when it is missing
+//	keyOrDefault "ns/key" "def"   value of a key, or def
+//	keyExists "ns/key"            whether the key exists
+//	ls "ns", tree "ns"            a namespace's keys as .Key/.Value pairs
+//	secret "ns/key"               a revealed secret as .Data.value
+//	env "NAME"                    an environment variable
+//	toJSON, toJSONPretty, toYAML, parseJSON
+//
+// Unlike consul-template, key does not block until a missing key appears;
+// the render fails and the previous file is kept.
+type ConsulTemplate struct {
+	Source      string
+	Destination string
+	// Command runs with sh -c after Destination changes, e.g. to reload a
+	// service
+	Command string
+	// Perms of Destination; 0644 when zero
+	Perms os.FileMode
+
+	tmpl *template.Template
+}
+
+// ParseConsulTemplate reads a template spec in consul-template's -template
+// form, SOURCE:DESTINATION[:COMMAND], and parses SOURCE
+func ParseConsulTemplate(spec string) (*ConsulTemplate, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("template %q must be SOURCE:DESTINATION[:COMMAND]", spec)
+	}
+	t := &ConsulTemplate{Source: parts[0], Destination: parts[1]}
+	if len(parts) == 3 {
+		t.Command = parts[2]
+	}
+
+	data, err := os.ReadFile(t.Source)
+	if err != nil {
+		return nil, err
+	}
+	// The functions are bound to a render in RenderConsulTemplates; these
+	// only let the template parse
+	t.tmpl, err = template.New(filepath.Base(t.Source)).Funcs((&consulRender{}).funcs()).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ConsulTemplateOptions configures RenderConsulTemplates and
+// RunConsulTemplates
+type ConsulTemplateOptions struct {
+	Environment string
+	Templates   []*ConsulTemplate
+	// SecretKey decrypts client-side encrypted secrets read with secret
+	SecretKey SecretKey
+	// Interval between polls of the namespaces the templates read; 5s when
+	// zero
+	Interval time.Duration
+}
+
+// consulKeyPair is an element of ls and tree, named as in consul-template
+type consulKeyPair struct {
+	Key   string
+	Value string
+}
+
+// consulSecret is the result of secret; .Data.value holds the value, as
+// with a Vault KV version 1 secret
+type consulSecret struct {
+	Data map[string]interface{}
+}
+
+// consulRender reads what one render of the templates needs, fetching each
+// namespace once and remembering which were read so they can be watched
+type consulRender struct {
+	ctx        context.Context
+	client     *LLMConfigClient
+	opts       ConsulTemplateOptions
+	namespaces map[string]map[string]interface{}
+}
+
+func (r *consulRender) funcs() template.FuncMap {
+	return template.FuncMap{
+		"key":          r.key,
+		"keyOrDefault": r.keyOrDefault,
+		"keyExists":    r.keyExists,
+		"ls":           r.ls,
+		"tree":         r.ls,
+		"secret":       r.secret,
+		"env":          os.Getenv,
+		"toJSON": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"toJSONPretty": func(v interface{}) (string, error) {
+			data, err := json.MarshalIndent(v, "", "  ")
+			return string(data), err
+		},
+		"toYAML": func(v interface{}) (string, error) {
+			data, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+		"parseJSON": func(s string) (interface{}, error) {
+			var v interface{}
+			err := json.Unmarshal([]byte(s), &v)
+			return v, err
+		},
+	}
+}
+
+// namespace lists a namespace once per render
+func (r *consulRender) namespace(namespace string) (map[string]interface{}, error) {
+	if values, ok := r.namespaces[namespace]; ok {
+		return values, nil
+	}
+	configs, err := r.client.ListConfigsContext(r.ctx, namespace, r.opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("list %s (%s): %w", namespace, r.opts.Environment, err)
+	}
+	values := make(map[string]interface{}, len(configs))
+	for _, cfg := range configs {
+		values[cfg.Key] = normalizeExportValue(cfg.Value)
+	}
+	r.namespaces[namespace] = values
+	return values, nil
+}
+
+// lookup resolves a "namespace/key" path
+func (r *consulRender) lookup(path string) (interface{}, bool, error) {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return nil, false, fmt.Errorf("key %q must be NAMESPACE/KEY", path)
+	}
+	values, err := r.namespace(path[:i])
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := values[path[i+1:]]
+	return value, ok, nil
+}
+
+func (r *consulRender) key(path string) (string, error) {
+	value, ok, err := r.lookup(path)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("key %s not found", path)
+	}
+	if value == encryptedPlaceholder {
+		return "", fmt.Errorf("key %s is a secret; read it with secret", path)
+	}
+	return formatCLIValue(value), nil
+}
+
+func (r *consulRender) keyOrDefault(path, def string) (string, error) {
+	_, ok, err := r.lookup(path)
+	if err != nil || !ok {
+		return def, err
+	}
+	return r.key(path)
+}
+
+func (r *consulRender) keyExists(path string) (bool, error) {
+	_, ok, err := r.lookup(path)
+	return ok, err
+}
+
+func (r *consulRender) ls(namespace string) ([]consulKeyPair, error) {
+	values, err := r.namespace(strings.TrimSuffix(namespace, "/"))
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]consulKeyPair, 0, len(values))
+	for key, value := range values {
+		if value != encryptedPlaceholder {
+			pairs = append(pairs, consulKeyPair{Key: key, Value: formatCLIValue(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+func (r *consulRender) secret(path string) (*consulSecret, error) {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return nil, fmt.Errorf("secret %q must be NAMESPACE/KEY", path)
+	}
+	// Listing the namespace makes it watched like the others
+	if _, err := r.namespace(path[:i]); err != nil {
+		return nil, err
+	}
+	secret, err := r.client.RevealSecret(r.ctx, path[:i], path[i+1:], r.opts.Environment, r.opts.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	return &consulSecret{Data: map[string]interface{}{"value": formatCLIValue(normalizeExportValue(secret.Value))}}, nil
+}
+
+// RenderConsulTemplates renders every template once, writing and running
+// the command of those whose output changed. It returns the namespaces the
+// templates read.
+func (c *LLMConfigClient) RenderConsulTemplates(ctx context.Context, opts ConsulTemplateOptions) ([]string, error) {
+	render := &consulRender{ctx: ctx, client: c, opts: opts, namespaces: map[string]map[string]interface{}{}}
+	for _, t := range opts.Templates {
+		tmpl, err := t.tmpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Funcs(render.funcs()).Execute(&buf, nil); err != nil {
+			return nil, fmt.Errorf("render %s: %w", t.Source, err)
+		}
+
+		if current, err := os.ReadFile(t.Destination); err == nil && bytes.Equal(current, buf.Bytes()) {
+			continue
+		}
+		perms := t.Perms
+		if perms == 0 {
+			perms = 0o644
+		}
+		if err := writeFileAtomic(t.Destination, buf.Bytes(), perms); err != nil {
+			return nil, err
+		}
+		log.Printf("llm-config template: rendered %s", t.Destination)
+		if t.Command != "" {
+			cmd := exec.CommandContext(ctx, "sh", "-c", t.Command)
+			cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+			if err := cmd.Run(); err != nil {
+				log.Printf("llm-config template: %s: %v", t.Command, err)
+			}
+		}
+	}
+
+	namespaces := make([]string, 0, len(render.namespaces))
+	for namespace := range render.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// RunConsulTemplates renders the templates, then re-renders them whenever
+// a namespace they read changes, until ctx is done. The first render must
+// succeed; later failures are logged and the previous files kept.
+func (c *LLMConfigClient) RunConsulTemplates(ctx context.Context, opts ConsulTemplateOptions) error {
+	namespaces, err := c.RenderConsulTemplates(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	updates := make(chan struct{}, 1)
+	watched := map[string]bool{}
+	watch := func(namespaces []string) error {
+		for _, namespace := range namespaces {
+			if watched[namespace] {
+				continue
+			}
+			w, err := c.Watch(ctx, namespace, WatchOptions{Environment: opts.Environment, Interval: opts.Interval})
+			if err != nil {
+				return err
+			}
+			watched[namespace] = true
+			go func() {
+				defer w.Close()
+				for range w.Events() {
+					select {
+					case updates <- struct{}{}:
+					default:
+					}
+				}
+			}()
+		}
+		return nil
+	}
+	if err := watch(namespaces); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-updates:
+			namespaces, err := c.RenderConsulTemplates(ctx, opts)
+			if err == nil {
+				// A template may start reading another namespace, e.g.
+				// behind keyExists
+				err = watch(namespaces)
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("llm-config template: %v; keeping the previous files", err)
+			}
+		}
+	}
+}
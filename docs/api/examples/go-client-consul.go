@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+// consulFlagsTag carries a Consul KV pair's flags through the config manager
+const consulFlagsTag = "consul_flags"
+
+// ConsulKV is the subset of *consul.KV used by the bridge
+type ConsulKV interface {
+	List(prefix string, q *consul.QueryOptions) (consul.KVPairs, *consul.QueryMeta, error)
+	Put(p *consul.KVPair, q *consul.WriteOptions) (*consul.WriteMeta, error)
+}
+
+// ConsulMigrationSteps maps every Consul key below prefix onto a namespace
+// key ("config/app/llm/model" under "config/" becomes "model" in "app/llm").
+// Values go through dotenv-style type inference and flags are preserved in
+// step metadata so ApplyMigration records them as tags.
+func ConsulMigrationSteps(ctx context.Context, kv ConsulKV, prefix string) ([]MigrationStep, error) {
+	pairs, _, err := kv.List(prefix, (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("consul list %s: %w", prefix, err)
+	}
+
+	steps := make([]MigrationStep, 0, len(pairs))
+	for _, pair := range pairs {
+		if strings.HasSuffix(pair.Key, "/") {
+			continue // folder placeholder
+		}
+
+		namespace, key, ok := splitHierarchy(pair.Key, prefix, "/")
+		if !ok {
+			return nil, fmt.Errorf("consul key %s has no namespace below %s", pair.Key, prefix)
+		}
+
+		steps = append(steps, MigrationStep{
+			Source:    "consul:" + pair.Key,
+			Namespace: namespace,
+			Key:       key,
+			Value:     InferValue(string(pair.Value)),
+			Metadata: map[string]string{
+				"source":         "consul",
+				consulFlagsTag:   strconv.FormatUint(pair.Flags, 10),
+				"source_version": strconv.FormatUint(pair.ModifyIndex, 10),
+			},
+		})
+	}
+
+	return steps, nil
+}
+
+// ExportConsul writes a namespace to Consul below prefix, restoring flags from
+// consul_flags tags. Non-string values are stored as JSON. Secrets masked by
+// the API cannot be exported and are skipped; their keys are returned.
+func (c *LLMConfigClient) ExportConsul(ctx context.Context, kv ConsulKV, namespace, env, prefix string) (skipped []string, err error) {
+	configs, err := c.ListConfigsContext(ctx, namespace, env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cfg := range configs {
+		pair, err := consulPair(strings.TrimSuffix(prefix, "/")+"/"+namespace+"/"+cfg.Key, cfg)
+		if err != nil {
+			return skipped, err
+		}
+		if pair == nil {
+			skipped = append(skipped, cfg.Key)
+			continue
+		}
+
+		if _, err := kv.Put(pair, (&consul.WriteOptions{}).WithContext(ctx)); err != nil {
+			return skipped, fmt.Errorf("consul put %s: %w", pair.Key, err)
+		}
+	}
+
+	return skipped, nil
+}
+
+// consulPair is cfg as the Consul KV pair key, nil for a secret masked by
+// the API. Non-string values are JSON, flags come from the consul_flags
+// tag, and the modify index is the config's version.
+func consulPair(key string, cfg ConfigResponse) (*consul.KVPair, error) {
+	var value []byte
+	switch v := cfg.Value.(type) {
+	case string:
+		if v == encryptedPlaceholder {
+			return nil, nil
+		}
+		value = []byte(v)
+	default:
+		var err error
+		value, err = json.Marshal(normalizeExportValue(v))
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", cfg.Key, err)
+		}
+	}
+
+	pair := &consul.KVPair{Key: key, Value: value, CreateIndex: 1, ModifyIndex: uint64(cfg.Version)}
+	if flags, ok := tagValue(cfg.Metadata.Tags, consulFlagsTag); ok {
+		var err error
+		if pair.Flags, err = strconv.ParseUint(flags, 10, 64); err != nil {
+			return nil, fmt.Errorf("parse %s tag on %s: %w", consulFlagsTag, cfg.Key, err)
+		}
+	}
+	return pair, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConsumerRegistration declares the keys a service reads in one
+// environment. Keys are "namespace/key"; the key part may be a glob such as
+// "app/llm/*" for services that read a whole namespace.
+type ConsumerRegistration struct {
+	Service     string   `json:"service"`
+	Environment string   `json:"environment"`
+	Keys        []string `json:"keys"`
+	// Owner is who to contact before changing what the service reads, e.g.
+	// "team:search"
+	Owner string `json:"owner,omitempty"`
+}
+
+// Consumer is a service that reads a key
+type Consumer struct {
+	Service     string `json:"service"`
+	Environment string `json:"environment"`
+	Owner       string `json:"owner,omitempty"`
+	// Pattern is the registered key or glob that matched
+	Pattern string `json:"pattern"`
+	// Via is the chain of keys the service reaches this one through, nearest
+	// first, when it reads an alias of it or a value that references it with
+	// ${namespace/key}; empty for direct reads
+	Via          []string  `json:"via,omitempty"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// Direct reports whether the service reads the key itself rather than
+// through an alias or reference
+func (c Consumer) Direct() bool {
+	return len(c.Via) == 0
+}
+
+// ConsumerListOptions filters Consumers
+type ConsumerListOptions struct {
+	// Environment limits results to one environment; all when empty
+	Environment string
+	// DirectOnly leaves out services that only reach the key through
+	// aliases and references
+	DirectOnly bool
+}
+
+// RegisterConsumer records which keys a service reads in an environment,
+// replacing what it registered there before, so deploys can re-register
+// unconditionally
+func (c *LLMConfigClient) RegisterConsumer(ctx context.Context, reg ConsumerRegistration) (*ConsumerRegistration, error) {
+	if reg.Service == "" || reg.Environment == "" {
+		return nil, fmt.Errorf("register consumer: a service and environment are required: %w", ErrValidation)
+	}
+	for _, key := range reg.Keys {
+		if err := validateConsumerPattern(key); err != nil {
+			return nil, fmt.Errorf("register consumer %s: %w", reg.Service, err)
+		}
+	}
+
+	var result ConsumerRegistration
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(reg).
+		SetResult(&result).
+		Put(fmt.Sprintf("/consumers/%s", url.PathEscape(reg.Service)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetConsumer returns what a service has registered, one registration per
+// environment
+func (c *LLMConfigClient) GetConsumer(ctx context.Context, service string) ([]ConsumerRegistration, error) {
+	var result []ConsumerRegistration
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/consumers/%s", url.PathEscape(service)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// DeregisterConsumer removes a service's registration in env, or in every
+// environment when env is empty, e.g. when the service is retired
+func (c *LLMConfigClient) DeregisterConsumer(ctx context.Context, service, env string) (bool, error) {
+	req := c.httpClient.R().
+		SetContext(ctx)
+	if env != "" {
+		req.SetQueryParam("env", env)
+	}
+
+	resp, err := req.Delete(fmt.Sprintf("/consumers/%s", url.PathEscape(service)))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
+
+// Consumers answers "who reads app/llm/model in production?": the services
+// that registered the key, a glob matching it, an alias of it, or a key
+// whose value references it, sorted by service. Check it before changing
+// or deleting a key to see the blast radius.
+func (c *LLMConfigClient) Consumers(ctx context.Context, namespace, key string, opts ConsumerListOptions) ([]Consumer, error) {
+	var result []Consumer
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("namespace", namespace).
+		SetQueryParam("key", key).
+		SetResult(&result)
+	if opts.Environment != "" {
+		req.SetQueryParam("env", opts.Environment)
+	}
+	if opts.DirectOnly {
+		req.SetQueryParam("direct", "true")
+	}
+
+	resp, err := req.Get("/consumers")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Service != result[j].Service {
+			return result[i].Service < result[j].Service
+		}
+		return result[i].Environment < result[j].Environment
+	})
+	return result, nil
+}
+
+// ConsumerMatches reports whether a registered pattern covers
+// namespace/key. A glob only applies within its namespace: "app/*" matches
+// app/model but not app/llm/model.
+func ConsumerMatches(pattern, namespace, key string) bool {
+	i := strings.LastIndex(pattern, "/")
+	if i <= 0 || pattern[:i] != namespace {
+		return false
+	}
+	ok, _ := path.Match(pattern[i+1:], key)
+	return ok
+}
+
+// validateConsumerPattern requires "namespace/key" with a well-formed glob
+func validateConsumerPattern(pattern string) error {
+	i := strings.LastIndex(pattern, "/")
+	if i <= 0 || i == len(pattern)-1 || strings.ContainsAny(pattern[:i], "*?[") {
+		return fmt.Errorf("key %q must be namespace/key, with a glob only in the key: %w", pattern, ErrValidation)
+	}
+	if _, err := path.Match(pattern[i+1:], ""); err != nil {
+		return fmt.Errorf("key %q: %v: %w", pattern, err, ErrValidation)
+	}
+	return nil
+}
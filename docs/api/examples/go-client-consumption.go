@@ -0,0 +1,214 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// Consumption manifest encodings of ConsumptionManifest.Encode
+const (
+	ConsumptionJSON = "json"
+	ConsumptionSPDX = "spdx"
+)
+
+// ConsumedValue is one version of a key a process read
+type ConsumedValue struct {
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Environment string `json:"environment"`
+	// ResolvedFrom is the environment an override chain took the value
+	// from, when it is not Environment
+	ResolvedFrom string `json:"resolved_from,omitempty"`
+	Version      int64  `json:"version"`
+	// SHA256 is the digest of the value's JSON, so audits can compare
+	// values without the manifest holding them; empty for secrets
+	SHA256 string `json:"sha256,omitempty"`
+	// Masked is set for secrets, read masked or revealed, which are
+	// recorded by version only
+	Masked bool `json:"masked,omitempty"`
+}
+
+// ConsumptionManifest records the configuration a deployment ran with
+type ConsumptionManifest struct {
+	// Deployment identifies what ran, e.g. a release name or image digest
+	Deployment string          `json:"deployment"`
+	Hostname   string          `json:"hostname,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	Values     []ConsumedValue `json:"values"`
+}
+
+// consumptionRecorder collects the values read since RecordConsumption
+type consumptionRecorder struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	values  map[ConsumedValue]bool
+}
+
+// RecordConsumption starts recording every value read through GetConfig,
+// ListConfigs, StreamConfigs, and RevealSecret, and the helpers built on
+// them, for ConsumptionManifest. Revealed secrets are recorded by version,
+// like masked ones, so the manifest holds no digest of their values:
+//
+//	client.RecordConsumption()
+//	// ... resolve the process's configuration
+//	manifest := client.ConsumptionManifest(os.Getenv("RELEASE"))
+//	err := manifest.WriteSigned("/var/run/llm-config/consumed.spdx.json", ConsumptionSPDX, signer)
+func (c *LLMConfigClient) RecordConsumption() {
+	c.consumption.enabled.Store(true)
+}
+
+// record adds configs read in env; it is a no-op when recording is off
+func (r *consumptionRecorder) record(env string, configs ...ConfigResponse) {
+	if !r.enabled.Load() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cfg := range configs {
+		v := ConsumedValue{Namespace: cfg.Namespace, Key: cfg.Key, Environment: env, Version: cfg.Version}
+		if cfg.Environment != "" && cfg.Environment != env {
+			v.ResolvedFrom = cfg.Environment
+		}
+		if cfg.Value == encryptedPlaceholder {
+			v.Masked = true
+		} else if data, err := json.Marshal(canonicalValue(cfg.Value)); err == nil {
+			sum := sha256.Sum256(data)
+			v.SHA256 = hex.EncodeToString(sum[:])
+		}
+		r.values[v] = true
+	}
+}
+
+// recordSecret adds a revealed secret's version, leaving its value out; it
+// is a no-op when recording is off
+func (r *consumptionRecorder) recordSecret(namespace, key, env string, version int64) {
+	if !r.enabled.Load() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[ConsumedValue{Namespace: namespace, Key: key, Environment: env, Version: version, Masked: true}] = true
+}
+
+// ConsumptionManifest returns every value read since RecordConsumption,
+// each version once, sorted by namespace, key, environment, and version.
+// Take it once the process has resolved its configuration; reads after
+// that show up in later manifests. It is empty when recording is off.
+func (c *LLMConfigClient) ConsumptionManifest(deployment string) *ConsumptionManifest {
+	m := &ConsumptionManifest{
+		Deployment: deployment,
+		CreatedAt:  c.clock.Now().UTC().Format(time.RFC3339),
+		Values:     []ConsumedValue{},
+	}
+	m.Hostname, _ = os.Hostname()
+
+	c.consumption.mu.Lock()
+	for v := range c.consumption.values {
+		m.Values = append(m.Values, v)
+	}
+	c.consumption.mu.Unlock()
+	sort.Slice(m.Values, func(i, j int) bool {
+		a, b := m.Values[i], m.Values[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Environment != b.Environment {
+			return a.Environment < b.Environment
+		}
+		return a.Version < b.Version
+	})
+	return m
+}
+
+// Encode returns the manifest as JSON, or with ConsumptionSPDX as an SPDX
+// 2.3 document whose packages are the values read, for tools that collect
+// attestations as SBOMs
+func (m *ConsumptionManifest) Encode(format string) ([]byte, error) {
+	switch format {
+	case ConsumptionJSON:
+		return json.MarshalIndent(m, "", "  ")
+	case ConsumptionSPDX:
+		return json.MarshalIndent(m.spdxDocument(), "", "  ")
+	}
+	return nil, fmt.Errorf("consumption manifest format %q must be %s or %s", format, ConsumptionJSON, ConsumptionSPDX)
+}
+
+// WriteSigned writes the manifest encoded in format to path and, with a
+// signer, its signature to path.sig, as SignSnapshot makes it, so audits
+// check it with VerifySnapshot or `cosign verify-blob`
+func (m *ConsumptionManifest) WriteSigned(path, format string, signer signature.Signer) error {
+	data, err := m.Encode(format)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	if signer == nil {
+		return nil
+	}
+	sig, err := SignSnapshot(signer, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+signatureSuffix, []byte(sig), 0o644)
+}
+
+// spdxDocument describes the manifest as an SPDX 2.3 document
+func (m *ConsumptionManifest) spdxDocument() map[string]interface{} {
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+
+	packages := make([]interface{}, len(m.Values))
+	relationships := make([]interface{}, len(m.Values))
+	for i, v := range m.Values {
+		id := fmt.Sprintf("SPDXRef-Config-%d", i+1)
+		pkg := map[string]interface{}{
+			"SPDXID":           id,
+			"name":             fmt.Sprintf("%s/%s (%s)", v.Namespace, v.Key, v.Environment),
+			"versionInfo":      fmt.Sprint(v.Version),
+			"downloadLocation": "NOASSERTION",
+			"filesAnalyzed":    false,
+			"supplier":         "NOASSERTION",
+		}
+		if v.SHA256 != "" {
+			pkg["checksums"] = []interface{}{map[string]string{"algorithm": "SHA256", "checksumValue": v.SHA256}}
+		}
+		if v.ResolvedFrom != "" {
+			pkg["comment"] = "resolved from " + v.ResolvedFrom
+		}
+		packages[i] = pkg
+		relationships[i] = map[string]string{
+			"spdxElementId":      "SPDXRef-DOCUMENT",
+			"relationshipType":   "DESCRIBES",
+			"relatedSpdxElement": id,
+		}
+	}
+
+	return map[string]interface{}{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              "llm-config consumption of " + m.Deployment,
+		"documentNamespace": "https://llm-config-manager.io/spdx/consumption/" + hex.EncodeToString(sum[:]),
+		"comment":           "Configuration read by " + m.Deployment + " on " + m.Hostname,
+		"creationInfo": map[string]interface{}{
+			"created":  m.CreatedAt,
+			"creators": []string{"Tool: llm-config-manager"},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+}
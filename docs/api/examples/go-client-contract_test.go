@@ -0,0 +1,943 @@
+package main
+
+// Contract tests: every request the client makes is checked against the
+// operation it targets in ../openapi.yaml (path, query parameters, body),
+// every response the client decodes is served from the spec's own examples,
+// and the client's model structs are compared field by field with the spec's
+// schemas. A renamed parameter, a new required field, or an operation the
+// client does not call fails here rather than in a production reader.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const specPath = "../openapi.yaml"
+
+// contractCalls exercises one spec operation each through the client
+var contractCalls = map[string]func(ctx context.Context, c *LLMConfigClient) (interface{}, error){
+	"getHealth": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.HealthCheckContext(ctx)
+	},
+	"getConfig": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetConfigContext(ctx, "llm", "model", "production", true)
+	},
+	"setConfig": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.SetConfigContext(ctx, "llm", "model", "gpt-4", "production", "admin", false)
+	},
+	"deleteConfig": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.DeleteConfigContext(ctx, "llm", "model", "production")
+	},
+	"listConfigs": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListConfigsContext(ctx, "llm", "production")
+	},
+	"getHistory": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetHistoryContext(ctx, "llm", "model", "production")
+	},
+	"rollbackConfig": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.RollbackContext(ctx, "llm", "model", 2, "production")
+	},
+	"getKeyUsage": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		usage, err := c.GetKeyUsage(ctx, "llm", "production")
+		var keys []KeyUsage
+		for _, key := range sortedMapKeys(usage) {
+			keys = append(keys, usage[key])
+		}
+		return keys, err
+	},
+	"getBoundSchema": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetBoundSchema(ctx, "llm", "params")
+	},
+	"bindSchema": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.BindSchema(ctx, SchemaBinding{Namespace: "llm", Key: "params", Subject: "llm-params"})
+	},
+	"putConstraint": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.PutConstraint(ctx, "llm", "model", Constraint{Enum: []interface{}{"gpt-4o", "gpt-4o-mini"}})
+	},
+	"deleteConstraint": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.DeleteConstraint(ctx, "llm", "model")
+	},
+	"clearExpiry": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ClearExpiry(ctx, "llm", "model", "production")
+	},
+	"revealSecret": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.RevealSecret(ctx, "llm", "api_key", "production", nil)
+	},
+	"updateTags": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		tags, err := c.AddTags(ctx, "llm", "model", "production", "gpt-4o-migration")
+		return updateTagsResponse{Tags: tags}, err
+	},
+	"listAnnotations": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListAnnotations(ctx, "llm", "temperature", "production")
+	},
+	"annotate": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.Annotate(ctx, "llm", "temperature", NewAnnotation{Environment: "production", Version: 4, Body: "Lowered after INC-1234"}, "alice")
+	},
+	"deleteAnnotation": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.DeleteAnnotation(ctx, "llm", "temperature", "an_01HMX2")
+	},
+	"deprecateKey": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.Deprecate(ctx, "llm", "model", KeyDeprecation{Message: "Superseded", ReplacedBy: "llm/model_v2"}, "admin")
+	},
+	"undeprecateKey": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.Undeprecate(ctx, "llm", "model")
+	},
+	"listEvals": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListEvals(ctx, ConfigVersion("llm", "params", "production", 0), EvalQuery{Dataset: "golden"})
+	},
+	"bestVersion": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.BestVersion(ctx, ConfigVersion("llm", "params", "production", 0), EvalQuery{Dataset: "golden", Metric: "latency_ms", LowerIsBetter: true})
+	},
+	"listVersionEvals": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListEvals(ctx, ConfigVersion("llm", "params", "production", 3), EvalQuery{Metric: "accuracy"})
+	},
+	"recordEvals": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.RecordEval(ctx, ConfigVersion("llm", "params", "production", 3), "ci",
+			EvalResult{Dataset: "golden", Metric: "accuracy", Value: 0.91, RunID: "run-1"})
+	},
+	"reportUsage": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		c.EnableUsageTracking()
+		c.usage.record(Organization{}, "app/llm", "model", "production", time.Now())
+		return nil, c.ReportUsage(ctx)
+	},
+	"listConstraints": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListConstraints(ctx, "llm")
+	},
+
+	"listNamespaces": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListNamespaceInfo(ctx, "app/")
+	},
+	"createNamespace": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.CreateNamespace(ctx, NamespaceSpec{Name: "app/search", Owners: []string{TeamOwner("search")}, Quota: NamespaceQuota{MaxKeys: 500}}, "admin")
+	},
+	"getNamespace": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetNamespace(ctx, "app/llm")
+	},
+	"deleteNamespace": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.DeleteNamespace(ctx, "app/search", DeleteNamespaceOptions{Confirm: "app/search", Force: true})
+	},
+	"setNamespaceQuota": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.SetNamespaceQuota(ctx, "app/llm", NamespaceQuota{MaxKeys: 500, MaxBytes: 1 << 20})
+	},
+	"getNamespaceStats": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetNamespaceStats(ctx, "app/llm", UsageStatsOptions{Environment: "production", Window: time.Hour})
+	},
+	"getOwnership": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetOwnership(ctx, "app/llm")
+	},
+	"setOwnership": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.SetOwnership(ctx, OwnershipPolicy{Namespace: "app/llm", Enforcement: OwnershipWarn, Rules: []OwnershipRule{
+			{Pattern: "prompts/", Owners: []string{TeamOwner("prompting")}},
+		}}, "admin")
+	},
+	"getKeyOwners": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.KeyOwners(ctx, "app/llm", "prompts/system")
+	},
+	"addNamespaceOwner": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.AddNamespaceOwner(ctx, "app/llm", TeamOwner("platform"))
+	},
+	"removeNamespaceOwner": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.RemoveNamespaceOwner(ctx, "app/llm", UserOwner("alice@example.com"))
+	},
+
+	"uploadBlob": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.UploadBlob(ctx, "llm", "tokenizer", "production", strings.NewReader("hello"), BlobUploadOptions{User: "admin"})
+	},
+	"downloadBlob": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.DownloadBlob(ctx, "llm", "tokenizer", "production", 3, io.Discard)
+	},
+	"getBlobInfo": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetBlobInfo(ctx, "llm", "tokenizer", "production")
+	},
+	"putBlobManifest": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		report, err := c.UploadChunked(ctx, "llm", "tokenizer", "production", strings.NewReader("hello"), BlobUploadOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return report.BlobInfo, nil
+	},
+	"getBlobManifest": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetChunkManifest(ctx, "llm", "tokenizer", "production", 0)
+	},
+	"findMissingChunks": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		missing, err := c.missingChunks(ctx, []string{contractChunkID})
+		return missingChunksResponse{Missing: sortedMapKeys(missing)}, err
+	},
+	"putChunk": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return nil, c.putChunk(ctx, contractChunkID, []byte("hello"))
+	},
+	"getChunk": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.chunk(ctx, contractChunkID, "")
+	},
+
+	"setBudget": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.SetBudget(ctx, Budget{Namespace: "app/llm", MonthlyTokens: 5000000, Enforce: true}, "finops")
+	},
+	"getBudgetUsage": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetBudgetUsage(ctx, "app/llm")
+	},
+	"recordSpend": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.RecordSpend(ctx, "app/llm", Spend{Tokens: 1200, Cost: 0.018})
+	},
+
+	"createUser": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.CreateUser(ctx, UserSpec{ID: "alice@example.com", DisplayName: "Alice"}, "admin")
+	},
+	"getUser": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetUser(ctx, "alice@example.com")
+	},
+	"listUsers": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListUsers(ctx)
+	},
+	"listUserMemberships": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListUserMemberships(ctx, "alice@example.com")
+	},
+	"createTeam": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.CreateTeam(ctx, TeamSpec{Name: "platform"}, "admin")
+	},
+	"listTeams": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListTeams(ctx)
+	},
+	"listTeamMembers": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListTeamMembers(ctx, "platform")
+	},
+	"addTeamMember": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.AddTeamMember(ctx, "platform", "alice@example.com", TeamRoleMaintainer)
+	},
+	"removeTeamMember": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.RemoveTeamMember(ctx, "platform", "alice@example.com")
+	},
+
+	"proposeChange": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ProposeChange(ctx, ChangeRequest{Namespace: "llm", Key: "model", Environment: "production", Value: "gpt-4o"}, "alice")
+	},
+	"getChange": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.GetChange(ctx, "ch_01HMX9")
+	},
+	"listChanges": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListPendingChanges(ctx, ListChangesOptions{Namespace: "app/llm", Environment: "production"})
+	},
+	"approveChange": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.Approve(ctx, "ch_01HMX9", "bob", "Eval scores look good")
+	},
+	"rejectChange": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.Reject(ctx, "ch_01HMX9", "bob", "Wait for the eval run")
+	},
+	"applyChange": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ApplyChange(ctx, "ch_01HMX9", "alice")
+	},
+
+	"registerValidationWebhook": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.RegisterValidationWebhook(ctx, ValidationWebhook{
+			Namespace: "app/llm", URL: "https://policy.internal.example.com/validate", FailurePolicy: WebhookFailClosed, Secret: "s3cret",
+		})
+	},
+	"listValidationWebhooks": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.ListValidationWebhooks(ctx, "app/llm")
+	},
+	"removeValidationWebhook": func(ctx context.Context, c *LLMConfigClient) (interface{}, error) {
+		return c.RemoveValidationWebhook(ctx, "wh_01HMXA")
+	},
+}
+
+// contractChunkID is the chunk the spec's chunk examples hold: the SHA-256
+// of "hello"
+const contractChunkID = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+// contractModels pairs client structs with the spec schema they decode or
+// encode. clientOnly lists JSON fields the client knows about that the spec
+// does not (yet) describe; specOnly lists spec properties the client
+// deliberately ignores.
+var contractModels = []struct {
+	schema     string
+	model      interface{}
+	clientOnly []string
+	specOnly   []string
+}{
+	{schema: "HealthResponse", model: HealthResponse{}, specOnly: []string{"uptime", "timestamp"}},
+	{schema: "ConfigResponse", model: ConfigResponse{}},
+	{schema: "ConfigMetadata", model: ConfigMetadata{}},
+	{schema: "ConfigExpiry", model: ConfigExpiry{}},
+	{schema: "ExpiryRequest", model: ExpiryRequest{}},
+	{schema: "KeyDeprecation", model: KeyDeprecation{}},
+	// Tags feed client-side policy checks; the server ignores them
+	{schema: "SetConfigRequest", model: SetConfigRequest{}, clientOnly: []string{"tags"}},
+	{schema: "VersionEntry", model: VersionEntry{}},
+	{schema: "ErrorResponse", model: ErrorResponse{}, specOnly: []string{"details"}},
+	{schema: "KeyUsage", model: KeyUsage{}},
+	{schema: "SchemaBinding", model: SchemaBinding{}},
+	{schema: "SchemaVersion", model: SchemaVersion{}},
+	{schema: "Constraint", model: Constraint{}},
+	{schema: "RevealedSecret", model: RevealedSecret{}},
+	{schema: "Annotation", model: Annotation{}},
+	{schema: "EvalResult", model: EvalResult{}},
+	{schema: "VersionScore", model: VersionScore{}},
+	{schema: "UsageReport", model: UsageReport{}},
+	{schema: "UsageRecord", model: UsageRecord{}},
+	{schema: "KeyConstraint", model: KeyConstraint{}},
+	{schema: "NamespaceQuota", model: NamespaceQuota{}},
+	{schema: "NamespaceUsage", model: NamespaceUsage{}},
+	{schema: "NamespaceInfo", model: NamespaceInfo{}},
+	{schema: "NamespaceStats", model: NamespaceStats{}},
+	{schema: "ReaderStats", model: ReaderStats{}},
+	{schema: "OwnershipRule", model: OwnershipRule{}},
+	{schema: "OwnershipPolicy", model: OwnershipPolicy{}},
+	{schema: "KeyOwnership", model: KeyOwnership{}},
+	{schema: "BlobInfo", model: BlobInfo{}},
+	{schema: "ChunkRef", model: ChunkRef{}},
+	{schema: "ChunkManifest", model: ChunkManifest{}},
+	{schema: "Budget", model: Budget{}},
+	{schema: "Spend", model: Spend{}},
+	{schema: "BudgetUsage", model: BudgetUsage{}},
+	{schema: "User", model: User{}},
+	{schema: "Team", model: Team{}},
+	{schema: "Membership", model: Membership{}},
+	{schema: "ChangeReview", model: ChangeReview{}},
+	{schema: "ReviewChangeRequest", model: reviewChangeRequest{}},
+	{schema: "ChangeProposal", model: ChangeProposal{}},
+	{schema: "ValidationWebhook", model: ValidationWebhook{}},
+}
+
+func TestContractCoversEveryOperation(t *testing.T) {
+	spec := loadSpec(t)
+	for _, op := range spec.operations {
+		if _, ok := contractCalls[op.id]; !ok {
+			t.Errorf("operation %s (%s %s) has no contract call; add one to contractCalls", op.id, op.method, op.path)
+		}
+	}
+	for id := range contractCalls {
+		if spec.operation(id) == nil {
+			t.Errorf("contractCalls has %s, which is not in the spec", id)
+		}
+	}
+}
+
+func TestContractRequestsAndResponses(t *testing.T) {
+	spec := loadSpec(t)
+	for _, op := range spec.operations {
+		call, ok := contractCalls[op.id]
+		if !ok {
+			continue
+		}
+		t.Run(op.id, func(t *testing.T) {
+			srv := newContractServer(t, spec, "")
+			client := NewLLMConfigClient(srv.URL+"/api/v1", "token")
+
+			result, err := call(context.Background(), client)
+			if err != nil {
+				t.Fatalf("%s: %v", op.id, err)
+			}
+			// Some calls read first, e.g. DeleteNamespace lists child
+			// namespaces; serve has checked those requests too
+			if got := srv.served(); len(got) == 0 || got[len(got)-1] != op.id {
+				t.Fatalf("client called %v, want them to end with %s", got, op.id)
+			}
+
+			status, response := op.successResponse()
+			schema := spec.resolve(dig(response, "content", "application/json", "schema"))
+			if schema == nil {
+				if status != http.StatusNoContent && dig(response, "content", "application/json") != nil {
+					t.Fatalf("%s %d has no JSON schema", op.id, status)
+				}
+				return
+			}
+
+			// What the client decoded must still satisfy the schema, i.e. no
+			// required field was dropped on the way in
+			data, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded interface{}
+			json.Unmarshal(data, &decoded)
+			for _, problem := range spec.validate(schema, decoded, "response") {
+				t.Errorf("%s: decoded response: %s", op.id, problem)
+			}
+		})
+	}
+}
+
+func TestContractErrorResponses(t *testing.T) {
+	spec := loadSpec(t)
+	sentinels := map[int]error{
+		http.StatusBadRequest:      ErrValidation,
+		http.StatusUnauthorized:    ErrUnauthorized,
+		http.StatusForbidden:       ErrUnauthorized,
+		http.StatusNotFound:        ErrNotFound,
+		http.StatusTooManyRequests: ErrRateLimited,
+	}
+
+	op := spec.operation("getConfig")
+	for _, code := range sortedMapKeys(op.responses) {
+		status, _ := strconv.Atoi(code)
+		if status < 400 {
+			continue
+		}
+		t.Run(code, func(t *testing.T) {
+			srv := newContractServer(t, spec, code)
+			client := NewLLMConfigClient(srv.URL+"/api/v1", "token")
+			client.SetClock(NewFakeClock(time.Unix(0, 0)))
+
+			_, err := contractCalls["getConfig"](context.Background(), client)
+			var clientErr *ConfigClientError
+			if !errors.As(err, &clientErr) {
+				t.Fatalf("error = %v, want *ConfigClientError", err)
+			}
+
+			want := spec.example(dig(spec.resolve(op.responses[code]), "content", "application/json")).(map[string]interface{})
+			if clientErr.StatusCode != status || clientErr.Code != want["error"] || clientErr.Message != want["message"] {
+				t.Errorf("error = {%d %q %q}, want {%d %q %q}",
+					clientErr.StatusCode, clientErr.Code, clientErr.Message, status, want["error"], want["message"])
+			}
+			if sentinel, ok := sentinels[status]; ok && !errors.Is(err, sentinel) {
+				t.Errorf("errors.Is(err, %v) = false", sentinel)
+			}
+		})
+	}
+}
+
+func TestContractModels(t *testing.T) {
+	spec := loadSpec(t)
+	for _, m := range contractModels {
+		t.Run(m.schema, func(t *testing.T) {
+			schema := spec.resolve(dig(spec.doc, "components", "schemas", m.schema))
+			if schema == nil {
+				t.Fatalf("schema %s not in spec", m.schema)
+			}
+			props, _ := schema["properties"].(map[string]interface{})
+
+			fields := map[string]bool{}
+			typ := reflect.TypeOf(m.model)
+			// Embedded structs' fields are promoted into the JSON object
+			for _, field := range reflect.VisibleFields(typ) {
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+				if name == "" || name == "-" {
+					continue
+				}
+				fields[name] = true
+				if _, ok := props[name]; !ok && !slices.Contains(m.clientOnly, name) {
+					t.Errorf("%s.%s (json %q) is not a property of %s", typ.Name(), field.Name, name, m.schema)
+				}
+			}
+			for _, name := range sortedMapKeys(props) {
+				if !fields[name] && !slices.Contains(m.specOnly, name) {
+					t.Errorf("%s property %q has no field in %s", m.schema, name, typ.Name())
+				}
+			}
+		})
+	}
+}
+
+// openAPISpec is the parsed spec plus an index of its operations
+type openAPISpec struct {
+	doc        map[string]interface{}
+	operations []*specOperation
+}
+
+type specOperation struct {
+	id      string
+	method  string
+	path    string
+	pattern *regexp.Regexp
+	params  []map[string]interface{}
+	body    map[string]interface{}
+	// rawBody is set for operations taking a body other than JSON
+	rawBody    bool
+	responses  map[string]interface{}
+	pathParams []string
+}
+
+var (
+	specOnce   sync.Once
+	specLoaded *openAPISpec
+	specErr    error
+)
+
+func loadSpec(t *testing.T) *openAPISpec {
+	t.Helper()
+	specOnce.Do(func() {
+		data, err := os.ReadFile(specPath)
+		if err != nil {
+			specErr = err
+			return
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			specErr = err
+			return
+		}
+		specLoaded = &openAPISpec{doc: doc}
+		specLoaded.index()
+	})
+	if specErr != nil {
+		t.Fatalf("load %s: %v", specPath, specErr)
+	}
+	return specLoaded
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (s *openAPISpec) index() {
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	for _, path := range sortedMapKeys(paths) {
+		item := paths[path].(map[string]interface{})
+		for _, method := range []string{"get", "post", "put", "patch", "delete"} {
+			raw, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op := &specOperation{
+				id:     fmt.Sprint(raw["operationId"]),
+				method: strings.ToUpper(method),
+				path:   path,
+			}
+			var params []interface{}
+			if shared, ok := item["parameters"].([]interface{}); ok {
+				params = append(params, shared...)
+			}
+			if own, ok := raw["parameters"].([]interface{}); ok {
+				params = append(params, own...)
+			}
+			for _, p := range params {
+				op.params = append(op.params, s.resolve(p))
+			}
+			if body := s.resolve(raw["requestBody"]); body != nil {
+				op.body = s.resolve(dig(body, "content", "application/json", "schema"))
+				op.rawBody = op.body == nil
+			}
+			op.responses, _ = raw["responses"].(map[string]interface{})
+
+			expr := "^" + regexp.QuoteMeta(path) + "$"
+			for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+				op.pathParams = append(op.pathParams, m[1])
+				expr = strings.Replace(expr, regexp.QuoteMeta(m[0]), "([^/]+)", 1)
+			}
+			op.pattern = regexp.MustCompile(expr)
+			s.operations = append(s.operations, op)
+		}
+	}
+}
+
+func (s *openAPISpec) operation(id string) *specOperation {
+	for _, op := range s.operations {
+		if op.id == id {
+			return op
+		}
+	}
+	return nil
+}
+
+// successResponse returns the operation's lowest 2xx response
+func (op *specOperation) successResponse() (int, map[string]interface{}) {
+	for _, code := range sortedMapKeys(op.responses) {
+		if status, err := strconv.Atoi(code); err == nil && status >= 200 && status < 300 {
+			response, _ := op.responses[code].(map[string]interface{})
+			return status, response
+		}
+	}
+	return 0, nil
+}
+
+// resolve follows a local $ref, returning node itself when it has none
+func (s *openAPISpec) resolve(node interface{}) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+	for m != nil {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			break
+		}
+		var target interface{} = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			target = dig(target, part)
+		}
+		m, _ = target.(map[string]interface{})
+	}
+	return m
+}
+
+// example picks a media type's example: "example", else the first entry of
+// "examples" by name, else one synthesized from the schema
+func (s *openAPISpec) example(media map[string]interface{}) interface{} {
+	if ex, ok := media["example"]; ok {
+		return ex
+	}
+	if examples, ok := media["examples"].(map[string]interface{}); ok && len(examples) > 0 {
+		first := sortedMapKeys(examples)[0]
+		return s.resolve(examples[first])["value"]
+	}
+	return s.synthesize(s.resolve(media["schema"]))
+}
+
+// exampleFor is example for a request with query: named examples whose
+// x-query parameters all match it come first, then those without x-query
+func (s *openAPISpec) exampleFor(media map[string]interface{}, query url.Values) interface{} {
+	examples, _ := media["examples"].(map[string]interface{})
+	var fallback map[string]interface{}
+	for _, name := range sortedMapKeys(examples) {
+		ex := s.resolve(examples[name])
+		when, ok := ex["x-query"].(map[string]interface{})
+		if !ok {
+			if fallback == nil {
+				fallback = ex
+			}
+			continue
+		}
+		matches := true
+		for param, value := range when {
+			if query.Get(param) != fmt.Sprint(value) {
+				matches = false
+			}
+		}
+		if matches {
+			return ex["value"]
+		}
+	}
+	if fallback != nil {
+		return fallback["value"]
+	}
+	return s.example(media)
+}
+
+// synthesize builds a minimal value satisfying schema
+func (s *openAPISpec) synthesize(schema map[string]interface{}) interface{} {
+	if ex, ok := schema["example"]; ok {
+		return ex
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok && len(oneOf) > 0 {
+		return s.synthesize(s.resolve(oneOf[0]))
+	}
+	switch schema["type"] {
+	case "object":
+		out := map[string]interface{}{}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range sortedMapKeys(props) {
+			out[name] = s.synthesize(s.resolve(props[name]))
+		}
+		return out
+	case "array":
+		return []interface{}{s.synthesize(s.resolve(schema["items"]))}
+	case "integer", "number":
+		return 1
+	case "boolean":
+		return false
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	return "x"
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validate checks a JSON-decoded value against schema, returning one
+// problem per violation
+func (s *openAPISpec) validate(schema map[string]interface{}, v interface{}, at string) []string {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if v == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": null is not allowed"}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		for _, alt := range oneOf {
+			if len(s.validate(s.resolve(alt), v, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: %v matches none of the oneOf alternatives", at, v)}
+	}
+
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema["type"] {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("%v is not a string", v)
+			break
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(str) {
+			fail("%q does not match %s", str, pattern)
+		}
+		if minLen, ok := schema["minLength"].(int); ok && len(str) < minLen {
+			fail("%q is shorter than %d", str, minLen)
+		}
+		if maxLen, ok := schema["maxLength"].(int); ok && len(str) > maxLen {
+			fail("%q is longer than %d", str, maxLen)
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				fail("%q is not an RFC 3339 date-time", str)
+			}
+		case "uuid":
+			if !uuidPattern.MatchString(str) {
+				fail("%q is not a UUID", str)
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			fail("%v is not a number", v)
+			break
+		}
+		if schema["type"] == "integer" && n != float64(int64(n)) {
+			fail("%v is not an integer", v)
+		}
+		if minimum, ok := schema["minimum"].(int); ok && n < float64(minimum) {
+			fail("%v is below the minimum %d", v, minimum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("%v is not a boolean", v)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			fail("%v is not an array", v)
+			break
+		}
+		for i, item := range items {
+			problems = append(problems, s.validate(s.resolve(schema["items"]), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("%v is not an object", v)
+			break
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				fail("required property %q is missing", name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range sortedMapKeys(obj) {
+			if prop, ok := props[name]; ok {
+				problems = append(problems, s.validate(s.resolve(prop), obj[name], at+"."+name)...)
+			}
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(v) {
+				found = true
+			}
+		}
+		if !found {
+			fail("%v is not one of %v", v, enum)
+		}
+	}
+	return problems
+}
+
+// contractServer answers each request from the spec, failing the test when a
+// request does not fit the operation it targets
+type contractServer struct {
+	*httptest.Server
+	t      *testing.T
+	spec   *openAPISpec
+	status string
+
+	mu  sync.Mutex
+	ops []string
+}
+
+// newContractServer serves each operation's lowest 2xx response, or the
+// response for status when one is given
+func newContractServer(t *testing.T, spec *openAPISpec, status string) *contractServer {
+	s := &contractServer{t: t, spec: spec, status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *contractServer) served() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ops...)
+}
+
+func (s *contractServer) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v1")
+	var op *specOperation
+	var match []string
+	for _, candidate := range s.spec.operations {
+		if candidate.method != r.Method {
+			continue
+		}
+		if match = candidate.pattern.FindStringSubmatch(path); match != nil {
+			op = candidate
+			break
+		}
+	}
+	if op == nil {
+		s.t.Errorf("%s %s matches no operation in the spec", r.Method, path)
+		http.Error(w, "no such operation", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	s.ops = append(s.ops, op.id)
+	s.mu.Unlock()
+
+	for _, problem := range s.requestProblems(op, r, match) {
+		s.t.Errorf("%s request: %s", op.id, problem)
+	}
+
+	code := s.status
+	if code == "" {
+		status, _ := op.successResponse()
+		code = strconv.Itoa(status)
+	}
+	response := s.spec.resolve(op.responses[code])
+	if response == nil {
+		// Fatalf must not be called from the handler's goroutine
+		s.t.Errorf("%s declares no %s response", op.id, code)
+		http.Error(w, "no such response", http.StatusNotImplemented)
+		return
+	}
+	status, _ := strconv.Atoi(code)
+
+	headers, _ := response["headers"].(map[string]interface{})
+	for _, name := range sortedMapKeys(headers) {
+		if ex, ok := s.spec.resolve(headers[name])["example"]; ok {
+			w.Header().Set(name, fmt.Sprint(ex))
+		}
+	}
+
+	media := dig(response, "content", "application/json")
+	if media == nil {
+		// Binary content is served from its example as is
+		content, _ := response["content"].(map[string]interface{})
+		for _, mediaType := range sortedMapKeys(content) {
+			if ex, ok := dig(content, mediaType)["example"].(string); ok {
+				w.Header().Set("Content-Type", mediaType)
+				w.WriteHeader(status)
+				io.WriteString(w, ex)
+				return
+			}
+		}
+		w.WriteHeader(status)
+		return
+	}
+	body, err := json.Marshal(s.spec.exampleFor(media, r.URL.Query()))
+	if err != nil {
+		s.t.Errorf("%s: marshal example: %v", op.id, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// requestProblems checks path parameters, query parameters, and the JSON
+// body against op
+func (s *contractServer) requestProblems(op *specOperation, r *http.Request, match []string) []string {
+	var problems []string
+
+	declared := map[string]bool{}
+	for _, p := range op.params {
+		name, _ := p["name"].(string)
+		schema := s.spec.resolve(p["schema"])
+		switch p["in"] {
+		case "path":
+			for i, pathParam := range op.pathParams {
+				if pathParam != name {
+					continue
+				}
+				value, err := url.PathUnescape(match[i+1])
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("path %s: %v", name, err))
+					continue
+				}
+				problems = append(problems, s.spec.validate(schema, queryValue(schema, value), "path "+name)...)
+			}
+		case "query":
+			declared[name] = true
+			values, present := r.URL.Query()[name]
+			if !present {
+				if p["required"] == true {
+					problems = append(problems, "missing required query parameter "+name)
+				}
+				continue
+			}
+			problems = append(problems, s.spec.validate(schema, queryValue(schema, values[0]), "query "+name)...)
+		}
+	}
+	for _, name := range sortedMapKeys(r.URL.Query()) {
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("query parameter %q is not declared", name))
+		}
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case op.body == nil && !op.rawBody && len(bytes.TrimSpace(body)) > 0:
+		problems = append(problems, "sent a body, but the operation takes none")
+	case op.body != nil:
+		var decoded interface{}
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			problems = append(problems, fmt.Sprintf("body is not JSON: %v", err))
+			break
+		}
+		problems = append(problems, s.spec.validate(op.body, decoded, "body")...)
+	}
+	return problems
+}
+
+// queryValue converts a raw query or path string to the JSON type schema
+// expects
+func queryValue(schema map[string]interface{}, raw string) interface{} {
+	switch schema["type"] {
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	}
+	return raw
+}
+
+// dig walks nested maps by key, returning nil when any step is missing
+func dig(node interface{}, keys ...string) map[string]interface{} {
+	for _, key := range keys {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[key]
+	}
+	m, _ := node.(map[string]interface{})
+	return m
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CredentialInfo describes a vaulted provider credential without its secret
+type CredentialInfo struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	// Version increases on every rotation
+	Version int64 `json:"version"`
+	// Fingerprint identifies the secret (e.g. "sk-...a1b2") for logs
+	Fingerprint string `json:"fingerprint"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   string `json:"created_by"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	// PreviousValidUntil is when the version before this one stops being
+	// served after a rotation with a grace period
+	PreviousValidUntil string `json:"previous_valid_until,omitempty"`
+}
+
+// CredentialSecret is the current secret of a credential
+type CredentialSecret struct {
+	Name    string `json:"name"`
+	Version int64  `json:"version"`
+	Secret  string `json:"secret"`
+}
+
+type putCredentialRequest struct {
+	Provider string `json:"provider"`
+	Secret   string `json:"secret"`
+	User     string `json:"user"`
+}
+
+type rotateCredentialRequest struct {
+	Secret             string `json:"secret"`
+	GracePeriodSeconds int64  `json:"grace_period_seconds"`
+	User               string `json:"user"`
+}
+
+// PutCredential stores the secret for a provider credential. Reference it
+// from a ProviderProfile with auth_ref "credential:NAME".
+func (c *LLMConfigClient) PutCredential(ctx context.Context, name, provider, secret, user string) (*CredentialInfo, error) {
+	var result CredentialInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(putCredentialRequest{Provider: provider, Secret: secret, User: user}).
+		SetResult(&result).
+		Put(fmt.Sprintf("/credentials/%s", url.PathEscape(name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// RotateCredential replaces the secret with a new version. The previous
+// secret keeps being accepted by whoever validates it for grace, giving
+// LiveCredential holders time to pick up the new one.
+func (c *LLMConfigClient) RotateCredential(ctx context.Context, name, secret string, grace time.Duration, user string) (*CredentialInfo, error) {
+	var result CredentialInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(rotateCredentialRequest{
+			Secret:             secret,
+			GracePeriodSeconds: int64(grace / time.Second),
+			User:               user,
+		}).
+		SetResult(&result).
+		Post(fmt.Sprintf("/credentials/%s/rotate", url.PathEscape(name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetCredential returns a credential's metadata
+func (c *LLMConfigClient) GetCredential(ctx context.Context, name string) (*CredentialInfo, error) {
+	var result CredentialInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/credentials/%s", url.PathEscape(name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListCredentials returns every credential's metadata, sorted by name
+func (c *LLMConfigClient) ListCredentials(ctx context.Context) ([]CredentialInfo, error) {
+	var result []CredentialInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/credentials")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// GetCredentialSecret reads a credential's current secret
+func (c *LLMConfigClient) GetCredentialSecret(ctx context.Context, name string) (*CredentialSecret, error) {
+	var result CredentialSecret
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/credentials/%s/secret", url.PathEscape(name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// CredentialRotation is delivered to OnRotate callbacks
+type CredentialRotation struct {
+	Name       string
+	OldVersion int64
+	NewVersion int64
+	Secret     string
+}
+
+// LiveCredential holds a credential's current secret and swaps it when the
+// credential is rotated, so long-running workers never restart for a new
+// key:
+//
+//	key, err := client.LiveCredential(ctx, "openai-prod", time.Minute)
+//	...
+//	req.Header.Set("Authorization", "Bearer "+key.Secret())
+type LiveCredential struct {
+	client   *LLMConfigClient
+	name     string
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu        sync.RWMutex
+	current   CredentialSecret
+	listeners []func(CredentialRotation)
+}
+
+// LiveCredential fetches name and checks for rotations every interval (a
+// minute when zero) until ctx ends or Close is called. Checks read only
+// metadata; the secret is refetched when the version changes.
+func (c *LLMConfigClient) LiveCredential(ctx context.Context, name string, interval time.Duration) (*LiveCredential, error) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	secret, err := c.GetCredentialSecret(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("credential %s: %w", name, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l := &LiveCredential{
+		client:   c,
+		name:     name,
+		interval: interval,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		current:  *secret,
+	}
+	go l.run(ctx)
+	return l, nil
+}
+
+// Secret returns the current secret
+func (l *LiveCredential) Secret() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.current.Secret
+}
+
+// Version returns the version of the current secret
+func (l *LiveCredential) Version() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.current.Version
+}
+
+// OnRotate calls fn after each swap to a new secret, e.g. to rebuild a
+// provider SDK client. fn runs on the checking goroutine.
+func (l *LiveCredential) OnRotate(fn func(CredentialRotation)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
+// Refresh checks for a rotation now, e.g. after the provider rejects the
+// current key
+func (l *LiveCredential) Refresh(ctx context.Context) error {
+	info, err := l.client.GetCredential(ctx, l.name)
+	if err != nil {
+		return fmt.Errorf("credential %s: %w", l.name, err)
+	}
+	if info.Version == l.Version() {
+		return nil
+	}
+
+	secret, err := l.client.GetCredentialSecret(ctx, l.name)
+	if err != nil {
+		return fmt.Errorf("credential %s: %w", l.name, err)
+	}
+
+	l.mu.Lock()
+	if secret.Version <= l.current.Version {
+		l.mu.Unlock()
+		return nil
+	}
+	rotation := CredentialRotation{
+		Name:       l.name,
+		OldVersion: l.current.Version,
+		NewVersion: secret.Version,
+		Secret:     secret.Secret,
+	}
+	l.current = *secret
+	listeners := slices.Clone(l.listeners)
+	l.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(rotation)
+	}
+	return nil
+}
+
+// Close stops checking for rotations; Secret keeps returning the last one
+func (l *LiveCredential) Close() {
+	l.cancel()
+	<-l.done
+}
+
+func (l *LiveCredential) run(ctx context.Context) {
+	defer close(l.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.client.clock.After(l.interval):
+		}
+		if err := l.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("llm-config: %v", err)
+		}
+	}
+}
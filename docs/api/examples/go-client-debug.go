@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// debugBodyLimit caps how much of each body a debug log line includes
+const debugBodyLimit = 1024
+
+// debugMask replaces secret values in debug output
+const debugMask = "***"
+
+// debugState is installed with the client and toggled at runtime
+type debugState struct {
+	enabled atomic.Bool
+}
+
+// installDebug logs exchanges while WithDebug is on
+func (c *LLMConfigClient) installDebug() {
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		if c.debug.enabled.Load() {
+			logDebugExchange(resp.Request, resp.StatusCode(), resp.Time(), resp.Body())
+		}
+		return nil
+	})
+	c.httpClient.OnError(func(req *resty.Request, err error) {
+		if c.debug.enabled.Load() {
+			log.Printf("llm-config debug: [%s] %s %s failed: %v", req.Header.Get(requestIDHeader), req.Method, req.URL, err)
+		}
+	})
+}
+
+// WithDebug turns request/response logging on or off; it can be flipped at
+// any time, e.g. from a signal handler or admin endpoint. Each request logs
+// its method, URL, status, latency, and bodies truncated to 1KiB, with
+// secret values and credential-looking keys masked. Headers, including
+// Authorization, are never logged.
+func (c *LLMConfigClient) WithDebug(enabled bool) *LLMConfigClient {
+	c.debug.enabled.Store(enabled)
+	return c
+}
+
+func logDebugExchange(req *resty.Request, status int, latency time.Duration, respBody []byte) {
+	url := req.URL
+	if req.RawRequest != nil {
+		url = req.RawRequest.URL.String()
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = json.Marshal(req.Body)
+	}
+
+	log.Printf("llm-config debug: [%s] %s %s -> %d (%s) request=%s response=%s",
+		req.Header.Get(requestIDHeader), req.Method, url, status, latency.Round(time.Millisecond),
+		sanitizeDebugBody(reqBody), sanitizeDebugBody(respBody))
+}
+
+// sanitizeDebugBody masks secrets in a JSON body and truncates it
+func sanitizeDebugBody(body []byte) string {
+	if len(body) == 0 {
+		return "-"
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err == nil {
+		if masked, err := json.Marshal(maskDebugValue(doc)); err == nil {
+			body = masked
+		}
+	}
+
+	if len(body) > debugBodyLimit {
+		return string(body[:debugBodyLimit]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// maskDebugValue walks a decoded JSON document masking the value of secret
+// writes ({"secret": true, "value": ...}), of config entries whose key looks
+// like a credential, and of any field whose name looks like a credential
+func maskDebugValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		secret, _ := val["secret"].(bool)
+		if key, ok := val["key"].(string); ok && secretKeyPattern.MatchString(key) {
+			secret = true
+		}
+		for k, child := range val {
+			switch {
+			case k == "value" && secret:
+				val[k] = debugMask
+			case k == "secret" && isBool(child):
+				// the write's secret flag, not a credential
+			case secretKeyPattern.MatchString(k):
+				val[k] = debugMask
+			default:
+				val[k] = maskDebugValue(child)
+			}
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = maskDebugValue(child)
+		}
+		return val
+	}
+	return v
+}
+
+func isBool(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok
+}
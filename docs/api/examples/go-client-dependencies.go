@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DependencyRule ties keys in a namespace together, e.g. "when provider is
+// azure, azure_deployment_id must be set":
+//
+//	DependencyRule{
+//		Name:        "azure-deployment",
+//		IfKey:       "provider",
+//		IfEquals:    "azure",
+//		RequireKeys: []string{"azure_deployment_id"},
+//	}
+type DependencyRule struct {
+	Name string `json:"name"`
+	// IfKey is the key whose value triggers the rule
+	IfKey string `json:"if_key"`
+	// IfEquals is the triggering value; the rule applies whenever IfKey is
+	// set when nil
+	IfEquals interface{} `json:"if_equals,omitempty"`
+	// RequireKeys must be set (and non-empty) when the rule applies
+	RequireKeys []string `json:"require_keys,omitempty"`
+	// ForbidKeys must not be set when the rule applies
+	ForbidKeys []string `json:"forbid_keys,omitempty"`
+}
+
+// DependencyViolation is a rule a namespace snapshot does not satisfy
+type DependencyViolation struct {
+	Rule    string `json:"rule"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// dependencyRegistry holds cross-key rules by namespace
+type dependencyRegistry struct {
+	mu    sync.RWMutex
+	rules map[string][]DependencyRule
+}
+
+func newDependencyRegistry() *dependencyRegistry {
+	return &dependencyRegistry{rules: map[string][]DependencyRule{}}
+}
+
+// AddDependencyRule enforces rule on writes to namespace made by this client
+func (c *LLMConfigClient) AddDependencyRule(namespace string, rule DependencyRule) error {
+	if rule.IfKey == "" {
+		return fmt.Errorf("dependency rule %q: if_key is required", rule.Name)
+	}
+	if len(rule.RequireKeys) == 0 && len(rule.ForbidKeys) == 0 {
+		return fmt.Errorf("dependency rule %q: needs require_keys or forbid_keys", rule.Name)
+	}
+	if rule.Name == "" {
+		rule.Name = rule.IfKey
+	}
+
+	c.dependencies.mu.Lock()
+	defer c.dependencies.mu.Unlock()
+	c.dependencies.rules[namespace] = append(c.dependencies.rules[namespace], rule)
+	return nil
+}
+
+// ValidateNamespace checks every dependency rule for namespace against the
+// values currently stored in env
+func (c *LLMConfigClient) ValidateNamespace(ctx context.Context, namespace, env string) ([]DependencyViolation, error) {
+	rules := c.dependencyRules(namespace)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	values, err := c.namespaceValues(ctx, namespace, env)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateDependencies(rules, values), nil
+}
+
+// checkDependencies evaluates the rules that mention key against the stored
+// namespace with the pending value applied. Keys a rule requires must
+// therefore be written before the key that triggers it.
+func (c *LLMConfigClient) checkDependencies(ctx context.Context, namespace, key string, req SetConfigRequest) ([]ValidationProblem, error) {
+	var relevant []DependencyRule
+	for _, rule := range c.dependencyRules(namespace) {
+		if rule.IfKey == key || slices.Contains(rule.RequireKeys, key) || slices.Contains(rule.ForbidKeys, key) {
+			relevant = append(relevant, rule)
+		}
+	}
+	if len(relevant) == 0 {
+		return nil, nil
+	}
+
+	values, err := c.namespaceValues(ctx, namespace, req.Env)
+	if err != nil {
+		return nil, fmt.Errorf("check dependency rules for %s/%s: %w", namespace, key, err)
+	}
+	values[key] = req.Value
+
+	var problems []ValidationProblem
+	for _, v := range evaluateDependencies(relevant, values) {
+		problems = append(problems, ValidationProblem{Message: fmt.Sprintf("rule %s: %s", v.Rule, v.Message)})
+	}
+	return problems, nil
+}
+
+func (c *LLMConfigClient) dependencyRules(namespace string) []DependencyRule {
+	c.dependencies.mu.RLock()
+	defer c.dependencies.mu.RUnlock()
+	return append([]DependencyRule(nil), c.dependencies.rules[namespace]...)
+}
+
+func (c *LLMConfigClient) namespaceValues(ctx context.Context, namespace, env string) (map[string]interface{}, error) {
+	configs, err := c.ListConfigsContext(ctx, namespace, env)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(configs))
+	for _, cfg := range configs {
+		values[cfg.Key] = cfg.Value
+	}
+	return values, nil
+}
+
+// evaluateDependencies returns violations sorted by rule and key
+func evaluateDependencies(rules []DependencyRule, values map[string]interface{}) []DependencyViolation {
+	var violations []DependencyViolation
+	for _, rule := range rules {
+		trigger, ok := values[rule.IfKey]
+		if !ok {
+			continue
+		}
+		if rule.IfEquals != nil && !reflect.DeepEqual(canonicalValue(trigger), canonicalValue(rule.IfEquals)) {
+			continue
+		}
+
+		condition := rule.IfKey + " is set"
+		if rule.IfEquals != nil {
+			condition = fmt.Sprintf("%s=%v", rule.IfKey, rule.IfEquals)
+		}
+		for _, required := range rule.RequireKeys {
+			if v, ok := values[required]; !ok || isEmptyValue(v) {
+				violations = append(violations, DependencyViolation{
+					Rule:    rule.Name,
+					Key:     required,
+					Message: fmt.Sprintf("%s must be set when %s", required, condition),
+				})
+			}
+		}
+		for _, forbidden := range rule.ForbidKeys {
+			if _, ok := values[forbidden]; ok {
+				violations = append(violations, DependencyViolation{
+					Rule:    rule.Name,
+					Key:     forbidden,
+					Message: fmt.Sprintf("%s must not be set when %s", forbidden, condition),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Rule != violations[j].Rule {
+			return violations[i].Rule < violations[j].Rule
+		}
+		return violations[i].Key < violations[j].Key
+	})
+	return violations
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(val) == ""
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		return len(val) == 0
+	}
+	return false
+}
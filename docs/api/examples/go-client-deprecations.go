@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyDeprecation marks a key as on its way out. It applies in every
+// environment and is returned with reads of the key.
+type KeyDeprecation struct {
+	Message string `json:"message"`
+	// ReplacedBy is the key to read instead, as "namespace/key"
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// RemoveAfter is when the key may be deleted
+	RemoveAfter  *time.Time `json:"remove_after,omitempty"`
+	DeprecatedBy string     `json:"deprecated_by,omitempty"`
+	DeprecatedAt time.Time  `json:"deprecated_at"`
+}
+
+// String is the warning logged on reads, e.g. "use gpt-4o; read
+// app/llm/model_v2 instead (removal after 2025-01-31)"
+func (d *KeyDeprecation) String() string {
+	var b strings.Builder
+	b.WriteString(d.Message)
+	if d.ReplacedBy != "" {
+		if b.Len() > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "read %s instead", d.ReplacedBy)
+	}
+	if d.RemoveAfter != nil {
+		fmt.Fprintf(&b, " (removal after %s)", d.RemoveAfter.Format(time.DateOnly))
+	}
+	return b.String()
+}
+
+// DeprecatedKey is a deprecated key as ListDeprecations reports it
+type DeprecatedKey struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	KeyDeprecation
+}
+
+type deprecateRequest struct {
+	Message     string     `json:"message"`
+	ReplacedBy  string     `json:"replaced_by,omitempty"`
+	RemoveAfter *time.Time `json:"remove_after,omitempty"`
+	User        string     `json:"user"`
+}
+
+// deprecationWarnings remembers which deprecated keys have been logged, so
+// each is reported once per client rather than on every read
+type deprecationWarnings struct {
+	mu     sync.Mutex
+	warned map[string]bool
+}
+
+// observeDeprecatedRead logs the first read of a deprecated key and counts
+// every read in the deprecated_reads_total metric. Gets, lists, streams,
+// and the watchers and agent built on them all report here.
+func (c *LLMConfigClient) observeDeprecatedRead(namespace, key string, d *KeyDeprecation) {
+	if d == nil {
+		return
+	}
+	c.metrics.Load().observeDeprecatedRead(namespace, key)
+
+	path := namespace + "/" + key
+	c.deprecations.mu.Lock()
+	defer c.deprecations.mu.Unlock()
+	if !c.deprecations.warned[path] {
+		c.deprecations.warned[path] = true
+		log.Printf("llm-config: %s is deprecated: %s", path, d)
+	}
+}
+
+// Deprecate marks namespace/key as deprecated with a message and,
+// optionally, the key replacing it and when it will be removed. The key
+// keeps working; clients reading it log a warning once and count the reads
+// in llm_config_client_deprecated_reads_total, so consumers can be found
+// and migrated before the key is deleted. Deprecating it again replaces the
+// message.
+func (c *LLMConfigClient) Deprecate(ctx context.Context, namespace, key string, d KeyDeprecation, user string) (*KeyDeprecation, error) {
+	if d.Message == "" && d.ReplacedBy == "" {
+		return nil, fmt.Errorf("deprecate %s/%s: a message or replacement is required: %w", namespace, key, ErrValidation)
+	}
+	if d.ReplacedBy == namespace+"/"+key {
+		return nil, fmt.Errorf("deprecate %s/%s: a key cannot replace itself: %w", namespace, key, ErrValidation)
+	}
+
+	var result KeyDeprecation
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(deprecateRequest{Message: d.Message, ReplacedBy: d.ReplacedBy, RemoveAfter: d.RemoveAfter, User: user}).
+		SetResult(&result).
+		Put(keyRoute(namespace, key, "deprecation"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// Undeprecate clears a key's deprecation, returning false when it had none
+func (c *LLMConfigClient) Undeprecate(ctx context.Context, namespace, key string) (bool, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(keyRoute(namespace, key, "deprecation"))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
+
+// ListDeprecations returns the deprecated keys in namespace and those under
+// it (all when empty), sorted by namespace and key
+func (c *LLMConfigClient) ListDeprecations(ctx context.Context, namespace string) ([]DeprecatedKey, error) {
+	var result []DeprecatedKey
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if namespace != "" {
+		req.SetQueryParam("namespace", namespace)
+	}
+
+	resp, err := req.Get("/deprecations")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DevServer serves the REST API's config routes from a Backend, so go test
+// suites and laptops get a working config manager with no server to run.
+// Over a FileBackend it reads and writes a directory of manifests:
+//
+//	srv := httptest.NewServer((&DevServer{Backend: NewFileBackend(t.TempDir())}).Handler())
+//	defer srv.Close()
+//	client := NewLLMConfigClient(srv.URL+"/api/v1", "")
+//
+// or standalone with `llmconfig serve --dir ./configs`. It answers
+//
+//	GET    /health, /api/v1/health
+//	GET    /api/versions
+//	GET    /api/v1/configs/{namespace}
+//	GET    /api/v1/configs/{namespace}/{key}
+//	POST   /api/v1/configs/{namespace}/{key}
+//	DELETE /api/v1/configs/{namespace}/{key}
+//	GET    /api/v1/configs/{namespace}/{key}/history
+//	POST   /api/v1/configs/{namespace}/{key}/rollback/{version}
+//	GET    /{application}/{profile}[/{label}]
+//
+// in the server's shapes, history and rollback only when the Backend is
+// also a ConfigHistorian, and the last in Spring Cloud Config's (see
+// SpringConfigServer). Namespaces with slashes are sent escaped
+// (app%2Fllm). Everything else answers 404.
+type DevServer struct {
+	Backend Backend
+	// Token, when set, is required as the bearer token of API requests
+	Token string
+}
+
+// devServerVersion is reported by the health check
+const devServerVersion = "dev"
+
+// Handler returns the server's routes
+func (s *DevServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	health := func(w http.ResponseWriter, r *http.Request) {
+		writeDevJSON(w, http.StatusOK, HealthResponse{Status: "healthy", Service: "llm-config-manager", Version: devServerVersion})
+	}
+	mux.HandleFunc("GET /health", health)
+	mux.HandleFunc("GET /api/v1/health", health)
+	mux.HandleFunc("GET /api/versions", func(w http.ResponseWriter, r *http.Request) {
+		writeDevJSON(w, http.StatusOK, map[string][]APIVersion{"versions": {APIVersion1}})
+	})
+	mux.HandleFunc("GET /api/v1/configs/{namespace}", s.authorized(s.serveList))
+	mux.HandleFunc("GET /api/v1/configs/{namespace}/{key}", s.authorized(s.serveGet))
+	mux.HandleFunc("POST /api/v1/configs/{namespace}/{key}", s.authorized(s.serveSet))
+	mux.HandleFunc("DELETE /api/v1/configs/{namespace}/{key}", s.authorized(s.serveDelete))
+	mux.HandleFunc("GET /api/v1/configs/{namespace}/{key}/history", s.authorized(s.serveHistory))
+	mux.HandleFunc("POST /api/v1/configs/{namespace}/{key}/rollback/{version}", s.authorized(s.serveRollback))
+	spring := &SpringConfigServer{Reader: s.Backend}
+	mux.HandleFunc("GET /{application}/{profile}", s.authorized(spring.serveEnvironment))
+	mux.HandleFunc("GET /{application}/{profile}/{label}", s.authorized(spring.serveEnvironment))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeDevError(w, http.StatusNotFound, "no route")
+	})
+	return mux
+}
+
+// Serve listens on addr until ctx is done, then shuts down gracefully
+func (s *DevServer) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// authorized checks the bearer token before calling next
+func (s *DevServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeDevError(w, http.StatusUnauthorized, "Missing or invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *DevServer) serveList(w http.ResponseWriter, r *http.Request) {
+	configs, err := s.Backend.ListConfigsContext(r.Context(), r.PathValue("namespace"), r.URL.Query().Get("env"))
+	if err != nil {
+		writeDevBackendError(w, err)
+		return
+	}
+	writeDevJSON(w, http.StatusOK, configs)
+}
+
+func (s *DevServer) serveGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cfg, err := s.Backend.GetConfigContext(r.Context(), r.PathValue("namespace"), r.PathValue("key"), query.Get("env"), query.Get("with_overrides") == "true")
+	if err != nil {
+		writeDevBackendError(w, err)
+		return
+	}
+	writeDevJSON(w, http.StatusOK, cfg)
+}
+
+func (s *DevServer) serveSet(w http.ResponseWriter, r *http.Request) {
+	var req SetConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDevError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if req.User == "" {
+		req.User = "api-user"
+	}
+	cfg, err := s.Backend.SetConfigContext(r.Context(), r.PathValue("namespace"), r.PathValue("key"), req.Value, req.Env, req.User, req.Secret)
+	if err != nil {
+		writeDevBackendError(w, err)
+		return
+	}
+	writeDevJSON(w, http.StatusOK, cfg)
+}
+
+func (s *DevServer) serveDelete(w http.ResponseWriter, r *http.Request) {
+	namespace, key := r.PathValue("namespace"), r.PathValue("key")
+	deleted, err := s.Backend.DeleteConfigContext(r.Context(), namespace, key, r.URL.Query().Get("env"))
+	if err != nil {
+		writeDevBackendError(w, err)
+		return
+	}
+	if !deleted {
+		writeDevError(w, http.StatusNotFound, "Configuration not found: "+namespace+":"+key)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *DevServer) serveHistory(w http.ResponseWriter, r *http.Request) {
+	historian, ok := s.Backend.(ConfigHistorian)
+	if !ok {
+		writeDevError(w, http.StatusNotFound, "history is not kept by this backend")
+		return
+	}
+	history, err := historian.GetHistoryContext(r.Context(), r.PathValue("namespace"), r.PathValue("key"), r.URL.Query().Get("env"))
+	if err != nil {
+		writeDevBackendError(w, err)
+		return
+	}
+	writeDevJSON(w, http.StatusOK, history)
+}
+
+func (s *DevServer) serveRollback(w http.ResponseWriter, r *http.Request) {
+	historian, ok := s.Backend.(ConfigHistorian)
+	if !ok {
+		writeDevError(w, http.StatusNotFound, "history is not kept by this backend")
+		return
+	}
+	version, err := strconv.ParseInt(r.PathValue("version"), 10, 64)
+	if err != nil {
+		writeDevError(w, http.StatusBadRequest, "Invalid version: "+r.PathValue("version"))
+		return
+	}
+	cfg, err := historian.RollbackContext(r.Context(), r.PathValue("namespace"), r.PathValue("key"), version, r.URL.Query().Get("env"))
+	if err != nil {
+		writeDevBackendError(w, err)
+		return
+	}
+	writeDevJSON(w, http.StatusOK, cfg)
+}
+
+// writeDevBackendError answers with the status matching err's sentinel
+func writeDevBackendError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrValidation):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, ErrUnauthorized):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrRateLimited):
+		status = http.StatusTooManyRequests
+	}
+	writeDevError(w, status, err.Error())
+}
+
+func writeDevJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeDevError writes the server's {"error", "message"} error body
+func writeDevError(w http.ResponseWriter, status int, message string) {
+	writeDevJSON(w, status, ErrorResponse{Error: http.StatusText(status), Message: message})
+}
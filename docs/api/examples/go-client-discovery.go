@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDiscoveryRefresh is how often endpoints are looked up again when
+// WithDiscovery is given no interval
+const defaultDiscoveryRefresh = 30 * time.Second
+
+// Endpoint is one server instance found by discovery
+type Endpoint struct {
+	// Host is the host:port to connect to
+	Host string
+	// Priority and Weight choose among endpoints as with SRV records: the
+	// lowest priority is used while it has endpoints, and requests are
+	// spread over those in proportion to their weights
+	Priority uint16
+	Weight   uint16
+}
+
+// Discoverer looks up the server's current endpoints, from DNS (see
+// SRVDiscoverer), a service registry, or anything else
+type Discoverer func(ctx context.Context) ([]Endpoint, error)
+
+// SRVDiscoverer looks up the endpoints in the SRV records of
+// _service._proto.name, e.g. SRVDiscoverer("llm-config", "tcp",
+// "example.com") for _llm-config._tcp.example.com; with service and proto
+// empty, name is looked up as is
+func SRVDiscoverer(service, proto, name string) Discoverer {
+	return func(ctx context.Context) ([]Endpoint, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, service, proto, name)
+		if err != nil {
+			return nil, err
+		}
+		endpoints := make([]Endpoint, 0, len(records))
+		for _, srv := range records {
+			host := strings.TrimSuffix(srv.Target, ".")
+			endpoints = append(endpoints, Endpoint{
+				Host:     net.JoinHostPort(host, strconv.Itoa(int(srv.Port))),
+				Priority: srv.Priority,
+				Weight:   srv.Weight,
+			})
+		}
+		return endpoints, nil
+	}
+}
+
+// WithDiscovery sends each request to an endpoint found by discover rather
+// than the base URL's host, so the client follows the servers as they are
+// added, removed, or moved without a redeploy. The base URL keeps
+// supplying the scheme and path:
+//
+//	client := NewLLMConfigClient("https://llm-config/api/v1", token)
+//	err := client.WithDiscovery(SRVDiscoverer("llm-config", "tcp", "prod.example.com"), time.Minute)
+//
+// The endpoints are looked up now, failing when there are none, and again
+// in the background once a request finds them older than refresh (30s
+// when zero). A failed lookup keeps the last endpoints. TLS is verified
+// against the endpoint's host name. Call it before making requests.
+func (c *LLMConfigClient) WithDiscovery(discover Discoverer, refresh time.Duration) error {
+	if refresh <= 0 {
+		refresh = defaultDiscoveryRefresh
+	}
+	t := &discoveryTransport{
+		client:   c,
+		discover: discover,
+		refresh:  refresh,
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := t.lookup(ctx); err != nil {
+		return fmt.Errorf("discover endpoints: %w", err)
+	}
+
+	t.base = c.httpClient.GetClient().Transport
+	if t.base == nil {
+		t.base = http.DefaultTransport
+	}
+	c.httpClient.SetTransport(t)
+	return nil
+}
+
+// Endpoints reports the endpoints discovery last found, nil without
+// discovery
+func (c *LLMConfigClient) Endpoints() []Endpoint {
+	for rt := c.httpClient.GetClient().Transport; rt != nil; {
+		if t, ok := rt.(*discoveryTransport); ok {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			return append([]Endpoint(nil), t.endpoints...)
+		}
+		w, ok := rt.(wrappingTransport)
+		if !ok {
+			break
+		}
+		rt = w.wrapped()
+	}
+	return nil
+}
+
+// discoveryTransport is the transport installed by WithDiscovery
+type discoveryTransport struct {
+	base     http.RoundTripper
+	client   *LLMConfigClient
+	discover Discoverer
+	refresh  time.Duration
+
+	mu         sync.Mutex
+	endpoints  []Endpoint
+	resolvedAt time.Time
+	refreshing bool
+	rng        *rand.Rand
+}
+
+func (t *discoveryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := t.pick()
+	out := req.Clone(req.Context())
+	out.URL.Host = endpoint.Host
+	out.Host = ""
+	return t.base.RoundTrip(out)
+}
+
+// pick chooses an endpoint as RFC 2782 does, starting a lookup in the
+// background when the endpoints are stale
+func (t *discoveryTransport) pick() Endpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.refreshing && t.client.clock.Now().Sub(t.resolvedAt) >= t.refresh {
+		t.refreshing = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err := t.lookup(ctx)
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.refreshing = false
+			if err != nil {
+				// Try again after another interval, not on the next request
+				t.resolvedAt = t.client.clock.Now()
+				log.Printf("llm-config: endpoint discovery: %v; keeping the %d endpoints found before", err, len(t.endpoints))
+			}
+		}()
+	}
+
+	// endpoints are sorted by priority
+	group := t.endpoints
+	for i := range group {
+		if group[i].Priority != group[0].Priority {
+			group = group[:i]
+			break
+		}
+	}
+	var total int
+	for _, e := range group {
+		total += int(e.Weight)
+	}
+	if total == 0 {
+		return group[t.rng.Intn(len(group))]
+	}
+	n := t.rng.Intn(total)
+	for _, e := range group {
+		if n -= int(e.Weight); n < 0 {
+			return e
+		}
+	}
+	return group[len(group)-1]
+}
+
+// lookup replaces the endpoints with a fresh lookup's, unless it fails or
+// finds none
+func (t *discoveryTransport) lookup(ctx context.Context) error {
+	endpoints, err := t.discover(ctx)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return errors.New("no endpoints found")
+	}
+	sorted := append([]Endpoint(nil), endpoints...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.endpoints = sorted
+	t.resolvedAt = t.client.clock.Now()
+	return nil
+}
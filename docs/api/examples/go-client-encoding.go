@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// PayloadEncoding is a body encoding the client can read list responses in
+type PayloadEncoding string
+
+const (
+	// EncodingJSON is the default, and the fallback of every negotiation
+	EncodingJSON PayloadEncoding = "json"
+	// EncodingMsgpack is MessagePack with the JSON field names
+	EncodingMsgpack PayloadEncoding = "msgpack"
+	// EncodingProtobuf is llmconfig.v1.ListConfigsResponse of
+	// ../llmconfig.proto; only config listings are offered in it
+	EncodingProtobuf PayloadEncoding = "protobuf"
+)
+
+// Media types of the encodings
+const (
+	mediaTypeJSON     = "application/json"
+	mediaTypeMsgpack  = "application/msgpack"
+	mediaTypeProtobuf = "application/x-protobuf"
+)
+
+var encodingMediaTypes = map[PayloadEncoding]string{
+	EncodingJSON:     mediaTypeJSON,
+	EncodingMsgpack:  mediaTypeMsgpack,
+	EncodingProtobuf: mediaTypeProtobuf,
+}
+
+// SetBulkEncodings lets list calls (ListConfigsPage, ListByTagPage,
+// ListNamespacesPage, and the calls built on them such as Watch) ask for
+// binary bodies, which decode several times faster than JSON for large
+// namespaces. encodings are in order of preference; the server picks one
+// and JSON stays the fallback, so servers without binary encodings keep
+// working. Values decode to the same Go types as from JSON. With no
+// encodings, lists are JSON only, the default. Call it before making
+// requests.
+//
+//	err := client.SetBulkEncodings(EncodingProtobuf, EncodingMsgpack)
+func (c *LLMConfigClient) SetBulkEncodings(encodings ...PayloadEncoding) error {
+	for _, enc := range encodings {
+		if _, ok := encodingMediaTypes[enc]; !ok {
+			return fmt.Errorf("unknown payload encoding %q: %w", enc, ErrValidation)
+		}
+	}
+	c.bulkEncodings = encodings
+	return nil
+}
+
+// bulkAccept is the Accept header for a list, preferring the configured
+// encodings in order; empty when only JSON is wanted
+func (c *LLMConfigClient) bulkAccept(protobuf bool) string {
+	var types []string
+	for _, enc := range c.bulkEncodings {
+		if enc == EncodingJSON || (enc == EncodingProtobuf && !protobuf) {
+			continue
+		}
+		types = append(types, fmt.Sprintf("%s;q=%.1f", encodingMediaTypes[enc], 1-0.1*float64(len(types))))
+	}
+	if len(types) == 0 {
+		return ""
+	}
+	return strings.Join(append(types, mediaTypeJSON+";q=0.1"), ", ")
+}
+
+// decodePage reads a list response in whichever encoding the server chose
+func decodePage[T any](resp *resty.Response, page *Page[T]) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header().Get("Content-Type"))
+	switch mediaType {
+	case mediaTypeMsgpack, "application/x-msgpack", "application/vnd.msgpack":
+		dec := msgpack.NewDecoder(bytes.NewReader(resp.Body()))
+		dec.SetCustomStructTag("json")
+		if err := dec.Decode(&page.Items); err != nil {
+			return fmt.Errorf("decode msgpack list: %w", err)
+		}
+		if configs, ok := any(page.Items).([]ConfigResponse); ok {
+			for i := range configs {
+				configs[i].Value = jsonNumbers(configs[i].Value)
+			}
+		}
+		return nil
+	case mediaTypeProtobuf:
+		configs, ok := any(page).(*Page[ConfigResponse])
+		if !ok {
+			return fmt.Errorf("server sent a %T list as protobuf", page.Items)
+		}
+		var msg grpcListConfigsResponse
+		if err := msg.unmarshalProto(resp.Body()); err != nil {
+			return fmt.Errorf("decode protobuf list: %w", err)
+		}
+		configs.Items, configs.Total, configs.NextCursor = msg.Items, msg.Total, msg.NextCursor
+		return nil
+	}
+	body := bytes.TrimSpace(resp.Body())
+	if len(body) > 0 && body[0] == '{' {
+		// The v2 list shape carries the paging in the body
+		var envelope struct {
+			Items      []T    `json:"items"`
+			Total      *int64 `json:"total"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return err
+		}
+		page.Items, page.NextCursor = envelope.Items, envelope.NextCursor
+		if envelope.Total != nil {
+			page.Total = *envelope.Total
+		}
+		return nil
+	}
+	return json.Unmarshal(body, &page.Items)
+}
+
+// jsonNumbers turns the integers MessagePack decodes into float64, as
+// encoding/json would decode them
+func jsonNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = jsonNumbers(v[k])
+		}
+	}
+	return v
+}
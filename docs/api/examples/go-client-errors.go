@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for errors.Is. Errors returned by the client match at
+// most one of these:
+//
+//	cfg, err := client.GetConfig("app/llm", "model", "production", false)
+//	switch {
+//	case errors.Is(err, ErrNotFound):
+//		// use a default
+//	case errors.Is(err, ErrRateLimited):
+//		wait, _ := RetryAfter(err)
+//		// back off for wait
+//	}
+var (
+	// ErrNotFound means the namespace, key, or version does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the write lost a race with another writer
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthorized means the token is missing, invalid, or lacks permission
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited means the server answered 429; see RetryAfter
+	ErrRateLimited = errors.New("rate limited")
+	// ErrValidation means the value was rejected, locally or by the server;
+	// use errors.As with *ValidationError for per-field problems
+	ErrValidation = errors.New("validation failed")
+	// ErrQuotaExceeded means the write would take a namespace past its
+	// NamespaceQuota
+	ErrQuotaExceeded = errors.New("namespace quota exceeded")
+)
+
+// errorCodeQuotaExceeded is the code the server sends with 403 when a
+// write is refused by a namespace quota rather than by permissions
+const errorCodeQuotaExceeded = "quota_exceeded"
+
+// Is maps the HTTP status to the matching sentinel error
+func (e *ConfigClientError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || (e.StatusCode == http.StatusForbidden && e.Code != errorCodeQuotaExceeded)
+	case ErrQuotaExceeded:
+		return e.StatusCode == http.StatusForbidden && e.Code == errorCodeQuotaExceeded
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	}
+	return false
+}
+
+// Is reports ValidationError as ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Is reports ManifestError as ErrValidation
+func (e *ManifestError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Is reports PromptBindingError as ErrValidation
+func (e *PromptBindingError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Is reports PolicyViolationError as ErrUnauthorized: the caller is not
+// permitted to make this write
+func (e *PolicyViolationError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+// RetryAfter returns how long the server asked the caller to wait, if err
+// is a rate-limit error carrying a Retry-After header
+func RetryAfter(err error) (time.Duration, bool) {
+	var clientErr *ConfigClientError
+	if errors.As(err, &clientErr) && clientErr.RetryAfter > 0 {
+		return clientErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter reads a response's Retry-After header in delta-seconds
+// or HTTP-date form. A date is taken relative to the response's Date
+// header when it has one, so clock skew between client and server does
+// not stretch or cut the wait, and relative to now otherwise. Missing,
+// malformed, and past values are 0.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		now = date
+	}
+	return max(at.Sub(now), 0)
+}
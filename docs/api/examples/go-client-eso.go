@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ESOProvider serves config values to the External Secrets Operator's
+// webhook provider, so ExternalSecrets can read from the config server with
+// ESO's own refresh and rotation. Secrets are revealed, decrypting
+// client-side encrypted ones with SecretKey.
+//
+// Each request is made with the bearer token the SecretStore sends, so what
+// an ExternalSecret can read is decided by the token in its store, not by
+// the provider; requests without one are rejected.
+//
+//	GET /v1/secrets/{namespace}/{key}?env=production -> {"value": "..."}
+//	GET /v1/namespaces/{namespace}?env=production    -> {"key": "...", ...}
+//
+// Namespaces may contain slashes; the last segment of a secrets path is the
+// key. Values that are not strings are returned as JSON.
+type ESOProvider struct {
+	// BaseURL of the config server API
+	BaseURL   string
+	SecretKey SecretKey
+	// DefaultEnvironment is used for requests without ?env=
+	DefaultEnvironment string
+}
+
+// Handler returns the provider's routes, plus /healthz
+func (p *ESOProvider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /v1/secrets/{path...}", p.serveSecret)
+	mux.HandleFunc("GET /v1/namespaces/{namespace...}", p.serveNamespace)
+	return mux
+}
+
+func (p *ESOProvider) serveSecret(w http.ResponseWriter, r *http.Request) {
+	path := r.PathValue("path")
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		http.Error(w, "path must be /v1/secrets/{namespace}/{key}", http.StatusBadRequest)
+		return
+	}
+	namespace, key := path[:i], path[i+1:]
+
+	c, env, ok := p.client(w, r)
+	if !ok {
+		return
+	}
+	value, err := p.value(r.Context(), c, namespace, key, env)
+	if err != nil {
+		writeESOError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]string{"value": formatCLIValue(value)})
+}
+
+func (p *ESOProvider) serveNamespace(w http.ResponseWriter, r *http.Request) {
+	c, env, ok := p.client(w, r)
+	if !ok {
+		return
+	}
+	values, err := c.agentValues(r.Context(), r.PathValue("namespace"), AgentOptions{
+		Environment:   env,
+		RevealSecrets: true,
+		SecretKey:     p.SecretKey,
+	})
+	if err != nil {
+		writeESOError(w, err)
+		return
+	}
+	flat := make(map[string]string, len(values))
+	for key, value := range values {
+		flat[key] = formatCLIValue(value)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, flat)
+}
+
+// client returns a config client using the request's bearer token, and the
+// requested environment
+func (p *ESOProvider) client(w http.ResponseWriter, r *http.Request) (*LLMConfigClient, string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		http.Error(w, "a bearer token for the config server is required", http.StatusUnauthorized)
+		return nil, "", false
+	}
+	env := r.URL.Query().Get("env")
+	if env == "" {
+		env = p.DefaultEnvironment
+	}
+	return NewLLMConfigClient(p.BaseURL, token), env, true
+}
+
+// value reads one key, revealing it if the API masks it
+func (p *ESOProvider) value(ctx context.Context, c *LLMConfigClient, namespace, key, env string) (interface{}, error) {
+	cfg, err := c.GetConfigContext(ctx, namespace, key, env, false)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Value != encryptedPlaceholder {
+		return normalizeExportValue(cfg.Value), nil
+	}
+	secret, err := c.RevealSecret(ctx, namespace, key, env, p.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeExportValue(secret.Value), nil
+}
+
+// writeESOError passes the config server's status through, so ESO reports
+// a missing key or a rejected token as such
+func writeESOError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	var clientErr *ConfigClientError
+	if errors.As(err, &clientErr) && clientErr.StatusCode >= 400 && clientErr.StatusCode < 500 {
+		status = clientErr.StatusCode
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// Serve listens on addr until ctx is done, then shuts down gracefully
+func (p *ESOProvider) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: p.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
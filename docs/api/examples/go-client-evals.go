@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// EvalSubject identifies the versioned thing an eval result is attached to:
+// a config version or a prompt version. Build one with ConfigVersion or
+// PromptVersion.
+type EvalSubject struct {
+	path    string
+	env     string
+	version int64
+}
+
+// ConfigVersion is the eval subject for version of namespace/key in env;
+// version 0 refers to every version, for ListEvals and BestVersion
+func ConfigVersion(namespace, key, env string, version int64) EvalSubject {
+	return EvalSubject{path: fmt.Sprintf("/configs/%s/%s/%s", namespace, key, routeSeparator) + versionSegment(version), env: env, version: version}
+}
+
+// PromptVersion is the eval subject for version of the prompt name;
+// version 0 refers to every version, for ListEvals and BestVersion
+func PromptVersion(name string, version int64) EvalSubject {
+	return EvalSubject{path: fmt.Sprintf("/prompts/%s", url.PathEscape(name)) + versionSegment(version), version: version}
+}
+
+func versionSegment(version int64) string {
+	if version <= 0 {
+		return ""
+	}
+	return "/versions/" + strconv.FormatInt(version, 10)
+}
+
+// EvalResult is one score from an evaluation run
+type EvalResult struct {
+	// Version is filled in by the server from the subject
+	Version int64   `json:"version,omitempty"`
+	Dataset string  `json:"dataset"`
+	Metric  string  `json:"metric"`
+	Value   float64 `json:"value"`
+	RunID   string  `json:"run_id"`
+	// Labels carry anything else about the run, e.g. the judge model
+	Labels     map[string]string `json:"labels,omitempty"`
+	RecordedAt string            `json:"recorded_at,omitempty"`
+	RecordedBy string            `json:"recorded_by,omitempty"`
+}
+
+// EvalQuery selects results for ListEvals and BestVersion; empty fields
+// match everything
+type EvalQuery struct {
+	Dataset string
+	Metric  string
+	RunID   string
+	// LowerIsBetter makes BestVersion pick the minimum, for metrics such as
+	// latency or cost
+	LowerIsBetter bool
+}
+
+// VersionScore is the result of BestVersion
+type VersionScore struct {
+	Version int64   `json:"version"`
+	Value   float64 `json:"value"`
+	RunID   string  `json:"run_id"`
+	// Runs is how many results for the version matched the query; Value is
+	// their mean
+	Runs int `json:"runs"`
+}
+
+type recordEvalRequest struct {
+	EvalResult
+	User string `json:"user"`
+}
+
+// RecordEval attaches results to subject, which must name a version:
+//
+//	subject := ConfigVersion("app/llm", "params", "production", cfg.Version)
+//	err := client.RecordEval(ctx, subject, "ci", EvalResult{
+//		Dataset: "support-golden-v3", Metric: "accuracy", Value: 0.91, RunID: runID,
+//	})
+func (c *LLMConfigClient) RecordEval(ctx context.Context, subject EvalSubject, user string, results ...EvalResult) error {
+	if subject.version <= 0 {
+		return fmt.Errorf("%w: eval results must be attached to a specific version", ErrValidation)
+	}
+	requests := make([]recordEvalRequest, len(results))
+	for i, r := range results {
+		if r.Dataset == "" || r.Metric == "" {
+			return fmt.Errorf("%w: eval results need a dataset and a metric", ErrValidation)
+		}
+		requests[i] = recordEvalRequest{EvalResult: r, User: user}
+	}
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(requests)
+	if subject.env != "" {
+		req.SetQueryParam("env", subject.env)
+	}
+
+	resp, err := req.Post(subject.path + "/evals")
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// ListEvals returns the results attached to subject that match query,
+// oldest first. A subject with version 0 lists results for every version.
+func (c *LLMConfigClient) ListEvals(ctx context.Context, subject EvalSubject, query EvalQuery) ([]EvalResult, error) {
+	var result []EvalResult
+
+	resp, err := c.evalRequest(ctx, subject, query).
+		SetResult(&result).
+		Get(subject.path + "/evals")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return []EvalResult{}, nil
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// BestVersion returns the version with the best mean score for query's
+// dataset and metric, both of which are required. Use it to automate
+// promotion:
+//
+//	best, err := client.BestVersion(ctx, PromptVersion("support-triage", 0),
+//		EvalQuery{Dataset: "triage-golden", Metric: "f1"})
+//	...
+//	client.SetConfig("app/llm", "triage_prompt", fmt.Sprintf("support-triage@%d", best.Version), "production", "ci", false)
+func (c *LLMConfigClient) BestVersion(ctx context.Context, subject EvalSubject, query EvalQuery) (*VersionScore, error) {
+	if query.Dataset == "" || query.Metric == "" {
+		return nil, fmt.Errorf("%w: BestVersion needs a dataset and a metric", ErrValidation)
+	}
+
+	var result VersionScore
+
+	req := c.evalRequest(ctx, subject, query).SetResult(&result)
+	if query.LowerIsBetter {
+		req.SetQueryParam("order", "asc")
+	}
+
+	resp, err := req.Get(subject.path + "/evals/best")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+func (c *LLMConfigClient) evalRequest(ctx context.Context, subject EvalSubject, query EvalQuery) *resty.Request {
+	req := c.httpClient.R().SetContext(ctx)
+	if subject.env != "" {
+		req.SetQueryParam("env", subject.env)
+	}
+	for name, value := range map[string]string{"dataset": query.Dataset, "metric": query.Metric, "run_id": query.RunID} {
+		if value != "" {
+			req.SetQueryParam(name, value)
+		}
+	}
+	return req
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ExecEnvOptions controls how a namespace maps onto environment variables
+type ExecEnvOptions struct {
+	// Prefix is prepended to every variable name, e.g. "LLM_" turns
+	// max-tokens into LLM_MAX_TOKENS
+	Prefix string
+	// Keys restricts the variables to these config keys; empty means all
+	Keys []string
+	// RevealSecrets injects the plaintext of secrets with RevealSecret,
+	// decrypting client-side encrypted ones with SecretKey, as vault exec
+	// does; the token needs permission to read them
+	RevealSecrets bool
+	SecretKey     SecretKey
+	// SkipMaskedSecrets leaves out secrets the API returned masked instead
+	// of failing, when they are not revealed
+	SkipMaskedSecrets bool
+}
+
+// EnvVar is one config rendered as an environment variable
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Key   string `json:"key"`
+	// Secret marks revealed secrets and keys named like credentials, whose
+	// values must not be logged
+	Secret bool `json:"secret"`
+}
+
+// ExecEnv resolves namespace into environment variables for a child
+// process, sorted by name. Names follow dotenvKey (upper case, anything
+// but letters, digits, and underscores becomes "_"); strings are passed
+// as-is and other values as JSON. Two keys mapping to the same name are an
+// error. Masked secrets are revealed with RevealSecrets, left out with
+// SkipMaskedSecrets, and an error otherwise.
+func (c *LLMConfigClient) ExecEnv(ctx context.Context, namespace, env string, opts ExecEnvOptions) ([]EnvVar, error) {
+	configs, err := c.ListConfigsContext(ctx, namespace, env)
+	if err != nil {
+		return nil, fmt.Errorf("list %s (%s): %w", namespace, env, err)
+	}
+
+	vars := make([]EnvVar, 0, len(configs))
+	names := map[string]string{}
+	for _, cfg := range configs {
+		if len(opts.Keys) > 0 && !slices.Contains(opts.Keys, cfg.Key) {
+			continue
+		}
+		value, secret := cfg.Value, secretKeyPattern.MatchString(cfg.Key)
+		if value == encryptedPlaceholder {
+			switch {
+			case opts.RevealSecrets:
+				revealed, err := c.RevealSecret(ctx, namespace, cfg.Key, env, opts.SecretKey)
+				if err != nil {
+					return nil, fmt.Errorf("reveal %s/%s: %w", namespace, cfg.Key, err)
+				}
+				value, secret = revealed.Value, true
+			case opts.SkipMaskedSecrets:
+				continue
+			default:
+				return nil, fmt.Errorf("%s/%s (%s) is a secret the API masked; reveal secrets, skip them, or inject it another way", namespace, cfg.Key, env)
+			}
+		}
+
+		name := opts.Prefix + dotenvKey(cfg.Key)
+		if other, dup := names[name]; dup {
+			return nil, fmt.Errorf("keys %s and %s both map to %s", other, cfg.Key, name)
+		}
+		names[name] = cfg.Key
+
+		vars = append(vars, EnvVar{
+			Name:   name,
+			Value:  formatCLIValue(normalizeExportValue(value)),
+			Key:    cfg.Key,
+			Secret: secret,
+		})
+	}
+
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExperimentStatus is where an experiment is in its lifecycle
+type ExperimentStatus string
+
+const (
+	// ExperimentRunning splits units across variants by weight
+	ExperimentRunning ExperimentStatus = "running"
+	// ExperimentStopped sends every unit to Winner, or to the first variant
+	// when no winner was chosen
+	ExperimentStopped ExperimentStatus = "stopped"
+)
+
+// experimentBuckets is the resolution of variant weights
+const experimentBuckets = 10000
+
+// ExperimentVariant is one arm of an experiment
+type ExperimentVariant struct {
+	Name string `json:"name"`
+	// Weight is the variant's share of traffic relative to the other
+	// variants' weights
+	Weight int `json:"weight"`
+	// Value replaces the experiment's config value for units in this
+	// variant; nil keeps the config's own value (a control arm)
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Experiment varies one config key across weighted variants, e.g. two
+// system prompts:
+//
+//	exp := Experiment{
+//		Name: "support-prompt-v2", Namespace: "app/llm", Key: "system_prompt",
+//		Variants: []ExperimentVariant{
+//			{Name: "control", Weight: 90},
+//			{Name: "concise", Weight: 10, Value: "Answer in two sentences."},
+//		},
+//	}
+type Experiment struct {
+	Name      string              `json:"name"`
+	Namespace string              `json:"namespace"`
+	Key       string              `json:"key"`
+	Status    ExperimentStatus    `json:"status"`
+	Variants  []ExperimentVariant `json:"variants"`
+	Winner    string              `json:"winner,omitempty"`
+	// Salt reshuffles assignments; changing it moves units between
+	// variants, so leave it alone once the experiment is running
+	Salt      string `json:"salt,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+type putExperimentRequest struct {
+	Experiment
+	User string `json:"user"`
+}
+
+// Validate checks the variants and status
+func (e Experiment) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if e.Name == "" {
+		fail("/name", "name is required")
+	}
+	if e.Namespace == "" || e.Key == "" {
+		fail("/key", "namespace and key are required")
+	}
+	switch e.Status {
+	case "", ExperimentRunning, ExperimentStopped:
+	default:
+		fail("/status", "status %q is not one of running, stopped", e.Status)
+	}
+
+	if len(e.Variants) < 2 {
+		fail("/variants", "an experiment needs at least two variants")
+	}
+	names := map[string]bool{}
+	total := 0
+	for i, v := range e.Variants {
+		if v.Name == "" {
+			fail(fmt.Sprintf("/variants/%d/name", i), "name is required")
+		} else if names[v.Name] {
+			fail(fmt.Sprintf("/variants/%d/name", i), "variant %s is declared twice", v.Name)
+		}
+		names[v.Name] = true
+		if v.Weight < 0 {
+			fail(fmt.Sprintf("/variants/%d/weight", i), "weight must not be negative")
+		}
+		total += v.Weight
+	}
+	if len(e.Variants) > 0 && total <= 0 {
+		fail("/variants", "variant weights must add up to more than zero")
+	}
+	if e.Winner != "" && !names[e.Winner] {
+		fail("/winner", "winner %s is not a variant", e.Winner)
+	}
+
+	return paramsError(problems)
+}
+
+// Assign returns the variant for unitID (a user, session, or tenant ID).
+// The same unit always lands in the same variant for a given name, salt,
+// and set of weights, on every client. It returns nil for an experiment
+// without variants.
+func (e *Experiment) Assign(unitID string) *ExperimentVariant {
+	if len(e.Variants) == 0 {
+		return nil
+	}
+	if e.Status == ExperimentStopped {
+		for i := range e.Variants {
+			if e.Variants[i].Name == e.Winner {
+				return &e.Variants[i]
+			}
+		}
+		return &e.Variants[0]
+	}
+
+	weights := make([]int, len(e.Variants))
+	for i, v := range e.Variants {
+		weights[i] = v.Weight
+	}
+	return &e.Variants[pickWeighted(stickyBucket(e.Name, e.Salt, unitID), weights)]
+}
+
+// stickyBucket hashes parts to a bucket in [0, experimentBuckets), the same
+// on every client
+func stickyBucket(parts ...string) int {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return int(binary.BigEndian.Uint64(sum[:8]) % experimentBuckets)
+}
+
+// pickWeighted returns the index whose share of the weights covers bucket;
+// negative weights count as zero, and all-zero weights pick index 0
+func pickWeighted(bucket int, weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += max(w, 0)
+	}
+	if total == 0 {
+		return 0
+	}
+	point := bucket * total / experimentBuckets
+	for i, w := range weights {
+		point -= max(w, 0)
+		if point < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
+
+// PutExperiment validates and creates or replaces an experiment
+func (c *LLMConfigClient) PutExperiment(ctx context.Context, experiment Experiment, user string) (*Experiment, error) {
+	if err := withValidationTarget(experiment.Validate(), experiment.Namespace, experiment.Key); err != nil {
+		return nil, err
+	}
+	if experiment.Status == "" {
+		experiment.Status = ExperimentRunning
+	}
+
+	var result Experiment
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(putExperimentRequest{Experiment: experiment, User: user}).
+		SetResult(&result).
+		Put(fmt.Sprintf("/experiments/%s", url.PathEscape(experiment.Name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetExperiment fetches an experiment by name
+func (c *LLMConfigClient) GetExperiment(ctx context.Context, name string) (*Experiment, error) {
+	var result Experiment
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/experiments/%s", url.PathEscape(name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListExperiments returns the experiments on keys in namespace, or every
+// experiment when namespace is empty
+func (c *LLMConfigClient) ListExperiments(ctx context.Context, namespace string) ([]Experiment, error) {
+	var result []Experiment
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if namespace != "" {
+		req.SetQueryParam("namespace", namespace)
+	}
+
+	resp, err := req.Get("/experiments")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// AssignVariant fetches experiment and assigns unitID to a variant. Hot
+// paths should fetch the experiment once (or Watch it) and call Assign.
+func (c *LLMConfigClient) AssignVariant(ctx context.Context, experiment, unitID string) (*ExperimentVariant, error) {
+	e, err := c.GetExperiment(ctx, experiment)
+	if err != nil {
+		return nil, fmt.Errorf("experiment %s: %w", experiment, err)
+	}
+	variant := e.Assign(unitID)
+	if variant == nil {
+		return nil, fmt.Errorf("experiment %s has no variants", experiment)
+	}
+	return variant, nil
+}
+
+// ExperimentValue returns the config value unitID should see in env: its
+// variant's value, or the key's own value for a control arm
+func (c *LLMConfigClient) ExperimentValue(ctx context.Context, experiment, unitID, env string) (interface{}, *ExperimentVariant, error) {
+	e, err := c.GetExperiment(ctx, experiment)
+	if err != nil {
+		return nil, nil, fmt.Errorf("experiment %s: %w", experiment, err)
+	}
+	variant := e.Assign(unitID)
+	if variant == nil {
+		return nil, nil, fmt.Errorf("experiment %s has no variants", experiment)
+	}
+	if variant.Value != nil {
+		return variant.Value, variant, nil
+	}
+
+	cfg, err := c.GetConfigContext(ctx, e.Namespace, e.Key, env, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s/%s: %w", e.Namespace, e.Key, err)
+	}
+	return cfg.Value, variant, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ExpiryAction is what the server does with a value when it expires
+type ExpiryAction string
+
+const (
+	// ExpireDelete removes the key
+	ExpireDelete ExpiryAction = "delete"
+	// ExpireRevert restores the value the key had before the expiring
+	// write, or removes the key if it had none
+	ExpireRevert ExpiryAction = "revert"
+)
+
+// ConfigExpiry schedules a value's removal or reversion
+type ConfigExpiry struct {
+	At     time.Time    `json:"at"`
+	Action ExpiryAction `json:"action"`
+	// RevertToVersion is the version ExpireRevert restores, set by the
+	// server; 0 means the key is removed
+	RevertToVersion int64 `json:"revert_to_version,omitempty"`
+}
+
+// ExpiryRequest asks the server to expire a write TTLSeconds after it
+// accepts it, so the schedule does not depend on the client's clock
+type ExpiryRequest struct {
+	TTLSeconds int64        `json:"ttl_seconds"`
+	Action     ExpiryAction `json:"action"`
+}
+
+// ConfigExpiration is an upcoming expiry, as listed by ListExpirations
+type ConfigExpiration struct {
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Environment string `json:"environment"`
+	Version     int64  `json:"version"`
+	ConfigExpiry
+	SetBy string `json:"set_by"`
+}
+
+// ExpirationOptions filters ListExpirations; empty fields match all
+type ExpirationOptions struct {
+	// Namespace limits results to this namespace and those under it
+	Namespace   string
+	Environment string
+	// Within limits results to expiries before now plus Within
+	Within time.Duration
+}
+
+// SetTemporaryConfig sets a value that the server removes or reverts once
+// ttl has passed, e.g. raising max_tokens for the length of an incident:
+//
+//	client.SetTemporaryConfig(ctx, "app/llm", "max_tokens", 8192, "production", "oncall", 4*time.Hour, ExpireRevert)
+//
+// The server computes the expiry time from ttl, rounded up to a whole
+// second, when it accepts the write. Use ClearExpiry to keep the value
+// after all. A later write to the key without an expiry replaces the
+// schedule.
+func (c *LLMConfigClient) SetTemporaryConfig(ctx context.Context, namespace, key string, value interface{}, env, user string, ttl time.Duration, action ExpiryAction) (*ConfigResponse, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("temporary %s/%s (%s): ttl %s must be positive: %w", namespace, key, env, ttl, ErrValidation)
+	}
+	if action != ExpireDelete && action != ExpireRevert {
+		return nil, fmt.Errorf("temporary %s/%s (%s): expiry action %q must be %s or %s: %w", namespace, key, env, action, ExpireDelete, ExpireRevert, ErrValidation)
+	}
+	return c.setConfig(ctx, namespace, key, SetConfigRequest{
+		Value:  value,
+		Env:    env,
+		User:   user,
+		Expiry: &ExpiryRequest{TTLSeconds: int64((ttl + time.Second - 1) / time.Second), Action: action},
+	})
+}
+
+// ClearExpiry cancels a key's scheduled expiry, keeping its current value,
+// and reports whether one was scheduled
+func (c *LLMConfigClient) ClearExpiry(ctx context.Context, namespace, key, env string) (bool, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("env", env).
+		Delete(keyRoute(namespace, key, "expiry"))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
+
+// ListExpirations returns scheduled expiries, soonest first
+func (c *LLMConfigClient) ListExpirations(ctx context.Context, opts ExpirationOptions) ([]ConfigExpiration, error) {
+	var result []ConfigExpiration
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if opts.Namespace != "" {
+		req.SetQueryParam("namespace", opts.Namespace)
+	}
+	if opts.Environment != "" {
+		req.SetQueryParam("env", opts.Environment)
+	}
+	if opts.Within > 0 {
+		req.SetQueryParam("within_seconds", strconv.FormatInt(int64(opts.Within/time.Second), 10))
+	}
+
+	resp, err := req.Get("/expirations")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportFormat selects the serialization used by Export
+type ExportFormat string
+
+const (
+	ExportFormatYAML   ExportFormat = "yaml"
+	ExportFormatJSON   ExportFormat = "json"
+	ExportFormatTOML   ExportFormat = "toml"
+	ExportFormatDotenv ExportFormat = "dotenv"
+	// ExportFormatSystemd is a systemd EnvironmentFile: dotenv with
+	// systemd's quoting, where escapes like \n are not interpreted
+	ExportFormatSystemd ExportFormat = "systemd"
+)
+
+// Export renders every configuration in a namespace as a flat key/value
+// document. Output is deterministic (sorted keys, fixed indentation, trailing
+// newline) so exports can be checked into git and diffed.
+func (c *LLMConfigClient) Export(ctx context.Context, namespace, env string, format ExportFormat) ([]byte, error) {
+	return c.ExportWithOptions(ctx, namespace, env, format, ExportOptions{})
+}
+
+// ExportOptions tunes Export output
+type ExportOptions struct {
+	// Redact replaces secret values with typed placeholders such as
+	// "<redacted:string:openai_api_key:v3>" so the export can be shared
+	Redact bool
+	// SecretKeys are redacted in addition to values the API masks and keys
+	// whose names look like credentials (api_key, token, password, ...)
+	SecretKeys []string
+}
+
+// secretKeyPattern matches key names that conventionally hold credentials
+var secretKeyPattern = regexp.MustCompile(`(?i)(^|[_.-])(api[_-]?key|secret|password|passwd|token|credentials?|private[_-]?key)$`)
+
+// ExportWithOptions is Export with redaction and other options applied
+func (c *LLMConfigClient) ExportWithOptions(ctx context.Context, namespace, env string, format ExportFormat, opts ExportOptions) ([]byte, error) {
+	configs, err := c.ListConfigsContext(ctx, namespace, env)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Redact {
+		configs = redactConfigs(configs, opts.SecretKeys)
+	}
+	return encodeExport(exportValues(configs), format)
+}
+
+// redactConfigs returns a copy of configs with secret values replaced by
+// placeholders naming the value type, key, and version
+func redactConfigs(configs []ConfigResponse, secretKeys []string) []ConfigResponse {
+	explicit := make(map[string]bool, len(secretKeys))
+	for _, key := range secretKeys {
+		explicit[key] = true
+	}
+
+	out := make([]ConfigResponse, len(configs))
+	for i, cfg := range configs {
+		out[i] = cfg
+		masked := cfg.Value == encryptedPlaceholder
+		if !masked && !explicit[cfg.Key] && !secretKeyPattern.MatchString(cfg.Key) {
+			continue
+		}
+
+		valueType := "secret"
+		if !masked {
+			valueType = valueTypeName(cfg.Value)
+		}
+		out[i].Value = fmt.Sprintf("<redacted:%s:%s:v%d>", valueType, cfg.Key, cfg.Version)
+	}
+	return out
+}
+
+// valueTypeName names the JSON type of a decoded value
+func valueTypeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, int64, int:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// exportValues flattens configs into a key -> value map with normalized numbers
+func exportValues(configs []ConfigResponse) map[string]interface{} {
+	values := make(map[string]interface{}, len(configs))
+	for _, cfg := range configs {
+		values[cfg.Key] = normalizeExportValue(cfg.Value)
+	}
+	return values
+}
+
+// normalizeExportValue turns integral float64 values (the JSON decoder's
+// default for every number) back into int64 so integers don't render as 2000.0
+func normalizeExportValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return int64(val)
+		}
+		return val
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = normalizeExportValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = normalizeExportValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// encodeExport serializes values in the requested format. TOML leaves out
+// null values; dotenv and systemd fail when two keys map to one variable
+// name (e.g. "max-tokens" and "max_tokens").
+func encodeExport(values map[string]interface{}, format ExportFormat) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case ExportFormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(values); err != nil {
+			return nil, fmt.Errorf("encode json: %w", err)
+		}
+	case ExportFormatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(values); err != nil {
+			return nil, fmt.Errorf("encode yaml: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("encode yaml: %w", err)
+		}
+	case ExportFormatTOML:
+		enc := toml.NewEncoder(&buf)
+		enc.Indent = "  "
+		if err := enc.Encode(tomlValue(values)); err != nil {
+			return nil, fmt.Errorf("encode toml: %w", err)
+		}
+	case ExportFormatDotenv, ExportFormatSystemd:
+		render := dotenvValue
+		if format == ExportFormatSystemd {
+			render = systemdEnvValue
+		}
+		lines := make([]string, 0, len(values))
+		names := make(map[string]string, len(values))
+		for key, value := range values {
+			name := dotenvKey(key)
+			if other, ok := names[name]; ok {
+				if other > key {
+					other, key = key, other
+				}
+				return nil, fmt.Errorf("encode %s: keys %s and %s are both %s", format, other, key, name)
+			}
+			names[name] = key
+			rendered, err := render(value)
+			if err != nil {
+				return nil, fmt.Errorf("encode %s key %s: %w", format, key, err)
+			}
+			lines = append(lines, name+"="+rendered)
+		}
+		sort.Strings(lines)
+		for _, line := range lines {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// tomlValue drops the nulls of v, which TOML has no way to write, from
+// tables and arrays
+func tomlValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		out := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			if item != nil {
+				out = append(out, tomlValue(item))
+			}
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if item != nil {
+				out[k] = tomlValue(item)
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// dotenvKey converts a config key into an environment variable name
+// (e.g. "max-tokens" -> "MAX_TOKENS")
+func dotenvKey(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// dotenvValue renders a value for a dotenv file. Scalars are written bare when
+// safe; everything else (including arrays and objects, as compact JSON) is
+// double-quoted with escapes.
+func dotenvValue(v interface{}) (string, error) {
+	var raw string
+	switch val := v.(type) {
+	case string:
+		raw = val
+	case bool, int64, float64:
+		return fmt.Sprint(val), nil
+	default:
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		raw = string(encoded)
+	}
+
+	if raw != "" && strings.IndexFunc(raw, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-./:@+,", r))
+	}) < 0 {
+		return raw, nil
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, `$`, `\$`)
+	return `"` + replacer.Replace(raw) + `"`, nil
+}
+
+// systemdEnvValue renders a value for a systemd EnvironmentFile. Inside
+// double quotes systemd only unescapes \\, \", \`, and \$, and keeps
+// newlines, so multi-line values are written as they are.
+func systemdEnvValue(v interface{}) (string, error) {
+	raw, err := dotenvValue(v)
+	if err != nil || !strings.HasPrefix(raw, `"`) {
+		return raw, err
+	}
+	s := formatCLIValue(v)
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	return `"` + replacer.Replace(s) + `"`, nil
+}
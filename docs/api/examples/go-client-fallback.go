@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FallbackModel is one entry in a FallbackChain
+type FallbackModel struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model"`
+	// Profile names a ProviderProfile in ProviderProfileNamespace to reach
+	// the model through
+	Profile string `json:"profile,omitempty"`
+	// Attempts is how many times to try the model before moving on; 1
+	// when zero
+	Attempts int `json:"attempts,omitempty"`
+}
+
+func (m FallbackModel) String() string {
+	name := m.Model
+	if m.Provider != "" {
+		name = m.Provider + "/" + name
+	}
+	if m.Profile != "" {
+		name += "@" + m.Profile
+	}
+	return name
+}
+
+// FallbackChain is an ordered list of models: the primary first, then
+// backups. Stored values may use the object form or the older bare array
+// of model names or model objects:
+//
+//	{"models": [{"provider": "openai", "model": "gpt-4o", "attempts": 2},
+//	            {"provider": "anthropic", "model": "claude-3-5-sonnet"}]}
+//	["gpt-4o", "gpt-4o-mini"]
+type FallbackChain struct {
+	Models []FallbackModel `json:"models"`
+}
+
+// UnmarshalJSON accepts the object form and both bare array forms
+func (ch *FallbackChain) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	items, isArray := raw.([]interface{})
+	if !isArray {
+		type plain FallbackChain
+		return json.Unmarshal(data, (*plain)(ch))
+	}
+
+	ch.Models = make([]FallbackModel, len(items))
+	for i, item := range items {
+		if name, ok := item.(string); ok {
+			ch.Models[i] = FallbackModel{Model: name}
+			continue
+		}
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(encoded, &ch.Models[i]); err != nil {
+			return fmt.Errorf("fallback chain entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the chain names at least one model and no model
+// twice
+func (ch FallbackChain) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(ch.Models) == 0 {
+		fail("/models", "a fallback chain needs at least one model")
+	}
+	seen := map[string]int{}
+	for i, m := range ch.Models {
+		if m.Model == "" {
+			fail(fmt.Sprintf("/models/%d/model", i), "model is required")
+		}
+		if m.Attempts < 0 {
+			fail(fmt.Sprintf("/models/%d/attempts", i), "attempts must not be negative")
+		}
+		if first, dup := seen[m.String()]; dup {
+			fail(fmt.Sprintf("/models/%d", i), "%s already appears at position %d", m, first)
+		} else {
+			seen[m.String()] = i
+		}
+	}
+
+	return paramsError(problems)
+}
+
+// FallbackAttempt is one call made while running a chain
+type FallbackAttempt struct {
+	Position int
+	Model    FallbackModel
+	Err      error
+}
+
+// FallbackResult reports which model in the chain answered
+type FallbackResult struct {
+	// Used is the model that succeeded, at Position in the chain (0 is the
+	// primary)
+	Used     FallbackModel
+	Position int
+	// Attempts lists every call made, failed ones included
+	Attempts []FallbackAttempt
+}
+
+// FellBack reports whether a backup answered instead of the primary
+func (r *FallbackResult) FellBack() bool {
+	return r.Position > 0
+}
+
+// FallbackExhaustedError is returned when every model in a chain failed
+type FallbackExhaustedError struct {
+	Attempts []FallbackAttempt
+}
+
+func (e *FallbackExhaustedError) Error() string {
+	parts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		parts[i] = fmt.Sprintf("%s: %v", a.Model, a.Err)
+	}
+	return fmt.Sprintf("all %d fallback attempts failed: %s", len(e.Attempts), strings.Join(parts, "; "))
+}
+
+// Unwrap returns every attempt's error
+func (e *FallbackExhaustedError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, a := range e.Attempts {
+		errs[i] = a.Err
+	}
+	return errs
+}
+
+// stopFallbackError marks an error that should end the chain
+type stopFallbackError struct{ err error }
+
+func (e stopFallbackError) Error() string { return e.err.Error() }
+func (e stopFallbackError) Unwrap() error { return e.err }
+
+// StopFallback wraps an error that another model would not fix (a bad
+// request, a content-policy refusal), so Run returns it without trying the
+// rest of the chain
+func StopFallback(err error) error {
+	if err == nil {
+		return nil
+	}
+	return stopFallbackError{err}
+}
+
+// Run calls fn with each model in order until one succeeds, trying each up
+// to its Attempts. It stops early when ctx ends or fn returns an error
+// wrapped with StopFallback. The result is returned on failure too, so the
+// attempts can be logged.
+func (ch *FallbackChain) Run(ctx context.Context, fn func(ctx context.Context, model FallbackModel) error) (*FallbackResult, error) {
+	result := &FallbackResult{Position: -1}
+	for position, model := range ch.Models {
+		for attempt := 0; attempt < max(model.Attempts, 1); attempt++ {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			err := fn(ctx, model)
+			if err == nil {
+				result.Used, result.Position = model, position
+				return result, nil
+			}
+			result.Attempts = append(result.Attempts, FallbackAttempt{Position: position, Model: model, Err: err})
+
+			var stop stopFallbackError
+			if errors.As(err, &stop) {
+				return result, stop.err
+			}
+		}
+	}
+	return result, &FallbackExhaustedError{Attempts: result.Attempts}
+}
+
+// GetFallbackChain reads and validates the chain stored at namespace/key
+func (c *LLMConfigClient) GetFallbackChain(ctx context.Context, namespace, key, env string) (*FallbackChain, error) {
+	var chain FallbackChain
+	if err := c.GetParams(ctx, namespace, key, env, &chain); err != nil {
+		return nil, err
+	}
+	return &chain, nil
+}
+
+// RunFallbackChain reads the chain at namespace/key and runs fn along it.
+// With metrics enabled, the position that answered is counted in
+// llm_config_client_fallbacks_total.
+func (c *LLMConfigClient) RunFallbackChain(ctx context.Context, namespace, key, env string, fn func(ctx context.Context, model FallbackModel) error) (*FallbackResult, error) {
+	chain, err := c.GetFallbackChain(ctx, namespace, key, env)
+	if err != nil {
+		return nil, err
+	}
+	result, err := chain.Run(ctx, fn)
+	c.metrics.Load().observeFallback(namespace+"/"+key, result.Position)
+	return result, err
+}
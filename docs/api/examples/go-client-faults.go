@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FaultConfig sets how often each kind of failure is injected, as a
+// probability between 0 and 1. At most one fault is injected per request;
+// rates are tried in field order.
+type FaultConfig struct {
+	// RateLimitRate answers with a 429 and a Retry-After header
+	RateLimitRate float64
+	// ServerErrorRate answers with a 500
+	ServerErrorRate float64
+	// TimeoutRate fails the request with a timeout error
+	TimeoutRate float64
+	// CorruptBodyRate sends the request but mangles the response body
+	CorruptBodyRate float64
+
+	// RetryAfter is advertised on injected 429s; 1s when zero
+	RetryAfter time.Duration
+	// Hang is how long an injected timeout blocks (on the client's clock)
+	// before failing; zero fails at once. A request context that ends sooner
+	// wins.
+	Hang time.Duration
+	// Seed makes the fault sequence reproducible; zero picks a random seed
+	Seed int64
+}
+
+// FaultStats counts the requests seen and the faults injected
+type FaultStats struct {
+	Requests     int64
+	RateLimited  int64
+	ServerErrors int64
+	Timeouts     int64
+	CorruptBody  int64
+}
+
+// FaultInjector is the transport installed by InjectFaults
+type FaultInjector struct {
+	base   http.RoundTripper
+	client *LLMConfigClient
+	cfg    FaultConfig
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	stats FaultStats
+}
+
+// faultHeader marks responses synthesized or altered by the injector
+const faultHeader = "X-Fault-Injected"
+
+// InjectFaults makes the client fail some of its requests on purpose, so
+// fallback, retry, and cache behavior can be exercised before the real
+// server misbehaves. Injected 429s and 500s never reach the server and go
+// through the normal retry path. Calling it again replaces the previous
+// configuration; a zero FaultConfig turns injection off.
+func (c *LLMConfigClient) InjectFaults(cfg FaultConfig) *FaultInjector {
+	base := c.httpClient.GetClient().Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if f, ok := base.(*FaultInjector); ok {
+		base = f.base
+	}
+
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Second
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	f := &FaultInjector{base: base, client: c, cfg: cfg, rng: rand.New(rand.NewSource(seed))}
+	c.httpClient.SetTransport(f)
+	return f
+}
+
+// Stats reports what has been injected so far
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// faultTimeoutError is returned for injected timeouts; it satisfies
+// net.Error so callers treat it like a real one
+type faultTimeoutError struct{}
+
+func (faultTimeoutError) Error() string   { return "injected fault: request timed out" }
+func (faultTimeoutError) Timeout() bool   { return true }
+func (faultTimeoutError) Temporary() bool { return true }
+
+// pick chooses the fault for one request, or "" for none
+func (f *FaultInjector) pick() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats.Requests++
+
+	roll := f.rng.Float64()
+	for _, fault := range []struct {
+		name  string
+		rate  float64
+		count *int64
+	}{
+		{"rate_limit", f.cfg.RateLimitRate, &f.stats.RateLimited},
+		{"server_error", f.cfg.ServerErrorRate, &f.stats.ServerErrors},
+		{"timeout", f.cfg.TimeoutRate, &f.stats.Timeouts},
+		{"corrupt_body", f.cfg.CorruptBodyRate, &f.stats.CorruptBody},
+	} {
+		if roll < fault.rate {
+			*fault.count++
+			return fault.name
+		}
+		roll -= fault.rate
+	}
+	return ""
+}
+
+// RoundTrip implements http.RoundTripper
+func (f *FaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch fault := f.pick(); fault {
+	case "rate_limit":
+		resp := faultResponse(req, http.StatusTooManyRequests, fault)
+		resp.Header.Set("Retry-After", strconv.Itoa(int(f.cfg.RetryAfter.Round(time.Second)/time.Second)))
+		resp.Header.Set("X-RateLimit-Remaining", "0")
+		return resp, nil
+	case "server_error":
+		return faultResponse(req, http.StatusInternalServerError, fault), nil
+	case "timeout":
+		if f.cfg.Hang > 0 {
+			select {
+			case <-f.client.clock.After(f.cfg.Hang):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+		return nil, faultTimeoutError{}
+	case "corrupt_body":
+		resp, err := f.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = corruptBody(body)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Del("Content-Length")
+		resp.Header.Set(faultHeader, fault)
+		return resp, nil
+	}
+	return f.base.RoundTrip(req)
+}
+
+// faultResponse synthesizes an error in the server's error body shape
+func faultResponse(req *http.Request, status int, fault string) *http.Response {
+	body := fmt.Sprintf(`{"error":%q,"message":"injected fault: %s"}`, http.StatusText(status), fault)
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(faultHeader, fault)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// corruptBody truncates body mid-document and appends bytes that are not
+// valid JSON, so decoding fails the way a cut-off response would
+func corruptBody(body []byte) []byte {
+	corrupted := append([]byte(nil), body[:len(body)/2]...)
+	return append(corrupted, 0xff, '{')
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileBackendUser is recorded as the author of every value a FileBackend
+// returns, since files keep no authorship
+const fileBackendUser = "file"
+
+// overrideChain lists the environments applied, in order, when
+// resolving with overrides, as the server does
+var overrideChain = map[string][]string{
+	"base":        {"base"},
+	"development": {"base", "development"},
+	"staging":     {"base", "development", "staging"},
+	"production":  {"base", "development", "staging", "production"},
+	"edge":        {"base", "edge"},
+}
+
+var _ Backend = (*FileBackend)(nil)
+
+// FileBackend serves configs from a directory of manifests, the same YAML
+// and JSON files Apply pushes to a server (see LoadManifests), so dev
+// environments and air-gapped deployments run the application code written
+// against a Backend without a server:
+//
+//	var backend Backend = NewLLMConfigClient(url, token)
+//	if dir := os.Getenv("LLM_CONFIG_DIR"); dir != "" {
+//		backend = NewFileBackend(dir)
+//	}
+//
+// Files are read on every call, so edits show up at once. Files keep no
+// history: every value is version 1 and its timestamps are the file's
+// modification time. Keys under a manifest's secrets read as
+// "<encrypted>", as from the server; secrets cannot be written.
+type FileBackend struct {
+	dir string
+	// mu serializes writes, which rewrite whole files
+	mu sync.Mutex
+}
+
+// NewFileBackend returns a backend over the manifests below dir
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{dir: dir}
+}
+
+// GetConfigContext reads a config value. With overrides, the value comes
+// from the nearest environment of the server's chain that sets the key
+// (base → development → staging → production, and base → edge).
+func (b *FileBackend) GetConfigContext(_ context.Context, namespace, key, env string, withOverrides bool) (*ConfigResponse, error) {
+	env, err := fileBackendEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	chain := []string{env}
+	if withOverrides {
+		chain = overrideChain[env]
+	}
+
+	manifests, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	var found *ConfigResponse
+	for _, candidate := range chain {
+		m := findManifest(manifests, namespace, candidate)
+		if m == nil {
+			continue
+		}
+		if cfg, ok := fileConfig(m, key); ok {
+			found = cfg
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("configuration not found: %s:%s (%s): %w", namespace, key, env, ErrNotFound)
+	}
+	return found, nil
+}
+
+// ListConfigsContext lists a namespace's configs in env, by key
+func (b *FileBackend) ListConfigsContext(_ context.Context, namespace, env string) ([]ConfigResponse, error) {
+	env, err := fileBackendEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	m := findManifest(manifests, namespace, env)
+	if m == nil {
+		return []ConfigResponse{}, nil
+	}
+
+	keys := make([]string, 0, len(m.Configs)+len(m.Secrets))
+	for key := range m.Configs {
+		keys = append(keys, key)
+	}
+	for key := range m.Secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]ConfigResponse, 0, len(keys))
+	for _, key := range keys {
+		cfg, _ := fileConfig(m, key)
+		result = append(result, *cfg)
+	}
+	return result, nil
+}
+
+// ListNamespaces returns the namespaces with a manifest in any
+// environment, sorted; a non-empty prefix ("app/") limits them to those
+// starting with it
+func (b *FileBackend) ListNamespaces(_ context.Context, prefix string) ([]string, error) {
+	manifests, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []string{}
+	for _, m := range manifests {
+		if strings.HasPrefix(m.Namespace, prefix) && !slices.Contains(namespaces, m.Namespace) {
+			namespaces = append(namespaces, m.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// SetConfigContext writes value into the manifest of namespace and env,
+// creating <dir>/<namespace>/<env>.yaml if there is none. The manifest's
+// file is rewritten, dropping any YAML comments. Secrets are refused with
+// ErrValidation: they belong under the manifest's secrets, which name an
+// environment variable rather than hold the value.
+func (b *FileBackend) SetConfigContext(_ context.Context, namespace, key string, value interface{}, env, user string, secret bool) (*ConfigResponse, error) {
+	env, err := fileBackendEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	if secret {
+		return nil, fmt.Errorf("file backend cannot store secret %s:%s; declare it under secrets in the manifest: %w", namespace, key, ErrValidation)
+	}
+	if namespace == "" || key == "" {
+		return nil, fmt.Errorf("namespace and key are required: %w", ErrValidation)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	manifests, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	m := findManifest(manifests, namespace, env)
+	if m == nil {
+		path, err := b.manifestPath(namespace, env)
+		if err != nil {
+			return nil, err
+		}
+		m = &Manifest{Namespace: namespace, Environment: env, Source: path}
+	}
+	if _, ok := m.Secrets[key]; ok {
+		return nil, fmt.Errorf("%s: key %s is a secret: %w", m.Source, key, ErrValidation)
+	}
+	if m.Configs == nil {
+		m.Configs = map[string]interface{}{}
+	}
+	m.Configs[key] = canonicalValue(value)
+
+	if err := writeManifestFile(m.Source, manifests, m); err != nil {
+		return nil, err
+	}
+	cfg, _ := fileConfig(m, key)
+	return cfg, nil
+}
+
+// DeleteConfigContext removes key, config or secret, from the manifest of
+// namespace and env, reporting false if it was not there
+func (b *FileBackend) DeleteConfigContext(_ context.Context, namespace, key, env string) (bool, error) {
+	env, err := fileBackendEnv(env)
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	manifests, err := b.load()
+	if err != nil {
+		return false, err
+	}
+	m := findManifest(manifests, namespace, env)
+	if m == nil {
+		return false, nil
+	}
+	_, isConfig := m.Configs[key]
+	_, isSecret := m.Secrets[key]
+	if !isConfig && !isSecret {
+		return false, nil
+	}
+	delete(m.Configs, key)
+	delete(m.Secrets, key)
+	return true, writeManifestFile(m.Source, manifests, m)
+}
+
+// load reads the manifests, none while the directory does not exist yet
+func (b *FileBackend) load() ([]Manifest, error) {
+	manifests, err := LoadManifests(b.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, statErr := os.Stat(b.dir); errors.Is(statErr, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	return manifests, err
+}
+
+// fileBackendEnv checks env, applying the server's default of development
+func fileBackendEnv(env string) (string, error) {
+	if env == "" {
+		return "development", nil
+	}
+	if _, ok := overrideChain[env]; !ok {
+		return "", fmt.Errorf("unknown environment %q, want one of %v: %w", env, knownEnvironments, ErrValidation)
+	}
+	return env, nil
+}
+
+// manifestPath is where a new manifest for namespace and env is written
+func (b *FileBackend) manifestPath(namespace, env string) (string, error) {
+	for _, part := range strings.Split(namespace, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("namespace %q cannot name a directory: %w", namespace, ErrValidation)
+		}
+	}
+	return filepath.Join(b.dir, filepath.FromSlash(namespace), env+".yaml"), nil
+}
+
+// findManifest returns the manifest of namespace and env, if any
+func findManifest(manifests []Manifest, namespace, env string) *Manifest {
+	for i := range manifests {
+		if manifests[i].Namespace == namespace && manifests[i].Environment == env {
+			return &manifests[i]
+		}
+	}
+	return nil
+}
+
+// fileConfig is key of m as the server would return it
+func fileConfig(m *Manifest, key string) (*ConfigResponse, bool) {
+	value, ok := m.Configs[key]
+	if ok {
+		// Numbers decode as float64, as from the server's JSON
+		value = jsonNumbers(canonicalValue(value))
+	} else if _, ok = m.Secrets[key]; ok {
+		value = encryptedPlaceholder
+	} else {
+		return nil, false
+	}
+
+	var modified string
+	if info, err := os.Stat(m.Source); err == nil {
+		modified = info.ModTime().UTC().Format(time.RFC3339)
+	}
+	return &ConfigResponse{
+		ID:          m.Namespace + "/" + key + "@" + m.Environment,
+		Namespace:   m.Namespace,
+		Key:         key,
+		Value:       value,
+		Environment: m.Environment,
+		Version:     1,
+		Metadata: ConfigMetadata{
+			CreatedAt: modified,
+			CreatedBy: fileBackendUser,
+			UpdatedAt: modified,
+			UpdatedBy: fileBackendUser,
+			Tags:      []string{},
+		},
+	}, true
+}
+
+// writeManifestFile rewrites path with every manifest loaded from it,
+// changed standing in for its old self or added at the end, replacing
+// the file atomically
+func writeManifestFile(path string, manifests []Manifest, changed *Manifest) error {
+	var docs []*Manifest
+	replaced := false
+	for i := range manifests {
+		if manifests[i].Source != path {
+			continue
+		}
+		if manifests[i].Namespace == changed.Namespace && manifests[i].Environment == changed.Environment {
+			docs, replaced = append(docs, changed), true
+			continue
+		}
+		docs = append(docs, &manifests[i])
+	}
+	if !replaced {
+		docs = append(docs, changed)
+	}
+
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if len(docs) != 1 {
+			return fmt.Errorf("%s: a JSON file holds one manifest", path)
+		}
+		encoded, err := json.MarshalIndent(docs[0], "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.Write(append(encoded, '\n'))
+	} else {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		for _, doc := range docs {
+			if err := enc.Encode(doc); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
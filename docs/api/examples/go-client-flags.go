@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultFlagNamespace is where flag definitions are stored, one key per
+// flag
+const DefaultFlagNamespace = "flags"
+
+// Flag evaluation reasons
+const (
+	FlagReasonOff         = "off"
+	FlagReasonRule        = "rule"
+	FlagReasonFallthrough = "fallthrough"
+	FlagReasonUnknown     = "unknown_flag"
+	FlagReasonError       = "error"
+)
+
+// Flag is a boolean or multivariate feature flag. Its definition is an
+// ordinary config value in the flag namespace:
+//
+//	{"enabled": true, "variations": {"on": true, "off": false},
+//	 "off_variation": "off", "fallthrough": {"variation": "off"},
+//	 "rules": [{"id": "enterprise-eu",
+//	            "clauses": [{"attribute": "tier", "op": "in", "values": ["enterprise"]},
+//	                        {"attribute": "region", "op": "starts_with", "values": ["eu-"]}],
+//	            "serve": {"rollout": {"on": 25, "off": 75}}}]}
+type Flag struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+	// Enabled false serves OffVariation to everyone
+	Enabled    bool                   `json:"enabled"`
+	Variations map[string]interface{} `json:"variations"`
+	// OffVariation is served while the flag is disabled
+	OffVariation string `json:"off_variation"`
+	// Rules are tried in order; the first whose clauses all match wins
+	Rules []FlagRule `json:"rules,omitempty"`
+	// Fallthrough is served when no rule matches
+	Fallthrough FlagServe `json:"fallthrough"`
+	// Salt reshuffles percentage rollouts
+	Salt string `json:"salt,omitempty"`
+}
+
+// FlagRule serves a variation to contexts matching every clause
+type FlagRule struct {
+	ID      string       `json:"id"`
+	Clauses []FlagClause `json:"clauses"`
+	Serve   FlagServe    `json:"serve"`
+}
+
+// FlagClause tests one context attribute. Op is one of in, starts_with,
+// ends_with, contains, matches (a regular expression), lt, lte, gt, gte;
+// the clause matches if any value does. Attribute "key" is the context key.
+type FlagClause struct {
+	Attribute string        `json:"attribute"`
+	Op        string        `json:"op"`
+	Values    []interface{} `json:"values"`
+	Negate    bool          `json:"negate,omitempty"`
+}
+
+// FlagServe is a fixed variation or a percentage rollout across variations
+type FlagServe struct {
+	Variation string `json:"variation,omitempty"`
+	// Rollout maps variation names to relative weights; a context key
+	// always lands on the same variation, as with Experiment.Assign
+	Rollout map[string]int `json:"rollout,omitempty"`
+}
+
+// FlagContext is what a flag is evaluated for: a stable key (user, org, or
+// request ID) and targeting attributes such as tier, region, or org
+type FlagContext struct {
+	Key        string
+	Attributes map[string]interface{}
+}
+
+// FlagEvaluation is the result of evaluating a flag
+type FlagEvaluation struct {
+	Flag      string      `json:"flag"`
+	Variation string      `json:"variation,omitempty"`
+	Value     interface{} `json:"value"`
+	// Reason is one of the FlagReason constants
+	Reason string `json:"reason"`
+	// RuleID is the matching rule when Reason is FlagReasonRule
+	RuleID string `json:"rule_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Validate checks that every served variation exists and every clause is
+// well formed
+func (f Flag) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	checkServe := func(path string, serve FlagServe) {
+		switch {
+		case serve.Variation != "" && len(serve.Rollout) > 0:
+			fail(path, "serve either a variation or a rollout, not both")
+		case serve.Variation != "":
+			if _, ok := f.Variations[serve.Variation]; !ok {
+				fail(path+"/variation", "variation %s is not defined", serve.Variation)
+			}
+		case len(serve.Rollout) > 0:
+			total := 0
+			for _, name := range sortedMapKeys(serve.Rollout) {
+				if _, ok := f.Variations[name]; !ok {
+					fail(path+"/rollout", "variation %s is not defined", name)
+				}
+				if serve.Rollout[name] < 0 {
+					fail(path+"/rollout", "weight for %s must not be negative", name)
+				}
+				total += serve.Rollout[name]
+			}
+			if total <= 0 {
+				fail(path+"/rollout", "rollout weights must add up to more than zero")
+			}
+		default:
+			fail(path, "nothing to serve")
+		}
+	}
+
+	if len(f.Variations) == 0 {
+		fail("/variations", "at least one variation is required")
+	}
+	if _, ok := f.Variations[f.OffVariation]; !ok {
+		fail("/off_variation", "variation %q is not defined", f.OffVariation)
+	}
+	checkServe("/fallthrough", f.Fallthrough)
+
+	for i, rule := range f.Rules {
+		path := fmt.Sprintf("/rules/%d", i)
+		if len(rule.Clauses) == 0 {
+			fail(path+"/clauses", "a rule needs at least one clause")
+		}
+		for j, clause := range rule.Clauses {
+			clausePath := fmt.Sprintf("%s/clauses/%d", path, j)
+			if clause.Attribute == "" {
+				fail(clausePath+"/attribute", "attribute is required")
+			}
+			switch clause.Op {
+			case "in", "starts_with", "ends_with", "contains", "lt", "lte", "gt", "gte":
+			case "matches":
+				for _, v := range clause.Values {
+					pattern, _ := v.(string)
+					if _, err := regexp.Compile(pattern); err != nil {
+						fail(clausePath+"/values", "%v", err)
+					}
+				}
+			default:
+				fail(clausePath+"/op", "unknown op %q", clause.Op)
+			}
+		}
+		checkServe(path+"/serve", rule.Serve)
+	}
+
+	return paramsError(problems)
+}
+
+// Evaluate picks the variation fctx receives
+func (f *Flag) Evaluate(fctx FlagContext) FlagEvaluation {
+	if !f.Enabled {
+		return f.serve(fctx, FlagServe{Variation: f.OffVariation}, FlagReasonOff, "")
+	}
+	for _, rule := range f.Rules {
+		if rule.matches(fctx) {
+			return f.serve(fctx, rule.Serve, FlagReasonRule, rule.ID)
+		}
+	}
+	return f.serve(fctx, f.Fallthrough, FlagReasonFallthrough, "")
+}
+
+func (f *Flag) serve(fctx FlagContext, serve FlagServe, reason, ruleID string) FlagEvaluation {
+	variation := serve.Variation
+	if len(serve.Rollout) > 0 {
+		names := sortedMapKeys(serve.Rollout)
+		weights := make([]int, len(names))
+		for i, name := range names {
+			weights[i] = serve.Rollout[name]
+		}
+		variation = names[pickWeighted(stickyBucket(f.Key, f.Salt, ruleID, fctx.Key), weights)]
+	}
+
+	value, ok := f.Variations[variation]
+	if !ok {
+		return FlagEvaluation{Flag: f.Key, Reason: FlagReasonError, Error: fmt.Sprintf("variation %q is not defined", variation)}
+	}
+	return FlagEvaluation{Flag: f.Key, Variation: variation, Value: value, Reason: reason, RuleID: ruleID}
+}
+
+func (r *FlagRule) matches(fctx FlagContext) bool {
+	for _, clause := range r.Clauses {
+		if clause.matches(fctx) == clause.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *FlagClause) matches(fctx FlagContext) bool {
+	var actual interface{}
+	if c.Attribute == "key" {
+		actual = fctx.Key
+	} else if v, ok := fctx.Attributes[c.Attribute]; ok {
+		actual = v
+	} else {
+		return false
+	}
+
+	// A list attribute (e.g. groups) matches if any element does
+	if list, ok := actual.([]interface{}); ok {
+		for _, element := range list {
+			if c.matchesValue(element) {
+				return true
+			}
+		}
+		return false
+	}
+	if list, ok := actual.([]string); ok {
+		for _, element := range list {
+			if c.matchesValue(element) {
+				return true
+			}
+		}
+		return false
+	}
+	return c.matchesValue(actual)
+}
+
+func (c *FlagClause) matchesValue(actual interface{}) bool {
+	for _, want := range c.Values {
+		if matchClauseOp(c.Op, actual, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchClauseOp(op string, actual, want interface{}) bool {
+	if op == "lt" || op == "lte" || op == "gt" || op == "gte" {
+		a, okA := numericValue(actual)
+		w, okW := numericValue(want)
+		if !okA || !okW {
+			return false
+		}
+		switch op {
+		case "lt":
+			return a < w
+		case "lte":
+			return a <= w
+		case "gt":
+			return a > w
+		default:
+			return a >= w
+		}
+	}
+
+	if op == "in" {
+		if a, ok := numericValue(actual); ok {
+			w, ok := numericValue(want)
+			return ok && a == w
+		}
+		return reflect.DeepEqual(actual, want)
+	}
+
+	a, okA := actual.(string)
+	w, okW := want.(string)
+	if !okA || !okW {
+		return false
+	}
+	switch op {
+	case "starts_with":
+		return strings.HasPrefix(a, w)
+	case "ends_with":
+		return strings.HasSuffix(a, w)
+	case "contains":
+		return strings.Contains(a, w)
+	case "matches":
+		re, err := clausePattern(w)
+		return err == nil && re.MatchString(a)
+	}
+	return false
+}
+
+// clausePatterns caches compiled "matches" patterns across evaluations
+var clausePatterns sync.Map
+
+func clausePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := clausePatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	clausePatterns.Store(pattern, re)
+	return re, nil
+}
+
+// FlagSet evaluates flags locally against a ruleset it keeps current by
+// watching the flag namespace, so evaluation makes no network calls
+type FlagSet struct {
+	watcher *Watcher
+	done    chan struct{}
+
+	mu    sync.RWMutex
+	flags map[string]*Flag
+	// invalid holds the error for each flag whose definition was rejected
+	invalid map[string]string
+}
+
+// FlagSetOptions controls StreamFlags
+type FlagSetOptions struct {
+	// Namespace holding the flag definitions; DefaultFlagNamespace when empty
+	Namespace   string
+	Environment string
+	// WatchOptions.Interval for ruleset updates
+	Watch WatchOptions
+}
+
+// StreamFlags loads the flag ruleset and keeps it current until ctx ends or
+// the set is closed. Definitions that fail to decode or validate evaluate
+// with FlagReasonError rather than stopping the stream.
+func (c *LLMConfigClient) StreamFlags(ctx context.Context, opts FlagSetOptions) (*FlagSet, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = DefaultFlagNamespace
+	}
+	opts.Watch.Environment = opts.Environment
+
+	// Start watching before the initial load so no change falls between
+	// them; events for changes the load already saw are reapplied harmlessly
+	watcher, err := c.Watch(ctx, opts.Namespace, opts.Watch)
+	if err != nil {
+		return nil, fmt.Errorf("stream flags: %w", err)
+	}
+	configs, err := c.ListConfigsContext(ctx, opts.Namespace, opts.Environment)
+	if err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("stream flags: %w", err)
+	}
+
+	fs := &FlagSet{
+		watcher: watcher,
+		done:    make(chan struct{}),
+		flags:   map[string]*Flag{},
+		invalid: map[string]string{},
+	}
+	for i := range configs {
+		fs.apply(configs[i].Key, &configs[i])
+	}
+
+	go func() {
+		defer close(fs.done)
+		for event := range watcher.Events() {
+			if event.Type == ConfigDeleted {
+				fs.apply(event.Key, nil)
+			} else {
+				fs.apply(event.Key, event.Config)
+			}
+		}
+	}()
+	return fs, nil
+}
+
+// apply installs, replaces, or (with a nil cfg) removes one flag definition
+func (fs *FlagSet) apply(key string, cfg *ConfigResponse) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	delete(fs.flags, key)
+	delete(fs.invalid, key)
+	if cfg == nil {
+		return
+	}
+
+	var flag Flag
+	data, err := json.Marshal(cfg.Value)
+	if err == nil {
+		err = json.Unmarshal(data, &flag)
+	}
+	if err == nil {
+		err = flag.Validate()
+	}
+	if err != nil {
+		fs.invalid[key] = err.Error()
+		return
+	}
+	flag.Key = key
+	fs.flags[key] = &flag
+}
+
+// Evaluate evaluates one flag for fctx
+func (fs *FlagSet) Evaluate(key string, fctx FlagContext) FlagEvaluation {
+	fs.mu.RLock()
+	flag, ok := fs.flags[key]
+	invalid := fs.invalid[key]
+	fs.mu.RUnlock()
+
+	switch {
+	case ok:
+		return flag.Evaluate(fctx)
+	case invalid != "":
+		return FlagEvaluation{Flag: key, Reason: FlagReasonError, Error: invalid}
+	}
+	return FlagEvaluation{Flag: key, Reason: FlagReasonUnknown}
+}
+
+// Bool evaluates a boolean flag, returning fallback when the flag is
+// unknown, invalid, or serves a non-boolean value
+func (fs *FlagSet) Bool(key string, fctx FlagContext, fallback bool) bool {
+	if value, ok := fs.Evaluate(key, fctx).Value.(bool); ok {
+		return value
+	}
+	return fallback
+}
+
+// String evaluates a multivariate flag with string values
+func (fs *FlagSet) String(key string, fctx FlagContext, fallback string) string {
+	if value, ok := fs.Evaluate(key, fctx).Value.(string); ok {
+		return value
+	}
+	return fallback
+}
+
+// Keys lists the loaded flags, including invalid ones
+func (fs *FlagSet) Keys() []string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	keys := make([]string, 0, len(fs.flags)+len(fs.invalid))
+	for key := range fs.flags {
+		keys = append(keys, key)
+	}
+	for key := range fs.invalid {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Status reports the health of the underlying watch
+func (fs *FlagSet) Status() WatcherStatus {
+	return fs.watcher.Status()
+}
+
+// Close stops following updates; the last ruleset stays usable
+func (fs *FlagSet) Close() {
+	fs.watcher.Close()
+	<-fs.done
+}
@@ -0,0 +1,224 @@
+package main
+
+// Fuzz targets for the paths that turn server bytes into Go values. A
+// malformed or hostile response may produce an error, never a panic:
+//
+//	go test -fuzz=FuzzResponseDecoding -fuzztime=60s .
+//
+// The seed corpus is the configtest golden responses plus a few edge cases;
+// FuzzGRPCDecoding seeds from the gRPC codec's own encodings.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubTransport answers every request with a fixed status and body
+type stubTransport struct {
+	status int
+	body   []byte
+}
+
+func (f stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: f.status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(f.body)),
+		Request:    req,
+	}, nil
+}
+
+// newStubClient returns a client whose every request gets status and body,
+// with retries waiting on a fake clock
+func newStubClient(status int, body []byte) *LLMConfigClient {
+	client := NewLLMConfigClient("http://config.invalid/api/v1", "")
+	client.SetClock(NewFakeClock(time.Unix(0, 0)))
+	client.httpClient.SetTransport(stubTransport{status: status, body: body})
+	return client
+}
+
+// addResponseSeeds seeds f with the golden response bodies
+func addResponseSeeds(f *testing.F) {
+	paths, _ := filepath.Glob("configtest/fixtures/*.json")
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		var fixture struct {
+			Response struct {
+				Body json.RawMessage `json:"body"`
+			} `json:"response"`
+		}
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			f.Fatalf("%s: %v", path, err)
+		}
+		f.Add([]byte(fixture.Response.Body))
+	}
+	for _, seed := range []string{
+		``,
+		`null`,
+		`{}`,
+		`[]`,
+		`[null]`,
+		`{"value":1e309}`,
+		`{"value":-0,"version":-1}`,
+		`{"value":{"temperature":"hot","max_tokens":1.5}}`,
+		`{"value":[[[[[]]]]],"metadata":{"tags":null}}`,
+		`[{"key":"a","value":"\u0000"},{"key":"a","value":{"":null}}]`,
+		`[{"version":"3","value":true,"change_description":7}]`,
+	} {
+		f.Add([]byte(seed))
+	}
+}
+
+func FuzzResponseDecoding(f *testing.F) {
+	addResponseSeeds(f)
+	f.Fuzz(func(t *testing.T, body []byte) {
+		ctx := context.Background()
+		client := newStubClient(http.StatusOK, body)
+
+		if cfg, err := client.GetConfigContext(ctx, "llm", "model", "production", true); err == nil {
+			exerciseValue(t, cfg.Value)
+		}
+		if configs, err := client.ListConfigsContext(ctx, "llm", "production"); err == nil {
+			for _, cfg := range configs {
+				exerciseValue(t, cfg.Value)
+			}
+			values := exportValues(configs)
+			for _, format := range []ExportFormat{ExportFormatJSON, ExportFormatYAML, ExportFormatTOML, ExportFormatDotenv} {
+				encodeExport(values, format)
+			}
+			snapshotToken(configs)
+			diffSnapshots("llm", "production", nil, indexConfigs(configs))
+		}
+		if history, err := client.GetHistoryContext(ctx, "llm", "model", "production"); err == nil {
+			for _, entry := range history {
+				exerciseValue(t, entry.Value)
+			}
+		}
+
+		var params ChatModelParams
+		client.GetParams(ctx, "llm", "chat", "production", &params)
+		var embedding EmbeddingParams
+		client.GetParams(ctx, "llm", "embedding", "production", &embedding)
+	})
+}
+
+// exerciseValue runs a decoded value through every coercion helper
+func exerciseValue(t *testing.T, v interface{}) {
+	if _, err := json.Marshal(v); err != nil {
+		t.Fatalf("decoded value does not re-encode: %v", err)
+	}
+	valueTypeName(v)
+	normalizeExportValue(v)
+	canonicalValue(v)
+	numericValue(v)
+	isEmptyValue(v)
+	maskDebugValue(v)
+	dotenvValue(v)
+	for _, check := range manifestTypeChecks {
+		check(v)
+	}
+}
+
+func FuzzErrorResponse(f *testing.F) {
+	addResponseSeeds(f)
+	f.Fuzz(func(t *testing.T, body []byte) {
+		for _, status := range []int{400, 404, 409, 429, 500} {
+			client := newStubClient(status, body)
+			_, err := client.ListConfigsContext(context.Background(), "llm", "production")
+
+			var clientErr *ConfigClientError
+			if !errors.As(err, &clientErr) {
+				t.Fatalf("status %d: error = %v, want *ConfigClientError", status, err)
+			}
+			if clientErr.StatusCode != status {
+				t.Fatalf("StatusCode = %d, want %d", clientErr.StatusCode, status)
+			}
+			_ = clientErr.Error()
+			RetryAfter(err)
+		}
+	})
+}
+
+func FuzzGRPCDecoding(f *testing.F) {
+	event := &grpcConfigEvent{ConfigEvent{
+		Type: ConfigUpdated, Namespace: "llm", Key: "model",
+		Config: &ConfigResponse{Key: "model", Value: map[string]interface{}{"name": "gpt-4"}, Version: 2},
+	}, 7}
+	list := &grpcListConfigsResponse{Page[ConfigResponse]{Items: []ConfigResponse{*event.Config}, Total: 1}}
+	for _, m := range []protoMessage{event, list, &grpcConfig{*event.Config}} {
+		seed, err := m.marshalProto()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(seed)
+	}
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, m := range []protoMessage{&grpcConfig{}, &grpcListConfigsResponse{}, &grpcConfigEvent{}} {
+			if err := m.unmarshalProto(data); err != nil {
+				continue
+			}
+			// Whatever decodes must encode again
+			if _, err := m.marshalProto(); err != nil {
+				t.Fatalf("%T decoded from %x does not re-encode: %v", m, data, err)
+			}
+		}
+	})
+}
+
+func FuzzInferValue(f *testing.F) {
+	for _, seed := range []string{"", "true", "FALSE", "007", "-0.5", "1e3", "9223372036854775808", "0x1F", `{"a":[1]}`, "[1,", "NaN", "+Inf", "-", "."} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		v := InferValue(raw)
+		switch v.(type) {
+		case bool, int64, float64, string, map[string]interface{}, []interface{}:
+		default:
+			t.Fatalf("InferValue(%q) = %T", raw, v)
+		}
+		if s, ok := v.(string); ok && s != raw {
+			t.Fatalf("InferValue(%q) changed the string to %q", raw, s)
+		}
+		dotenvValue(v)
+		normalizeExportValue(v)
+	})
+}
+
+func FuzzParseDotenv(f *testing.F) {
+	for _, seed := range []string{
+		"A=1\nB=two\n",
+		"export KEY='literal $x'\n",
+		"MULTI=\"line1\nline2\"\n",
+		"ESC=\"a\\\"b\\\\c\\$d\"\n",
+		"X=value # comment\n",
+		"=\n#only comment\nBROKEN\n",
+		"UNTERMINATED=\"abc\n",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data string) {
+		entries, err := ParseDotenv(strings.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if e.Name == "" {
+				t.Fatalf("ParseDotenv(%q) returned an entry with an empty name", data)
+			}
+			InferValue(e.Value)
+		}
+	})
+}
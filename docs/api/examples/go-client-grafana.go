@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// grafanaValueLimit is how much of a value an annotation quotes
+const grafanaValueLimit = 80
+
+// GrafanaAnnotationOptions configures RunGrafanaAnnotations
+type GrafanaAnnotationOptions struct {
+	// URL of Grafana, e.g. https://grafana.example.com
+	URL string
+	// Token is a service account token allowed to write annotations
+	Token       string
+	Namespaces  []string
+	Environment string
+	// Interval between polls of each namespace; 5s when zero
+	Interval time.Duration
+	// DashboardUID, and PanelID within it, pin annotations to a dashboard.
+	// Without them annotations belong to the organization and show on
+	// dashboards whose annotation queries match their tags.
+	DashboardUID string
+	PanelID      int64
+	// Tags are added to those of every annotation: llm-config,
+	// namespace:<namespace>, env:<environment>, and key:<key> per key
+	// changed
+	Tags []string
+	// HTTPClient posts the annotations; http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// grafanaAnnotation is the body of Grafana's POST /api/annotations
+type grafanaAnnotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int64    `json:"panelId,omitempty"`
+	Time         int64    `json:"time"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+// RunGrafanaAnnotations watches opts.Namespaces and posts an annotation to
+// Grafana for each change, so model and prompt changes line up with the
+// latency and quality graphs they move. Keys changed in the same poll
+// share one annotation, dated by the latest update:
+//
+//	app/llm (production): model gpt-4 → gpt-4o (v7, alice); max_tokens deleted
+//
+// The namespaces must be watchable at the start; failed posts are logged
+// and skipped. It runs until ctx is done.
+func (c *LLMConfigClient) RunGrafanaAnnotations(ctx context.Context, opts GrafanaAnnotationOptions) error {
+	if opts.URL == "" {
+		return fmt.Errorf("grafana: URL is required")
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	watchOpts := WatchOptions{Environment: opts.Environment, Interval: opts.Interval}
+	return c.watchBatches(ctx, opts.Namespaces, watchOpts, func(events []ConfigEvent) {
+		if err := postGrafanaAnnotation(ctx, opts, c.grafanaAnnotation(opts, events)); err != nil && ctx.Err() == nil {
+			log.Printf("llm-config grafana: %v", err)
+		}
+	})
+}
+
+// grafanaAnnotation describes the changes of one poll of a namespace
+func (c *LLMConfigClient) grafanaAnnotation(opts GrafanaAnnotationOptions, events []ConfigEvent) grafanaAnnotation {
+	at := c.clock.Now()
+	var latest time.Time
+	tags := []string{"llm-config", "namespace:" + events[0].Namespace, "env:" + events[0].Environment}
+	changes := make([]string, 0, len(events))
+	for _, ev := range events {
+		tags = append(tags, "key:"+ev.Key)
+		switch ev.Type {
+		case ConfigCreated:
+			changes = append(changes, fmt.Sprintf("%s = %s%s", ev.Key, grafanaValue(ev.Config.Value), grafanaVersion(ev.Config)))
+		case ConfigUpdated:
+			changes = append(changes, fmt.Sprintf("%s %s → %s%s", ev.Key, grafanaValue(ev.Previous.Value), grafanaValue(ev.Config.Value), grafanaVersion(ev.Config)))
+		case ConfigDeleted:
+			changes = append(changes, ev.Key+" deleted")
+		}
+		if ev.Config != nil {
+			if t, err := time.Parse(time.RFC3339, ev.Config.Metadata.UpdatedAt); err == nil && t.After(latest) {
+				latest = t
+			}
+		}
+	}
+	if !latest.IsZero() {
+		at = latest
+	}
+	return grafanaAnnotation{
+		DashboardUID: opts.DashboardUID,
+		PanelID:      opts.PanelID,
+		Time:         at.UnixMilli(),
+		Tags:         append(tags, opts.Tags...),
+		Text:         fmt.Sprintf("%s (%s): %s", events[0].Namespace, events[0].Environment, strings.Join(changes, "; ")),
+	}
+}
+
+// grafanaValue quotes a value briefly
+func grafanaValue(v interface{}) string {
+	s := formatCLIValue(v)
+	if r := []rune(s); len(r) > grafanaValueLimit {
+		s = string(r[:grafanaValueLimit]) + "…"
+	}
+	return s
+}
+
+// grafanaVersion notes a config's version and author
+func grafanaVersion(cfg *ConfigResponse) string {
+	if cfg.Metadata.UpdatedBy == "" {
+		return fmt.Sprintf(" (v%d)", cfg.Version)
+	}
+	return fmt.Sprintf(" (v%d, %s)", cfg.Version, cfg.Metadata.UpdatedBy)
+}
+
+// postGrafanaAnnotation sends a to Grafana's annotations API
+func postGrafanaAnnotation(ctx context.Context, opts GrafanaAnnotationOptions, a grafanaAnnotation) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(opts.URL, "/")+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+	resp, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("post annotation: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
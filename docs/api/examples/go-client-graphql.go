@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// graphqlPath serves the read-only GraphQL API of ../llmconfig.graphql
+const graphqlPath = "/graphql"
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphQLError  `json:"errors"`
+}
+
+// GraphQLError is one entry of a GraphQL response's errors
+type GraphQLError struct {
+	Message string `json:"message"`
+	// Path locates the failed field, e.g. ["configs", 3, "value"]
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Code is the error's extensions.code, e.g. "NOT_FOUND"
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+func (e GraphQLError) String() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%s (at %s)", e.Message, strings.Join(path, "."))
+}
+
+// graphqlSentinels maps extensions.code to the matching sentinel error
+var graphqlSentinels = map[string]error{
+	"NOT_FOUND":                 ErrNotFound,
+	"UNAUTHENTICATED":           ErrUnauthorized,
+	"FORBIDDEN":                 ErrUnauthorized,
+	"BAD_USER_INPUT":            ErrValidation,
+	"RATE_LIMITED":              ErrRateLimited,
+	errorCodeQuotaExceeded:      ErrQuotaExceeded,
+	"GRAPHQL_PARSE_FAILED":      ErrValidation,
+	"GRAPHQL_VALIDATION_FAILED": ErrValidation,
+}
+
+// GraphQLErrors is a GraphQL response that carried errors. The data that
+// did resolve is still decoded, so a query over many keys can use the
+// results of the others. errors.Is matches the sentinel of the first
+// error's code.
+type GraphQLErrors struct {
+	Errors []GraphQLError
+}
+
+func (e *GraphQLErrors) Error() string {
+	msg := "graphql: " + e.Errors[0].String()
+	if len(e.Errors) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", len(e.Errors)-1)
+	}
+	return msg
+}
+
+// Is maps the first error's code to the matching sentinel error
+func (e *GraphQLErrors) Is(target error) bool {
+	sentinel, ok := graphqlSentinels[e.Errors[0].Code()]
+	return ok && sentinel == target
+}
+
+// GraphQL runs query with variables and decodes its data into data. When
+// the response carries errors, whatever data resolved is decoded and a
+// *GraphQLErrors is returned.
+//
+//	var data struct {
+//		Configs []*ConfigResponse `json:"configs"`
+//	}
+//	err := client.GraphQL(ctx, `query($env: String!, $keys: [ConfigKeyInput!]!) {
+//		configs(env: $env, keys: $keys) { key value version }
+//	}`, map[string]interface{}{"env": "production", "keys": keys}, &data)
+func (c *LLMConfigClient) GraphQL(ctx context.Context, query string, variables map[string]interface{}, data interface{}) error {
+	var result graphqlResponse
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(graphqlRequest{Query: query, Variables: variables}).
+		SetResult(&result).
+		Post(graphqlPath)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	if data != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, data); err != nil {
+			return fmt.Errorf("graphql: decode data: %w", err)
+		}
+	}
+	if len(result.Errors) > 0 {
+		return &GraphQLErrors{Errors: result.Errors}
+	}
+	return nil
+}
+
+// ConfigField is a field of a config to fetch with SelectConfigs
+type ConfigField string
+
+const (
+	FieldID          ConfigField = "id"
+	FieldNamespace   ConfigField = "namespace"
+	FieldKey         ConfigField = "key"
+	FieldValue       ConfigField = "value"
+	FieldEnvironment ConfigField = "environment"
+	FieldVersion     ConfigField = "version"
+	// FieldMetadata fetches all of ConfigMetadata
+	FieldMetadata ConfigField = "metadata"
+)
+
+// defaultConfigFields are fetched when no fields are given
+var defaultConfigFields = []ConfigField{FieldKey, FieldValue, FieldVersion}
+
+// configSelection is the GraphQL selection set for fields
+func configSelection(fields []ConfigField) (string, error) {
+	if len(fields) == 0 {
+		fields = defaultConfigFields
+	}
+	selection := make([]string, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case FieldID, FieldNamespace, FieldKey, FieldValue, FieldEnvironment, FieldVersion:
+			selection = append(selection, string(field))
+		case FieldMetadata:
+			selection = append(selection, "metadata { created_at created_by updated_at updated_by tags description }")
+		default:
+			return "", fmt.Errorf("graphql: unknown config field %q: %w", field, ErrValidation)
+		}
+	}
+	return "{ " + strings.Join(selection, " ") + " }", nil
+}
+
+// SelectConfigs fetches only fields (key, value and version when none are
+// given) of each of refs in env, in one round trip. The result lines up
+// with refs, with nil for keys that do not exist; fields not fetched are
+// left zero. When some keys fail, the others are returned along with a
+// *GraphQLErrors whose paths index refs.
+func (c *LLMConfigClient) SelectConfigs(ctx context.Context, env string, fields []ConfigField, refs ...ConfigRef) ([]*ConfigResponse, error) {
+	selection, err := configSelection(fields)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]map[string]string, len(refs))
+	for i, ref := range refs {
+		keys[i] = map[string]string{"namespace": ref.Namespace, "key": ref.Key}
+	}
+
+	var data struct {
+		Configs []*ConfigResponse `json:"configs"`
+	}
+	query := "query($env: String!, $keys: [ConfigKeyInput!]!) { configs(env: $env, keys: $keys) " + selection + " }"
+	err = c.GraphQL(ctx, query, map[string]interface{}{"env": env, "keys": keys}, &data)
+	var partial *GraphQLErrors
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	if len(data.Configs) != len(refs) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("graphql: asked for %d configs, got %d", len(refs), len(data.Configs))
+	}
+	return data.Configs, err
+}
+
+// SelectNamespaces fetches only fields (key, value and version when none
+// are given) of every config of each namespace in env, in one round trip,
+// keyed by namespace. Namespaces without configs are left out, as are any
+// that fail, which are reported in a *GraphQLErrors alongside the rest.
+func (c *LLMConfigClient) SelectNamespaces(ctx context.Context, env string, fields []ConfigField, namespaces ...string) (map[string][]ConfigResponse, error) {
+	selection, err := configSelection(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Namespaces []*struct {
+			Name    string           `json:"name"`
+			Configs []ConfigResponse `json:"configs"`
+		} `json:"namespaces"`
+	}
+	query := "query($env: String!, $names: [String!]!) { namespaces(names: $names) { name configs(env: $env) " + selection + " } }"
+	err = c.GraphQL(ctx, query, map[string]interface{}{"env": env, "names": namespaces}, &data)
+	var partial *GraphQLErrors
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+
+	result := make(map[string][]ConfigResponse, len(data.Namespaces))
+	for _, ns := range data.Namespaces {
+		if ns != nil {
+			result[ns.Name] = ns.Configs
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,881 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcService prefixes the methods of ConfigService in ../llmconfig.proto
+const grpcService = "/llmconfig.v1.ConfigService/"
+
+// grpcRevisionHeader is the header metadata in which Watch reports the
+// namespace's revision as the stream opens
+const grpcRevisionHeader = "x-llm-config-revision"
+
+// GRPCConfigClient reads and writes config over the gRPC API described in
+// ../llmconfig.proto, for services on a gRPC mesh and read paths where JSON
+// decoding shows up in profiles. It covers the hot path only, get, list,
+// set, delete and a streaming watch; use LLMConfigClient for the rest.
+// Client-side validation, policies and usage tracking are REST features.
+//
+// Errors match the same sentinels as LLMConfigClient's (ErrNotFound,
+// ErrConflict, ...), and WithRequestID and WithOwnerOverride apply.
+type GRPCConfigClient struct {
+	conn *grpc.ClientConn
+}
+
+var (
+	_ ConfigReader = (*GRPCConfigClient)(nil)
+	_ ConfigWriter = (*GRPCConfigClient)(nil)
+)
+
+// NewGRPCConfigClient connects to target, e.g. "dns:///llm-config:9090",
+// sending token as a bearer token with every call. opts must choose the
+// transport security:
+//
+//	client, err := NewGRPCConfigClient("dns:///llm-config:9090", token,
+//		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+//
+// insecure.NewCredentials() suits meshes whose sidecars terminate TLS.
+// Connecting is lazy; the first call reports an unreachable server.
+func NewGRPCConfigClient(target, token string, opts ...grpc.DialOption) (*GRPCConfigClient, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(grpcCodec{})),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(grpcOutgoingContext(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(grpcOutgoingContext(ctx), desc, cc, method, opts...)
+		}),
+	}, opts...)
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerCredentials(token)))
+	}
+
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("grpc client for %s: %w", target, err)
+	}
+	return &GRPCConfigClient{conn: conn}, nil
+}
+
+// Close closes the connection; watchers stop with it
+func (c *GRPCConfigClient) Close() error {
+	return c.conn.Close()
+}
+
+// bearerCredentials sends the API token. It does not insist on TLS so it
+// works behind a mesh sidecar; the caller picks the transport security.
+type bearerCredentials string
+
+func (t bearerCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// grpcOutgoingContext adds the request ID and any owner override to ctx's
+// metadata, as installRequestIDs and installOwnership do for REST
+func grpcOutgoingContext(ctx context.Context) context.Context {
+	id, ok := RequestIDFromContext(ctx)
+	if !ok {
+		id = newRequestID()
+	}
+	pairs := []string{"x-request-id", id}
+	if reason, ok := ctx.Value(ownerOverrideKey{}).(string); ok && reason != "" {
+		pairs = append(pairs, "x-llm-config-owner-override", reason)
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// grpcHTTPStatus gives each status code the HTTP status REST uses for the
+// same failure, so ConfigClientError's sentinels apply
+var grpcHTTPStatus = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.OutOfRange:         http.StatusGone,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+}
+
+// grpcError turns a status error into a ConfigClientError, or a
+// NotOwnerError for writes refused for ownership. Cancellations, deadlines
+// and transport errors are returned unchanged.
+func grpcError(ctx context.Context, err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	code, ok := grpcHTTPStatus[st.Code()]
+	if !ok {
+		return err
+	}
+
+	clientErr := &ConfigClientError{StatusCode: code, Message: st.Message()}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		clientErr.RequestID = id
+	}
+	var owners string
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			clientErr.Code = detail.GetReason()
+			owners = detail.GetMetadata()["owners"]
+		case *errdetails.RetryInfo:
+			clientErr.RetryAfter = detail.GetRetryDelay().AsDuration()
+		}
+	}
+	if code == http.StatusForbidden && clientErr.Code == errorCodeNotOwner {
+		return &NotOwnerError{Owners: splitOwners(owners), Err: clientErr}
+	}
+	return clientErr
+}
+
+func (c *GRPCConfigClient) invoke(ctx context.Context, method string, req, reply protoMessage) error {
+	if err := c.conn.Invoke(ctx, grpcService+method, req, reply); err != nil {
+		return grpcError(ctx, err)
+	}
+	return nil
+}
+
+// GetConfigContext retrieves a configuration value. A missing key is
+// reported as an error matching ErrNotFound.
+func (c *GRPCConfigClient) GetConfigContext(ctx context.Context, namespace, key, env string, withOverrides bool) (*ConfigResponse, error) {
+	var result grpcConfig
+	req := &grpcGetConfigRequest{Namespace: namespace, Key: key, Env: env, WithOverrides: withOverrides}
+	if err := c.invoke(ctx, "GetConfig", req, &result); err != nil {
+		return nil, err
+	}
+	return &result.ConfigResponse, nil
+}
+
+// ListConfigsPage returns one page of a namespace's configs, sorted by key
+func (c *GRPCConfigClient) ListConfigsPage(ctx context.Context, namespace, env string, opts PageOptions) (*Page[ConfigResponse], error) {
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("page limit %d is negative: %w", opts.Limit, ErrValidation)
+	}
+
+	var result grpcListConfigsResponse
+	req := &grpcListConfigsRequest{Namespace: namespace, Env: env, Limit: int64(opts.Limit), Cursor: opts.Cursor}
+	if err := c.invoke(ctx, "ListConfigs", req, &result); err != nil {
+		return nil, err
+	}
+	return &result.Page, nil
+}
+
+// ListConfigsContext lists all configurations in a namespace, following
+// the pages to the end
+func (c *GRPCConfigClient) ListConfigsContext(ctx context.Context, namespace, env string) ([]ConfigResponse, error) {
+	return collectPages(ctx, func(ctx context.Context, opts PageOptions) (*Page[ConfigResponse], error) {
+		return c.ListConfigsPage(ctx, namespace, env, opts)
+	})
+}
+
+// SetConfigContext sets a configuration value. It travels as a
+// google.protobuf.Value, so values other than nil, bools, numbers, strings
+// and slices and maps of those are converted through their JSON encoding.
+func (c *GRPCConfigClient) SetConfigContext(ctx context.Context, namespace, key string, value interface{}, env, user string, secret bool) (*ConfigResponse, error) {
+	var result grpcConfig
+	req := &grpcSetConfigRequest{Namespace: namespace, Key: key, Value: value, Env: env, User: user, Secret: secret}
+	if err := c.invoke(ctx, "SetConfig", req, &result); err != nil {
+		return nil, err
+	}
+	return &result.ConfigResponse, nil
+}
+
+// DeleteConfigContext deletes a configuration, reporting false if it did
+// not exist
+func (c *GRPCConfigClient) DeleteConfigContext(ctx context.Context, namespace, key, env string) (bool, error) {
+	var result grpcDeleteConfigResponse
+	req := &grpcDeleteConfigRequest{Namespace: namespace, Key: key, Env: env}
+	if err := c.invoke(ctx, "DeleteConfig", req, &result); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return result.Deleted, nil
+}
+
+// GRPCWatchOptions controls GRPCConfigClient.Watch
+type GRPCWatchOptions struct {
+	Environment string
+	// SinceRevision replays the changes after it, e.g. a revision saved
+	// from GRPCWatcher.Revision; 0 streams new changes only
+	SinceRevision int64
+}
+
+// GRPCWatcher receives a namespace's changes as the server pushes them,
+// instead of polling like Watcher
+type GRPCWatcher struct {
+	client *GRPCConfigClient
+	req    grpcWatchRequest
+	events chan ConfigEvent
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	revision int64
+	err      error
+}
+
+// Watch streams namespace's changes. A dropped stream is reopened from the
+// last revision delivered, backing off from 1s to 30s, so no change is
+// missed or repeated. The watcher stops on errors that retrying cannot fix;
+// see Err.
+func (c *GRPCConfigClient) Watch(ctx context.Context, namespace string, opts GRPCWatchOptions) (*GRPCWatcher, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("watch: namespace is required: %w", ErrValidation)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &GRPCWatcher{
+		client:   c,
+		req:      grpcWatchRequest{Namespace: namespace, Env: opts.Environment},
+		events:   make(chan ConfigEvent, 64),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		revision: opts.SinceRevision,
+	}
+	go w.run(ctx)
+	return w, nil
+}
+
+// Events delivers changes; it is closed when the watcher stops
+func (w *GRPCWatcher) Events() <-chan ConfigEvent {
+	return w.events
+}
+
+// Close stops the watcher and waits for it to exit
+func (w *GRPCWatcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// Revision is the revision of the last event delivered, or the one the
+// stream opened at before any arrived, to resume from
+func (w *GRPCWatcher) Revision() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.revision
+}
+
+// Err reports why the watcher stopped on its own, or nil. A
+// ConfigClientError with StatusCode http.StatusGone means the server no
+// longer keeps the revision to resume from: list again and watch from 0.
+func (w *GRPCWatcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *GRPCWatcher) run(ctx context.Context) {
+	defer func() {
+		close(w.events)
+		close(w.done)
+	}()
+
+	backoff := time.Second
+	for {
+		delivered, err := w.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if !grpcRetryable(err) {
+			w.mu.Lock()
+			w.err = fmt.Errorf("watch %s: %w", w.req.Namespace, grpcError(ctx, err))
+			w.mu.Unlock()
+			return
+		}
+		if delivered {
+			backoff = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// stream delivers events from one Watch call until it ends, reporting
+// whether any arrived
+func (w *GRPCWatcher) stream(ctx context.Context) (bool, error) {
+	stream, err := w.client.conn.NewStream(ctx, &grpc.StreamDesc{StreamName: "Watch", ServerStreams: true}, grpcService+"Watch")
+	if err != nil {
+		return false, err
+	}
+	req := w.req
+	req.SinceRevision = w.Revision()
+	// A failed send shows up as the status of the first receive
+	if err := stream.SendMsg(&req); err != nil && err != io.EOF {
+		return false, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return false, err
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return false, err
+	}
+	if req.SinceRevision == 0 {
+		// Without a revision to resume from, start from the one the stream
+		// opened at, so a reconnect before the first event misses nothing
+		if values := header.Get(grpcRevisionHeader); len(values) > 0 {
+			if revision, err := strconv.ParseInt(values[0], 10, 64); err == nil {
+				w.mu.Lock()
+				if w.revision == 0 {
+					w.revision = revision
+				}
+				w.mu.Unlock()
+			}
+		}
+	}
+
+	delivered := false
+	for {
+		var event grpcConfigEvent
+		if err := stream.RecvMsg(&event); err != nil {
+			return delivered, err
+		}
+		select {
+		case w.events <- event.ConfigEvent:
+		case <-ctx.Done():
+			return delivered, ctx.Err()
+		}
+		delivered = true
+		w.mu.Lock()
+		w.revision = event.Revision
+		w.mu.Unlock()
+	}
+}
+
+// grpcRetryable reports whether reopening a watch stream may succeed
+func grpcRetryable(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// The messages of ../llmconfig.proto are encoded by hand with protowire,
+// so neither the client nor its users need generated code. Field numbers
+// must follow the .proto file.
+
+// protoMessage is a message grpcCodec can carry
+type protoMessage interface {
+	marshalProto() ([]byte, error)
+	unmarshalProto(b []byte) error
+}
+
+// grpcCodec sends protoMessages as protobuf; being named "proto", it is
+// wire-compatible with servers built from generated code
+type grpcCodec struct{}
+
+func (grpcCodec) Name() string {
+	return "proto"
+}
+
+func (grpcCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(protoMessage)
+	if !ok {
+		return nil, fmt.Errorf("grpc codec: cannot marshal %T", v)
+	}
+	return m.marshalProto()
+}
+
+func (grpcCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(protoMessage)
+	if !ok {
+		return fmt.Errorf("grpc codec: cannot unmarshal into %T", v)
+	}
+	return m.unmarshalProto(data)
+}
+
+func appendProtoString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendProtoVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendProtoBool(b []byte, num protowire.Number, v bool) []byte {
+	return appendProtoVarint(b, num, protowire.EncodeBool(v))
+}
+
+// protoField is one decoded field; scalars are in varint, strings and
+// messages in bytes
+type protoField struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+// readProtoFields calls each for every varint and length-delimited field
+// of b, skipping other wire types
+func readProtoFields(b []byte, each func(f protoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := protoField{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if typ == protowire.VarintType || typ == protowire.BytesType {
+			if err := each(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// marshalProtoValue encodes a JSON-shaped value as google.protobuf.Value
+func marshalProtoValue(v interface{}) ([]byte, error) {
+	value, err := structpb.NewValue(v)
+	if err != nil {
+		// Structs and typed slices and maps go through their JSON form
+		data, jsonErr := json.Marshal(v)
+		if jsonErr != nil {
+			return nil, fmt.Errorf("encode value: %w", jsonErr)
+		}
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return nil, fmt.Errorf("encode value: %w", err)
+		}
+		if value, err = structpb.NewValue(generic); err != nil {
+			return nil, fmt.Errorf("encode value: %w", err)
+		}
+	}
+	return proto.Marshal(value)
+}
+
+func unmarshalProtoValue(b []byte) (interface{}, error) {
+	var value structpb.Value
+	if err := proto.Unmarshal(b, &value); err != nil {
+		return nil, fmt.Errorf("decode value: %w", err)
+	}
+	return value.AsInterface(), nil
+}
+
+// grpcConfig is llmconfig.v1.Config
+type grpcConfig struct {
+	ConfigResponse
+}
+
+func (m *grpcConfig) marshalProto() ([]byte, error) {
+	value, err := marshalProtoValue(m.Value)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	b = appendProtoString(b, 1, m.ID)
+	b = appendProtoString(b, 2, m.Namespace)
+	b = appendProtoString(b, 3, m.Key)
+	b = appendProtoBytes(b, 4, value)
+	b = appendProtoString(b, 5, m.Environment)
+	b = appendProtoVarint(b, 6, uint64(m.Version))
+
+	var md []byte
+	md = appendProtoString(md, 1, m.Metadata.CreatedAt)
+	md = appendProtoString(md, 2, m.Metadata.CreatedBy)
+	md = appendProtoString(md, 3, m.Metadata.UpdatedAt)
+	md = appendProtoString(md, 4, m.Metadata.UpdatedBy)
+	for _, tag := range m.Metadata.Tags {
+		md = protowire.AppendTag(md, 5, protowire.BytesType)
+		md = protowire.AppendString(md, tag)
+	}
+	if m.Metadata.Description != nil {
+		md = protowire.AppendTag(md, 6, protowire.BytesType)
+		md = protowire.AppendString(md, *m.Metadata.Description)
+	}
+	return appendProtoBytes(b, 7, md), nil
+}
+
+func (m *grpcConfig) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			m.ID = string(f.bytes)
+		case 2:
+			m.Namespace = string(f.bytes)
+		case 3:
+			m.Key = string(f.bytes)
+		case 4:
+			m.Value, err = unmarshalProtoValue(f.bytes)
+		case 5:
+			m.Environment = string(f.bytes)
+		case 6:
+			m.Version = int64(f.varint)
+		case 7:
+			err = readProtoFields(f.bytes, func(f protoField) error {
+				switch f.num {
+				case 1:
+					m.Metadata.CreatedAt = string(f.bytes)
+				case 2:
+					m.Metadata.CreatedBy = string(f.bytes)
+				case 3:
+					m.Metadata.UpdatedAt = string(f.bytes)
+				case 4:
+					m.Metadata.UpdatedBy = string(f.bytes)
+				case 5:
+					m.Metadata.Tags = append(m.Metadata.Tags, string(f.bytes))
+				case 6:
+					description := string(f.bytes)
+					m.Metadata.Description = &description
+				}
+				return nil
+			})
+		}
+		return err
+	})
+}
+
+// grpcGetConfigRequest is llmconfig.v1.GetConfigRequest
+type grpcGetConfigRequest struct {
+	Namespace, Key, Env string
+	WithOverrides       bool
+}
+
+func (m *grpcGetConfigRequest) marshalProto() ([]byte, error) {
+	var b []byte
+	b = appendProtoString(b, 1, m.Namespace)
+	b = appendProtoString(b, 2, m.Key)
+	b = appendProtoString(b, 3, m.Env)
+	return appendProtoBool(b, 4, m.WithOverrides), nil
+}
+
+func (m *grpcGetConfigRequest) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Namespace = string(f.bytes)
+		case 2:
+			m.Key = string(f.bytes)
+		case 3:
+			m.Env = string(f.bytes)
+		case 4:
+			m.WithOverrides = protowire.DecodeBool(f.varint)
+		}
+		return nil
+	})
+}
+
+// grpcListConfigsRequest is llmconfig.v1.ListConfigsRequest
+type grpcListConfigsRequest struct {
+	Namespace, Env string
+	Limit          int64
+	Cursor         string
+}
+
+func (m *grpcListConfigsRequest) marshalProto() ([]byte, error) {
+	var b []byte
+	b = appendProtoString(b, 1, m.Namespace)
+	b = appendProtoString(b, 2, m.Env)
+	b = appendProtoVarint(b, 3, uint64(m.Limit))
+	return appendProtoString(b, 4, m.Cursor), nil
+}
+
+func (m *grpcListConfigsRequest) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Namespace = string(f.bytes)
+		case 2:
+			m.Env = string(f.bytes)
+		case 3:
+			m.Limit = int64(int32(f.varint))
+		case 4:
+			m.Cursor = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// grpcListConfigsResponse is llmconfig.v1.ListConfigsResponse
+type grpcListConfigsResponse struct {
+	Page[ConfigResponse]
+}
+
+func (m *grpcListConfigsResponse) marshalProto() ([]byte, error) {
+	var b []byte
+	for i := range m.Items {
+		config, err := (&grpcConfig{m.Items[i]}).marshalProto()
+		if err != nil {
+			return nil, err
+		}
+		b = appendProtoBytes(b, 1, config)
+	}
+	if m.Total >= 0 {
+		// total is optional: a known total of 0 is still sent
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Total))
+	}
+	return appendProtoString(b, 3, m.NextCursor), nil
+}
+
+func (m *grpcListConfigsResponse) unmarshalProto(b []byte) error {
+	m.Total = -1
+	return readProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			var config grpcConfig
+			if err := config.unmarshalProto(f.bytes); err != nil {
+				return err
+			}
+			m.Items = append(m.Items, config.ConfigResponse)
+		case 2:
+			m.Total = int64(f.varint)
+		case 3:
+			m.NextCursor = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// grpcSetConfigRequest is llmconfig.v1.SetConfigRequest
+type grpcSetConfigRequest struct {
+	Namespace, Key string
+	Value          interface{}
+	Env, User      string
+	Secret         bool
+	Tags           []string
+}
+
+func (m *grpcSetConfigRequest) marshalProto() ([]byte, error) {
+	value, err := marshalProtoValue(m.Value)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	b = appendProtoString(b, 1, m.Namespace)
+	b = appendProtoString(b, 2, m.Key)
+	b = appendProtoBytes(b, 3, value)
+	b = appendProtoString(b, 4, m.Env)
+	b = appendProtoString(b, 5, m.User)
+	b = appendProtoBool(b, 6, m.Secret)
+	for _, tag := range m.Tags {
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendString(b, tag)
+	}
+	return b, nil
+}
+
+func (m *grpcSetConfigRequest) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			m.Namespace = string(f.bytes)
+		case 2:
+			m.Key = string(f.bytes)
+		case 3:
+			m.Value, err = unmarshalProtoValue(f.bytes)
+		case 4:
+			m.Env = string(f.bytes)
+		case 5:
+			m.User = string(f.bytes)
+		case 6:
+			m.Secret = protowire.DecodeBool(f.varint)
+		case 7:
+			m.Tags = append(m.Tags, string(f.bytes))
+		}
+		return err
+	})
+}
+
+// grpcDeleteConfigRequest is llmconfig.v1.DeleteConfigRequest
+type grpcDeleteConfigRequest struct {
+	Namespace, Key, Env string
+}
+
+func (m *grpcDeleteConfigRequest) marshalProto() ([]byte, error) {
+	var b []byte
+	b = appendProtoString(b, 1, m.Namespace)
+	b = appendProtoString(b, 2, m.Key)
+	return appendProtoString(b, 3, m.Env), nil
+}
+
+func (m *grpcDeleteConfigRequest) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Namespace = string(f.bytes)
+		case 2:
+			m.Key = string(f.bytes)
+		case 3:
+			m.Env = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// grpcDeleteConfigResponse is llmconfig.v1.DeleteConfigResponse
+type grpcDeleteConfigResponse struct {
+	Deleted bool
+}
+
+func (m *grpcDeleteConfigResponse) marshalProto() ([]byte, error) {
+	return appendProtoBool(nil, 1, m.Deleted), nil
+}
+
+func (m *grpcDeleteConfigResponse) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		if f.num == 1 {
+			m.Deleted = protowire.DecodeBool(f.varint)
+		}
+		return nil
+	})
+}
+
+// grpcWatchRequest is llmconfig.v1.WatchRequest
+type grpcWatchRequest struct {
+	Namespace, Env string
+	SinceRevision  int64
+}
+
+func (m *grpcWatchRequest) marshalProto() ([]byte, error) {
+	var b []byte
+	b = appendProtoString(b, 1, m.Namespace)
+	b = appendProtoString(b, 2, m.Env)
+	return appendProtoVarint(b, 3, uint64(m.SinceRevision)), nil
+}
+
+func (m *grpcWatchRequest) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Namespace = string(f.bytes)
+		case 2:
+			m.Env = string(f.bytes)
+		case 3:
+			m.SinceRevision = int64(f.varint)
+		}
+		return nil
+	})
+}
+
+// grpcEventTypes are the values of llmconfig.v1.EventType
+var grpcEventTypes = []string{"", ConfigCreated, ConfigUpdated, ConfigDeleted}
+
+// grpcConfigEvent is llmconfig.v1.ConfigEvent
+type grpcConfigEvent struct {
+	ConfigEvent
+	Revision int64
+}
+
+func (m *grpcConfigEvent) marshalProto() ([]byte, error) {
+	var b []byte
+	for i, t := range grpcEventTypes {
+		if t != "" && t == m.Type {
+			b = appendProtoVarint(b, 1, uint64(i))
+		}
+	}
+	b = appendProtoString(b, 2, m.Namespace)
+	b = appendProtoString(b, 3, m.Environment)
+	b = appendProtoString(b, 4, m.Key)
+	for _, field := range []struct {
+		num    protowire.Number
+		config *ConfigResponse
+	}{{5, m.Config}, {6, m.Previous}} {
+		if field.config == nil {
+			continue
+		}
+		data, err := (&grpcConfig{*field.config}).marshalProto()
+		if err != nil {
+			return nil, err
+		}
+		b = appendProtoBytes(b, field.num, data)
+	}
+	return appendProtoVarint(b, 7, uint64(m.Revision)), nil
+}
+
+func (m *grpcConfigEvent) unmarshalProto(b []byte) error {
+	return readProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			if f.varint < uint64(len(grpcEventTypes)) {
+				m.Type = grpcEventTypes[f.varint]
+			}
+		case 2:
+			m.Namespace = string(f.bytes)
+		case 3:
+			m.Environment = string(f.bytes)
+		case 4:
+			m.Key = string(f.bytes)
+		case 5, 6:
+			var config grpcConfig
+			if err := config.unmarshalProto(f.bytes); err != nil {
+				return err
+			}
+			if f.num == 5 {
+				m.Config = &config.ConfigResponse
+			} else {
+				m.Previous = &config.ConfigResponse
+			}
+		case 7:
+			m.Revision = int64(f.varint)
+		}
+		return nil
+	})
+}
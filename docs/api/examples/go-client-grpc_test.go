@@ -0,0 +1,220 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcTestDescriptor is the part of ../llmconfig.proto the hand-written
+// codec answers for, as the descriptor protoc would produce, so the codec
+// is checked against the real protobuf encoding without generated code
+const grpcTestDescriptor = `
+name: "llmconfig.proto"
+package: "llmconfig.v1"
+dependency: "google/protobuf/struct.proto"
+syntax: "proto3"
+message_type: {
+  name: "ConfigMetadata"
+  field: { name: "created_at" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "createdAt" }
+  field: { name: "created_by" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "createdBy" }
+  field: { name: "updated_at" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "updatedAt" }
+  field: { name: "updated_by" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "updatedBy" }
+  field: { name: "tags" number: 5 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" }
+  field: { name: "description" number: 6 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "description" oneof_index: 0 proto3_optional: true }
+  oneof_decl: { name: "_description" }
+}
+message_type: {
+  name: "Config"
+  field: { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+  field: { name: "namespace" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "namespace" }
+  field: { name: "key" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+  field: { name: "value" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Value" json_name: "value" }
+  field: { name: "environment" number: 5 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "environment" }
+  field: { name: "version" number: 6 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "version" }
+  field: { name: "metadata" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".llmconfig.v1.ConfigMetadata" json_name: "metadata" }
+}
+message_type: {
+  name: "ListConfigsResponse"
+  field: { name: "configs" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".llmconfig.v1.Config" json_name: "configs" }
+  field: { name: "total" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "total" oneof_index: 0 proto3_optional: true }
+  field: { name: "next_cursor" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "nextCursor" }
+  oneof_decl: { name: "_total" }
+}
+message_type: {
+  name: "ConfigEvent"
+  field: { name: "type" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".llmconfig.v1.EventType" json_name: "type" }
+  field: { name: "namespace" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "namespace" }
+  field: { name: "environment" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "environment" }
+  field: { name: "key" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+  field: { name: "config" number: 5 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".llmconfig.v1.Config" json_name: "config" }
+  field: { name: "previous" number: 6 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".llmconfig.v1.Config" json_name: "previous" }
+  field: { name: "revision" number: 7 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "revision" }
+}
+enum_type: {
+  name: "EventType"
+  value: { name: "EVENT_TYPE_UNSPECIFIED" number: 0 }
+  value: { name: "EVENT_TYPE_CREATED" number: 1 }
+  value: { name: "EVENT_TYPE_UPDATED" number: 2 }
+  value: { name: "EVENT_TYPE_DELETED" number: 3 }
+}
+`
+
+func grpcTestMessage(t *testing.T, name string) protoreflect.MessageDescriptor {
+	t.Helper()
+	var fdp descriptorpb.FileDescriptorProto
+	if err := prototext.Unmarshal([]byte(grpcTestDescriptor), &fdp); err != nil {
+		t.Fatal(err)
+	}
+	file, err := protodesc.NewFile(&fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file.Messages().ByName(protoreflect.Name(name))
+}
+
+func TestGRPCCodecMatchesProto(t *testing.T) {
+	description := "Default sampling"
+	config := ConfigResponse{
+		ID:          "c-1",
+		Namespace:   "app/llm",
+		Key:         "sampling",
+		Value:       map[string]interface{}{"temperature": 0.7, "models": []interface{}{"a", "b"}, "stream": true},
+		Environment: "production",
+		Version:     3,
+		Metadata: ConfigMetadata{
+			CreatedAt:   "2024-01-01T00:00:00Z",
+			CreatedBy:   "alice",
+			UpdatedAt:   "2024-01-02T00:00:00Z",
+			UpdatedBy:   "bob",
+			Tags:        []string{"llm", "sampling"},
+			Description: &description,
+		},
+	}
+	configJSON := `{
+		"id": "c-1", "namespace": "app/llm", "key": "sampling",
+		"value": {"temperature": 0.7, "models": ["a", "b"], "stream": true},
+		"environment": "production", "version": "3",
+		"metadata": {
+			"createdAt": "2024-01-01T00:00:00Z", "createdBy": "alice",
+			"updatedAt": "2024-01-02T00:00:00Z", "updatedBy": "bob",
+			"tags": ["llm", "sampling"], "description": "Default sampling"
+		}
+	}`
+	previous := config
+	previous.Version = 2
+	previous.Value = "plain"
+	previous.Metadata.Tags = nil
+	previous.Metadata.Description = nil
+	previousJSON := `{
+		"id": "c-1", "namespace": "app/llm", "key": "sampling", "value": "plain",
+		"environment": "production", "version": "2",
+		"metadata": {
+			"createdAt": "2024-01-01T00:00:00Z", "createdBy": "alice",
+			"updatedAt": "2024-01-02T00:00:00Z", "updatedBy": "bob"
+		}
+	}`
+
+	tests := []struct {
+		name    string
+		message string
+		msg     protoMessage
+		empty   func() protoMessage
+		json    string
+	}{
+		{
+			name:    "config",
+			message: "Config",
+			msg:     &grpcConfig{config},
+			empty:   func() protoMessage { return &grpcConfig{} },
+			json:    configJSON,
+		},
+		{
+			name:    "list with total",
+			message: "ListConfigsResponse",
+			msg: &grpcListConfigsResponse{Page[ConfigResponse]{
+				Items: []ConfigResponse{config, previous}, Total: 12, NextCursor: "next",
+			}},
+			empty: func() protoMessage { return &grpcListConfigsResponse{} },
+			json:  `{"configs": [` + configJSON + `, ` + previousJSON + `], "total": "12", "nextCursor": "next"}`,
+		},
+		{
+			name:    "list with zero total",
+			message: "ListConfigsResponse",
+			msg:     &grpcListConfigsResponse{Page[ConfigResponse]{Total: 0}},
+			empty:   func() protoMessage { return &grpcListConfigsResponse{} },
+			json:    `{"total": "0"}`,
+		},
+		{
+			name:    "list with unknown total",
+			message: "ListConfigsResponse",
+			msg:     &grpcListConfigsResponse{Page[ConfigResponse]{Items: []ConfigResponse{previous}, Total: -1}},
+			empty:   func() protoMessage { return &grpcListConfigsResponse{} },
+			json:    `{"configs": [` + previousJSON + `]}`,
+		},
+		{
+			name:    "update event",
+			message: "ConfigEvent",
+			msg: &grpcConfigEvent{ConfigEvent{
+				Type: ConfigUpdated, Namespace: "app/llm", Environment: "production", Key: "sampling",
+				Config: &config, Previous: &previous,
+			}, 42},
+			empty: func() protoMessage { return &grpcConfigEvent{} },
+			json: `{"type": "EVENT_TYPE_UPDATED", "namespace": "app/llm", "environment": "production", "key": "sampling",
+				"config": ` + configJSON + `, "previous": ` + previousJSON + `, "revision": "42"}`,
+		},
+		{
+			name:    "delete event",
+			message: "ConfigEvent",
+			msg: &grpcConfigEvent{ConfigEvent{
+				Type: ConfigDeleted, Namespace: "app/llm", Key: "sampling", Previous: &previous,
+			}, 43},
+			empty: func() protoMessage { return &grpcConfigEvent{} },
+			json:  `{"type": "EVENT_TYPE_DELETED", "namespace": "app/llm", "key": "sampling", "previous": ` + previousJSON + `, "revision": "43"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc := grpcTestMessage(t, tt.message)
+			want := dynamicpb.NewMessage(desc)
+			if err := protojson.Unmarshal([]byte(tt.json), want); err != nil {
+				t.Fatal(err)
+			}
+
+			// The codec's bytes decode to the message protobuf expects
+			data, err := tt.msg.marshalProto()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := dynamicpb.NewMessage(desc)
+			if err := proto.Unmarshal(data, got); err != nil {
+				t.Fatalf("proto.Unmarshal: %v", err)
+			}
+			if !proto.Equal(got, want) {
+				t.Errorf("codec encoding decodes to\n%v\nwant\n%v", got, want)
+			}
+
+			// and protobuf's bytes decode to the codec's value
+			data, err = proto.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded := tt.empty()
+			if err := decoded.unmarshalProto(data); err != nil {
+				t.Fatalf("unmarshalProto: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.msg) {
+				t.Errorf("proto encoding decodes to\n%+v\nwant\n%+v", decoded, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GuardrailsKey is the key holding a namespace's guardrails
+const GuardrailsKey = "guardrails"
+
+// GlobalGuardrailsNamespace holds the guardrails every namespace inherits
+const GlobalGuardrailsNamespace = "global"
+
+// Guardrails are the limits a service must enforce around model calls.
+// Levels combine so the strictest setting wins: blocklists and categories
+// accumulate, token limits take the smallest, and an allow-list of models
+// can only narrow.
+type Guardrails struct {
+	// BlockedTerms are rejected case-insensitively in input and output
+	BlockedTerms []string `json:"blocked_terms,omitempty"`
+	// BlockedPatterns are regular expressions rejected in input and output
+	BlockedPatterns []string `json:"blocked_patterns,omitempty"`
+	MaxInputTokens  int      `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+	// AllowedModels limits which models may be called. Nil allows all; an
+	// empty, non-nil list allows none, and is kept as [] in JSON.
+	AllowedModels []string        `json:"allowed_models"`
+	Safety        *SafetySettings `json:"safety,omitempty"`
+}
+
+// guardrailsSchema is the JSON Schema for a Guardrails value
+const guardrailsSchema = `{
+	"type": "object",
+	"additionalProperties": false,
+	"properties": {
+		"blocked_terms": {"type": "array", "items": {"type": "string", "minLength": 1}},
+		"blocked_patterns": {"type": "array", "items": {"type": "string", "minLength": 1}},
+		"max_input_tokens": {"type": "integer", "minimum": 0},
+		"max_output_tokens": {"type": "integer", "minimum": 0},
+		"allowed_models": {"type": ["array", "null"], "items": {"type": "string", "minLength": 1}},
+		"safety": {
+			"type": "object",
+			"additionalProperties": false,
+			"required": ["block_threshold"],
+			"properties": {
+				"block_threshold": {"enum": ["none", "low", "medium", "high"]},
+				"categories": {"type": "array", "items": {"type": "string"}},
+				"redact_pii": {"type": "boolean"},
+				"max_input_chars": {"type": "integer", "minimum": 0}
+			}
+		}
+	}
+}`
+
+// UseGuardrailsSchema registers the guardrails schema for the guardrails key
+// in namespaces matching namespacePattern, so malformed guardrails are
+// rejected before SetConfig sends them
+func (c *LLMConfigClient) UseGuardrailsSchema(namespacePattern string) error {
+	return c.RegisterSchema(namespacePattern, GuardrailsKey, []byte(guardrailsSchema))
+}
+
+// Validate checks limits, patterns, and safety settings
+func (g Guardrails) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, pattern := range g.BlockedPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			fail(fmt.Sprintf("/blocked_patterns/%d", i), "%v", err)
+		}
+	}
+	if g.MaxInputTokens < 0 {
+		fail("/max_input_tokens", "max_input_tokens must not be negative")
+	}
+	if g.MaxOutputTokens < 0 {
+		fail("/max_output_tokens", "max_output_tokens must not be negative")
+	}
+	if g.Safety != nil {
+		var verr *ValidationError
+		if errors.As(g.Safety.Validate(), &verr) {
+			for _, p := range verr.Problems {
+				fail("/safety"+p.Path, "%s", p.Message)
+			}
+		}
+	}
+
+	return paramsError(problems)
+}
+
+// blockStrictness orders safety block thresholds from least to most
+// content blocked: "high" blocks only high-severity content, "low" blocks
+// anything above low
+var blockStrictness = map[string]int{"none": 0, "high": 1, "medium": 2, "low": 3}
+
+// Merge returns the guardrails in effect when g applies on top of parent
+func (g Guardrails) Merge(parent Guardrails) Guardrails {
+	merged := Guardrails{
+		BlockedTerms:    unionStrings(parent.BlockedTerms, g.BlockedTerms),
+		BlockedPatterns: unionStrings(parent.BlockedPatterns, g.BlockedPatterns),
+		MaxInputTokens:  minLimit(parent.MaxInputTokens, g.MaxInputTokens),
+		MaxOutputTokens: minLimit(parent.MaxOutputTokens, g.MaxOutputTokens),
+	}
+
+	switch {
+	case parent.AllowedModels == nil:
+		merged.AllowedModels = slices.Clone(g.AllowedModels)
+	case g.AllowedModels == nil:
+		merged.AllowedModels = slices.Clone(parent.AllowedModels)
+	default:
+		// An empty intersection stays non-nil so that it allows nothing
+		merged.AllowedModels = []string{}
+		for _, model := range g.AllowedModels {
+			if slices.Contains(parent.AllowedModels, model) {
+				merged.AllowedModels = append(merged.AllowedModels, model)
+			}
+		}
+	}
+
+	switch {
+	case parent.Safety == nil && g.Safety != nil:
+		safety := *g.Safety
+		merged.Safety = &safety
+	case parent.Safety != nil && g.Safety == nil:
+		safety := *parent.Safety
+		merged.Safety = &safety
+	case parent.Safety != nil && g.Safety != nil:
+		safety := SafetySettings{
+			BlockThreshold: parent.Safety.BlockThreshold,
+			Categories:     unionStrings(parent.Safety.Categories, g.Safety.Categories),
+			RedactPII:      parent.Safety.RedactPII || g.Safety.RedactPII,
+			MaxInputChars:  minLimit(parent.Safety.MaxInputChars, g.Safety.MaxInputChars),
+		}
+		if blockStrictness[g.Safety.BlockThreshold] > blockStrictness[safety.BlockThreshold] {
+			safety.BlockThreshold = g.Safety.BlockThreshold
+		}
+		merged.Safety = &safety
+	}
+
+	return merged
+}
+
+// AllowsModel reports whether model may be called
+func (g *Guardrails) AllowsModel(model string) bool {
+	return g.AllowedModels == nil || slices.Contains(g.AllowedModels, model)
+}
+
+// EffectiveGuardrails merges the global guardrails with those of namespace
+// and each of its parents, so "app/llm" applies global, then "app", then
+// "app/llm". Levels without a guardrails key are skipped; each level's
+// value is validated.
+func (c *LLMConfigClient) EffectiveGuardrails(ctx context.Context, namespace, env string) (*Guardrails, error) {
+	levels := []string{GlobalGuardrailsNamespace}
+	parts := strings.Split(namespace, "/")
+	for i := range parts {
+		levels = append(levels, strings.Join(parts[:i+1], "/"))
+	}
+
+	var effective Guardrails
+	for _, level := range levels {
+		var g Guardrails
+		err := c.GetParams(ctx, level, GuardrailsKey, env, &g)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		effective = g.Merge(effective)
+	}
+	return &effective, nil
+}
+
+// unionStrings returns the distinct values of a and b, sorted
+func unionStrings(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		seen[s] = true
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(seen))
+	for s := range seen {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// minLimit returns the stricter of two limits where zero means unlimited
+func minLimit(a, b int) int {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	}
+	return min(a, b)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// terraformResourceType is the resource type exposed by the Terraform provider
+const terraformResourceType = "llmconfig_config"
+
+// HCLConfigResource mirrors one llmconfig_config Terraform resource
+type HCLConfigResource struct {
+	Name        string
+	Namespace   string
+	Key         string
+	Environment string
+	Value       interface{}
+	Secret      bool
+}
+
+// hclResourceBody is the attribute schema of an llmconfig_config block
+type hclResourceBody struct {
+	Namespace   string    `hcl:"namespace"`
+	Key         string    `hcl:"key"`
+	Environment string    `hcl:"environment"`
+	Value       cty.Value `hcl:"value"`
+	Secret      *bool     `hcl:"secret"`
+	Remain      hcl.Body  `hcl:",remain"`
+}
+
+// ExportHCL renders a namespace as llmconfig_config resources. Secrets the
+// API returns masked are written as var.<resource name> references so the
+// real value can be supplied from a sensitive Terraform variable.
+func (c *LLMConfigClient) ExportHCL(ctx context.Context, namespace, env string) ([]byte, error) {
+	configs, err := c.ListConfigsContext(ctx, namespace, env)
+	if err != nil {
+		return nil, err
+	}
+	return RenderHCL(configs)
+}
+
+// RenderHCL converts configs into Terraform resource blocks, sorted by key
+func RenderHCL(configs []ConfigResponse) ([]byte, error) {
+	sorted := append([]ConfigResponse(nil), configs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+
+	file := hclwrite.NewEmptyFile()
+	root := file.Body()
+
+	used := make(map[string]bool, len(sorted))
+	for i, cfg := range sorted {
+		if i > 0 {
+			root.AppendNewline()
+		}
+
+		// Keys that differ only in characters identifiers cannot hold
+		// ("a.b" and "a_b") get numbered names in key order
+		name := hclResourceName(cfg.Namespace, cfg.Key)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", hclResourceName(cfg.Namespace, cfg.Key), n)
+		}
+		used[name] = true
+		block := root.AppendNewBlock("resource", []string{terraformResourceType, name})
+		body := block.Body()
+		body.SetAttributeValue("namespace", cty.StringVal(cfg.Namespace))
+		body.SetAttributeValue("key", cty.StringVal(cfg.Key))
+		body.SetAttributeValue("environment", cty.StringVal(cfg.Environment))
+
+		if s, ok := cfg.Value.(string); ok && s == encryptedPlaceholder {
+			body.SetAttributeTraversal("value", hcl.Traversal{
+				hcl.TraverseRoot{Name: "var"},
+				hcl.TraverseAttr{Name: name},
+			})
+			body.SetAttributeValue("secret", cty.True)
+			continue
+		}
+
+		value, err := goToCty(normalizeExportValue(cfg.Value))
+		if err != nil {
+			return nil, fmt.Errorf("render %s/%s: %w", cfg.Namespace, cfg.Key, err)
+		}
+		body.SetAttributeValue("value", value)
+	}
+
+	return file.Bytes(), nil
+}
+
+// ParseHCL reads llmconfig_config resources from HCL source. vars supplies
+// values for var.* references (typically secrets); other resource types and
+// blocks are ignored.
+func ParseHCL(src []byte, filename string, vars map[string]interface{}) ([]HCLConfigResource, error) {
+	file, diags := hclparse.NewParser().ParseHCL(src, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "resource", LabelNames: []string{"type", "name"}}},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	varValues := make(map[string]cty.Value, len(vars))
+	for name, v := range vars {
+		converted, err := goToCty(v)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		varValues[name] = converted
+	}
+	evalCtx := &hcl.EvalContext{Variables: map[string]cty.Value{"var": cty.ObjectVal(varValues)}}
+
+	var resources []HCLConfigResource
+	for _, block := range content.Blocks {
+		if block.Labels[0] != terraformResourceType {
+			continue
+		}
+
+		var body hclResourceBody
+		if diags := gohcl.DecodeBody(block.Body, evalCtx, &body); diags.HasErrors() {
+			return nil, diags
+		}
+
+		value, err := ctyToGo(body.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", block.Labels[0], block.Labels[1], err)
+		}
+
+		resources = append(resources, HCLConfigResource{
+			Name:        block.Labels[1],
+			Namespace:   body.Namespace,
+			Key:         body.Key,
+			Environment: body.Environment,
+			Value:       value,
+			Secret:      body.Secret != nil && *body.Secret,
+		})
+	}
+
+	return resources, nil
+}
+
+// ImportHCL parses llmconfig_config resources and writes each one
+func (c *LLMConfigClient) ImportHCL(ctx context.Context, src []byte, filename, user string, vars map[string]interface{}) ([]HCLConfigResource, error) {
+	resources, err := ParseHCL(src, filename, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range resources {
+		if _, err := c.SetConfigContext(ctx, r.Namespace, r.Key, r.Value, r.Environment, user, r.Secret); err != nil {
+			return nil, fmt.Errorf("import %s: %w", r.Name, err)
+		}
+	}
+
+	return resources, nil
+}
+
+// hclResourceName builds a Terraform identifier from a namespace and key
+func hclResourceName(namespace, key string) string {
+	var b strings.Builder
+	for _, r := range namespace + "_" + key {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+	return name
+}
+
+// goToCty converts a JSON-compatible Go value into a cty value
+func goToCty(v interface{}) (cty.Value, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ty, err := ctyjson.ImpliedType(encoded)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(encoded, ty)
+}
+
+// ctyToGo converts a cty value back into plain Go values
+func ctyToGo(v cty.Value) (interface{}, error) {
+	if !v.IsWhollyKnown() {
+		return nil, fmt.Errorf("value is not known")
+	}
+	encoded, err := ctyjson.SimpleJSONValue{Value: v}.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(encoded, &out); err != nil {
+		return nil, err
+	}
+	return normalizeExportValue(out), nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DotenvEntry is a single KEY=value pair read from a dotenv file
+type DotenvEntry struct {
+	Name   string
+	Value  string
+	Quoted bool
+	Line   int
+}
+
+// DotenvImportOptions controls how dotenv entries map onto namespace keys
+type DotenvImportOptions struct {
+	Env  string
+	User string
+	// StripPrefix drops a leading prefix (e.g. "MYAPP_") from variable names;
+	// variables without the prefix are skipped
+	StripPrefix string
+	// SecretKeys lists namespace keys (after mapping) to store as secrets
+	SecretKeys []string
+	// DryRun parses and maps entries without writing them
+	DryRun bool
+}
+
+// ImportEntry describes a namespace key produced by an import
+type ImportEntry struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Secret bool        `json:"secret"`
+}
+
+// ImportDotenv parses a dotenv file and writes each variable into namespace,
+// inferring bool/int/float/JSON types for unquoted values. Entries are
+// returned in file order; with DryRun set nothing is written.
+func (c *LLMConfigClient) ImportDotenv(ctx context.Context, namespace string, r io.Reader, opts DotenvImportOptions) ([]ImportEntry, error) {
+	parsed, err := ParseDotenv(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := MapDotenvEntries(parsed, opts)
+	if opts.DryRun {
+		return entries, nil
+	}
+
+	for _, entry := range entries {
+		if _, err := c.SetConfigContext(ctx, namespace, entry.Key, entry.Value, opts.Env, opts.User, entry.Secret); err != nil {
+			return nil, fmt.Errorf("import %s/%s: %w", namespace, entry.Key, err)
+		}
+	}
+
+	return entries, nil
+}
+
+// MapDotenvEntries applies prefix stripping, key mapping, and type inference
+func MapDotenvEntries(parsed []DotenvEntry, opts DotenvImportOptions) []ImportEntry {
+	secrets := make(map[string]bool, len(opts.SecretKeys))
+	for _, key := range opts.SecretKeys {
+		secrets[key] = true
+	}
+
+	entries := make([]ImportEntry, 0, len(parsed))
+	for _, p := range parsed {
+		name := p.Name
+		if opts.StripPrefix != "" {
+			if !strings.HasPrefix(name, opts.StripPrefix) {
+				continue
+			}
+			name = strings.TrimPrefix(name, opts.StripPrefix)
+		}
+		if name == "" {
+			continue
+		}
+
+		key := strings.ToLower(name)
+		value := interface{}(p.Value)
+		if !p.Quoted || looksLikeJSON(p.Value) {
+			value = InferValue(p.Value)
+		}
+
+		entries = append(entries, ImportEntry{Key: key, Value: value, Secret: secrets[key]})
+	}
+	return entries
+}
+
+// InferValue converts a raw dotenv string into a bool, int64, float64, JSON
+// object/array, or leaves it as a string. Numbers with leading zeros (zip
+// codes, IDs) stay strings.
+func InferValue(raw string) interface{} {
+	switch strings.ToLower(raw) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if looksLikeJSON(raw) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err == nil {
+			return v
+		}
+	}
+
+	digits := strings.TrimPrefix(raw, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return raw
+	}
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return i
+	}
+	if strings.ContainsAny(digits, "0123456789") && strings.Trim(digits, "0123456789.eE+-") == "" {
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	}
+
+	return raw
+}
+
+func looksLikeJSON(raw string) bool {
+	raw = strings.TrimSpace(raw)
+	return len(raw) >= 2 &&
+		((raw[0] == '{' && raw[len(raw)-1] == '}') || (raw[0] == '[' && raw[len(raw)-1] == ']'))
+}
+
+// ParseDotenv reads KEY=value lines. It supports comments, an optional
+// "export " prefix, single quotes (literal), double quotes (with \n, \", \\
+// and \$ escapes, possibly spanning lines), and trailing " #" comments on
+// unquoted values.
+func ParseDotenv(r io.Reader) ([]DotenvEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var entries []DotenvEntry
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("dotenv line %d: expected KEY=value", lineNo)
+		}
+		name := strings.TrimSpace(line[:eq])
+		raw := strings.TrimSpace(line[eq+1:])
+		entry := DotenvEntry{Name: name, Line: lineNo}
+
+		switch {
+		case strings.HasPrefix(raw, "'"):
+			end := strings.IndexByte(raw[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("dotenv line %d: unterminated single quote", lineNo)
+			}
+			entry.Value = raw[1 : end+1]
+			entry.Quoted = true
+		case strings.HasPrefix(raw, `"`):
+			value, closed := unquoteDotenv(raw[1:])
+			for !closed {
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("dotenv line %d: unterminated double quote", entry.Line)
+				}
+				lineNo++
+				var rest string
+				rest, closed = unquoteDotenv(scanner.Text())
+				value += "\n" + rest
+			}
+			entry.Value = value
+			entry.Quoted = true
+		default:
+			if i := strings.Index(raw, " #"); i >= 0 {
+				raw = strings.TrimSpace(raw[:i])
+			}
+			entry.Value = raw
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// unquoteDotenv decodes a double-quoted segment, reporting whether the
+// closing quote was found
+func unquoteDotenv(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"':
+			return b.String(), true
+		case ch == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String(), false
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EffectiveConfig is a config as a namespace sees it once inherited values
+// are merged in
+type EffectiveConfig struct {
+	ConfigResponse
+	// Source is the namespace the value comes from: the namespace itself or
+	// the nearest ancestor that sets the key
+	Source string `json:"source"`
+	// Shadowed are the ancestors that also set the key, nearest first,
+	// whose values Source overrides
+	Shadowed []string `json:"shadowed,omitempty"`
+}
+
+// Inherited reports whether the value comes from an ancestor namespace
+func (e *EffectiveConfig) Inherited() bool {
+	return e.Source != e.Namespace
+}
+
+// namespaceLineage returns namespace and its ancestors, nearest first:
+// "app/llm/chat" gives app/llm/chat, app/llm, app
+func namespaceLineage(namespace string) []string {
+	lineage := []string{namespace}
+	for i := strings.LastIndex(namespace, "/"); i > 0; i = strings.LastIndex(namespace, "/") {
+		namespace = namespace[:i]
+		lineage = append(lineage, namespace)
+	}
+	return lineage
+}
+
+// EffectiveConfigs returns the configs namespace sees with inheritance: its
+// own keys, plus each key of an ancestor (app for app/llm/chat) that no
+// nearer namespace sets. Every config reports the namespace it comes from,
+// so `app/llm/chat` can tell its own temperature from one set on `app/llm`.
+// Configs are sorted by key and, like the Namespace field of each, are
+// reported against namespace itself.
+func (c *LLMConfigClient) EffectiveConfigs(ctx context.Context, namespace, env string) ([]EffectiveConfig, error) {
+	effective := map[string]*EffectiveConfig{}
+	for _, ns := range namespaceLineage(namespace) {
+		configs, err := c.ListConfigsContext(ctx, ns, env)
+		if errors.Is(err, ErrNotFound) {
+			// Intermediate namespaces need not exist
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", ns, err)
+		}
+		for _, cfg := range configs {
+			if existing, ok := effective[cfg.Key]; ok {
+				existing.Shadowed = append(existing.Shadowed, ns)
+				continue
+			}
+			cfg.Namespace = namespace
+			effective[cfg.Key] = &EffectiveConfig{ConfigResponse: cfg, Source: ns}
+		}
+	}
+
+	result := make([]EffectiveConfig, 0, len(effective))
+	for _, e := range effective {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
+
+// GetConfigInherited reads key from namespace, or failing that from the
+// nearest ancestor that sets it, stopping at the first found; Shadowed is
+// left empty (see EffectiveConfigs). A key set nowhere in the lineage fails
+// with ErrNotFound.
+func (c *LLMConfigClient) GetConfigInherited(ctx context.Context, namespace, key, env string) (*EffectiveConfig, error) {
+	lineage := namespaceLineage(namespace)
+	for _, ns := range lineage {
+		cfg, err := c.GetConfigContext(ctx, ns, key, env, true)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfg.Namespace = namespace
+		return &EffectiveConfig{ConfigResponse: *cfg, Source: ns}, nil
+	}
+	return nil, fmt.Errorf("%s/%s (%s) is not set in %s: %w", namespace, key, env, strings.Join(lineage, ", "), ErrNotFound)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// The client's capabilities, split so consumers can depend on (and mock)
+// only the part they use. *LLMConfigClient implements all of them; a
+// service that only reads config should take a ConfigReader:
+//
+//	func NewRouter(cfg ConfigReader) *Router { ... }
+
+// ConfigReader reads current config values
+type ConfigReader interface {
+	GetConfigContext(ctx context.Context, namespace, key, env string, withOverrides bool) (*ConfigResponse, error)
+	ListConfigsContext(ctx context.Context, namespace, env string) ([]ConfigResponse, error)
+}
+
+// ConfigPager lists page by page, for UIs and batch jobs over large
+// namespaces
+type ConfigPager interface {
+	ListConfigsPage(ctx context.Context, namespace, env string, opts PageOptions) (*Page[ConfigResponse], error)
+	ListNamespacesPage(ctx context.Context, prefix string, opts PageOptions) (*Page[string], error)
+	ListByTagPage(ctx context.Context, tag string, filter TagListOptions, opts PageOptions) (*Page[ConfigResponse], error)
+}
+
+// ConfigSelector fetches only the chosen fields of many configs in one
+// round trip
+type ConfigSelector interface {
+	SelectConfigs(ctx context.Context, env string, fields []ConfigField, refs ...ConfigRef) ([]*ConfigResponse, error)
+	SelectNamespaces(ctx context.Context, env string, fields []ConfigField, namespaces ...string) (map[string][]ConfigResponse, error)
+}
+
+// ConfigWriter creates, updates, and deletes config values
+type ConfigWriter interface {
+	SetConfigContext(ctx context.Context, namespace, key string, value interface{}, env, user string, secret bool) (*ConfigResponse, error)
+	DeleteConfigContext(ctx context.Context, namespace, key, env string) (bool, error)
+}
+
+// ConfigTagger groups configs across namespaces with tags
+type ConfigTagger interface {
+	AddTags(ctx context.Context, namespace, key, env string, tags ...string) ([]string, error)
+	RemoveTags(ctx context.Context, namespace, key, env string, tags ...string) ([]string, error)
+	ListByTag(ctx context.Context, tag string, opts TagListOptions) ([]ConfigResponse, error)
+	ListTags(ctx context.Context, namespace string) ([]TagCount, error)
+}
+
+// ConsumerRegistry records which services read which keys, to size the
+// blast radius of a change
+type ConsumerRegistry interface {
+	RegisterConsumer(ctx context.Context, reg ConsumerRegistration) (*ConsumerRegistration, error)
+	GetConsumer(ctx context.Context, service string) ([]ConsumerRegistration, error)
+	DeregisterConsumer(ctx context.Context, service, env string) (bool, error)
+	Consumers(ctx context.Context, namespace, key string, opts ConsumerListOptions) ([]Consumer, error)
+}
+
+// ConfigHistorian reads version history and rolls back to earlier versions
+type ConfigHistorian interface {
+	GetHistoryContext(ctx context.Context, namespace, key, env string) ([]VersionEntry, error)
+	RollbackContext(ctx context.Context, namespace, key string, version int64, env string) (*ConfigResponse, error)
+}
+
+// ConfigAnnotator attaches notes to keys and versions for change
+// archaeology
+type ConfigAnnotator interface {
+	Annotate(ctx context.Context, namespace, key string, a NewAnnotation, user string) (*Annotation, error)
+	ListAnnotations(ctx context.Context, namespace, key, env string) ([]Annotation, error)
+	DeleteAnnotation(ctx context.Context, namespace, key, id string) (bool, error)
+	GetAnnotatedHistory(ctx context.Context, namespace, key, env string) (*AnnotatedHistory, error)
+}
+
+// ConfigWatcher follows namespaces for changes
+type ConfigWatcher interface {
+	Watch(ctx context.Context, namespace string, opts WatchOptions) (*Watcher, error)
+	WatcherStatuses() []WatcherStatus
+}
+
+// ConfigAdmin covers operational endpoints: health, namespaces with their
+// quotas and usage statistics, key aliases and deprecations, namespace
+// templates, server-side constraints, validation webhooks and change
+// subscriptions, and backup/restore
+type ConfigAdmin interface {
+	HealthCheckContext(ctx context.Context) (*HealthResponse, error)
+	CreateNamespace(ctx context.Context, spec NamespaceSpec, user string) (*NamespaceInfo, error)
+	GetNamespace(ctx context.Context, namespace string) (*NamespaceInfo, error)
+	ListNamespaceInfo(ctx context.Context, prefix string) ([]NamespaceInfo, error)
+	SetNamespaceQuota(ctx context.Context, namespace string, quota NamespaceQuota) (*NamespaceInfo, error)
+	DeleteNamespace(ctx context.Context, namespace string, opts DeleteNamespaceOptions) error
+	GetNamespaceStats(ctx context.Context, namespace string, opts UsageStatsOptions) (*NamespaceStats, error)
+	ListNamespaceStats(ctx context.Context, prefix string, opts UsageStatsOptions) ([]NamespaceStats, error)
+	CreateKeyAlias(ctx context.Context, namespace, key, targetNamespace, targetKey, user string) (*KeyAlias, error)
+	ListKeyAliases(ctx context.Context, namespace string) ([]KeyAlias, error)
+	DeleteKeyAlias(ctx context.Context, namespace, key string) (bool, error)
+	Deprecate(ctx context.Context, namespace, key string, d KeyDeprecation, user string) (*KeyDeprecation, error)
+	Undeprecate(ctx context.Context, namespace, key string) (bool, error)
+	ListDeprecations(ctx context.Context, namespace string) ([]DeprecatedKey, error)
+	PutTemplate(ctx context.Context, t ConfigTemplate, user string) (*ConfigTemplate, error)
+	GetTemplate(ctx context.Context, name string, version int64) (*ConfigTemplate, error)
+	ListTemplates(ctx context.Context) ([]ConfigTemplate, error)
+	Instantiate(ctx context.Context, name string, params map[string]interface{}, opts InstantiateOptions) (*TemplateInstance, *ApplyPlan, error)
+	PutConstraint(ctx context.Context, namespace, key string, constraint Constraint) error
+	DeleteConstraint(ctx context.Context, namespace, key string) error
+	ListConstraints(ctx context.Context, namespace string) ([]KeyConstraint, error)
+	RegisterValidationWebhook(ctx context.Context, webhook ValidationWebhook) (*ValidationWebhook, error)
+	ListValidationWebhooks(ctx context.Context, namespace string) ([]ValidationWebhook, error)
+	RemoveValidationWebhook(ctx context.Context, id string) (bool, error)
+	CreateWebhookSubscription(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	TestWebhookSubscription(ctx context.Context, id string) (*WebhookDelivery, error)
+	DeleteWebhookSubscription(ctx context.Context, id string) (bool, error)
+	Backup(ctx context.Context, w io.Writer, namespaces ...string) (*BackupManifest, error)
+	Restore(ctx context.Context, r io.Reader, opts RestoreOptions) (*RestoreReport, error)
+}
+
+// AccessAdmin provisions users and teams, assigns namespace and key
+// ownership, and introspects the calling token
+type AccessAdmin interface {
+	WhoAmI(ctx context.Context) (*TokenInfo, error)
+	CreateUser(ctx context.Context, spec UserSpec, user string) (*User, error)
+	GetUser(ctx context.Context, id string) (*User, error)
+	ListUsers(ctx context.Context) ([]User, error)
+	CreateTeam(ctx context.Context, spec TeamSpec, user string) (*Team, error)
+	ListTeams(ctx context.Context) ([]Team, error)
+	AddTeamMember(ctx context.Context, team, userID string, role TeamRole) (*Membership, error)
+	RemoveTeamMember(ctx context.Context, team, userID string) (bool, error)
+	ListTeamMembers(ctx context.Context, team string) ([]Membership, error)
+	ListUserMemberships(ctx context.Context, userID string) ([]Membership, error)
+	AddNamespaceOwner(ctx context.Context, namespace, owner string) error
+	RemoveNamespaceOwner(ctx context.Context, namespace, owner string) error
+	SetOwnership(ctx context.Context, policy OwnershipPolicy, user string) (*OwnershipPolicy, error)
+	GetOwnership(ctx context.Context, namespace string) (*OwnershipPolicy, error)
+	KeyOwners(ctx context.Context, namespace, key string) (*KeyOwnership, error)
+}
+
+// ConfigAPI is every capability together
+type ConfigAPI interface {
+	ConfigReader
+	ConfigPager
+	ConfigSelector
+	ConfigWriter
+	ConfigTagger
+	ConsumerRegistry
+	ConfigHistorian
+	ConfigAnnotator
+	ConfigWatcher
+	ConfigAdmin
+	AccessAdmin
+}
+
+// Backend is the core of the API, reading and writing config values, that
+// application code can take to run against a server or, through
+// FileBackend, a directory of manifests alike
+type Backend interface {
+	ConfigReader
+	ConfigWriter
+}
+
+var _ ConfigAPI = (*LLMConfigClient)(nil)
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxReferenceDepth bounds how deeply references may chain
+const maxReferenceDepth = 16
+
+// ResolveOptions configures reference resolution
+type ResolveOptions struct {
+	// Environment is the environment referenced keys are read from
+	Environment string
+	// RevealSecrets resolves references to secret keys, decrypting
+	// client-side encrypted ones with SecretKey. Otherwise a reference to a
+	// secret is an error rather than "<encrypted>" spliced into the value.
+	RevealSecrets bool
+	SecretKey     SecretKey
+}
+
+// References returns the keys value refers to with ${namespace/key},
+// sorted, searching strings inside maps and slices. A reference without a
+// namespace, ${key}, is to namespace; $${ is a literal "${".
+func References(namespace string, value interface{}) []string {
+	var refs []string
+	walkReferences(value, func(s string) {
+		for _, ref := range parseReferences(s) {
+			if ref = qualifyReference(namespace, ref); !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	})
+	slices.Sort(refs)
+	return refs
+}
+
+// GetConfigResolved is GetConfigContext with references in the value
+// resolved; see ResolveReferences
+func (c *LLMConfigClient) GetConfigResolved(ctx context.Context, namespace, key, env string) (*ConfigResponse, error) {
+	cfg, err := c.GetConfigContext(ctx, namespace, key, env, true)
+	if err != nil {
+		return nil, err
+	}
+	r := &referenceResolver{client: c, opts: ResolveOptions{Environment: env}, values: map[string]interface{}{}}
+	if cfg.Value, err = r.resolveKey(ctx, namespace+"/"+key, cfg.Value); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ResolveReferences replaces each ${namespace/key} in value with that key's
+// value, resolving references in it in turn, so shared settings such as
+// base URLs are kept in one key:
+//
+//	"${shared/endpoints/llm_base_url}/v1/chat"
+//
+// A string that is exactly one reference takes the referenced value with
+// its type, so "${app/llm/max_tokens}" stays a number; otherwise values are
+// spliced in as text, JSON-encoded if they are not strings. Reference cycles
+// fail with ErrValidation and missing keys with ErrNotFound, naming the
+// chain of references that led there.
+func (c *LLMConfigClient) ResolveReferences(ctx context.Context, namespace string, value interface{}, opts ResolveOptions) (interface{}, error) {
+	r := &referenceResolver{client: c, opts: opts, values: map[string]interface{}{}}
+	return r.resolve(ctx, namespace, value)
+}
+
+// referenceResolver resolves one value, reading each referenced key once
+type referenceResolver struct {
+	client *LLMConfigClient
+	opts   ResolveOptions
+	values map[string]interface{}
+	// chain is the keys being resolved, outermost first
+	chain []string
+}
+
+// resolveKey resolves the value of ref, a "namespace/key"
+func (r *referenceResolver) resolveKey(ctx context.Context, ref string, value interface{}) (interface{}, error) {
+	if slices.Contains(r.chain, ref) {
+		return nil, fmt.Errorf("reference cycle %s -> %s: %w", strings.Join(r.chain, " -> "), ref, ErrValidation)
+	}
+	if len(r.chain) >= maxReferenceDepth {
+		return nil, fmt.Errorf("references nested deeper than %d at %s: %w", maxReferenceDepth, strings.Join(r.chain, " -> "), ErrValidation)
+	}
+	r.chain = append(r.chain, ref)
+	defer func() { r.chain = r.chain[:len(r.chain)-1] }()
+
+	namespace := ref[:strings.LastIndex(ref, "/")]
+	resolved, err := r.resolve(ctx, namespace, value)
+	if err != nil {
+		return nil, err
+	}
+	r.values[ref] = resolved
+	return resolved, nil
+}
+
+func (r *referenceResolver) resolve(ctx context.Context, namespace string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return r.resolveString(ctx, namespace, v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			resolved, err := r.resolve(ctx, namespace, item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := r.resolve(ctx, namespace, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+func (r *referenceResolver) resolveString(ctx context.Context, namespace, s string) (interface{}, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for rest := s; ; {
+		i := strings.Index(rest, "${")
+		if i < 0 {
+			b.WriteString(rest)
+			break
+		}
+		if i > 0 && rest[i-1] == '$' {
+			// $${ is an escaped, literal ${
+			b.WriteString(rest[:i-1] + "${")
+			rest = rest[i+2:]
+			continue
+		}
+		end := strings.IndexByte(rest[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated reference in %q: %w", s, ErrValidation)
+		}
+		ref := qualifyReference(namespace, rest[i+2:i+end])
+		value, err := r.lookup(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && end == len(rest)-1 && b.Len() == 0 {
+			// The whole string is one reference: keep the value's type
+			return value, nil
+		}
+		b.WriteString(rest[:i] + formatCLIValue(value))
+		rest = rest[i+end+1:]
+	}
+	return b.String(), nil
+}
+
+// lookup reads and resolves the referenced key
+func (r *referenceResolver) lookup(ctx context.Context, ref string) (interface{}, error) {
+	if value, ok := r.values[ref]; ok {
+		return value, nil
+	}
+	slash := strings.LastIndex(ref, "/")
+	if slash <= 0 || slash == len(ref)-1 || strings.ContainsAny(ref, " \t\n") {
+		return nil, fmt.Errorf("invalid reference ${%s}: %w", ref, ErrValidation)
+	}
+	namespace, key := ref[:slash], ref[slash+1:]
+
+	cfg, err := r.client.GetConfigContext(ctx, namespace, key, r.opts.Environment, true)
+	if err != nil {
+		return nil, fmt.Errorf("resolve ${%s} in %s: %w", ref, strings.Join(r.chain, " -> "), err)
+	}
+	value := cfg.Value
+	if value == encryptedPlaceholder {
+		if !r.opts.RevealSecrets {
+			return nil, fmt.Errorf("resolve ${%s} in %s: the key is secret; set RevealSecrets: %w", ref, strings.Join(r.chain, " -> "), ErrValidation)
+		}
+		secret, err := r.client.RevealSecret(ctx, namespace, key, r.opts.Environment, r.opts.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("reveal ${%s}: %w", ref, err)
+		}
+		value = secret.Value
+	}
+	return r.resolveKey(ctx, ref, value)
+}
+
+// parseReferences returns the references in s as written
+func parseReferences(s string) []string {
+	var refs []string
+	for rest := s; ; {
+		i := strings.Index(rest, "${")
+		if i < 0 {
+			return refs
+		}
+		end := strings.IndexByte(rest[i:], '}')
+		if end < 0 {
+			return refs
+		}
+		if i == 0 || rest[i-1] != '$' {
+			refs = append(refs, rest[i+2:i+end])
+		}
+		rest = rest[i+end+1:]
+	}
+}
+
+// qualifyReference puts a namespace-less ${key} into namespace
+func qualifyReference(namespace, ref string) string {
+	if !strings.Contains(ref, "/") {
+		return namespace + "/" + ref
+	}
+	return ref
+}
+
+func walkReferences(value interface{}, visit func(string)) {
+	switch v := value.(type) {
+	case string:
+		visit(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			walkReferences(item, visit)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkReferences(item, visit)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encryptedPlaceholder is what the API returns in place of secret values
+const encryptedPlaceholder = "<encrypted>"
+
+// Annotations recorded on generated Kubernetes objects
+const (
+	annotationSourceNamespace = "llm-config-manager.io/source-namespace"
+	annotationSourceEnv       = "llm-config-manager.io/source-environment"
+	annotationSourceVersion   = "llm-config-manager.io/source-version"
+	annotationKeyVersions     = "llm-config-manager.io/key-versions"
+)
+
+// K8sManifestOptions controls ConfigMap/Secret generation
+type K8sManifestOptions struct {
+	// Name of the generated objects; defaults to the namespace with "/"
+	// replaced by "-"
+	Name string
+	// KubeNamespace sets metadata.namespace when non-empty
+	KubeNamespace string
+	// Keys restricts output to the listed config keys (all keys when empty)
+	Keys []string
+	// SecretKeys are written to the Secret instead of the ConfigMap
+	SecretKeys []string
+	// SkipMaskedSecrets omits secrets the API returned masked instead of failing
+	SkipMaskedSecrets bool
+	Labels            map[string]string
+}
+
+type k8sObjectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type k8sConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sObjectMeta     `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type k8sSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sObjectMeta     `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+// GenerateK8sManifests renders a namespace into ConfigMap (and, if any secret
+// keys are selected, Secret) YAML documents
+func (c *LLMConfigClient) GenerateK8sManifests(ctx context.Context, namespace, env string, opts K8sManifestOptions) ([]byte, error) {
+	configs, err := c.ListConfigsContext(ctx, namespace, env)
+	if err != nil {
+		return nil, err
+	}
+	return RenderK8sManifests(namespace, env, configs, opts)
+}
+
+// RenderK8sManifests builds ConfigMap/Secret YAML from already-fetched configs.
+// Each object is annotated with the source namespace, environment, highest
+// version, and per-key versions so drift can be traced back to the API.
+func RenderK8sManifests(namespace, env string, configs []ConfigResponse, opts K8sManifestOptions) ([]byte, error) {
+	objects, err := renderK8sData(namespace, env, configs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	cm := k8sConfigMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   objects.ConfigMeta,
+		Data:       objects.ConfigData,
+	}
+	if err := enc.Encode(cm); err != nil {
+		return nil, fmt.Errorf("encode configmap: %w", err)
+	}
+
+	if len(objects.SecretData) > 0 {
+		secretData := make(map[string]string, len(objects.SecretData))
+		for key, value := range objects.SecretData {
+			secretData[key] = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+		secret := k8sSecret{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   objects.SecretMeta,
+			Type:       "Opaque",
+			Data:       secretData,
+		}
+		if err := enc.Encode(secret); err != nil {
+			return nil, fmt.Errorf("encode secret: %w", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// k8sObjects is a namespace split into ConfigMap and Secret data
+type k8sObjects struct {
+	ConfigMeta k8sObjectMeta
+	ConfigData map[string]string
+	SecretMeta k8sObjectMeta
+	// SecretData holds plaintext values; empty when no key is secret
+	SecretData map[string]string
+}
+
+// renderK8sData selects and renders the values of configs for
+// RenderK8sManifests and the ConfigMap sync. Two keys with the same data
+// key, such as a/b and a_b, are an error rather than one overwriting the
+// other.
+func renderK8sData(namespace, env string, configs []ConfigResponse, opts K8sManifestOptions) (*k8sObjects, error) {
+	selected := make(map[string]bool, len(opts.Keys))
+	for _, key := range opts.Keys {
+		selected[key] = true
+	}
+	secrets := make(map[string]bool, len(opts.SecretKeys))
+	for _, key := range opts.SecretKeys {
+		secrets[key] = true
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = k8sName(namespace)
+	}
+
+	configData := map[string]string{}
+	secretData := map[string]string{}
+	configVersions := map[string]int64{}
+	secretVersions := map[string]int64{}
+	// dataKeys maps each data key used to its config key
+	dataKeys := map[string]string{}
+
+	for _, cfg := range configs {
+		if len(selected) > 0 && !selected[cfg.Key] {
+			continue
+		}
+
+		value, err := k8sDataValue(cfg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("render %s: %w", cfg.Key, err)
+		}
+
+		if value == encryptedPlaceholder {
+			if opts.SkipMaskedSecrets {
+				continue
+			}
+			return nil, fmt.Errorf("render %s: secret value is masked by the API", cfg.Key)
+		}
+
+		dataKey := k8sDataKey(cfg.Key)
+		if other, ok := dataKeys[dataKey]; ok {
+			return nil, fmt.Errorf("render: keys %s and %s are both data key %s", other, cfg.Key, dataKey)
+		}
+		dataKeys[dataKey] = cfg.Key
+		if secrets[cfg.Key] {
+			secretData[dataKey] = value
+			secretVersions[cfg.Key] = cfg.Version
+			continue
+		}
+
+		configData[dataKey] = value
+		configVersions[cfg.Key] = cfg.Version
+	}
+
+	return &k8sObjects{
+		ConfigMeta: k8sMeta(name, namespace, env, configVersions, opts),
+		ConfigData: configData,
+		SecretMeta: k8sMeta(name, namespace, env, secretVersions, opts),
+		SecretData: secretData,
+	}, nil
+}
+
+func k8sMeta(name, namespace, env string, versions map[string]int64, opts K8sManifestOptions) k8sObjectMeta {
+	var maxVersion int64
+	for _, v := range versions {
+		if v > maxVersion {
+			maxVersion = v
+		}
+	}
+	keyVersions, _ := json.Marshal(versions)
+
+	return k8sObjectMeta{
+		Name:      name,
+		Namespace: opts.KubeNamespace,
+		Labels:    opts.Labels,
+		Annotations: map[string]string{
+			annotationSourceNamespace: namespace,
+			annotationSourceEnv:       env,
+			annotationSourceVersion:   strconv.FormatInt(maxVersion, 10),
+			annotationKeyVersions:     string(keyVersions),
+		},
+	}
+}
+
+// k8sDataValue renders a config value as a ConfigMap string; non-string
+// values are JSON-encoded
+func k8sDataValue(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(normalizeExportValue(v))
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// k8sName converts a namespace path into a DNS-1123 object name
+func k8sName(namespace string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(namespace) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	name := strings.Trim(b.String(), "-.")
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return name
+}
+
+// k8sDataKey restricts a config key to the characters allowed in data keys
+func k8sDataKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// K8sImportOptions controls ImportK8sManifests
+type K8sImportOptions struct {
+	Env  string
+	User string
+	// DryRun parses and maps entries without writing them
+	DryRun bool
+}
+
+// k8sDataObject is the part of a ConfigMap or Secret an import reads
+type k8sDataObject struct {
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sObjectMeta     `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+// ParseK8sManifests reads the ConfigMap and Secret documents in r, e.g.
+// the output of RenderK8sManifests or kubectl get -o yaml; other kinds are
+// ignored. ConfigMap values go through InferValue, so JSON written for
+// non-string values round-trips. Secret values are base64-decoded and kept as
+// strings. Entries are sorted by key. sourceNamespace is the source-namespace
+// annotation the objects agree on, or "" when they carry none.
+func ParseK8sManifests(r io.Reader) (sourceNamespace string, entries []ImportEntry, err error) {
+	dec := yaml.NewDecoder(r)
+	seen := map[string]string{}
+	for {
+		var obj k8sDataObject
+		err := dec.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("parse manifests: %w", err)
+		}
+		if obj.Kind != "ConfigMap" && obj.Kind != "Secret" {
+			continue
+		}
+
+		object := obj.Kind + "/" + obj.Metadata.Name
+		if ns := obj.Metadata.Annotations[annotationSourceNamespace]; ns != "" {
+			if sourceNamespace != "" && ns != sourceNamespace {
+				return "", nil, fmt.Errorf("%s comes from %s, not %s", object, ns, sourceNamespace)
+			}
+			sourceNamespace = ns
+		}
+
+		add := func(key string, value interface{}, secret bool) error {
+			if other, dup := seen[key]; dup {
+				return fmt.Errorf("key %s is in both %s and %s", key, other, object)
+			}
+			seen[key] = object
+			entries = append(entries, ImportEntry{Key: key, Value: value, Secret: secret})
+			return nil
+		}
+
+		if obj.Kind == "ConfigMap" {
+			for key, value := range obj.Data {
+				if err := add(key, InferValue(value), false); err != nil {
+					return "", nil, err
+				}
+			}
+			continue
+		}
+		for key, encoded := range obj.Data {
+			value, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: key %s is not valid base64", object, key)
+			}
+			if err := add(key, string(value), true); err != nil {
+				return "", nil, err
+			}
+		}
+		for key, value := range obj.StringData {
+			if err := add(key, value, true); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return sourceNamespace, entries, nil
+}
+
+// ImportK8sManifests writes the ConfigMap and Secret data in r into
+// namespace, storing Secret data as secrets. An empty namespace uses the
+// source-namespace annotation left by RenderK8sManifests. With DryRun set
+// nothing is written.
+func (c *LLMConfigClient) ImportK8sManifests(ctx context.Context, namespace string, r io.Reader, opts K8sImportOptions) ([]ImportEntry, error) {
+	sourceNamespace, entries, err := ParseK8sManifests(r)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = sourceNamespace
+	}
+	if namespace == "" {
+		return nil, errors.New("import manifests: no namespace given and no " + annotationSourceNamespace + " annotation")
+	}
+	if opts.DryRun {
+		return entries, nil
+	}
+
+	for _, entry := range entries {
+		if _, err := c.SetConfigContext(ctx, namespace, entry.Key, entry.Value, opts.Env, opts.User, entry.Secret); err != nil {
+			return nil, fmt.Errorf("import %s/%s: %w", namespace, entry.Key, err)
+		}
+	}
+	return entries, nil
+}
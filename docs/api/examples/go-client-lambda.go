@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lambdaExtensionAPI is the version prefix of the Lambda Extensions API
+const lambdaExtensionAPI = "/2020-01-01/extension"
+
+// defaultLambdaAddr is where the extension serves snapshots when
+// LambdaExtensionOptions.Addr is empty; LambdaConfig reads it from
+// $LLM_CONFIG_LAMBDA_ADDR with the same default
+const defaultLambdaAddr = "localhost:2772"
+
+// LambdaExtensionOptions configures RunLambdaExtension
+type LambdaExtensionOptions struct {
+	// Name must match the extension's file name in /opt/extensions
+	Name        string
+	Namespaces  []string
+	Environment string
+	// Addr the snapshots are served on; localhost:2772 when empty
+	Addr string
+	// MaxAge after which an invocation triggers a background refresh; 5m
+	// when zero
+	MaxAge time.Duration
+	// RevealSecrets includes the plaintext of secrets in the snapshots,
+	// decrypting client-side encrypted ones with SecretKey. Otherwise
+	// secrets the API masks are left out.
+	RevealSecrets bool
+	SecretKey     SecretKey
+}
+
+// lambdaSnapshots holds the namespaces the extension serves
+type lambdaSnapshots struct {
+	mu         sync.RWMutex
+	values     map[string]map[string]interface{}
+	fetchedAt  time.Time
+	refreshing atomic.Bool
+}
+
+// RunLambdaExtension runs as an AWS Lambda extension: it registers with the
+// Extensions API, fetches opts.Namespaces during the init phase, and serves
+// them to the function over HTTP on opts.Addr:
+//
+//	GET /v1/namespaces/{namespace}        -> {"key": value, ...}
+//	GET /v1/namespaces/{namespace}/{key}  -> value
+//
+// Invocations never wait on the config server: once the snapshots are
+// older than MaxAge, the next invocation refreshes them in the background
+// and the old ones are served until that succeeds. It returns when Lambda
+// sends SHUTDOWN.
+func (c *LLMConfigClient) RunLambdaExtension(ctx context.Context, opts LambdaExtensionOptions) error {
+	runtimeAPI := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if runtimeAPI == "" {
+		return errors.New("lambda: AWS_LAMBDA_RUNTIME_API is not set; run this as a Lambda extension")
+	}
+	if opts.Addr == "" {
+		opts.Addr = defaultLambdaAddr
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 5 * time.Minute
+	}
+
+	id, err := lambdaRegister(ctx, runtimeAPI, opts.Name)
+	if err != nil {
+		return err
+	}
+
+	snapshots := &lambdaSnapshots{}
+	if err := c.refreshLambdaSnapshots(ctx, snapshots, opts); err != nil {
+		lambdaInitError(ctx, runtimeAPI, id, err)
+		return err
+	}
+	listener, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		lambdaInitError(ctx, runtimeAPI, id, err)
+		return err
+	}
+	srv := &http.Server{Handler: snapshots.handler(), ReadHeaderTimeout: 5 * time.Second}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	for {
+		event, err := lambdaNextEvent(ctx, runtimeAPI, id)
+		if err != nil {
+			return err
+		}
+		switch event.EventType {
+		case "SHUTDOWN":
+			return nil
+		case "INVOKE":
+			snapshots.mu.RLock()
+			stale := c.clock.Now().Sub(snapshots.fetchedAt) > opts.MaxAge
+			snapshots.mu.RUnlock()
+			if stale && snapshots.refreshing.CompareAndSwap(false, true) {
+				go func() {
+					defer snapshots.refreshing.Store(false)
+					if err := c.refreshLambdaSnapshots(ctx, snapshots, opts); err != nil {
+						log.Printf("llm-config lambda: refresh: %v; serving the previous snapshots", err)
+					}
+				}()
+			}
+		}
+	}
+}
+
+// refreshLambdaSnapshots fetches every namespace and swaps them in together
+func (c *LLMConfigClient) refreshLambdaSnapshots(ctx context.Context, snapshots *lambdaSnapshots, opts LambdaExtensionOptions) error {
+	values := make(map[string]map[string]interface{}, len(opts.Namespaces))
+	for _, namespace := range opts.Namespaces {
+		nsValues, err := c.agentValues(ctx, namespace, AgentOptions{
+			Environment:   opts.Environment,
+			RevealSecrets: opts.RevealSecrets,
+			SecretKey:     opts.SecretKey,
+		})
+		if err != nil {
+			return err
+		}
+		values[namespace] = nsValues
+	}
+	snapshots.mu.Lock()
+	snapshots.values, snapshots.fetchedAt = values, c.clock.Now()
+	snapshots.mu.Unlock()
+	return nil
+}
+
+func (s *lambdaSnapshots) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/namespaces/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		path := r.PathValue("path")
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if values, ok := s.values[path]; ok {
+			writeJSON(w, values)
+			return
+		}
+		// Otherwise the last segment is a key
+		for namespace, values := range s.values {
+			key, ok := cutPathPrefix(path, namespace)
+			if !ok {
+				continue
+			}
+			if value, ok := values[key]; ok {
+				writeJSON(w, value)
+				return
+			}
+		}
+		http.Error(w, fmt.Sprintf("%s is not in the extension's snapshots", path), http.StatusNotFound)
+	})
+	return mux
+}
+
+// cutPathPrefix returns the key when path is namespace/key
+func cutPathPrefix(path, namespace string) (string, bool) {
+	if len(path) <= len(namespace)+1 || path[:len(namespace)] != namespace || path[len(namespace)] != '/' {
+		return "", false
+	}
+	return path[len(namespace)+1:], true
+}
+
+// lambdaEvent is the part of an Extensions API event the extension reads
+type lambdaEvent struct {
+	EventType string `json:"eventType"`
+}
+
+// lambdaRegister registers for INVOKE and SHUTDOWN events and returns the
+// extension identifier
+func lambdaRegister(ctx context.Context, runtimeAPI, name string) (string, error) {
+	body, _ := json.Marshal(map[string][]string{"events": {"INVOKE", "SHUTDOWN"}})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+runtimeAPI+lambdaExtensionAPI+"/register", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Lambda-Extension-Name", name)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("lambda: register: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("lambda: register: %s", resp.Status)
+	}
+	return resp.Header.Get("Lambda-Extension-Identifier"), nil
+}
+
+// lambdaNextEvent blocks until Lambda sends the next event
+func lambdaNextEvent(ctx context.Context, runtimeAPI, id string) (*lambdaEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+runtimeAPI+lambdaExtensionAPI+"/event/next", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Lambda-Extension-Identifier", id)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lambda: next event: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lambda: next event: %s", resp.Status)
+	}
+	var event lambdaEvent
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		return nil, fmt.Errorf("lambda: next event: %w", err)
+	}
+	return &event, nil
+}
+
+// lambdaInitError reports a failed init, so the function fails to start
+// rather than running without its config
+func lambdaInitError(ctx context.Context, runtimeAPI, id string, cause error) {
+	body, _ := json.Marshal(map[string]string{"errorMessage": cause.Error(), "errorType": "Extension.ConfigUnavailable"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+runtimeAPI+lambdaExtensionAPI+"/init/error", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Lambda-Extension-Identifier", id)
+	req.Header.Set("Lambda-Extension-Function-Error-Type", "Extension.ConfigUnavailable")
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// LambdaConfig reads configs inside a Lambda function from the llmconfig
+// extension, which answers from memory
+type LambdaConfig struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewLambdaConfig returns a reader for the extension at
+// $LLM_CONFIG_LAMBDA_ADDR, localhost:2772 by default
+func NewLambdaConfig() *LambdaConfig {
+	return &LambdaConfig{
+		baseURL:    "http://" + envOrDefault("LLM_CONFIG_LAMBDA_ADDR", defaultLambdaAddr) + "/v1/namespaces/",
+		httpClient: &http.Client{Timeout: time.Second},
+	}
+}
+
+// Namespace returns a namespace's key -> value map
+func (l *LambdaConfig) Namespace(ctx context.Context, namespace string) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if err := l.get(ctx, namespace, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Get returns one value; a key missing from the snapshots matches
+// ErrNotFound
+func (l *LambdaConfig) Get(ctx context.Context, namespace, key string) (interface{}, error) {
+	var value interface{}
+	if err := l.get(ctx, namespace+"/"+url.PathEscape(key), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (l *LambdaConfig) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("llmconfig extension: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(v)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	return fmt.Errorf("llmconfig extension: %s", resp.Status)
+}
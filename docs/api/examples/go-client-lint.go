@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// LintSeverity ranks lint findings
+type LintSeverity string
+
+const (
+	LintInfo    LintSeverity = "info"
+	LintWarning LintSeverity = "warning"
+	LintError   LintSeverity = "error"
+)
+
+var lintSeverityRank = map[LintSeverity]int{LintInfo: 0, LintWarning: 1, LintError: 2}
+
+// LintFinding is one rule violation
+type LintFinding struct {
+	Rule        string       `json:"rule"`
+	Severity    LintSeverity `json:"severity"`
+	Namespace   string       `json:"namespace"`
+	Environment string       `json:"environment"`
+	Key         string       `json:"key,omitempty"`
+	Message     string       `json:"message"`
+}
+
+// KeyUsage is the server's read statistics for a key
+type KeyUsage struct {
+	Key        string `json:"key"`
+	Reads      int64  `json:"reads"`
+	LastReadAt string `json:"last_read_at"`
+}
+
+// LintTarget is what a rule inspects: one namespace/environment snapshot
+type LintTarget struct {
+	Namespace   string
+	Environment string
+	Configs     []ConfigResponse
+	// Usage is keyed by config key; nil when the server has no usage data
+	Usage map[string]KeyUsage
+	Now   time.Time
+}
+
+// LintRule is a pluggable lint check
+type LintRule interface {
+	Name() string
+	Check(target *LintTarget) []LintFinding
+}
+
+// LintOptions controls Lint
+type LintOptions struct {
+	// Rules to run; DefaultLintRules() when nil
+	Rules []LintRule
+}
+
+// LintReport collects findings across namespaces
+type LintReport struct {
+	Findings []LintFinding `json:"findings"`
+}
+
+// DefaultLintRules returns the built-in rule set
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		NamingConventionRule{},
+		OrphanedKeyRule{},
+		MissingDescriptionRule{},
+		UntaggedSecretRule{},
+	}
+}
+
+// Lint runs rules against each namespace in env
+func (c *LLMConfigClient) Lint(ctx context.Context, namespaces []string, env string, opts LintOptions) (*LintReport, error) {
+	rules := opts.Rules
+	if rules == nil {
+		rules = DefaultLintRules()
+	}
+
+	report := &LintReport{}
+	for _, namespace := range namespaces {
+		configs, err := c.ListConfigsContext(ctx, namespace, env)
+		if err != nil {
+			return nil, fmt.Errorf("lint %s: %w", namespace, err)
+		}
+		usage, err := c.GetKeyUsage(ctx, namespace, env)
+		if err != nil {
+			return nil, fmt.Errorf("lint %s: %w", namespace, err)
+		}
+
+		target := &LintTarget{
+			Namespace:   namespace,
+			Environment: env,
+			Configs:     configs,
+			Usage:       usage,
+			Now:         c.clock.Now(),
+		}
+		for _, rule := range rules {
+			for _, finding := range rule.Check(target) {
+				finding.Rule = rule.Name()
+				finding.Namespace = namespace
+				finding.Environment = env
+				report.Findings = append(report.Findings, finding)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Rule < b.Rule
+	})
+	return report, nil
+}
+
+// GetKeyUsage returns per-key read statistics, or nil if the server doesn't
+// track usage for the namespace
+func (c *LLMConfigClient) GetKeyUsage(ctx context.Context, namespace, env string) (map[string]KeyUsage, error) {
+	var result []KeyUsage
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("env", env).
+		SetResult(&result).
+		Get(namespaceRoute(namespace, "usage"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return nil, nil
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	usage := make(map[string]KeyUsage, len(result))
+	for _, u := range result {
+		usage[u.Key] = u
+	}
+	return usage, nil
+}
+
+// Failed reports whether any finding is at or above severity
+func (r *LintReport) Failed(severity LintSeverity) bool {
+	for _, f := range r.Findings {
+		if lintSeverityRank[f.Severity] >= lintSeverityRank[severity] {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes the report as indented JSON for CI tooling
+func (r *LintReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the report as an aligned table
+func (r *LintReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "SEVERITY\tRULE\tNAMESPACE\tKEY\tMESSAGE\n")
+	for _, f := range r.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Severity, f.Rule, f.Namespace, f.Key, f.Message)
+	}
+	return tw.Flush()
+}
+
+// NamingConventionRule flags keys not matching Pattern (snake_case by default)
+type NamingConventionRule struct {
+	Pattern *regexp.Regexp
+}
+
+var snakeCasePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+func (r NamingConventionRule) Name() string { return "naming-convention" }
+
+func (r NamingConventionRule) Check(target *LintTarget) []LintFinding {
+	pattern := r.Pattern
+	if pattern == nil {
+		pattern = snakeCasePattern
+	}
+	var findings []LintFinding
+	for _, cfg := range target.Configs {
+		if !pattern.MatchString(cfg.Key) {
+			findings = append(findings, LintFinding{
+				Severity: LintWarning,
+				Key:      cfg.Key,
+				Message:  fmt.Sprintf("key does not match naming convention %s", pattern),
+			})
+		}
+	}
+	return findings
+}
+
+// OrphanedKeyRule flags keys not read within MaxIdle (30 days by default).
+// It is skipped when the server has no usage data.
+type OrphanedKeyRule struct {
+	MaxIdle time.Duration
+}
+
+func (r OrphanedKeyRule) Name() string { return "orphaned-key" }
+
+func (r OrphanedKeyRule) Check(target *LintTarget) []LintFinding {
+	if target.Usage == nil {
+		return nil
+	}
+	maxIdle := r.MaxIdle
+	if maxIdle == 0 {
+		maxIdle = 30 * 24 * time.Hour
+	}
+
+	var findings []LintFinding
+	for _, cfg := range target.Configs {
+		usage, ok := target.Usage[cfg.Key]
+		lastRead, err := time.Parse(time.RFC3339, usage.LastReadAt)
+		if ok && err == nil && target.Now.Sub(lastRead) <= maxIdle {
+			continue
+		}
+		msg := "key has never been read"
+		if ok && err == nil {
+			msg = fmt.Sprintf("key not read in %d days", int(target.Now.Sub(lastRead).Hours()/24))
+		}
+		findings = append(findings, LintFinding{Severity: LintWarning, Key: cfg.Key, Message: msg})
+	}
+	return findings
+}
+
+// MissingDescriptionRule flags keys without a metadata description
+type MissingDescriptionRule struct{}
+
+func (MissingDescriptionRule) Name() string { return "missing-description" }
+
+func (MissingDescriptionRule) Check(target *LintTarget) []LintFinding {
+	var findings []LintFinding
+	for _, cfg := range target.Configs {
+		if cfg.Metadata.Description == nil || strings.TrimSpace(*cfg.Metadata.Description) == "" {
+			findings = append(findings, LintFinding{Severity: LintInfo, Key: cfg.Key, Message: "key has no description"})
+		}
+	}
+	return findings
+}
+
+// UntaggedSecretRule flags secrets missing Tag ("secret" by default), and
+// credential-looking keys stored in plaintext
+type UntaggedSecretRule struct {
+	Tag string
+}
+
+func (r UntaggedSecretRule) Name() string { return "untagged-secret" }
+
+func (r UntaggedSecretRule) Check(target *LintTarget) []LintFinding {
+	tag := r.Tag
+	if tag == "" {
+		tag = "secret"
+	}
+
+	var findings []LintFinding
+	for _, cfg := range target.Configs {
+		masked := cfg.Value == encryptedPlaceholder
+		switch {
+		case masked && !hasTag(cfg.Metadata.Tags, tag):
+			findings = append(findings, LintFinding{
+				Severity: LintWarning,
+				Key:      cfg.Key,
+				Message:  fmt.Sprintf("secret is missing the %q tag", tag),
+			})
+		case !masked && secretKeyPattern.MatchString(cfg.Key):
+			findings = append(findings, LintFinding{
+				Severity: LintError,
+				Key:      cfg.Key,
+				Message:  "key looks like a credential but is stored as a plaintext value",
+			})
+		}
+	}
+	return findings
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// runLintCommand implements `go run . lint`. It exits non-zero when any
+// finding reaches the -fail-on severity, so it can gate CI pipelines.
+func runLintCommand(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	namespaces := fs.String("namespaces", "", "comma-separated namespaces to lint")
+	env := fs.String("env", "production", "environment to lint")
+	outputFormat := fs.String("format", "text", "output format: text or json")
+	failOn := fs.String("fail-on", string(LintError), "lowest severity that fails the run: info, warning, or error")
+	maxIdle := fs.Duration("max-idle", 30*24*time.Hour, "read age after which keys count as orphaned")
+	baseURL := fs.String("url", envOrDefault("LLM_CONFIG_URL", "http://localhost:8080/api/v1"), "API base URL")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *namespaces == "" {
+		return fmt.Errorf("lint: -namespaces is required")
+	}
+	if _, ok := lintSeverityRank[LintSeverity(*failOn)]; !ok {
+		return fmt.Errorf("lint: unknown severity %q", *failOn)
+	}
+
+	rules := []LintRule{
+		NamingConventionRule{},
+		OrphanedKeyRule{MaxIdle: *maxIdle},
+		MissingDescriptionRule{},
+		UntaggedSecretRule{},
+	}
+
+	client := NewLLMConfigClient(*baseURL, os.Getenv("LLM_CONFIG_TOKEN"))
+	report, err := client.Lint(context.Background(), strings.Split(*namespaces, ","), *env, LintOptions{Rules: rules})
+	if err != nil {
+		return err
+	}
+
+	if *outputFormat == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.Failed(LintSeverity(*failOn)) {
+		return fmt.Errorf("lint: findings at or above %s severity", *failOn)
+	}
+	return nil
+}
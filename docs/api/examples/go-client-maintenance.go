@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Headers the server sends on every response while in maintenance
+const (
+	readOnlyHeader          = "X-LLM-Config-Read-Only"
+	maintenanceUntilHeader  = "X-LLM-Config-Maintenance-Until"
+	maintenanceReasonHeader = "X-LLM-Config-Maintenance-Reason"
+)
+
+// errorCodeReadOnly is the code of writes refused during maintenance
+const errorCodeReadOnly = "read_only"
+
+// queuedWritePoll is how often queued writes are retried when the server
+// does not say when maintenance ends
+const queuedWritePoll = 30 * time.Second
+
+// ErrReadOnly means the server is in maintenance and refuses writes; use
+// errors.As with *ReadOnlyError for when it ends
+var ErrReadOnly = errors.New("server is read-only")
+
+// MaintenanceStatus is the server's maintenance state as last seen by the
+// client, from response headers or a health check
+type MaintenanceStatus struct {
+	ReadOnly bool
+	// Until is when the server expects to accept writes again; zero if it
+	// did not say
+	Until      time.Time
+	Reason     string
+	ObservedAt time.Time
+}
+
+// ReadOnlyError is a write refused during maintenance
+type ReadOnlyError struct {
+	Until  time.Time
+	Reason string
+	Err    *ConfigClientError
+}
+
+func (e *ReadOnlyError) Error() string {
+	msg := "server is read-only for maintenance"
+	if e.Reason != "" {
+		msg += " (" + e.Reason + ")"
+	}
+	if !e.Until.IsZero() {
+		msg += " until " + e.Until.Format(time.RFC3339)
+	}
+	return msg
+}
+
+// Is reports ReadOnlyError as ErrReadOnly
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// Unwrap returns the underlying HTTP error
+func (e *ReadOnlyError) Unwrap() error {
+	return e.Err
+}
+
+// maintenanceState tracks the server's mode and the writes waiting it out
+type maintenanceState struct {
+	// status is from the latest response's headers, health from the latest
+	// health check body; a server may report maintenance in either
+	status atomic.Pointer[MaintenanceStatus]
+	health atomic.Pointer[MaintenanceStatus]
+
+	// order is held from checking the queue until a write is made or
+	// queued, so a SetConfigOrQueue write never overtakes a queued one
+	order    sync.Mutex
+	mu       sync.Mutex
+	queue    []*DeferredWrite
+	draining bool
+}
+
+// MaintenanceStatus returns the maintenance state seen on the latest
+// response and health check. The server is read-only if either says so.
+func (c *LLMConfigClient) MaintenanceStatus() MaintenanceStatus {
+	var headers, health MaintenanceStatus
+	if status := c.maintenance.status.Load(); status != nil {
+		headers = *status
+	}
+	if status := c.maintenance.health.Load(); status != nil {
+		health = *status
+	}
+	return mergeMaintenance(headers, health)
+}
+
+// mergeMaintenance combines two observations: read-only if either is,
+// with the later end and the newer reason
+func mergeMaintenance(a, b MaintenanceStatus) MaintenanceStatus {
+	if b.ObservedAt.After(a.ObservedAt) {
+		a, b = b, a
+	}
+	switch {
+	case !b.ReadOnly:
+		return a
+	case !a.ReadOnly:
+		return b
+	}
+	merged := a
+	if b.Until.After(merged.Until) {
+		merged.Until = b.Until
+	}
+	if merged.Reason == "" {
+		merged.Reason = b.Reason
+	}
+	return merged
+}
+
+// installMaintenance records the maintenance headers of every response
+func (c *LLMConfigClient) installMaintenance() {
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		status := maintenanceFromHeaders(resp.Header())
+		status.ObservedAt = c.clock.Now()
+		c.maintenance.status.Store(&status)
+		return nil
+	})
+}
+
+func maintenanceFromHeaders(h http.Header) MaintenanceStatus {
+	readOnly, _ := strconv.ParseBool(h.Get(readOnlyHeader))
+	status := MaintenanceStatus{ReadOnly: readOnly, Reason: h.Get(maintenanceReasonHeader)}
+	if until, err := time.Parse(time.RFC3339, h.Get(maintenanceUntilHeader)); err == nil {
+		status.Until = until
+	}
+	return status
+}
+
+// readOnlyResponse reports whether resp refused a write for maintenance;
+// such responses are not retried
+func readOnlyResponse(resp *resty.Response) bool {
+	if resp.StatusCode() != http.StatusServiceUnavailable {
+		return false
+	}
+	readOnly, _ := strconv.ParseBool(resp.Header().Get(readOnlyHeader))
+	return readOnly
+}
+
+// readOnlyError turns a refused write into a ReadOnlyError
+func readOnlyError(resp *resty.Response, clientErr *ConfigClientError) error {
+	if !readOnlyResponse(resp) && !(clientErr.StatusCode == http.StatusServiceUnavailable && clientErr.Code == errorCodeReadOnly) {
+		return clientErr
+	}
+	status := maintenanceFromHeaders(resp.Header())
+	return &ReadOnlyError{Until: status.Until, Reason: status.Reason, Err: clientErr}
+}
+
+// DeferredWrite is a SetConfigOrQueue write, made at once or held until
+// maintenance ends
+type DeferredWrite struct {
+	Namespace   string
+	Key         string
+	Environment string
+	QueuedAt    time.Time
+
+	ctx    context.Context
+	req    SetConfigRequest
+	queued bool
+	done   chan struct{}
+	result *ConfigResponse
+	err    error
+}
+
+// Queued reports whether the write had to wait for maintenance to end
+func (d *DeferredWrite) Queued() bool {
+	return d.queued
+}
+
+// Wait returns the outcome of the write once it has been made, or ctx's
+// error if that comes first; the write stays queued either way
+func (d *DeferredWrite) Wait(ctx context.Context) (*ConfigResponse, error) {
+	select {
+	case <-d.done:
+		return d.result, d.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SetConfigOrQueue is SetConfigContext for writes that can wait: refused
+// for maintenance, the write is queued in memory and retried, in order with
+// other queued writes, once the server's maintenance window ends. Other
+// failures are returned by Wait at once. Queued writes are made with
+// context.WithoutCancel(ctx) and are lost if the process exits.
+func (c *LLMConfigClient) SetConfigOrQueue(ctx context.Context, namespace, key string, value interface{}, env, user string, secret bool) *DeferredWrite {
+	d := &DeferredWrite{
+		Namespace:   namespace,
+		Key:         key,
+		Environment: env,
+		ctx:         context.WithoutCancel(ctx),
+		req:         SetConfigRequest{Value: value, Env: env, User: user, Secret: secret},
+		done:        make(chan struct{}),
+	}
+
+	c.maintenance.order.Lock()
+	defer c.maintenance.order.Unlock()
+	c.maintenance.mu.Lock()
+	// Writes queued earlier go first, so this one cannot overtake them
+	waiting := len(c.maintenance.queue) > 0
+	c.maintenance.mu.Unlock()
+	if !waiting {
+		d.result, d.err = c.setConfig(ctx, namespace, key, d.req)
+		if !errors.Is(d.err, ErrReadOnly) {
+			close(d.done)
+			return d
+		}
+	}
+
+	d.queued, d.QueuedAt, d.result, d.err = true, c.clock.Now(), nil, nil
+	c.maintenance.mu.Lock()
+	defer c.maintenance.mu.Unlock()
+	c.maintenance.queue = append(c.maintenance.queue, d)
+	if !c.maintenance.draining {
+		c.maintenance.draining = true
+		go c.drainQueuedWrites()
+	}
+	log.Printf("llm-config: server is read-only; queued write of %s/%s (%s)", namespace, key, env)
+	return d
+}
+
+// QueuedWrites returns how many writes are waiting for maintenance to end
+func (c *LLMConfigClient) QueuedWrites() int {
+	c.maintenance.mu.Lock()
+	defer c.maintenance.mu.Unlock()
+	return len(c.maintenance.queue)
+}
+
+// drainQueuedWrites makes queued writes in order, waiting whenever the
+// server is still read-only, until the queue is empty
+func (c *LLMConfigClient) drainQueuedWrites() {
+	for {
+		c.maintenance.mu.Lock()
+		if len(c.maintenance.queue) == 0 {
+			c.maintenance.draining = false
+			c.maintenance.mu.Unlock()
+			return
+		}
+		d := c.maintenance.queue[0]
+		c.maintenance.mu.Unlock()
+
+		wait := queuedWritePoll
+		if until := c.MaintenanceStatus().Until; until.After(c.clock.Now()) {
+			wait = until.Sub(c.clock.Now())
+		}
+		c.clock.Sleep(context.Background(), wait)
+
+		result, err := c.setConfig(d.ctx, d.Namespace, d.Key, d.req)
+		if errors.Is(err, ErrReadOnly) {
+			continue
+		}
+		if err != nil {
+			log.Printf("llm-config: queued write of %s/%s (%s) failed: %v", d.Namespace, d.Key, d.Environment, err)
+		}
+
+		c.maintenance.mu.Lock()
+		c.maintenance.queue = c.maintenance.queue[1:]
+		c.maintenance.mu.Unlock()
+		d.result, d.err = result, err
+		close(d.done)
+	}
+}
+
+// recordHealthMaintenance takes the maintenance state from a health check
+// body, for servers that report it there rather than in headers. It holds
+// until the next health check, whatever other responses' headers say.
+func (c *LLMConfigClient) recordHealthMaintenance(h *HealthResponse) {
+	status := MaintenanceStatus{ReadOnly: h.ReadOnly, ObservedAt: c.clock.Now()}
+	if h.ReadOnly {
+		status.Reason = h.MaintenanceReason
+		if until, err := time.Parse(time.RFC3339, h.MaintenanceUntil); err == nil {
+			status.Until = until
+		}
+	}
+	c.maintenance.health.Store(&status)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RequiredKey declares a key an application needs and its expected type:
+// string, int, float, number, bool, array, object, secret, or any
+type RequiredKey struct {
+	Namespace string `yaml:"namespace" json:"namespace"`
+	Key       string `yaml:"key" json:"key"`
+	Type      string `yaml:"type" json:"type"`
+}
+
+// KeyManifest is the set of keys an application requires in an environment
+type KeyManifest struct {
+	Environment string        `yaml:"environment" json:"environment"`
+	Keys        []RequiredKey `yaml:"keys" json:"keys"`
+}
+
+// ManifestMismatch is a required key whose value has the wrong type
+type ManifestMismatch struct {
+	RequiredKey
+	Actual string
+}
+
+// ManifestError lists every missing and mistyped key found by VerifyManifest
+type ManifestError struct {
+	Environment string
+	Missing     []RequiredKey
+	Mistyped    []ManifestMismatch
+}
+
+func (e *ManifestError) Error() string {
+	var parts []string
+	for _, k := range e.Missing {
+		parts = append(parts, fmt.Sprintf("%s/%s: missing", k.Namespace, k.Key))
+	}
+	for _, m := range e.Mistyped {
+		parts = append(parts, fmt.Sprintf("%s/%s: expected %s, got %s", m.Namespace, m.Key, m.Type, m.Actual))
+	}
+	return fmt.Sprintf("config manifest check failed in %s (%d missing, %d mistyped): %s",
+		e.Environment, len(e.Missing), len(e.Mistyped), strings.Join(parts, "; "))
+}
+
+// LoadKeyManifest reads a YAML or JSON manifest file
+func LoadKeyManifest(path string) (*KeyManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m KeyManifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	for _, k := range m.Keys {
+		if _, ok := manifestTypeChecks[k.Type]; !ok {
+			return nil, fmt.Errorf("manifest %s: key %s/%s has unknown type %q", path, k.Namespace, k.Key, k.Type)
+		}
+	}
+	return &m, nil
+}
+
+// DeclareManifest sets the keys VerifyManifest checks
+func (c *LLMConfigClient) DeclareManifest(manifest KeyManifest) {
+	c.manifest = &manifest
+}
+
+// VerifyManifest fetches every namespace named in the declared manifest and
+// returns a *ManifestError listing all missing or mistyped keys at once, so a
+// service can fail fast at startup instead of panicking on first use
+func (c *LLMConfigClient) VerifyManifest(ctx context.Context) error {
+	if c.manifest == nil {
+		return fmt.Errorf("no manifest declared; call DeclareManifest first")
+	}
+	manifest := c.manifest
+
+	byNamespace := map[string][]RequiredKey{}
+	for _, k := range manifest.Keys {
+		byNamespace[k.Namespace] = append(byNamespace[k.Namespace], k)
+	}
+
+	result := &ManifestError{Environment: manifest.Environment}
+	for _, namespace := range sortedMapKeys(byNamespace) {
+		configs, err := c.ListConfigsContext(ctx, namespace, manifest.Environment)
+		if err != nil {
+			return fmt.Errorf("verify manifest: list %s: %w", namespace, err)
+		}
+		values := make(map[string]interface{}, len(configs))
+		for _, cfg := range configs {
+			values[cfg.Key] = cfg.Value
+		}
+
+		for _, required := range byNamespace[namespace] {
+			value, ok := values[required.Key]
+			if !ok {
+				result.Missing = append(result.Missing, required)
+				continue
+			}
+			check, known := manifestTypeChecks[required.Type]
+			if !known || !check(value) {
+				result.Mistyped = append(result.Mistyped, ManifestMismatch{RequiredKey: required, Actual: valueTypeName(value)})
+			}
+		}
+	}
+
+	if len(result.Missing) == 0 && len(result.Mistyped) == 0 {
+		return nil
+	}
+	sort.Slice(result.Missing, func(i, j int) bool {
+		return result.Missing[i].Namespace+"/"+result.Missing[i].Key < result.Missing[j].Namespace+"/"+result.Missing[j].Key
+	})
+	sort.Slice(result.Mistyped, func(i, j int) bool {
+		return result.Mistyped[i].Namespace+"/"+result.Mistyped[i].Key < result.Mistyped[j].Namespace+"/"+result.Mistyped[j].Key
+	})
+	return result
+}
+
+// manifestTypeChecks maps manifest type names to value predicates
+var manifestTypeChecks = map[string]func(interface{}) bool{
+	"any": func(interface{}) bool { return true },
+	"string": func(v interface{}) bool {
+		s, ok := v.(string)
+		return ok && s != encryptedPlaceholder
+	},
+	"secret": func(v interface{}) bool { return v == encryptedPlaceholder },
+	"bool": func(v interface{}) bool {
+		_, ok := v.(bool)
+		return ok
+	},
+	"number": func(v interface{}) bool {
+		_, ok := v.(float64)
+		return ok
+	},
+	"float": func(v interface{}) bool {
+		_, ok := v.(float64)
+		return ok
+	},
+	"int": func(v interface{}) bool {
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	},
+	"array": func(v interface{}) bool {
+		_, ok := v.([]interface{})
+		return ok
+	},
+	"object": func(v interface{}) bool {
+		_, ok := v.(map[string]interface{})
+		return ok
+	},
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClientMetrics is a prometheus.Collector for the client's request, retry,
+// cache, watcher, rate-limit, slow-request, fallback, and deprecated-read
+// statistics. Register
+// it with
+//
+//	prometheus.MustRegister(client.EnableMetrics())
+type ClientMetrics struct {
+	requestDuration    *prometheus.HistogramVec
+	errors             *prometheus.CounterVec
+	retries            prometheus.Counter
+	cacheLookups       *prometheus.CounterVec
+	watcherReconnects  prometheus.Counter
+	rateLimitRemaining prometheus.Gauge
+	slowRequests       *prometheus.CounterVec
+	fallbacks          *prometheus.CounterVec
+	deprecatedReads    *prometheus.CounterVec
+}
+
+// NewClientMetrics creates the metric set with the llm_config_client_ prefix
+func NewClientMetrics() *ClientMetrics {
+	return &ClientMetrics{
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "llm_config_client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of API requests, including failed ones.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "llm_config_client",
+			Name:      "errors_total",
+			Help:      "Failed API requests by HTTP status code, or \"transport\" when no response was received.",
+		}, []string{"code"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "llm_config_client",
+			Name:      "retries_total",
+			Help:      "Requests retried after a 429 or 5xx response.",
+		}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "llm_config_client",
+			Name:      "cache_lookups_total",
+			Help:      "Prompt cache and chunk cache lookups by result (hit or miss).",
+		}, []string{"result"}),
+		watcherReconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "llm_config_client",
+			Name:      "watcher_reconnects_total",
+			Help:      "Times a config watcher re-established its connection.",
+		}),
+		rateLimitRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "llm_config_client",
+			Name:      "rate_limit_remaining",
+			Help:      "Requests remaining in the current rate-limit window, from X-RateLimit-Remaining.",
+		}),
+		slowRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "llm_config_client",
+			Name:      "slow_requests_total",
+			Help:      "Request attempts slower than the configured slow-request threshold.",
+		}, []string{"method", "route"}),
+		fallbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "llm_config_client",
+			Name:      "fallbacks_total",
+			Help:      "Fallback chain runs by chain and the position of the model that answered (0 is the primary, \"none\" when all failed).",
+		}, []string{"chain", "position"}),
+		deprecatedReads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "llm_config_client",
+			Name:      "deprecated_reads_total",
+			Help:      "Reads of keys marked deprecated, by namespace and key.",
+		}, []string{"namespace", "key"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *ClientMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requestDuration.Describe(ch)
+	m.errors.Describe(ch)
+	m.retries.Describe(ch)
+	m.cacheLookups.Describe(ch)
+	m.watcherReconnects.Describe(ch)
+	m.rateLimitRemaining.Describe(ch)
+	m.slowRequests.Describe(ch)
+	m.fallbacks.Describe(ch)
+	m.deprecatedReads.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *ClientMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.requestDuration.Collect(ch)
+	m.errors.Collect(ch)
+	m.retries.Collect(ch)
+	m.cacheLookups.Collect(ch)
+	m.watcherReconnects.Collect(ch)
+	m.rateLimitRemaining.Collect(ch)
+	m.slowRequests.Collect(ch)
+	m.fallbacks.Collect(ch)
+	m.deprecatedReads.Collect(ch)
+}
+
+// EnableMetrics starts recording client metrics and returns the collector to
+// register; later calls return the same collector. Once enabled, low
+// rate-limit warnings are no longer logged; alert on
+// llm_config_client_rate_limit_remaining instead.
+func (c *LLMConfigClient) EnableMetrics() *ClientMetrics {
+	if metrics := c.metrics.Load(); metrics != nil {
+		return metrics
+	}
+	c.metrics.CompareAndSwap(nil, NewClientMetrics())
+	return c.metrics.Load()
+}
+
+// installMetrics records requests, errors, and retries into the collector
+// of EnableMetrics, once there is one
+func (c *LLMConfigClient) installMetrics() {
+	basePath := baseURLPath(c.baseURL)
+
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		metrics := c.metrics.Load()
+		if metrics == nil {
+			return nil
+		}
+		route, _ := configRouteAttributes(trimBasePath(resp.Request.RawRequest.URL.Path, basePath), nil)
+		metrics.requestDuration.WithLabelValues(resp.Request.Method, route).Observe(resp.Time().Seconds())
+		if resp.IsError() {
+			metrics.errors.WithLabelValues(strconv.Itoa(resp.StatusCode())).Inc()
+		}
+		if remaining := resp.Header().Get("X-RateLimit-Remaining"); remaining != "" {
+			if n, err := strconv.Atoi(remaining); err == nil {
+				metrics.rateLimitRemaining.Set(float64(n))
+			}
+		}
+		return nil
+	})
+
+	c.httpClient.OnError(func(_ *resty.Request, err error) {
+		var respErr *resty.ResponseError
+		if metrics := c.metrics.Load(); metrics != nil && !errors.As(err, &respErr) {
+			metrics.errors.WithLabelValues("transport").Inc()
+		}
+	})
+
+	c.httpClient.AddRetryHook(func(_ *resty.Response, _ error) {
+		if metrics := c.metrics.Load(); metrics != nil {
+			metrics.retries.Inc()
+		}
+	})
+}
+
+// observeCacheLookup records a prompt or chunk cache hit or miss
+func (m *ClientMetrics) observeCacheLookup(hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// observeSlowRequest records a request over the slow-request threshold
+func (m *ClientMetrics) observeSlowRequest(method, route string) {
+	if m == nil {
+		return
+	}
+	m.slowRequests.WithLabelValues(method, route).Inc()
+}
+
+// observeWatcherReconnect records a watcher reconnect
+func (m *ClientMetrics) observeWatcherReconnect() {
+	if m == nil {
+		return
+	}
+	m.watcherReconnects.Inc()
+}
+
+// observeFallback records which position of a fallback chain answered;
+// -1 means none did
+func (m *ClientMetrics) observeFallback(chain string, position int) {
+	if m == nil {
+		return
+	}
+	label := "none"
+	if position >= 0 {
+		label = strconv.Itoa(position)
+	}
+	m.fallbacks.WithLabelValues(chain, label).Inc()
+}
+
+// observeDeprecatedRead records a read of a deprecated key
+func (m *ClientMetrics) observeDeprecatedRead(namespace, key string) {
+	if m == nil {
+		return
+	}
+	m.deprecatedReads.WithLabelValues(namespace, key).Inc()
+}
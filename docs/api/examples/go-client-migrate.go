@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Migration actions reported in a plan
+const (
+	MigrationCreate    = "create"
+	MigrationUpdate    = "update"
+	MigrationUnchanged = "unchanged"
+)
+
+// MigrationStep is one key an external source maps onto
+type MigrationStep struct {
+	Source    string
+	Namespace string
+	Key       string
+	Value     interface{}
+	Secret    bool
+	Metadata  map[string]string
+	Action    string
+}
+
+// MigrationPlan lists what an import would change, for review before Apply
+type MigrationPlan struct {
+	Env   string
+	Steps []MigrationStep
+}
+
+// PlanMigration compares source steps against the server's current state and
+// fills in each step's action. Masked secrets can't be compared, so existing
+// secret keys are always planned as updates.
+func (c *LLMConfigClient) PlanMigration(ctx context.Context, env string, steps []MigrationStep) (*MigrationPlan, error) {
+	current := map[string]map[string]ConfigResponse{}
+
+	for i := range steps {
+		step := &steps[i]
+		existing, ok := current[step.Namespace]
+		if !ok {
+			configs, err := c.ListConfigsContext(ctx, step.Namespace, env)
+			if err != nil {
+				return nil, fmt.Errorf("list %s: %w", step.Namespace, err)
+			}
+			existing = make(map[string]ConfigResponse, len(configs))
+			for _, cfg := range configs {
+				existing[cfg.Key] = cfg
+			}
+			current[step.Namespace] = existing
+		}
+
+		cfg, found := existing[step.Key]
+		switch {
+		case !found:
+			step.Action = MigrationCreate
+		case !step.Secret && reflect.DeepEqual(normalizeExportValue(cfg.Value), normalizeExportValue(step.Value)):
+			step.Action = MigrationUnchanged
+		default:
+			step.Action = MigrationUpdate
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].Namespace != steps[j].Namespace {
+			return steps[i].Namespace < steps[j].Namespace
+		}
+		return steps[i].Key < steps[j].Key
+	})
+
+	return &MigrationPlan{Env: env, Steps: steps}, nil
+}
+
+// ApplyMigration writes every create/update step of plan. Step metadata is
+// preserved as "name=value" tags.
+func (c *LLMConfigClient) ApplyMigration(ctx context.Context, plan *MigrationPlan, user string) error {
+	for _, step := range plan.Steps {
+		if step.Action == MigrationUnchanged {
+			continue
+		}
+		req := SetConfigRequest{
+			Value:  step.Value,
+			Env:    plan.Env,
+			User:   user,
+			Secret: step.Secret,
+			Tags:   metadataTags(step.Metadata),
+		}
+		if _, err := c.setConfig(ctx, step.Namespace, step.Key, req); err != nil {
+			return fmt.Errorf("migrate %s -> %s/%s: %w", step.Source, step.Namespace, step.Key, err)
+		}
+	}
+	return nil
+}
+
+// Summary counts steps per action
+func (p *MigrationPlan) Summary() map[string]int {
+	counts := map[string]int{}
+	for _, step := range p.Steps {
+		counts[step.Action]++
+	}
+	return counts
+}
+
+// WriteTo prints the plan as a table with secret values masked
+func (p *MigrationPlan) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	tw := tabwriter.NewWriter(cw, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "ACTION\tNAMESPACE\tKEY\tVALUE\tSOURCE\n")
+	for _, step := range p.Steps {
+		value := fmt.Sprint(step.Value)
+		if step.Secret {
+			value = "(secret)"
+		} else if len(value) > 40 {
+			value = value[:37] + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", step.Action, step.Namespace, step.Key, value, step.Source)
+	}
+	if err := tw.Flush(); err != nil {
+		return cw.n, err
+	}
+
+	counts := p.Summary()
+	_, err := fmt.Fprintf(cw, "\nPlan: %d to create, %d to update, %d unchanged.\n",
+		counts[MigrationCreate], counts[MigrationUpdate], counts[MigrationUnchanged])
+	return cw.n, err
+}
+
+// metadataTags renders metadata as sorted "name=value" tags
+func metadataTags(metadata map[string]string) []string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	tags := make([]string, 0, len(metadata))
+	for name, value := range metadata {
+		tags = append(tags, name+"="+value)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// tagValue returns the value of a "name=value" tag
+func tagValue(tags []string, name string) (string, bool) {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, name+"=") {
+			return tag[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+// splitHierarchy maps a slash-separated path below root onto a namespace and
+// key: "/prod/app/llm/model" with root "/prod" becomes ("app/llm", "model")
+func splitHierarchy(path, root, sep string) (namespace, key string, ok bool) {
+	rel := strings.Trim(strings.TrimPrefix(path, root), sep)
+	i := strings.LastIndex(rel, sep)
+	if i <= 0 || i == len(rel)-1 {
+		return "", "", false
+	}
+	return strings.ReplaceAll(rel[:i], sep, "/"), rel[i+1:], true
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
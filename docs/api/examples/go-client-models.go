@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ModelStatus is where a catalog model is in its lifecycle
+type ModelStatus string
+
+const (
+	ModelActive     ModelStatus = "active"
+	ModelDeprecated ModelStatus = "deprecated"
+	ModelRetired    ModelStatus = "retired"
+)
+
+// CatalogModel is one model offered by a provider
+type CatalogModel struct {
+	ID              string `json:"id"`
+	Provider        string `json:"provider"`
+	DisplayName     string `json:"display_name,omitempty"`
+	ContextWindow   int    `json:"context_window"`
+	MaxOutputTokens int    `json:"max_output_tokens,omitempty"`
+	// Modalities lists accepted inputs: text, image, audio, embedding
+	Modalities []string    `json:"modalities"`
+	Status     ModelStatus `json:"status"`
+	// Aliases are other names the provider accepts for this model
+	Aliases      []string `json:"aliases,omitempty"`
+	DeprecatedAt string   `json:"deprecated_at,omitempty"`
+	RetiresAt    string   `json:"retires_at,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+}
+
+// ModelFilter narrows ListModels; zero fields match everything. Retired
+// models are left out unless IncludeRetired is set.
+type ModelFilter struct {
+	Provider       string
+	Modality       string
+	IncludeRetired bool
+}
+
+// ModelResolution is the outcome of checking a config's model reference
+// against the catalog
+type ModelResolution struct {
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Environment string `json:"environment"`
+	// Reference is the model name as written in the config
+	Reference string `json:"reference"`
+	// Model is the matching catalog entry, nil when there is none
+	Model *CatalogModel `json:"model,omitempty"`
+	// Problem is empty when the reference names an active model
+	Problem     string   `json:"problem,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// OK reports whether the reference names an active catalog model
+func (r *ModelResolution) OK() bool {
+	return r.Problem == ""
+}
+
+// ListModels returns catalog models matching filter, sorted by provider and ID
+func (c *LLMConfigClient) ListModels(ctx context.Context, filter ModelFilter) ([]CatalogModel, error) {
+	var result []CatalogModel
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if filter.Provider != "" {
+		req.SetQueryParam("provider", filter.Provider)
+	}
+	if filter.Modality != "" {
+		req.SetQueryParam("modality", filter.Modality)
+	}
+	if filter.IncludeRetired {
+		req.SetQueryParam("include_retired", "true")
+	}
+
+	resp, err := req.Get("/models")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Provider != result[j].Provider {
+			return result[i].Provider < result[j].Provider
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// GetModel fetches one model by ID or alias. An unknown model is reported as
+// an error matching ErrNotFound.
+func (c *LLMConfigClient) GetModel(ctx context.Context, id string) (*CatalogModel, error) {
+	var result CatalogModel
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/models/%s", url.PathEscape(id)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ResolveModelRef checks the model named by namespace/key against the
+// catalog. The value may be a bare model name or an object with a "model"
+// field (and optionally "provider", as in ChatModelParams). Typos come back
+// with close catalog names as suggestions; deprecated and retired models are
+// reported with their replacement.
+func (c *LLMConfigClient) ResolveModelRef(ctx context.Context, namespace, key, env string) (*ModelResolution, error) {
+	cfg, err := c.GetConfigContext(ctx, namespace, key, env, true)
+	if err != nil {
+		return nil, fmt.Errorf("read %s/%s: %w", namespace, key, err)
+	}
+
+	ref, provider := modelReference(cfg.Value)
+	if ref == "" {
+		return nil, fmt.Errorf("%s/%s: value of type %s does not name a model", namespace, key, valueTypeName(cfg.Value))
+	}
+
+	models, err := c.ListModels(ctx, ModelFilter{Provider: provider, IncludeRetired: true})
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := resolveModel(ref, models)
+	resolution.Namespace = namespace
+	resolution.Key = key
+	resolution.Environment = env
+	return resolution, nil
+}
+
+// ErrModelReference is matched by errors from CheckModelRefs
+var ErrModelReference = errors.New("invalid model reference")
+
+// CheckModelRefs resolves every listed key and returns an error matching
+// ErrModelReference that describes each one that is not OK, e.g. for a
+// startup check or CI gate
+func (c *LLMConfigClient) CheckModelRefs(ctx context.Context, env string, refs ...ConfigRef) ([]*ModelResolution, error) {
+	var resolutions []*ModelResolution
+	var problems []string
+	for _, ref := range refs {
+		resolution, err := c.ResolveModelRef(ctx, ref.Namespace, ref.Key, env)
+		if err != nil {
+			return nil, err
+		}
+		resolutions = append(resolutions, resolution)
+		if !resolution.OK() {
+			problems = append(problems, fmt.Sprintf("%s/%s: %s", ref.Namespace, ref.Key, resolution.Problem))
+		}
+	}
+	if len(problems) > 0 {
+		return resolutions, fmt.Errorf("%w: %s", ErrModelReference, strings.Join(problems, "; "))
+	}
+	return resolutions, nil
+}
+
+// ConfigRef names a config key
+type ConfigRef struct {
+	Namespace string
+	Key       string
+}
+
+// modelReference extracts the model name (and provider, if given) from a
+// config value
+func modelReference(v interface{}) (model, provider string) {
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val), ""
+	case map[string]interface{}:
+		model, _ = val["model"].(string)
+		provider, _ = val["provider"].(string)
+		return strings.TrimSpace(model), provider
+	}
+	return "", ""
+}
+
+// resolveModel matches ref against the catalog by ID or alias
+func resolveModel(ref string, models []CatalogModel) *ModelResolution {
+	resolution := &ModelResolution{Reference: ref}
+
+	for i := range models {
+		m := &models[i]
+		if !strings.EqualFold(m.ID, ref) && !slices.ContainsFunc(m.Aliases, func(alias string) bool { return strings.EqualFold(alias, ref) }) {
+			continue
+		}
+		resolution.Model = m
+		switch m.Status {
+		case ModelRetired:
+			resolution.Problem = "model is retired" + replacementHint(m)
+		case ModelDeprecated:
+			resolution.Problem = "model is deprecated"
+			if m.RetiresAt != "" {
+				resolution.Problem += " and retires " + m.RetiresAt
+			}
+			resolution.Problem += replacementHint(m)
+		}
+		if m.Replacement != "" && m.Status != ModelActive {
+			resolution.Suggestions = []string{m.Replacement}
+		}
+		return resolution
+	}
+
+	resolution.Suggestions = closestModels(ref, models, 3)
+	resolution.Problem = "unknown model"
+	if len(resolution.Suggestions) > 0 {
+		resolution.Problem += fmt.Sprintf(" (did you mean %s?)", strings.Join(resolution.Suggestions, ", "))
+	}
+	return resolution
+}
+
+func replacementHint(m *CatalogModel) string {
+	if m.Replacement == "" {
+		return ""
+	}
+	return "; use " + m.Replacement
+}
+
+// closestModels returns up to limit active model IDs within a small edit
+// distance of ref, nearest first
+func closestModels(ref string, models []CatalogModel, limit int) []string {
+	type candidate struct {
+		id       string
+		distance int
+	}
+	ref = strings.ToLower(ref)
+	maxDistance := len(ref)/4 + 1
+
+	var candidates []candidate
+	for _, m := range models {
+		if m.Status == ModelRetired {
+			continue
+		}
+		if d := editDistance(ref, strings.ToLower(m.ID)); d <= maxDistance {
+			candidates = append(candidates, candidate{m.ID, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
+	})
+
+	var ids []string
+	for _, c := range candidates {
+		if len(ids) == limit {
+			break
+		}
+		ids = append(ids, c.id)
+	}
+	return ids
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
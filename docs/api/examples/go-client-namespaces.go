@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// NamespaceQuota limits what a namespace may hold across environments; zero
+// means unlimited. Writes that would exceed it fail with an error matching
+// ErrQuotaExceeded.
+type NamespaceQuota struct {
+	MaxKeys int `json:"max_keys" yaml:"max_keys,omitempty"`
+	// MaxBytes bounds the total size of the current values, as JSON
+	MaxBytes int64 `json:"max_bytes" yaml:"max_bytes,omitempty"`
+}
+
+// NamespaceUsage is what a namespace holds now, counted like its quota
+type NamespaceUsage struct {
+	Keys  int   `json:"keys"`
+	Bytes int64 `json:"bytes"`
+}
+
+// NamespaceSpec is what CreateNamespace sets
+type NamespaceSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Owners may change the namespace's quota and delete it; see UserOwner
+	// and TeamOwner
+	Owners []string `json:"owners"`
+	// DefaultTags are added to every config written without tags
+	DefaultTags []string       `json:"default_tags,omitempty"`
+	Quota       NamespaceQuota `json:"quota"`
+}
+
+// NamespaceInfo is a namespace as the server reports it
+type NamespaceInfo struct {
+	NamespaceSpec
+	Usage     NamespaceUsage `json:"usage"`
+	CreatedAt string         `json:"created_at"`
+	CreatedBy string         `json:"created_by"`
+}
+
+type createNamespaceRequest struct {
+	NamespaceSpec
+	User string `json:"user"`
+}
+
+// CreateNamespace declares a namespace. On servers that require it, configs
+// can only be written to declared namespaces; writes elsewhere fail with an
+// error matching ErrNotFound. Creating one that exists matches ErrConflict.
+func (c *LLMConfigClient) CreateNamespace(ctx context.Context, spec NamespaceSpec, user string) (*NamespaceInfo, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("create namespace: a name is required: %w", ErrValidation)
+	}
+	if len(spec.Owners) == 0 {
+		return nil, fmt.Errorf("create namespace %s: at least one owner is required: %w", spec.Name, ErrValidation)
+	}
+
+	var result NamespaceInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(createNamespaceRequest{NamespaceSpec: spec, User: user}).
+		SetResult(&result).
+		Post("/namespaces")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetNamespace returns a namespace's owners, quota, and usage
+func (c *LLMConfigClient) GetNamespace(ctx context.Context, namespace string) (*NamespaceInfo, error) {
+	var result NamespaceInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/namespaces/%s", url.PathEscape(namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListNamespaceInfo is ListNamespaces with each namespace's owners, quota,
+// and usage
+func (c *LLMConfigClient) ListNamespaceInfo(ctx context.Context, prefix string) ([]NamespaceInfo, error) {
+	var result []NamespaceInfo
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("detail", "true").
+		SetResult(&result)
+	if prefix != "" {
+		req.SetQueryParam("prefix", prefix)
+	}
+
+	resp, err := req.Get("/namespaces")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// SetNamespaceQuota replaces a namespace's quota. Lowering it below the
+// current usage is allowed; only further growth is refused.
+func (c *LLMConfigClient) SetNamespaceQuota(ctx context.Context, namespace string, quota NamespaceQuota) (*NamespaceInfo, error) {
+	if quota.MaxKeys < 0 || quota.MaxBytes < 0 {
+		return nil, fmt.Errorf("quota for %s: limits cannot be negative: %w", namespace, ErrValidation)
+	}
+
+	var result NamespaceInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(quota).
+		SetResult(&result).
+		Put(fmt.Sprintf("/namespaces/%s/quota", url.PathEscape(namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// DeleteNamespaceOptions are the safety checks of DeleteNamespace
+type DeleteNamespaceOptions struct {
+	// Confirm must repeat the namespace's name, so a variable holding the
+	// wrong namespace cannot delete it
+	Confirm string
+	// Force deletes the namespace's configs in every environment, with
+	// their history. Otherwise only an empty namespace is deleted.
+	Force bool
+}
+
+// DeleteNamespace removes a namespace. Without opts.Force it refuses while
+// the namespace holds configs, and the server refuses too, with an error
+// matching ErrConflict, should configs be written in the meantime. A
+// namespace with child namespaces ("app/llm" under "app") is never deleted;
+// they must go first.
+func (c *LLMConfigClient) DeleteNamespace(ctx context.Context, namespace string, opts DeleteNamespaceOptions) error {
+	if opts.Confirm != namespace {
+		return fmt.Errorf("delete namespace %s: confirmation %q does not match: %w", namespace, opts.Confirm, ErrValidation)
+	}
+
+	if !opts.Force {
+		info, err := c.GetNamespace(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		if info.Usage.Keys > 0 {
+			return fmt.Errorf("delete namespace %s: it holds %d configs; delete them first or force", namespace, info.Usage.Keys)
+		}
+	}
+	children, err := c.ListNamespaces(ctx, namespace+"/")
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return fmt.Errorf("delete namespace %s: it has %d child namespaces, e.g. %s; delete them first", namespace, len(children), children[0])
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("force", fmt.Sprintf("%t", opts.Force)).
+		Delete(fmt.Sprintf("/namespaces/%s", url.PathEscape(namespace)))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// llmConfigGroupVersion is the API group of the LLMConfig CRD, installed
+// from deployment/kubernetes/operator/crd.yaml
+var llmConfigGroupVersion = schema.GroupVersion{Group: "llm-config-manager.io", Version: "v1alpha1"}
+
+// LLMConfigSpec declares the contents of one namespace/environment on the
+// config server, like an apply Manifest
+type LLMConfigSpec struct {
+	Namespace   string `json:"namespace"`
+	Environment string `json:"environment"`
+	// Prune deletes server keys the spec does not declare
+	Prune   bool                   `json:"prune,omitempty"`
+	Configs map[string]interface{} `json:"configs,omitempty"`
+	// Secrets take their values from Kubernetes Secrets in the LLMConfig's
+	// namespace. Existing secrets are read back unmasked to detect
+	// changes, so the operator's token needs permission to read secrets
+	// in the config namespace.
+	Secrets map[string]corev1.SecretKeySelector `json:"secrets,omitempty"`
+	// DriftOnly reports drift in the status without changing the server
+	DriftOnly bool `json:"driftOnly,omitempty"`
+	// Interval between drift checks; 5m when unset
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// LLMConfigDrift is a key where the server differs from the spec
+type LLMConfigDrift struct {
+	Key    string       `json:"key"`
+	Action ChangeAction `json:"action"`
+}
+
+// LLMConfigStatus is what the operator last saw on the server
+type LLMConfigStatus struct {
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	LastSyncTime       *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Drift lists the changes the server needs to match the spec. It is
+	// empty once they are applied; with driftOnly it stays until someone
+	// converges the server.
+	Drift      []LLMConfigDrift   `json:"drift,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// LLMConfig is a namespace/environment on the config server managed from
+// Kubernetes. Deleting one leaves its keys on the server; at most one
+// LLMConfig should target each namespace/environment.
+type LLMConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LLMConfigSpec   `json:"spec"`
+	Status LLMConfigStatus `json:"status,omitempty"`
+}
+
+// LLMConfigList is a list of LLMConfigs
+type LLMConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []LLMConfig `json:"items"`
+}
+
+// DeepCopyInto copies in into out. Config values are JSON values as
+// decoded from the API server.
+func (in *LLMConfig) DeepCopyInto(out *LLMConfig) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+
+	if in.Spec.Configs != nil {
+		out.Spec.Configs = make(map[string]interface{}, len(in.Spec.Configs))
+		for key, value := range in.Spec.Configs {
+			out.Spec.Configs[key] = runtime.DeepCopyJSONValue(value)
+		}
+	}
+	if in.Spec.Secrets != nil {
+		out.Spec.Secrets = make(map[string]corev1.SecretKeySelector, len(in.Spec.Secrets))
+		for key, ref := range in.Spec.Secrets {
+			out.Spec.Secrets[key] = *ref.DeepCopy()
+		}
+	}
+	if in.Spec.Interval != nil {
+		interval := *in.Spec.Interval
+		out.Spec.Interval = &interval
+	}
+
+	out.Status.LastSyncTime = in.Status.LastSyncTime.DeepCopy()
+	out.Status.Drift = append([]LLMConfigDrift(nil), in.Status.Drift...)
+	if in.Status.Conditions != nil {
+		out.Status.Conditions = make([]metav1.Condition, len(in.Status.Conditions))
+		for i := range in.Status.Conditions {
+			in.Status.Conditions[i].DeepCopyInto(&out.Status.Conditions[i])
+		}
+	}
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *LLMConfig) DeepCopyObject() runtime.Object {
+	out := &LLMConfig{}
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *LLMConfigList) DeepCopyObject() runtime.Object {
+	out := &LLMConfigList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]LLMConfig, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+	return out
+}
+
+// addLLMConfigTypes registers LLMConfig with a scheme
+func addLLMConfigTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(llmConfigGroupVersion, &LLMConfig{}, &LLMConfigList{})
+	metav1.AddToGroupVersion(scheme, llmConfigGroupVersion)
+	return nil
+}
+
+// defaultDriftInterval is how often an LLMConfig is re-checked when its
+// spec sets no interval
+const defaultDriftInterval = 5 * time.Minute
+
+// conditionSynced is the LLMConfig condition reporting whether the server
+// matches the spec
+const conditionSynced = "Synced"
+
+// LLMConfigReconciler converges the config server on LLMConfig resources
+// with PlanApply and Apply, and re-plans every interval to catch changes
+// made on the server directly
+type LLMConfigReconciler struct {
+	client.Client
+	Config *LLMConfigClient
+	// User is recorded as the author of the changes the operator makes
+	User     string
+	Recorder record.EventRecorder
+}
+
+// Reconcile plans the changes for one LLMConfig, applies them unless the
+// spec is driftOnly, and records the outcome in its status
+func (r *LLMConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var obj LLMConfig
+	if err := r.Get(ctx, req.NamespacedName, &obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	interval := defaultDriftInterval
+	if obj.Spec.Interval != nil && obj.Spec.Interval.Duration > 0 {
+		interval = obj.Spec.Interval.Duration
+	}
+
+	synced := metav1.Condition{Type: conditionSynced, ObservedGeneration: obj.Generation}
+	plan, err := r.plan(ctx, &obj)
+	switch {
+	case err != nil:
+	case !plan.HasChanges():
+		synced.Status, synced.Reason, synced.Message = metav1.ConditionTrue, "InSync", "the server matches the spec"
+	case obj.Spec.DriftOnly:
+		synced.Status, synced.Reason = metav1.ConditionFalse, "Drifted"
+		synced.Message = fmt.Sprintf("%d key(s) differ from the spec", countChanges(plan))
+	default:
+		if err = r.Config.Apply(ctx, plan, r.User); err == nil {
+			r.Recorder.Eventf(&obj, corev1.EventTypeNormal, "Applied", "applied %d change(s) to %s (%s)",
+				countChanges(plan), obj.Spec.Namespace, obj.Spec.Environment)
+			synced.Status, synced.Reason = metav1.ConditionTrue, "Applied"
+			synced.Message = fmt.Sprintf("applied %d change(s)", countChanges(plan))
+			plan = &ApplyPlan{}
+		}
+	}
+
+	if err != nil {
+		r.Recorder.Event(&obj, corev1.EventTypeWarning, "SyncFailed", err.Error())
+		synced.Status, synced.Reason, synced.Message = metav1.ConditionFalse, "Error", err.Error()
+	} else {
+		now := metav1.Now()
+		obj.Status.LastSyncTime = &now
+		obj.Status.Drift = nil
+		for _, change := range plan.Changes {
+			if change.Action != ChangeUnchanged {
+				obj.Status.Drift = append(obj.Status.Drift, LLMConfigDrift{Key: change.Key, Action: change.Action})
+			}
+		}
+	}
+	obj.Status.ObservedGeneration = obj.Generation
+	meta.SetStatusCondition(&obj.Status.Conditions, synced)
+	if updateErr := r.Status().Update(ctx, &obj); updateErr != nil && err == nil {
+		err = updateErr
+	}
+
+	// Errors are retried with the controller's backoff; otherwise check for
+	// drift again after the interval
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// plan resolves obj's secret references and diffs its spec against the
+// server
+func (r *LLMConfigReconciler) plan(ctx context.Context, obj *LLMConfig) (*ApplyPlan, error) {
+	manifest := Manifest{
+		Namespace:     obj.Spec.Namespace,
+		Environment:   obj.Spec.Environment,
+		Prune:         obj.Spec.Prune,
+		Configs:       obj.Spec.Configs,
+		Secrets:       map[string]string{},
+		SecretValues:  map[string]string{},
+		RevealSecrets: true,
+		Source:        fmt.Sprintf("LLMConfig %s/%s", obj.Namespace, obj.Name),
+	}
+	for key, ref := range obj.Spec.Secrets {
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Namespace: obj.Namespace, Name: ref.Name}, &secret); err != nil {
+			return nil, fmt.Errorf("secret %s for key %s: %w", ref.Name, key, err)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf("secret %s has no key %s (for key %s)", ref.Name, ref.Key, key)
+		}
+		manifest.Secrets[key] = ref.Name + "/" + ref.Key
+		manifest.SecretValues[key] = string(value)
+	}
+	return r.Config.PlanApply(ctx, []Manifest{manifest})
+}
+
+// SetupWithManager runs the reconciler on spec changes and on changes to
+// the Secrets an LLMConfig references. Status updates don't bump the
+// generation, so they don't trigger a reconcile.
+func (r *LLMConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&LLMConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.requestsForSecret)).
+		Complete(r)
+}
+
+// requestsForSecret maps a Secret to the LLMConfigs in its namespace that
+// reference it
+func (r *LLMConfigReconciler) requestsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	var list LLMConfigList
+	if err := r.List(ctx, &list, client.InNamespace(secret.GetNamespace())); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, obj := range list.Items {
+		for _, ref := range obj.Spec.Secrets {
+			if ref.Name == secret.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&obj)})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// logrFromSlog routes controller-runtime's logging to the default slog
+// logger
+func logrFromSlog() logr.Logger {
+	return logr.FromSlogHandler(slog.Default().Handler())
+}
+
+// OperatorOptions configures RunOperator
+type OperatorOptions struct {
+	// User is recorded as the author of the operator's changes
+	User string
+	// WatchNamespace limits the operator to one Kubernetes namespace; all
+	// namespaces when empty
+	WatchNamespace string
+	MetricsAddr    string
+	ProbeAddr      string
+	LeaderElection bool
+}
+
+// RunOperator reconciles LLMConfig resources against c until ctx is done.
+// It uses the in-cluster config, or the current kubeconfig context when run
+// outside a cluster.
+func RunOperator(ctx context.Context, c *LLMConfigClient, opts OperatorOptions) error {
+	ctrl.SetLogger(logrFromSlog())
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := addLLMConfigTypes(scheme); err != nil {
+		return err
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("operator: %w", err)
+	}
+	mgrOpts := ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: opts.MetricsAddr},
+		HealthProbeBindAddress: opts.ProbeAddr,
+		LeaderElection:         opts.LeaderElection,
+		LeaderElectionID:       "llmconfig-operator.llm-config-manager.io",
+	}
+	if opts.WatchNamespace != "" {
+		mgrOpts.Cache = cache.Options{DefaultNamespaces: map[string]cache.Config{opts.WatchNamespace: {}}}
+	}
+	mgr, err := ctrl.NewManager(restConfig, mgrOpts)
+	if err != nil {
+		return fmt.Errorf("operator: %w", err)
+	}
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return err
+	}
+
+	reconciler := &LLMConfigReconciler{
+		Client:   mgr.GetClient(),
+		Config:   c,
+		User:     opts.User,
+		Recorder: mgr.GetEventRecorderFor("llmconfig-operator"),
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("operator: %w", err)
+	}
+	return mgr.Start(ctx)
+}
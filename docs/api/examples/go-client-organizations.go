@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Headers scoping a request to a tenant; the server echoes the organization
+// on responses
+const (
+	organizationHeader = "X-LLM-Config-Organization"
+	projectHeader      = "X-LLM-Config-Project"
+)
+
+// Organization is the tenant a request is made for. Namespaces, history,
+// and everything else are resolved inside it, so one deployment can hold
+// "app/llm" for many tenants.
+type Organization struct {
+	ID string
+	// Project narrows the scope within the organization; optional
+	Project string
+	// Token replaces the client's token for this tenant's requests, for
+	// deployments that issue tokens per organization; optional
+	Token string
+}
+
+type organizationKey struct{}
+
+// organizationScope is the client's default tenant and whether requests
+// may go without one
+type organizationScope struct {
+	defaultOrg atomic.Pointer[Organization]
+	required   atomic.Bool
+}
+
+// WithOrganization makes calls using ctx act for org, overriding the
+// client's default, so a single client can serve many tenants:
+//
+//	ctx := WithOrganization(r.Context(), Organization{ID: tenant.ID})
+//	cfg, err := client.GetConfigContext(ctx, "app/llm", "model", "production", false)
+//
+// Watches started with ctx keep polling as org.
+func WithOrganization(ctx context.Context, org Organization) context.Context {
+	return context.WithValue(ctx, organizationKey{}, org)
+}
+
+// OrganizationFromContext returns the organization set by WithOrganization
+func OrganizationFromContext(ctx context.Context) (Organization, bool) {
+	org, ok := ctx.Value(organizationKey{}).(Organization)
+	return org, ok && org.ID != ""
+}
+
+// SetOrganization scopes calls whose context carries no organization; an
+// empty Organization clears the default
+func (c *LLMConfigClient) SetOrganization(org Organization) {
+	c.organizations.defaultOrg.Store(&org)
+}
+
+// RequireOrganization makes calls without an organization, from the
+// context or SetOrganization, fail before anything is sent instead of
+// reaching the server unscoped. Multi-tenant services should turn it on so
+// a handler that forgot WithOrganization cannot read another tenant's data.
+func (c *LLMConfigClient) RequireOrganization(required bool) {
+	c.organizations.required.Store(required)
+}
+
+// organizationFor returns the organization calls using ctx act for
+func (c *LLMConfigClient) organizationFor(ctx context.Context) Organization {
+	if org, ok := OrganizationFromContext(ctx); ok {
+		return org
+	}
+	if org := c.organizations.defaultOrg.Load(); org != nil {
+		return *org
+	}
+	return Organization{}
+}
+
+// organizationScopeID identifies the tenant of ctx in client-side state:
+// "org/project", or empty for unscoped calls
+func (c *LLMConfigClient) organizationScopeID(ctx context.Context) string {
+	org := c.organizationFor(ctx)
+	if org.ID == "" {
+		return ""
+	}
+	return org.ID + "/" + org.Project
+}
+
+// organizationCacheKey prefixes a client-side cache key with the tenant,
+// so cached values never cross organizations
+func (c *LLMConfigClient) organizationCacheKey(ctx context.Context, key string) string {
+	scope := c.organizationScopeID(ctx)
+	if scope == "" {
+		return key
+	}
+	return scope + ":" + key
+}
+
+// installOrganizations scopes each request to its organization and
+// rejects responses the server attributes to a different one
+func (c *LLMConfigClient) installOrganizations() {
+	c.httpClient.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		org := c.organizationFor(req.Context())
+		if org.ID == "" {
+			if c.organizations.required.Load() {
+				return fmt.Errorf("%s %s: no organization; use WithOrganization or SetOrganization: %w", req.Method, req.URL, ErrValidation)
+			}
+			return nil
+		}
+		req.SetHeader(organizationHeader, org.ID)
+		if org.Project != "" {
+			req.SetHeader(projectHeader, org.Project)
+		}
+		if org.Token != "" {
+			req.SetAuthToken(org.Token)
+		}
+		return nil
+	})
+
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		sent := resp.Request.Header.Get(organizationHeader)
+		if got := resp.Header().Get(organizationHeader); sent != "" && got != "" && got != sent {
+			return fmt.Errorf("%s %s: response is for organization %q, not %q; discarded", resp.Request.Method, resp.Request.URL, got, sent)
+		}
+		return nil
+	})
+}
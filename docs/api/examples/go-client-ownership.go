@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Ownership headers: the server names a key's owners when it refuses (or,
+// in warn mode, accepts) a write by someone else, and reads the reason a
+// non-owner gives for overriding
+const (
+	ownersHeader        = "X-LLM-Config-Owners"
+	notOwnerHeader      = "X-LLM-Config-Not-Owner"
+	ownerOverrideHeader = "X-LLM-Config-Owner-Override"
+)
+
+// errorCodeNotOwner is the code sent with 403 when a write is refused
+// because the caller does not own the key
+const errorCodeNotOwner = "not_owner"
+
+// OwnershipEnforcement is what happens when a non-owner writes a key
+type OwnershipEnforcement string
+
+const (
+	// OwnershipOff records owners for information only
+	OwnershipOff OwnershipEnforcement = "off"
+	// OwnershipWarn accepts the write but flags it; the client logs it
+	OwnershipWarn OwnershipEnforcement = "warn"
+	// OwnershipEnforce refuses the write unless it carries an override
+	// reason (see WithOwnerOverride), which is recorded in the audit log
+	OwnershipEnforce OwnershipEnforcement = "enforce"
+)
+
+// OwnershipRule assigns owners to the keys matching Pattern, like a
+// CODEOWNERS line. Patterns are relative to the policy's namespace:
+// "model" is one key, "prompts/*" the keys of the prompts child namespace,
+// and "prompts/" everything under it.
+type OwnershipRule struct {
+	Pattern string `json:"pattern"`
+	// Owners are users and teams, see UserOwner and TeamOwner
+	Owners []string `json:"owners"`
+}
+
+// OwnershipPolicy holds a namespace's ownership rules. As in CODEOWNERS,
+// the last rule matching a key wins; keys no rule matches are owned by the
+// namespace's owners.
+type OwnershipPolicy struct {
+	Namespace   string               `json:"namespace"`
+	Enforcement OwnershipEnforcement `json:"enforcement"`
+	Rules       []OwnershipRule      `json:"rules"`
+	UpdatedBy   string               `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// RuleFor returns the rule owning key, a path relative to the policy's
+// namespace such as "prompts/system", or nil when no rule matches
+func (p *OwnershipPolicy) RuleFor(key string) *OwnershipRule {
+	for i := len(p.Rules) - 1; i >= 0; i-- {
+		if ownershipMatches(p.Rules[i].Pattern, key) {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+func ownershipMatches(pattern, key string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+		return strings.HasPrefix(key, dir+"/")
+	}
+	ok, _ := path.Match(pattern, key)
+	return ok
+}
+
+// KeyOwnership is who owns a key and why
+type KeyOwnership struct {
+	Namespace string   `json:"namespace"`
+	Key       string   `json:"key"`
+	Owners    []string `json:"owners"`
+	// Pattern is the rule that matched; empty when the owners are the
+	// namespace's own
+	Pattern string `json:"pattern,omitempty"`
+	// PolicyNamespace is the namespace whose policy applies, which may be
+	// an ancestor of Namespace
+	PolicyNamespace string               `json:"policy_namespace,omitempty"`
+	Enforcement     OwnershipEnforcement `json:"enforcement"`
+}
+
+// NotOwnerError is a write refused because the caller does not own the
+// key. It matches ErrUnauthorized; retry with WithOwnerOverride if the
+// change is agreed with Owners.
+type NotOwnerError struct {
+	Owners []string
+	Err    *ConfigClientError
+}
+
+func (e *NotOwnerError) Error() string {
+	return fmt.Sprintf("not an owner: the key is owned by %s; ask them or give an override reason", strings.Join(e.Owners, ", "))
+}
+
+// Unwrap returns the underlying HTTP error
+func (e *NotOwnerError) Unwrap() error {
+	return e.Err
+}
+
+// notOwnerError turns a write refused for ownership into a NotOwnerError,
+// returning nil for other errors
+func notOwnerError(resp *resty.Response, clientErr *ConfigClientError) error {
+	if clientErr.StatusCode != http.StatusForbidden || clientErr.Code != errorCodeNotOwner {
+		return nil
+	}
+	return &NotOwnerError{Owners: splitOwners(resp.Header().Get(ownersHeader)), Err: clientErr}
+}
+
+func splitOwners(header string) []string {
+	var owners []string
+	for _, owner := range strings.Split(header, ",") {
+		if owner = strings.TrimSpace(owner); owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+type ownerOverrideKey struct{}
+
+// WithOwnerOverride makes writes using ctx go through ownership
+// enforcement on keys the caller does not own. The reason, e.g. "agreed
+// with team:search in INC-1234", is recorded with the change.
+func WithOwnerOverride(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, ownerOverrideKey{}, reason)
+}
+
+// installOwnership sends override reasons and logs writes accepted in warn
+// mode from non-owners
+func (c *LLMConfigClient) installOwnership() {
+	c.httpClient.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if reason, ok := req.Context().Value(ownerOverrideKey{}).(string); ok && reason != "" {
+			req.SetHeader(ownerOverrideHeader, reason)
+		}
+		return nil
+	})
+
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		owners := resp.Header().Get(notOwnerHeader)
+		if owners == "" || resp.IsError() {
+			return nil
+		}
+		write := resp.Request.RawRequest.URL.Path
+		if _, key, ok := strings.Cut(write, "/configs/"); ok {
+			write = key
+		}
+		log.Printf("llm-config: wrote %s without owning it; owners: %s", write, strings.Join(splitOwners(owners), ", "))
+		return nil
+	})
+}
+
+// ParseOwnershipRules reads CODEOWNERS-style rules: one pattern per line
+// followed by its owners, with blank lines and # comments ignored.
+//
+//	# app/llm
+//	*            team:platform
+//	prompts/     team:prompting
+//	prompts/eval user:alice@example.com
+func ParseOwnershipRules(r io.Reader) ([]OwnershipRule, error) {
+	var rules []OwnershipRule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: %q has no owners: %w", line, fields[0], ErrValidation)
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("line %d: %q: %v: %w", line, fields[0], err, ErrValidation)
+		}
+		rules = append(rules, OwnershipRule{Pattern: fields[0], Owners: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+type setOwnershipRequest struct {
+	Enforcement OwnershipEnforcement `json:"enforcement"`
+	Rules       []OwnershipRule      `json:"rules"`
+	User        string               `json:"user"`
+}
+
+// SetOwnership replaces a namespace's ownership rules and enforcement mode.
+// Only the namespace's owners may change it. The policy also covers child
+// namespaces without a policy of their own.
+func (c *LLMConfigClient) SetOwnership(ctx context.Context, policy OwnershipPolicy, user string) (*OwnershipPolicy, error) {
+	switch policy.Enforcement {
+	case OwnershipOff, OwnershipWarn, OwnershipEnforce:
+	case "":
+		policy.Enforcement = OwnershipOff
+	default:
+		return nil, fmt.Errorf("ownership of %s: enforcement must be off, warn, or enforce, not %q: %w", policy.Namespace, policy.Enforcement, ErrValidation)
+	}
+	for _, rule := range policy.Rules {
+		if len(rule.Owners) == 0 {
+			return nil, fmt.Errorf("ownership of %s: rule %q has no owners: %w", policy.Namespace, rule.Pattern, ErrValidation)
+		}
+	}
+
+	var result OwnershipPolicy
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(setOwnershipRequest{Enforcement: policy.Enforcement, Rules: policy.Rules, User: user}).
+		SetResult(&result).
+		Put(fmt.Sprintf("/namespaces/%s/ownership", url.PathEscape(policy.Namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetOwnership returns a namespace's own ownership policy; one without
+// rules or enforcement is reported as ErrNotFound
+func (c *LLMConfigClient) GetOwnership(ctx context.Context, namespace string) (*OwnershipPolicy, error) {
+	var result OwnershipPolicy
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/namespaces/%s/ownership", url.PathEscape(namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// KeyOwners resolves who owns namespace/key: the last matching rule of the
+// nearest policy, or else the namespace's owners
+func (c *LLMConfigClient) KeyOwners(ctx context.Context, namespace, key string) (*KeyOwnership, error) {
+	var result KeyOwnership
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("key", key).
+		SetResult(&result).
+		Get(fmt.Sprintf("/namespaces/%s/ownership/owners", url.PathEscape(namespace)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
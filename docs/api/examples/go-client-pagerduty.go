@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pagerDutyEventsURL is PagerDuty's Events API v2
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+const (
+	defaultViolationThreshold = 3
+	defaultViolationWindow    = 10 * time.Minute
+)
+
+// PagerDutyAlerter opens PagerDuty incidents for unreviewed changes
+// (WatchDrift) and repeated policy-violating writes
+// (WatchPolicyViolations). Incidents are deduplicated per environment,
+// namespace, and key, so a flapping key adds alerts to one open incident
+// instead of paging again.
+type PagerDutyAlerter struct {
+	// RoutingKey is the integration key of the PagerDuty service
+	RoutingKey string
+	// Source names the reporting system in incidents; llm-config-manager
+	// when empty
+	Source string
+	// URL of the Events API; PagerDuty's when empty
+	URL string
+	// ViolationThreshold writes to one key rejected within ViolationWindow
+	// open an incident; 3 in 10 minutes when zero
+	ViolationThreshold int
+	ViolationWindow    time.Duration
+	// HTTPClient sends the events; http.DefaultClient when nil
+	HTTPClient *http.Client
+
+	mu sync.Mutex
+	// rejected holds the recent rejections of each key
+	rejected map[string][]time.Time
+}
+
+// pagerDutyEvent is the body of an Events API v2 request
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// PagerDutyDedupKey is the dedup key of incidents of class ("drift" or
+// "policy") about a key
+func PagerDutyDedupKey(class, env, namespace, key string) string {
+	return strings.Join([]string{"llm-config", class, env, namespace, key}, ":")
+}
+
+// WatchDrift watches namespaces and opens an incident for each change no
+// applied change request accounts for, i.e. one written directly rather
+// than through ProposeChange and review. Watch the environments reviews
+// guard, e.g. production:
+//
+//	alerter.WatchDrift(ctx, client, []string{"app/llm"}, WatchOptions{Environment: "production"})
+//
+// An incident is resolved when a reviewed change next sets its key. Failed
+// lookups and events are logged and skipped. It runs until ctx is done.
+func (a *PagerDutyAlerter) WatchDrift(ctx context.Context, client *LLMConfigClient, namespaces []string, opts WatchOptions) error {
+	return client.watchBatches(ctx, namespaces, opts, func(events []ConfigEvent) {
+		unreviewed, err := client.UnreviewedChanges(ctx, events)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("llm-config pagerduty: %v", err)
+			}
+			return
+		}
+		drifted := map[string]bool{}
+		for _, ev := range unreviewed {
+			drifted[ev.Key] = true
+			if err := a.triggerDrift(ctx, ev); err != nil && ctx.Err() == nil {
+				log.Printf("llm-config pagerduty: %v", err)
+			}
+		}
+		for _, ev := range events {
+			if drifted[ev.Key] {
+				continue
+			}
+			if err := a.resolveDrift(ctx, ev); err != nil && ctx.Err() == nil {
+				log.Printf("llm-config pagerduty: %v", err)
+			}
+		}
+	})
+}
+
+// UnreviewedChanges returns the events no applied change request
+// accounts for: sets whose version is not a request's AppliedVersion, and
+// deletes of versions no request proposed deleting
+func (c *LLMConfigClient) UnreviewedChanges(ctx context.Context, events []ConfigEvent) ([]ConfigEvent, error) {
+	applied := map[string][]ChangeProposal{}
+	var unreviewed []ConfigEvent
+	for _, ev := range events {
+		scope := ev.Namespace + "\x00" + ev.Environment
+		proposals, ok := applied[scope]
+		if !ok {
+			var err error
+			proposals, err = c.ListChanges(ctx, ChangeApplied, ListChangesOptions{Namespace: ev.Namespace, Environment: ev.Environment})
+			if err != nil {
+				return nil, fmt.Errorf("list applied changes to %s (%s): %w", ev.Namespace, ev.Environment, err)
+			}
+			applied[scope] = proposals
+		}
+		reviewed := false
+		for _, p := range proposals {
+			if p.Namespace != ev.Namespace || p.Key != ev.Key || p.Environment != ev.Environment {
+				continue
+			}
+			if ev.Type == ConfigDeleted {
+				reviewed = p.Delete && ev.Previous != nil && p.BaseVersion == ev.Previous.Version
+			} else {
+				reviewed = !p.Delete && p.AppliedVersion == ev.Config.Version
+			}
+			if reviewed {
+				break
+			}
+		}
+		if !reviewed {
+			unreviewed = append(unreviewed, ev)
+		}
+	}
+	return unreviewed, nil
+}
+
+// WatchPolicyViolations opens an incident when ViolationThreshold writes
+// to one key are rejected by client's policy within ViolationWindow
+func (a *PagerDutyAlerter) WatchPolicyViolations(client *LLMConfigClient) {
+	client.OnPolicyViolation(func(input PolicyInput, violations []PolicyViolation) {
+		threshold, window := a.ViolationThreshold, a.ViolationWindow
+		if threshold <= 0 {
+			threshold = defaultViolationThreshold
+		}
+		if window <= 0 {
+			window = defaultViolationWindow
+		}
+
+		dedupKey := PagerDutyDedupKey("policy", input.Environment, input.Namespace, input.Key)
+		now := client.clock.Now()
+		a.mu.Lock()
+		if a.rejected == nil {
+			a.rejected = map[string][]time.Time{}
+		}
+		recent := a.rejected[dedupKey][:0]
+		for _, at := range a.rejected[dedupKey] {
+			if now.Sub(at) < window {
+				recent = append(recent, at)
+			}
+		}
+		recent = append(recent, now)
+		count := len(recent)
+		if count >= threshold {
+			recent = nil
+		}
+		a.rejected[dedupKey] = recent
+		a.mu.Unlock()
+		if count < threshold {
+			return
+		}
+
+		rules := make([]string, len(violations))
+		for i, v := range violations {
+			rules[i] = v.Rule + ": " + v.Message
+		}
+		event := pagerDutyEvent{
+			EventAction: "trigger",
+			DedupKey:    dedupKey,
+			Payload: &pagerDutyPayload{
+				Summary:   fmt.Sprintf("%d writes to %s/%s (%s) rejected by policy in %s", count, input.Namespace, input.Key, input.Environment, window),
+				Severity:  "warning",
+				Component: input.Namespace,
+				Group:     input.Environment,
+				Class:     "policy",
+				CustomDetails: map[string]interface{}{
+					"key":        input.Key,
+					"operation":  input.Operation,
+					"user":       input.User,
+					"violations": rules,
+				},
+			},
+		}
+		// The write is failing now; page without holding it up
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := a.send(ctx, event); err != nil {
+				log.Printf("llm-config pagerduty: %v", err)
+			}
+		}()
+	})
+}
+
+// triggerDrift opens the incident of an unreviewed change
+func (a *PagerDutyAlerter) triggerDrift(ctx context.Context, ev ConfigEvent) error {
+	dedupKey := PagerDutyDedupKey("drift", ev.Environment, ev.Namespace, ev.Key)
+	details := map[string]interface{}{"key": ev.Key, "change": ev.Type}
+	if ev.Previous != nil {
+		details["previous_version"] = ev.Previous.Version
+	}
+	if ev.Config != nil {
+		details["version"] = ev.Config.Version
+		details["updated_by"] = ev.Config.Metadata.UpdatedBy
+		details["updated_at"] = ev.Config.Metadata.UpdatedAt
+	}
+	return a.send(ctx, pagerDutyEvent{
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload: &pagerDutyPayload{
+			Summary:       fmt.Sprintf("Unreviewed change: %s/%s (%s) %s", ev.Namespace, ev.Key, ev.Environment, ev.Type),
+			Severity:      "error",
+			Component:     ev.Namespace,
+			Group:         ev.Environment,
+			Class:         "drift",
+			CustomDetails: details,
+		},
+	})
+}
+
+// resolveDrift resolves the incident of a key a reviewed change set. The
+// resolve is sent whether or not this alerter triggered the incident, so
+// one opened before a restart is closed too; PagerDuty ignores resolves
+// of keys with no open incident.
+func (a *PagerDutyAlerter) resolveDrift(ctx context.Context, ev ConfigEvent) error {
+	dedupKey := PagerDutyDedupKey("drift", ev.Environment, ev.Namespace, ev.Key)
+	return a.send(ctx, pagerDutyEvent{EventAction: "resolve", DedupKey: dedupKey})
+}
+
+// send posts event to the Events API
+func (a *PagerDutyAlerter) send(ctx context.Context, event pagerDutyEvent) error {
+	event.RoutingKey = a.RoutingKey
+	if event.Payload != nil && event.Payload.Source == "" {
+		event.Payload.Source = a.Source
+		if event.Payload.Source == "" {
+			event.Payload.Source = "llm-config-manager"
+		}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	endpoint := a.URL
+	if endpoint == "" {
+		endpoint = pagerDutyEventsURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("%s %s: %s: %s", event.EventAction, event.DedupKey, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
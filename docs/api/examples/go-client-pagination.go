@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Paging headers of list responses; the body stays a plain array
+const (
+	totalCountHeader = "X-Total-Count"
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+// PageOptions selects one page of a list
+type PageOptions struct {
+	// Limit is the page size; the server's default when 0
+	Limit int
+	// Cursor continues after the page that returned it as NextCursor;
+	// empty for the first page
+	Cursor string
+}
+
+// Page is one page of a list. Cursors are opaque and positional: they
+// encode the last item returned rather than an offset, so items created
+// or deleted while paging neither shift later pages nor make items repeat.
+// An item deleted before its page is fetched is not returned, and one
+// created before the cursor is not seen until the next listing.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// Total is how many items the whole list holds as of this page; -1 if
+	// the server did not report it
+	Total int64 `json:"total"`
+	// NextCursor fetches the following page; empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// HasMore reports whether there are pages after this one
+func (p *Page[T]) HasMore() bool {
+	return p.NextCursor != ""
+}
+
+// fetchPage sends req for one page, adding opts, and reads the paging
+// headers off the response. The body is negotiated per SetBulkEncodings,
+// and may be an array or a v2 list object.
+func fetchPage[T any](c *LLMConfigClient, req *resty.Request, path string, opts PageOptions) (*Page[T], error) {
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("page limit %d is negative: %w", opts.Limit, ErrValidation)
+	}
+	if opts.Limit > 0 {
+		req.SetQueryParam("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		req.SetQueryParam("cursor", opts.Cursor)
+	}
+
+	page := &Page[T]{Total: -1}
+	_, configs := any(page).(*Page[ConfigResponse])
+	if accept := c.bulkAccept(configs); accept != "" {
+		req.SetHeader("Accept", accept)
+	}
+	resp, err := req.Get(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	if err := decodePage(resp, page); err != nil {
+		return nil, err
+	}
+	if total, err := strconv.ParseInt(resp.Header().Get(totalCountHeader), 10, 64); err == nil {
+		page.Total = total
+	}
+	if next := resp.Header().Get(nextCursorHeader); next != "" {
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
+// ForEachPage fetches pages in turn with fetch, starting at opts.Cursor,
+// and hands each page's items to each until the last page or an error.
+// Batch jobs can save the cursor passed to each to resume after a crash.
+//
+//	err := ForEachPage(ctx, PageOptions{Limit: 500},
+//		func(ctx context.Context, opts PageOptions) (*Page[ConfigResponse], error) {
+//			return client.ListConfigsPage(ctx, "app/llm", "production", opts)
+//		},
+//		func(items []ConfigResponse, next string) error { ... })
+func ForEachPage[T any](ctx context.Context, opts PageOptions, fetch func(context.Context, PageOptions) (*Page[T], error), each func(items []T, nextCursor string) error) error {
+	for {
+		page, err := fetch(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if err := each(page.Items, page.NextCursor); err != nil {
+			return err
+		}
+		if !page.HasMore() {
+			return nil
+		}
+		if page.NextCursor == opts.Cursor {
+			return fmt.Errorf("server returned cursor %q again", page.NextCursor)
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// collectPages fetches every page with fetch and concatenates the items
+func collectPages[T any](ctx context.Context, fetch func(context.Context, PageOptions) (*Page[T], error)) ([]T, error) {
+	var all []T
+	err := ForEachPage(ctx, PageOptions{}, fetch, func(items []T, _ string) error {
+		if all == nil {
+			all = make([]T, 0, len(items))
+		}
+		all = append(all, items...)
+		return nil
+	})
+	return all, err
+}
+
+// ListConfigsPage returns one page of a namespace's configs, sorted by key
+func (c *LLMConfigClient) ListConfigsPage(ctx context.Context, namespace, env string, opts PageOptions) (*Page[ConfigResponse], error) {
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("env", env)
+	page, err := fetchPage[ConfigResponse](c, req, fmt.Sprintf("/configs/%s", namespace), opts)
+	if err != nil {
+		return nil, err
+	}
+	c.consumption.record(env, page.Items...)
+	for _, cfg := range page.Items {
+		c.observeDeprecatedRead(namespace, cfg.Key, cfg.Deprecation)
+	}
+	return page, nil
+}
+
+// ListNamespacesPage returns one page of ListNamespaces
+func (c *LLMConfigClient) ListNamespacesPage(ctx context.Context, prefix string, opts PageOptions) (*Page[string], error) {
+	req := c.httpClient.R().
+		SetContext(ctx)
+	if prefix != "" {
+		req.SetQueryParam("prefix", prefix)
+	}
+	return fetchPage[string](c, req, "/namespaces", opts)
+}
+
+// ListByTagPage returns one page of ListByTag
+func (c *LLMConfigClient) ListByTagPage(ctx context.Context, tag string, filter TagListOptions, opts PageOptions) (*Page[ConfigResponse], error) {
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+	req := c.httpClient.R().
+		SetContext(ctx)
+	if filter.Namespace != "" {
+		req.SetQueryParam("namespace", filter.Namespace)
+	}
+	if filter.Environment != "" {
+		req.SetQueryParam("env", filter.Environment)
+	}
+	page, err := fetchPage[ConfigResponse](c, req, fmt.Sprintf("/tags/%s/configs", url.PathEscape(tag)), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range page.Items {
+		c.observeDeprecatedRead(cfg.Namespace, cfg.Key, cfg.Deprecation)
+	}
+	return page, nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParamBlock is a typed, self-validating config value such as
+// ChatModelParams
+type ParamBlock interface {
+	Validate() error
+}
+
+// ChatModelParams are the sampling settings for a chat/completion model
+type ChatModelParams struct {
+	Provider         string   `json:"provider"`
+	Model            string   `json:"model"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	MaxTokens        int      `json:"max_tokens,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+}
+
+// EmbeddingParams configure an embedding model
+type EmbeddingParams struct {
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions,omitempty"`
+	BatchSize  int    `json:"batch_size,omitempty"`
+}
+
+// SafetySettings configure content filtering around model calls
+type SafetySettings struct {
+	// BlockThreshold is none, low, medium, or high
+	BlockThreshold string   `json:"block_threshold"`
+	Categories     []string `json:"categories,omitempty"`
+	RedactPII      bool     `json:"redact_pii"`
+	MaxInputChars  int      `json:"max_input_chars,omitempty"`
+}
+
+// providerLimits are the documented sampling bounds for a provider
+type providerLimits struct {
+	maxTemperature float64
+	penalties      bool
+	maxStop        int
+}
+
+var chatProviderLimits = map[string]providerLimits{
+	"openai":    {maxTemperature: 2, penalties: true, maxStop: 4},
+	"azure":     {maxTemperature: 2, penalties: true, maxStop: 4},
+	"anthropic": {maxTemperature: 1, maxStop: 8191},
+	"google":    {maxTemperature: 2, penalties: true, maxStop: 5},
+	"mistral":   {maxTemperature: 1.5, penalties: true, maxStop: 4},
+}
+
+// chatModelMaxTokens is the output token ceiling by model name prefix;
+// the longest matching prefix wins
+var chatModelMaxTokens = map[string]int{
+	"gpt-4o":            16384,
+	"gpt-4-turbo":       4096,
+	"gpt-4":             8192,
+	"gpt-3.5-turbo":     4096,
+	"o1":                100000,
+	"claude-3-5-sonnet": 8192,
+	"claude-3-5-haiku":  8192,
+	"claude-3":          4096,
+	"gemini-1.5":        8192,
+	"gemini-2.0":        8192,
+}
+
+// embeddingModelDimensions is the native output size by model
+var embeddingModelDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+	"text-embedding-004":     768,
+}
+
+// Validate checks the parameters against the provider's and model's bounds
+func (p ChatModelParams) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	limits, known := chatProviderLimits[p.Provider]
+	if !known {
+		fail("/provider", "unknown provider %q", p.Provider)
+		limits = providerLimits{maxTemperature: 2, penalties: true}
+	}
+	if p.Model == "" {
+		fail("/model", "model is required")
+	}
+
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > limits.maxTemperature) {
+		fail("/temperature", "temperature %v out of range [0, %v] for %s", *p.Temperature, limits.maxTemperature, p.Provider)
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		fail("/top_p", "top_p %v out of range (0, 1]", *p.TopP)
+	}
+
+	if p.MaxTokens < 0 {
+		fail("/max_tokens", "max_tokens must be positive")
+	} else if ceiling := longestPrefixLimit(chatModelMaxTokens, p.Model); ceiling > 0 && p.MaxTokens > ceiling {
+		fail("/max_tokens", "max_tokens %d exceeds the %d output tokens %s supports", p.MaxTokens, ceiling, p.Model)
+	}
+
+	penalties := []struct {
+		name  string
+		value *float64
+	}{{"frequency_penalty", p.FrequencyPenalty}, {"presence_penalty", p.PresencePenalty}}
+	for _, penalty := range penalties {
+		switch {
+		case penalty.value == nil:
+		case !limits.penalties:
+			fail("/"+penalty.name, "%s does not support %s", p.Provider, penalty.name)
+		case *penalty.value < -2 || *penalty.value > 2:
+			fail("/"+penalty.name, "%s %v out of range [-2, 2]", penalty.name, *penalty.value)
+		}
+	}
+
+	if limits.maxStop > 0 && len(p.Stop) > limits.maxStop {
+		fail("/stop", "%s allows at most %d stop sequences, got %d", p.Provider, limits.maxStop, len(p.Stop))
+	}
+
+	return paramsError(problems)
+}
+
+// Validate checks the model's dimension and batch limits
+func (p EmbeddingParams) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if p.Model == "" {
+		fail("/model", "model is required")
+	}
+	native, known := embeddingModelDimensions[p.Model]
+	switch {
+	case p.Dimensions < 0:
+		fail("/dimensions", "dimensions must be positive")
+	case p.Dimensions > 0 && p.Model == "text-embedding-ada-002" && p.Dimensions != native:
+		fail("/dimensions", "%s does not support custom dimensions", p.Model)
+	case known && p.Dimensions > native:
+		fail("/dimensions", "%s produces at most %d dimensions, got %d", p.Model, native, p.Dimensions)
+	}
+
+	if p.BatchSize < 0 || (p.Provider == "openai" && p.BatchSize > 2048) {
+		fail("/batch_size", "batch_size %d out of range [1, 2048]", p.BatchSize)
+	}
+
+	return paramsError(problems)
+}
+
+// Validate checks the threshold and limits
+func (s SafetySettings) Validate() error {
+	var problems []ValidationProblem
+	switch s.BlockThreshold {
+	case "none", "low", "medium", "high":
+	default:
+		problems = append(problems, ValidationProblem{
+			Path:    "/block_threshold",
+			Message: fmt.Sprintf("block_threshold %q is not one of none, low, medium, high", s.BlockThreshold),
+		})
+	}
+	if s.MaxInputChars < 0 {
+		problems = append(problems, ValidationProblem{Path: "/max_input_chars", Message: "max_input_chars must be positive"})
+	}
+	return paramsError(problems)
+}
+
+func paramsError(problems []ValidationProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+func longestPrefixLimit(limits map[string]int, model string) int {
+	best, limit := "", 0
+	for prefix, l := range limits {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, limit = prefix, l
+		}
+	}
+	return limit
+}
+
+// GetParams decodes namespace/key into out and validates it
+func (c *LLMConfigClient) GetParams(ctx context.Context, namespace, key, env string, out ParamBlock) error {
+	cfg, err := c.GetConfigContext(ctx, namespace, key, env, true)
+	if err != nil {
+		return fmt.Errorf("read %s/%s: %w", namespace, key, err)
+	}
+
+	data, err := json.Marshal(cfg.Value)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode %s/%s: %w", namespace, key, err)
+	}
+	return withValidationTarget(out.Validate(), namespace, key)
+}
+
+// SetParams validates params and stores them as a JSON object value
+func (c *LLMConfigClient) SetParams(ctx context.Context, namespace, key, env, user string, params ParamBlock) (*ConfigResponse, error) {
+	if err := withValidationTarget(params.Validate(), namespace, key); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return c.setConfig(ctx, namespace, key, SetConfigRequest{Value: value, Env: env, User: user})
+}
+
+// withValidationTarget fills in the namespace/key of a *ValidationError
+func withValidationTarget(err error, namespace, key string) error {
+	if verr, ok := err.(*ValidationError); ok {
+		verr.Namespace, verr.Key = namespace, key
+	}
+	return err
+}
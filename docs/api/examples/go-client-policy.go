@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy operations
+const (
+	PolicyOperationSet    = "set"
+	PolicyOperationDelete = "delete"
+)
+
+// PolicyInput is the document a policy evaluates for each write
+type PolicyInput struct {
+	Operation   string `json:"operation"`
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Environment string `json:"environment"`
+	User        string `json:"user,omitempty"`
+	// Value is "<encrypted>" for secrets, whose values never leave the
+	// client for a policy engine or violation listener
+	Value  interface{} `json:"value,omitempty"`
+	Secret bool        `json:"secret"`
+	Tags   []string    `json:"tags,omitempty"`
+}
+
+// PolicyViolation names a rule that rejected a write
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyDecision is the outcome of evaluating a write
+type PolicyDecision struct {
+	Allow      bool
+	Violations []PolicyViolation
+}
+
+// PolicyEvaluator decides whether a write is allowed. OPAEvaluator talks to
+// an OPA server; an embedded engine can be plugged in by implementing this.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, input PolicyInput) (*PolicyDecision, error)
+}
+
+// PolicyViolationError is returned when a policy rejects a write
+type PolicyViolationError struct {
+	Namespace  string
+	Key        string
+	Violations []PolicyViolation
+}
+
+func (e *PolicyViolationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Rule + ": " + v.Message
+	}
+	return fmt.Sprintf("write to %s/%s rejected by policy: %s", e.Namespace, e.Key, strings.Join(msgs, "; "))
+}
+
+// SetPolicyEvaluator installs a policy hook consulted before every write and
+// delete. Evaluation errors fail closed: the write is not sent.
+func (c *LLMConfigClient) SetPolicyEvaluator(evaluator PolicyEvaluator) {
+	c.policy = evaluator
+}
+
+type policyListeners struct {
+	mu        sync.Mutex
+	listeners []func(PolicyInput, []PolicyViolation)
+}
+
+// OnPolicyViolation calls fn with each write the policy rejects, e.g. to
+// alert on repeated attempts. fn runs before the write fails and should
+// not block.
+func (c *LLMConfigClient) OnPolicyViolation(fn func(input PolicyInput, violations []PolicyViolation)) {
+	c.policyHooks.mu.Lock()
+	defer c.policyHooks.mu.Unlock()
+	c.policyHooks.listeners = append(c.policyHooks.listeners, fn)
+}
+
+// checkPolicy evaluates input against the installed policy, if any
+func (c *LLMConfigClient) checkPolicy(ctx context.Context, input PolicyInput) error {
+	if c.policy == nil {
+		return nil
+	}
+
+	decision, err := c.policy.Evaluate(ctx, input)
+	if err != nil {
+		return fmt.Errorf("policy evaluation for %s/%s: %w", input.Namespace, input.Key, err)
+	}
+	if decision.Allow && len(decision.Violations) == 0 {
+		return nil
+	}
+
+	violations := decision.Violations
+	if len(violations) == 0 {
+		violations = []PolicyViolation{{Rule: "default", Message: "write not allowed"}}
+	}
+	c.policyHooks.mu.Lock()
+	listeners := slices.Clone(c.policyHooks.listeners)
+	c.policyHooks.mu.Unlock()
+	for _, fn := range listeners {
+		fn(input, violations)
+	}
+	return &PolicyViolationError{Namespace: input.Namespace, Key: input.Key, Violations: violations}
+}
+
+// OPAEvaluator queries OPA's Data API (POST /v1/data/<Path>). The policy
+// document at Path must produce {"allow": bool, "deny": [...]}, where deny
+// entries are either strings or {"rule": ..., "msg": ...} objects, e.g.:
+//
+//	package llmconfig.write
+//
+//	default allow := false
+//	allow if count(deny) == 0
+//
+//	deny contains {"rule": "ticket-required", "msg": "production model changes need a ticket tag"} if {
+//		input.environment == "production"
+//		input.key == "model"
+//		not has_ticket
+//	}
+//
+//	has_ticket if startswith(input.tags[_], "ticket=")
+type OPAEvaluator struct {
+	// URL of the OPA server, e.g. http://localhost:8181
+	URL string
+	// Path of the decision document, e.g. "llmconfig/write"
+	Path       string
+	HTTPClient *http.Client
+}
+
+type opaRequest struct {
+	Input PolicyInput `json:"input"`
+}
+
+type opaResponse struct {
+	Result *struct {
+		Allow *bool             `json:"allow"`
+		Deny  []json.RawMessage `json:"deny"`
+	} `json:"result"`
+}
+
+// Evaluate implements PolicyEvaluator
+func (e *OPAEvaluator) Evaluate(ctx context.Context, input PolicyInput) (*PolicyDecision, error) {
+	body, err := json.Marshal(opaRequest{Input: input})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := strings.TrimSuffix(e.URL, "/") + "/v1/data/" + strings.Trim(e.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := e.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("opa returned status %d", resp.StatusCode)
+	}
+
+	var decoded opaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decode opa response: %w", err)
+	}
+	if decoded.Result == nil {
+		return nil, fmt.Errorf("opa policy %s is undefined", e.Path)
+	}
+
+	decision := &PolicyDecision{Allow: decoded.Result.Allow != nil && *decoded.Result.Allow}
+	for _, raw := range decoded.Result.Deny {
+		var msg string
+		if json.Unmarshal(raw, &msg) == nil {
+			decision.Violations = append(decision.Violations, PolicyViolation{Rule: e.Path, Message: msg})
+			continue
+		}
+		var obj struct {
+			Rule string `json:"rule"`
+			Msg  string `json:"msg"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("decode opa deny entry %s: %w", raw, err)
+		}
+		decision.Violations = append(decision.Violations, PolicyViolation{Rule: obj.Rule, Message: obj.Msg})
+	}
+	return decision, nil
+}
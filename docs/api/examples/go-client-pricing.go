@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ModelPrice is the list price of one model, in USD per million tokens
+type ModelPrice struct {
+	Model    string `json:"model"`
+	Provider string `json:"provider"`
+	// Input and Output are USD per million tokens
+	Input  float64 `json:"input_per_million"`
+	Output float64 `json:"output_per_million"`
+	// CachedInput is the price of cache hits where the provider offers
+	// prompt caching; zero means cached input is billed as Input
+	CachedInput   float64 `json:"cached_input_per_million,omitempty"`
+	EffectiveFrom string  `json:"effective_from,omitempty"`
+}
+
+// ErrUnknownPrice is returned when the pricing table has no entry for a
+// model
+var ErrUnknownPrice = errors.New("no price for model")
+
+// PriceTable is a snapshot of the pricing data, keyed by model
+type PriceTable struct {
+	Prices   map[string]ModelPrice
+	SyncedAt time.Time
+}
+
+// Lookup finds the price for model, preferring an exact match and falling
+// back to the longest priced prefix, so dated snapshots
+// ("gpt-4o-2024-08-06") use their family's price rather than a shorter
+// family's ("gpt-4")
+func (t *PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := t.Prices[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range t.Prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return t.Prices[best], true
+}
+
+// EstimateCost returns the USD cost of a call to model with the given token
+// counts, priced by Lookup. cachedInputTokens is the part of inputTokens
+// served from the provider's prompt cache, as usage reports count it, and
+// is billed at CachedInput where the model has one.
+func (t *PriceTable) EstimateCost(model string, inputTokens, cachedInputTokens, outputTokens int64) (float64, error) {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0, fmt.Errorf("%w %s", ErrUnknownPrice, model)
+	}
+	cachedInputTokens = min(max(cachedInputTokens, 0), inputTokens)
+	cachedPrice := price.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = price.Input
+	}
+	cost := float64(inputTokens-cachedInputTokens)*price.Input +
+		float64(cachedInputTokens)*cachedPrice +
+		float64(outputTokens)*price.Output
+	return cost / 1e6, nil
+}
+
+// ListPrices returns the pricing data for every model
+func (c *LLMConfigClient) ListPrices(ctx context.Context) ([]ModelPrice, error) {
+	var result []ModelPrice
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/pricing")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// GetPrice returns the pricing data for one model
+func (c *LLMConfigClient) GetPrice(ctx context.Context, model string) (*ModelPrice, error) {
+	var result ModelPrice
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/pricing/%s", url.PathEscape(model)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// SyncPricing fetches the pricing data and makes it the table used by
+// EstimateCost and EstimateSpend. Call it at startup and periodically.
+func (c *LLMConfigClient) SyncPricing(ctx context.Context) (*PriceTable, error) {
+	prices, err := c.ListPrices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("sync pricing: %w", err)
+	}
+
+	table := &PriceTable{Prices: make(map[string]ModelPrice, len(prices)), SyncedAt: c.clock.Now()}
+	for _, price := range prices {
+		table.Prices[price.Model] = price
+	}
+	c.pricing.Store(table)
+	return table, nil
+}
+
+// EstimateCost returns the USD cost of a call to model using the table from
+// the last SyncPricing
+func (c *LLMConfigClient) EstimateCost(model string, inputTokens, cachedInputTokens, outputTokens int64) (float64, error) {
+	table := c.pricing.Load()
+	if table == nil {
+		return 0, errors.New("pricing has not been synced; call SyncPricing first")
+	}
+	return table.EstimateCost(model, inputTokens, cachedInputTokens, outputTokens)
+}
+
+// EstimateSpend is EstimateCost in the form CheckBudget and RecordSpend take:
+//
+//	estimate, err := client.EstimateSpend("gpt-4o", promptTokens, 0, maxTokens)
+//	...
+//	_, err = client.CheckBudget(ctx, "app/llm", estimate)
+func (c *LLMConfigClient) EstimateSpend(model string, inputTokens, cachedInputTokens, outputTokens int64) (Spend, error) {
+	cost, err := c.EstimateCost(model, inputTokens, cachedInputTokens, outputTokens)
+	if err != nil {
+		return Spend{}, err
+	}
+	return Spend{Tokens: inputTokens + outputTokens, Cost: cost}, nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ProcessError reports a value Process could not store in its field
+type ProcessError struct {
+	Namespace string
+	Key       string
+	Field     string
+	Type      string
+	Value     interface{}
+	Err       error
+}
+
+func (e *ProcessError) Error() string {
+	return fmt.Sprintf("%s/%s: cannot store %v in %s (%s): %v", e.Namespace, e.Key, e.Value, e.Field, e.Type, e.Err)
+}
+
+func (e *ProcessError) Unwrap() error {
+	return e.Err
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Process fills the struct spec points to from one read of namespace in
+// env, as kelseyhightower/envconfig fills one from environment variables,
+// so services move their settings over without restructuring them:
+//
+//	var cfg struct {
+//		Model     string        `default:"gpt-4"`
+//		MaxTokens int           `required:"true"`
+//		Timeout   time.Duration `llmconfig:"request_timeout" default:"30s"`
+//		Stop      []string
+//		Limits    struct{ RPM int }
+//		Debug     bool `ignored:"true"`
+//	}
+//	err := client.Process(ctx, "app/llm", "production", &cfg)
+//
+// A field reads the key its llmconfig tag names, or its name in
+// snake_case (MaxTokens from max_tokens); `llmconfig:"-"` and
+// `ignored:"true"` skip it. Keys that are missing take the field's default
+// tag, and required ones without a default are reported together in a
+// *MissingKeysError; other fields are left as they were.
+//
+// Values convert as their JSON allows, and strings, defaults included, as
+// envconfig parses them: numbers and bools, durations ("30s"; plain
+// numbers are seconds), comma-separated slices, "k:v,k2:v2" maps, and
+// encoding.TextUnmarshaler. Nested structs read the object under their
+// key; embedded ones read the same level. Masked secrets are revealed for
+// the fields that read them. A value that does not fit is a *ProcessError.
+func (c *LLMConfigClient) Process(ctx context.Context, namespace, env string, spec interface{}) error {
+	target := reflect.ValueOf(spec)
+	if target.Kind() != reflect.Pointer || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("process: spec must be a pointer to a struct, got %T", spec)
+	}
+
+	configs, err := c.ListConfigsContext(ctx, namespace, env)
+	if err != nil {
+		return fmt.Errorf("process %s (%s): %w", namespace, env, err)
+	}
+	values := make(map[string]interface{}, len(configs))
+	for _, cfg := range configs {
+		values[cfg.Key] = cfg.Value
+	}
+
+	p := &processor{client: c, ctx: ctx, namespace: namespace, env: env}
+	if err := p.fill(target.Elem(), values, ""); err != nil {
+		return err
+	}
+	if len(p.missing) > 0 {
+		sort.Strings(p.missing)
+		return &MissingKeysError{Namespace: namespace, Environment: env, Keys: p.missing}
+	}
+	return nil
+}
+
+// processor carries one Process call
+type processor struct {
+	client         *LLMConfigClient
+	ctx            context.Context
+	namespace, env string
+	missing        []string
+}
+
+// fill sets the fields of the struct v from values; path is the dotted
+// key of values within the namespace
+func (p *processor) fill(v reflect.Value, values map[string]interface{}, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("ignored") == "true" {
+			continue
+		}
+		name := field.Tag.Get("llmconfig")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if field.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			if err := p.fill(fv, values, path); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = snakeCase(field.Name)
+		}
+		key := path + name
+
+		raw, ok := values[name]
+		if ok && raw == encryptedPlaceholder && path == "" {
+			secret, err := p.client.RevealSecret(p.ctx, p.namespace, name, p.env, nil)
+			if err != nil {
+				return fmt.Errorf("process: reveal %s/%s: %w", p.namespace, name, err)
+			}
+			raw = secret.Value
+		}
+		if !ok || raw == nil {
+			def, hasDefault := field.Tag.Lookup("default")
+			if !hasDefault {
+				if field.Tag.Get("required") == "true" {
+					p.missing = append(p.missing, key)
+				}
+				continue
+			}
+			raw = def
+		}
+
+		if err := p.set(fv, raw, key); err != nil {
+			var processErr *ProcessError
+			if errors.As(err, &processErr) {
+				return err
+			}
+			return &ProcessError{Namespace: p.namespace, Key: key, Field: field.Name, Type: field.Type.String(), Value: raw, Err: err}
+		}
+	}
+	return nil
+}
+
+// set stores raw, a JSON value or a string to parse, in v
+func (p *processor) set(v reflect.Value, raw interface{}, key string) error {
+	if raw == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	s, isString := raw.(string)
+	if isString && v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch {
+	case v.Kind() == reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := p.set(elem.Elem(), raw, key); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case v.Type() == durationType:
+		switch val := raw.(type) {
+		case string:
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				seconds, numErr := strconv.ParseFloat(val, 64)
+				if numErr != nil {
+					return err
+				}
+				d = time.Duration(seconds * float64(time.Second))
+			}
+			v.SetInt(int64(d))
+		case float64:
+			v.SetInt(int64(val * float64(time.Second)))
+		default:
+			return errors.New("want a duration or a number of seconds")
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if isString {
+			v.SetString(s)
+			return nil
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(data))
+	case reflect.Bool:
+		switch val := raw.(type) {
+		case bool:
+			v.SetBool(val)
+		case string:
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		default:
+			return errors.New("want a bool")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch val := raw.(type) {
+		case float64:
+			if val != math.Trunc(val) || val < math.MinInt64 || val >= math.MaxInt64 {
+				return errors.New("want an integer")
+			}
+			n = int64(val)
+		case string:
+			var err error
+			if n, err = strconv.ParseInt(val, 0, v.Type().Bits()); err != nil {
+				return err
+			}
+		default:
+			return errors.New("want an integer")
+		}
+		if v.OverflowInt(n) {
+			return errors.New("out of range")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		switch val := raw.(type) {
+		case float64:
+			if val != math.Trunc(val) || val < 0 || val >= math.MaxUint64 {
+				return errors.New("want a non-negative integer")
+			}
+			n = uint64(val)
+		case string:
+			var err error
+			if n, err = strconv.ParseUint(val, 0, v.Type().Bits()); err != nil {
+				return err
+			}
+		default:
+			return errors.New("want a non-negative integer")
+		}
+		if v.OverflowUint(n) {
+			return errors.New("out of range")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		switch val := raw.(type) {
+		case float64:
+			v.SetFloat(val)
+		case string:
+			f, err := strconv.ParseFloat(val, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetFloat(f)
+		default:
+			return errors.New("want a number")
+		}
+	case reflect.Slice:
+		var items []interface{}
+		switch val := raw.(type) {
+		case []interface{}:
+			items = val
+		case string:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				v.SetBytes([]byte(val))
+				return nil
+			}
+			for _, item := range strings.Split(val, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		default:
+			return errors.New("want a list or a comma-separated string")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := p.set(slice.Index(i), item, fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		entries := map[string]interface{}{}
+		switch val := raw.(type) {
+		case map[string]interface{}:
+			entries = val
+		case string:
+			for _, pair := range strings.Split(val, ",") {
+				if pair = strings.TrimSpace(pair); pair == "" {
+					continue
+				}
+				k, item, ok := strings.Cut(pair, ":")
+				if !ok {
+					return fmt.Errorf("map entry %q is not key:value", pair)
+				}
+				entries[strings.TrimSpace(k)] = strings.TrimSpace(item)
+			}
+		default:
+			return errors.New("want an object or key:value pairs")
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(entries))
+		for k, item := range entries {
+			mk := reflect.New(v.Type().Key()).Elem()
+			if err := p.set(mk, k, key); err != nil {
+				return err
+			}
+			mv := reflect.New(v.Type().Elem()).Elem()
+			if err := p.set(mv, item, key+"."+k); err != nil {
+				return err
+			}
+			m.SetMapIndex(mk, mv)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return errors.New("want an object")
+		}
+		return p.fill(v, obj, key+".")
+	case reflect.Interface:
+		if raw != nil && !reflect.TypeOf(raw).AssignableTo(v.Type()) {
+			return fmt.Errorf("%T does not implement %s", raw, v.Type())
+		}
+		v.Set(reflect.ValueOf(raw))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// snakeCase spells a Go field name as a config key: MaxTokens is
+// max_tokens, APIKey api_key
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			boundary := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+			if boundary {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
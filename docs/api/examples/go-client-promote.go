@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// PromoteOptions narrow a promotion between environments
+type PromoteOptions struct {
+	// Keys limits the promotion to these keys; empty promotes every key
+	Keys []string
+	// Prune deletes keys the target has and the source does not
+	Prune bool
+}
+
+// PromotionPlan is the ApplyPlan that makes one environment of a namespace
+// match another
+type PromotionPlan struct {
+	ApplyPlan
+	Namespace string `json:"namespace"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	// SkippedSecrets are source keys holding secrets. The API masks their
+	// values, so they cannot be compared or copied and must be set in the
+	// target by hand.
+	SkippedSecrets []string `json:"skipped_secrets,omitempty"`
+}
+
+// PlanPromotion diffs namespace between the from and to environments and
+// plans the changes that make to match from. Secrets in the target are
+// never changed or pruned.
+func (c *LLMConfigClient) PlanPromotion(ctx context.Context, namespace, from, to string, opts PromoteOptions) (*PromotionPlan, error) {
+	source, err := c.ListConfigsContext(ctx, namespace, from)
+	if err != nil {
+		return nil, fmt.Errorf("list %s (%s): %w", namespace, from, err)
+	}
+	target, err := c.ListConfigsContext(ctx, namespace, to)
+	if err != nil {
+		return nil, fmt.Errorf("list %s (%s): %w", namespace, to, err)
+	}
+
+	selected := func(key string) bool {
+		return len(opts.Keys) == 0 || slices.Contains(opts.Keys, key)
+	}
+
+	plan := &PromotionPlan{Namespace: namespace, From: from, To: to}
+	manifest := Manifest{
+		Namespace:   namespace,
+		Environment: to,
+		Prune:       opts.Prune,
+		Configs:     map[string]interface{}{},
+		Secrets:     map[string]string{},
+		Source:      fmt.Sprintf("%s (%s)", namespace, from),
+	}
+	for _, cfg := range source {
+		switch {
+		case !selected(cfg.Key):
+		case cfg.Value == encryptedPlaceholder:
+			plan.SkippedSecrets = append(plan.SkippedSecrets, cfg.Key)
+		default:
+			manifest.Configs[cfg.Key] = cfg.Value
+		}
+	}
+	// Declaring the target's secrets keeps them out of the prune; PlanApply
+	// leaves existing secrets unchanged
+	for _, cfg := range target {
+		if cfg.Value == encryptedPlaceholder {
+			delete(manifest.Configs, cfg.Key)
+			manifest.Secrets[cfg.Key] = ""
+		}
+	}
+	sort.Strings(plan.SkippedSecrets)
+
+	applyPlan, err := c.PlanApply(ctx, []Manifest{manifest})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range applyPlan.Changes {
+		if selected(change.Key) {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+	return plan, nil
+}
+
+// Promote applies a promotion plan
+func (c *LLMConfigClient) Promote(ctx context.Context, plan *PromotionPlan, user string) error {
+	if err := c.Apply(ctx, &plan.ApplyPlan, user); err != nil {
+		return fmt.Errorf("promote %s from %s to %s: %w", plan.Namespace, plan.From, plan.To, err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultPromptCacheTTL is how long RenderPrompt reuses the latest version
+// of a prompt before checking for a newer one. Pinned versions never
+// change and are cached until the client is discarded.
+const DefaultPromptCacheTTL = time.Minute
+
+// RenderedPrompt is the output of RenderPrompt
+type RenderedPrompt struct {
+	Name    string
+	Version int64
+	Text    string
+	// EstimatedTokens is EstimateTokens(Text)
+	EstimatedTokens int
+	// Partials maps each included prompt to the version rendered
+	Partials map[string]int64
+}
+
+type promptCacheEntry struct {
+	prompt    *PromptTemplate
+	fetchedAt time.Time
+}
+
+type promptCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]promptCacheEntry
+}
+
+func newPromptCache() *promptCache {
+	return &promptCache{ttl: DefaultPromptCacheTTL, entries: map[string]promptCacheEntry{}}
+}
+
+// SetPromptCacheTTL changes how long the latest version of a prompt is
+// reused; zero disables caching of latest versions
+func (c *LLMConfigClient) SetPromptCacheTTL(ttl time.Duration) {
+	c.prompts.mu.Lock()
+	defer c.prompts.mu.Unlock()
+	c.prompts.ttl = ttl
+}
+
+// cachedPrompt is GetPrompt through the prompt cache
+func (c *LLMConfigClient) cachedPrompt(ctx context.Context, name string, version int64) (*PromptTemplate, error) {
+	cacheKey := c.organizationCacheKey(ctx, fmt.Sprintf("%s@%d", name, version))
+
+	c.prompts.mu.Lock()
+	entry, ok := c.prompts.entries[cacheKey]
+	fresh := ok && (version > 0 || c.clock.Now().Sub(entry.fetchedAt) < c.prompts.ttl)
+	c.prompts.mu.Unlock()
+	c.metrics.Load().observeCacheLookup(fresh)
+	if fresh {
+		return entry.prompt, nil
+	}
+
+	prompt, err := c.GetPrompt(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	c.prompts.mu.Lock()
+	defer c.prompts.mu.Unlock()
+	entry = promptCacheEntry{prompt: prompt, fetchedAt: c.clock.Now()}
+	c.prompts.entries[cacheKey] = entry
+	c.prompts.entries[c.organizationCacheKey(ctx, fmt.Sprintf("%s@%d", name, prompt.Version))] = entry
+	return prompt, nil
+}
+
+// RenderPrompt fetches a prompt (version 0 for latest), binds vars, and
+// renders it. The template can include other prompts as partials with
+// {{template "name" .}} for their latest version or {{template "name@3" .}}
+// for a pinned one; partials see the same variables, so the outer prompt
+// must declare any a partial uses.
+func (c *LLMConfigClient) RenderPrompt(ctx context.Context, name string, version int64, vars map[string]interface{}) (*RenderedPrompt, error) {
+	prompt, err := c.cachedPrompt(ctx, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("prompt %s: %w", name, err)
+	}
+	bound, err := prompt.Bind(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(prompt.Template)
+	if err != nil {
+		return nil, fmt.Errorf("prompt %s v%d: %w", name, prompt.Version, err)
+	}
+
+	partials := map[string]int64{}
+	pending := templateIncludes(tmpl)
+	for len(pending) > 0 {
+		ref := pending[0]
+		pending = pending[1:]
+		if tmpl.Lookup(ref) != nil {
+			continue
+		}
+
+		partialName, partialVersion, err := parsePromptRef(ref)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %s v%d: %w", name, prompt.Version, err)
+		}
+		partial, err := c.cachedPrompt(ctx, partialName, partialVersion)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %s v%d: partial %s: %w", name, prompt.Version, ref, err)
+		}
+		included, err := tmpl.New(ref).Parse(partial.Template)
+		if err != nil {
+			return nil, fmt.Errorf("partial %s v%d: %w", partialName, partial.Version, err)
+		}
+		partials[partialName] = partial.Version
+		pending = append(pending, templateIncludes(included)...)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, bound); err != nil {
+		return nil, fmt.Errorf("render prompt %s v%d: %w", name, prompt.Version, err)
+	}
+
+	return &RenderedPrompt{
+		Name:            name,
+		Version:         prompt.Version,
+		Text:            out.String(),
+		EstimatedTokens: EstimateTokens(out.String()),
+		Partials:        partials,
+	}, nil
+}
+
+// EstimateTokens approximates the token count of text for budgeting:
+// about four characters per token for ASCII, one per token otherwise.
+// Use the provider's tokenizer where an exact count matters.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// templateIncludes returns the template names t includes
+func templateIncludes(t *template.Template) []string {
+	var names []string
+	if t.Tree == nil {
+		return nil
+	}
+	walkTemplate(t.Tree.Root, true, func(n parse.Node, _ bool) {
+		if include, ok := n.(*parse.TemplateNode); ok {
+			names = append(names, include.Name)
+		}
+	})
+	return names
+}
+
+// parsePromptRef splits "name" or "name@version"
+func parsePromptRef(ref string) (string, int64, error) {
+	name, v, pinned := strings.Cut(ref, "@")
+	if !pinned {
+		return ref, 0, nil
+	}
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version <= 0 {
+		return "", 0, fmt.Errorf("partial %q: version must be a positive integer", ref)
+	}
+	return name, version, nil
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// PromptVariable is a value a prompt template expects
+type PromptVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Required variables must be bound unless they have a Default
+	Required bool        `json:"required"`
+	Default  interface{} `json:"default,omitempty"`
+}
+
+// PromptTemplate is one published version of a prompt. Template is Go
+// text/template syntax and refers to variables as fields of dot:
+//
+//	You are a support agent for {{.product}}. Answer in {{.language}}.
+type PromptTemplate struct {
+	Name        string           `json:"name"`
+	Version     int64            `json:"version"`
+	Description string           `json:"description,omitempty"`
+	Template    string           `json:"template"`
+	Variables   []PromptVariable `json:"variables"`
+	CreatedAt   string           `json:"created_at,omitempty"`
+	CreatedBy   string           `json:"created_by,omitempty"`
+}
+
+// PromptSummary describes a prompt without its template text
+type PromptSummary struct {
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	LatestVersion int64  `json:"latest_version"`
+	UpdatedAt     string `json:"updated_at"`
+	UpdatedBy     string `json:"updated_by"`
+}
+
+type publishPromptRequest struct {
+	Description string           `json:"description,omitempty"`
+	Template    string           `json:"template"`
+	Variables   []PromptVariable `json:"variables"`
+	User        string           `json:"user"`
+}
+
+// PromptBindingError lists the variables a binding left out or does not
+// declare
+type PromptBindingError struct {
+	Prompt  string
+	Version int64
+	Missing []string
+	Unknown []string
+}
+
+func (e *PromptBindingError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "undeclared "+strings.Join(e.Unknown, ", "))
+	}
+	return fmt.Sprintf("prompt %s v%d: %s", e.Prompt, e.Version, strings.Join(parts, "; "))
+}
+
+// promptFuncs are the functions prompt templates may call
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// Validate checks that the template parses and that the variables it uses
+// are exactly the ones declared
+func (p PromptTemplate) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if p.Name == "" {
+		fail("/name", "name is required")
+	}
+
+	declared := make(map[string]bool, len(p.Variables))
+	for i, v := range p.Variables {
+		switch {
+		case v.Name == "":
+			fail(fmt.Sprintf("/variables/%d/name", i), "name is required")
+		case declared[v.Name]:
+			fail(fmt.Sprintf("/variables/%d/name", i), "variable %s is declared twice", v.Name)
+		}
+		declared[v.Name] = true
+	}
+
+	used, err := promptTemplateVariables(p.Name, p.Template)
+	if err != nil {
+		fail("/template", "%v", err)
+		return paramsError(problems)
+	}
+	for _, name := range sortedMapKeys(used) {
+		if !declared[name] {
+			fail("/template", "template uses undeclared variable %s", name)
+		}
+	}
+	for i, v := range p.Variables {
+		if v.Name != "" && !used[v.Name] {
+			fail(fmt.Sprintf("/variables/%d", i), "variable %s is declared but never used", v.Name)
+		}
+	}
+
+	return paramsError(problems)
+}
+
+// Bind checks vars against the declared variables and returns them with
+// defaults filled in. Every required variable without a default must be
+// bound, and every bound variable must be declared.
+func (p *PromptTemplate) Bind(vars map[string]interface{}) (map[string]interface{}, error) {
+	bound := make(map[string]interface{}, len(p.Variables))
+	declared := make(map[string]bool, len(p.Variables))
+	var missing, unknown []string
+
+	for _, v := range p.Variables {
+		declared[v.Name] = true
+		if value, ok := vars[v.Name]; ok {
+			bound[v.Name] = value
+		} else if v.Default != nil {
+			bound[v.Name] = v.Default
+		} else if v.Required {
+			missing = append(missing, v.Name)
+		} else {
+			bound[v.Name] = ""
+		}
+	}
+	for _, name := range sortedMapKeys(vars) {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(missing) > 0 || len(unknown) > 0 {
+		return nil, &PromptBindingError{Prompt: p.Name, Version: p.Version, Missing: missing, Unknown: unknown}
+	}
+	return bound, nil
+}
+
+// PublishPrompt validates prompt and stores it as the next version of its
+// name. Version, CreatedAt, and CreatedBy are assigned by the server.
+func (c *LLMConfigClient) PublishPrompt(ctx context.Context, prompt PromptTemplate, user string) (*PromptTemplate, error) {
+	if err := withValidationTarget(prompt.Validate(), "prompts", prompt.Name); err != nil {
+		return nil, err
+	}
+
+	var result PromptTemplate
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(publishPromptRequest{
+			Description: prompt.Description,
+			Template:    prompt.Template,
+			Variables:   prompt.Variables,
+			User:        user,
+		}).
+		SetResult(&result).
+		Post(fmt.Sprintf("/prompts/%s/versions", url.PathEscape(prompt.Name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListPrompts returns every prompt, sorted by name
+func (c *LLMConfigClient) ListPrompts(ctx context.Context) ([]PromptSummary, error) {
+	var result []PromptSummary
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/prompts")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// ListPromptVersions returns every version of name, oldest first
+func (c *LLMConfigClient) ListPromptVersions(ctx context.Context, name string) ([]PromptTemplate, error) {
+	var result []PromptTemplate
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/prompts/%s/versions", url.PathEscape(name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return []PromptTemplate{}, nil
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// GetPrompt fetches one version of name; version 0 means latest
+func (c *LLMConfigClient) GetPrompt(ctx context.Context, name string, version int64) (*PromptTemplate, error) {
+	var result PromptTemplate
+
+	ref := "latest"
+	if version > 0 {
+		ref = fmt.Sprintf("%d", version)
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/prompts/%s/versions/%s", url.PathEscape(name), ref))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// promptTemplateVariables parses text and returns the variables it reads
+// from the top-level data: {{.name}} outside range/with blocks, and
+// {{$.name}} anywhere
+func promptTemplateVariables(name, text string) (map[string]bool, error) {
+	tmpl, err := template.New(name).Funcs(promptFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			collectTemplateFields(t.Tree.Root, used)
+		}
+	}
+	return used, nil
+}
+
+// collectTemplateFields records the top-level fields read under node
+func collectTemplateFields(node parse.Node, used map[string]bool) {
+	walkTemplate(node, true, func(n parse.Node, atRoot bool) {
+		switch n := n.(type) {
+		case *parse.FieldNode:
+			if atRoot {
+				used[n.Ident[0]] = true
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				used[n.Ident[1]] = true
+			}
+		}
+	})
+}
+
+// walkTemplate calls visit for node and everything below it; atRoot is
+// false inside range and with bodies, where dot no longer refers to the
+// prompt variables
+func walkTemplate(node parse.Node, atRoot bool, visit func(n parse.Node, atRoot bool)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkTemplate(child, atRoot, visit)
+		}
+		return
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkTemplate(cmd, atRoot, visit)
+		}
+		return
+	}
+
+	visit(node, atRoot)
+
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		walkTemplate(n.Pipe, atRoot, visit)
+	case *parse.TemplateNode:
+		walkTemplate(n.Pipe, atRoot, visit)
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkTemplate(arg, atRoot, visit)
+		}
+	case *parse.ChainNode:
+		walkTemplate(n.Node, atRoot, visit)
+	case *parse.IfNode:
+		walkTemplate(n.Pipe, atRoot, visit)
+		walkTemplate(n.List, atRoot, visit)
+		walkTemplate(n.ElseList, atRoot, visit)
+	case *parse.RangeNode:
+		walkTemplate(n.Pipe, atRoot, visit)
+		walkTemplate(n.List, false, visit)
+		walkTemplate(n.ElseList, atRoot, visit)
+	case *parse.WithNode:
+		walkTemplate(n.Pipe, atRoot, visit)
+		walkTemplate(n.List, false, visit)
+		walkTemplate(n.ElseList, atRoot, visit)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Provider names, matching ChatModelParams.Provider
+const (
+	ProviderOpenAI      = "openai"
+	ProviderAnthropic   = "anthropic"
+	ProviderAzureOpenAI = "azure"
+	ProviderBedrock     = "bedrock"
+)
+
+// ProviderProfileNamespace holds shared profiles that services refer to by
+// name
+const ProviderProfileNamespace = "providers"
+
+// ProviderProfile is how a service reaches one model provider. Store one per
+// environment and fetch it with GetProviderProfile:
+//
+//	{"provider": "azure", "endpoint": "https://acme.openai.azure.com",
+//	 "api_version": "2024-06-01", "deployment": "gpt-4o-prod",
+//	 "auth_ref": "secrets/azure_openai_key"}
+type ProviderProfile struct {
+	Provider string `json:"provider"`
+	// Endpoint is the API base URL; OpenAI, Anthropic, and Bedrock have
+	// defaults, Azure OpenAI requires the resource endpoint
+	Endpoint string `json:"endpoint,omitempty"`
+	// APIVersion is the api-version query parameter for Azure OpenAI and
+	// the anthropic-version header for Anthropic
+	APIVersion string `json:"api_version,omitempty"`
+	// Deployment is the Azure OpenAI deployment name
+	Deployment string `json:"deployment,omitempty"`
+	// Region is the AWS region for Bedrock
+	Region       string `json:"region,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	// AuthRef says where the credential lives: "namespace/key" of a secret
+	// config, "credential:NAME" for a vaulted credential, or "env:NAME" for
+	// an environment variable. Bedrock may leave it empty to use the AWS
+	// default credential chain.
+	AuthRef string            `json:"auth_ref,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// defaultProviderEndpoints are used when a profile sets no endpoint
+var defaultProviderEndpoints = map[string]string{
+	ProviderOpenAI:    "https://api.openai.com/v1",
+	ProviderAnthropic: "https://api.anthropic.com",
+}
+
+// defaultProviderAPIVersions are used when a profile sets no API version
+var defaultProviderAPIVersions = map[string]string{
+	ProviderAnthropic: "2023-06-01",
+}
+
+// Validate checks that the fields the provider needs are present
+func (p ProviderProfile) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch p.Provider {
+	case ProviderOpenAI, ProviderAnthropic:
+	case ProviderAzureOpenAI:
+		if p.Endpoint == "" {
+			fail("/endpoint", "azure requires the resource endpoint")
+		}
+		if p.Deployment == "" {
+			fail("/deployment", "azure requires a deployment")
+		}
+		if p.APIVersion == "" {
+			fail("/api_version", "azure requires an api_version")
+		}
+	case ProviderBedrock:
+		if p.Region == "" {
+			fail("/region", "bedrock requires a region")
+		}
+	default:
+		fail("/provider", "provider %q is not one of openai, anthropic, azure, bedrock", p.Provider)
+	}
+
+	if p.Endpoint != "" {
+		if u, err := url.Parse(p.Endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+			fail("/endpoint", "endpoint %q is not an https URL", p.Endpoint)
+		}
+	}
+
+	if p.AuthRef == "" && p.Provider != ProviderBedrock {
+		fail("/auth_ref", "auth_ref is required")
+	} else if name, ok := strings.CutPrefix(p.AuthRef, "credential:"); ok {
+		if name == "" {
+			fail("/auth_ref", "auth_ref %q names no credential", p.AuthRef)
+		}
+	} else if p.AuthRef != "" {
+		if _, _, _, err := parseAuthRef(p.AuthRef); err != nil {
+			fail("/auth_ref", "%v", err)
+		}
+	}
+
+	return paramsError(problems)
+}
+
+// Resolved returns a copy with provider defaults filled in
+func (p ProviderProfile) Resolved() ProviderProfile {
+	if p.Endpoint == "" {
+		p.Endpoint = defaultProviderEndpoints[p.Provider]
+		if p.Provider == ProviderBedrock && p.Region != "" {
+			p.Endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", p.Region)
+		}
+	}
+	p.Endpoint = strings.TrimRight(p.Endpoint, "/")
+	if p.APIVersion == "" {
+		p.APIVersion = defaultProviderAPIVersions[p.Provider]
+	}
+	return p
+}
+
+// GetProviderProfile returns the validated, defaulted profile that
+// namespace/key names for env. The value is either a profile object or the
+// name of a shared profile in ProviderProfileNamespace, so services can
+// point at "azure-eastus" instead of copying it.
+func (c *LLMConfigClient) GetProviderProfile(ctx context.Context, namespace, key, env string) (*ProviderProfile, error) {
+	cfg, err := c.GetConfigContext(ctx, namespace, key, env, true)
+	if err != nil {
+		return nil, fmt.Errorf("read %s/%s: %w", namespace, key, err)
+	}
+
+	value := cfg.Value
+	if name, ok := value.(string); ok {
+		namespace, key = ProviderProfileNamespace, name
+		shared, err := c.GetConfigContext(ctx, namespace, key, env, true)
+		if err != nil {
+			return nil, fmt.Errorf("read provider profile %s: %w", name, err)
+		}
+		value = shared.Value
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var profile ProviderProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("decode %s/%s: %w", namespace, key, err)
+	}
+	if err := withValidationTarget(profile.Validate(), namespace, key); err != nil {
+		return nil, err
+	}
+
+	resolved := profile.Resolved()
+	return &resolved, nil
+}
+
+// ProviderCredential reads the credential a profile's AuthRef points to; a
+// namespace/key reference is read with RevealSecret, so the token needs
+// permission to read secrets there. It returns "" for a Bedrock profile
+// without one.
+func (c *LLMConfigClient) ProviderCredential(ctx context.Context, profile *ProviderProfile, env string) (string, error) {
+	if profile.AuthRef == "" {
+		return "", nil
+	}
+	if name, ok := strings.CutPrefix(profile.AuthRef, "credential:"); ok {
+		secret, err := c.GetCredentialSecret(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("auth_ref %s: %w", profile.AuthRef, err)
+		}
+		return secret.Secret, nil
+	}
+
+	envVar, namespace, key, err := parseAuthRef(profile.AuthRef)
+	if err != nil {
+		return "", err
+	}
+
+	if envVar != "" {
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			return "", fmt.Errorf("auth_ref %s: environment variable %s is not set", profile.AuthRef, envVar)
+		}
+		return value, nil
+	}
+
+	// Reads mask secrets, so credentials are read unmasked
+	secret, err := c.RevealSecret(ctx, namespace, key, env, nil)
+	if err != nil {
+		return "", fmt.Errorf("auth_ref %s: %w", profile.AuthRef, err)
+	}
+	credential, ok := secret.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("auth_ref %s: value of type %s is not a string", profile.AuthRef, valueTypeName(secret.Value))
+	}
+	if credential == encryptedPlaceholder {
+		return "", fmt.Errorf("auth_ref %s: the server returned the secret masked", profile.AuthRef)
+	}
+	return credential, nil
+}
+
+// parseAuthRef splits "env:NAME" or "namespace/key"; the namespace may
+// itself contain slashes
+func parseAuthRef(ref string) (envVar, namespace, key string, err error) {
+	if name, ok := strings.CutPrefix(ref, "env:"); ok {
+		if name == "" {
+			return "", "", "", fmt.Errorf("auth_ref %q names no environment variable", ref)
+		}
+		return name, "", "", nil
+	}
+	i := strings.LastIndex(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", "", fmt.Errorf("auth_ref %q is neither env:NAME nor namespace/key", ref)
+	}
+	return "", ref[:i], ref[i+1:], nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Rate-limit event reasons
+const (
+	// RateLimitLow fires when remaining capacity drops below the threshold
+	RateLimitLow = "low"
+	// RateLimitThrottled fires when the server answers 429
+	RateLimitThrottled = "throttled"
+)
+
+// RateLimitEvent describes rate-limit pressure observed on a response
+type RateLimitEvent struct {
+	Reason     string
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+// rateLimitListener is one OnRateLimit registration
+type rateLimitListener struct {
+	threshold int
+	fn        func(RateLimitEvent)
+	// below suppresses repeat "low" events until capacity recovers
+	below bool
+}
+
+// rateLimitHooks fans rate-limit events out to listeners
+type rateLimitHooks struct {
+	mu        sync.Mutex
+	listeners []*rateLimitListener
+}
+
+// OnRateLimit calls fn when remaining capacity first drops below threshold
+// requests (a tenth of the limit when threshold is 0) and on every 429, so
+// callers can shed low-priority refreshes. fn runs on the request goroutine
+// and must not block.
+func (c *LLMConfigClient) OnRateLimit(threshold int, fn func(RateLimitEvent)) {
+	c.rateLimitHooks.mu.Lock()
+	defer c.rateLimitHooks.mu.Unlock()
+	c.rateLimitHooks.listeners = append(c.rateLimitHooks.listeners, &rateLimitListener{threshold: threshold, fn: fn})
+}
+
+// RateLimitEvents is OnRateLimit delivered on a channel. Events are dropped
+// rather than blocking requests when the buffer is full.
+func (c *LLMConfigClient) RateLimitEvents(threshold, buffer int) <-chan RateLimitEvent {
+	ch := make(chan RateLimitEvent, buffer)
+	c.OnRateLimit(threshold, func(e RateLimitEvent) {
+		select {
+		case ch <- e:
+		default:
+		}
+	})
+	return ch
+}
+
+// installRateLimitHooks dispatches every response to the OnRateLimit
+// listeners
+func (c *LLMConfigClient) installRateLimitHooks() {
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		c.rateLimitHooks.dispatch(resp, c.clock.Now())
+		return nil
+	})
+}
+
+func (h *rateLimitHooks) dispatch(resp *resty.Response, now time.Time) {
+	h.mu.Lock()
+	listening := len(h.listeners) > 0
+	h.mu.Unlock()
+	if !listening {
+		return
+	}
+
+	event := RateLimitEvent{Limit: -1, Remaining: -1}
+	if v, err := strconv.Atoi(resp.Header().Get("X-RateLimit-Limit")); err == nil {
+		event.Limit = v
+	}
+	if v, err := strconv.Atoi(resp.Header().Get("X-RateLimit-Remaining")); err == nil {
+		event.Remaining = v
+	}
+	if v, err := strconv.ParseInt(resp.Header().Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		event.Reset = time.Unix(v, 0)
+	}
+	event.RetryAfter = parseRetryAfter(resp.Header(), now)
+	throttled := resp.StatusCode() == 429
+
+	h.mu.Lock()
+	var fire []func(RateLimitEvent)
+	var events []RateLimitEvent
+	for _, l := range h.listeners {
+		switch {
+		case throttled:
+			e := event
+			e.Reason = RateLimitThrottled
+			fire, events = append(fire, l.fn), append(events, e)
+		case event.Remaining >= 0:
+			threshold := l.threshold
+			if threshold == 0 && event.Limit > 0 {
+				threshold = event.Limit / 10
+			}
+			if event.Remaining < threshold {
+				if !l.below {
+					e := event
+					e.Reason = RateLimitLow
+					fire, events = append(fire, l.fn), append(events, e)
+				}
+				l.below = true
+			} else {
+				l.below = false
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	for i, fn := range fire {
+		fn(events[i])
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// requestIDHeader correlates a client call with the server's logs
+const requestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+type requestIDCaptureKey struct{}
+
+// requestIDCapture receives the server's request ID for a call
+type requestIDCapture struct {
+	mu sync.Mutex
+	id string
+}
+
+// WithRequestID makes calls using ctx send id as X-Request-ID instead of a
+// generated one, e.g. to reuse the inbound request's ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID set by WithRequestID
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// CaptureRequestID returns a context that records the request ID of the
+// call made with it, and a function reading it back afterwards:
+//
+//	ctx, requestID := CaptureRequestID(ctx)
+//	cfg, err := client.GetConfigContext(ctx, "app/llm", "model", "production", false)
+//	log.Printf("request %s", requestID())
+//
+// The server's X-Request-ID is preferred; the ID the client sent is used
+// when the server does not echo one.
+func CaptureRequestID(ctx context.Context) (context.Context, func() string) {
+	capture := &requestIDCapture{}
+	return context.WithValue(ctx, requestIDCaptureKey{}, capture), func() string {
+		capture.mu.Lock()
+		defer capture.mu.Unlock()
+		return capture.id
+	}
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// installRequestIDs tags every request with an ID, kept across retries
+func (c *LLMConfigClient) installRequestIDs() {
+	c.httpClient.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if req.Header.Get(requestIDHeader) != "" {
+			return nil
+		}
+		id, ok := RequestIDFromContext(req.Context())
+		if !ok {
+			id = newRequestID()
+		}
+		req.SetHeader(requestIDHeader, id)
+		return nil
+	})
+
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		if capture, ok := resp.Request.Context().Value(requestIDCaptureKey{}).(*requestIDCapture); ok {
+			capture.mu.Lock()
+			capture.id = responseRequestID(resp)
+			capture.mu.Unlock()
+		}
+		return nil
+	})
+}
+
+// responseRequestID is the server's request ID, or the one the client sent
+func responseRequestID(resp *resty.Response) string {
+	if id := resp.Header().Get(requestIDHeader); id != "" {
+		return id
+	}
+	return resp.Request.Header.Get(requestIDHeader)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ChunkingSettings control how documents are split before embedding
+type ChunkingSettings struct {
+	// Strategy is fixed, sentence, paragraph, or markdown
+	Strategy string `json:"strategy"`
+	// Size and Overlap are in tokens
+	Size    int `json:"size"`
+	Overlap int `json:"overlap,omitempty"`
+}
+
+// IndexRef names the vector index a pipeline reads and writes
+type IndexRef struct {
+	// Backend is the vector store, e.g. pgvector, pinecone, qdrant, opensearch
+	Backend string `json:"backend"`
+	Name    string `json:"name"`
+	// Namespace partitions the index where the backend supports it
+	Namespace string `json:"namespace,omitempty"`
+	// Metric is cosine, dot, or euclidean
+	Metric string `json:"metric,omitempty"`
+}
+
+// RerankerSettings configure an optional second-stage reranker
+type RerankerSettings struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// TopN is how many results survive reranking
+	TopN int `json:"top_n"`
+	// MinScore drops results scoring below it
+	MinScore float64 `json:"min_score,omitempty"`
+}
+
+// RetrievalPipeline is the typed config of a RAG pipeline. Overrides holds
+// per-environment changes merged over the base when the pipeline is read,
+// so one key describes every environment:
+//
+//	{"chunking": {"strategy": "markdown", "size": 512, "overlap": 64},
+//	 "embedding": {"provider": "openai", "model": "text-embedding-3-small"},
+//	 "index": {"backend": "pgvector", "name": "docs_dev"},
+//	 "top_k": 8,
+//	 "overrides": {"production": {"index": {"name": "docs_prod"}, "top_k": 12}}}
+type RetrievalPipeline struct {
+	Chunking  ChunkingSettings                  `json:"chunking"`
+	Embedding EmbeddingParams                   `json:"embedding"`
+	Index     IndexRef                          `json:"index"`
+	TopK      int                               `json:"top_k"`
+	Reranker  *RerankerSettings                 `json:"reranker,omitempty"`
+	Overrides map[string]map[string]interface{} `json:"overrides,omitempty"`
+}
+
+// Validate checks chunking bounds, the embedding model, the index, and the
+// reranker against top_k
+func (p RetrievalPipeline) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch p.Chunking.Strategy {
+	case "fixed", "sentence", "paragraph", "markdown":
+	default:
+		fail("/chunking/strategy", "strategy %q is not one of fixed, sentence, paragraph, markdown", p.Chunking.Strategy)
+	}
+	if p.Chunking.Size <= 0 {
+		fail("/chunking/size", "size must be positive")
+	}
+	if p.Chunking.Overlap < 0 || (p.Chunking.Size > 0 && p.Chunking.Overlap >= p.Chunking.Size) {
+		fail("/chunking/overlap", "overlap %d must be in [0, size)", p.Chunking.Overlap)
+	}
+
+	var verr *ValidationError
+	if errors.As(p.Embedding.Validate(), &verr) {
+		for _, problem := range verr.Problems {
+			fail("/embedding"+problem.Path, "%s", problem.Message)
+		}
+	}
+
+	if p.Index.Backend == "" {
+		fail("/index/backend", "backend is required")
+	}
+	if p.Index.Name == "" {
+		fail("/index/name", "name is required")
+	}
+	switch p.Index.Metric {
+	case "", "cosine", "dot", "euclidean":
+	default:
+		fail("/index/metric", "metric %q is not one of cosine, dot, euclidean", p.Index.Metric)
+	}
+
+	if p.TopK <= 0 {
+		fail("/top_k", "top_k must be positive")
+	}
+	if r := p.Reranker; r != nil {
+		if r.Model == "" {
+			fail("/reranker/model", "model is required")
+		}
+		if r.TopN <= 0 || r.TopN > p.TopK {
+			fail("/reranker/top_n", "top_n %d must be in [1, top_k=%d]", r.TopN, p.TopK)
+		}
+	}
+
+	// Every environment must also be valid once its overrides are applied
+	for _, env := range sortedMapKeys(p.Overrides) {
+		resolved, err := p.ForEnvironment(env)
+		if err != nil {
+			fail("/overrides/"+env, "%v", err)
+			continue
+		}
+		if errors.As(resolved.Validate(), &verr) {
+			for _, problem := range verr.Problems {
+				fail("/overrides/"+env+problem.Path, "%s", problem.Message)
+			}
+		}
+	}
+
+	return paramsError(problems)
+}
+
+// ForEnvironment returns the pipeline with env's overrides merged in:
+// objects merge key by key, anything else replaces the base value
+func (p RetrievalPipeline) ForEnvironment(env string) (RetrievalPipeline, error) {
+	override, ok := p.Overrides[env]
+	if !ok {
+		p.Overrides = nil
+		return p, nil
+	}
+
+	p.Overrides = nil
+	data, err := json.Marshal(p)
+	if err != nil {
+		return RetrievalPipeline{}, err
+	}
+	var base map[string]interface{}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return RetrievalPipeline{}, err
+	}
+
+	merged, err := json.Marshal(mergeObjects(base, override))
+	if err != nil {
+		return RetrievalPipeline{}, err
+	}
+	var resolved RetrievalPipeline
+	if err := json.Unmarshal(merged, &resolved); err != nil {
+		return RetrievalPipeline{}, fmt.Errorf("overrides for %s: %w", env, err)
+	}
+	return resolved, nil
+}
+
+// GetRetrievalPipeline reads the pipeline at namespace/key, applies env's
+// overrides, and validates the result
+func (c *LLMConfigClient) GetRetrievalPipeline(ctx context.Context, namespace, key, env string) (*RetrievalPipeline, error) {
+	cfg, err := c.GetConfigContext(ctx, namespace, key, env, true)
+	if err != nil {
+		return nil, fmt.Errorf("read %s/%s: %w", namespace, key, err)
+	}
+
+	data, err := json.Marshal(cfg.Value)
+	if err != nil {
+		return nil, err
+	}
+	var pipeline RetrievalPipeline
+	if err := json.Unmarshal(data, &pipeline); err != nil {
+		return nil, fmt.Errorf("decode %s/%s: %w", namespace, key, err)
+	}
+
+	resolved, err := pipeline.ForEnvironment(env)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", namespace, key, err)
+	}
+	if err := withValidationTarget(resolved.Validate(), namespace, key); err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+// mergeObjects returns base with override applied recursively
+func mergeObjects(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		baseObj, baseIsObj := merged[k].(map[string]interface{})
+		overrideObj, overrideIsObj := v.(map[string]interface{})
+		if baseIsObj && overrideIsObj {
+			merged[k] = mergeObjects(baseObj, overrideObj)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// RolloutStatus is where a canary rollout is in its lifecycle
+type RolloutStatus string
+
+const (
+	RolloutRamping    RolloutStatus = "ramping"
+	RolloutPaused     RolloutStatus = "paused"
+	RolloutCompleted  RolloutStatus = "completed"
+	RolloutRolledBack RolloutStatus = "rolled_back"
+	RolloutAborted    RolloutStatus = "aborted"
+)
+
+// Rollout arms, for ReportRolloutMetrics
+const (
+	RolloutCanary   = "canary"
+	RolloutBaseline = "baseline"
+)
+
+// RolloutStep holds the canary at Percent of traffic for Hold before the
+// controller moves to the next step
+type RolloutStep struct {
+	Percent int
+	Hold    time.Duration
+}
+
+type rolloutStepJSON struct {
+	Percent     int   `json:"percent"`
+	HoldSeconds int64 `json:"hold_seconds"`
+}
+
+// MarshalJSON encodes Hold as whole seconds
+func (s RolloutStep) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rolloutStepJSON{Percent: s.Percent, HoldSeconds: int64(s.Hold / time.Second)})
+}
+
+// UnmarshalJSON decodes the form written by MarshalJSON
+func (s *RolloutStep) UnmarshalJSON(data []byte) error {
+	var raw rolloutStepJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	s.Percent, s.Hold = raw.Percent, time.Duration(raw.HoldSeconds)*time.Second
+	return nil
+}
+
+// RolloutThreshold rolls the canary back when Metric regresses: the canary
+// value exceeds Max, or exceeds the baseline by more than MaxIncrease
+// (0.2 is 20% worse). Zero bounds are not checked.
+type RolloutThreshold struct {
+	Metric      string  `json:"metric"`
+	Max         float64 `json:"max,omitempty"`
+	MaxIncrease float64 `json:"max_increase,omitempty"`
+	// MinSamples is how many reports each arm needs before the threshold
+	// is evaluated
+	MinSamples int `json:"min_samples,omitempty"`
+}
+
+// RolloutSpec describes a canary rollout of a new value for one key:
+//
+//	spec := RolloutSpec{
+//		Namespace: "app/llm", Key: "model", Environment: "production",
+//		Value: "gpt-4o-2024-08-06",
+//		Steps: []RolloutStep{{5, 30 * time.Minute}, {25, time.Hour}, {50, time.Hour}},
+//		Thresholds: []RolloutThreshold{
+//			{Metric: "error_rate", Max: 0.02},
+//			{Metric: "p95_latency_ms", MaxIncrease: 0.25, MinSamples: 100},
+//		},
+//	}
+type RolloutSpec struct {
+	Namespace   string             `json:"namespace"`
+	Key         string             `json:"key"`
+	Environment string             `json:"environment"`
+	Value       interface{}        `json:"value"`
+	Steps       []RolloutStep      `json:"steps"`
+	Thresholds  []RolloutThreshold `json:"thresholds,omitempty"`
+}
+
+// Rollout is the controller's view of a running or finished rollout
+type Rollout struct {
+	ID string `json:"id"`
+	RolloutSpec
+	// BaselineVersion is the version of the key being replaced
+	BaselineVersion int64         `json:"baseline_version"`
+	Status          RolloutStatus `json:"status"`
+	// StatusReason explains pauses and rollbacks, e.g. the threshold that
+	// tripped
+	StatusReason string `json:"status_reason,omitempty"`
+	Step         int    `json:"step"`
+	Percent      int    `json:"percent"`
+	StartedAt    string `json:"started_at"`
+	UpdatedAt    string `json:"updated_at"`
+	StartedBy    string `json:"started_by"`
+}
+
+// Done reports whether the rollout has reached a final status
+func (r *Rollout) Done() bool {
+	switch r.Status {
+	case RolloutCompleted, RolloutRolledBack, RolloutAborted:
+		return true
+	}
+	return false
+}
+
+// InCanary reports whether unitID (a user, session, or tenant ID) should
+// get the canary value at the current percentage. Assignment is sticky:
+// a unit in the canary at 5% stays in it at 25%.
+func (r *Rollout) InCanary(unitID string) bool {
+	if r.Status == RolloutCompleted {
+		return true
+	}
+	if r.Status != RolloutRamping && r.Status != RolloutPaused {
+		return false
+	}
+	return stickyBucket(r.ID, unitID) < r.Percent*experimentBuckets/100
+}
+
+type startRolloutRequest struct {
+	RolloutSpec
+	User string `json:"user"`
+}
+
+type rolloutMetricsRequest struct {
+	Arm     string             `json:"arm"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// Validate checks the steps and thresholds
+func (s RolloutSpec) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Namespace == "" || s.Key == "" {
+		fail("/key", "namespace and key are required")
+	}
+	if len(s.Steps) == 0 {
+		fail("/steps", "a rollout needs at least one step")
+	}
+	previous := 0
+	for i, step := range s.Steps {
+		if step.Percent <= previous || step.Percent > 100 {
+			fail(fmt.Sprintf("/steps/%d/percent", i), "percent %d must increase from %d and be at most 100", step.Percent, previous)
+		}
+		if step.Hold < 0 {
+			fail(fmt.Sprintf("/steps/%d/hold_seconds", i), "hold must not be negative")
+		}
+		previous = step.Percent
+	}
+	for i, t := range s.Thresholds {
+		if t.Metric == "" {
+			fail(fmt.Sprintf("/thresholds/%d/metric", i), "metric is required")
+		}
+		if t.Max == 0 && t.MaxIncrease == 0 {
+			fail(fmt.Sprintf("/thresholds/%d", i), "set max or max_increase")
+		}
+	}
+
+	return paramsError(problems)
+}
+
+// StartRollout begins a canary rollout of spec.Value. The controller ramps
+// through the steps, evaluating thresholds against reported metrics, and
+// rolls back to the baseline version on a regression.
+func (c *LLMConfigClient) StartRollout(ctx context.Context, spec RolloutSpec, user string) (*Rollout, error) {
+	if err := withValidationTarget(spec.Validate(), spec.Namespace, spec.Key); err != nil {
+		return nil, err
+	}
+
+	var result Rollout
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(startRolloutRequest{RolloutSpec: spec, User: user}).
+		SetResult(&result).
+		Post("/rollouts")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetRollout fetches a rollout by ID
+func (c *LLMConfigClient) GetRollout(ctx context.Context, id string) (*Rollout, error) {
+	var result Rollout
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/rollouts/%s", url.PathEscape(id)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListRollouts returns the rollouts in namespace, newest first; an empty
+// namespace lists all
+func (c *LLMConfigClient) ListRollouts(ctx context.Context, namespace string) ([]Rollout, error) {
+	var result []Rollout
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if namespace != "" {
+		req.SetQueryParam("namespace", namespace)
+	}
+
+	resp, err := req.Get("/rollouts")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// PauseRollout holds the canary at its current percentage
+func (c *LLMConfigClient) PauseRollout(ctx context.Context, id, user string) (*Rollout, error) {
+	return c.rolloutAction(ctx, id, "pause", user)
+}
+
+// ResumeRollout continues a paused rollout from its current step
+func (c *LLMConfigClient) ResumeRollout(ctx context.Context, id, user string) (*Rollout, error) {
+	return c.rolloutAction(ctx, id, "resume", user)
+}
+
+// PromoteRollout skips the remaining steps and makes the canary value the
+// key's value for everyone
+func (c *LLMConfigClient) PromoteRollout(ctx context.Context, id, user string) (*Rollout, error) {
+	return c.rolloutAction(ctx, id, "promote", user)
+}
+
+// AbortRollout stops the rollout and restores the baseline version
+func (c *LLMConfigClient) AbortRollout(ctx context.Context, id, user string) (*Rollout, error) {
+	return c.rolloutAction(ctx, id, "abort", user)
+}
+
+func (c *LLMConfigClient) rolloutAction(ctx context.Context, id, action, user string) (*Rollout, error) {
+	var result Rollout
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(map[string]string{"user": user}).
+		SetResult(&result).
+		Post(fmt.Sprintf("/rollouts/%s/%s", url.PathEscape(id), action))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ReportRolloutMetrics sends health observations for one arm
+// (RolloutCanary or RolloutBaseline), e.g. {"error_rate": 0.004,
+// "p95_latency_ms": 820}. The controller aggregates reports from every
+// instance before evaluating thresholds.
+func (c *LLMConfigClient) ReportRolloutMetrics(ctx context.Context, id, arm string, metrics map[string]float64) error {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(rolloutMetricsRequest{Arm: arm, Metrics: metrics}).
+		Post(fmt.Sprintf("/rollouts/%s/metrics", url.PathEscape(id)))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// WatchRollout polls a rollout every interval (10s when zero) and delivers
+// it each time its status or percentage changes, starting with its current
+// state. The channel is closed once the rollout is done or ctx ends.
+func (c *LLMConfigClient) WatchRollout(ctx context.Context, id string, interval time.Duration) (<-chan Rollout, error) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	current, err := c.GetRollout(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("watch rollout %s: %w", id, err)
+	}
+
+	updates := make(chan Rollout, 1)
+	updates <- *current
+	go func() {
+		defer close(updates)
+		last := *current
+		for !last.Done() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.clock.After(interval):
+			}
+
+			r, err := c.GetRollout(ctx, id)
+			if err != nil {
+				continue
+			}
+			if r.Status == last.Status && r.Percent == last.Percent {
+				continue
+			}
+			select {
+			case updates <- *r:
+			case <-ctx.Done():
+				return
+			}
+			last = *r
+		}
+	}()
+	return updates, nil
+}
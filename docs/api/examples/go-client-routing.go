@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+)
+
+// ErrNoRoute is returned by Resolve when no rule matches and there is no
+// default
+var ErrNoRoute = errors.New("no routing rule matches")
+
+// RoutingRequest describes a call to be routed
+type RoutingRequest struct {
+	Task        string
+	Tags        []string
+	InputTokens int
+}
+
+// RoutingMatch is the condition of a rule; empty fields match anything
+type RoutingMatch struct {
+	// Tasks are glob patterns ("summarize", "extract-*"); any may match
+	Tasks []string `json:"tasks,omitempty"`
+	// Tags must all be present on the request
+	Tags []string `json:"tags,omitempty"`
+	// MinInputTokens and MaxInputTokens bound the request length; zero is
+	// unbounded
+	MinInputTokens int `json:"min_input_tokens,omitempty"`
+	MaxInputTokens int `json:"max_input_tokens,omitempty"`
+}
+
+// RoutingTarget is where a matched request goes
+type RoutingTarget struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model"`
+}
+
+// RoutingRule sends requests matching Match to its target
+type RoutingRule struct {
+	Name  string       `json:"name"`
+	Match RoutingMatch `json:"match"`
+	RoutingTarget
+}
+
+// RoutingRules route requests to models. Rules are tried in order and the
+// first match wins:
+//
+//	{"rules": [
+//	   {"name": "long-context", "match": {"min_input_tokens": 100000}, "model": "gemini-1.5-pro"},
+//	   {"name": "code", "match": {"tasks": ["codegen", "review-*"]}, "model": "claude-3-5-sonnet"},
+//	   {"name": "cheap-extract", "match": {"tasks": ["extract-*"], "tags": ["batch"]}, "model": "gpt-4o-mini"}],
+//	 "default": {"model": "gpt-4o"}}
+type RoutingRules struct {
+	Rules   []RoutingRule  `json:"rules"`
+	Default *RoutingTarget `json:"default,omitempty"`
+}
+
+// RoutingDecision is the result of Resolve
+type RoutingDecision struct {
+	RoutingTarget
+	// Rule is the name of the matching rule, empty for the default
+	Rule string
+}
+
+// Validate checks rule names, task patterns, token bounds, and that every
+// rule is reachable
+func (r RoutingRules) Validate() error {
+	var problems []ValidationProblem
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]bool{}
+	catchAll := ""
+	for i, rule := range r.Rules {
+		at := fmt.Sprintf("/rules/%d", i)
+		switch {
+		case rule.Name == "":
+			fail(at+"/name", "name is required")
+		case seen[rule.Name]:
+			fail(at+"/name", "duplicate rule name %q", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		if rule.Model == "" {
+			fail(at+"/model", "model is required")
+		}
+		for j, pattern := range rule.Match.Tasks {
+			if _, err := path.Match(pattern, ""); err != nil {
+				fail(fmt.Sprintf("%s/match/tasks/%d", at, j), "invalid pattern %q", pattern)
+			}
+		}
+		m := rule.Match
+		if m.MinInputTokens < 0 || m.MaxInputTokens < 0 {
+			fail(at+"/match", "token bounds must not be negative")
+		}
+		if m.MaxInputTokens > 0 && m.MinInputTokens > m.MaxInputTokens {
+			fail(at+"/match", "min_input_tokens %d exceeds max_input_tokens %d", m.MinInputTokens, m.MaxInputTokens)
+		}
+
+		if catchAll != "" {
+			fail(at, "unreachable: rule %q before it matches every request", catchAll)
+		}
+		if len(m.Tasks) == 0 && len(m.Tags) == 0 && m.MinInputTokens == 0 && m.MaxInputTokens == 0 {
+			catchAll = rule.Name
+		}
+	}
+
+	if r.Default != nil && r.Default.Model == "" {
+		fail("/default/model", "model is required")
+	}
+
+	return paramsError(problems)
+}
+
+// Matches reports whether req satisfies the condition
+func (m RoutingMatch) Matches(req RoutingRequest) bool {
+	if len(m.Tasks) > 0 && !slices.ContainsFunc(m.Tasks, func(pattern string) bool {
+		ok, _ := path.Match(pattern, req.Task)
+		return ok
+	}) {
+		return false
+	}
+	for _, tag := range m.Tags {
+		if !slices.Contains(req.Tags, tag) {
+			return false
+		}
+	}
+	if req.InputTokens < m.MinInputTokens {
+		return false
+	}
+	return m.MaxInputTokens == 0 || req.InputTokens <= m.MaxInputTokens
+}
+
+// Resolve returns the target of the first rule matching req, or the
+// default. It returns an error matching ErrNoRoute when neither applies.
+func (r *RoutingRules) Resolve(req RoutingRequest) (RoutingDecision, error) {
+	for _, rule := range r.Rules {
+		if rule.Match.Matches(req) {
+			return RoutingDecision{RoutingTarget: rule.RoutingTarget, Rule: rule.Name}, nil
+		}
+	}
+	if r.Default != nil {
+		return RoutingDecision{RoutingTarget: *r.Default}, nil
+	}
+	return RoutingDecision{}, fmt.Errorf("%w: task %q, tags %v", ErrNoRoute, req.Task, req.Tags)
+}
+
+// GetRoutingRules reads and validates the routing rules at namespace/key
+func (c *LLMConfigClient) GetRoutingRules(ctx context.Context, namespace, key, env string) (*RoutingRules, error) {
+	var rules RoutingRules
+	if err := c.GetParams(ctx, namespace, key, env, &rules); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
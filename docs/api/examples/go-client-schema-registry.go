@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// CompatibilityMode controls which schema changes the registry accepts
+type CompatibilityMode string
+
+const (
+	// CompatibilityNone accepts any new schema version
+	CompatibilityNone CompatibilityMode = "NONE"
+	// CompatibilityBackward requires new schemas to read values written
+	// under the previous version
+	CompatibilityBackward CompatibilityMode = "BACKWARD"
+	// CompatibilityForward requires the previous schema to read values
+	// written under the new version
+	CompatibilityForward CompatibilityMode = "FORWARD"
+	// CompatibilityFull requires both backward and forward compatibility
+	CompatibilityFull CompatibilityMode = "FULL"
+)
+
+// SchemaVersion is one published version of a schema subject
+type SchemaVersion struct {
+	Subject       string            `json:"subject"`
+	Version       int64             `json:"version"`
+	Schema        json.RawMessage   `json:"schema"`
+	Compatibility CompatibilityMode `json:"compatibility"`
+	CreatedAt     string            `json:"created_at"`
+	CreatedBy     string            `json:"created_by"`
+}
+
+// SchemaBinding links a config key to a schema subject version
+type SchemaBinding struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Subject   string `json:"subject"`
+	// Version is 0 to always track the latest version of Subject
+	Version int64 `json:"version"`
+}
+
+type publishSchemaRequest struct {
+	Schema json.RawMessage `json:"schema"`
+	User   string          `json:"user"`
+}
+
+type compatibilityRequest struct {
+	Compatibility CompatibilityMode `json:"compatibility"`
+}
+
+// PublishSchema registers a new version of subject. The server rejects
+// versions that violate the subject's compatibility mode.
+func (c *LLMConfigClient) PublishSchema(ctx context.Context, subject string, schema []byte, user string) (*SchemaVersion, error) {
+	var result SchemaVersion
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(publishSchemaRequest{Schema: schema, User: user}).
+		SetResult(&result).
+		Post(fmt.Sprintf("/schemas/%s/versions", url.PathEscape(subject)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListSchemaVersions returns every version of subject, oldest first
+func (c *LLMConfigClient) ListSchemaVersions(ctx context.Context, subject string) ([]SchemaVersion, error) {
+	var result []SchemaVersion
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/schemas/%s/versions", url.PathEscape(subject)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return []SchemaVersion{}, nil
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// GetSchemaVersion fetches one version of subject; version 0 means latest
+func (c *LLMConfigClient) GetSchemaVersion(ctx context.Context, subject string, version int64) (*SchemaVersion, error) {
+	var result SchemaVersion
+
+	ref := "latest"
+	if version > 0 {
+		ref = fmt.Sprintf("%d", version)
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/schemas/%s/versions/%s", url.PathEscape(subject), ref))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// SetSchemaCompatibility changes the compatibility mode of subject
+func (c *LLMConfigClient) SetSchemaCompatibility(ctx context.Context, subject string, mode CompatibilityMode) error {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(compatibilityRequest{Compatibility: mode}).
+		Put(fmt.Sprintf("/schemas/%s/compatibility", url.PathEscape(subject)))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// BindSchema binds a config key to a schema subject (version 0 tracks latest)
+func (c *LLMConfigClient) BindSchema(ctx context.Context, binding SchemaBinding) error {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(binding).
+		Put(keyRoute(binding.Namespace, binding.Key, "schema"))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// GetBoundSchema returns the schema version bound to a key, or nil when the
+// key has no binding
+func (c *LLMConfigClient) GetBoundSchema(ctx context.Context, namespace, key string) (*SchemaVersion, error) {
+	var result SchemaVersion
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(keyRoute(namespace, key, "schema"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		if resp.StatusCode() == 404 {
+			return nil, nil
+		}
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// UseBoundSchema fetches the schema bound to a key and registers it for local
+// validation in the organization of ctx, so SetConfig rejects invalid values
+// before they reach the server. It reports whether a binding was found.
+func (c *LLMConfigClient) UseBoundSchema(ctx context.Context, namespace, key string) (bool, error) {
+	bound, err := c.GetBoundSchema(ctx, namespace, key)
+	if err != nil || bound == nil {
+		return false, err
+	}
+	if err := c.RegisterSchemaContext(ctx, namespace, key, bound.Schema); err != nil {
+		return false, fmt.Errorf("schema %s v%d: %w", bound.Subject, bound.Version, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ValidationProblem is a single failed check at a JSON pointer inside a value
+type ValidationProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a value is rejected before being sent
+type ValidationError struct {
+	Namespace string
+	Key       string
+	Problems  []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		location := p.Path
+		if location == "" {
+			location = "/"
+		}
+		msgs[i] = location + ": " + p.Message
+	}
+	if e.Namespace == "" && e.Key == "" {
+		return "validation failed: " + strings.Join(msgs, "; ")
+	}
+	return fmt.Sprintf("validation failed for %s/%s: %s", e.Namespace, e.Key, strings.Join(msgs, "; "))
+}
+
+// schemaBinding attaches a compiled schema to namespace/key glob patterns
+// within an organization scope; an empty organization applies to every
+// organization
+type schemaBinding struct {
+	organization     string
+	namespacePattern string
+	keyPattern       string
+	schema           *jsonschema.Schema
+}
+
+// schemaRegistry holds the client-side JSON Schemas consulted on write
+type schemaRegistry struct {
+	mu       sync.RWMutex
+	bindings []schemaBinding
+	seq      int
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{}
+}
+
+// RegisterSchema attaches a JSON Schema to every key matching keyPattern in
+// namespaces matching namespacePattern (path.Match globs, e.g. "app/*" and
+// "*_params"), in every organization. SetConfig validates values locally
+// against every matching schema before sending them.
+func (c *LLMConfigClient) RegisterSchema(namespacePattern, keyPattern string, schema []byte) error {
+	return c.registerSchema("", namespacePattern, keyPattern, schema)
+}
+
+// RegisterSchemaContext is RegisterSchema for writes made in the
+// organization of ctx only
+func (c *LLMConfigClient) RegisterSchemaContext(ctx context.Context, namespacePattern, keyPattern string, schema []byte) error {
+	return c.registerSchema(c.organizationScopeID(ctx), namespacePattern, keyPattern, schema)
+}
+
+func (c *LLMConfigClient) registerSchema(organization, namespacePattern, keyPattern string, schema []byte) error {
+	if _, err := path.Match(namespacePattern, ""); err != nil {
+		return fmt.Errorf("invalid namespace pattern %q: %w", namespacePattern, err)
+	}
+	if _, err := path.Match(keyPattern, ""); err != nil {
+		return fmt.Errorf("invalid key pattern %q: %w", keyPattern, err)
+	}
+
+	compiled, err := compileSchema(schema, c.schemas.nextURL())
+	if err != nil {
+		return err
+	}
+
+	c.schemas.mu.Lock()
+	defer c.schemas.mu.Unlock()
+	c.schemas.bindings = append(c.schemas.bindings, schemaBinding{
+		organization:     organization,
+		namespacePattern: namespacePattern,
+		keyPattern:       keyPattern,
+		schema:           compiled,
+	})
+	return nil
+}
+
+// ValidateValue checks value against every schema registered for
+// namespace/key, returning a *ValidationError listing each failing path.
+// Schemas registered for an organization apply when it is the client's
+// default.
+func (c *LLMConfigClient) ValidateValue(namespace, key string, value interface{}) error {
+	return c.ValidateValueContext(context.Background(), namespace, key, value)
+}
+
+// ValidateValueContext is ValidateValue in the organization of ctx
+func (c *LLMConfigClient) ValidateValueContext(ctx context.Context, namespace, key string, value interface{}) error {
+	organization := c.organizationScopeID(ctx)
+	c.schemas.mu.RLock()
+	var matched []*jsonschema.Schema
+	for _, b := range c.schemas.bindings {
+		if b.organization != "" && b.organization != organization {
+			continue
+		}
+		nsOK, _ := path.Match(b.namespacePattern, namespace)
+		keyOK, _ := path.Match(b.keyPattern, key)
+		if nsOK && keyOK {
+			matched = append(matched, b.schema)
+		}
+	}
+	c.schemas.mu.RUnlock()
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	instance, err := toSchemaInstance(value)
+	if err != nil {
+		return &ValidationError{Namespace: namespace, Key: key, Problems: []ValidationProblem{{Path: "", Message: err.Error()}}}
+	}
+
+	var problems []ValidationProblem
+	for _, schema := range matched {
+		problems = append(problems, schemaProblems(schema.Validate(instance))...)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Namespace: namespace, Key: key, Problems: problems}
+	}
+	return nil
+}
+
+// validateWrite runs every client-side check that guards the write path:
+// registered JSON Schemas, declarative constraints, custom validators, then
+// cross-key dependency rules
+func (c *LLMConfigClient) validateWrite(ctx context.Context, namespace, key string, req SetConfigRequest) error {
+	var problems []ValidationProblem
+	if err := c.ValidateValueContext(ctx, namespace, key, req.Value); err != nil {
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			return err
+		}
+		problems = append(problems, verr.Problems...)
+	}
+	problems = append(problems, c.checkConstraints(ctx, namespace, key, req.Value)...)
+
+	customProblems, err := c.runValidators(ctx, namespace, key, req)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, customProblems...)
+
+	dependencyProblems, err := c.checkDependencies(ctx, namespace, key, req)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, dependencyProblems...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Namespace: namespace, Key: key, Problems: problems}
+	}
+	return nil
+}
+
+func (r *schemaRegistry) nextURL() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	return fmt.Sprintf("mem://llm-config-manager/schema-%d.json", r.seq)
+}
+
+// compileSchema compiles a JSON Schema document registered under url
+func compileSchema(schema []byte, url string) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, fmt.Errorf("load schema: %w", err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("compile schema: %w", err)
+	}
+	return compiled, nil
+}
+
+// toSchemaInstance round-trips a Go value through JSON so it has the shape
+// the validator expects (json.Number, map[string]any, []any)
+func toSchemaInstance(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not JSON-encodable: %w", err)
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+}
+
+// schemaProblems flattens a validation error into path-level problems
+func schemaProblems(err error) []ValidationProblem {
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []ValidationProblem{{Message: err.Error()}}
+	}
+
+	var problems []ValidationProblem
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		problems = append(problems, ValidationProblem{
+			Path:    unit.InstanceLocation,
+			Message: unit.Error.String(),
+		})
+	}
+	if len(problems) == 0 {
+		problems = append(problems, ValidationProblem{Message: verr.Error()})
+	}
+	return problems
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// secretKeySize is the AES-256 key length
+const secretKeySize = 32
+
+// secretAlgorithm is the only algorithm EncryptedValue supports
+const secretAlgorithm = "aes-256-gcm"
+
+// SecretKey is a 256-bit key for client-side secret encryption
+type SecretKey []byte
+
+// ParseSecretKey reads a key in hex or standard base64, the encodings the
+// server's crypto crate accepts
+func ParseSecretKey(s string) (SecretKey, error) {
+	s = strings.TrimSpace(s)
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return nil, errors.New("secret key must be hex or base64")
+	}
+	if len(key) != secretKeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, got %d", secretKeySize, len(key))
+	}
+	return key, nil
+}
+
+// EncryptedValue is a client-side encrypted secret. Its JSON form matches
+// the server's EncryptedData, so either side can decrypt with the key.
+type EncryptedValue struct {
+	Algorithm  string `json:"algorithm"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+	KeyVersion int    `json:"key_version"`
+	// AADContext is authenticated with the ciphertext; EncryptSecret binds
+	// it to the config's location so the value can't be copied elsewhere
+	AADContext string `json:"aad_context,omitempty"`
+}
+
+// secretAAD is the additional authenticated data for a secret's location
+func secretAAD(namespace, key, env string) string {
+	return fmt.Sprintf("%s/%s@%s", namespace, key, env)
+}
+
+// EncryptSecret seals plaintext with AES-256-GCM, authenticating aad
+func EncryptSecret(key SecretKey, plaintext []byte, aad string) (*EncryptedValue, error) {
+	gcm, err := newSecretGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &EncryptedValue{
+		Algorithm:  secretAlgorithm,
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(gcm.Seal(nil, nonce, plaintext, []byte(aad))),
+		KeyVersion: 1,
+		AADContext: aad,
+	}, nil
+}
+
+// DecryptSecret opens a value sealed by EncryptSecret or the server
+func DecryptSecret(key SecretKey, v *EncryptedValue) ([]byte, error) {
+	if v.Algorithm != secretAlgorithm {
+		return nil, fmt.Errorf("unsupported secret algorithm %q", v.Algorithm)
+	}
+	gcm, err := newSecretGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(v.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("decrypt secret: malformed nonce")
+	}
+	ciphertext, err := hex.DecodeString(v.Ciphertext)
+	if err != nil {
+		return nil, errors.New("decrypt secret: malformed ciphertext")
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(v.AADContext))
+	if err != nil {
+		return nil, errors.New("decrypt secret: wrong key or tampered value")
+	}
+	return plaintext, nil
+}
+
+func newSecretGCM(key SecretKey) (cipher.AEAD, error) {
+	if len(key) != secretKeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, got %d", secretKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedValueOf returns v as an EncryptedValue when it has that shape
+func encryptedValueOf(v interface{}) (*EncryptedValue, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok || obj["algorithm"] == nil || obj["ciphertext"] == nil {
+		return nil, false
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, false
+	}
+	var enc EncryptedValue
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return nil, false
+	}
+	return &enc, true
+}
+
+// RevealedSecret is the unmasked value of a secret config
+type RevealedSecret struct {
+	Value   interface{} `json:"value"`
+	Version int64       `json:"version"`
+	// ClientEncrypted reports that Value was an EncryptedValue that
+	// RevealSecret decrypted with the caller's key
+	ClientEncrypted bool `json:"-"`
+}
+
+// RevealSecret reads a secret without the API's masking; the token needs
+// permission to read secrets in namespace. Values stored with
+// SetClientEncryptedSecret are decrypted with key, which may be nil
+// otherwise.
+func (c *LLMConfigClient) RevealSecret(ctx context.Context, namespace, key, env string, secretKey SecretKey) (*RevealedSecret, error) {
+	var result RevealedSecret
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("env", env).
+		SetResult(&result).
+		Get(keyRoute(namespace, key, "secret"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	if enc, ok := encryptedValueOf(result.Value); ok {
+		if secretKey == nil {
+			return nil, fmt.Errorf("%s/%s is encrypted client-side; a secret key is required", namespace, key)
+		}
+		plaintext, err := DecryptSecret(secretKey, enc)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", namespace, key, err)
+		}
+		result.Value, result.ClientEncrypted = string(plaintext), true
+	}
+	c.consumption.recordSecret(namespace, key, env, result.Version)
+	return &result, nil
+}
+
+// SetClientEncryptedSecret encrypts plaintext with key before sending it, so
+// the server only ever stores ciphertext bound to namespace/key@env
+func (c *LLMConfigClient) SetClientEncryptedSecret(ctx context.Context, namespace, key, env, user, plaintext string, secretKey SecretKey) (*ConfigResponse, error) {
+	enc, err := EncryptSecret(secretKey, []byte(plaintext), secretAAD(namespace, key, env))
+	if err != nil {
+		return nil, fmt.Errorf("encrypt %s/%s: %w", namespace, key, err)
+	}
+	return c.SetConfigContext(ctx, namespace, key, enc, env, user, true)
+}
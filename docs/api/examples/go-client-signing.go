@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// ErrSignatureInvalid means a snapshot's signature is missing or does not
+// match the snapshot and key
+var ErrSignatureInvalid = errors.New("signature invalid")
+
+// signatureSuffix names the signature file of a manifest at apply time
+const signatureSuffix = ".sig"
+
+// LoadSnapshotSigner reads a private key to sign snapshots with: a key from
+// `cosign generate-key-pair`, decrypted with password ($COSIGN_PASSWORD in
+// cosign's tools), or an unencrypted PEM ECDSA, RSA, or Ed25519 key
+func LoadSnapshotSigner(path string, password []byte) (signature.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := cryptoutils.UnmarshalPEMToPrivateKey(data, cryptoutils.StaticPasswordFunc(password))
+	if err != nil {
+		return nil, fmt.Errorf("load signing key %s: %w", path, err)
+	}
+	signer, err := signature.LoadSigner(key, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("load signing key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// LoadSnapshotVerifier reads the PEM public key snapshots are verified
+// with, e.g. cosign.pub
+func LoadSnapshotVerifier(path string) (signature.Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := cryptoutils.UnmarshalPEMToPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("load verification key %s: %w", path, err)
+	}
+	verifier, err := signature.LoadVerifier(key, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("load verification key %s: %w", path, err)
+	}
+	return verifier, nil
+}
+
+// SignSnapshot signs an exported snapshot, backup archive, or manifest and
+// returns the base64 signature, as `cosign sign-blob --key` prints it, so
+// either tool verifies the other's signatures:
+//
+//	cosign verify-blob --key cosign.pub --signature app-llm.yaml.sig app-llm.yaml
+func SignSnapshot(signer signature.Signer, data []byte) (string, error) {
+	sig, err := signer.SignMessage(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("sign snapshot: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifySnapshot checks a signature of SignSnapshot or `cosign sign-blob`
+// over data, failing with ErrSignatureInvalid
+func VerifySnapshot(verifier signature.Verifier, data []byte, sig string) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil || len(raw) == 0 {
+		return fmt.Errorf("signature is not base64: %w", ErrSignatureInvalid)
+	}
+	if err := verifier.VerifySignature(bytes.NewReader(raw), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("%v: %w", err, ErrSignatureInvalid)
+	}
+	return nil
+}
+
+// LoadSignedManifests is LoadManifests for promotion pipelines: every
+// manifest file must have a signature of verifier's key beside it, in
+// <file>.sig, or nothing is loaded. Signing the reviewed files with
+// `cosign sign-blob --key cosign.key --output-signature FILE.sig FILE`
+// proves what is applied is what was reviewed.
+func LoadSignedManifests(dir string, verifier signature.Verifier) ([]Manifest, error) {
+	return loadManifests(dir, func(path string, data []byte) error {
+		sig, err := os.ReadFile(path + signatureSuffix)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s has no signature %s%s: %w", path, path, signatureSuffix, ErrSignatureInvalid)
+		}
+		if err != nil {
+			return err
+		}
+		if err := VerifySnapshot(verifier, data, string(sig)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	})
+}
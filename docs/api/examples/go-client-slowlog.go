@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// slowRequestLog holds the runtime-adjustable slow-request threshold
+type slowRequestLog struct {
+	threshold atomic.Int64
+	count     atomic.Int64
+}
+
+// installSlowRequests logs and counts attempts over the threshold of
+// SetSlowRequestThreshold
+func (c *LLMConfigClient) installSlowRequests() {
+	basePath := baseURLPath(c.baseURL)
+	c.httpClient.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		limit := time.Duration(c.slowRequests.threshold.Load())
+		if limit <= 0 || resp.Time() <= limit {
+			return nil
+		}
+		c.slowRequests.count.Add(1)
+
+		route, _ := configRouteAttributes(trimBasePath(resp.Request.RawRequest.URL.Path, basePath), nil)
+		c.metrics.Load().observeSlowRequest(resp.Request.Method, route)
+		log.Printf("llm-config: slow request [%s] %s %s took %s (threshold %s, attempt %d, status %d)",
+			resp.Request.Header.Get(requestIDHeader), resp.Request.Method, resp.Request.URL,
+			resp.Time().Round(time.Millisecond), limit, resp.Request.Attempt, resp.StatusCode())
+		return nil
+	})
+}
+
+// SetSlowRequestThreshold logs and counts every request attempt that takes
+// longer than threshold, including which retry attempt it was. Zero turns
+// it off. Counts are exported as llm_config_client_slow_requests_total when
+// metrics are enabled, and via SlowRequestCount.
+func (c *LLMConfigClient) SetSlowRequestThreshold(threshold time.Duration) {
+	c.slowRequests.threshold.Store(int64(threshold))
+}
+
+// SlowRequestCount is the number of slow requests seen so far
+func (c *LLMConfigClient) SlowRequestCount() int64 {
+	return c.slowRequests.count.Load()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// spendWindow is the span a namespace's spend is summed over and
+	// compared with its baseline
+	spendWindow = 5 * time.Minute
+	// spendBaselineWindows is how many windows before the current one make
+	// up the baseline: the trailing hour
+	spendBaselineWindows = 12
+	// spendMinHistory is how many windows must have passed since a
+	// namespace's first spend before spikes are reported
+	spendMinHistory = 3
+)
+
+// spendBaselineFloor is the least a window's baseline is taken to be, so
+// spend after a quiet hour is measured against it rather than never
+// reported, and a few stray tokens do not count as a spike
+var spendBaselineFloor = Spend{Tokens: 1000, Cost: 0.01}
+
+// SpendAnomaly reports a window whose spend passed its budget's
+// AnomalyFactor times the trailing baseline, e.g. after a model change to
+// a pricier model or a prompt change that loops
+type SpendAnomaly struct {
+	// Organization is the "org/project" the spend was recorded for, empty
+	// when unscoped
+	Organization string    `json:"organization,omitempty"`
+	Namespace    string    `json:"namespace"`
+	Start        time.Time `json:"window_start"`
+	Window       string    `json:"window"`
+	// Spend is the window's spend so far
+	Spend Spend `json:"spend"`
+	// Baseline is the mean spend of the windows of the trailing hour
+	Baseline Spend `json:"baseline"`
+	// Factor is how many times the baseline, or spendBaselineFloor when
+	// higher, was spent, by whichever of tokens or cost rose further
+	Factor    float64     `json:"factor"`
+	Threshold float64     `json:"threshold"`
+	Usage     BudgetUsage `json:"usage"`
+}
+
+type spendAnomalies struct {
+	mu        sync.Mutex
+	listeners []func(SpendAnomaly)
+	series    map[spendSeriesKey]*spendSeries
+}
+
+// spendSeriesKey tells apart the same namespace in different
+// organizations
+type spendSeriesKey struct {
+	organization, namespace string
+}
+
+// spendSeries is a namespace's recent spend per window
+type spendSeries struct {
+	first   time.Time
+	start   time.Time
+	current Spend
+	// history holds the windows before current, oldest first
+	history []Spend
+	// reported is set once current has been reported
+	reported bool
+}
+
+// OnSpendAnomaly calls fn when RecordSpend sees a namespace's spend spike.
+// Spend is summed over 5-minute windows; once a window passes its
+// budget's AnomalyFactor times the mean of the hour before it, fn is
+// called, at most once per window, so a misconfigured model or runaway
+// prompt is caught within minutes. A mean under 1,000 tokens or $0.01 is
+// taken as that much, so spend after an idle hour is still compared with
+// something. Spend is tracked per organization and namespace, in this
+// client only, so record it through one client per process. Without a
+// listener, anomalies are logged.
+func (c *LLMConfigClient) OnSpendAnomaly(fn func(SpendAnomaly)) {
+	c.anomalies.mu.Lock()
+	defer c.anomalies.mu.Unlock()
+	c.anomalies.listeners = append(c.anomalies.listeners, fn)
+}
+
+// SpendAnomalyWebhook returns an OnSpendAnomaly listener that POSTs each
+// anomaly as JSON to url, signed like validation webhooks with
+// X-LLM-Config-Signature when secret is set. Deliveries run in the
+// background and failures are logged.
+func SpendAnomalyWebhook(url, secret string) func(SpendAnomaly) {
+	return func(anomaly SpendAnomaly) {
+		body, err := json.Marshal(anomaly)
+		if err != nil {
+			log.Printf("llm-config: spend anomaly webhook: %v", err)
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+			if err != nil {
+				log.Printf("llm-config: spend anomaly webhook: %v", err)
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if secret != "" {
+				req.Header.Set(validationSignatureHeader, SignWebhookBody(secret, body))
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Printf("llm-config: spend anomaly webhook: %v", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				log.Printf("llm-config: spend anomaly webhook: %s", resp.Status)
+			}
+		}()
+	}
+}
+
+// detectSpendAnomaly adds spend to the series of namespace in the
+// organization of ctx and reports its window if it spiked
+func (c *LLMConfigClient) detectSpendAnomaly(ctx context.Context, namespace string, spend Spend, usage *BudgetUsage) {
+	threshold := usage.Budget.AnomalyFactor
+	if threshold <= 1 {
+		return
+	}
+	organization := c.organizationScopeID(ctx)
+	now := c.clock.Now()
+	start := now.Truncate(spendWindow)
+
+	a := c.anomalies
+	a.mu.Lock()
+	key := spendSeriesKey{organization, namespace}
+	s := a.series[key]
+	if s == nil {
+		s = &spendSeries{first: start, start: start}
+		a.series[key] = s
+	}
+	if start.After(s.start) {
+		s.history = append(s.history, s.current)
+		// Windows without spend count toward the baseline as zero
+		for t := s.start.Add(spendWindow); t.Before(start) && len(s.history) <= spendBaselineWindows; t = t.Add(spendWindow) {
+			s.history = append(s.history, Spend{})
+		}
+		if n := len(s.history); n > spendBaselineWindows {
+			s.history = slices.Clone(s.history[n-spendBaselineWindows:])
+		}
+		s.start, s.current, s.reported = start, Spend{}, false
+	}
+	s.current.Tokens += spend.Tokens
+	s.current.Cost += spend.Cost
+
+	var anomaly *SpendAnomaly
+	if !s.reported && start.Sub(s.first) >= spendMinHistory*spendWindow {
+		var baseline Spend
+		for _, w := range s.history {
+			baseline.Tokens += w.Tokens
+			baseline.Cost += w.Cost
+		}
+		n := float64(len(s.history))
+		mean := Spend{Tokens: int64(float64(baseline.Tokens) / n), Cost: baseline.Cost / n}
+
+		factor := max(
+			float64(s.current.Tokens)/max(float64(baseline.Tokens)/n, float64(spendBaselineFloor.Tokens)),
+			s.current.Cost/max(mean.Cost, spendBaselineFloor.Cost),
+		)
+		if factor > threshold {
+			s.reported = true
+			anomaly = &SpendAnomaly{
+				Organization: organization,
+				Namespace:    namespace,
+				Start:        start,
+				Window:       spendWindow.String(),
+				Spend:        s.current,
+				Baseline:     mean,
+				Factor:       factor,
+				Threshold:    threshold,
+				Usage:        *usage,
+			}
+		}
+	}
+	listeners := slices.Clone(a.listeners)
+	a.mu.Unlock()
+
+	if anomaly == nil {
+		return
+	}
+	if len(listeners) == 0 {
+		log.Printf("llm-config: %s spent %s in the %s window from %s, %.1fx its hourly baseline (anomaly factor %g)",
+			namespace, formatSpend(anomaly.Spend), spendWindow, start.Format(time.Kitchen), anomaly.Factor, threshold)
+	}
+	for _, fn := range listeners {
+		fn(*anomaly)
+	}
+}
+
+// formatSpend prints spend as tokens and, when priced, dollars
+func formatSpend(s Spend) string {
+	if s.Cost == 0 {
+		return fmt.Sprintf("%d tokens", s.Tokens)
+	}
+	return fmt.Sprintf("%d tokens ($%.2f)", s.Tokens, s.Cost)
+}
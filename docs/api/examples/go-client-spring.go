@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SpringEnvironment is the Spring Cloud Config server's answer to
+// /{application}/{profile}
+type SpringEnvironment struct {
+	Name     string   `json:"name"`
+	Profiles []string `json:"profiles"`
+	Label    *string  `json:"label"`
+	Version  *string  `json:"version"`
+	State    *string  `json:"state"`
+	// PropertySources come highest precedence first
+	PropertySources []SpringPropertySource `json:"propertySources"`
+}
+
+// SpringPropertySource is one layer of a SpringEnvironment
+type SpringPropertySource struct {
+	Name   string                 `json:"name"`
+	Source map[string]interface{} `json:"source"`
+}
+
+// SpringConfigServer serves namespaces over the Spring Cloud Config
+// server's contract, so JVM services read the same configs through
+// spring-cloud-config-client while they are migrated:
+//
+//	spring.config.import=configserver:http://localhost:8888
+//	spring.application.name=app(_)llm
+//	spring.profiles.active=production
+//
+// The application names the namespace, after Prefix, with "(_)" standing
+// for "/" as in Spring's labels. Each profile names an environment, or is
+// mapped to one by Profiles; "default" is development, and profiles
+// naming no environment are skipped. An environment is served as a
+// property source per environment of its override chain, so
+// production's values shadow staging's, staging's development's, and so
+// on, as on the server. Nested values are flattened to Spring's
+// properties (limits.max_tokens, stop[0]). Labels are accepted and
+// ignored. Masked secrets are left out.
+//
+// It answers GET /{application}/{profile}[/{label}] and
+// /actuator/health; DevServer answers the same routes from its Backend.
+type SpringConfigServer struct {
+	Reader ConfigReader
+	// Prefix is put before application names, e.g. "services/"
+	Prefix string
+	// Profiles maps Spring profiles to environments, e.g. "prod" to
+	// "production"
+	Profiles map[string]string
+	// Token, when set, is required as the bearer token of requests
+	Token string
+}
+
+// Handler returns the server's routes
+func (s *SpringConfigServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /actuator/health", func(w http.ResponseWriter, r *http.Request) {
+		writeDevJSON(w, http.StatusOK, map[string]string{"status": "UP"})
+	})
+	mux.HandleFunc("GET /{application}/{profile}", s.authorized(s.serveEnvironment))
+	mux.HandleFunc("GET /{application}/{profile}/{label}", s.authorized(s.serveEnvironment))
+	return mux
+}
+
+// Serve listens on addr until ctx is done, then shuts down gracefully
+func (s *SpringConfigServer) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// authorized checks the bearer token before calling next
+func (s *SpringConfigServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeDevError(w, http.StatusUnauthorized, "Missing or invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *SpringConfigServer) serveEnvironment(w http.ResponseWriter, r *http.Request) {
+	application := r.PathValue("application")
+	profiles := strings.Split(r.PathValue("profile"), ",")
+	env, err := s.Environment(r.Context(), application, profiles)
+	if err != nil {
+		writeDevBackendError(w, err)
+		return
+	}
+	if label := r.PathValue("label"); label != "" {
+		env.Label = &label
+	}
+	writeDevJSON(w, http.StatusOK, env)
+}
+
+// Environment reads application's configs in profiles as a Spring config
+// server would answer them
+func (s *SpringConfigServer) Environment(ctx context.Context, application string, profiles []string) (*SpringEnvironment, error) {
+	namespace := s.Prefix + strings.ReplaceAll(application, "(_)", "/")
+
+	// Later profiles take precedence, and each environment over those
+	// earlier in its chain
+	var envs []string
+	seen := map[string]bool{}
+	for i := len(profiles) - 1; i >= 0; i-- {
+		env, ok := s.environment(profiles[i])
+		if !ok {
+			continue
+		}
+		chain := overrideChain[env]
+		for j := len(chain) - 1; j >= 0; j-- {
+			if !seen[chain[j]] {
+				seen[chain[j]] = true
+				envs = append(envs, chain[j])
+			}
+		}
+	}
+
+	result := &SpringEnvironment{Name: application, Profiles: profiles, PropertySources: []SpringPropertySource{}}
+	for _, env := range envs {
+		configs, err := s.Reader.ListConfigsContext(ctx, namespace, env)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list %s (%s): %w", namespace, env, err)
+		}
+		if len(configs) == 0 {
+			continue
+		}
+		source := make(map[string]interface{}, len(configs))
+		for _, cfg := range configs {
+			if cfg.Value == encryptedPlaceholder {
+				continue
+			}
+			flattenSpringProperty(source, cfg.Key, normalizeExportValue(cfg.Value))
+		}
+		result.PropertySources = append(result.PropertySources, SpringPropertySource{
+			Name:   "llm-config:" + namespace + ":" + env,
+			Source: source,
+		})
+	}
+	return result, nil
+}
+
+// environment maps a Spring profile to an environment
+func (s *SpringConfigServer) environment(profile string) (string, bool) {
+	if env, ok := s.Profiles[profile]; ok {
+		profile = env
+	} else if profile == "default" {
+		profile = "development"
+	}
+	_, ok := overrideChain[profile]
+	return profile, ok
+}
+
+// flattenSpringProperty adds value under key to source, spelling out maps
+// and lists as Spring's relaxed binding reads them
+func flattenSpringProperty(source map[string]interface{}, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, item := range v {
+			flattenSpringProperty(source, key+"."+name, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenSpringProperty(source, fmt.Sprintf("%s[%d]", key, i), item)
+		}
+	default:
+		source[key] = v
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// mediaTypeNDJSON is newline-delimited JSON, one value per line
+const mediaTypeNDJSON = "application/x-ndjson"
+
+// streamAccept prefers NDJSON and takes JSON from servers without it
+const streamAccept = mediaTypeNDJSON + ", " + mediaTypeJSON + ";q=0.5"
+
+// StreamConfigs lists a namespace like ListConfigsContext, but calls
+// handle for each config as it arrives instead of collecting them, so
+// namespaces of tens of thousands of keys are read in bounded memory and
+// work starts before the last config lands:
+//
+//	err := client.StreamConfigs(ctx, "app/prompts", "production", func(cfg ConfigResponse) error {
+//		return index.Add(cfg.Key, cfg.Value)
+//	})
+//
+// The server sends the namespace as NDJSON; servers without it send JSON,
+// which is decoded one config at a time all the same, page after page.
+// An error from handle stops the stream and is returned as is.
+func (c *LLMConfigClient) StreamConfigs(ctx context.Context, namespace, env string, handle func(ConfigResponse) error) error {
+	observed := func(cfg ConfigResponse) error {
+		c.consumption.record(env, cfg)
+		c.observeDeprecatedRead(namespace, cfg.Key, cfg.Deprecation)
+		return handle(cfg)
+	}
+	query := url.Values{"env": {env}}
+	for {
+		resp, err := c.openStream(ctx, fmt.Sprintf("/configs/%s?%s", namespace, query.Encode()), streamAccept)
+		if err != nil {
+			return err
+		}
+		next, err := decodeConfigStream(resp, observed)
+		resp.Body.Close()
+		if err != nil || next == "" {
+			return err
+		}
+		if next == query.Get("cursor") {
+			return fmt.Errorf("server returned cursor %q again", next)
+		}
+		query.Set("cursor", next)
+	}
+}
+
+// decodeConfigStream calls handle for each config of a list response as it
+// is read, and returns the cursor of the next page, if any
+func decodeConfigStream(resp *http.Response, handle func(ConfigResponse) error) (string, error) {
+	next := resp.Header.Get(nextCursorHeader)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == mediaTypeNDJSON {
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64<<10), 10<<20)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var cfg ConfigResponse
+			if err := json.Unmarshal(line, &cfg); err != nil {
+				return "", fmt.Errorf("decode streamed config: %w", err)
+			}
+			if err := handle(cfg); err != nil {
+				return "", err
+			}
+		}
+		return next, scanner.Err()
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	tok, err := dec.Token()
+	if err != nil {
+		return "", fmt.Errorf("decode config list: %w", err)
+	}
+	switch tok {
+	case json.Delim('['):
+		return next, decodeConfigArray(dec, handle)
+	case json.Delim('{'):
+		// The v2 list shape, whose items come before or after its paging
+		for dec.More() {
+			field, err := dec.Token()
+			if err != nil {
+				return "", fmt.Errorf("decode config list: %w", err)
+			}
+			switch field {
+			case "items":
+				if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+					return "", fmt.Errorf("decode config list: items is not an array")
+				}
+				err = decodeConfigArray(dec, handle)
+			case "next_cursor":
+				var cursor string
+				if err = dec.Decode(&cursor); cursor != "" {
+					next = cursor
+				}
+			default:
+				var skip json.RawMessage
+				err = dec.Decode(&skip)
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+		return next, nil
+	}
+	return "", fmt.Errorf("decode config list: unexpected %v", tok)
+}
+
+// decodeConfigArray calls handle for each config up to the end of the
+// array whose opening bracket dec has just read
+func decodeConfigArray(dec *json.Decoder, handle func(ConfigResponse) error) error {
+	for dec.More() {
+		var cfg ConfigResponse
+		if err := dec.Decode(&cfg); err != nil {
+			return fmt.Errorf("decode streamed config: %w", err)
+		}
+		if err := handle(cfg); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil && err != io.EOF {
+		return fmt.Errorf("decode config list: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// WebhookRetryPolicy controls how the server redelivers a notification the
+// subscriber did not accept with a 2xx; zero fields take the server's
+// defaults
+type WebhookRetryPolicy struct {
+	MaxAttempts      int `json:"max_attempts,omitempty"`
+	InitialBackoffMs int `json:"initial_backoff_ms,omitempty"`
+	MaxBackoffMs     int `json:"max_backoff_ms,omitempty"`
+}
+
+// WebhookSubscription asks the server to POST a ChangeNotification to URL
+// for every matching config change
+type WebhookSubscription struct {
+	ID  string `json:"id,omitempty"`
+	URL string `json:"url"`
+	// Secret signs deliveries as with validation webhooks; it is write-only
+	// and never returned
+	Secret string `json:"secret,omitempty"`
+	// Namespaces are glob patterns ("app/*"); all namespaces when empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Environments limits deliveries to these environments; all when empty
+	Environments []string `json:"environments,omitempty"`
+	// Events are ConfigCreated, ConfigUpdated, and ConfigDeleted; all when
+	// empty
+	Events    []string           `json:"events,omitempty"`
+	Retry     WebhookRetryPolicy `json:"retry"`
+	Disabled  bool               `json:"disabled,omitempty"`
+	CreatedAt string             `json:"created_at,omitempty"`
+	CreatedBy string             `json:"created_by,omitempty"`
+}
+
+// WebhookDelivery is the outcome of one attempt to deliver a notification
+type WebhookDelivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	Attempt        int    `json:"attempt"`
+	// StatusCode is 0 when the subscriber could not be reached
+	StatusCode  int    `json:"status_code"`
+	DurationMs  int64  `json:"duration_ms"`
+	Error       string `json:"error,omitempty"`
+	DeliveredAt string `json:"delivered_at"`
+}
+
+// Succeeded reports whether the subscriber accepted the delivery
+func (d *WebhookDelivery) Succeeded() bool {
+	return d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+// ChangeNotification is the body the server POSTs to a subscription
+type ChangeNotification struct {
+	// DeliveryID is the same on every retry of a notification, so
+	// subscribers can drop duplicates
+	DeliveryID     string      `json:"delivery_id"`
+	SubscriptionID string      `json:"subscription_id"`
+	Event          ConfigEvent `json:"event"`
+	// Test marks notifications sent by TestWebhookSubscription
+	Test bool   `json:"test,omitempty"`
+	At   string `json:"at"`
+}
+
+// CreateWebhookSubscription registers a subscription; check the receiving
+// end with TestWebhookSubscription
+func (c *LLMConfigClient) CreateWebhookSubscription(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, error) {
+	if u, err := url.Parse(sub.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("subscription URL %q must be an http(s) URL: %w", sub.URL, ErrValidation)
+	}
+	for _, event := range sub.Events {
+		if !slices.Contains([]string{ConfigCreated, ConfigUpdated, ConfigDeleted}, event) {
+			return nil, fmt.Errorf("subscription event %q must be %s, %s, or %s: %w", event, ConfigCreated, ConfigUpdated, ConfigDeleted, ErrValidation)
+		}
+	}
+
+	var result WebhookSubscription
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(sub).
+		SetResult(&result).
+		Post("/subscriptions")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListWebhookSubscriptions returns every subscription the token can see
+func (c *LLMConfigClient) ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	var result []WebhookSubscription
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/subscriptions")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// TestWebhookSubscription sends a notification marked Test to the
+// subscription's URL now and returns how it went. A failed delivery is
+// reported in the result, not as an error.
+func (c *LLMConfigClient) TestWebhookSubscription(ctx context.Context, id string) (*WebhookDelivery, error) {
+	var result WebhookDelivery
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Post(fmt.Sprintf("/subscriptions/%s/test", url.PathEscape(id)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListWebhookDeliveries returns a subscription's recent delivery attempts,
+// newest first, for debugging a subscriber
+func (c *LLMConfigClient) ListWebhookDeliveries(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	var result []WebhookDelivery
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/subscriptions/%s/deliveries", url.PathEscape(id)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// DeleteWebhookSubscription removes a subscription, reporting whether it
+// existed; deliveries already being retried are dropped
+func (c *LLMConfigClient) DeleteWebhookSubscription(ctx context.Context, id string) (bool, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(fmt.Sprintf("/subscriptions/%s", url.PathEscape(id)))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
+
+// ChangeNotificationHandler adapts handle into the receiving end of a
+// subscription. Requests without a valid X-LLM-Config-Signature are
+// rejected when secret is non-empty. An error from handle answers 500, so
+// the server retries under the subscription's WebhookRetryPolicy.
+func ChangeNotificationHandler(secret string, handle func(ctx context.Context, n ChangeNotification) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+		if err != nil {
+			http.Error(w, "read body", http.StatusBadRequest)
+			return
+		}
+
+		if secret != "" && !validWebhookSignature(secret, body, r.Header.Get(validationSignatureHeader)) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var n ChangeNotification
+		if err := json.Unmarshal(body, &n); err != nil {
+			http.Error(w, "decode notification", http.StatusBadRequest)
+			return
+		}
+
+		if err := handle(r.Context(), n); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
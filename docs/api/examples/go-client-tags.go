@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TagListOptions filters ListByTag; empty fields match all
+type TagListOptions struct {
+	// Namespace limits results to this namespace and those under it
+	Namespace   string
+	Environment string
+}
+
+// TagCount is how many configs carry a tag
+type TagCount struct {
+	Tag     string `json:"tag"`
+	Configs int    `json:"configs"`
+}
+
+type updateTagsRequest struct {
+	Env    string   `json:"env"`
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+type updateTagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+// AddTags tags a config, e.g. "gpt-4o-migration", without writing a new
+// version of its value, and returns its tags afterwards. Tags it already
+// has are left alone.
+func (c *LLMConfigClient) AddTags(ctx context.Context, namespace, key, env string, tags ...string) ([]string, error) {
+	return c.updateTags(ctx, namespace, key, updateTagsRequest{Env: env, Add: tags})
+}
+
+// RemoveTags untags a config without writing a new version of its value,
+// and returns its tags afterwards. Tags it does not have are ignored.
+func (c *LLMConfigClient) RemoveTags(ctx context.Context, namespace, key, env string, tags ...string) ([]string, error) {
+	return c.updateTags(ctx, namespace, key, updateTagsRequest{Env: env, Remove: tags})
+}
+
+func (c *LLMConfigClient) updateTags(ctx context.Context, namespace, key string, req updateTagsRequest) ([]string, error) {
+	for _, tag := range append(req.Add, req.Remove...) {
+		if err := validateTag(tag); err != nil {
+			return nil, fmt.Errorf("tag %s/%s: %w", namespace, key, err)
+		}
+	}
+
+	var result updateTagsResponse
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(req).
+		SetResult(&result).
+		Patch(keyRoute(namespace, key, "tags"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result.Tags, nil
+}
+
+// ListByTag returns the configs carrying tag across namespaces, sorted by
+// namespace and key, so a group such as a migration can be reviewed or
+// changed as a set. Secret values are returned as "<encrypted>".
+func (c *LLMConfigClient) ListByTag(ctx context.Context, tag string, opts TagListOptions) ([]ConfigResponse, error) {
+	return collectPages(ctx, func(ctx context.Context, page PageOptions) (*Page[ConfigResponse], error) {
+		return c.ListByTagPage(ctx, tag, opts, page)
+	})
+}
+
+// ListTags returns the tags in use under namespace (everywhere when empty)
+// with how many configs carry each
+func (c *LLMConfigClient) ListTags(ctx context.Context, namespace string) ([]TagCount, error) {
+	var result []TagCount
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if namespace != "" {
+		req.SetQueryParam("namespace", namespace)
+	}
+
+	resp, err := req.Get("/tags")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// validateTag rejects tags that could not be given on a command line or in
+// a comma-separated list
+func validateTag(tag string) error {
+	if tag == "" || strings.ContainsAny(tag, ", \t\n") {
+		return fmt.Errorf("tag %q must be non-empty without spaces or commas: %w", tag, ErrValidation)
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Types a template parameter may declare
+const (
+	ParamString = "string"
+	ParamNumber = "number"
+	ParamBool   = "boolean"
+)
+
+// TemplateParameter is a value supplied when a template is instantiated
+type TemplateParameter struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Type is ParamString (the default), ParamNumber, or ParamBool
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Default is used when the parameter is not given; parameters without
+	// one are required
+	Default interface{}   `yaml:"default,omitempty" json:"default,omitempty"`
+	Enum    []interface{} `yaml:"enum,omitempty" json:"enum,omitempty"`
+	// Secret parameters may only fill Secrets, and are never stored with
+	// the template or printed
+	Secret bool `yaml:"secret,omitempty" json:"secret,omitempty"`
+}
+
+// ConfigTemplate is a vetted baseline, such as a standard chat service
+// bundle, that Instantiate stamps out into a new namespace. Namespace,
+// namespace fields, and config values may use {{param}} placeholders; a
+// value that is exactly one placeholder takes the parameter's type.
+type ConfigTemplate struct {
+	Name        string              `yaml:"name" json:"name"`
+	Version     int64               `yaml:"-" json:"version,omitempty"`
+	Description string              `yaml:"description,omitempty" json:"description,omitempty"`
+	Parameters  []TemplateParameter `yaml:"parameters" json:"parameters"`
+
+	// Namespace is the namespace to create, e.g. "teams/{{team}}/chat"
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Owners, e.g. "team:{{team}}", own the created namespace
+	Owners []string       `yaml:"owners" json:"owners"`
+	Quota  NamespaceQuota `yaml:"quota,omitempty" json:"quota"`
+	// Environments to write Configs and Secrets into
+	Environments []string               `yaml:"environments" json:"environments"`
+	Configs      map[string]interface{} `yaml:"configs" json:"configs"`
+	// Secrets maps a key to the secret parameter holding its value
+	Secrets map[string]string `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+}
+
+// templatePlaceholder matches {{param}}
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// LoadTemplate reads a ConfigTemplate from a YAML or JSON file
+func LoadTemplate(path string) (*ConfigTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t ConfigTemplate
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &t, nil
+}
+
+// Validate checks that the template is self-consistent: every placeholder
+// and secret names a declared parameter, and defaults fit their types
+func (t *ConfigTemplate) Validate() error {
+	if t.Name == "" || t.Namespace == "" || len(t.Environments) == 0 {
+		return fmt.Errorf("template needs a name, namespace, and environments: %w", ErrValidation)
+	}
+	params := map[string]TemplateParameter{}
+	for _, p := range t.Parameters {
+		if !templatePlaceholder.MatchString("{{" + p.Name + "}}") {
+			return fmt.Errorf("template %s: parameter name %q is not an identifier: %w", t.Name, p.Name, ErrValidation)
+		}
+		if p.Secret && p.Default != nil {
+			return fmt.Errorf("template %s: secret parameter %s cannot have a default: %w", t.Name, p.Name, ErrValidation)
+		}
+		if p.Default != nil {
+			if _, err := p.coerce(p.Default); err != nil {
+				return fmt.Errorf("template %s: default of %s: %v: %w", t.Name, p.Name, err, ErrValidation)
+			}
+		}
+		params[p.Name] = p
+	}
+
+	var problems []string
+	check := func(where string, value interface{}) {
+		walkReferences(value, func(s string) {
+			for _, m := range templatePlaceholder.FindAllStringSubmatch(s, -1) {
+				p, ok := params[m[1]]
+				switch {
+				case !ok:
+					problems = append(problems, fmt.Sprintf("%s uses undeclared parameter %s", where, m[1]))
+				case p.Secret:
+					problems = append(problems, fmt.Sprintf("%s uses secret parameter %s outside secrets", where, m[1]))
+				}
+			}
+		})
+	}
+	check("namespace", t.Namespace)
+	for _, owner := range t.Owners {
+		check("owners", owner)
+	}
+	for key, value := range t.Configs {
+		check("configs."+key, value)
+	}
+	for key, param := range t.Secrets {
+		if p, ok := params[param]; !ok || !p.Secret {
+			problems = append(problems, fmt.Sprintf("secrets.%s names %s, which is not a secret parameter", key, param))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("template %s: %s: %w", t.Name, strings.Join(problems, "; "), ErrValidation)
+	}
+	return nil
+}
+
+// coerce converts a supplied value (a string from the command line, or a
+// decoded JSON value) to the parameter's type and checks its enum
+func (p TemplateParameter) coerce(value interface{}) (interface{}, error) {
+	s, isString := value.(string)
+	switch p.Type {
+	case ParamNumber:
+		if isString {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", s)
+			}
+			value = f
+		}
+		value = canonicalValue(value)
+		switch value.(type) {
+		case int64, float64:
+		default:
+			return nil, fmt.Errorf("%v is not a number", value)
+		}
+	case ParamBool:
+		if isString {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", s)
+			}
+			value = b
+		}
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("%v is not a boolean", value)
+		}
+	case "", ParamString:
+		if !isString {
+			return nil, fmt.Errorf("%v is not a string", value)
+		}
+	default:
+		return nil, fmt.Errorf("unknown parameter type %q", p.Type)
+	}
+	if len(p.Enum) > 0 && !slices.ContainsFunc(p.Enum, func(e interface{}) bool { return canonicalValue(e) == value }) {
+		return nil, fmt.Errorf("%v is not one of %v", value, p.Enum)
+	}
+	return value, nil
+}
+
+// TemplateInstance is a template rendered with parameters: the namespace to
+// create and the manifests to apply to it
+type TemplateInstance struct {
+	Namespace NamespaceSpec `json:"namespace"`
+	Manifests []Manifest    `json:"manifests"`
+}
+
+// Render fills in parameters, which may be strings to be parsed as the
+// declared types. Unknown and missing required parameters are errors.
+func (t *ConfigTemplate) Render(params map[string]interface{}) (*TemplateInstance, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	var problems []string
+	for _, p := range t.Parameters {
+		given, ok := params[p.Name]
+		switch {
+		case ok:
+			v, err := p.coerce(given)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", p.Name, err))
+				continue
+			}
+			values[p.Name] = v
+		case p.Default != nil:
+			values[p.Name], _ = p.coerce(p.Default)
+		default:
+			problems = append(problems, p.Name+" is required")
+		}
+	}
+	for name := range params {
+		if !slices.ContainsFunc(t.Parameters, func(p TemplateParameter) bool { return p.Name == name }) {
+			problems = append(problems, name+" is not a parameter of the template")
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("template %s: %s: %w", t.Name, strings.Join(problems, "; "), ErrValidation)
+	}
+
+	// A namespace or owner that is exactly one placeholder takes the
+	// parameter's type, so format it back into a string
+	namespace := formatCLIValue(fillPlaceholders(t.Namespace, values))
+	instance := &TemplateInstance{Namespace: NamespaceSpec{
+		Name:        namespace,
+		Description: fmt.Sprintf("Created from template %s version %d", t.Name, t.Version),
+		Quota:       t.Quota,
+		DefaultTags: []string{fmt.Sprintf("template=%s@%d", t.Name, t.Version)},
+	}}
+	for _, owner := range t.Owners {
+		instance.Namespace.Owners = append(instance.Namespace.Owners, formatCLIValue(fillPlaceholders(owner, values)))
+	}
+
+	configs := fillPlaceholders(t.Configs, values).(map[string]interface{})
+	for _, env := range t.Environments {
+		m := Manifest{
+			Namespace:   namespace,
+			Environment: env,
+			Configs:     configs,
+			Source:      "template " + t.Name,
+		}
+		if len(t.Secrets) > 0 {
+			m.Secrets = map[string]string{}
+			m.SecretValues = map[string]string{}
+			for key, param := range t.Secrets {
+				m.Secrets[key] = param
+				m.SecretValues[key] = formatCLIValue(values[param])
+			}
+		}
+		instance.Manifests = append(instance.Manifests, m)
+	}
+	return instance, nil
+}
+
+// fillPlaceholders substitutes {{param}} in strings inside value
+func fillPlaceholders(value interface{}, params map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if m := templatePlaceholder.FindStringSubmatch(v); m != nil && m[0] == v {
+			// The whole string is one placeholder: keep the parameter's type
+			return params[m[1]]
+		}
+		return templatePlaceholder.ReplaceAllStringFunc(v, func(match string) string {
+			return formatCLIValue(params[templatePlaceholder.FindStringSubmatch(match)[1]])
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = fillPlaceholders(item, params)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = fillPlaceholders(item, params)
+		}
+		return out
+	}
+	return value
+}
+
+// PutTemplate stores a template as a new version, which later
+// instantiations use unless they pin an older one
+func (c *LLMConfigClient) PutTemplate(ctx context.Context, t ConfigTemplate, user string) (*ConfigTemplate, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	var result ConfigTemplate
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("user", user).
+		SetBody(t).
+		SetResult(&result).
+		Put(fmt.Sprintf("/templates/%s", url.PathEscape(t.Name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetTemplate returns a template; version 0 is the latest
+func (c *LLMConfigClient) GetTemplate(ctx context.Context, name string, version int64) (*ConfigTemplate, error) {
+	var result ConfigTemplate
+
+	req := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result)
+	if version > 0 {
+		req.SetQueryParam("version", strconv.FormatInt(version, 10))
+	}
+
+	resp, err := req.Get(fmt.Sprintf("/templates/%s", url.PathEscape(name)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListTemplates returns the latest version of every template
+func (c *LLMConfigClient) ListTemplates(ctx context.Context) ([]ConfigTemplate, error) {
+	var result []ConfigTemplate
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/templates")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// InstantiateOptions configures Instantiate
+type InstantiateOptions struct {
+	// Version pins the template version; 0 is the latest
+	Version int64
+	User    string
+	// DryRun renders and plans without creating or writing anything
+	DryRun bool
+}
+
+// Instantiate stamps out a template for a new team or service: it creates
+// the namespace with the template's owners and quota, then writes its
+// configs to every environment. The namespace must not exist yet (an error
+// matching ErrConflict), so an instance never overwrites an existing
+// project. If a write fails, the namespace is deleted again so the
+// instantiation can simply be re-run. The namespace's default tags record
+// the template and version.
+func (c *LLMConfigClient) Instantiate(ctx context.Context, name string, params map[string]interface{}, opts InstantiateOptions) (*TemplateInstance, *ApplyPlan, error) {
+	t, err := c.GetTemplate(ctx, name, opts.Version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("instantiate %s: %w", name, err)
+	}
+	instance, err := t.Render(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := c.GetNamespace(ctx, instance.Namespace.Name); err == nil {
+		return instance, nil, fmt.Errorf("instantiate %s: namespace %s already exists: %w", name, instance.Namespace.Name, ErrConflict)
+	} else if !errors.Is(err, ErrNotFound) {
+		return instance, nil, fmt.Errorf("instantiate %s: %w", name, err)
+	}
+
+	plan := &ApplyPlan{}
+	for _, m := range instance.Manifests {
+		plan.Changes = append(plan.Changes, plannedCreates(m)...)
+	}
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		a, b := plan.Changes[i], plan.Changes[j]
+		return a.Environment < b.Environment || a.Environment == b.Environment && a.Key < b.Key
+	})
+	if opts.DryRun {
+		return instance, plan, nil
+	}
+
+	if _, err := c.CreateNamespace(ctx, instance.Namespace, opts.User); err != nil {
+		return instance, plan, fmt.Errorf("instantiate %s: %w", name, err)
+	}
+	if err := c.Apply(ctx, plan, opts.User); err != nil {
+		err = fmt.Errorf("instantiate %s: %w", name, err)
+		if rollbackErr := c.rollbackInstance(ctx, instance.Namespace.Name); rollbackErr != nil {
+			err = errors.Join(err, fmt.Errorf("instantiate %s: namespace %s was left partly written: %w", name, instance.Namespace.Name, rollbackErr))
+		}
+		return instance, plan, err
+	}
+	return instance, plan, nil
+}
+
+// rollbackInstance deletes a namespace Instantiate created, with whatever
+// configs it got, after a failed write. It runs even when ctx was
+// cancelled, which may be why the write failed.
+func (c *LLMConfigClient) rollbackInstance(ctx context.Context, namespace string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	return c.DeleteNamespace(ctx, namespace, DeleteNamespaceOptions{Confirm: namespace, Force: true})
+}
+
+// plannedCreates lists a manifest's keys as creates, for a namespace that
+// does not exist yet and so cannot be diffed
+func plannedCreates(m Manifest) []ApplyChange {
+	var changes []ApplyChange
+	for _, key := range sortedMapKeys(m.Configs) {
+		changes = append(changes, ApplyChange{Action: ChangeCreate, Namespace: m.Namespace, Environment: m.Environment, Key: key, New: canonicalValue(m.Configs[key]), Source: m.Source})
+	}
+	for _, key := range sortedMapKeys(m.Secrets) {
+		changes = append(changes, ApplyChange{Action: ChangeCreate, Namespace: m.Namespace, Environment: m.Environment, Key: key, New: m.SecretValues[key], Secret: true, Source: m.Source})
+	}
+	return changes
+}
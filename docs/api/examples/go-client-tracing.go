@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies spans emitted by this client
+const tracerName = "llm-config-manager/go-client"
+
+// EnableTracing emits a client span for every HTTP request the client makes
+// and propagates W3C trace context and baggage to the server. Spans carry the
+// namespace, key, and environment; config values and bodies are never
+// recorded. A nil provider uses the global otel.GetTracerProvider().
+func (c *LLMConfigClient) EnableTracing(provider trace.TracerProvider) {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	base := c.httpClient.GetClient().Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t, ok := base.(*tracingTransport); ok {
+		base = t.base
+	}
+
+	c.httpClient.SetTransport(&tracingTransport{
+		base:       base,
+		basePath:   baseURLPath(c.baseURL),
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	})
+}
+
+// tracingTransport wraps each request in a span
+type tracingTransport struct {
+	base       http.RoundTripper
+	basePath   string
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	route, attrs := configRouteAttributes(trimBasePath(req.URL.Path, t.basePath), req.URL.Query())
+	attrs = append(attrs,
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+	)
+
+	ctx, span := t.tracer.Start(req.Context(), req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	if rid := resp.Header.Get("X-Request-ID"); rid != "" {
+		span.SetAttributes(attribute.String("llmconfig.request_id", rid))
+	}
+	return resp, nil
+}
+
+// configRouteAttributes turns an API path such as
+// /configs/app/model/history into a low-cardinality route template and the
+// namespace/key/environment attributes it names
+func configRouteAttributes(apiPath string, query url.Values) (string, []attribute.KeyValue) {
+	segments := strings.Split(strings.Trim(apiPath, "/"), "/")
+	var attrs []attribute.KeyValue
+	if env := query.Get("env"); env != "" {
+		attrs = append(attrs, attribute.String("llmconfig.environment", env))
+	}
+
+	if len(segments) >= 2 && segments[0] == "configs" {
+		attrs = append(attrs, attribute.String("llmconfig.namespace", segments[1]))
+		segments[1] = "{namespace}"
+		if len(segments) >= 3 && segments[2] != "usage" {
+			attrs = append(attrs, attribute.String("llmconfig.key", segments[2]))
+			segments[2] = "{key}"
+		}
+		if len(segments) >= 5 && segments[3] == "rollback" {
+			segments[4] = "{version}"
+		}
+	} else if len(segments) >= 2 {
+		// Other resources take an identifier as their second segment
+		// (/schemas/{id}, /constraints/{id}, /validation-webhooks/{id})
+		segments[1] = "{id}"
+	}
+	return "/" + strings.Join(segments, "/"), attrs
+}
+
+// baseURLPath is the path prefix of the API base URL, e.g. /api/v1
+func baseURLPath(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
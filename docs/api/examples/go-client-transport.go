@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// wrappingTransport is a RoundTripper the client layers over its base
+// transport, such as tracing, fault injection, a cassette, middlewares
+// (see Use), endpoint discovery, or the caller's own (see WrapTransport)
+type wrappingTransport interface {
+	http.RoundTripper
+	wrapped() http.RoundTripper
+}
+
+func (t *tracingTransport) wrapped() http.RoundTripper    { return t.base }
+func (f *FaultInjector) wrapped() http.RoundTripper       { return f.base }
+func (c *Cassette) wrapped() http.RoundTripper            { return c.base }
+func (t *customTransport) wrapped() http.RoundTripper     { return t.base }
+func (t *middlewareTransport) wrapped() http.RoundTripper { return t.base }
+func (t *discoveryTransport) wrapped() http.RoundTripper  { return t.base }
+
+// customTransport is a RoundTripper installed with WrapTransport
+type customTransport struct {
+	http.RoundTripper
+	base http.RoundTripper
+}
+
+// WrapTransport layers a RoundTripper of the caller's over the client's
+// transport, e.g. corporate auth middleware or an observability wrapper:
+//
+//	client.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+//		return sso.NewTransport(next)
+//	})
+//
+// wrap gets the current transport, including any tracing or fault
+// injection, and should send requests on through it so WithUnixSocket and
+// WithProxy keep working; a RoundTripper that ignores next replaces the
+// client's transport outright. Call it before making requests.
+func (c *LLMConfigClient) WrapTransport(wrap func(next http.RoundTripper) http.RoundTripper) *LLMConfigClient {
+	base := c.httpClient.GetClient().Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.httpClient.SetTransport(&customTransport{RoundTripper: wrap(base), base: base})
+	return c
+}
+
+// baseTransport returns the *http.Transport beneath the client's wrapping
+// transports, for changing how connections are made. It is replaced by a
+// clone of http.DefaultTransport if the base is some other RoundTripper.
+func (c *LLMConfigClient) baseTransport() *http.Transport {
+	var outer wrappingTransport
+	rt := c.httpClient.GetClient().Transport
+	for {
+		w, ok := rt.(wrappingTransport)
+		if !ok {
+			break
+		}
+		outer, rt = w, w.wrapped()
+	}
+	if t, ok := rt.(*http.Transport); ok {
+		return t
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	switch outer := outer.(type) {
+	case nil:
+		c.httpClient.SetTransport(t)
+	case *tracingTransport:
+		outer.base = t
+	case *FaultInjector:
+		outer.base = t
+	case *Cassette:
+		outer.base = t
+	case *customTransport:
+		outer.base = t
+	case *middlewareTransport:
+		outer.base = t
+	case *discoveryTransport:
+		outer.base = t
+	}
+	return t
+}
+
+// WithUnixSocket makes the client connect to the server over the Unix
+// domain socket at path instead of TCP, for the sidecar and node-agent
+// deployments where the server listens on e.g. /run/llm-config/agent.sock.
+// The host of the base URL is then only sent as the Host header; use an
+// http:// base URL, since the socket needs no TLS:
+//
+//	client := NewLLMConfigClient("http://llm-config/api/v1", token).
+//		WithUnixSocket("/run/llm-config/agent.sock")
+//
+// Call it before making requests. GRPCConfigClient takes a
+// "unix:///run/llm-config/agent.sock" target for the same.
+func (c *LLMConfigClient) WithUnixSocket(path string) *LLMConfigClient {
+	var dialer net.Dialer
+	t := c.baseTransport()
+	t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+	t.CloseIdleConnections()
+	return c
+}
+
+// WithDialer makes the client's connections with dial, e.g. to bind a
+// source address, mark sockets, or tunnel through a bastion. It replaces
+// the dialing of WithUnixSocket and WithDNSResolver, and they replace it;
+// the last call wins. Call it before making requests.
+func (c *LLMConfigClient) WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *LLMConfigClient {
+	t := c.baseTransport()
+	t.DialContext = dial
+	t.CloseIdleConnections()
+	return c
+}
+
+// WithDNSResolver looks up the server's host name with resolver instead of
+// the system's, e.g. to ask an internal DNS server directly:
+//
+//	client.WithDNSResolver(&net.Resolver{
+//		PreferGo: true,
+//		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+//			return (&net.Dialer{}).DialContext(ctx, network, "10.0.0.53:53")
+//		},
+//	})
+//
+// It dials as http.DefaultTransport does otherwise, and replaces WithDialer.
+// Call it before making requests. SRVDiscoverer looks up with the system's
+// resolver.
+func (c *LLMConfigClient) WithDNSResolver(resolver *net.Resolver) *LLMConfigClient {
+	dialer := &net.Dialer{Resolver: resolver, Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return c.WithDialer(dialer.DialContext)
+}
+
+// WithTLSConfig sets how the client's TLS connections are made, e.g. a
+// minimum version, a private CA pool, or the server name to send as SNI
+// and verify the certificate against when it differs from the URL's host:
+//
+//	pool := x509.NewCertPool()
+//	pool.AppendCertsFromPEM(caPEM)
+//	client.WithTLSConfig(&tls.Config{
+//		MinVersion: tls.VersionTLS13,
+//		RootCAs:    pool,
+//		ServerName: "llm-config.internal",
+//	})
+//
+// cfg is copied. Call it before making requests.
+func (c *LLMConfigClient) WithTLSConfig(cfg *tls.Config) *LLMConfigClient {
+	t := c.baseTransport()
+	t.TLSClientConfig = cfg.Clone()
+	t.CloseIdleConnections()
+	return c
+}
+
+// ProxyAuth authenticates the client to a proxy: as Basic
+// Proxy-Authorization to HTTP proxies, and as a username and password
+// (RFC 1929) to SOCKS5 proxies
+type ProxyAuth struct {
+	Username string
+	Password string
+}
+
+// WithProxy sends requests through the proxy at proxyURL, an http://,
+// https://, or socks5:// URL, instead of the one HTTPS_PROXY or HTTP_PROXY
+// names, which the client uses by default. Hosts in NO_PROXY and loopback
+// addresses are still reached directly. auth, when not nil, replaces any
+// credentials in proxyURL. Call it before making requests;
+// GRPCConfigClient follows HTTPS_PROXY on its own.
+func (c *LLMConfigClient) WithProxy(proxyURL string, auth *ProxyAuth) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("proxy %q: %v: %w", proxyURL, err, ErrValidation)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("proxy %q: the scheme must be http, https, or socks5: %w", proxyURL, ErrValidation)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy %q has no host: %w", proxyURL, ErrValidation)
+	}
+	if auth != nil {
+		u.User = url.UserPassword(auth.Username, auth.Password)
+	}
+
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    httpproxy.FromEnvironment().NoProxy,
+	}).ProxyFunc()
+	t := c.baseTransport()
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+	t.CloseIdleConnections()
+	return nil
+}
+
+// WithSOCKS5 sends requests through the SOCKS5 proxy at addr, a host:port,
+// like WithProxy. Host names are resolved by the proxy.
+func (c *LLMConfigClient) WithSOCKS5(addr string, auth *ProxyAuth) error {
+	return c.WithProxy("socks5://"+addr, auth)
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tuiScreen is the level of the browser currently shown
+type tuiScreen int
+
+const (
+	screenNamespaces tuiScreen = iota
+	screenKeys
+	screenDetail
+)
+
+var (
+	tuiTitleStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	tuiLabelStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	tuiStatusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	tuiErrorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// tuiItem is a row in the namespace and key lists
+type tuiItem struct {
+	title, description string
+}
+
+func (i tuiItem) Title() string       { return i.title }
+func (i tuiItem) Description() string { return i.description }
+func (i tuiItem) FilterValue() string { return i.title }
+
+// Messages carrying the results of API calls back to Update
+type (
+	tuiNamespacesMsg struct {
+		namespaces []string
+		err        error
+	}
+	tuiConfigsMsg struct {
+		configs []ConfigResponse
+		err     error
+	}
+	tuiHistoryMsg struct {
+		config  *ConfigResponse
+		history []VersionEntry
+		err     error
+	}
+	tuiSavedMsg struct {
+		config *ConfigResponse
+		err    error
+	}
+)
+
+// tuiModel browses namespaces, then keys, then one key's value and
+// history, which can be edited in place
+type tuiModel struct {
+	ctx    context.Context
+	client *LLMConfigClient
+	env    string
+	user   string
+
+	screen     tuiScreen
+	namespaces list.Model
+	keys       list.Model
+	detail     viewport.Model
+	input      textinput.Model
+	editing    bool
+
+	namespace string
+	configs   []ConfigResponse
+	config    *ConfigResponse
+	history   []VersionEntry
+	status    string
+	err       error
+}
+
+// RunTUI starts the interactive browser on env. With a namespace it opens
+// straight on that namespace's keys. Keys: enter opens, esc goes back,
+// / filters, e edits a value, r reloads, q quits.
+func (c *LLMConfigClient) RunTUI(ctx context.Context, namespace, env, user string) error {
+	newList := func(title string) list.Model {
+		l := list.New(nil, list.NewDefaultDelegate(), 0, 0)
+		l.Title = title
+		l.SetShowHelp(false)
+		return l
+	}
+
+	input := textinput.New()
+	input.Prompt = "new value> "
+
+	m := tuiModel{
+		ctx:        ctx,
+		client:     c,
+		env:        env,
+		user:       user,
+		namespaces: newList("Namespaces (" + env + ")"),
+		keys:       newList(""),
+		detail:     viewport.New(0, 0),
+		input:      input,
+	}
+	if namespace != "" {
+		m.screen = screenKeys
+		m.namespace = namespace
+		m.keys.Title = namespace + " (" + env + ")"
+	}
+
+	_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return err
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	if m.screen == screenKeys {
+		return m.loadConfigs()
+	}
+	return m.loadNamespaces()
+}
+
+func (m tuiModel) loadNamespaces() tea.Cmd {
+	return func() tea.Msg {
+		namespaces, err := m.client.ListNamespaces(m.ctx, "")
+		return tuiNamespacesMsg{namespaces: namespaces, err: err}
+	}
+}
+
+func (m tuiModel) loadConfigs() tea.Cmd {
+	namespace := m.namespace
+	return func() tea.Msg {
+		configs, err := m.client.ListConfigsContext(m.ctx, namespace, m.env)
+		return tuiConfigsMsg{configs: configs, err: err}
+	}
+}
+
+func (m tuiModel) loadHistory(cfg ConfigResponse) tea.Cmd {
+	return func() tea.Msg {
+		history, err := m.client.GetHistoryContext(m.ctx, cfg.Namespace, cfg.Key, m.env)
+		return tuiHistoryMsg{config: &cfg, history: history, err: err}
+	}
+}
+
+func (m tuiModel) save(raw string) tea.Cmd {
+	cfg := *m.config
+	return func() tea.Msg {
+		// A masked value can only be replaced by another secret
+		secret := cfg.Value == encryptedPlaceholder
+		saved, err := m.client.SetConfigContext(m.ctx, cfg.Namespace, cfg.Key, parseCLIValue(raw, secret), m.env, m.user, secret)
+		return tuiSavedMsg{config: saved, err: err}
+	}
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.namespaces.SetSize(msg.Width, msg.Height-1)
+		m.keys.SetSize(msg.Width, msg.Height-1)
+		m.detail.Width, m.detail.Height = msg.Width, msg.Height-3
+		m.input.Width = msg.Width - len(m.input.Prompt) - 1
+		return m, nil
+
+	case tuiNamespacesMsg:
+		m.err = msg.err
+		items := make([]list.Item, len(msg.namespaces))
+		for i, ns := range msg.namespaces {
+			items[i] = tuiItem{title: ns}
+		}
+		return m, m.namespaces.SetItems(items)
+
+	case tuiConfigsMsg:
+		m.err = msg.err
+		m.configs = msg.configs
+		items := make([]list.Item, len(msg.configs))
+		for i, cfg := range msg.configs {
+			items[i] = tuiItem{
+				title:       cfg.Key,
+				description: fmt.Sprintf("v%d  %s", cfg.Version, formatCLIValue(cfg.Value)),
+			}
+		}
+		return m, m.keys.SetItems(items)
+
+	case tuiHistoryMsg:
+		m.err = msg.err
+		m.config, m.history = msg.config, msg.history
+		m.detail.SetContent(m.renderDetail())
+		m.detail.GotoTop()
+		return m, nil
+
+	case tuiSavedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.err = nil
+		m.status = fmt.Sprintf("saved %s as version %d", msg.config.Key, msg.config.Version)
+		return m, tea.Batch(m.loadHistory(*msg.config), m.loadConfigs())
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+
+	return m.updateFocused(msg)
+}
+
+func (m tuiModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	if m.editing {
+		switch msg.String() {
+		case "esc":
+			m.editing = false
+			m.input.Blur()
+			return m, nil
+		case "enter":
+			m.editing = false
+			m.input.Blur()
+			return m, m.save(m.input.Value())
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	// While a list filter is being typed, every key belongs to the filter
+	if (m.screen == screenNamespaces && m.namespaces.FilterState() == list.Filtering) ||
+		(m.screen == screenKeys && m.keys.FilterState() == list.Filtering) {
+		return m.updateFocused(msg)
+	}
+
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "r":
+		switch m.screen {
+		case screenNamespaces:
+			return m, m.loadNamespaces()
+		case screenKeys:
+			return m, m.loadConfigs()
+		case screenDetail:
+			return m, m.loadHistory(*m.config)
+		}
+	case "esc", "backspace":
+		m.status, m.err = "", nil
+		switch m.screen {
+		case screenKeys:
+			m.screen = screenNamespaces
+			if len(m.namespaces.Items()) == 0 {
+				return m, m.loadNamespaces()
+			}
+		case screenDetail:
+			m.screen = screenKeys
+		}
+		return m, nil
+	case "enter":
+		m.status, m.err = "", nil
+		switch m.screen {
+		case screenNamespaces:
+			if item, ok := m.namespaces.SelectedItem().(tuiItem); ok {
+				m.screen = screenKeys
+				m.namespace = item.title
+				m.keys.Title = item.title + " (" + m.env + ")"
+				m.keys.ResetFilter()
+				return m, m.loadConfigs()
+			}
+		case screenKeys:
+			if item, ok := m.keys.SelectedItem().(tuiItem); ok {
+				for _, cfg := range m.configs {
+					if cfg.Key == item.title {
+						m.screen = screenDetail
+						m.config = &cfg
+						m.detail.SetContent("loading…")
+						return m, m.loadHistory(cfg)
+					}
+				}
+			}
+		}
+		return m, nil
+	case "e":
+		if m.screen == screenDetail && m.config != nil {
+			m.editing = true
+			m.input.SetValue("")
+			if m.config.Value != encryptedPlaceholder {
+				m.input.SetValue(formatCLIValue(m.config.Value))
+			}
+			m.input.CursorEnd()
+			return m, m.input.Focus()
+		}
+		return m, nil
+	}
+
+	return m.updateFocused(msg)
+}
+
+// updateFocused passes msg to the component of the current screen
+func (m tuiModel) updateFocused(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch m.screen {
+	case screenNamespaces:
+		m.namespaces, cmd = m.namespaces.Update(msg)
+	case screenKeys:
+		m.keys, cmd = m.keys.Update(msg)
+	case screenDetail:
+		m.detail, cmd = m.detail.Update(msg)
+	}
+	return m, cmd
+}
+
+func (m tuiModel) renderDetail() string {
+	if m.config == nil {
+		return ""
+	}
+	cfg := m.config
+
+	var b strings.Builder
+	b.WriteString(tuiTitleStyle.Render(fmt.Sprintf("%s/%s (%s)", cfg.Namespace, cfg.Key, cfg.Environment)))
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "%s v%d, updated %s by %s\n", tuiLabelStyle.Render("version"), cfg.Version, cfg.Metadata.UpdatedAt, cfg.Metadata.UpdatedBy)
+	if cfg.Metadata.Description != nil {
+		fmt.Fprintf(&b, "%s %s\n", tuiLabelStyle.Render("description"), *cfg.Metadata.Description)
+	}
+	if len(cfg.Metadata.Tags) > 0 {
+		fmt.Fprintf(&b, "%s %s\n", tuiLabelStyle.Render("tags"), strings.Join(cfg.Metadata.Tags, ", "))
+	}
+
+	b.WriteString("\n")
+	if value, err := json.MarshalIndent(cfg.Value, "", "  "); err == nil {
+		b.Write(value)
+	} else {
+		fmt.Fprint(&b, cfg.Value)
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString(tuiTitleStyle.Render("History"))
+	b.WriteString("\n")
+	for _, entry := range m.history {
+		fmt.Fprintf(&b, "v%-4d %s  %-12s %s\n", entry.Version, entry.CreatedAt, entry.CreatedBy, formatCLIValue(entry.Value))
+	}
+	return b.String()
+}
+
+func (m tuiModel) View() string {
+	var body, help string
+	switch m.screen {
+	case screenNamespaces:
+		body = m.namespaces.View()
+		help = "enter open · / filter · r reload · q quit"
+	case screenKeys:
+		body = m.keys.View()
+		help = "enter open · / filter · esc back · r reload · q quit"
+	case screenDetail:
+		body = m.detail.View()
+		help = "e edit · esc back · r reload · q quit"
+		if m.editing {
+			help = m.input.View() + "\n" + "enter save · esc cancel"
+		}
+	}
+
+	footer := tuiStatusStyle.Render(help)
+	switch {
+	case m.err != nil:
+		footer = tuiErrorStyle.Render(m.err.Error()) + "\n" + footer
+	case m.status != "":
+		footer = tuiStatusStyle.Render(m.status) + "\n" + footer
+	}
+	return body + "\n" + footer
+}
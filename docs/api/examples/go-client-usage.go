@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// UsageRecord counts this process's reads of one key
+type UsageRecord struct {
+	// Organization is the "org/project" the reads were made for, empty
+	// when unscoped; reports carry it in their headers
+	Organization string    `json:"-"`
+	Namespace    string    `json:"namespace"`
+	Key          string    `json:"key"`
+	Environment  string    `json:"environment"`
+	Reads        int64     `json:"reads"`
+	LastReadAt   time.Time `json:"last_read_at"`
+}
+
+// UsageReport is the body posted to /usage
+type UsageReport struct {
+	Client  string        `json:"client"`
+	Records []UsageRecord `json:"records"`
+}
+
+type usageKey struct {
+	org                 Organization
+	namespace, key, env string
+}
+
+// usageTracker accumulates reads between reports
+type usageTracker struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	records map[usageKey]*UsageRecord
+}
+
+// EnableUsageTracking records every key read through GetConfig (and the
+// helpers built on it) so usage can be inspected with UsageSnapshot or
+// reported to the server. List calls are not counted as key reads.
+func (c *LLMConfigClient) EnableUsageTracking() {
+	c.usage.enabled.Store(true)
+}
+
+// record counts one read made for org; it is a no-op when tracking is
+// disabled
+func (t *usageTracker) record(org Organization, namespace, key, env string, now time.Time) {
+	if !t.enabled.Load() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := usageKey{org, namespace, key, env}
+	rec, ok := t.records[k]
+	if !ok {
+		rec = &UsageRecord{Namespace: namespace, Key: key, Environment: env}
+		if org.ID != "" {
+			rec.Organization = org.ID + "/" + org.Project
+		}
+		t.records[k] = rec
+	}
+	rec.Reads++
+	rec.LastReadAt = now.UTC()
+}
+
+// UsageSnapshot returns the reads recorded since the last successful report,
+// sorted by organization, namespace, and key
+func (c *LLMConfigClient) UsageSnapshot() []UsageRecord {
+	if !c.usage.enabled.Load() {
+		return nil
+	}
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	records := make([]UsageRecord, 0, len(c.usage.records))
+	for _, rec := range c.usage.records {
+		records = append(records, *rec)
+	}
+	sortUsageRecords(records)
+	return records
+}
+
+func sortUsageRecords(records []UsageRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Organization != b.Organization {
+			return a.Organization < b.Organization
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Environment < b.Environment
+	})
+}
+
+// ReportUsage sends the accumulated reads to the server, which merges them
+// into the per-key statistics returned by GetKeyUsage. Reads are reported
+// for the organization they were made for, one report per organization,
+// whatever ctx carries. Counters are reset only once the server accepts
+// the report.
+func (c *LLMConfigClient) ReportUsage(ctx context.Context) error {
+	if !c.usage.enabled.Load() {
+		return nil
+	}
+	c.usage.mu.Lock()
+	pending := map[Organization][]UsageRecord{}
+	for k, rec := range c.usage.records {
+		pending[k.org] = append(pending[k.org], *rec)
+	}
+	c.usage.mu.Unlock()
+
+	hostname, _ := os.Hostname()
+	var errs []error
+	for org, records := range pending {
+		sortUsageRecords(records)
+		if err := c.reportUsage(WithOrganization(ctx, org), hostname, records); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		c.usage.mu.Lock()
+		for _, sent := range records {
+			k := usageKey{org, sent.Namespace, sent.Key, sent.Environment}
+			if rec, ok := c.usage.records[k]; ok {
+				rec.Reads -= sent.Reads
+				if rec.Reads <= 0 {
+					delete(c.usage.records, k)
+				}
+			}
+		}
+		c.usage.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// reportUsage posts one organization's records
+func (c *LLMConfigClient) reportUsage(ctx context.Context, hostname string, records []UsageRecord) error {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(UsageReport{Client: hostname, Records: records}).
+		Post("/usage")
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// StartUsageReporter calls ReportUsage every interval until ctx is done,
+// sending a final report on the way out. Failed reports are logged and
+// retried with the next batch.
+func (c *LLMConfigClient) StartUsageReporter(ctx context.Context, interval time.Duration) {
+	c.EnableUsageTracking()
+	go func() {
+		for {
+			select {
+			case <-c.clock.After(interval):
+				if err := c.ReportUsage(ctx); err != nil {
+					log.Printf("llm-config: usage report failed: %v", err)
+				}
+			case <-ctx.Done():
+				flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := c.ReportUsage(flushCtx); err != nil {
+					log.Printf("llm-config: final usage report failed: %v", err)
+				}
+				cancel()
+				return
+			}
+		}
+	}()
+}
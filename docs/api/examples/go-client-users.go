@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// TeamRole is a member's role within a team
+type TeamRole string
+
+const (
+	// TeamRoleMember shares the team's access to namespaces
+	TeamRoleMember TeamRole = "member"
+	// TeamRoleMaintainer can also add and remove members
+	TeamRoleMaintainer TeamRole = "maintainer"
+)
+
+// UserSpec is what CreateUser sets
+type UserSpec struct {
+	// ID is the login the user's tokens carry, usually an email address
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+}
+
+// User is an account as the server reports it
+type User struct {
+	UserSpec
+	Disabled  bool   `json:"disabled"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+}
+
+// TeamSpec is what CreateTeam sets
+type TeamSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Team is a group of users as the server reports it
+type Team struct {
+	TeamSpec
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+}
+
+// Membership is one user's place in one team
+type Membership struct {
+	Team string   `json:"team"`
+	User string   `json:"user"`
+	Role TeamRole `json:"role"`
+}
+
+// UserOwner and TeamOwner name the principals in NamespaceSpec.Owners and
+// AddNamespaceOwner, e.g. "user:alice@example.com" or "team:platform"
+func UserOwner(id string) string { return "user:" + id }
+
+// TeamOwner names a team as a namespace owner; see UserOwner
+func TeamOwner(name string) string { return "team:" + name }
+
+type createUserRequest struct {
+	UserSpec
+	User string `json:"user"`
+}
+
+type createTeamRequest struct {
+	TeamSpec
+	User string `json:"user"`
+}
+
+type membershipRequest struct {
+	Role TeamRole `json:"role"`
+}
+
+// CreateUser adds an account; one that exists matches ErrConflict, so
+// provisioning scripts can treat that as done
+func (c *LLMConfigClient) CreateUser(ctx context.Context, spec UserSpec, user string) (*User, error) {
+	if spec.ID == "" {
+		return nil, fmt.Errorf("create user: an ID is required: %w", ErrValidation)
+	}
+
+	var result User
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(createUserRequest{UserSpec: spec, User: user}).
+		SetResult(&result).
+		Post("/users")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// GetUser returns an account
+func (c *LLMConfigClient) GetUser(ctx context.Context, id string) (*User, error) {
+	var result User
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("/users/%s", url.PathEscape(id)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListUsers returns every account, sorted by ID
+func (c *LLMConfigClient) ListUsers(ctx context.Context) ([]User, error) {
+	var result []User
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/users")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// CreateTeam adds a team; one that exists matches ErrConflict
+func (c *LLMConfigClient) CreateTeam(ctx context.Context, spec TeamSpec, user string) (*Team, error) {
+	if spec.Name == "" || strings.Contains(spec.Name, "/") {
+		return nil, fmt.Errorf("create team %q: a name without slashes is required: %w", spec.Name, ErrValidation)
+	}
+
+	var result Team
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(createTeamRequest{TeamSpec: spec, User: user}).
+		SetResult(&result).
+		Post("/teams")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListTeams returns every team, sorted by name
+func (c *LLMConfigClient) ListTeams(ctx context.Context) ([]Team, error) {
+	var result []Team
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/teams")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// AddTeamMember puts a user in a team with role, or changes the role of an
+// existing member
+func (c *LLMConfigClient) AddTeamMember(ctx context.Context, team, userID string, role TeamRole) (*Membership, error) {
+	if role == "" {
+		role = TeamRoleMember
+	}
+
+	var result Membership
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(membershipRequest{Role: role}).
+		SetResult(&result).
+		Put(fmt.Sprintf("/teams/%s/members/%s", url.PathEscape(team), url.PathEscape(userID)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// RemoveTeamMember takes a user out of a team; it returns false if the user
+// was not a member
+func (c *LLMConfigClient) RemoveTeamMember(ctx context.Context, team, userID string) (bool, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(fmt.Sprintf("/teams/%s/members/%s", url.PathEscape(team), url.PathEscape(userID)))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
+
+// ListTeamMembers returns a team's memberships
+func (c *LLMConfigClient) ListTeamMembers(ctx context.Context, team string) ([]Membership, error) {
+	return c.listMemberships(ctx, fmt.Sprintf("/teams/%s/members", url.PathEscape(team)))
+}
+
+// ListUserMemberships returns the teams a user belongs to
+func (c *LLMConfigClient) ListUserMemberships(ctx context.Context, userID string) ([]Membership, error) {
+	return c.listMemberships(ctx, fmt.Sprintf("/users/%s/memberships", url.PathEscape(userID)))
+}
+
+func (c *LLMConfigClient) listMemberships(ctx context.Context, path string) ([]Membership, error) {
+	var result []Membership
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// AddNamespaceOwner makes a user or team (see UserOwner and TeamOwner) an
+// owner of namespace; adding an existing owner is a no-op
+func (c *LLMConfigClient) AddNamespaceOwner(ctx context.Context, namespace, owner string) error {
+	if !strings.HasPrefix(owner, "user:") && !strings.HasPrefix(owner, "team:") {
+		return fmt.Errorf("owner %q must be user:ID or team:NAME: %w", owner, ErrValidation)
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Put(fmt.Sprintf("/namespaces/%s/owners/%s", url.PathEscape(namespace), url.PathEscape(owner)))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// RemoveNamespaceOwner takes an owner off namespace. The server refuses,
+// with an error matching ErrConflict, to remove the last one.
+func (c *LLMConfigClient) RemoveNamespaceOwner(ctx context.Context, namespace, owner string) error {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(fmt.Sprintf("/namespaces/%s/owners/%s", url.PathEscape(namespace), url.PathEscape(owner)))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() && resp.StatusCode() != 404 {
+		return c.handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Failure policies for validation webhooks
+const (
+	// WebhookFailClosed rejects the write when the webhook is unreachable
+	WebhookFailClosed = "fail"
+	// WebhookFailOpen accepts the write when the webhook is unreachable
+	WebhookFailOpen = "ignore"
+)
+
+// validationSignatureHeader carries the HMAC-SHA256 of the request body
+const validationSignatureHeader = "X-LLM-Config-Signature"
+
+// ValidationWebhook is a server-side validator called before writes
+type ValidationWebhook struct {
+	ID        string `json:"id,omitempty"`
+	Namespace string `json:"namespace"`
+	URL       string `json:"url"`
+	// KeyPattern limits the webhook to matching keys (glob, all keys when empty)
+	KeyPattern    string `json:"key_pattern,omitempty"`
+	TimeoutMs     int    `json:"timeout_ms,omitempty"`
+	FailurePolicy string `json:"failure_policy,omitempty"`
+	// Secret signs webhook requests; it is write-only and never returned
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+// ValidationWebhookRequest is the body the server POSTs to a webhook
+type ValidationWebhookRequest struct {
+	Namespace   string      `json:"namespace"`
+	Key         string      `json:"key"`
+	Environment string      `json:"environment"`
+	User        string      `json:"user"`
+	Value       interface{} `json:"value"`
+	Secret      bool        `json:"secret"`
+}
+
+// ValidationWebhookResponse is what a webhook must answer
+type ValidationWebhookResponse struct {
+	Allowed  bool                `json:"allowed"`
+	Problems []ValidationProblem `json:"problems,omitempty"`
+}
+
+// RegisterValidationWebhook adds a validation webhook to a namespace
+func (c *LLMConfigClient) RegisterValidationWebhook(ctx context.Context, webhook ValidationWebhook) (*ValidationWebhook, error) {
+	var result ValidationWebhook
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetBody(webhook).
+		SetResult(&result).
+		Post("/validation-webhooks")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// ListValidationWebhooks returns the webhooks registered for a namespace
+func (c *LLMConfigClient) ListValidationWebhooks(ctx context.Context, namespace string) ([]ValidationWebhook, error) {
+	var result []ValidationWebhook
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetQueryParam("namespace", namespace).
+		SetResult(&result).
+		Get("/validation-webhooks")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return result, nil
+}
+
+// RemoveValidationWebhook deletes a webhook, reporting whether it existed
+func (c *LLMConfigClient) RemoveValidationWebhook(ctx context.Context, id string) (bool, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		Delete(fmt.Sprintf("/validation-webhooks/%s", id))
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode() == 404 {
+		return false, nil
+	}
+
+	if resp.IsError() {
+		return false, c.handleErrorResponse(resp)
+	}
+
+	return true, nil
+}
+
+// ValidationWebhookHandler adapts validate into an http.Handler implementing
+// the webhook contract. When secret is non-empty, requests without a valid
+// X-LLM-Config-Signature ("sha256=<hex hmac of body>") are rejected.
+func ValidationWebhookHandler(secret string, validate func(ctx context.Context, req ValidationWebhookRequest) ValidationWebhookResponse) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+		if err != nil {
+			http.Error(w, "read body", http.StatusBadRequest)
+			return
+		}
+
+		if secret != "" && !validWebhookSignature(secret, body, r.Header.Get(validationSignatureHeader)) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var req ValidationWebhookRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "decode request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(validate(r.Context(), req))
+	})
+}
+
+// SignWebhookBody computes the signature header value for body
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validWebhookSignature(secret string, body []byte, header string) bool {
+	if !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(SignWebhookBody(secret, body)), []byte(header))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+)
+
+// Validator is a custom check run before every write to the namespaces it is
+// registered for. Returned problems reject the write with a
+// *ValidationError; a non-nil error aborts the write as-is.
+type Validator interface {
+	ValidateWrite(ctx context.Context, namespace, key string, req SetConfigRequest) ([]ValidationProblem, error)
+}
+
+// ValidatorFunc adapts a function to Validator
+type ValidatorFunc func(ctx context.Context, namespace, key string, req SetConfigRequest) ([]ValidationProblem, error)
+
+// ValidateWrite implements Validator
+func (f ValidatorFunc) ValidateWrite(ctx context.Context, namespace, key string, req SetConfigRequest) ([]ValidationProblem, error) {
+	return f(ctx, namespace, key, req)
+}
+
+// ChainValidators runs validators in order and stops at the first one that
+// reports problems, so expensive checks can sit behind cheap ones
+func ChainValidators(validators ...Validator) Validator {
+	return ValidatorFunc(func(ctx context.Context, namespace, key string, req SetConfigRequest) ([]ValidationProblem, error) {
+		for _, v := range validators {
+			problems, err := v.ValidateWrite(ctx, namespace, key, req)
+			if err != nil || len(problems) > 0 {
+				return problems, err
+			}
+		}
+		return nil, nil
+	})
+}
+
+type validatorBinding struct {
+	organization     string
+	namespacePattern string
+	validator        Validator
+}
+
+// validatorRegistry holds custom validators in registration order
+type validatorRegistry struct {
+	mu       sync.RWMutex
+	bindings []validatorBinding
+}
+
+func newValidatorRegistry() *validatorRegistry {
+	return &validatorRegistry{}
+}
+
+// UseValidator runs validators before writes to namespaces matching
+// namespacePattern (a path.Match glob; "*" for all), in every organization.
+// Every matching validator runs and their problems are reported together.
+func (c *LLMConfigClient) UseValidator(namespacePattern string, validators ...Validator) error {
+	return c.useValidator("", namespacePattern, validators)
+}
+
+// UseValidatorContext is UseValidator for writes made in the organization
+// of ctx only
+func (c *LLMConfigClient) UseValidatorContext(ctx context.Context, namespacePattern string, validators ...Validator) error {
+	return c.useValidator(c.organizationScopeID(ctx), namespacePattern, validators)
+}
+
+func (c *LLMConfigClient) useValidator(organization, namespacePattern string, validators []Validator) error {
+	if _, err := path.Match(namespacePattern, ""); err != nil {
+		return fmt.Errorf("invalid namespace pattern %q: %w", namespacePattern, err)
+	}
+
+	c.validators.mu.Lock()
+	defer c.validators.mu.Unlock()
+	for _, v := range validators {
+		c.validators.bindings = append(c.validators.bindings, validatorBinding{organization: organization, namespacePattern: namespacePattern, validator: v})
+	}
+	return nil
+}
+
+// runValidators collects problems from every validator matching namespace
+// in the organization of ctx
+func (c *LLMConfigClient) runValidators(ctx context.Context, namespace, key string, req SetConfigRequest) ([]ValidationProblem, error) {
+	organization := c.organizationScopeID(ctx)
+	c.validators.mu.RLock()
+	var matched []Validator
+	for _, b := range c.validators.bindings {
+		if b.organization != "" && b.organization != organization {
+			continue
+		}
+		if ok, _ := path.Match(b.namespacePattern, namespace); ok {
+			matched = append(matched, b.validator)
+		}
+	}
+	c.validators.mu.RUnlock()
+
+	var problems []ValidationProblem
+	for _, v := range matched {
+		p, err := v.ValidateWrite(ctx, namespace, key, req)
+		if err != nil {
+			return nil, fmt.Errorf("validator for %s/%s: %w", namespace, key, err)
+		}
+		problems = append(problems, p...)
+	}
+	return problems, nil
+}
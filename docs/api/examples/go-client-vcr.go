@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// CassetteMode selects whether a cassette records or replays
+type CassetteMode int
+
+const (
+	// CassetteReplay serves recorded responses and fails unmatched requests
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests to the server and records them
+	CassetteRecord
+	// CassetteAuto replays when the cassette file exists, otherwise records
+	CassetteAuto
+)
+
+// Interaction is one recorded request/response pair. Bodies are scrubbed
+// before they are stored: secret write values and credential-looking fields
+// are masked, and no request headers are kept.
+type Interaction struct {
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	Query           string            `json:"query,omitempty"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	Status          int               `json:"status"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+}
+
+// Cassette is a recording of HTTP interactions installed as the client's
+// transport by UseCassette
+type Cassette struct {
+	path         string
+	recording    bool
+	base         http.RoundTripper
+	mu           sync.Mutex
+	Interactions []Interaction `json:"interactions"`
+	used         []bool
+}
+
+// UseCassette routes the client's traffic through a cassette at path. When
+// recording, call Save at the end of the run to write it; in replay mode
+// each request is answered by the first unused interaction with the same
+// method, path, query, and scrubbed body.
+func (c *LLMConfigClient) UseCassette(path string, mode CassetteMode) (*Cassette, error) {
+	if mode == CassetteAuto {
+		mode = CassetteRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = CassetteReplay
+		}
+	}
+
+	cassette := &Cassette{path: path, recording: mode == CassetteRecord}
+	if mode == CassetteReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("load cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, cassette); err != nil {
+			return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+		}
+		cassette.used = make([]bool, len(cassette.Interactions))
+	} else {
+		cassette.base = c.httpClient.GetClient().Transport
+		if cassette.base == nil {
+			cassette.base = http.DefaultTransport
+		}
+	}
+
+	c.httpClient.SetTransport(cassette)
+	return cassette, nil
+}
+
+// Save writes a recording cassette to its file
+func (k *Cassette) Save() error {
+	if !k.recording {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(k.path, append(data, '\n'), 0o644)
+}
+
+// RoundTrip implements http.RoundTripper
+func (k *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if k.recording {
+		return k.record(req, body)
+	}
+	return k.replay(req, body)
+}
+
+func (k *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := k.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	headers := map[string]string{}
+	for name := range resp.Header {
+		if name != "Date" && name != "Set-Cookie" {
+			headers[name] = resp.Header.Get(name)
+		}
+	}
+
+	k.mu.Lock()
+	k.Interactions = append(k.Interactions, Interaction{
+		Method:          req.Method,
+		Path:            req.URL.Path,
+		Query:           req.URL.RawQuery,
+		RequestBody:     scrubCassetteBody(body),
+		Status:          resp.StatusCode,
+		ResponseHeaders: headers,
+		ResponseBody:    scrubCassetteBody(respBody),
+	})
+	k.mu.Unlock()
+	return resp, nil
+}
+
+func (k *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	scrubbed := scrubCassetteBody(body)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i, in := range k.Interactions {
+		if k.used[i] || in.Method != req.Method || in.Path != req.URL.Path ||
+			in.Query != req.URL.RawQuery || in.RequestBody != scrubbed {
+			continue
+		}
+		k.used[i] = true
+
+		header := http.Header{}
+		for name, value := range in.ResponseHeaders {
+			header.Set(name, value)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			StatusCode:    in.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(in.ResponseBody))),
+			ContentLength: int64(len(in.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s: no unused interaction for %s %s?%s", k.path, req.Method, req.URL.Path, req.URL.RawQuery)
+}
+
+// scrubCassetteBody masks secrets in a JSON body so recordings are safe to
+// commit; non-JSON bodies are stored as-is
+func scrubCassetteBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return string(body)
+	}
+	masked, err := json.Marshal(maskDebugValue(doc))
+	if err != nil {
+		return string(body)
+	}
+	return string(masked)
+}
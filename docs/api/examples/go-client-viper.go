@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ViperProvider names the config manager to viper.AddRemoteProvider
+const ViperProvider = "llmconfig"
+
+// viperRemote is the interface of viper.RemoteConfig
+type viperRemote interface {
+	Get(rp viper.RemoteProvider) (io.Reader, error)
+	Watch(rp viper.RemoteProvider) (io.Reader, error)
+	WatchChannel(rp viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool)
+}
+
+// RegisterViperRemote makes the config manager a Viper remote provider
+// read through client, so services on Viper adopt it by adding a provider:
+//
+//	RegisterViperRemote(client)
+//	viper.AddRemoteProvider("llmconfig", "production", "app/llm")
+//	viper.SetConfigType("json")
+//	err := viper.ReadRemoteConfig()
+//
+// The provider's endpoint is the environment and its path the namespace,
+// read as a JSON object of its keys, so nested values are reached as
+// viper.GetInt("limits.max_tokens"). Masked secrets are left out; with
+// viper.AddSecureRemoteProvider they are revealed, the keyring naming the
+// file of the client-side secret key decrypting those encrypted with one.
+//
+// WatchRemoteConfig reads the namespace again, as Viper's own providers
+// do, and WatchRemoteConfigOnChannel follows it with Watch, delivering
+// the namespace after each change. Other providers are left to the remote
+// installed before, e.g. by importing github.com/spf13/viper/remote.
+func RegisterViperRemote(client *LLMConfigClient) {
+	if !slices.Contains(viper.SupportedRemoteProviders, ViperProvider) {
+		viper.SupportedRemoteProviders = append(viper.SupportedRemoteProviders, ViperProvider)
+	}
+	remote := &viperRemoteConfig{client: client}
+	if viper.RemoteConfig != nil {
+		remote.next = viper.RemoteConfig
+	}
+	viper.RemoteConfig = remote
+}
+
+// viperRemoteConfig is the viper.RemoteConfig installed by
+// RegisterViperRemote
+type viperRemoteConfig struct {
+	client *LLMConfigClient
+	// next serves the other providers
+	next viperRemote
+}
+
+func (r *viperRemoteConfig) Get(rp viper.RemoteProvider) (io.Reader, error) {
+	if rp.Provider() != ViperProvider {
+		if r.next == nil {
+			return nil, viper.UnsupportedRemoteProviderError(rp.Provider())
+		}
+		return r.next.Get(rp)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	data, err := r.read(ctx, rp)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (r *viperRemoteConfig) Watch(rp viper.RemoteProvider) (io.Reader, error) {
+	if rp.Provider() != ViperProvider && r.next != nil {
+		return r.next.Watch(rp)
+	}
+	return r.Get(rp)
+}
+
+// WatchChannel delivers the namespace after each change until quit is
+// sent to or closed. Viper reads the channel for good, so it is never
+// closed, and failed reads are logged rather than delivered.
+func (r *viperRemoteConfig) WatchChannel(rp viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool) {
+	if rp.Provider() != ViperProvider && r.next != nil {
+		return r.next.WatchChannel(rp)
+	}
+	responses := make(chan *viper.RemoteResponse)
+	quit := make(chan bool)
+	if rp.Provider() != ViperProvider {
+		log.Printf("llm-config viper: %v", viper.UnsupportedRemoteProviderError(rp.Provider()))
+		return responses, quit
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-quit
+		cancel()
+	}()
+	go func() {
+		defer cancel()
+		// One read covers every key changed in the same poll
+		deliver := func([]ConfigEvent) {
+			data, err := r.read(ctx, rp)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("llm-config viper: %v", err)
+				}
+				return
+			}
+			select {
+			case responses <- &viper.RemoteResponse{Value: data}:
+			case <-ctx.Done():
+			}
+		}
+		for {
+			err := r.client.watchBatches(ctx, []string{rp.Path()}, WatchOptions{Environment: rp.Endpoint()}, deliver)
+			if err == nil {
+				return
+			}
+			log.Printf("llm-config viper: %v; retrying", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+	return responses, quit
+}
+
+// read encodes rp's namespace for Viper
+func (r *viperRemoteConfig) read(ctx context.Context, rp viper.RemoteProvider) ([]byte, error) {
+	opts := AgentOptions{Environment: rp.Endpoint()}
+	if keyring := rp.SecretKeyring(); keyring != "" {
+		raw, err := os.ReadFile(keyring)
+		if err != nil {
+			return nil, err
+		}
+		if opts.SecretKey, err = ParseSecretKey(string(raw)); err != nil {
+			return nil, err
+		}
+		opts.RevealSecrets = true
+	}
+	values, err := r.client.agentValues(ctx, rp.Path(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(values)
+}
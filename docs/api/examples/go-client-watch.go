@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config event types
+const (
+	ConfigCreated = "created"
+	ConfigUpdated = "updated"
+	ConfigDeleted = "deleted"
+)
+
+// ConfigEvent is a change observed by a Watcher
+type ConfigEvent struct {
+	Type        string          `json:"type"`
+	Namespace   string          `json:"namespace"`
+	Environment string          `json:"environment"`
+	Key         string          `json:"key"`
+	Config      *ConfigResponse `json:"config,omitempty"`
+	Previous    *ConfigResponse `json:"previous,omitempty"`
+}
+
+// WatchOptions controls Watch
+type WatchOptions struct {
+	Environment string
+	// Interval between polls; 5s when zero
+	Interval time.Duration
+}
+
+// WatcherStatus is a point-in-time view of a watcher's health
+type WatcherStatus struct {
+	ID          string    `json:"id"`
+	Namespace   string    `json:"namespace"`
+	Environment string    `json:"environment"`
+	Connected   bool      `json:"connected"`
+	LastEventAt time.Time `json:"last_event_at,omitempty"`
+	LastPollAt  time.Time `json:"last_poll_at,omitempty"`
+	// ResumeToken fingerprints the last snapshot the watcher delivered;
+	// watchers reporting the same token have seen the same state
+	ResumeToken string `json:"resume_token,omitempty"`
+	Reconnects  int64  `json:"reconnects"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// Watcher polls a namespace and emits an event for each key that changes
+type Watcher struct {
+	client    *LLMConfigClient
+	id        string
+	namespace string
+	opts      WatchOptions
+	events    chan ConfigEvent
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu     sync.Mutex
+	status WatcherStatus
+}
+
+// watcherRegistry tracks live watchers for status reporting
+type watcherRegistry struct {
+	mu       sync.Mutex
+	seq      atomic.Int64
+	watchers map[string]*Watcher
+}
+
+func newWatcherRegistry() *watcherRegistry {
+	return &watcherRegistry{watchers: map[string]*Watcher{}}
+}
+
+// Watch starts watching namespace. The first poll establishes a baseline
+// and emits no events; it must succeed for Watch to return. Later poll
+// failures are retried at the same interval and show up in Status.
+func (c *LLMConfigClient) Watch(ctx context.Context, namespace string, opts WatchOptions) (*Watcher, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+
+	baseline, err := c.ListConfigsContext(ctx, namespace, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("watch %s: %w", namespace, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watcher{
+		client:    c,
+		id:        fmt.Sprintf("w%d", c.watchers.seq.Add(1)),
+		namespace: namespace,
+		opts:      opts,
+		events:    make(chan ConfigEvent, 64),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	w.status = WatcherStatus{
+		ID:          w.id,
+		Namespace:   namespace,
+		Environment: opts.Environment,
+		Connected:   true,
+		LastPollAt:  c.clock.Now(),
+		ResumeToken: snapshotToken(baseline),
+	}
+
+	c.watchers.mu.Lock()
+	c.watchers.watchers[w.id] = w
+	c.watchers.mu.Unlock()
+
+	go w.run(ctx, indexConfigs(baseline))
+	return w, nil
+}
+
+// Events delivers changes; it is closed when the watcher stops
+func (w *Watcher) Events() <-chan ConfigEvent {
+	return w.events
+}
+
+// Close stops the watcher and waits for it to exit
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// Status reports the watcher's connection state and progress
+func (w *Watcher) Status() WatcherStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *Watcher) run(ctx context.Context, previous map[string]ConfigResponse) {
+	defer func() {
+		w.client.watchers.mu.Lock()
+		delete(w.client.watchers.watchers, w.id)
+		w.client.watchers.mu.Unlock()
+		close(w.events)
+		close(w.done)
+	}()
+
+	clock := w.client.clock
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(w.opts.Interval):
+		}
+
+		configs, err := w.client.ListConfigsContext(ctx, w.namespace, w.opts.Environment)
+		if ctx.Err() != nil {
+			return
+		}
+
+		w.mu.Lock()
+		w.status.LastPollAt = clock.Now()
+		if err != nil {
+			w.status.Connected = false
+			w.status.LastError = err.Error()
+			w.mu.Unlock()
+			continue
+		}
+		if !w.status.Connected {
+			w.status.Reconnects++
+			w.client.metrics.Load().observeWatcherReconnect()
+		}
+		w.status.Connected = true
+		w.status.LastError = ""
+		w.mu.Unlock()
+
+		current := indexConfigs(configs)
+		events := diffSnapshots(w.namespace, w.opts.Environment, previous, current)
+		for _, event := range events {
+			select {
+			case w.events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		previous = current
+
+		w.mu.Lock()
+		w.status.ResumeToken = snapshotToken(configs)
+		if len(events) > 0 {
+			w.status.LastEventAt = clock.Now()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// watchBatches watches namespaces and calls handle with each namespace's
+// changes from one poll until ctx is done. Every namespace must be
+// watchable at the start.
+func (c *LLMConfigClient) watchBatches(ctx context.Context, namespaces []string, opts WatchOptions, handle func(events []ConfigEvent)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var watchers []*Watcher
+	for _, namespace := range namespaces {
+		w, err := c.Watch(ctx, namespace, opts)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		watchers = append(watchers, w)
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range watchers {
+		wg.Add(1)
+		go func(w *Watcher) {
+			defer wg.Done()
+			for ev := range w.Events() {
+				events := []ConfigEvent{ev}
+				for drained := false; !drained; {
+					select {
+					case ev, ok := <-w.Events():
+						if !ok {
+							drained = true
+							break
+						}
+						events = append(events, ev)
+					default:
+						drained = true
+					}
+				}
+				handle(events)
+			}
+		}(w)
+	}
+	<-ctx.Done()
+	for _, w := range watchers {
+		w.Close()
+	}
+	wg.Wait()
+	return nil
+}
+
+// WatcherStatuses reports every live watcher, ordered by ID
+func (c *LLMConfigClient) WatcherStatuses() []WatcherStatus {
+	c.watchers.mu.Lock()
+	watchers := make([]*Watcher, 0, len(c.watchers.watchers))
+	for _, w := range c.watchers.watchers {
+		watchers = append(watchers, w)
+	}
+	c.watchers.mu.Unlock()
+
+	statuses := make([]WatcherStatus, len(watchers))
+	for i, w := range watchers {
+		statuses[i] = w.Status()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// WatcherDebugHandler serves WatcherStatuses as JSON, for mounting on an
+// internal debug mux (e.g. /debug/llm-config/watchers). It answers 503 when
+// any watcher is disconnected so it can double as a readiness probe.
+func (c *LLMConfigClient) WatcherDebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := c.WatcherStatuses()
+		code := http.StatusOK
+		for _, s := range statuses {
+			if !s.Connected {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(statuses)
+	})
+}
+
+func indexConfigs(configs []ConfigResponse) map[string]ConfigResponse {
+	index := make(map[string]ConfigResponse, len(configs))
+	for _, cfg := range configs {
+		index[cfg.Key] = cfg
+	}
+	return index
+}
+
+// diffSnapshots returns events for keys created, updated, or deleted
+// between two snapshots, sorted by key
+func diffSnapshots(namespace, env string, previous, current map[string]ConfigResponse) []ConfigEvent {
+	var events []ConfigEvent
+	for key, cfg := range current {
+		cfg := cfg
+		prev, existed := previous[key]
+		switch {
+		case !existed:
+			events = append(events, ConfigEvent{Type: ConfigCreated, Key: key, Config: &cfg})
+		case prev.Version != cfg.Version:
+			prev := prev
+			events = append(events, ConfigEvent{Type: ConfigUpdated, Key: key, Config: &cfg, Previous: &prev})
+		}
+	}
+	for key, prev := range previous {
+		if _, ok := current[key]; !ok {
+			prev := prev
+			events = append(events, ConfigEvent{Type: ConfigDeleted, Key: key, Previous: &prev})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Key < events[j].Key })
+	for i := range events {
+		events[i].Namespace = namespace
+		events[i].Environment = env
+	}
+	return events
+}
+
+// snapshotToken fingerprints the key/version pairs of a snapshot
+func snapshotToken(configs []ConfigResponse) string {
+	versions := make([]string, len(configs))
+	for i, cfg := range configs {
+		versions[i] = fmt.Sprintf("%s@%d", cfg.Key, cfg.Version)
+	}
+	sort.Strings(versions)
+
+	h := sha256.New()
+	for _, v := range versions {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Pod annotations read and written by PodInjector
+const (
+	// annotationInjectNamespace requests injection of a config namespace
+	annotationInjectNamespace = "llm-config-manager.io/inject-namespace"
+	// annotationInjectEnv selects the environment; the webhook's default
+	// when absent
+	annotationInjectEnv = "llm-config-manager.io/inject-environment"
+	// annotationInjectAs is "env" (the default) or "volume"
+	annotationInjectAs = "llm-config-manager.io/inject-as"
+	// annotationInjectPrefix is prepended to injected variable names
+	annotationInjectPrefix = "llm-config-manager.io/inject-prefix"
+	// annotationInjectContainers lists the containers to inject into,
+	// comma-separated; all containers when absent
+	annotationInjectContainers = "llm-config-manager.io/inject-containers"
+	// annotationInjectMountPath is where the volume is mounted
+	annotationInjectMountPath = "llm-config-manager.io/inject-mount-path"
+
+	// annotationInjected records what was injected, so a pod is never
+	// injected twice
+	annotationInjected = "llm-config-manager.io/injected"
+	// annotationInjectedConfig holds the JSON the volume projects
+	annotationInjectedConfig = "llm-config-manager.io/injected-config"
+)
+
+// injectVolumeName is the volume added to pods in volume mode
+const injectVolumeName = "llm-config"
+
+// defaultInjectMountPath is where the volume is mounted when the pod does
+// not choose
+const defaultInjectMountPath = "/etc/llm-config"
+
+// InjectionGrant lets pods in a Kubernetes namespace, optionally only those
+// running as one service account, have some config namespaces injected
+type InjectionGrant struct {
+	KubernetesNamespace string
+	// ServiceAccount limits the grant to pods running as it; any when empty
+	ServiceAccount string
+	// ConfigNamespaces are path.Match globs ("app/*")
+	ConfigNamespaces []string
+}
+
+// ParseInjectionGrant parses the webhook's --allow flag,
+// K8S_NAMESPACE[/SERVICE_ACCOUNT]=CONFIG_NAMESPACE[,CONFIG_NAMESPACE...]:
+//
+//	team-a=app/team-a/*
+//	team-b/inference=app/llm,app/shared
+func ParseInjectionGrant(s string) (InjectionGrant, error) {
+	subject, patterns, ok := strings.Cut(s, "=")
+	if !ok || subject == "" || patterns == "" {
+		return InjectionGrant{}, fmt.Errorf("grant %q: want K8S_NAMESPACE[/SERVICE_ACCOUNT]=CONFIG_NAMESPACE[,...]: %w", s, ErrValidation)
+	}
+	var grant InjectionGrant
+	grant.KubernetesNamespace, grant.ServiceAccount, _ = strings.Cut(subject, "/")
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return InjectionGrant{}, fmt.Errorf("grant %q: bad config namespace %q: %w", s, pattern, ErrValidation)
+		}
+		grant.ConfigNamespaces = append(grant.ConfigNamespaces, pattern)
+	}
+	return grant, nil
+}
+
+// allows reports whether the grant covers a pod in k8sNamespace running
+// as serviceAccount reading namespace
+func (g InjectionGrant) allows(k8sNamespace, serviceAccount, namespace string) bool {
+	if g.KubernetesNamespace != k8sNamespace || (g.ServiceAccount != "" && g.ServiceAccount != serviceAccount) {
+		return false
+	}
+	for _, pattern := range g.ConfigNamespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PodInjector is a mutating admission handler that resolves the namespace
+// a pod's annotations name and injects it, either as environment variables
+// or as config.json in a volume projected from a pod annotation. Secrets
+// the API masks are never injected, and variables a container already sets
+// are left alone.
+//
+// Anyone who can create pods could otherwise read any namespace with the
+// webhook's token, so a pod is only injected with a namespace one of Grants
+// allows for its Kubernetes namespace and service account; others are
+// denied admission.
+type PodInjector struct {
+	Config  *LLMConfigClient
+	Decoder admission.Decoder
+	// DefaultEnvironment is used for pods without an environment annotation
+	DefaultEnvironment string
+	Grants             []InjectionGrant
+}
+
+// Handle implements admission.Handler
+func (p *PodInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var pod corev1.Pod
+	if err := p.Decoder.Decode(req, &pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if pod.Annotations[annotationInjectNamespace] == "" {
+		return admission.Allowed("no injection requested")
+	}
+	if pod.Annotations[annotationInjected] != "" {
+		return admission.Allowed("already injected")
+	}
+	if as := pod.Annotations[annotationInjectAs]; as != "" && as != "env" && as != "volume" {
+		return admission.Denied(fmt.Sprintf("%s must be env or volume, not %q", annotationInjectAs, as))
+	}
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	namespace := pod.Annotations[annotationInjectNamespace]
+	allowed := slices.ContainsFunc(p.Grants, func(g InjectionGrant) bool {
+		return g.allows(req.Namespace, serviceAccount, namespace)
+	})
+	if !allowed {
+		return admission.Denied(fmt.Sprintf("service account %s/%s may not have %s injected", req.Namespace, serviceAccount, namespace))
+	}
+
+	if err := p.inject(ctx, &pod); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	marshaled, err := json.Marshal(&pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// inject mutates pod as its annotations request
+func (p *PodInjector) inject(ctx context.Context, pod *corev1.Pod) error {
+	namespace := pod.Annotations[annotationInjectNamespace]
+	env := pod.Annotations[annotationInjectEnv]
+	if env == "" {
+		env = p.DefaultEnvironment
+	}
+
+	var selected []string
+	if list := pod.Annotations[annotationInjectContainers]; list != "" {
+		for _, name := range strings.Split(list, ",") {
+			selected = append(selected, strings.TrimSpace(name))
+		}
+	}
+	containers := func(visit func(*corev1.Container)) {
+		for _, list := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+			for i := range list {
+				if len(selected) == 0 || slices.Contains(selected, list[i].Name) {
+					visit(&list[i])
+				}
+			}
+		}
+	}
+
+	if pod.Annotations[annotationInjectAs] != "volume" {
+		vars, err := p.Config.ExecEnv(ctx, namespace, env, ExecEnvOptions{
+			Prefix:            pod.Annotations[annotationInjectPrefix],
+			SkipMaskedSecrets: true,
+		})
+		if err != nil {
+			return err
+		}
+		containers(func(c *corev1.Container) {
+			for _, v := range vars {
+				set := slices.ContainsFunc(c.Env, func(e corev1.EnvVar) bool { return e.Name == v.Name })
+				if !set {
+					c.Env = append(c.Env, corev1.EnvVar{Name: v.Name, Value: v.Value})
+				}
+			}
+		})
+	} else {
+		configs, err := p.Config.ListConfigsContext(ctx, namespace, env)
+		if err != nil {
+			return fmt.Errorf("list %s (%s): %w", namespace, env, err)
+		}
+		unmasked := make([]ConfigResponse, 0, len(configs))
+		for _, cfg := range configs {
+			if cfg.Value != encryptedPlaceholder {
+				unmasked = append(unmasked, cfg)
+			}
+		}
+		data, err := encodeExport(exportValues(unmasked), ExportFormatJSON)
+		if err != nil {
+			return err
+		}
+		pod.Annotations[annotationInjectedConfig] = string(data)
+
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: injectVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				DownwardAPI: &corev1.DownwardAPIVolumeSource{
+					Items: []corev1.DownwardAPIVolumeFile{{
+						Path:     "config.json",
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", annotationInjectedConfig)},
+					}},
+				},
+			},
+		})
+		mountPath := pod.Annotations[annotationInjectMountPath]
+		if mountPath == "" {
+			mountPath = defaultInjectMountPath
+		}
+		containers(func(c *corev1.Container) {
+			c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: injectVolumeName, MountPath: mountPath, ReadOnly: true})
+		})
+	}
+
+	pod.Annotations[annotationInjected] = fmt.Sprintf("%s (%s)", namespace, env)
+	return nil
+}
+
+// WebhookOptions configures RunWebhook
+type WebhookOptions struct {
+	Port int
+	// CertDir holds tls.crt and tls.key, e.g. from a cert-manager Secret
+	CertDir            string
+	DefaultEnvironment string
+	Grants             []InjectionGrant
+}
+
+// webhookPath is where the pod injector is served; the
+// MutatingWebhookConfiguration in deployment/kubernetes/webhook points here
+const webhookPath = "/mutate-v1-pod"
+
+// RunWebhook serves PodInjector over TLS until ctx is done
+func RunWebhook(ctx context.Context, c *LLMConfigClient, opts WebhookOptions) error {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+	ctrl.SetLogger(logrFromSlog())
+
+	srv := webhook.NewServer(webhook.Options{Port: opts.Port, CertDir: opts.CertDir})
+	srv.Register(webhookPath, &webhook.Admission{Handler: &PodInjector{
+		Config:             c,
+		Decoder:            admission.NewDecoder(scheme),
+		DefaultEnvironment: opts.DefaultEnvironment,
+		Grants:             opts.Grants,
+	}})
+	return srv.Start(ctx)
+}
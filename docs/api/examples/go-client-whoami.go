@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Actions a namespace permission can grant
+const (
+	PermissionRead   = "read"
+	PermissionWrite  = "write"
+	PermissionReveal = "reveal"
+	PermissionAdmin  = "admin"
+)
+
+// Principal is who a token authenticates as
+type Principal struct {
+	// Kind is "user" or "service"
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+func (p Principal) String() string {
+	return p.Kind + ":" + p.ID
+}
+
+// NamespacePermission grants actions on namespaces matching a pattern and
+// everything under them
+type NamespacePermission struct {
+	// Namespace is a name or glob pattern ("app/*")
+	Namespace string   `json:"namespace"`
+	Actions   []string `json:"actions"`
+	// Via is the team or grant the permission comes from; empty if direct
+	Via string `json:"via,omitempty"`
+}
+
+// TokenInfo is what the server knows about the calling token
+type TokenInfo struct {
+	Principal    Principal             `json:"principal"`
+	Scopes       []string              `json:"scopes"`
+	Permissions  []NamespacePermission `json:"permissions"`
+	Organization string                `json:"organization,omitempty"`
+	IssuedAt     time.Time             `json:"issued_at"`
+	// ExpiresAt is zero for tokens that do not expire
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HasScope reports whether the token carries scope
+func (t *TokenInfo) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Can reports whether the token may perform action on namespace. Admin
+// implies every other action.
+func (t *TokenInfo) Can(action, namespace string) bool {
+	for _, perm := range t.Permissions {
+		if !slices.Contains(perm.Actions, action) && !slices.Contains(perm.Actions, PermissionAdmin) {
+			continue
+		}
+		// A grant on a namespace covers the namespaces under it
+		for ns := namespace; ns != "."; ns = path.Dir(ns) {
+			if ok, _ := path.Match(perm.Namespace, ns); ok {
+				return true
+			}
+			if !strings.Contains(ns, "/") {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// ExpiresWithin reports whether the token expires within d of now
+func (t *TokenInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !t.ExpiresAt.IsZero() && t.ExpiresAt.Before(now.Add(d))
+}
+
+// WhoAmI returns the principal, scopes, namespace permissions, and expiry of
+// the client's token, or of the organization token in ctx
+func (c *LLMConfigClient) WhoAmI(ctx context.Context) (*TokenInfo, error) {
+	var result TokenInfo
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get("/auth/whoami")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, c.handleErrorResponse(resp)
+	}
+
+	return &result, nil
+}
+
+// TokenRequirements is what a service needs from its token to start
+type TokenRequirements struct {
+	Scopes []string
+	// Read, Write, and Reveal list namespaces the token must have that
+	// permission on
+	Read   []string
+	Write  []string
+	Reveal []string
+	// MinValidity refuses tokens expiring sooner than this
+	MinValidity time.Duration
+}
+
+// RequireToken calls WhoAmI and checks the token against req, so a service
+// running with the wrong credentials fails at startup rather than on its
+// first write. The error, matching ErrUnauthorized, names the principal and
+// everything missing:
+//
+//	if _, err := client.RequireToken(ctx, TokenRequirements{
+//		Read:        []string{"app/billing"},
+//		MinValidity: time.Hour,
+//	}); err != nil {
+//		log.Fatal(err)
+//	}
+func (c *LLMConfigClient) RequireToken(ctx context.Context, req TokenRequirements) (*TokenInfo, error) {
+	info, err := c.WhoAmI(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("check token: %w", err)
+	}
+
+	var missing []string
+	for _, scope := range req.Scopes {
+		if !info.HasScope(scope) {
+			missing = append(missing, "scope "+scope)
+		}
+	}
+	for _, want := range []struct {
+		action     string
+		namespaces []string
+	}{
+		{PermissionRead, req.Read},
+		{PermissionWrite, req.Write},
+		{PermissionReveal, req.Reveal},
+	} {
+		for _, ns := range want.namespaces {
+			if !info.Can(want.action, ns) {
+				missing = append(missing, want.action+" on "+ns)
+			}
+		}
+	}
+	if req.MinValidity > 0 && info.ExpiresWithin(c.clock.Now(), req.MinValidity) {
+		missing = append(missing, fmt.Sprintf("validity of %s (expires %s)", req.MinValidity, info.ExpiresAt.Format(time.RFC3339)))
+	}
+
+	if len(missing) > 0 {
+		return info, fmt.Errorf("token for %s lacks %s: %w", info.Principal, strings.Join(missing, ", "), ErrUnauthorized)
+	}
+	return info, nil
+}
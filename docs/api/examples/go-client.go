@@ -9,24 +9,55 @@ and retry logic.
 
 Requirements:
 	go get github.com/go-resty/resty/v2
+	go get github.com/BurntSushi/toml gopkg.in/yaml.v3
+	go get github.com/hashicorp/hcl/v2 github.com/zclconf/go-cty
+	go get github.com/aws/aws-sdk-go-v2/service/ssm github.com/aws/aws-sdk-go-v2/service/secretsmanager
+	go get github.com/hashicorp/consul/api
+	go get github.com/santhosh-tekuri/jsonschema/v6
+	go get go.opentelemetry.io/otel go.opentelemetry.io/otel/trace
+	go get github.com/prometheus/client_golang
+	go get google.golang.org/grpc google.golang.org/protobuf google.golang.org/genproto/googleapis/rpc golang.org/x/net
+	go get github.com/vmihailenco/msgpack/v5
+	go get github.com/sigstore/sigstore
+	go get github.com/spf13/cobra github.com/spf13/pflag github.com/spf13/viper github.com/zalando/go-keyring golang.org/x/oauth2
+	go get sigs.k8s.io/controller-runtime k8s.io/api k8s.io/apimachinery k8s.io/client-go github.com/go-logr/logr
+	go get github.com/charmbracelet/bubbletea github.com/charmbracelet/bubbles github.com/charmbracelet/lipgloss github.com/muesli/termenv
+	go get github.com/testcontainers/testcontainers-go
+
+The client is split across the go-client*.go files in this directory;
+run it with `go run .`.
 */
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
-// ConfigClientError represents client errors
+// ConfigClientError represents client errors. Match it against the
+// sentinel errors (ErrNotFound, ErrRateLimited, ...) with errors.Is.
 type ConfigClientError struct {
 	StatusCode int
-	Message    string
+	// Code is the server's machine-readable error code, if any
+	Code    string
+	Message string
+	// RetryAfter is the server's requested backoff on 429 responses
+	RetryAfter time.Duration
+	// RequestID correlates the failure with the server's logs
+	RequestID string
 }
 
 func (e *ConfigClientError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("config client error (status %d, request %s): %s", e.StatusCode, e.RequestID, e.Message)
+	}
 	return fmt.Sprintf("config client error (status %d): %s", e.StatusCode, e.Message)
 }
 
@@ -41,6 +72,10 @@ type HealthResponse struct {
 	Status  string `json:"status"`
 	Service string `json:"service"`
 	Version string `json:"version"`
+	// ReadOnly is set while the server is in maintenance and refuses writes
+	ReadOnly          bool   `json:"read_only,omitempty"`
+	MaintenanceUntil  string `json:"maintenance_until,omitempty"`
+	MaintenanceReason string `json:"maintenance_reason,omitempty"`
 }
 
 // ConfigMetadata represents configuration metadata
@@ -55,13 +90,17 @@ type ConfigMetadata struct {
 
 // ConfigResponse represents a configuration entry
 type ConfigResponse struct {
-	ID          string                 `json:"id"`
-	Namespace   string                 `json:"namespace"`
-	Key         string                 `json:"key"`
-	Value       interface{}            `json:"value"`
-	Environment string                 `json:"environment"`
-	Version     int64                  `json:"version"`
-	Metadata    ConfigMetadata         `json:"metadata"`
+	ID          string         `json:"id"`
+	Namespace   string         `json:"namespace"`
+	Key         string         `json:"key"`
+	Value       interface{}    `json:"value"`
+	Environment string         `json:"environment"`
+	Version     int64          `json:"version"`
+	Metadata    ConfigMetadata `json:"metadata"`
+	// Expiry is set on values written with SetTemporaryConfig
+	Expiry *ConfigExpiry `json:"expiry,omitempty"`
+	// Deprecation is set on keys marked with Deprecate
+	Deprecation *KeyDeprecation `json:"deprecation,omitempty"`
 }
 
 // SetConfigRequest represents a request to set configuration
@@ -70,6 +109,9 @@ type SetConfigRequest struct {
 	Env    string      `json:"env"`
 	User   string      `json:"user"`
 	Secret bool        `json:"secret"`
+	Tags   []string    `json:"tags,omitempty"`
+	// Expiry makes the value temporary; see SetTemporaryConfig
+	Expiry *ExpiryRequest `json:"expiry,omitempty"`
 }
 
 // VersionEntry represents a version history entry
@@ -81,7 +123,7 @@ type VersionEntry struct {
 	ChangeDescription *string     `json:"change_description"`
 }
 
-// RateLimitInfo tracks rate limit status
+// RateLimitInfo is the rate limit status as of the latest response
 type RateLimitInfo struct {
 	Limit     int
 	Remaining int
@@ -89,12 +131,41 @@ type RateLimitInfo struct {
 	ResetTime time.Time
 }
 
-// LLMConfigClient provides access to the LLM Config Manager API
+// LLMConfigClient provides access to the LLM Config Manager API. It is
+// safe for concurrent use: requests, and the state they share such as the
+// rate-limit status, caches, and the token, may be used from any number
+// of goroutines. Configure it (the Set*, With*, Use*, and Enable* methods)
+// before sharing it, unless a method says it may be called at any time.
 type LLMConfigClient struct {
-	baseURL    string
-	token      string
-	httpClient *resty.Client
-	rateLimit  *RateLimitInfo
+	baseURL        string
+	token          string
+	httpClient     *resty.Client
+	rateLimit      atomic.Pointer[RateLimitInfo]
+	schemas        *schemaRegistry
+	constraints    *constraintRegistry
+	dependencies   *dependencyRegistry
+	validators     *validatorRegistry
+	policy         PolicyEvaluator
+	policyHooks    *policyListeners
+	manifest       *KeyManifest
+	metrics        atomic.Pointer[ClientMetrics]
+	debug          *debugState
+	usage          *usageTracker
+	consumption    *consumptionRecorder
+	rateLimitHooks *rateLimitHooks
+	watchers       *watcherRegistry
+	slowRequests   *slowRequestLog
+	prompts        *promptCache
+	budgets        *budgetWarnings
+	anomalies      *spendAnomalies
+	pricing        atomic.Pointer[PriceTable]
+	organizations  *organizationScope
+	maintenance    *maintenanceState
+	aliases        *aliasWarnings
+	deprecations   *deprecationWarnings
+	bulkEncodings  []PayloadEncoding
+	apiVersion     *apiVersionState
+	clock          Clock
 }
 
 // NewLLMConfigClient creates a new client instance
@@ -103,19 +174,50 @@ func NewLLMConfigClient(baseURL, token string) *LLMConfigClient {
 		SetBaseURL(baseURL).
 		SetTimeout(10 * time.Second).
 		SetRetryCount(3).
-		SetRetryWaitTime(1 * time.Second).
-		SetRetryMaxWaitTime(30 * time.Second)
+		// The real waits happen on the client's clock (see SetRetryAfter below)
+		SetRetryWaitTime(0)
 
 	if token != "" {
 		client.SetAuthToken(token)
 	}
 
 	llmClient := &LLMConfigClient{
-		baseURL:    baseURL,
-		token:      token,
-		httpClient: client,
-		rateLimit:  &RateLimitInfo{},
-	}
+		baseURL:        baseURL,
+		token:          token,
+		httpClient:     client,
+		schemas:        newSchemaRegistry(),
+		constraints:    newConstraintRegistry(),
+		dependencies:   newDependencyRegistry(),
+		validators:     newValidatorRegistry(),
+		policyHooks:    &policyListeners{},
+		debug:          &debugState{},
+		watchers:       newWatcherRegistry(),
+		slowRequests:   &slowRequestLog{},
+		prompts:        newPromptCache(),
+		budgets:        &budgetWarnings{fired: map[string]bool{}},
+		rateLimitHooks: &rateLimitHooks{},
+		usage:          &usageTracker{records: map[usageKey]*UsageRecord{}},
+		consumption:    &consumptionRecorder{values: map[ConsumedValue]bool{}},
+		anomalies:      &spendAnomalies{series: map[spendSeriesKey]*spendSeries{}},
+		organizations:  &organizationScope{},
+		maintenance:    &maintenanceState{},
+		aliases:        &aliasWarnings{warned: map[string]bool{}},
+		deprecations:   &deprecationWarnings{warned: map[string]bool{}},
+		apiVersion:     &apiVersionState{},
+		clock:          realClock{},
+	}
+
+	llmClient.rateLimit.Store(&RateLimitInfo{})
+	llmClient.installRequestIDs()
+	llmClient.installDebug()
+	llmClient.installSlowRequests()
+	llmClient.installAPIVersion()
+	llmClient.installOrganizations()
+	llmClient.installMaintenance()
+	llmClient.installAliases()
+	llmClient.installOwnership()
+	llmClient.installRateLimitHooks()
+	llmClient.installMetrics()
 
 	// Add response middleware to track rate limits
 	client.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
@@ -123,68 +225,151 @@ func NewLLMConfigClient(baseURL, token string) *LLMConfigClient {
 		return nil
 	})
 
-	// Add retry condition for rate limiting
+	// Add retry condition for rate limiting and server errors. There is no
+	// response when a request hook refused to send the request, a write
+	// refused for maintenance would only be refused again, and a wait that
+	// would outlast the caller's context or maxRetryWait is not started:
+	// the caller gets the error, with its RetryAfter, at once.
 	client.AddRetryCondition(func(r *resty.Response, err error) bool {
-		if r.StatusCode() == 429 {
-			retryAfter := r.Header().Get("Retry-After")
-			if retryAfter != "" {
-				duration, _ := time.ParseDuration(retryAfter + "s")
-				log.Printf("Rate limited. Waiting %v...", duration)
-				time.Sleep(duration)
-				return true
-			}
-			time.Sleep(60 * time.Second)
-			return true
+		if r == nil || !(r.StatusCode() == 429 || (r.StatusCode() >= 500 && !readOnlyResponse(r))) {
+			return false
+		}
+		wait := llmClient.retryDelay(r)
+		if deadline, ok := r.Request.Context().Deadline(); ok && time.Until(deadline) < wait {
+			return false
+		}
+		return wait <= maxRetryWait
+	})
+
+	// Wait between attempts on the client's clock instead of resty's timer,
+	// so tests using a FakeClock never really sleep. Each request waits on
+	// its own goroutine, and stops waiting when its context is done.
+	client.SetRetryAfter(func(_ *resty.Client, r *resty.Response) (time.Duration, error) {
+		if err := llmClient.clock.Sleep(r.Request.Context(), llmClient.retryDelay(r)); err != nil {
+			return 0, err
 		}
-		return r.StatusCode() >= 500
+		return time.Nanosecond, nil
 	})
 
 	return llmClient
 }
 
-// updateRateLimits updates rate limit info from response headers
-func (c *LLMConfigClient) updateRateLimits(resp *resty.Response) {
-	if limit := resp.Header().Get("X-RateLimit-Limit"); limit != "" {
-		fmt.Sscanf(limit, "%d", &c.rateLimit.Limit)
+// Retry backoff bounds
+const (
+	retryWaitTime    = 1 * time.Second
+	retryMaxWaitTime = 30 * time.Second
+	// defaultRateLimitWait is waited on 429s without a Retry-After
+	defaultRateLimitWait = 60 * time.Second
+	// maxRetryWait is the longest wait retried; the server asking for
+	// longer fails the request instead
+	maxRetryWait = 5 * time.Minute
+)
+
+// retryDelay honors Retry-After, on 429s and on 5xx answers that carry
+// one, such as a 503 during a restart; 429s without one wait
+// defaultRateLimitWait, and other answers back off exponentially from
+// retryWaitTime up to retryMaxWaitTime
+func (c *LLMConfigClient) retryDelay(r *resty.Response) time.Duration {
+	if wait := parseRetryAfter(r.Header(), c.clock.Now()); wait > 0 {
+		return wait
 	}
-	if remaining := resp.Header().Get("X-RateLimit-Remaining"); remaining != "" {
-		fmt.Sscanf(remaining, "%d", &c.rateLimit.Remaining)
+	if r.StatusCode() == 429 {
+		return defaultRateLimitWait
 	}
-	if reset := resp.Header().Get("X-RateLimit-Reset"); reset != "" {
-		var resetTimestamp int64
-		fmt.Sscanf(reset, "%d", &resetTimestamp)
-		c.rateLimit.Reset = resetTimestamp
-		c.rateLimit.ResetTime = time.Unix(resetTimestamp, 0)
+
+	wait := retryWaitTime
+	for i := 1; i < r.Request.Attempt && wait < retryMaxWaitTime; i++ {
+		wait *= 2
+	}
+	return min(wait, retryMaxWaitTime)
+}
+
+// updateRateLimits updates rate limit info from response headers.
+// Responses arriving together each publish a new snapshot rather than
+// writing into a shared one.
+func (c *LLMConfigClient) updateRateLimits(resp *resty.Response) {
+	limit := resp.Header().Get("X-RateLimit-Limit")
+	remaining := resp.Header().Get("X-RateLimit-Remaining")
+	reset := resp.Header().Get("X-RateLimit-Reset")
+	if limit == "" && remaining == "" && reset == "" {
+		return
+	}
+
+	var info RateLimitInfo
+	for {
+		old := c.rateLimit.Load()
+		info = *old
+		if limit != "" {
+			fmt.Sscanf(limit, "%d", &info.Limit)
+		}
+		if remaining != "" {
+			fmt.Sscanf(remaining, "%d", &info.Remaining)
+		}
+		if reset != "" {
+			fmt.Sscanf(reset, "%d", &info.Reset)
+			info.ResetTime = time.Unix(info.Reset, 0)
+		}
+		if c.rateLimit.CompareAndSwap(old, &info) {
+			break
+		}
 	}
 
-	// Log warning if rate limit is low
-	if c.rateLimit.Limit > 0 && c.rateLimit.Remaining < c.rateLimit.Limit/10 {
+	// Log warning if rate limit is low, unless it is exported as a metric
+	if c.metrics.Load() == nil && info.Limit > 0 && info.Remaining < info.Limit/10 {
 		log.Printf("Warning: Rate limit low: %d/%d remaining",
-			c.rateLimit.Remaining, c.rateLimit.Limit)
+			info.Remaining, info.Limit)
 	}
 }
 
 // handleErrorResponse handles API error responses
 func (c *LLMConfigClient) handleErrorResponse(resp *resty.Response) error {
-	var errorResp ErrorResponse
-	if err := json.Unmarshal(resp.Body(), &errorResp); err != nil {
-		return &ConfigClientError{
-			StatusCode: resp.StatusCode(),
-			Message:    string(resp.Body()),
-		}
+	clientErr := &ConfigClientError{
+		StatusCode: resp.StatusCode(),
+		RetryAfter: parseRetryAfter(resp.Header(), c.clock.Now()),
+		RequestID:  responseRequestID(resp),
 	}
 
-	return &ConfigClientError{
-		StatusCode: resp.StatusCode(),
-		Message:    errorResp.Message,
+	code, message, ok := decodeErrorBody(resp.Body())
+	if !ok {
+		clientErr.Message = string(resp.Body())
+		return readOnlyError(resp, clientErr)
+	}
+
+	clientErr.Code = code
+	clientErr.Message = message
+	if err := notOwnerError(resp, clientErr); err != nil {
+		return err
 	}
+	return readOnlyError(resp, clientErr)
+}
+
+// routeSeparator starts the part of a path naming a route about a namespace
+// or key. Neither may contain "~", so /configs/app/llm/~/usage cannot be
+// read as the key "usage" of the namespace "app/llm".
+const routeSeparator = "~"
+
+// namespaceRoute is the path of route about namespace, e.g. "usage"
+func namespaceRoute(namespace, route string) string {
+	return fmt.Sprintf("/configs/%s/%s/%s", namespace, routeSeparator, route)
+}
+
+// keyRoute is the path of route about namespace/key, e.g. "schema"
+func keyRoute(namespace, key, route string) string {
+	return fmt.Sprintf("/configs/%s/%s/%s/%s", namespace, key, routeSeparator, route)
 }
 
 // GetConfig retrieves a configuration value
 func (c *LLMConfigClient) GetConfig(namespace, key, env string, withOverrides bool) (*ConfigResponse, error) {
+	return c.GetConfigContext(context.Background(), namespace, key, env, withOverrides)
+}
+
+// GetConfigContext retrieves a configuration value, honoring ctx. A missing
+// key is reported as an error matching ErrNotFound.
+func (c *LLMConfigClient) GetConfigContext(ctx context.Context, namespace, key, env string, withOverrides bool) (*ConfigResponse, error) {
 	var result ConfigResponse
 
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"env":            env,
 			"with_overrides": fmt.Sprintf("%t", withOverrides),
@@ -197,27 +382,56 @@ func (c *LLMConfigClient) GetConfig(namespace, key, env string, withOverrides bo
 	}
 
 	if resp.IsError() {
-		if resp.StatusCode() == 404 {
-			return nil, nil
-		}
 		return nil, c.handleErrorResponse(resp)
 	}
 
+	c.usage.record(c.organizationFor(ctx), namespace, key, env, c.clock.Now())
+	c.consumption.record(env, result)
+	c.observeDeprecatedRead(namespace, key, result.Deprecation)
 	return &result, nil
 }
 
 // SetConfig sets a configuration value
 func (c *LLMConfigClient) SetConfig(namespace, key string, value interface{}, env, user string, secret bool) (*ConfigResponse, error) {
-	var result ConfigResponse
+	return c.SetConfigContext(context.Background(), namespace, key, value, env, user, secret)
+}
 
-	req := SetConfigRequest{
+// SetConfigContext sets a configuration value, honoring ctx
+func (c *LLMConfigClient) SetConfigContext(ctx context.Context, namespace, key string, value interface{}, env, user string, secret bool) (*ConfigResponse, error) {
+	return c.setConfig(ctx, namespace, key, SetConfigRequest{
 		Value:  value,
 		Env:    env,
 		User:   user,
 		Secret: secret,
+	})
+}
+
+// setConfig posts a fully populated SetConfigRequest
+func (c *LLMConfigClient) setConfig(ctx context.Context, namespace, key string, req SetConfigRequest) (*ConfigResponse, error) {
+	var result ConfigResponse
+
+	if err := c.validateWrite(ctx, namespace, key, req); err != nil {
+		return nil, err
+	}
+	input := PolicyInput{
+		Operation:   PolicyOperationSet,
+		Namespace:   namespace,
+		Key:         key,
+		Environment: req.Env,
+		User:        req.User,
+		Value:       req.Value,
+		Secret:      req.Secret,
+		Tags:        req.Tags,
+	}
+	if req.Secret {
+		input.Value = encryptedPlaceholder
+	}
+	if err := c.checkPolicy(ctx, input); err != nil {
+		return nil, err
 	}
 
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetBody(req).
 		SetResult(&result).
 		Post(fmt.Sprintf("/configs/%s/%s", namespace, key))
@@ -235,7 +449,22 @@ func (c *LLMConfigClient) SetConfig(namespace, key string, value interface{}, en
 
 // DeleteConfig deletes a configuration
 func (c *LLMConfigClient) DeleteConfig(namespace, key, env string) (bool, error) {
+	return c.DeleteConfigContext(context.Background(), namespace, key, env)
+}
+
+// DeleteConfigContext deletes a configuration, honoring ctx
+func (c *LLMConfigClient) DeleteConfigContext(ctx context.Context, namespace, key, env string) (bool, error) {
+	if err := c.checkPolicy(ctx, PolicyInput{
+		Operation:   PolicyOperationDelete,
+		Namespace:   namespace,
+		Key:         key,
+		Environment: env,
+	}); err != nil {
+		return false, err
+	}
+
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetQueryParam("env", env).
 		Delete(fmt.Sprintf("/configs/%s/%s", namespace, key))
 
@@ -256,29 +485,43 @@ func (c *LLMConfigClient) DeleteConfig(namespace, key, env string) (bool, error)
 
 // ListConfigs lists all configurations in a namespace
 func (c *LLMConfigClient) ListConfigs(namespace, env string) ([]ConfigResponse, error) {
-	var result []ConfigResponse
+	return c.ListConfigsContext(context.Background(), namespace, env)
+}
 
-	resp, err := c.httpClient.R().
-		SetQueryParam("env", env).
-		SetResult(&result).
-		Get(fmt.Sprintf("/configs/%s", namespace))
+// ListConfigsContext lists all configurations in a namespace, honoring ctx.
+// A paginated listing is followed to the end; see ListConfigsPage.
+func (c *LLMConfigClient) ListConfigsContext(ctx context.Context, namespace, env string) ([]ConfigResponse, error) {
+	return collectPages(ctx, func(ctx context.Context, opts PageOptions) (*Page[ConfigResponse], error) {
+		return c.ListConfigsPage(ctx, namespace, env, opts)
+	})
+}
 
+// ListNamespaces returns the namespaces that have at least one config,
+// sorted; a non-empty prefix ("app/") limits them to those starting with it
+func (c *LLMConfigClient) ListNamespaces(ctx context.Context, prefix string) ([]string, error) {
+	result, err := collectPages(ctx, func(ctx context.Context, opts PageOptions) (*Page[string], error) {
+		return c.ListNamespacesPage(ctx, prefix, opts)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.IsError() {
-		return nil, c.handleErrorResponse(resp)
-	}
-
+	sort.Strings(result)
 	return result, nil
 }
 
 // GetHistory retrieves version history for a configuration
 func (c *LLMConfigClient) GetHistory(namespace, key, env string) ([]VersionEntry, error) {
+	return c.GetHistoryContext(context.Background(), namespace, key, env)
+}
+
+// GetHistoryContext retrieves version history, honoring ctx. A missing key
+// is reported as an error matching ErrNotFound.
+func (c *LLMConfigClient) GetHistoryContext(ctx context.Context, namespace, key, env string) ([]VersionEntry, error) {
 	var result []VersionEntry
 
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetQueryParam("env", env).
 		SetResult(&result).
 		Get(fmt.Sprintf("/configs/%s/%s/history", namespace, key))
@@ -287,10 +530,6 @@ func (c *LLMConfigClient) GetHistory(namespace, key, env string) ([]VersionEntry
 		return nil, err
 	}
 
-	if resp.StatusCode() == 404 {
-		return []VersionEntry{}, nil
-	}
-
 	if resp.IsError() {
 		return nil, c.handleErrorResponse(resp)
 	}
@@ -300,9 +539,15 @@ func (c *LLMConfigClient) GetHistory(namespace, key, env string) ([]VersionEntry
 
 // Rollback rolls back a configuration to a specific version
 func (c *LLMConfigClient) Rollback(namespace, key string, version int64, env string) (*ConfigResponse, error) {
+	return c.RollbackContext(context.Background(), namespace, key, version, env)
+}
+
+// RollbackContext rolls back a configuration, honoring ctx
+func (c *LLMConfigClient) RollbackContext(ctx context.Context, namespace, key string, version int64, env string) (*ConfigResponse, error) {
 	var result ConfigResponse
 
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetQueryParam("env", env).
 		SetResult(&result).
 		Post(fmt.Sprintf("/configs/%s/%s/rollback/%d", namespace, key, version))
@@ -320,9 +565,15 @@ func (c *LLMConfigClient) Rollback(namespace, key string, version int64, env str
 
 // HealthCheck checks API health status
 func (c *LLMConfigClient) HealthCheck() (*HealthResponse, error) {
+	return c.HealthCheckContext(context.Background())
+}
+
+// HealthCheckContext checks API health status, honoring ctx
+func (c *LLMConfigClient) HealthCheckContext(ctx context.Context) (*HealthResponse, error) {
 	var result HealthResponse
 
 	resp, err := c.httpClient.R().
+		SetContext(ctx).
 		SetResult(&result).
 		Get("/health")
 
@@ -334,16 +585,32 @@ func (c *LLMConfigClient) HealthCheck() (*HealthResponse, error) {
 		return nil, c.handleErrorResponse(resp)
 	}
 
+	c.recordHealthMaintenance(&result)
 	return &result, nil
 }
 
-// GetRateLimitStatus returns current rate limit status
+// GetRateLimitStatus returns the rate limit status as of the latest
+// response. It is a copy; later responses do not change it.
 func (c *LLMConfigClient) GetRateLimitStatus() *RateLimitInfo {
-	return c.rateLimit
+	info := *c.rateLimit.Load()
+	return &info
 }
 
 // Example usage
 func main() {
+	// With arguments, run as the llmconfig CLI (e.g. `go run . get app/llm/model`,
+	// or `go run . gen ...` from go:generate)
+	if len(os.Args) > 1 {
+		if err := newCLI().Execute(); err != nil {
+			var exitErr *cliExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.code)
+			}
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initialize client
 	client := NewLLMConfigClient(
 		"http://localhost:8080/api/v1",
@@ -383,11 +650,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to get config: %v", err)
 	}
-	if config != nil {
-		fmt.Printf("Model: %v\n", config.Value)
-		fmt.Printf("Version: %d\n", config.Version)
-		fmt.Printf("Updated by: %s\n", config.Metadata.UpdatedBy)
-	}
+	fmt.Printf("Model: %v\n", config.Value)
+	fmt.Printf("Version: %d\n", config.Version)
+	fmt.Printf("Updated by: %s\n", config.Metadata.UpdatedBy)
 	fmt.Println()
 
 	// List configurations